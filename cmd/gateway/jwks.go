@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+
+	_ "github.com/lib/pq" // registers "postgres" for the platform key store
+
+	"github.com/mind-engage/mindengage-lms/internal/auth/jwks"
+	"github.com/mind-engage/mindengage-lms/internal/config"
+	platformlti "github.com/mind-engage/mindengage-lms/pkg/platform/lti"
+	platformstorage "github.com/mind-engage/mindengage-lms/pkg/platform/storage"
+	"github.com/mind-engage/mindengage-lms/pkg/platform/tenants"
+)
+
+// jwksHandler serves /.well-known/jwks.json. With PLATFORM_DB_DSN set it
+// publishes the tenant's keys from the platform's tenant_keys store, the
+// same keys platformd signs with, and makes sure LTI_TENANT has an active
+// one; without a DSN the key set is empty. closeFn releases the store.
+func jwksHandler(ctx context.Context, cfg config.Config) (h http.Handler, closeFn func() error, err error) {
+	if cfg.PlatformDBDSN == "" {
+		log.Printf("jwks: PLATFORM_DB_DSN not set; publishing an empty key set")
+		return jwks.Handler(jwks.JWKS{Keys: []jwks.JWK{}}), func() error { return nil }, nil
+	}
+	enc, err := platformlti.NewEncryptor("", cfg.PlatformKeysMasterKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	pdb, err := platformstorage.Connect(ctx, cfg.PlatformDBDriver, cfg.PlatformDBDSN)
+	if err != nil {
+		return nil, nil, err
+	}
+	keyManager := &platformlti.KeyManager{
+		Storage:          &platformlti.SQLKeyStorage{DB: pdb.SQL, Enc: enc},
+		Alg:              "RS256",
+		RotationInterval: cfg.PlatformKeysRotateEvery,
+		Overlap:          cfg.PlatformKeysGrace,
+	}
+	if _, err := keyManager.EnsureKey(ctx, cfg.LTITenant); err != nil {
+		_ = pdb.Close()
+		return nil, nil, err
+	}
+	resolver := tenants.NewResolver(tenants.Options{
+		BaseDomain:    cfg.LTITenantDomain,
+		HostIsTenant:  cfg.LTITenantDomain != "",
+		DefaultTenant: cfg.LTITenant,
+	})
+	return &platformlti.JWKSHandler{
+		ResolveTenantID: func(r *http.Request) (string, error) {
+			id, _, err := resolver.Resolve(r)
+			return id, err
+		},
+		Provider: keyManager,
+	}, pdb.Close, nil
+}
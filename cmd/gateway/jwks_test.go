@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mind-engage/mindengage-lms/internal/config"
+	"github.com/mind-engage/mindengage-lms/pkg/platform/storage"
+)
+
+func TestJWKSHandler_PublishesPlatformKeys(t *testing.T) {
+	ctx := context.Background()
+	dsn := filepath.Join(t.TempDir(), "platform.db")
+	pdb, err := storage.Connect(ctx, "sqlite", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { pdb.Close() })
+	if err := storage.Up(ctx, pdb, "sqlite"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := pdb.SQL.Exec(`INSERT INTO tenants (id, issuer) VALUES ('default', 'https://lti.example.com')`); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := config.Config{
+		EnableJWKS:              true,
+		LTITenant:               "default",
+		PlatformDBDriver:        "sqlite",
+		PlatformDBDSN:           dsn,
+		PlatformKeysMasterKey:   base64.StdEncoding.EncodeToString([]byte(strings.Repeat("k", 32))),
+		PlatformKeysGrace:       7 * 24 * time.Hour,
+		PlatformKeysRotateEvery: 90 * 24 * time.Hour,
+	}
+	kids := func() []string {
+		t.Helper()
+		h, closeFn, err := jwksHandler(ctx, cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer closeFn()
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/.well-known/jwks.json", nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d body=%s", rec.Code, rec.Body.String())
+		}
+		var set struct {
+			Keys []struct {
+				Kid string `json:"kid"`
+			} `json:"keys"`
+		}
+		if err := json.NewDecoder(rec.Body).Decode(&set); err != nil {
+			t.Fatal(err)
+		}
+		var out []string
+		for _, k := range set.Keys {
+			out = append(out, k.Kid)
+		}
+		return out
+	}
+
+	first := kids()
+	var active string
+	if err := pdb.SQL.QueryRow(`SELECT kid FROM tenant_keys WHERE tenant_id='default'`).Scan(&active); err != nil {
+		t.Fatal(err)
+	}
+	if len(first) != 1 || first[0] != active {
+		t.Fatalf("published kids = %v, want the active kid %q", first, active)
+	}
+	// A restarted (or second) gateway publishes the same stored key.
+	if again := kids(); len(again) != 1 || again[0] != active {
+		t.Fatalf("after restart kids = %v, want %q", again, active)
+	}
+}
@@ -8,26 +8,36 @@ import (
 	"encoding/json"
 	"io/fs"
 	"log"
+	"log/slog"
 	"net/http"
 	"os"
 	"strings"
 	"time"
+	_ "time/tzdata" // TIME_ZONE must resolve in minimal images
 
 	api "github.com/mind-engage/mindengage-lms/internal/api/http"
 	auth "github.com/mind-engage/mindengage-lms/internal/auth"
-	"github.com/mind-engage/mindengage-lms/internal/auth/jwks"
 	authmw "github.com/mind-engage/mindengage-lms/internal/auth/middleware"
+	"github.com/mind-engage/mindengage-lms/internal/auth/password"
+	"github.com/mind-engage/mindengage-lms/internal/basepath"
 	"github.com/mind-engage/mindengage-lms/internal/config"
 	"github.com/mind-engage/mindengage-lms/internal/db"
 	"github.com/mind-engage/mindengage-lms/internal/exam"
 	"github.com/mind-engage/mindengage-lms/internal/grading"
+	"github.com/mind-engage/mindengage-lms/internal/httplog"
+	"github.com/mind-engage/mindengage-lms/internal/httpsec"
+	"github.com/mind-engage/mindengage-lms/internal/i18n"
 	"github.com/mind-engage/mindengage-lms/internal/lti"
+	"github.com/mind-engage/mindengage-lms/internal/metrics"
+	"github.com/mind-engage/mindengage-lms/internal/ratelimit"
 	rbac "github.com/mind-engage/mindengage-lms/internal/rbac"
 	storage "github.com/mind-engage/mindengage-lms/internal/storage"
+	"github.com/mind-engage/mindengage-lms/pkg/lti-ags-gradebook/gradebook"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
-	"github.com/go-chi/cors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
 
 	_ "github.com/mind-engage/mindengage-lms/internal/formats/act"
 	_ "github.com/mind-engage/mindengage-lms/internal/formats/jee"
@@ -54,51 +64,144 @@ func main() {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	dbh, err := db.Open(ctx, db.Driver(cfg.DBDriver), cfg.DBDSN)
+	dbh, err := db.OpenWithPool(ctx, db.Driver(cfg.DBDriver), cfg.DBDSN, db.PoolConfig{
+		MaxOpenConns:    cfg.DBMaxOpenConns,
+		MaxIdleConns:    cfg.DBMaxIdleConns,
+		ConnMaxLifetime: cfg.DBConnMaxLifetime,
+	})
 	if err != nil {
 		log.Fatalf("db open failed: %v", err)
 	}
-	grader := grading.NewDefaultGrader() // or grading.NewDefaultGrader(grading.WithOCR(ocr.NewTesseractOCR()))
-	store := exam.NewSQLStore(dbh, cfg.DBDriver, grader)
+	// Gradebook tables (lti_platforms, lti_links, ...) share the app DB;
+	// LTI launches record their links there for grade passback.
+	if err := gradebook.Migrate(ctx, dbh, cfg.DBDriver); err != nil {
+		log.Fatalf("gradebook migrate: %v", err)
+	}
+	// --- Metrics ---
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(collectors.NewGoCollector(), collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}))
+	mtr := metrics.New(reg)
+
+	grader := metrics.Grader(grading.NewDefaultGrader(), mtr) // or grading.NewDefaultGrader(grading.WithOCR(ocr.NewTesseractOCR()))
+	sqlStore := exam.NewSQLStore(dbh, cfg.DBDriver, grader)
+	sqlStore.OnSubmitted = mtr.ObserveSubmitted
+	// Scores of LTI-launched takers go back to the platform's gradebook
+	// through the passback outbox.
+	sqlStore.Passback = cfg.EnableLTI
+	store := metrics.Store(sqlStore, mtr)
+	if cfg.EnableLTI {
+		passback := &lti.Passback{DB: dbh, ClientSecret: cfg.LTIToolClientSecret}
+		outbox := &exam.OutboxWorker{
+			DB:      dbh,
+			Deliver: metrics.Passback(passback.Deliver, mtr),
+			OnError: func(p exam.PassbackIntent, err error) {
+				log.Printf("passback %s: %v", p.AttemptID, err)
+			},
+		}
+		outbox.Start()
+	}
+
+	// Offerings with grading_mode=deferred leave submitted attempts ungraded;
+	// grade them in the background (teachers opening one grade it first).
+	gradingWorker := &exam.GradingWorker{
+		Store:    sqlStore,
+		Interval: cfg.DeferredGradingInterval,
+		OnError: func(err error) {
+			log.Printf("deferred grading: %v", err)
+		},
+	}
+	gradingWorker.Start()
+	defer gradingWorker.Stop()
 
 	// --- Auth ---
 	secret := getenvOr("AUTH_HMAC_SECRET", "supersecret-dev-key")
 	authSvc := authmw.NewAuthService(secret)
 
+	// Offering windows are also shown in the tenant's zone.
+	tz, err := time.LoadLocation(cfg.TimeZone)
+	if err != nil {
+		log.Fatalf("TIME_ZONE %q: %v", cfg.TimeZone, err)
+	}
+	// Signing keys published at /.well-known/jwks.json; see jwksHandler.
+	var jwksH http.Handler
+	if cfg.EnableJWKS {
+		h, closeJWKS, err := jwksHandler(ctx, cfg)
+		if err != nil {
+			log.Fatalf("jwks: %v", err)
+		}
+		defer closeJWKS()
+		jwksH = h
+	}
+	pwPolicy := password.Policy{
+		MinLength:  cfg.PasswordMinLength,
+		MinClasses: cfg.PasswordMinClasses,
+		DenyCommon: cfg.PasswordDenyCommon,
+		Cost:       cfg.BcryptCost,
+	}
+	if err := pwPolicy.Validate(); err != nil {
+		log.Fatalf("BCRYPT_COST: %v", err)
+	}
+
 	// --- Router ---
 	r := chi.NewRouter()
-	r.Use(middleware.RequestID, middleware.RealIP, middleware.Logger, middleware.Recoverer)
+	// Structured JSON access logs (request id, route, status, latency, sub/role)
+	logger := slog.New(slog.NewJSONHandler(os.Stdout, nil))
+	r.Use(middleware.RequestID, middleware.RealIP)
+	r.Use(httplog.Middleware(logger, httplog.Options{
+		Tenant: func(r *http.Request) string { return r.Header.Get("X-Tenant-ID") },
+	}))
+	r.Use(mtr.Middleware)
+	r.Use(middleware.Recoverer)
 	r.Use(middleware.Timeout(30 * time.Second))
-	r.Use(securityHeaders())
+	r.Use(httpsec.Headers(httpsec.HeaderOptions{
+		CSP:            cfg.CSP,
+		AssetHost:      cfg.AssetHost,
+		FrameAncestors: cfg.LTIFrameAncestors,
+		ReportOnly:     cfg.CSPReportOnly,
+	}))
 
 	// --- CORS ---
-	if cfg.Mode == config.ModeOnline {
-		r.Use(cors.Handler(cors.Options{
-			AllowedOrigins:   cfg.CORSOriginsOnline,
-			AllowedMethods:   []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
-			AllowedHeaders:   []string{"Authorization", "Content-Type"},
-			ExposedHeaders:   []string{"Content-Length"},
-			AllowCredentials: true,
-			MaxAge:           300,
-		}))
-	} else {
-		r.Use(cors.Handler(cors.Options{
-			AllowedOrigins:   cfg.CORSOriginsOffline,
-			AllowedMethods:   []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
-			AllowedHeaders:   []string{"Authorization", "Content-Type"},
-			ExposedHeaders:   []string{"Content-Length"},
-			AllowCredentials: true,
-			MaxAge:           300,
-		}))
+	// Anonymous endpoints (public catalogue, link offerings, anonymous
+	// attempts) are embeddable anywhere; everything else is credentialed.
+	publicCORS := httpsec.CORSPolicy{Origins: cfg.CORSOriginsPublic, Headers: []string{"X-Anon-Token"}}
+	r.Use(httpsec.CORS(
+		httpsec.CORSPolicy{Origins: cfg.CORSOrigins(), AllowCredentials: true},
+		httpsec.CORSGroup{Prefix: "/api/public", Policy: publicCORS},
+		httpsec.CORSGroup{Prefix: "/api/offerings", Policy: publicCORS},
+		httpsec.CORSGroup{Prefix: "/api/anonymous", Policy: publicCORS},
+		httpsec.CORSGroup{Prefix: "/api/admin", Policy: httpsec.CORSPolicy{Origins: cfg.CORSOriginsAdmin, AllowCredentials: true}},
+	))
+
+	// Metrics are scraped on their own listener (METRICS_ADDR, loopback by
+	// default), never through the public router.
+	if cfg.MetricsAddr != "" {
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", metrics.Handler(reg))
+		go func() {
+			msrv := &http.Server{Addr: cfg.MetricsAddr, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+			log.Printf("metrics on %s/metrics", cfg.MetricsAddr)
+			if err := msrv.ListenAndServe(); err != nil {
+				log.Printf("metrics listener: %v", err)
+			}
+		}()
 	}
 
 	// ======================
 	// API under /api prefix
 	// ======================
 	r.Route("/api", func(apiR chi.Router) {
+		// JSON bodies are capped here (413); multipart uploads are exempt.
+		apiR.Use(httpsec.MaxJSONBody(cfg.MaxJSONBodyBytes))
+		apiR.Use(api.StrictJSON(cfg.StrictJSON))
+		apiR.Use(api.ExamLimits(exam.Limits{MaxQuestions: cfg.MaxExamQuestions, MaxChoices: cfg.MaxQuestionChoices}))
+		apiR.Use(api.ScoreDecimals(cfg.ScoreDecimals))
+		apiR.Use(i18n.Middleware) // Accept-Language for error bodies and feedback
+		apiR.Use(api.TimeZone(tz))
+
 		// --- Health ---
 		apiR.Get("/healthz", func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) })
 		apiR.Get("/readyz", func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) })
+		apiR.Get("/version", api.VersionHandler(string(cfg.Mode), cfg.DBDriver))
 
 		apiR.Get("/features", func(w http.ResponseWriter, r *http.Request) {
 			type resp struct {
@@ -113,8 +216,8 @@ func main() {
 			})
 		})
 		// --- JWKS ---
-		if cfg.EnableJWKS {
-			apiR.Get("/.well-known/jwks.json", jwks.Handler(jwks.JWKS{Keys: []jwks.JWK{}}))
+		if jwksH != nil {
+			apiR.Method(http.MethodGet, "/.well-known/jwks.json", jwksH)
 		}
 
 		// --- LTI ---
@@ -148,6 +251,7 @@ func main() {
 		apiR.Group(func(pr chi.Router) {
 			pr.Use(authmw.JWTMiddleware(authSvc))
 			pr.Use(authmw.AttachRoleFromDB(dbh, allowClaimFallback))
+			pr.Use(httplog.CaptureAuth)
 			pr.Route("/assets", func(ar chi.Router) {
 				api.MountAssets(ar, bs)
 			})
@@ -156,10 +260,26 @@ func main() {
 		apiR.Get("/offerings/{offeringID}/resolve", api.GetOfferingByTokenHandler(dbh, store))
 		apiR.Post("/offerings/{offeringID}/grade_ephemeral", api.GradeEphemeralHandler(dbh, store, grader))
 		apiR.Get("/offerings/{offeringID}/ephemeral_stats", api.GetEphemeralStatsHandler(dbh))
+		apiR.Post("/offerings/{offeringID}/stats/reset", api.ResetEphemeralStatsHandler(dbh, authSvc))
+		apiR.Get("/offerings/{offeringID}/stats/distribution", api.GetEphemeralDistributionHandler(dbh, store, authSvc))
+
+		// Anonymous attempts on public/link offerings (anon token instead of JWT)
+		anonLim := &ratelimit.Limiter{Rate: 0.2, Burst: 5}
+		apiR.With(anonLim.Middleware).
+			Post("/offerings/{offeringID}/anonymous_attempts", api.CreateAnonymousAttemptHandler(dbh, store, authSvc))
+		apiR.Route("/anonymous/attempts/{attemptID}", func(ar chi.Router) {
+			ar.Use(api.RequireAnonymousOwner(authSvc, store))
+			ar.Get("/", api.GetAttemptHandler(store))
+			ar.Post("/responses", api.SaveResponsesHandler(store))
+			ar.Post("/navigate", api.NavigateHandler(store))
+			ar.Post("/submit", api.SubmitAttemptHandler(store))
+			ar.Post("/heartbeat", api.HeartbeatHandler(store))
+		})
 
 		apiR.Group(func(pr chi.Router) {
 			pr.Use(authmw.JWTMiddleware(authSvc))
 			pr.Use(authmw.AttachRoleFromDB(dbh, allowClaimFallback))
+			pr.Use(httplog.CaptureAuth)
 
 			// Exams
 			pr.With(rbac.Require("exam:create")).
@@ -168,8 +288,22 @@ func main() {
 				Get("/exams/{examID}", api.GetExamHandler(store))
 			pr.With(rbac.Require("exam:create")).
 				Post("/qti/import", api.ImportQTIHandler(store, bs))
+			pr.With(rbac.Require("exam:create")).
+				Post("/qti/import-package", api.ImportQTIPackageHandler(store, bs))
 			pr.With(rbac.Require("exam:export")).
 				Get("/exams/{id}/export", api.ExportQTIHandler(store))
+			pr.With(rbac.Require("attempt:view-all")).
+				Get("/exams/{examID}/stats", api.GetExamStatsHandler(store))
+			pr.With(rbac.Require("exam:export")).
+				Get("/exams/{examID}/questions", api.ListExamQuestionsHandler(store))
+
+			// Question bank (reused across exams via bank_id references)
+			pr.With(rbac.Require("exam:create")).
+				Post("/bank/questions", api.PutBankQuestionHandler(dbh, authSvc))
+			pr.With(rbac.Require("exam:create")).
+				Get("/bank/questions", api.ListBankQuestionsHandler(dbh))
+			pr.With(rbac.Require("exam:create")).
+				Get("/bank/questions/{questionID}", api.GetBankQuestionHandler(dbh))
 			pr.With(rbac.Require("exam:view")).
 				Get("/exams", api.ListExamsHandler(store, authSvc))
 
@@ -179,38 +313,57 @@ func main() {
 			// Attempts (create/save/submit/next)
 			pr.With(rbac.Require("attempt:create")).
 				Post("/attempts", api.CreateAttemptHandler(store))
-			pr.With(rbac.Require("attempt:save")).
+			// Writes: owner only; other attempt IDs are 404 (see api.RequireAttemptOwner).
+			ownAttempt := api.RequireAttemptOwner(store)
+			pr.With(rbac.Require("attempt:save"), ownAttempt).
 				Post("/attempts/{attemptID}/responses", api.SaveResponsesHandler(store))
 
-			pr.With(rbac.Require("attempt:save")).
+			pr.With(rbac.Require("attempt:save"), ownAttempt).
 				Post("/attempts/{attemptID}/navigate", api.NavigateHandler(store))
-			pr.With(rbac.Require("attempt:submit")).
+			pr.With(rbac.Require("attempt:submit"), ownAttempt).
 				Post("/attempts/{attemptID}/submit", api.SubmitAttemptHandler(store))
-			pr.With(rbac.Require("attempt:save")).
+			pr.With(rbac.Require("attempt:save"), ownAttempt).
 				Post("/attempts/{attemptID}/next-module", api.NextModuleHandler(store))
+			pr.With(rbac.Require("attempt:save"), ownAttempt).
+				Post("/attempts/{attemptID}/heartbeat", api.HeartbeatHandler(store))
+
+			// Move anonymous attempts onto the logged-in account
+			pr.Post("/attempts/claim", api.ClaimAnonymousAttemptsHandler(store, authSvc))
 
 			// Attempts (read)
-			// Single attempt: owner OR role with attempt:view-all
-			pr.With(rbac.RequireOwnerOr("attempt:view-all", api.IsAttemptOwner(store))).
+			// Single attempt: owner OR role with attempt:view-all; others get 404
+			pr.With(rbac.RequireOwnerOrNotFound("attempt:view-all", api.IsAttemptOwner(store))).
 				Get("/attempts/{attemptID}", api.GetAttemptHandler(store))
+			pr.With(rbac.RequireOwnerOrNotFound("attempt:view-all", api.IsAttemptOwner(store))).
+				Get("/attempts/{attemptID}/exam", api.GetAttemptExamHandler(store))
 
 			// List attempts: teachers/admins see all; students only their own (enforced in handler too)
 			pr.With(rbac.RequireAny("attempt:view-all", "attempt:view-own")).
 				Get("/attempts", api.ListAttemptsHandler(store))
+			// Batch read: per-attempt owner OR attempt:view-all (checked in handler)
+			pr.With(rbac.RequireAny("attempt:view-all", "attempt:view-own")).
+				Post("/attempts/batch", api.BatchGetAttemptsHandler(store))
+
+			// Proctoring: in-progress attempts whose heartbeat went quiet
+			pr.With(rbac.Require("attempt:view-all")).
+				Get("/attempts/stale", api.ListStaleAttemptsHandler(store))
 
 			// in /api group where JWT + role middleware are attached
 			pr.With(rbac.Require("attempt:grade")).
 				Get("/attempts/{attemptID}/grading", api.GetAttemptGradingHandler(store))
 			pr.With(rbac.Require("attempt:grade")).
 				Post("/attempts/{attemptID}/grading", api.ApplyAttemptGradingHandler(store, authSvc))
+			// Printable report (answer keys included): staff only
+			pr.With(rbac.Require("attempt:view-all")).
+				Get("/attempts/{attemptID}/report", api.AttemptReportHandler(store))
 
 			// Users admin
 			pr.With(rbac.Require("users:bulk_upsert")).
-				Post("/users/bulk", api.BulkUpsertUsersHandler(dbh, authSvc))
+				Post("/users/bulk", api.BulkUpsertUsersHandler(dbh, authSvc, pwPolicy))
 			pr.With(rbac.Require("users:list")).
 				Get("/users", api.ListUsersHandler(dbh))
 			pr.With(rbac.Require("user:change_password")).
-				Post("/users/change-password", api.ChangePasswordHandler(dbh))
+				Post("/users/change-password", api.ChangePasswordHandler(dbh, pwPolicy))
 
 			// ===========================
 			// Courses & offerings mapping
@@ -225,9 +378,15 @@ func main() {
 				// Add co-teachers
 				cr.With(rbac.Require("course:manage_teachers")).Post("/{courseID}/teachers", api.AddCoTeachersHandler(dbh, authSvc))
 
+				// Remove a teacher (owners/admin; never the last owner)
+				cr.With(rbac.Require("course:manage_teachers")).Delete("/{courseID}/teachers/{teacherID}", api.RemoveCoTeacherHandler(dbh, authSvc))
+
 				// Enroll students
 				cr.With(rbac.Require("course:manage_students")).Post("/{courseID}/students", api.EnrollStudentsHandler(dbh, authSvc))
 
+				// Drop a student (soft: status='dropped'; ?hard=1 removes the row)
+				cr.With(rbac.Require("course:manage_students")).Delete("/{courseID}/students/{studentID}", api.UnenrollStudentHandler(dbh, authSvc))
+
 				// Create an exam offering for a course
 				cr.With(rbac.Require("course:create_offering")).Post("/{courseID}/offerings", api.CreateOfferingHandler(dbh, authSvc))
 
@@ -237,18 +396,32 @@ func main() {
 				cr.With(rbac.RequireAny("course:delete_any", "course:delete_own")).
 					Delete("/{courseID}", api.DeleteCourseHandler(dbh, authSvc))
 
+				// Archive (soft, keeps attempts); ?undo=1 restores
+				cr.With(rbac.RequireAny("course:archive_any", "course:archive_own")).
+					Post("/{courseID}/archive", api.ArchiveCourseHandler(dbh, authSvc))
+
 				cr.Post("/{courseID}/offerings/{offID}/share-link", api.ShareOfferingLinkHandler(dbh, authSvc))
+				// Extra join tokens for a link offering (e.g. per class section)
+				cr.Post("/{courseID}/offerings/{offID}/tokens", api.CreateOfferingTokenHandler(dbh, authSvc))
+				cr.Get("/{courseID}/offerings/{offID}/tokens", api.ListOfferingTokensHandler(dbh, authSvc))
+				cr.Delete("/{courseID}/offerings/{offID}/tokens/{token}", api.RevokeOfferingTokenHandler(dbh, authSvc))
 
 			})
 			apiR.Route("/public", func(pr chi.Router) {
 				pr.Get("/courses", api.ListPublicCoursesHandler(dbh))
 				pr.Get("/courses/{courseID}/offerings", api.ListCoursePublicOfferingsHandler(dbh))
+
+				// Anonymous access to visibility='public' offerings (rate-limited per IP)
+				publicLim := &ratelimit.Limiter{Rate: 1, Burst: 20}
+				pr.With(publicLim.Middleware).Get("/offerings/{offeringID}", api.GetPublicOfferingHandler(dbh, store))
+				pr.With(publicLim.Middleware).Post("/offerings/{offeringID}/grade_ephemeral", api.GradePublicEphemeralHandler(dbh, store, grader))
 			})
 			pr.Get("/offerings/public", api.ListPublicOfferingsHandler(dbh))
 
 			apiR.Group(func(pr chi.Router) {
 				pr.Use(authmw.JWTMiddleware(authSvc))
 				pr.Use(authmw.AttachRoleFromDB(dbh, allowClaimFallback))
+				pr.Use(httplog.CaptureAuth)
 				mountAdminRoutes(pr, dbh, authSvc)
 			})
 		})
@@ -263,8 +436,10 @@ func main() {
 	mountSPA(r, "/admin/", "static/admin")
 	mountSPA(r, "/quiz/", "static/quiz")
 
-	log.Printf("listening on %s (mode=%s, db=%s)", cfg.HTTPAddr, cfg.Mode, cfg.DBDriver)
-	log.Fatal(http.ListenAndServe(cfg.HTTPAddr, r))
+	// Everything above is mounted at the root; BASE_PATH (e.g. /lms) moves it
+	// under a reverse-proxy prefix.
+	log.Printf("listening on %s%s (mode=%s, db=%s)", cfg.HTTPAddr, cfg.BasePath, cfg.Mode, cfg.DBDriver)
+	log.Fatal(cfg.HTTPServer(basepath.Strip(cfg.BasePath)(r)).ListenAndServe())
 }
 
 func getenvOr(k, def string) string {
@@ -274,17 +449,6 @@ func getenvOr(k, def string) string {
 	return def
 }
 
-func securityHeaders() func(http.Handler) http.Handler {
-	return func(next http.Handler) http.Handler {
-		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			w.Header().Set("X-Content-Type-Options", "nosniff")
-			//w.Header().Set("X-Frame-Options", "DENY")
-			w.Header().Set("Referrer-Policy", "strict-origin-when-cross-origin")
-			next.ServeHTTP(w, r)
-		})
-	}
-}
-
 func mountStatic(r chi.Router, prefix, dir string) {
 	sub, _ := fs.Sub(staticFS, dir)
 	r.Get(prefix, func(w http.ResponseWriter, req *http.Request) {
@@ -306,7 +470,7 @@ func mountSPA(r chi.Router, prefix, dir string) {
 	// Redirect no-slash -> slash (e.g., /exam -> /exam/)
 	noslash := strings.TrimSuffix(prefix, "/")
 	r.Get(noslash, func(w http.ResponseWriter, req *http.Request) {
-		http.Redirect(w, req, prefix, http.StatusPermanentRedirect)
+		http.Redirect(w, req, basepath.Join(req, prefix), http.StatusPermanentRedirect)
 	})
 
 	sub, _ := fs.Sub(staticFS, dir)
@@ -3,17 +3,25 @@ package main
 import (
 	"context"
 	"errors"
+	"flag"
 	"log"
 	"net/http"
+	"os"
 	"time"
 
 	"github.com/go-chi/chi/v5"
+	_ "github.com/lib/pq"  // registers "postgres"
+	_ "modernc.org/sqlite" // registers "sqlite"
 
+	"github.com/mind-engage/mindengage-lms/pkg/platform/admin"
 	"github.com/mind-engage/mindengage-lms/pkg/platform/config"
 	"github.com/mind-engage/mindengage-lms/pkg/platform/lti"
 	"github.com/mind-engage/mindengage-lms/pkg/platform/lti/ags"
 	"github.com/mind-engage/mindengage-lms/pkg/platform/lti/deeplinking"
+	mw "github.com/mind-engage/mindengage-lms/pkg/platform/lti/middleware"
 	"github.com/mind-engage/mindengage-lms/pkg/platform/lti/nrps"
+	"github.com/mind-engage/mindengage-lms/pkg/platform/storage"
+	"github.com/mind-engage/mindengage-lms/pkg/platform/tenants"
 )
 
 /* --------- tiny stubs so the server compiles; replace later --------- */
@@ -25,7 +33,7 @@ func (f issuerResolverFunc) IssuerForTenant(ctx context.Context, tenantID string
 	return f(ctx, tenantID)
 }
 
-// OAuth client registry (for /oauth/token). Replace with real impl.
+// OAuth client registry (for /oauth/token) when no DB is configured.
 type stubOAuthRegistry struct{}
 
 func (stubOAuthRegistry) GetOAuthClient(ctx context.Context, tenantID, clientID string) (lti.OAuthClient, error) {
@@ -33,14 +41,14 @@ func (stubOAuthRegistry) GetOAuthClient(ctx context.Context, tenantID, clientID
 	return lti.OAuthClient{
 		ClientID:      clientID,
 		SecretHash:    "$2a$14$replace-me-with-real-bcrypt-hash",
-		AllowedScopes: nil, // allow default set
+		AllowedScopes: nil, // falls back to TokenServer.DefaultScopes (deny by default)
 	}, nil
 }
 
 // NRPS storage stub
 type stubNRPSStore struct{}
 
-func (stubNRPSStore) ListMemberships(ctx context.Context, tenantID, contextID, roleFilter, pageToken string, limit int) ([]nrps.Membership, string, error) {
+func (stubNRPSStore) ListMemberships(ctx context.Context, tenantID, contextID, roleFilter, resourceLinkID, pageToken string, limit int) ([]nrps.Membership, string, error) {
 	return []nrps.Membership{}, "", nil
 }
 func (stubNRPSStore) GetContextMeta(ctx context.Context, tenantID, contextID string) (nrps.ContextMeta, error) {
@@ -72,30 +80,85 @@ func (stubDLStore) UpsertPlatformLineItem(ctx context.Context, tenantID, context
 /* --------------------------------------------------------------------- */
 
 func main() {
-	var cfg config.Config // TODO: load from env/file
-	// If cfg.Platform.Bind is not set in your config, fall back to :8080
-	if cfg.Platform.Bind == "" {
-		cfg.Platform.Bind = ":8080"
+	// JSON config file (optional); PLATFORM_* env vars override it.
+	cfgPath := flag.String("config", os.Getenv("PLATFORM_CONFIG"), "path to platformd JSON config")
+	flag.Parse()
+	cfg, err := config.Load(*cfgPath)
+	if err != nil {
+		log.Fatal(err)
 	}
 
+	tenantResolver := tenants.NewResolver(cfg.TenantOptions())
 	resolveTenantID := func(r *http.Request) (string, error) {
-		// TODO: derive tenant from host/path/header
-		return "default", nil
+		id, _, err := tenantResolver.Resolve(r)
+		return id, err
 	}
 
-	// Until you wire a real issuer per tenant, use a static base. Replace later.
-	issuerResolver := issuerResolverFunc(func(ctx context.Context, tenantID string) (string, error) {
-		// Example issuer; set this to your public base URL
-		return "http://localhost:8080", nil
+	// Without a DB, issuers follow the configured pattern; with one, they come
+	// from tenants.issuer (set below).
+	var issuerResolver lti.IssuerResolver = issuerResolverFunc(func(ctx context.Context, tenantID string) (string, error) {
+		return cfg.IssuerFor(tenantID), nil
 	})
 
 	// Key manager for JWKS + signing
 	keyManager := &lti.KeyManager{
-		Storage:          lti.NewInMemoryKeyStorage(), // TODO: replace in prod
+		Storage:          lti.NewInMemoryKeyStorage(), // dev only: keys die with the process
 		Alg:              "RS256",
 		RSAKeyBits:       2048,
-		RotationInterval: 90 * 24 * time.Hour,
-		Overlap:          7 * 24 * time.Hour,
+		RotationInterval: cfg.Keys.RotateEvery,
+		Overlap:          cfg.Keys.GracePeriod,
+	}
+
+	// Tool JWKS are fetched once and shared by every verification.
+	toolKeys := &lti.JWKSCache{TTL: 10 * time.Minute}
+
+	// AGS keeps line items and results in the DB; without one it has no store.
+	agsServer := ags.NewServer(nil)
+	agsServer.ResolveTenantID = resolveTenantID
+
+	// Deep Linking verifier needs the tools table; stay on the stub without a DB.
+	var dlVerifier deeplinking.Verifier = stubDLVerifier{}
+	// Tool self-test needs the tools table too; not mounted without a DB.
+	var toolRegistry lti.ToolRegistry
+	// NRPS reads contexts/enrollments, which the admin context API fills.
+	var nrpsStore nrps.Storage = stubNRPSStore{}
+	// Client secrets and scopes live in the tools table too.
+	var oauthRegistry lti.OAuthClientRegistry = stubOAuthRegistry{}
+	// Admin API: tools, deployments, contexts and enrollments.
+	var adminRoutes http.Handler
+	if cfg.DB.DSN != "" {
+		db, err := storage.Connect(context.Background(), cfg.DB.Driver, cfg.DB.DSN)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer db.Close()
+		if err := storage.Up(context.Background(), db, cfg.DB.Driver); err != nil {
+			log.Fatal(err)
+		}
+		toolRegistry = lti.SQLToolRegistry{DB: db.SQL}
+		sqlNRPS := &nrps.SQLStore{DB: db.SQL}
+		nrpsStore = sqlNRPS
+		oauthRegistry = lti.SQLOAuthRegistry{DB: db.SQL, Keys: toolKeys}
+		adminRoutes = admin.RoutesWithContexts(&admin.SQLStore{DB: db.SQL}, sqlNRPS)
+		dlVerifier = &deeplinking.JWTVerifier{
+			Tools: deeplinking.SQLToolJWKS{DB: db.SQL},
+			Keys:  toolKeys,
+		}
+
+		enc, err := lti.NewEncryptor(cfg.Keys.KeystoreURI, cfg.Keys.MasterKey)
+		if err != nil {
+			log.Fatal(err)
+		}
+		keyManager.Storage = &lti.SQLKeyStorage{DB: db.SQL, Enc: enc}
+		issuerResolver = &tenants.SQLIssuerResolver{DB: db.SQL}
+
+		agsStore := &ags.SQLStore{DB: db.SQL}
+		if cfg.AGS.RescaleOnMaxChange {
+			agsStore.OnMaxChange = ags.MaxChangeRescale
+		}
+		agsServer.Store = agsStore
+	} else {
+		log.Printf("platformd: no database configured; signing keys are in memory only")
 	}
 
 	r := chi.NewRouter()
@@ -111,7 +174,7 @@ func main() {
 	ts := &lti.TokenServer{
 		ResolveTenantID: resolveTenantID,
 		Issuers:         issuerResolver,
-		Registry:        stubOAuthRegistry{},
+		Registry:        oauthRegistry,
 		Signer:          keyManager,
 		AccessTokenTTL:  time.Hour,
 	}
@@ -125,23 +188,51 @@ func main() {
 	//   pkg/platform/lti/authorize.go
 	// and mount the returned http.Handlers here.
 
-	// AGS routes (fill Server deps if your ags.Server requires them)
-	agsServer := &ags.Server{}
-	r.Mount("/api/lti/ags", ags.Routes(agsServer))
+	// Service & admin calls carry access tokens from /oauth/token; verify them
+	// against the tenant's keys before any scope check.
+	bearer := mw.BearerAuthWithOptions(&lti.AccessTokenVerifier{
+		Keys:    keyManager,
+		Issuers: issuerResolver,
+	}, mw.AuthOptions{
+		EnforceTenantMatch: true,
+		ResolveTenantID:    resolveTenantID,
+	})
+
+	// AGS routes
+	r.With(bearer).Mount("/api/lti/ags", ags.Routes(agsServer))
 
 	// NRPS routes
 	nrpsServer := &nrps.Server{
-		Store:           stubNRPSStore{},
+		Store:           nrpsStore,
 		ResolveTenantID: resolveTenantID,
 	}
-	r.Mount("/api/lti/nrps", nrps.Routes(nrpsServer))
+	r.With(bearer, mw.RequireScopes(mw.ScopeNRPSContextMembershipRead)).
+		Mount("/api/lti/nrps", nrps.Routes(nrpsServer))
+
+	// Tool self-test: synthetic launch against a registered tool (admin only)
+	if toolRegistry != nil {
+		selfTest := &lti.SelfTestServer{
+			ResolveTenantID: resolveTenantID,
+			Issuers:         issuerResolver,
+			Registry:        toolRegistry,
+			Signer:          keyManager,
+		}
+		r.With(bearer, mw.RequireScopes(mw.ScopePlatformAdmin)).
+			Post("/admin/tools/{clientID}/self-test", selfTest.Handler())
+	}
+
+	// Tools, deployments, contexts & enrollments (admin only)
+	if adminRoutes != nil {
+		r.With(bearer, mw.RequireScopes(mw.ScopePlatformAdmin)).
+			Mount("/admin", adminRoutes)
+	}
 
 	// Deep Linking response
 	dl := &deeplinking.Server{
 		ResolveTenantID: resolveTenantID,
 		Issuers:         issuerResolver,
 		Tools:           stubDLRegistry{},
-		Verify:          stubDLVerifier{},
+		Verify:          dlVerifier,
 		Store:           stubDLStore{},
 	}
 	r.Handle("/lti/deep-linking/response", dl.ResponseHandler())
@@ -151,5 +242,8 @@ func main() {
 		Handler:           r,
 		ReadHeaderTimeout: 5 * time.Second,
 	}
+	if cfg.TLS.CertFile != "" {
+		log.Fatal(s.ListenAndServeTLS(cfg.TLS.CertFile, cfg.TLS.KeyFile))
+	}
 	log.Fatal(s.ListenAndServe())
 }
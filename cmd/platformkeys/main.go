@@ -0,0 +1,97 @@
+// Command platformkeys backs up and restores tenant signing keys.
+//
+//	platformkeys export -tenant t1 -out t1-keys.json
+//	platformkeys import -in t1-keys.json [-tenant t1]
+//
+// It reads the same config as platformd (-config / PLATFORM_CONFIG plus
+// PLATFORM_* env) to reach the database and the key encryptor. The bundle
+// passphrase comes from PLATFORM_BUNDLE_PASSPHRASE so it stays out of shell
+// history and process listings.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	_ "github.com/lib/pq"  // registers "postgres"
+	_ "modernc.org/sqlite" // registers "sqlite"
+
+	"github.com/mind-engage/mindengage-lms/pkg/platform/config"
+	"github.com/mind-engage/mindengage-lms/pkg/platform/lti"
+	"github.com/mind-engage/mindengage-lms/pkg/platform/storage"
+)
+
+func main() {
+	log.SetFlags(0)
+	if len(os.Args) < 2 || (os.Args[1] != "export" && os.Args[1] != "import") {
+		fmt.Fprintln(os.Stderr, "usage: platformkeys export|import [flags]")
+		os.Exit(2)
+	}
+	cmd := os.Args[1]
+
+	fs := flag.NewFlagSet(cmd, flag.ExitOnError)
+	cfgPath := fs.String("config", os.Getenv("PLATFORM_CONFIG"), "path to platformd JSON config")
+	tenant := fs.String("tenant", "", "tenant id (import: defaults to the bundle's tenant)")
+	out := fs.String("out", "", "export: bundle file to write (default stdout)")
+	in := fs.String("in", "", "import: bundle file to read")
+	_ = fs.Parse(os.Args[2:])
+
+	passphrase := os.Getenv("PLATFORM_BUNDLE_PASSPHRASE")
+	if passphrase == "" {
+		log.Fatal("PLATFORM_BUNDLE_PASSPHRASE is required")
+	}
+	cfg, err := config.Load(*cfgPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if cfg.DB.DSN == "" {
+		log.Fatal("a database (db.dsn / PLATFORM_DB_DSN) is required")
+	}
+
+	ctx := context.Background()
+	db, err := storage.Connect(ctx, cfg.DB.Driver, cfg.DB.DSN)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+	enc, err := lti.NewEncryptor(cfg.Keys.KeystoreURI, cfg.Keys.MasterKey)
+	if err != nil {
+		log.Fatal(err)
+	}
+	st := &lti.SQLKeyStorage{DB: db.SQL, Enc: enc}
+
+	switch cmd {
+	case "export":
+		if *tenant == "" {
+			log.Fatal("-tenant is required")
+		}
+		bundle, err := lti.ExportKeys(ctx, st, *tenant, passphrase)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if *out == "" {
+			_, err = os.Stdout.Write(append(bundle, '\n'))
+		} else {
+			err = os.WriteFile(*out, bundle, 0o600)
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+	case "import":
+		if *in == "" {
+			log.Fatal("-in is required")
+		}
+		bundle, err := os.ReadFile(*in)
+		if err != nil {
+			log.Fatal(err)
+		}
+		n, err := lti.ImportKeys(ctx, st, *tenant, bundle, passphrase)
+		if err != nil {
+			log.Fatal(err)
+		}
+		log.Printf("imported %d key(s)", n)
+	}
+}
@@ -0,0 +1,92 @@
+package http_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	api "github.com/mind-engage/mindengage-lms/internal/api/http"
+	authmw "github.com/mind-engage/mindengage-lms/internal/auth/middleware"
+	"github.com/mind-engage/mindengage-lms/internal/exam"
+	"github.com/mind-engage/mindengage-lms/internal/grading"
+	"github.com/mind-engage/mindengage-lms/internal/rbac"
+)
+
+// Resources the caller has not shown access to are 404, indistinguishable
+// from missing ones; 403 only once access is proven (or for role-wide denials).
+func TestAccessPolicy_NotFoundVsForbidden(t *testing.T) {
+	dbh := newTestDB(t)
+	authSvc := authmw.NewAuthService("test")
+	store := exam.NewSQLStore(dbh, "sqlite", grading.NewDefaultGrader())
+	if err := store.PutExam(exam.Exam{
+		ID:        "e1",
+		Title:     "Quiz",
+		Questions: []exam.Question{{ID: "q1", Type: "mcq_single", Points: 1, AnswerKey: []string{"a"}}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	future := time.Now().Add(time.Hour).Unix()
+	for _, q := range []string{
+		`INSERT INTO exam_offerings (id, exam_id, course_id, assigned_by, visibility, access_token) VALUES ('lnk','e1','c1','t1','link','secret')`,
+		fmt.Sprintf(`INSERT INTO exam_offerings (id, exam_id, course_id, assigned_by, visibility, access_token, start_at) VALUES ('soon','e1','c1','t1','link','later',%d)`, future),
+	} {
+		if _, err := dbh.Exec(q); err != nil {
+			t.Fatalf("%s: %v", q, err)
+		}
+	}
+	a, err := store.NewAttempt(context.Background(), "e1", "s1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := chi.NewRouter()
+	r.Post("/offerings/{offeringID}/grade_ephemeral", api.GradeEphemeralHandler(dbh, store, grading.NewDefaultGrader()))
+	r.Get("/offerings/{offeringID}/resolve", api.GetOfferingByTokenHandler(dbh, store))
+	r.Group(func(pr chi.Router) {
+		pr.Use(authmw.JWTMiddleware(authSvc))
+		pr.With(rbac.RequireOwnerOrNotFound("attempt:view-all", api.IsAttemptOwner(store))).
+			Get("/attempts/{attemptID}", api.GetAttemptHandler(store))
+		pr.With(rbac.Require("attempt:save"), api.RequireAttemptOwner(store)).
+			Post("/attempts/{attemptID}/responses", api.SaveResponsesHandler(store))
+	})
+	do := func(method, path, sub, role, body string) int {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if sub != "" {
+			req.Header.Set("Authorization", bearer(t, authSvc, sub, role))
+		}
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	grade := `{"responses":{"q1":"a"}}`
+	for _, c := range []struct {
+		name, method, path, sub, role, body string
+		want                                int
+	}{
+		{"link resolve, wrong token", http.MethodGet, "/offerings/lnk/resolve?access_token=nope", "", "", "", 404},
+		{"link resolve, missing offering", http.MethodGet, "/offerings/zzz/resolve?access_token=secret", "", "", "", 404},
+		{"link grade, wrong token", http.MethodPost, "/offerings/lnk/grade_ephemeral?access_token=nope", "", "", grade, 404},
+		{"link grade, missing offering", http.MethodPost, "/offerings/zzz/grade_ephemeral?access_token=secret", "", "", grade, 404},
+		{"link grade, not started", http.MethodPost, "/offerings/soon/grade_ephemeral?access_token=later", "", "", grade, 403},
+		{"link grade, ok", http.MethodPost, "/offerings/lnk/grade_ephemeral?access_token=secret", "", "", grade, 200},
+
+		{"attempt, owner", http.MethodGet, "/attempts/" + a.ID, "s1", "student", "", 200},
+		{"attempt, teacher", http.MethodGet, "/attempts/" + a.ID, "t1", "teacher", "", 200},
+		{"attempt, other student", http.MethodGet, "/attempts/" + a.ID, "s2", "student", "", 404},
+		{"attempt, missing", http.MethodGet, "/attempts/nope", "s2", "student", "", 404},
+		{"save, other student", http.MethodPost, "/attempts/" + a.ID + "/responses", "s2", "student", `{"q1":"b"}`, 404},
+		{"save, teacher lacks attempt:save", http.MethodPost, "/attempts/" + a.ID + "/responses", "t1", "teacher", `{"q1":"b"}`, 403},
+		{"save, owner", http.MethodPost, "/attempts/" + a.ID + "/responses", "s1", "student", `{"q1":"a"}`, 200},
+	} {
+		if got := do(c.method, c.path, c.sub, c.role, c.body); got != c.want {
+			t.Errorf("%s: status = %d, want %d", c.name, got, c.want)
+		}
+	}
+}
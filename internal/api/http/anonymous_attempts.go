@@ -0,0 +1,179 @@
+// internal/api/http/anonymous_attempts.go
+package http
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	authmw "github.com/mind-engage/mindengage-lms/internal/auth/middleware"
+	ex "github.com/mind-engage/mindengage-lms/internal/exam"
+	"github.com/mind-engage/mindengage-lms/internal/rbac"
+)
+
+/*
+Anonymous attempts for public/link offerings.
+
+	POST /api/offerings/{offeringID}/anonymous_attempts[?access_token=...]
+	     -> { attempt, anon_token }   (also sets the me_anon_token cookie)
+	GET  /api/anonymous/attempts/{attemptID}
+	POST /api/anonymous/attempts/{attemptID}/responses | /navigate | /submit
+	POST /api/attempts/claim   (authenticated) { "anon_token": "..." }
+
+The anonymous id ("anon-…") is stored as attempts.user_id; the anon token
+(X-Anon-Token header or cookie) proves ownership. Claiming rewrites user_id to
+the logged-in subject so the attempts show up in their history. Starting and
+claiming both respect the offering's max_attempts.
+*/
+
+// anonTokenFromRequest returns the raw anon token (header wins over cookie).
+func anonTokenFromRequest(r *http.Request) string {
+	if t := strings.TrimSpace(r.Header.Get("X-Anon-Token")); t != "" {
+		return t
+	}
+	if c, err := r.Cookie(authmw.AnonymousCookie); err == nil {
+		return c.Value
+	}
+	return ""
+}
+
+// CreateAnonymousAttemptHandler starts an attempt for an open public or link
+// offering without login. An existing valid anon token is reused so one
+// browser keeps a single anonymous identity.
+func CreateAnonymousAttemptHandler(db *sql.DB, store ex.Store, authSvc *authmw.AuthService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		offeringID := chi.URLParam(r, "offeringID")
+
+		var vis, dbTok, examID string
+		var start, end sql.NullInt64
+		var maxAttempts int
+		err := db.QueryRowContext(r.Context(), `
+			SELECT o.visibility, COALESCE(o.access_token,''), o.exam_id, o.start_at, o.end_at, o.max_attempts
+			  FROM exam_offerings o
+			  JOIN courses c ON c.id = o.course_id
+			 WHERE o.id = $1 AND c.status = 'active'`, offeringID).
+			Scan(&vis, &dbTok, &examID, &start, &end, &maxAttempts)
+		if err != nil {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		switch vis {
+		case "public":
+		case "link":
+			tok := strings.TrimSpace(r.URL.Query().Get("access_token"))
+			if !requireLinkToken(w, r, db, offeringID, dbTok, tok) {
+				return
+			}
+		default:
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		now := time.Now().UTC().Unix()
+		if start.Valid && now < start.Int64 {
+			http.Error(w, "not started", http.StatusForbidden)
+			return
+		}
+		if end.Valid && now > end.Int64 {
+			http.Error(w, "ended", http.StatusForbidden)
+			return
+		}
+
+		anonTok := anonTokenFromRequest(r)
+		anonID, err := authSvc.ParseAnonymousToken(anonTok)
+		if err != nil {
+			if anonID, anonTok, err = authSvc.IssueAnonymousToken(); err != nil {
+				http.Error(w, "token error", http.StatusInternalServerError)
+				return
+			}
+		}
+
+		// The in-progress attempt on this offering is resumed; a new one
+		// counts against the offering's max_attempts.
+		a, created, err := store.StartAttempt(r.Context(), ex.StartOpts{
+			ExamID:      examID,
+			UserID:      anonID,
+			Resume:      true,
+			OfferingID:  offeringID,
+			MaxAttempts: maxAttempts,
+		})
+		if err != nil {
+			writeAttemptError(w, r, err)
+			return
+		}
+
+		http.SetCookie(w, &http.Cookie{
+			Name:     authmw.AnonymousCookie,
+			Value:    anonTok,
+			Path:     "/api",
+			HttpOnly: true,
+			Secure:   r.TLS != nil,
+			SameSite: http.SameSiteLaxMode,
+			MaxAge:   30 * 24 * 3600,
+		})
+		status := http.StatusOK
+		if created {
+			status = http.StatusCreated
+		}
+		respondJSON(w, status, map[string]any{"attempt": a, "anon_token": anonTok})
+	}
+}
+
+// RequireAnonymousOwner admits the request only if the anon token's id owns
+// {attemptID}. Mount in front of the regular attempt handlers.
+func RequireAnonymousOwner(authSvc *authmw.AuthService, store ex.Store) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			anonID, err := authSvc.ParseAnonymousToken(anonTokenFromRequest(r))
+			if err != nil {
+				http.Error(w, "unauthorized", http.StatusUnauthorized)
+				return
+			}
+			a, err := store.GetAttempt(chi.URLParam(r, "attemptID"))
+			if err != nil || a.UserID != anonID {
+				http.Error(w, "not found", http.StatusNotFound)
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(rbac.WithSubject(r.Context(), anonID)))
+		})
+	}
+}
+
+// ClaimAnonymousAttemptsHandler moves the caller's anonymous attempts onto
+// their account. The anon token comes from the body or the cookie.
+func ClaimAnonymousAttemptsHandler(store ex.Store, authSvc *authmw.AuthService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		sub := rbac.SubjectFromContext(r.Context())
+		if sub == "" {
+			sub, _ = subjectFromBearer(authSvc, r)
+		}
+		if sub == "" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		var req struct {
+			AnonToken string `json:"anon_token"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req) // body is optional (cookie fallback)
+		tok := req.AnonToken
+		if tok == "" {
+			tok = anonTokenFromRequest(r)
+		}
+		anonID, err := authSvc.ParseAnonymousToken(tok)
+		if err != nil {
+			http.Error(w, "bad anonymous token", http.StatusBadRequest)
+			return
+		}
+
+		n, err := store.ClaimAttempts(r.Context(), anonID, sub)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		// The anonymous identity is spent; drop the cookie.
+		http.SetCookie(w, &http.Cookie{Name: authmw.AnonymousCookie, Value: "", Path: "/api", MaxAge: -1})
+		respondJSON(w, http.StatusOK, map[string]any{"claimed": n})
+	}
+}
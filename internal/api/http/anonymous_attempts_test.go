@@ -0,0 +1,223 @@
+package http_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+
+	api "github.com/mind-engage/mindengage-lms/internal/api/http"
+	authmw "github.com/mind-engage/mindengage-lms/internal/auth/middleware"
+	"github.com/mind-engage/mindengage-lms/internal/exam"
+	"github.com/mind-engage/mindengage-lms/internal/grading"
+)
+
+func TestAnonymousAttempt_SubmitAndClaim(t *testing.T) {
+	dbh := newTestDB(t)
+	store := exam.NewSQLStore(dbh, "sqlite", grading.NewDefaultGrader())
+	authSvc := authmw.NewAuthService("test-secret")
+	if err := store.PutExam(exam.Exam{
+		ID:        "e1",
+		Title:     "Public quiz",
+		Questions: []exam.Question{{ID: "q1", Type: "mcq_single", Points: 1, AnswerKey: []string{"a"}}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	for _, q := range []string{
+		`INSERT INTO exam_offerings (id, exam_id, course_id, assigned_by, visibility) VALUES ('pub','e1','c1','t1','public')`,
+		`INSERT INTO exam_offerings (id, exam_id, course_id, assigned_by, visibility, access_token) VALUES ('lnk','e1','c1','t1','link','secret')`,
+		`INSERT INTO exam_offerings (id, exam_id, course_id, assigned_by, visibility) VALUES ('priv','e1','c1','t1','course')`,
+	} {
+		if _, err := dbh.Exec(q); err != nil {
+			t.Fatalf("%s: %v", q, err)
+		}
+	}
+
+	r := chi.NewRouter()
+	r.Post("/offerings/{offeringID}/anonymous_attempts", api.CreateAnonymousAttemptHandler(dbh, store, authSvc))
+	r.Route("/anonymous/attempts/{attemptID}", func(ar chi.Router) {
+		ar.Use(api.RequireAnonymousOwner(authSvc, store))
+		ar.Get("/", api.GetAttemptHandler(store))
+		ar.Post("/responses", api.SaveResponsesHandler(store))
+		ar.Post("/submit", api.SubmitAttemptHandler(store))
+	})
+	r.With(authmw.JWTMiddleware(authSvc)).Post("/attempts/claim", api.ClaimAnonymousAttemptsHandler(store, authSvc))
+
+	do := func(method, path, body string, hdr map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		for k, v := range hdr {
+			req.Header.Set(k, v)
+		}
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec
+	}
+
+	for path, want := range map[string]int{
+		"/offerings/priv/anonymous_attempts":                    http.StatusNotFound,
+		"/offerings/lnk/anonymous_attempts":                     http.StatusNotFound,
+		"/offerings/lnk/anonymous_attempts?access_token=nope":   http.StatusNotFound,
+		"/offerings/lnk/anonymous_attempts?access_token=secret": http.StatusCreated,
+	} {
+		if rec := do(http.MethodPost, path, "", nil); rec.Code != want {
+			t.Fatalf("%s: status = %d, want %d", path, rec.Code, want)
+		}
+	}
+
+	rec := do(http.MethodPost, "/offerings/pub/anonymous_attempts", "", nil)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create status = %d body=%s", rec.Code, rec.Body.String())
+	}
+	var created struct {
+		Attempt   exam.Attempt `json:"attempt"`
+		AnonToken string       `json:"anon_token"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&created); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(created.Attempt.UserID, authmw.AnonymousPrefix) || created.AnonToken == "" {
+		t.Fatalf("got %+v", created)
+	}
+	attemptPath := "/anonymous/attempts/" + created.Attempt.ID
+	anonHdr := map[string]string{"X-Anon-Token": created.AnonToken}
+
+	if rec := do(http.MethodGet, attemptPath+"/", "", nil); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("no token status = %d, want 401", rec.Code)
+	}
+	_, otherTok, _ := authSvc.IssueAnonymousToken()
+	if rec := do(http.MethodGet, attemptPath+"/", "", map[string]string{"X-Anon-Token": otherTok}); rec.Code != http.StatusNotFound {
+		t.Fatalf("foreign token status = %d, want 404", rec.Code)
+	}
+
+	if rec := do(http.MethodPost, attemptPath+"/responses", `{"q1":"a"}`, anonHdr); rec.Code != http.StatusOK {
+		t.Fatalf("save status = %d body=%s", rec.Code, rec.Body.String())
+	}
+	rec = do(http.MethodPost, attemptPath+"/submit", "", anonHdr)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("submit status = %d body=%s", rec.Code, rec.Body.String())
+	}
+	var submitted exam.Attempt
+	if err := json.NewDecoder(rec.Body).Decode(&submitted); err != nil {
+		t.Fatal(err)
+	}
+	if submitted.Status != "submitted" || submitted.Score != 1 {
+		t.Fatalf("submitted = %+v", submitted)
+	}
+
+	// Logging in later moves the attempt onto the real account.
+	rec = do(http.MethodPost, "/attempts/claim", `{"anon_token":"`+created.AnonToken+`"}`,
+		map[string]string{"Authorization": bearer(t, authSvc, "s1", "student")})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("claim status = %d body=%s", rec.Code, rec.Body.String())
+	}
+	var claimed struct {
+		Claimed int64 `json:"claimed"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&claimed); err != nil {
+		t.Fatal(err)
+	}
+	if claimed.Claimed != 1 {
+		t.Fatalf("claimed = %d, want 1", claimed.Claimed)
+	}
+	a, err := store.GetAttempt(created.Attempt.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.UserID != "s1" || a.Score != 1 {
+		t.Fatalf("after claim = %+v", a)
+	}
+	if rec := do(http.MethodGet, attemptPath+"/", "", anonHdr); rec.Code != http.StatusNotFound {
+		t.Fatalf("anon access after claim status = %d, want 404", rec.Code)
+	}
+
+	if rec := do(http.MethodPost, "/attempts/claim", `{"anon_token":"anon-x.bogus"}`,
+		map[string]string{"Authorization": bearer(t, authSvc, "s1", "student")}); rec.Code != http.StatusBadRequest {
+		t.Fatalf("bad token claim status = %d, want 400", rec.Code)
+	}
+}
+
+func TestAnonymousAttempt_MaxAttempts(t *testing.T) {
+	dbh := newTestDB(t)
+	store := exam.NewSQLStore(dbh, "sqlite", grading.NewDefaultGrader())
+	authSvc := authmw.NewAuthService("test-secret")
+	if err := store.PutExam(exam.Exam{
+		ID:        "e1",
+		Title:     "Public quiz",
+		Questions: []exam.Question{{ID: "q1", Type: "mcq_single", Points: 1, AnswerKey: []string{"a"}}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := dbh.Exec(`INSERT INTO exam_offerings (id, exam_id, course_id, assigned_by, visibility, max_attempts)
+		VALUES ('pub','e1','c1','t1','public',1)`); err != nil {
+		t.Fatal(err)
+	}
+
+	r := chi.NewRouter()
+	r.Post("/offerings/{offeringID}/anonymous_attempts", api.CreateAnonymousAttemptHandler(dbh, store, authSvc))
+	r.With(authmw.JWTMiddleware(authSvc)).Post("/attempts/claim", api.ClaimAnonymousAttemptsHandler(store, authSvc))
+	start := func(tok string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/offerings/pub/anonymous_attempts", nil)
+		if tok != "" {
+			req.Header.Set("X-Anon-Token", tok)
+		}
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec
+	}
+	var first struct {
+		Attempt   exam.Attempt `json:"attempt"`
+		AnonToken string       `json:"anon_token"`
+	}
+	rec := start("")
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create status = %d body=%s", rec.Code, rec.Body.String())
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&first); err != nil {
+		t.Fatal(err)
+	}
+
+	// Starting again resumes the open attempt instead of using up another.
+	rec = start(first.AnonToken)
+	var again struct {
+		Attempt exam.Attempt `json:"attempt"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&again); err != nil {
+		t.Fatal(err)
+	}
+	if rec.Code != http.StatusOK || again.Attempt.ID != first.Attempt.ID {
+		t.Fatalf("resume: status %d attempt %q, want 200 %q", rec.Code, again.Attempt.ID, first.Attempt.ID)
+	}
+	if _, err := store.Submit(context.Background(), first.Attempt.ID); err != nil {
+		t.Fatal(err)
+	}
+	if rec := start(first.AnonToken); rec.Code != http.StatusConflict || rec.Header().Get("X-Error-Code") != "no_attempts_left" {
+		t.Fatalf("over limit: status = %d body=%s", rec.Code, rec.Body.String())
+	}
+
+	// A user who already used the offering's attempt cannot claim another.
+	if _, _, err := store.StartAttempt(context.Background(), exam.StartOpts{
+		ExamID: "e1", UserID: "s1", OfferingID: "pub", MaxAttempts: 1,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest(http.MethodPost, "/attempts/claim", strings.NewReader(`{"anon_token":"`+first.AnonToken+`"}`))
+	req.Header.Set("Authorization", bearer(t, authSvc, "s1", "student"))
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	var claimed struct {
+		Claimed int64 `json:"claimed"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&claimed); err != nil {
+		t.Fatal(err)
+	}
+	if rec.Code != http.StatusOK || claimed.Claimed != 0 {
+		t.Fatalf("claim: status %d claimed %d, want 200 0", rec.Code, claimed.Claimed)
+	}
+	if a, err := store.GetAttempt(first.Attempt.ID); err != nil || a.UserID != first.Attempt.UserID {
+		t.Fatalf("attempt after claim = %+v, %v; want it left anonymous", a, err)
+	}
+}
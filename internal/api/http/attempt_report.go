@@ -0,0 +1,206 @@
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/mind-engage/mindengage-lms/internal/exam"
+)
+
+// AttemptReport is everything an attempt report shows: the attempt, the
+// full exam (with answer keys) and one entry per question.
+type AttemptReport struct {
+	Attempt     exam.Attempt
+	Exam        exam.Exam
+	Items       []ReportItem
+	GeneratedAt time.Time
+}
+
+// ReportItem is one question of the report. Item holds the graded points
+// and teacher comment; Graded is false if the attempt has no row for it.
+type ReportItem struct {
+	Number   int
+	Question exam.Question
+	Response string // display form of the taker's response; "" if unanswered
+	Item     exam.AttemptItem
+	Graded   bool
+}
+
+// ReportRenderer writes an attempt report in one format. HTML is built in;
+// other formats (e.g. PDF) are added with RegisterReportRenderer.
+type ReportRenderer interface {
+	ContentType() string
+	Render(w io.Writer, rep AttemptReport) error
+}
+
+var reportRenderers = struct {
+	sync.RWMutex
+	m map[string]ReportRenderer
+}{m: map[string]ReportRenderer{"html": htmlReport{}}}
+
+// RegisterReportRenderer makes format available as
+// GET /attempts/{id}/report?format=<format>, replacing any earlier renderer.
+func RegisterReportRenderer(format string, r ReportRenderer) {
+	format = strings.ToLower(strings.TrimSpace(format))
+	if format == "" || r == nil {
+		return
+	}
+	reportRenderers.Lock()
+	defer reportRenderers.Unlock()
+	reportRenderers.m[format] = r
+}
+
+// GET /attempts/{attemptID}/report?format=html
+// Staff only (attempt:view-all): the report includes the answer keys.
+func AttemptReportHandler(store exam.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		format := strings.ToLower(r.URL.Query().Get("format"))
+		if format == "" {
+			format = "html"
+		}
+		reportRenderers.RLock()
+		renderer := reportRenderers.m[format]
+		reportRenderers.RUnlock()
+		if renderer == nil {
+			http.Error(w, "unsupported format: "+format, http.StatusBadRequest)
+			return
+		}
+
+		id := chi.URLParam(r, "attemptID")
+		a, err := store.GetAttempt(id)
+		if err != nil {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		// Items first: reading them grades a deferred attempt, which the
+		// attempt and its score must then reflect.
+		items, err := store.GetAttemptItems(r.Context(), id)
+		if err != nil {
+			http.Error(w, "grading items: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if a, err = store.GetAttempt(id); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		ex, err := store.GetAttemptExam(r.Context(), id)
+		if err != nil {
+			http.Error(w, "exam: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		rep := buildAttemptReport(a, ex, items)
+		rep.GeneratedAt = time.Now().UTC()
+		w.Header().Set("Content-Type", renderer.ContentType())
+		if err := renderer.Render(w, rep); err != nil {
+			http.Error(w, "render: "+err.Error(), http.StatusInternalServerError)
+		}
+	}
+}
+
+func buildAttemptReport(a exam.Attempt, ex exam.Exam, items []exam.AttemptItem) AttemptReport {
+	byQID := make(map[string]exam.AttemptItem, len(items))
+	for _, it := range items {
+		byQID[it.QuestionID] = it
+	}
+	rep := AttemptReport{Attempt: a, Exam: ex}
+	for i, q := range ex.Questions {
+		it, graded := byQID[q.ID]
+		rep.Items = append(rep.Items, ReportItem{
+			Number:   i + 1,
+			Question: q,
+			Response: responseText(a.Responses[q.ID]),
+			Item:     it,
+			Graded:   graded,
+		})
+	}
+	return rep
+}
+
+// responseText renders a stored response for reading: strings as is, lists
+// comma-separated, anything else as JSON.
+func responseText(v interface{}) string {
+	switch r := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return r
+	case []interface{}:
+		parts := make([]string, 0, len(r))
+		for _, p := range r {
+			parts = append(parts, responseText(p))
+		}
+		return strings.Join(parts, ", ")
+	case float64, bool:
+		return fmt.Sprint(r)
+	}
+	b, _ := json.Marshal(v)
+	return string(b)
+}
+
+type htmlReport struct{}
+
+func (htmlReport) ContentType() string { return "text/html; charset=utf-8" }
+
+func (htmlReport) Render(w io.Writer, rep AttemptReport) error {
+	return attemptReportTmpl.Execute(w, rep)
+}
+
+// Prompts and choice labels are exam HTML, authored by staff and rendered
+// unescaped just as the taking UI does; everything else is escaped.
+var attemptReportTmpl = template.Must(template.New("report").Funcs(template.FuncMap{
+	"trusted": func(s string) template.HTML { return template.HTML(s) },
+	"unix": func(sec int64) string {
+		if sec == 0 {
+			return "-"
+		}
+		return time.Unix(sec, 0).UTC().Format("2006-01-02 15:04 UTC")
+	},
+	"join": strings.Join,
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.Exam.Title}} - attempt {{.Attempt.ID}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table.meta td { padding: 0 1em 0 0; }
+.question { border-top: 1px solid #ccc; padding: 1em 0; page-break-inside: avoid; }
+.label { color: #555; }
+.comment { font-style: italic; }
+</style>
+</head>
+<body>
+<h1>{{.Exam.Title}}</h1>
+<table class="meta">
+<tr><td class="label">Attempt</td><td>{{.Attempt.ID}}</td></tr>
+<tr><td class="label">Student</td><td>{{.Attempt.UserID}}</td></tr>
+<tr><td class="label">Status</td><td>{{.Attempt.Status}}</td></tr>
+<tr><td class="label">Started</td><td>{{unix .Attempt.StartedAt}}</td></tr>
+<tr><td class="label">Submitted</td><td>{{unix .Attempt.SubmittedAt}}</td></tr>
+<tr><td class="label">Score</td><td>{{.Attempt.Score}}</td></tr>
+</table>
+{{range .Items}}
+<div class="question" id="q-{{.Question.ID}}">
+<h2>Question {{.Number}} <span class="label">({{.Question.ID}}, {{.Question.Points}} pts)</span></h2>
+<div class="prompt">{{trusted .Question.PromptHTML}}</div>
+{{if .Question.Choices}}<ul class="choices">{{range .Question.Choices}}
+<li>{{.ID}}. {{trusted .LabelHTML}}</li>{{end}}
+</ul>{{end}}
+<p><span class="label">Response:</span> {{if .Response}}<span class="response">{{.Response}}</span>{{else}}<em>unanswered</em>{{end}}</p>
+{{if .Question.AnswerKey}}<p><span class="label">Answer key:</span> {{join .Question.AnswerKey ", "}}</p>{{end}}
+{{if .Graded}}<p><span class="label">Points:</span> {{.Item.AutoPoints}} auto + {{.Item.ManualPoints}} manual of {{.Item.PointsMax}}{{if .Item.NeedsManual}} (needs manual grading){{end}}</p>
+{{if .Item.Comment}}<p class="comment"><span class="label">Feedback:</span> {{.Item.Comment}}</p>{{end}}{{else}}<p class="label">Not graded yet.</p>{{end}}
+</div>
+{{end}}
+<footer class="label">Generated {{.GeneratedAt.Format "2006-01-02 15:04 UTC"}}</footer>
+</body>
+</html>
+`))
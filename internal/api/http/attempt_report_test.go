@@ -0,0 +1,96 @@
+package http_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+
+	api "github.com/mind-engage/mindengage-lms/internal/api/http"
+	authmw "github.com/mind-engage/mindengage-lms/internal/auth/middleware"
+	"github.com/mind-engage/mindengage-lms/internal/exam"
+	"github.com/mind-engage/mindengage-lms/internal/grading"
+	"github.com/mind-engage/mindengage-lms/internal/rbac"
+)
+
+func TestAttemptReport_HTML(t *testing.T) {
+	ctx := context.Background()
+	dbh := newTestDB(t)
+	authSvc := authmw.NewAuthService("test")
+	store := exam.NewSQLStore(dbh, "sqlite", grading.NewDefaultGrader())
+	if err := store.PutExam(exam.Exam{
+		ID:    "e1",
+		Title: "Midterm",
+		Questions: []exam.Question{
+			{ID: "q1", Type: "mcq_single", PromptHTML: "<p>Pick the prime</p>", Points: 1, AnswerKey: []string{"b"},
+				Choices: []exam.Choice{{ID: "a", LabelHTML: "4"}, {ID: "b", LabelHTML: "5"}}},
+			{ID: "q2", Type: "short_word", PromptHTML: "<p>Capital of France</p>", Points: 2, AnswerKey: []string{"paris"}},
+			{ID: "q3", Type: "essay", PromptHTML: "<p>Explain recursion</p>", Points: 5},
+		},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	a, err := store.NewAttempt(ctx, "e1", "s1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.SaveResponses(ctx, a.ID, map[string]interface{}{
+		"q1": "b", "q2": "paris", "q3": "<script>alert(1)</script> calls itself",
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Submit(ctx, a.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.ApplyManualGrades(ctx, a.ID, map[string]exam.ManualGradeInput{
+		"q3": {ManualPoints: 4, Comment: "Good base case"},
+	}, "t1", false); err != nil {
+		t.Fatal(err)
+	}
+
+	r := chi.NewRouter()
+	r.Group(func(pr chi.Router) {
+		pr.Use(authmw.JWTMiddleware(authSvc))
+		pr.With(rbac.Require("attempt:view-all")).
+			Get("/attempts/{attemptID}/report", api.AttemptReportHandler(store))
+	})
+	get := func(path, sub, role string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Authorization", bearer(t, authSvc, sub, role))
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := get("/attempts/"+a.ID+"/report", "s1", "student"); rec.Code != http.StatusForbidden {
+		t.Fatalf("student: status = %d, want 403", rec.Code)
+	}
+	if rec := get("/attempts/"+a.ID+"/report?format=docx", "t1", "teacher"); rec.Code != http.StatusBadRequest {
+		t.Fatalf("unknown format: status = %d, want 400", rec.Code)
+	}
+	if rec := get("/attempts/nope/report", "t1", "teacher"); rec.Code != http.StatusNotFound {
+		t.Fatalf("missing attempt: status = %d, want 404", rec.Code)
+	}
+
+	rec := get("/attempts/"+a.ID+"/report?format=html", "t1", "teacher")
+	if rec.Code != http.StatusOK || !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/html") {
+		t.Fatalf("status = %d, content type %q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	body := rec.Body.String()
+	for _, want := range []string{
+		"<p>Pick the prime</p>", "<p>Capital of France</p>", "<p>Explain recursion</p>",
+		`<span class="response">b</span>`, `<span class="response">paris</span>`,
+		"&lt;script&gt;alert(1)&lt;/script&gt; calls itself",
+		"Good base case",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("report lacks %q", want)
+		}
+	}
+	if strings.Contains(body, "<script>") {
+		t.Error("report contains the response's script tag unescaped")
+	}
+}
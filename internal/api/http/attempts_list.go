@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/mind-engage/mindengage-lms/internal/exam"
 	"github.com/mind-engage/mindengage-lms/internal/rbac"
@@ -50,6 +51,35 @@ func ListAttemptsHandler(store exam.Store) http.HandlerFunc {
 			return
 		}
 		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(displayAttempts(r.Context(), list))
+	}
+}
+
+// GET /attempts/stale?exam_id=&stale_after=90 (seconds): in-progress attempts
+// whose browser has not sent a heartbeat for stale_after, for proctors.
+// Teachers only see attempts in offerings of their own courses.
+func ListStaleAttemptsHandler(store exam.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		examID := strings.TrimSpace(r.URL.Query().Get("exam_id"))
+		after := parseIntDefault(r.URL.Query().Get("stale_after"), 90)
+		if after <= 0 {
+			http.Error(w, "stale_after must be positive", http.StatusBadRequest)
+			return
+		}
+		teacherID := ""
+		if rbac.RoleFromContext(r.Context()) != "admin" {
+			teacherID = rbac.SubjectFromContext(r.Context())
+			if teacherID == "" {
+				http.Error(w, "forbidden", http.StatusForbidden)
+				return
+			}
+		}
+		list, err := store.ListStaleAttempts(r.Context(), examID, teacherID, time.Duration(after)*time.Second)
+		if err != nil {
+			http.Error(w, err.Error(), 500)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
 		_ = json.NewEncoder(w).Encode(list)
 	}
 }
@@ -1,9 +1,13 @@
 package http
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log/slog"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -12,6 +16,8 @@ import (
 
 	"github.com/go-chi/chi/v5"
 	authmw "github.com/mind-engage/mindengage-lms/internal/auth/middleware"
+	"github.com/mind-engage/mindengage-lms/internal/db"
+	"github.com/mind-engage/mindengage-lms/internal/exam"
 )
 
 // Handlers only — routes remain in main.go
@@ -19,8 +25,18 @@ import (
 type Course struct {
 	ID   string `json:"id"`
 	Name string `json:"name"`
+
+	// Only with ?with_counts=1 (ListCoursesHandler).
+	StudentCount  *int `json:"student_count,omitempty"`
+	OfferingCount *int `json:"offering_count,omitempty"`
 }
 
+// courseCountCols is spliced into the list queries for ?with_counts=1.
+// Students counts only active enrollments, matching what students can see.
+const courseCountCols = `
+	(SELECT COUNT(*) FROM course_students cs WHERE cs.course_id=c.id AND cs.status='active'),
+	(SELECT COUNT(*) FROM exam_offerings o WHERE o.course_id=c.id)`
+
 func CreateCourseHandler(dbh *sql.DB, authSvc *authmw.AuthService) nethttp.HandlerFunc {
 	return func(w nethttp.ResponseWriter, r *nethttp.Request) {
 		sub, role := subjectFromBearer(authSvc, r)
@@ -35,8 +51,8 @@ func CreateCourseHandler(dbh *sql.DB, authSvc *authmw.AuthService) nethttp.Handl
 		var req struct {
 			Name string `json:"name"`
 		}
-		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || strings.TrimSpace(req.Name) == "" {
-			nethttp.Error(w, "bad json", nethttp.StatusBadRequest)
+		if err := decodeBody(r, &req); err != nil || strings.TrimSpace(req.Name) == "" {
+			badJSON(w, err)
 			return
 		}
 		courseID := "c-" + strconv.FormatInt(time.Now().UnixNano(), 10)
@@ -63,6 +79,12 @@ func ListCoursesHandler(db *sql.DB, authSvc *authmw.AuthService) nethttp.Handler
 		teacherID := strings.TrimSpace(r.URL.Query().Get("teacher_id"))
 		studentID := strings.TrimSpace(r.URL.Query().Get("student_id"))
 		all := r.URL.Query().Get("all") == "1"
+		// Archived courses are hidden unless asked for explicitly.
+		courseStatus := "active"
+		if r.URL.Query().Get("archived") == "1" {
+			courseStatus = "archived"
+		}
+		withCounts := r.URL.Query().Get("with_counts") == "1"
 
 		limit := 50
 		offset := 0
@@ -83,6 +105,7 @@ func ListCoursesHandler(db *sql.DB, authSvc *authmw.AuthService) nethttp.Handler
 
 		addNameFilter := func(base string, argStart int) (string, []any) {
 			// argStart = next placeholder index (1-based)
+			base += " AND c.status='" + courseStatus + "' "
 			if q != "" {
 				base += fmt.Sprintf(" AND c.name ILIKE '%%' || $%d || '%%' ", argStart)
 				return base, []any{q}
@@ -171,6 +194,10 @@ func ListCoursesHandler(db *sql.DB, authSvc *authmw.AuthService) nethttp.Handler
 			sqlStr += ` ORDER BY c.created_at DESC LIMIT $` + strconv.Itoa(len(args)-1) + ` OFFSET $` + strconv.Itoa(len(args))
 		}
 
+		if withCounts {
+			sqlStr = strings.Replace(sqlStr, "SELECT c.id, c.name", "SELECT c.id, c.name,"+courseCountCols, 1)
+		}
+
 		rows, err := db.QueryContext(r.Context(), sqlStr, args...)
 		if err != nil {
 			nethttp.Error(w, "db error", nethttp.StatusInternalServerError)
@@ -181,7 +208,12 @@ func ListCoursesHandler(db *sql.DB, authSvc *authmw.AuthService) nethttp.Handler
 		out := []Course{}
 		for rows.Next() {
 			var c Course
-			if err := rows.Scan(&c.ID, &c.Name); err == nil {
+			dest := []any{&c.ID, &c.Name}
+			if withCounts {
+				c.StudentCount, c.OfferingCount = new(int), new(int)
+				dest = append(dest, c.StudentCount, c.OfferingCount)
+			}
+			if err := rows.Scan(dest...); err == nil {
 				out = append(out, c)
 			}
 		}
@@ -214,15 +246,15 @@ func AddCoTeachersHandler(dbh *sql.DB, authSvc *authmw.AuthService) nethttp.Hand
 		if req.Role == "owner" {
 			role = "owner"
 		}
-		for _, uid := range req.UserIDs {
-			uid = strings.TrimSpace(uid)
-			if uid == "" {
-				continue
-			}
-			_, _ = dbh.Exec(`INSERT INTO course_teachers (course_id, teacher_id, role) VALUES ($1, $2, $3)
-                       ON CONFLICT (course_id, teacher_id) DO UPDATE SET role=EXCLUDED.role`, courseID, uid, role)
+		sum, err := applyCourseMembers(r.Context(), dbh, req.UserIDs, []string{"teacher", "admin"},
+			`INSERT INTO course_teachers (course_id, teacher_id, role) VALUES ($1, $2, $3)
+			 ON CONFLICT (course_id, teacher_id) DO UPDATE SET role=EXCLUDED.role`,
+			func(uid string) []any { return []any{courseID, uid, role} })
+		if err != nil {
+			writeMembersError(w, r, err)
+			return
 		}
-		w.WriteHeader(nethttp.StatusNoContent)
+		respondJSON(w, nethttp.StatusOK, sum)
 	}
 }
 
@@ -250,18 +282,227 @@ func EnrollStudentsHandler(dbh *sql.DB, authSvc *authmw.AuthService) nethttp.Han
 		if s := strings.ToLower(strings.TrimSpace(req.Status)); s == "invited" || s == "dropped" {
 			status = s
 		}
-		for _, uid := range req.UserIDs {
-			uid = strings.TrimSpace(uid)
-			if uid == "" {
-				continue
+		sum, err := applyCourseMembers(r.Context(), dbh, req.UserIDs, nil,
+			`INSERT INTO course_students (course_id, student_id, status) VALUES ($1, $2, $3)
+			 ON CONFLICT (course_id, student_id) DO UPDATE SET status=EXCLUDED.status`,
+			func(uid string) []any { return []any{courseID, uid, status} })
+		if err != nil {
+			writeMembersError(w, r, err)
+			return
+		}
+		respondJSON(w, nethttp.StatusOK, sum)
+	}
+}
+
+// RemoveCoTeacherHandler removes a teacher from a course. Only owners (or
+// admins) may remove, and the course's last owner can never be removed —
+// including by themselves — so a course is never left unmanageable.
+func RemoveCoTeacherHandler(dbh *sql.DB, authSvc *authmw.AuthService) nethttp.HandlerFunc {
+	return func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		courseID := chi.URLParam(r, "courseID")
+		teacherID := strings.TrimSpace(chi.URLParam(r, "teacherID"))
+		sub, role := subjectFromBearer(authSvc, r)
+		if sub == "" {
+			nethttp.Error(w, "unauthorized", nethttp.StatusUnauthorized)
+			return
+		}
+		if role != "admin" && !isCourseOwner(dbh, sub, courseID) {
+			nethttp.Error(w, "forbidden (not course owner)", nethttp.StatusForbidden)
+			return
+		}
+
+		if err := removeCoTeacher(r.Context(), dbh, courseID, teacherID); err != nil {
+			switch {
+			case errors.Is(err, errLastOwner):
+				nethttp.Error(w, "cannot remove the last owner", nethttp.StatusConflict)
+			case errors.Is(err, sql.ErrNoRows):
+				nethttp.Error(w, "not a teacher of this course", nethttp.StatusNotFound)
+			default:
+				nethttp.Error(w, "db error", nethttp.StatusInternalServerError)
 			}
-			_, _ = dbh.Exec(`INSERT INTO course_students (course_id, student_id, status) VALUES ($1, $2, $3)
-                       ON CONFLICT (course_id, student_id) DO UPDATE SET status=EXCLUDED.status`, courseID, uid, status)
+			return
 		}
 		w.WriteHeader(nethttp.StatusNoContent)
 	}
 }
 
+var errLastOwner = errors.New("last course owner")
+
+// removeCoTeacher deletes teacherID from the course with the course's owner
+// rows locked, so two owners removing each other concurrently can't both
+// see "another owner remains".
+func removeCoTeacher(ctx context.Context, dbh *sql.DB, courseID, teacherID string) error {
+	tx, err := dbh.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// SELECT ... FOR UPDATE on postgres; on sqlite a no-op write takes the
+	// database write lock.
+	lock := `UPDATE course_teachers SET role=role WHERE course_id=$1 AND role='owner'`
+	if db.DriverOf(dbh) == db.DriverPostgres {
+		lock = `SELECT teacher_id FROM course_teachers WHERE course_id=$1 AND role='owner' FOR UPDATE`
+	}
+	if _, err := tx.ExecContext(ctx, lock, courseID); err != nil {
+		return err
+	}
+
+	var role string
+	if err := tx.QueryRowContext(ctx,
+		`SELECT role FROM course_teachers WHERE course_id=$1 AND teacher_id=$2`,
+		courseID, teacherID).Scan(&role); err != nil {
+		return err
+	}
+	if role == "owner" {
+		var owners int
+		if err := tx.QueryRowContext(ctx,
+			`SELECT COUNT(*) FROM course_teachers WHERE course_id=$1 AND role='owner'`,
+			courseID).Scan(&owners); err != nil {
+			return err
+		}
+		if owners <= 1 {
+			return errLastOwner
+		}
+	}
+	if _, err := tx.ExecContext(ctx,
+		`DELETE FROM course_teachers WHERE course_id=$1 AND teacher_id=$2`,
+		courseID, teacherID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// UnenrollStudentHandler drops a student from a course. By default the
+// enrollment is kept with status='dropped' (the student loses access to the
+// course's offerings; attempts are untouched). ?hard=1 deletes the row.
+func UnenrollStudentHandler(dbh *sql.DB, authSvc *authmw.AuthService) nethttp.HandlerFunc {
+	return func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		courseID := chi.URLParam(r, "courseID")
+		studentID := strings.TrimSpace(chi.URLParam(r, "studentID"))
+		sub, role := subjectFromBearer(authSvc, r)
+		if sub == "" {
+			nethttp.Error(w, "unauthorized", nethttp.StatusUnauthorized)
+			return
+		}
+		if role != "admin" && !isCourseTeacher(dbh, sub, courseID) {
+			nethttp.Error(w, "forbidden", nethttp.StatusForbidden)
+			return
+		}
+
+		hard := r.URL.Query().Get("hard") == "1"
+		var res sql.Result
+		var err error
+		if hard {
+			res, err = dbh.ExecContext(r.Context(),
+				`DELETE FROM course_students WHERE course_id=$1 AND student_id=$2`, courseID, studentID)
+		} else {
+			res, err = dbh.ExecContext(r.Context(),
+				`UPDATE course_students SET status='dropped' WHERE course_id=$1 AND student_id=$2`, courseID, studentID)
+		}
+		if err != nil {
+			nethttp.Error(w, "db error", nethttp.StatusInternalServerError)
+			return
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			nethttp.Error(w, "not enrolled", nethttp.StatusNotFound)
+			return
+		}
+		if hard {
+			w.WriteHeader(nethttp.StatusNoContent)
+			return
+		}
+		respondJSON(w, nethttp.StatusOK, map[string]string{"course_id": courseID, "student_id": studentID, "status": "dropped"})
+	}
+}
+
+// invalidMember is a user id rejected by pre-validation, and why.
+type invalidMember struct {
+	UserID string `json:"user_id"`
+	Error  string `json:"error"`
+}
+
+// memberSummary is the reply to a bulk course membership change: the ids
+// written, in request order. The batch is all or nothing, so there are no
+// per-user failures to report.
+type memberSummary struct {
+	Added   int      `json:"added"`
+	UserIDs []string `json:"user_ids"`
+}
+
+// invalidMembersError lists ids rejected by pre-validation; nothing is written.
+type invalidMembersError struct {
+	Invalid []invalidMember
+}
+
+func (e *invalidMembersError) Error() string { return "invalid user ids" }
+
+// applyCourseMembers validates every user id (exists and, if roles is
+// non-empty, has one of roles) and then runs stmt for each inside one
+// transaction. Any invalid id rejects the whole batch with
+// *invalidMembersError; any DB error rolls back all.
+func applyCourseMembers(ctx context.Context, dbh *sql.DB, userIDs []string, roles []string, stmt string, args func(uid string) []any) (memberSummary, error) {
+	tx, err := dbh.BeginTx(ctx, nil)
+	if err != nil {
+		return memberSummary{}, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var ids []string
+	var invalid []invalidMember
+	seen := map[string]bool{}
+	for _, uid := range userIDs {
+		uid = strings.TrimSpace(uid)
+		if uid == "" || seen[uid] {
+			continue
+		}
+		seen[uid] = true
+
+		var role string
+		err := tx.QueryRowContext(ctx, `SELECT role FROM users WHERE id=$1`, uid).Scan(&role)
+		switch {
+		case err == sql.ErrNoRows:
+			invalid = append(invalid, invalidMember{UserID: uid, Error: "user not found"})
+			continue
+		case err != nil:
+			return memberSummary{}, err
+		}
+		if len(roles) > 0 && !slices.Contains(roles, role) {
+			invalid = append(invalid, invalidMember{UserID: uid, Error: "role " + role + " not allowed"})
+			continue
+		}
+		ids = append(ids, uid)
+	}
+	if len(invalid) > 0 {
+		return memberSummary{}, &invalidMembersError{Invalid: invalid}
+	}
+
+	for _, uid := range ids {
+		if _, err := tx.ExecContext(ctx, stmt, args(uid)...); err != nil {
+			return memberSummary{}, fmt.Errorf("%s: %w", uid, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return memberSummary{}, err
+	}
+	return memberSummary{Added: len(ids), UserIDs: ids}, nil
+}
+
+// writeMembersError maps applyCourseMembers errors to a response. Database
+// errors are logged, not echoed to the client.
+func writeMembersError(w nethttp.ResponseWriter, r *nethttp.Request, err error) {
+	var inv *invalidMembersError
+	if errors.As(err, &inv) {
+		respondJSON(w, nethttp.StatusUnprocessableEntity, map[string]any{
+			"error":   inv.Error(),
+			"invalid": inv.Invalid,
+		})
+		return
+	}
+	slog.ErrorContext(r.Context(), "course members", "course", chi.URLParam(r, "courseID"), "err", err)
+	nethttp.Error(w, "db error", nethttp.StatusInternalServerError)
+}
+
 func CreateOfferingHandler(dbh *sql.DB, authSvc *authmw.AuthService) nethttp.HandlerFunc {
 	return func(w nethttp.ResponseWriter, r *nethttp.Request) {
 		courseID := chi.URLParam(r, "courseID")
@@ -282,9 +523,16 @@ func CreateOfferingHandler(dbh *sql.DB, authSvc *authmw.AuthService) nethttp.Han
 			MaxAttempts  *int    `json:"max_attempts,omitempty"`
 			Visibility   *string `json:"visibility,omitempty"`
 			AccessToken  *string `json:"access_token,omitempty"`
-		}
-		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || strings.TrimSpace(req.ExamID) == "" {
-			nethttp.Error(w, "bad json", nethttp.StatusBadRequest)
+			// AccessTokenExpiresAt (unix seconds) ends the access token's
+			// use; link handlers then answer 403.
+			AccessTokenExpiresAt *int64  `json:"access_token_expires_at,omitempty"`
+			GradingMode          *string `json:"grading_mode,omitempty"` // on_submit (default) | deferred
+			// StrictWindow refuses (422) a window too short for the time
+			// limit instead of creating the offering with a warning.
+			StrictWindow bool `json:"strict_window,omitempty"`
+		}
+		if err := decodeBody(r, &req); err != nil || strings.TrimSpace(req.ExamID) == "" {
+			badJSON(w, err)
 			return
 		}
 
@@ -319,19 +567,67 @@ func CreateOfferingHandler(dbh *sql.DB, authSvc *authmw.AuthService) nethttp.Han
 			accTok.Valid = true
 			accTok.String = strings.TrimSpace(*req.AccessToken)
 		}
+		if req.AccessTokenExpiresAt != nil && *req.AccessTokenExpiresAt <= time.Now().Unix() {
+			nethttp.Error(w, "access_token_expires_at must be in the future", nethttp.StatusBadRequest)
+			return
+		}
+		gradingMode := exam.GradeOnSubmit
+		if req.GradingMode != nil && *req.GradingMode != "" {
+			if *req.GradingMode != exam.GradeOnSubmit && *req.GradingMode != exam.GradeDeferred {
+				nethttp.Error(w, "grading_mode must be on_submit or deferred", nethttp.StatusBadRequest)
+				return
+			}
+			gradingMode = *req.GradingMode
+		}
+
+		var warnings []string
+		if msg := shortWindow(dbh, req.ExamID, startAt, endAt, timeLimit); msg != "" {
+			if req.StrictWindow {
+				nethttp.Error(w, msg, nethttp.StatusUnprocessableEntity)
+				return
+			}
+			warnings = append(warnings, msg)
+		}
 
 		if _, err := dbh.Exec(`
             INSERT INTO exam_offerings
-                (id, exam_id, course_id, assigned_by, start_at, end_at, time_limit_sec, max_attempts, visibility, access_token)
-            VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
-        `, offID, req.ExamID, courseID, sub, startAt, endAt, timeLimit, maxAttempts, visibility, accTok); err != nil {
+                (id, exam_id, course_id, assigned_by, start_at, end_at, time_limit_sec, max_attempts, visibility, access_token, grading_mode,
+                 access_token_expires_at)
+            VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+        `, offID, req.ExamID, courseID, sub, startAt, endAt, timeLimit, maxAttempts, visibility, accTok, gradingMode,
+			req.AccessTokenExpiresAt); err != nil {
 			nethttp.Error(w, "db error", nethttp.StatusInternalServerError)
 			return
 		}
-		_ = json.NewEncoder(w).Encode(map[string]any{"id": offID})
+		out := map[string]any{"id": offID}
+		if len(warnings) > 0 {
+			out["warnings"] = warnings
+		}
+		_ = json.NewEncoder(w).Encode(out)
 	}
 }
 
+// shortWindow describes why the window [startAt, endAt] cannot fit a full
+// attempt: it is shorter than the offering's time limit or, without one,
+// the exam's. "" when it fits or is open-ended.
+func shortWindow(dbh *sql.DB, examID string, startAt, endAt *int64, timeLimit sql.NullInt64) string {
+	if startAt == nil || endAt == nil {
+		return ""
+	}
+	limit, source := timeLimit.Int64, "offering"
+	if !timeLimit.Valid {
+		source = "exam"
+		if err := dbh.QueryRow(`SELECT time_limit_sec FROM exams WHERE id=$1`, examID).Scan(&limit); err != nil {
+			return ""
+		}
+	}
+	window := *endAt - *startAt
+	if limit <= 0 || window >= limit {
+		return ""
+	}
+	return fmt.Sprintf("window is %ds, shorter than the %s time limit of %ds: attempts cannot run to the limit", window, source, limit)
+}
+
 func ListOfferingsHandler(dbh *sql.DB, authSvc *authmw.AuthService) nethttp.HandlerFunc {
 	return func(w nethttp.ResponseWriter, r *nethttp.Request) {
 		courseID := chi.URLParam(r, "courseID")
@@ -350,7 +646,7 @@ func ListOfferingsHandler(dbh *sql.DB, authSvc *authmw.AuthService) nethttp.Hand
 		}
 
 		rows, err := dbh.Query(`
-			SELECT id, exam_id, start_at, end_at, time_limit_sec, max_attempts, visibility
+			SELECT id, exam_id, start_at, end_at, time_limit_sec, max_attempts, visibility, grading_mode
 			FROM exam_offerings
 			WHERE course_id=$1
 			ORDER BY start_at NULLS FIRST, id
@@ -369,6 +665,8 @@ func ListOfferingsHandler(dbh *sql.DB, authSvc *authmw.AuthService) nethttp.Hand
 			TimeLimitSec *int       `json:"time_limit_sec,omitempty"`
 			MaxAttempts  int        `json:"max_attempts"`
 			Visibility   string     `json:"visibility"`
+			GradingMode  string     `json:"grading_mode"`
+			localWindow
 		}
 
 		out := make([]off, 0, 8) // ensures [] not null
@@ -378,7 +676,7 @@ func ListOfferingsHandler(dbh *sql.DB, authSvc *authmw.AuthService) nethttp.Hand
 			var start, end sql.NullInt64
 			var tls sql.NullInt64
 
-			if err := rows.Scan(&o.ID, &o.ExamID, &start, &end, &tls, &o.MaxAttempts, &o.Visibility); err != nil {
+			if err := rows.Scan(&o.ID, &o.ExamID, &start, &end, &tls, &o.MaxAttempts, &o.Visibility, &o.GradingMode); err != nil {
 				// optionally log the scan error
 				continue
 			}
@@ -394,6 +692,7 @@ func ListOfferingsHandler(dbh *sql.DB, authSvc *authmw.AuthService) nethttp.Hand
 				v := int(tls.Int64)
 				o.TimeLimitSec = &v
 			}
+			o.localWindow = localWindowFor(r, start, end)
 			out = append(out, o)
 		}
 
@@ -422,6 +721,12 @@ func isCourseTeacher(db *sql.DB, userID, courseID string) bool {
 	return ok
 }
 
+func isCourseOwner(db *sql.DB, userID, courseID string) bool {
+	var ok bool
+	_ = db.QueryRow(`SELECT EXISTS(SELECT 1 FROM course_teachers WHERE course_id=$1 AND teacher_id=$2 AND role='owner')`, courseID, userID).Scan(&ok)
+	return ok
+}
+
 func isCourseStudent(db *sql.DB, userID, courseID string) bool {
 	var ok bool
 	_ = db.QueryRow(`SELECT EXISTS(SELECT 1 FROM course_students WHERE course_id=$1 AND student_id=$2 AND status='active')`, courseID, userID).Scan(&ok)
@@ -21,6 +21,7 @@ func ListPublicCoursesHandler(db *sql.DB) http.HandlerFunc {
 			  FROM courses c
 			  JOIN exam_offerings o ON o.course_id = c.id
 			 WHERE o.visibility = 'public'
+			   AND c.status = 'active'
 			   AND (o.start_at IS NULL OR o.start_at <= $1)
 			   AND (o.end_at   IS NULL OR o.end_at   >= $1)
 			 GROUP BY c.id, c.name
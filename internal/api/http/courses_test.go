@@ -0,0 +1,451 @@
+package http_test
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+
+	api "github.com/mind-engage/mindengage-lms/internal/api/http"
+	authmw "github.com/mind-engage/mindengage-lms/internal/auth/middleware"
+	"github.com/mind-engage/mindengage-lms/internal/db"
+)
+
+// newTestDB opens a fresh SQLite DB with a teacher (t1) owning course c1 and
+// two students (s1, s2).
+func newTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	dbh, err := db.Open(context.Background(), db.DriverSQLite, "file:"+filepath.Join(t.TempDir(), "api.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = dbh.Close() })
+	for _, q := range []string{
+		`INSERT INTO users (id, username, role) VALUES ('t1','t1','teacher'), ('t2','t2','teacher'), ('s1','s1','student'), ('s2','s2','student')`,
+		`INSERT INTO courses (id, name, created_by) VALUES ('c1','Course 1','t1')`,
+		`INSERT INTO course_teachers (course_id, teacher_id, role) VALUES ('c1','t1','owner')`,
+	} {
+		if _, err := dbh.Exec(q); err != nil {
+			t.Fatalf("%s: %v", q, err)
+		}
+	}
+	return dbh
+}
+
+func bearer(t *testing.T, authSvc *authmw.AuthService, sub, role string) string {
+	t.Helper()
+	tok, err := authSvc.IssueJWT(sub, role)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return "Bearer " + tok
+}
+
+type invalidMember struct {
+	UserID string `json:"user_id"`
+	Error  string `json:"error"`
+}
+
+type membersResponse struct {
+	Added   int             `json:"added"`
+	UserIDs []string        `json:"user_ids"`
+	Invalid []invalidMember `json:"invalid"`
+}
+
+func postMembers(t *testing.T, h http.HandlerFunc, pattern, path, auth, body string) (int, membersResponse) {
+	t.Helper()
+	r := chi.NewRouter()
+	r.Post(pattern, h)
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	req.Header.Set("Authorization", auth)
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	var out membersResponse
+	if strings.HasPrefix(rec.Header().Get("Content-Type"), "application/json") {
+		if err := json.NewDecoder(rec.Body).Decode(&out); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return rec.Code, out
+}
+
+func count(t *testing.T, dbh *sql.DB, q string, args ...any) int {
+	t.Helper()
+	var n int
+	if err := dbh.QueryRow(q, args...).Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func TestEnrollStudents(t *testing.T) {
+	dbh := newTestDB(t)
+	authSvc := authmw.NewAuthService("test")
+
+	code, out := postMembers(t, api.EnrollStudentsHandler(dbh, authSvc),
+		"/courses/{courseID}/students", "/courses/c1/students",
+		bearer(t, authSvc, "t1", "teacher"),
+		`{"user_ids":["s1","s2"," ","s1"]}`)
+	if code != http.StatusOK {
+		t.Fatalf("status = %d", code)
+	}
+	if out.Added != 2 || len(out.UserIDs) != 2 || out.UserIDs[0] != "s1" || out.UserIDs[1] != "s2" {
+		t.Fatalf("summary = %+v", out)
+	}
+	if n := count(t, dbh, `SELECT COUNT(*) FROM course_students WHERE course_id='c1'`); n != 2 {
+		t.Fatalf("enrolled = %d, want 2", n)
+	}
+}
+
+func TestEnrollStudents_NonexistentIDRejectsBatch(t *testing.T) {
+	dbh := newTestDB(t)
+	authSvc := authmw.NewAuthService("test")
+
+	code, out := postMembers(t, api.EnrollStudentsHandler(dbh, authSvc),
+		"/courses/{courseID}/students", "/courses/c1/students",
+		bearer(t, authSvc, "t1", "teacher"),
+		`{"user_ids":["s1","ghost","s2"]}`)
+	if code != http.StatusUnprocessableEntity {
+		t.Fatalf("status = %d, want 422", code)
+	}
+	if len(out.Invalid) != 1 || out.Invalid[0].UserID != "ghost" || out.Invalid[0].Error == "" {
+		t.Fatalf("invalid = %+v", out.Invalid)
+	}
+	if n := count(t, dbh, `SELECT COUNT(*) FROM course_students WHERE course_id='c1'`); n != 0 {
+		t.Fatalf("enrolled = %d, want 0 (batch rejected)", n)
+	}
+}
+
+func TestAddCoTeachers(t *testing.T) {
+	dbh := newTestDB(t)
+	authSvc := authmw.NewAuthService("test")
+
+	code, out := postMembers(t, api.AddCoTeachersHandler(dbh, authSvc),
+		"/courses/{courseID}/teachers", "/courses/c1/teachers",
+		bearer(t, authSvc, "t1", "teacher"),
+		`{"user_ids":["t2"]}`)
+	if code != http.StatusOK || out.Added != 1 {
+		t.Fatalf("status = %d summary = %+v", code, out)
+	}
+	if n := count(t, dbh, `SELECT COUNT(*) FROM course_teachers WHERE course_id='c1' AND teacher_id='t2' AND role='co'`); n != 1 {
+		t.Fatalf("co-teacher rows = %d, want 1", n)
+	}
+}
+
+func TestAddCoTeachers_StudentRejected(t *testing.T) {
+	dbh := newTestDB(t)
+	authSvc := authmw.NewAuthService("test")
+
+	code, out := postMembers(t, api.AddCoTeachersHandler(dbh, authSvc),
+		"/courses/{courseID}/teachers", "/courses/c1/teachers",
+		bearer(t, authSvc, "t1", "teacher"),
+		`{"user_ids":["t2","s1","nobody"]}`)
+	if code != http.StatusUnprocessableEntity {
+		t.Fatalf("status = %d, want 422", code)
+	}
+	got := map[string]string{}
+	for _, r := range out.Invalid {
+		got[r.UserID] = r.Error
+	}
+	if len(got) != 2 || got["s1"] == "" || got["nobody"] == "" {
+		t.Fatalf("invalid = %+v", out.Invalid)
+	}
+	if n := count(t, dbh, `SELECT COUNT(*) FROM course_teachers WHERE course_id='c1'`); n != 1 {
+		t.Fatalf("teacher rows = %d, want only the owner", n)
+	}
+}
+
+func TestEnrollStudents_ForbiddenForNonTeacher(t *testing.T) {
+	dbh := newTestDB(t)
+	authSvc := authmw.NewAuthService("test")
+
+	code, _ := postMembers(t, api.EnrollStudentsHandler(dbh, authSvc),
+		"/courses/{courseID}/students", "/courses/c1/students",
+		bearer(t, authSvc, "s1", "student"),
+		`{"user_ids":["s2"]}`)
+	if code != http.StatusForbidden {
+		t.Fatalf("status = %d, want 403", code)
+	}
+}
+
+func TestListCourses_WithCounts(t *testing.T) {
+	dbh := newTestDB(t)
+	authSvc := authmw.NewAuthService("test")
+	for _, q := range []string{
+		`INSERT INTO courses (id, name, created_by) VALUES ('c2','Course 2','t1')`,
+		`INSERT INTO course_teachers (course_id, teacher_id, role) VALUES ('c2','t1','owner')`,
+		`INSERT INTO course_students (course_id, student_id, status) VALUES ('c1','s1','active'), ('c1','s2','dropped'), ('c2','s2','active')`,
+		`INSERT INTO exams (id, title, time_limit_sec, questions_json) VALUES ('e1','E1',0,'[]')`,
+		`INSERT INTO exam_offerings (id, exam_id, course_id, assigned_by) VALUES ('o1','e1','c1','t1'), ('o2','e1','c1','t1')`,
+	} {
+		if _, err := dbh.Exec(q); err != nil {
+			t.Fatalf("%s: %v", q, err)
+		}
+	}
+
+	list := func(query string) []map[string]any {
+		req := httptest.NewRequest(http.MethodGet, "/courses"+query, nil)
+		req.Header.Set("Authorization", bearer(t, authSvc, "t1", "teacher"))
+		rec := httptest.NewRecorder()
+		api.ListCoursesHandler(dbh, authSvc)(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d body=%s", rec.Code, rec.Body.String())
+		}
+		var out []map[string]any
+		if err := json.NewDecoder(rec.Body).Decode(&out); err != nil {
+			t.Fatal(err)
+		}
+		return out
+	}
+
+	got := map[string]map[string]any{}
+	for _, c := range list("?with_counts=1") {
+		got[c["id"].(string)] = c
+	}
+	want := map[string][2]float64{"c1": {1, 2}, "c2": {1, 0}}
+	for id, w := range want {
+		c, ok := got[id]
+		if !ok {
+			t.Fatalf("course %s missing", id)
+		}
+		if c["student_count"] != w[0] || c["offering_count"] != w[1] {
+			t.Errorf("%s counts = %v/%v, want %v/%v", id, c["student_count"], c["offering_count"], w[0], w[1])
+		}
+	}
+
+	for _, c := range list("") {
+		if _, ok := c["student_count"]; ok {
+			t.Fatalf("counts present without with_counts: %v", c)
+		}
+	}
+}
+
+func TestUnenrollStudent(t *testing.T) {
+	dbh := newTestDB(t)
+	authSvc := authmw.NewAuthService("test")
+	for _, q := range []string{
+		`INSERT INTO course_students (course_id, student_id) VALUES ('c1','s1'), ('c1','s2')`,
+		`INSERT INTO exams (id, title, time_limit_sec, questions_json) VALUES ('e1','E1',0,'[]')`,
+		`INSERT INTO exam_offerings (id, exam_id, course_id, assigned_by) VALUES ('o1','e1','c1','t1')`,
+		`INSERT INTO attempts (id, exam_id, user_id, status, responses_json, offering_id) VALUES ('a1','e1','s1','submitted','{}','o1')`,
+	} {
+		if _, err := dbh.Exec(q); err != nil {
+			t.Fatalf("%s: %v", q, err)
+		}
+	}
+
+	r := chi.NewRouter()
+	r.Get("/courses/{courseID}/offerings", api.ListOfferingsHandler(dbh, authSvc))
+	r.Delete("/courses/{courseID}/students/{studentID}", api.UnenrollStudentHandler(dbh, authSvc))
+	do := func(method, path, sub, role string) int {
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set("Authorization", bearer(t, authSvc, sub, role))
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	if code := do(http.MethodGet, "/courses/c1/offerings", "s1", "student"); code != http.StatusOK {
+		t.Fatalf("enrolled student offerings status = %d", code)
+	}
+	if code := do(http.MethodDelete, "/courses/c1/students/s1", "s2", "student"); code != http.StatusForbidden {
+		t.Fatalf("student drop status = %d, want 403", code)
+	}
+
+	t.Run("soft drop hides offerings and keeps attempts", func(t *testing.T) {
+		if code := do(http.MethodDelete, "/courses/c1/students/s1", "t1", "teacher"); code != http.StatusOK {
+			t.Fatalf("drop status = %d", code)
+		}
+		if code := do(http.MethodGet, "/courses/c1/offerings", "s1", "student"); code != http.StatusForbidden {
+			t.Fatalf("dropped student offerings status = %d, want 403", code)
+		}
+		if n := count(t, dbh, `SELECT COUNT(*) FROM course_students WHERE course_id='c1' AND student_id='s1' AND status='dropped'`); n != 1 {
+			t.Fatalf("dropped rows = %d, want 1", n)
+		}
+		if n := count(t, dbh, `SELECT COUNT(*) FROM attempts WHERE id='a1'`); n != 1 {
+			t.Fatal("attempt removed by drop")
+		}
+	})
+
+	t.Run("hard delete removes the row", func(t *testing.T) {
+		if code := do(http.MethodDelete, "/courses/c1/students/s2?hard=1", "t1", "teacher"); code != http.StatusNoContent {
+			t.Fatalf("hard delete status = %d", code)
+		}
+		if n := count(t, dbh, `SELECT COUNT(*) FROM course_students WHERE course_id='c1' AND student_id='s2'`); n != 0 {
+			t.Fatalf("rows = %d, want 0", n)
+		}
+		if code := do(http.MethodDelete, "/courses/c1/students/s2?hard=1", "t1", "teacher"); code != http.StatusNotFound {
+			t.Fatalf("repeat delete status = %d, want 404", code)
+		}
+	})
+}
+
+func TestRemoveCoTeacher(t *testing.T) {
+	dbh := newTestDB(t)
+	authSvc := authmw.NewAuthService("test")
+	if _, err := dbh.Exec(`INSERT INTO users (id, username, role) VALUES ('t3','t3','teacher')`); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := dbh.Exec(`INSERT INTO course_teachers (course_id, teacher_id, role) VALUES ('c1','t2','co'), ('c1','t3','co')`); err != nil {
+		t.Fatal(err)
+	}
+
+	r := chi.NewRouter()
+	r.Delete("/courses/{courseID}/teachers/{teacherID}", api.RemoveCoTeacherHandler(dbh, authSvc))
+	del := func(path, sub string) int {
+		req := httptest.NewRequest(http.MethodDelete, path, nil)
+		req.Header.Set("Authorization", bearer(t, authSvc, sub, "teacher"))
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	if code := del("/courses/c1/teachers/t3", "t2"); code != http.StatusForbidden {
+		t.Fatalf("co-teacher removing status = %d, want 403", code)
+	}
+	if code := del("/courses/c1/teachers/t2", "t1"); code != http.StatusNoContent {
+		t.Fatalf("remove co-teacher status = %d, want 204", code)
+	}
+	if n := count(t, dbh, `SELECT COUNT(*) FROM course_teachers WHERE course_id='c1' AND teacher_id='t2'`); n != 0 {
+		t.Fatalf("t2 rows = %d, want 0", n)
+	}
+
+	// t1 is the only owner: self-removal is refused.
+	if code := del("/courses/c1/teachers/t1", "t1"); code != http.StatusConflict {
+		t.Fatalf("last owner removal status = %d, want 409", code)
+	}
+
+	// With a second owner, the first may leave.
+	if _, err := dbh.Exec(`UPDATE course_teachers SET role='owner' WHERE course_id='c1' AND teacher_id='t3'`); err != nil {
+		t.Fatal(err)
+	}
+	if code := del("/courses/c1/teachers/t1", "t1"); code != http.StatusNoContent {
+		t.Fatalf("owner removal with another owner status = %d, want 204", code)
+	}
+	if code := del("/courses/c1/teachers/t3", "t3"); code != http.StatusConflict {
+		t.Fatalf("new last owner removal status = %d, want 409", code)
+	}
+	if code := del("/courses/c1/teachers/t1", "t3"); code != http.StatusNotFound {
+		t.Fatalf("removing a non-teacher status = %d, want 404", code)
+	}
+
+	// Two owners removing each other at once: exactly one wins.
+	if _, err := dbh.Exec(`INSERT INTO course_teachers (course_id, teacher_id, role) VALUES ('c1','t1','owner')`); err != nil {
+		t.Fatal(err)
+	}
+	var wg sync.WaitGroup
+	codes := make([]int, 2)
+	for i, pair := range [][2]string{{"t1", "t3"}, {"t3", "t1"}} {
+		wg.Add(1)
+		go func(i int, target, caller string) {
+			defer wg.Done()
+			codes[i] = del("/courses/c1/teachers/"+target, caller)
+		}(i, pair[0], pair[1])
+	}
+	wg.Wait()
+	if n := count(t, dbh, `SELECT COUNT(*) FROM course_teachers WHERE course_id='c1' AND role='owner'`); n != 1 {
+		t.Fatalf("owners after concurrent removal = %d (codes %v), want 1", n, codes)
+	}
+}
+
+func TestStrictJSON_UnknownFieldRejected(t *testing.T) {
+	dbh := newTestDB(t)
+	authSvc := authmw.NewAuthService("test")
+	if _, err := dbh.Exec(`INSERT INTO exams (id, title, time_limit_sec, questions_json) VALUES ('e1','E1',0,'[]')`); err != nil {
+		t.Fatal(err)
+	}
+	router := func(strict bool) http.Handler {
+		r := chi.NewRouter()
+		r.Use(api.StrictJSON(strict))
+		r.Post("/courses", api.CreateCourseHandler(dbh, authSvc))
+		r.Post("/courses/{courseID}/offerings", api.CreateOfferingHandler(dbh, authSvc))
+		return r
+	}
+	post := func(h http.Handler, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		req.Header.Set("Authorization", bearer(t, authSvc, "t1", "teacher"))
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	typo := `{"exam_id":"e1","time_limitsec":600}`
+	rec := post(router(true), "/courses/c1/offerings", typo)
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), `unknown field "time_limitsec"`) {
+		t.Fatalf("strict offering: status = %d body=%q", rec.Code, rec.Body.String())
+	}
+	if rec := post(router(true), "/courses", `{"name":"N","colour":"red"}`); rec.Code != http.StatusBadRequest {
+		t.Fatalf("strict course: status = %d, want 400", rec.Code)
+	}
+	if rec := post(router(true), "/courses", `{"name":"N"}`); rec.Code >= 300 {
+		t.Fatalf("strict valid course: status = %d body=%s", rec.Code, rec.Body.String())
+	}
+
+	// Lenient (default): the unknown field is ignored as before.
+	if rec := post(router(false), "/courses/c1/offerings", typo); rec.Code >= 300 {
+		t.Fatalf("lenient offering: status = %d body=%s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestCreateOffering_WarnsOnShortWindow(t *testing.T) {
+	dbh := newTestDB(t)
+	authSvc := authmw.NewAuthService("test")
+	if _, err := dbh.Exec(`INSERT INTO exams (id, title, time_limit_sec, questions_json) VALUES ('e1','E1',3600,'[]')`); err != nil {
+		t.Fatal(err)
+	}
+	r := chi.NewRouter()
+	r.Post("/courses/{courseID}/offerings", api.CreateOfferingHandler(dbh, authSvc))
+	post := func(body string) (int, []string) {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPost, "/courses/c1/offerings", strings.NewReader(body))
+		req.Header.Set("Authorization", bearer(t, authSvc, "t1", "teacher"))
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		var out struct {
+			Warnings []string `json:"warnings"`
+		}
+		_ = json.Unmarshal(rec.Body.Bytes(), &out)
+		return rec.Code, out.Warnings
+	}
+
+	// 30 minutes for a one-hour exam.
+	code, warnings := post(`{"exam_id":"e1","start_at":1000,"end_at":2800}`)
+	if code != http.StatusOK || len(warnings) != 1 || !strings.Contains(warnings[0], "exam time limit of 3600s") {
+		t.Fatalf("short window: status = %d, warnings = %q", code, warnings)
+	}
+	// The offering's own limit is what counts when set.
+	if code, warnings := post(`{"exam_id":"e1","start_at":1000,"end_at":2800,"time_limit_sec":1200}`); code != http.StatusOK || len(warnings) != 0 {
+		t.Fatalf("window fits the offering limit: status = %d, warnings = %q", code, warnings)
+	}
+	if code, warnings := post(`{"exam_id":"e1","start_at":1000,"end_at":2800,"time_limit_sec":2400}`); code != http.StatusOK || len(warnings) != 1 {
+		t.Fatalf("window shorter than the offering limit: status = %d, warnings = %q", code, warnings)
+	}
+	// Long enough, or open-ended: no warning.
+	for _, body := range []string{
+		`{"exam_id":"e1","start_at":1000,"end_at":4600}`,
+		`{"exam_id":"e1","start_at":1000}`,
+	} {
+		if code, warnings := post(body); code != http.StatusOK || len(warnings) != 0 {
+			t.Fatalf("%s: status = %d, warnings = %q", body, code, warnings)
+		}
+	}
+
+	// Opted in, the short window is an error and nothing is created.
+	var before int
+	_ = dbh.QueryRow(`SELECT COUNT(*) FROM exam_offerings`).Scan(&before)
+	if code, _ := post(`{"exam_id":"e1","start_at":1000,"end_at":2800,"strict_window":true}`); code != http.StatusUnprocessableEntity {
+		t.Fatalf("strict short window: status = %d, want 422", code)
+	}
+	var after int
+	_ = dbh.QueryRow(`SELECT COUNT(*) FROM exam_offerings`).Scan(&after)
+	if after != before {
+		t.Fatalf("offerings %d -> %d after a refused create", before, after)
+	}
+}
@@ -0,0 +1,48 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+type strictJSONKey struct{}
+
+// StrictJSON makes the create/update handlers (courses, offerings, exams)
+// reject request bodies with fields they do not know, so a typo such as
+// "time_limitsec" is an error instead of a silently ignored field. Off, they
+// decode leniently as before.
+func StrictJSON(on bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), strictJSONKey{}, on)))
+		})
+	}
+}
+
+// decodeBody decodes the JSON request body into v, refusing unknown fields
+// when StrictJSON is on for the request.
+func decodeBody(r *http.Request, v any) error {
+	dec := json.NewDecoder(r.Body)
+	if on, _ := r.Context().Value(strictJSONKey{}).(bool); on {
+		dec.DisallowUnknownFields()
+	}
+	return dec.Decode(v)
+}
+
+// badJSON reports a decodeBody error as 400, naming the field when the body
+// was refused for an unknown one; a body cut off by httpsec.MaxJSONBody is 413.
+func badJSON(w http.ResponseWriter, err error) {
+	var mbe *http.MaxBytesError
+	if errors.As(err, &mbe) {
+		http.Error(w, "request body too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+	msg := "bad json"
+	if err != nil && strings.HasPrefix(err.Error(), "json: unknown field ") {
+		msg += ": " + strings.TrimPrefix(err.Error(), "json: ")
+	}
+	http.Error(w, msg, http.StatusBadRequest)
+}
@@ -0,0 +1,123 @@
+package http
+
+import (
+	"database/sql"
+	"encoding/json"
+	nethttp "net/http"
+	"strings"
+
+	authmw "github.com/mind-engage/mindengage-lms/internal/auth/middleware"
+	ex "github.com/mind-engage/mindengage-lms/internal/exam"
+)
+
+// OptionStat is one choice of a question with how often it was picked.
+// Share is Count over the question's graded responses.
+type OptionStat struct {
+	ChoiceID  string  `json:"choice_id"`
+	LabelHTML string  `json:"label_html,omitempty"`
+	Correct   bool    `json:"correct"`
+	Count     int64   `json:"count"`
+	Share     float64 `json:"share"`
+}
+
+// QuestionDistribution is a question's ephemeral stats in exam order, with
+// option buckets matched to the exam's choices. Other keeps the buckets
+// that are not a choice (mcq_multi combinations, text answers, unknown ids).
+type QuestionDistribution struct {
+	QuestionID string       `json:"question_id"`
+	Type       string       `json:"type"`
+	PromptHTML string       `json:"prompt_html,omitempty"`
+	Total      int64        `json:"total"`
+	Avg        float64      `json:"avg_points"`
+	Max        float64      `json:"max_points"`
+	Options    []OptionStat `json:"options,omitempty"`
+	Other      []Bucket     `json:"other,omitempty"`
+}
+
+type EphemeralDistributionResp struct {
+	OfferingID string                 `json:"offering_id"`
+	ExamID     string                 `json:"exam_id"`
+	UpdatedAt  int64                  `json:"updated_at"`
+	Questions  []QuestionDistribution `json:"questions"`
+}
+
+// GET /api/offerings/{offeringID}/stats/distribution
+// Teacher view of an offering's ephemeral stats: every question of the exam,
+// its choices labeled and the correct ones marked, ready to chart. It shows
+// the answer key, so it takes teacher auth rather than the access token.
+func GetEphemeralDistributionHandler(db *sql.DB, store ex.Store, authSvc *authmw.AuthService) nethttp.HandlerFunc {
+	return func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		offID, examID, ok := teacherOffering(w, r, db, authSvc)
+		if !ok {
+			return
+		}
+		exam, err := store.GetExamAdmin(r.Context(), examID)
+		if err != nil {
+			nethttp.Error(w, "exam not found", nethttp.StatusNotFound)
+			return
+		}
+		stats, err := loadEphemeralStats(r.Context(), db, offID, 0)
+		if err != nil {
+			nethttp.Error(w, "db error", nethttp.StatusInternalServerError)
+			return
+		}
+		byQID := make(map[string]QStat, len(stats.Questions))
+		for _, q := range stats.Questions {
+			byQID[q.QuestionID] = q
+		}
+
+		out := EphemeralDistributionResp{
+			OfferingID: offID,
+			ExamID:     examID,
+			UpdatedAt:  stats.UpdatedAt,
+			Questions:  make([]QuestionDistribution, 0, len(exam.Questions)),
+		}
+		for _, q := range exam.Questions {
+			out.Questions = append(out.Questions, questionDistribution(q, byQID[q.ID]))
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		_ = json.NewEncoder(w).Encode(out)
+	}
+}
+
+// questionDistribution matches st's buckets (see bucketKeys) to q's choices:
+// the bare choice id for mcq_single/true_false, "opt:<id>" for mcq_multi.
+func questionDistribution(q ex.Question, st QStat) QuestionDistribution {
+	d := QuestionDistribution{
+		QuestionID: q.ID, Type: q.Type, PromptHTML: q.PromptHTML,
+		Total: st.Total, Avg: st.Avg, Max: st.Max,
+	}
+	prefix := ""
+	switch strings.ToLower(strings.TrimSpace(q.Type)) {
+	case "mcq_single", "true_false":
+	case "mcq_multi":
+		prefix = "opt:"
+	default:
+		d.Other = st.Buckets
+		return d
+	}
+	counts := make(map[string]int64, len(st.Buckets))
+	for _, b := range st.Buckets {
+		counts[b.Key] = b.Count
+	}
+	key := make(map[string]bool, len(q.AnswerKey))
+	for _, k := range q.AnswerKey {
+		key[k] = true
+	}
+	matched := make(map[string]bool, len(q.Choices))
+	for _, c := range q.Choices {
+		o := OptionStat{ChoiceID: c.ID, LabelHTML: c.LabelHTML, Correct: key[c.ID], Count: counts[prefix+c.ID]}
+		if st.Total > 0 {
+			o.Share = float64(o.Count) / float64(st.Total)
+		}
+		matched[prefix+c.ID] = true
+		d.Options = append(d.Options, o)
+	}
+	for _, b := range st.Buckets {
+		if !matched[b.Key] {
+			d.Other = append(d.Other, b)
+		}
+	}
+	return d
+}
@@ -0,0 +1,26 @@
+package http
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/mind-engage/mindengage-lms/internal/exam"
+)
+
+type examLimitsKey struct{}
+
+// ExamLimits sets the size caps UploadExamHandler enforces: an exam with
+// more questions is refused with 413, a question with more choices with
+// 422. Without it uploads are not capped.
+func ExamLimits(l exam.Limits) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), examLimitsKey{}, l)))
+		})
+	}
+}
+
+func examLimitsFrom(ctx context.Context) exam.Limits {
+	l, _ := ctx.Value(examLimitsKey{}).(exam.Limits)
+	return l
+}
@@ -28,7 +28,7 @@ func GetAttemptGradingHandler(store exam.Store) http.HandlerFunc {
 			http.Error(w, "grading items: "+err.Error(), http.StatusInternalServerError)
 			return
 		}
-		_ = json.NewEncoder(w).Encode(items)
+		_ = json.NewEncoder(w).Encode(displayItems(r.Context(), items))
 	}
 }
 
@@ -51,6 +51,6 @@ func ApplyAttemptGradingHandler(store exam.Store, authSvc *authmw.AuthService) h
 			http.Error(w, "apply grades: "+err.Error(), http.StatusInternalServerError)
 			return
 		}
-		_ = json.NewEncoder(w).Encode(a)
+		_ = json.NewEncoder(w).Encode(displayAttempt(r.Context(), a))
 	}
 }
@@ -0,0 +1,205 @@
+package http
+
+import (
+	"context"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/json"
+	nethttp "net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	authmw "github.com/mind-engage/mindengage-lms/internal/auth/middleware"
+)
+
+type linkTokenCheck int
+
+const (
+	linkTokenInvalid linkTokenCheck = iota
+	linkTokenExpired
+	linkTokenOK
+)
+
+// checkLinkToken says whether tok opens link offering offeringID: it must
+// be the offering's own access_token (primary, unless past
+// access_token_expires_at) or one of its offering_access_tokens, unexpired.
+func checkLinkToken(ctx context.Context, db *sql.DB, offeringID, primary, tok string) linkTokenCheck {
+	tok = strings.TrimSpace(tok)
+	if tok == "" {
+		return linkTokenInvalid
+	}
+	now := time.Now().Unix()
+	var exp sql.NullInt64
+	if primary = strings.TrimSpace(primary); primary != "" &&
+		subtle.ConstantTimeCompare([]byte(primary), []byte(tok)) == 1 {
+		if err := db.QueryRowContext(ctx, `SELECT access_token_expires_at FROM exam_offerings WHERE id=$1`, offeringID).
+			Scan(&exp); err != nil {
+			return linkTokenInvalid
+		}
+	} else if err := db.QueryRowContext(ctx, `
+		SELECT expires_at FROM offering_access_tokens WHERE token=$1 AND offering_id=$2`, tok, offeringID).
+		Scan(&exp); err != nil {
+		return linkTokenInvalid
+	}
+	if exp.Valid && exp.Int64 <= now {
+		return linkTokenExpired
+	}
+	return linkTokenOK
+}
+
+// requireLinkToken checks tok with checkLinkToken and, unless it is good,
+// writes the answer: 404 for a wrong token (like a missing offering; see
+// the access policy note on RequireAttemptOwner), 403 for an expired one.
+func requireLinkToken(w nethttp.ResponseWriter, r *nethttp.Request, db *sql.DB, offeringID, primary, tok string) bool {
+	switch checkLinkToken(r.Context(), db, offeringID, primary, tok) {
+	case linkTokenOK:
+		return true
+	case linkTokenExpired:
+		nethttp.Error(w, "access token expired", nethttp.StatusForbidden)
+	default:
+		nethttp.Error(w, "not found", nethttp.StatusNotFound)
+	}
+	return false
+}
+
+type offeringToken struct {
+	Token     string `json:"token"`
+	Label     string `json:"label,omitempty"`
+	ExpiresAt *int64 `json:"expires_at,omitempty"` // unix seconds
+	CreatedAt int64  `json:"created_at"`
+	Expired   bool   `json:"expired,omitempty"`
+	ShareURL  string `json:"share_url"`
+}
+
+// teacherLinkOffering checks that the caller teaches the course (or is an
+// admin) and that the offering in the URL is a link offering of it; on
+// failure it has written the response.
+func teacherLinkOffering(w nethttp.ResponseWriter, r *nethttp.Request, dbh *sql.DB, authSvc *authmw.AuthService) (sub, offID string, ok bool) {
+	courseID := chi.URLParam(r, "courseID")
+	offID = chi.URLParam(r, "offID")
+	sub, role := subjectFromBearer(authSvc, r)
+	if sub == "" {
+		nethttp.Error(w, "unauthorized", nethttp.StatusUnauthorized)
+		return "", "", false
+	}
+	if role != "admin" && !isCourseTeacher(dbh, sub, courseID) {
+		nethttp.Error(w, "forbidden", nethttp.StatusForbidden)
+		return "", "", false
+	}
+	var visibility string
+	err := dbh.QueryRowContext(r.Context(), `SELECT visibility FROM exam_offerings WHERE id=$1 AND course_id=$2`, offID, courseID).
+		Scan(&visibility)
+	if err == sql.ErrNoRows {
+		nethttp.Error(w, "not found", nethttp.StatusNotFound)
+		return "", "", false
+	}
+	if err != nil {
+		nethttp.Error(w, "db error", nethttp.StatusInternalServerError)
+		return "", "", false
+	}
+	if visibility != "link" {
+		nethttp.Error(w, "offering is not link-visible", nethttp.StatusBadRequest)
+		return "", "", false
+	}
+	return sub, offID, true
+}
+
+// POST /courses/{courseID}/offerings/{offID}/tokens {label?, expires_at?}
+// Mints another join token for a link offering, e.g. one per class section.
+func CreateOfferingTokenHandler(dbh *sql.DB, authSvc *authmw.AuthService) nethttp.HandlerFunc {
+	return func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		sub, offID, ok := teacherLinkOffering(w, r, dbh, authSvc)
+		if !ok {
+			return
+		}
+		var req struct {
+			Label     string `json:"label"`
+			ExpiresAt *int64 `json:"expires_at,omitempty"`
+		}
+		if err := decodeBody(r, &req); err != nil {
+			badJSON(w, err)
+			return
+		}
+		now := time.Now().Unix()
+		if req.ExpiresAt != nil && *req.ExpiresAt <= now {
+			nethttp.Error(w, "expires_at must be in the future", nethttp.StatusBadRequest)
+			return
+		}
+		tok, err := randomHex(32)
+		if err != nil {
+			nethttp.Error(w, "token gen error", nethttp.StatusInternalServerError)
+			return
+		}
+		label := strings.TrimSpace(req.Label)
+		if _, err := dbh.ExecContext(r.Context(), `
+			INSERT INTO offering_access_tokens (token, offering_id, label, expires_at, created_by, created_at)
+			VALUES ($1,$2,$3,$4,$5,$6)`, tok, offID, label, req.ExpiresAt, sub, now); err != nil {
+			nethttp.Error(w, "db error", nethttp.StatusInternalServerError)
+			return
+		}
+		respondJSON(w, nethttp.StatusCreated, offeringToken{
+			Token: tok, Label: label, ExpiresAt: req.ExpiresAt, CreatedAt: now,
+			ShareURL: offeringShareURL(r, offID, tok),
+		})
+	}
+}
+
+// GET /courses/{courseID}/offerings/{offID}/tokens
+// Lists the offering's extra join tokens, oldest first, expired ones flagged.
+func ListOfferingTokensHandler(dbh *sql.DB, authSvc *authmw.AuthService) nethttp.HandlerFunc {
+	return func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		_, offID, ok := teacherLinkOffering(w, r, dbh, authSvc)
+		if !ok {
+			return
+		}
+		rows, err := dbh.QueryContext(r.Context(), `
+			SELECT token, label, expires_at, created_at FROM offering_access_tokens
+			 WHERE offering_id=$1 ORDER BY created_at, token`, offID)
+		if err != nil {
+			nethttp.Error(w, "db error", nethttp.StatusInternalServerError)
+			return
+		}
+		defer rows.Close()
+		now := time.Now().Unix()
+		out := []offeringToken{}
+		for rows.Next() {
+			var t offeringToken
+			var exp sql.NullInt64
+			if err := rows.Scan(&t.Token, &t.Label, &exp, &t.CreatedAt); err != nil {
+				nethttp.Error(w, "db error", nethttp.StatusInternalServerError)
+				return
+			}
+			if exp.Valid {
+				v := exp.Int64
+				t.ExpiresAt = &v
+				t.Expired = v <= now
+			}
+			t.ShareURL = offeringShareURL(r, offID, t.Token)
+			out = append(out, t)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(out)
+	}
+}
+
+// DELETE /courses/{courseID}/offerings/{offID}/tokens/{token}
+func RevokeOfferingTokenHandler(dbh *sql.DB, authSvc *authmw.AuthService) nethttp.HandlerFunc {
+	return func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		_, offID, ok := teacherLinkOffering(w, r, dbh, authSvc)
+		if !ok {
+			return
+		}
+		res, err := dbh.ExecContext(r.Context(), `DELETE FROM offering_access_tokens WHERE token=$1 AND offering_id=$2`,
+			chi.URLParam(r, "token"), offID)
+		if err != nil {
+			nethttp.Error(w, "db error", nethttp.StatusInternalServerError)
+			return
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			nethttp.Error(w, "not found", nethttp.StatusNotFound)
+			return
+		}
+		w.WriteHeader(nethttp.StatusNoContent)
+	}
+}
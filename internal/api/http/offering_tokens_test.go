@@ -0,0 +1,417 @@
+package http_test
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	api "github.com/mind-engage/mindengage-lms/internal/api/http"
+	authmw "github.com/mind-engage/mindengage-lms/internal/auth/middleware"
+	"github.com/mind-engage/mindengage-lms/internal/exam"
+	"github.com/mind-engage/mindengage-lms/internal/grading"
+)
+
+func TestOfferingTokens_PerSection(t *testing.T) {
+	dbh := newTestDB(t)
+	authSvc := authmw.NewAuthService("test")
+	store := exam.NewSQLStore(dbh, "sqlite", grading.NewDefaultGrader())
+	if err := store.PutExam(exam.Exam{
+		ID:        "e1",
+		Title:     "Quiz",
+		Questions: []exam.Question{{ID: "q1", Type: "mcq_single", Points: 1, AnswerKey: []string{"a"}}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := dbh.Exec(`INSERT INTO exam_offerings (id, exam_id, course_id, assigned_by, visibility, access_token) VALUES ('lnk','e1','c1','t1','link','primary')`); err != nil {
+		t.Fatal(err)
+	}
+
+	r := chi.NewRouter()
+	r.Post("/courses/{courseID}/offerings/{offID}/tokens", api.CreateOfferingTokenHandler(dbh, authSvc))
+	r.Get("/courses/{courseID}/offerings/{offID}/tokens", api.ListOfferingTokensHandler(dbh, authSvc))
+	r.Get("/offerings/{offeringID}/resolve", api.GetOfferingByTokenHandler(dbh, store))
+	r.Post("/offerings/{offeringID}/grade_ephemeral", api.GradeEphemeralHandler(dbh, store, grading.NewDefaultGrader()))
+	do := func(method, path, sub, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if sub != "" {
+			req.Header.Set("Authorization", bearer(t, authSvc, sub, "teacher"))
+		}
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec
+	}
+	mint := func(body string) string {
+		t.Helper()
+		rec := do(http.MethodPost, "/courses/c1/offerings/lnk/tokens", "t1", body)
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("mint %s: status = %d body=%s", body, rec.Code, rec.Body.String())
+		}
+		var out struct {
+			Token    string `json:"token"`
+			ShareURL string `json:"share_url"`
+		}
+		if err := json.NewDecoder(rec.Body).Decode(&out); err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(out.ShareURL, "access_token="+out.Token) {
+			t.Fatalf("share_url %q lacks the token", out.ShareURL)
+		}
+		return out.Token
+	}
+
+	if rec := do(http.MethodPost, "/courses/c1/offerings/lnk/tokens", "t2", `{}`); rec.Code != http.StatusForbidden {
+		t.Fatalf("non-teacher mint: status = %d, want 403", rec.Code)
+	}
+	secA := mint(`{"label":"Section A"}`)
+	secB := mint(fmt.Sprintf(`{"label":"Section B","expires_at":%d}`, time.Now().Add(time.Hour).Unix()))
+	old := mint(fmt.Sprintf(`{"label":"Last term","expires_at":%d}`, time.Now().Add(time.Hour).Unix()))
+	if _, err := dbh.Exec(`UPDATE offering_access_tokens SET expires_at=$1 WHERE token=$2`, time.Now().Add(-time.Minute).Unix(), old); err != nil {
+		t.Fatal(err)
+	}
+
+	grade := `{"responses":{"q1":"a"}}`
+	for _, tok := range []string{"primary", secA, secB} {
+		if rec := do(http.MethodGet, "/offerings/lnk/resolve?access_token="+tok, "", ""); rec.Code != http.StatusOK {
+			t.Errorf("resolve with %s: status = %d", tok, rec.Code)
+		}
+		if rec := do(http.MethodPost, "/offerings/lnk/grade_ephemeral?access_token="+tok, "", grade); rec.Code != http.StatusOK {
+			t.Errorf("grade with %s: status = %d", tok, rec.Code)
+		}
+	}
+	if rec := do(http.MethodGet, "/offerings/lnk/resolve?access_token="+old, "", ""); rec.Code != http.StatusForbidden {
+		t.Errorf("resolve with expired token: status = %d, want 403", rec.Code)
+	}
+	if rec := do(http.MethodPost, "/offerings/lnk/grade_ephemeral?access_token="+old, "", grade); rec.Code != http.StatusForbidden {
+		t.Errorf("grade with expired token: status = %d, want 403", rec.Code)
+	}
+
+	rec := do(http.MethodGet, "/courses/c1/offerings/lnk/tokens", "t1", "")
+	var list []struct {
+		Token   string `json:"token"`
+		Label   string `json:"label"`
+		Expired bool   `json:"expired"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&list); err != nil {
+		t.Fatal(err)
+	}
+	expired := map[string]bool{}
+	for _, tk := range list {
+		expired[tk.Label] = tk.Expired
+	}
+	if len(list) != 3 || expired["Section A"] || expired["Section B"] || !expired["Last term"] {
+		t.Fatalf("tokens = %+v", list)
+	}
+}
+
+func TestOfferingAccessToken_Expiry(t *testing.T) {
+	dbh := newTestDB(t)
+	authSvc := authmw.NewAuthService("test")
+	store := exam.NewSQLStore(dbh, "sqlite", grading.NewDefaultGrader())
+	if err := store.PutExam(exam.Exam{
+		ID:        "e1",
+		Title:     "Quiz",
+		Questions: []exam.Question{{ID: "q1", Type: "mcq_single", Points: 1, AnswerKey: []string{"a"}}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	r := chi.NewRouter()
+	r.Post("/courses/{courseID}/offerings", api.CreateOfferingHandler(dbh, authSvc))
+	r.Get("/offerings/{offeringID}/resolve", api.GetOfferingByTokenHandler(dbh, store))
+	r.Post("/offerings/{offeringID}/grade_ephemeral", api.GradeEphemeralHandler(dbh, store, grading.NewDefaultGrader()))
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Authorization", bearer(t, authSvc, "t1", "teacher"))
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := do(http.MethodPost, "/courses/c1/offerings",
+		`{"exam_id":"e1","visibility":"link","access_token":"tok","access_token_expires_at":1}`); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expiry in the past: status = %d, want 400", rec.Code)
+	}
+	rec := do(http.MethodPost, "/courses/c1/offerings", fmt.Sprintf(
+		`{"exam_id":"e1","visibility":"link","access_token":"tok","access_token_expires_at":%d}`, time.Now().Add(time.Hour).Unix()))
+	var created struct {
+		ID string `json:"id"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&created); err != nil || created.ID == "" {
+		t.Fatalf("create: status = %d body=%s", rec.Code, rec.Body.String())
+	}
+
+	grade := `{"responses":{"q1":"a"}}`
+	if rec := do(http.MethodGet, "/offerings/"+created.ID+"/resolve?access_token=tok", ""); rec.Code != http.StatusOK {
+		t.Fatalf("unexpired token: resolve status = %d", rec.Code)
+	}
+	if rec := do(http.MethodPost, "/offerings/"+created.ID+"/grade_ephemeral?access_token=tok", grade); rec.Code != http.StatusOK {
+		t.Fatalf("unexpired token: grade status = %d", rec.Code)
+	}
+
+	if _, err := dbh.Exec(`UPDATE exam_offerings SET access_token_expires_at=$1 WHERE id=$2`, time.Now().Add(-time.Minute).Unix(), created.ID); err != nil {
+		t.Fatal(err)
+	}
+	if rec := do(http.MethodGet, "/offerings/"+created.ID+"/resolve?access_token=tok", ""); rec.Code != http.StatusForbidden {
+		t.Fatalf("expired token: resolve status = %d, want 403", rec.Code)
+	}
+	if rec := do(http.MethodPost, "/offerings/"+created.ID+"/grade_ephemeral?access_token=tok", grade); rec.Code != http.StatusForbidden {
+		t.Fatalf("expired token: grade status = %d, want 403", rec.Code)
+	}
+	if rec := do(http.MethodGet, "/offerings/"+created.ID+"/resolve?access_token=wrong", ""); rec.Code != http.StatusNotFound {
+		t.Fatalf("wrong token: resolve status = %d, want 404", rec.Code)
+	}
+}
+
+func TestResetEphemeralStats(t *testing.T) {
+	dbh := newTestDB(t)
+	authSvc := authmw.NewAuthService("test")
+	store := exam.NewSQLStore(dbh, "sqlite", grading.NewDefaultGrader())
+	if err := store.PutExam(exam.Exam{
+		ID:        "e1",
+		Title:     "Quiz",
+		Questions: []exam.Question{{ID: "q1", Type: "mcq_single", Points: 1, AnswerKey: []string{"a"}}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := dbh.Exec(`INSERT INTO exam_offerings (id, exam_id, course_id, assigned_by, visibility, access_token) VALUES ('lnk','e1','c1','t1','link','tok')`); err != nil {
+		t.Fatal(err)
+	}
+
+	r := chi.NewRouter()
+	r.Post("/offerings/{offeringID}/grade_ephemeral", api.GradeEphemeralHandler(dbh, store, grading.NewDefaultGrader()))
+	r.Get("/offerings/{offeringID}/ephemeral_stats", api.GetEphemeralStatsHandler(dbh))
+	r.Post("/offerings/{offeringID}/stats/reset", api.ResetEphemeralStatsHandler(dbh, authSvc))
+	do := func(method, path, sub, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if sub != "" {
+			req.Header.Set("Authorization", bearer(t, authSvc, sub, "teacher"))
+		}
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec
+	}
+	stats := func() int {
+		t.Helper()
+		rec := do(http.MethodGet, "/offerings/lnk/ephemeral_stats?access_token=tok", "", "")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("stats: status = %d body=%s", rec.Code, rec.Body.String())
+		}
+		var out struct {
+			Questions []struct {
+				Total int64 `json:"total"`
+			} `json:"questions"`
+		}
+		if err := json.NewDecoder(rec.Body).Decode(&out); err != nil {
+			t.Fatal(err)
+		}
+		n := 0
+		for _, q := range out.Questions {
+			n += int(q.Total)
+		}
+		return n
+	}
+
+	for _, resp := range []string{"a", "b"} {
+		if rec := do(http.MethodPost, "/offerings/lnk/grade_ephemeral?access_token=tok", "", `{"responses":{"q1":"`+resp+`"}}`); rec.Code != http.StatusOK {
+			t.Fatalf("grade: status = %d body=%s", rec.Code, rec.Body.String())
+		}
+	}
+	if n := stats(); n != 2 {
+		t.Fatalf("before reset: %d responses counted, want 2", n)
+	}
+
+	if rec := do(http.MethodPost, "/offerings/lnk/stats/reset?access_token=tok", "", ""); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("reset with only the access token: status = %d, want 401", rec.Code)
+	}
+	if rec := do(http.MethodPost, "/offerings/lnk/stats/reset", "t2", ""); rec.Code != http.StatusForbidden {
+		t.Fatalf("non-teacher reset: status = %d, want 403", rec.Code)
+	}
+	if rec := do(http.MethodPost, "/offerings/zzz/stats/reset", "t1", ""); rec.Code != http.StatusNotFound {
+		t.Fatalf("missing offering: status = %d, want 404", rec.Code)
+	}
+	if rec := do(http.MethodPost, "/offerings/lnk/stats/reset", "t1", ""); rec.Code != http.StatusOK {
+		t.Fatalf("reset: status = %d body=%s", rec.Code, rec.Body.String())
+	}
+	if n := stats(); n != 0 {
+		t.Fatalf("after reset: %d responses counted, want 0", n)
+	}
+}
+
+func TestEphemeralDistribution_LabelsOptions(t *testing.T) {
+	dbh := newTestDB(t)
+	authSvc := authmw.NewAuthService("test")
+	store := exam.NewSQLStore(dbh, "sqlite", grading.NewDefaultGrader())
+	if err := store.PutExam(exam.Exam{
+		ID:    "e1",
+		Title: "Quiz",
+		Questions: []exam.Question{
+			{ID: "single", Type: "mcq_single", Points: 1, AnswerKey: []string{"b"},
+				Choices: []exam.Choice{{ID: "a", LabelHTML: "Paris"}, {ID: "b", LabelHTML: "Rome"}, {ID: "c", LabelHTML: "Oslo"}}},
+			{ID: "multi", Type: "mcq_multi", Points: 1, AnswerKey: []string{"x", "z"},
+				Choices: []exam.Choice{{ID: "x", LabelHTML: "2"}, {ID: "y", LabelHTML: "4"}, {ID: "z", LabelHTML: "7"}}},
+			{ID: "word", Type: "short_word", Points: 1, AnswerKey: []string{"blue"}},
+		},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := dbh.Exec(`INSERT INTO exam_offerings (id, exam_id, course_id, assigned_by, visibility, access_token) VALUES ('lnk','e1','c1','t1','link','tok')`); err != nil {
+		t.Fatal(err)
+	}
+
+	r := chi.NewRouter()
+	r.Post("/offerings/{offeringID}/grade_ephemeral", api.GradeEphemeralHandler(dbh, store, grading.NewDefaultGrader()))
+	r.Get("/offerings/{offeringID}/stats/distribution", api.GetEphemeralDistributionHandler(dbh, store, authSvc))
+	do := func(method, path, sub, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if sub != "" {
+			req.Header.Set("Authorization", bearer(t, authSvc, sub, "teacher"))
+		}
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec
+	}
+	for _, body := range []string{
+		`{"responses":{"single":"b","multi":["x","z"],"word":"blue"}}`,
+		`{"responses":{"single":"a","multi":["x","y"],"word":"red"}}`,
+		`{"responses":{"single":"b","multi":["z"],"word":"red"}}`,
+	} {
+		if rec := do(http.MethodPost, "/offerings/lnk/grade_ephemeral?access_token=tok", "", body); rec.Code != http.StatusOK {
+			t.Fatalf("grade: status = %d body=%s", rec.Code, rec.Body.String())
+		}
+	}
+
+	if rec := do(http.MethodGet, "/offerings/lnk/stats/distribution?access_token=tok", "", ""); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("access token only: status = %d, want 401", rec.Code)
+	}
+	if rec := do(http.MethodGet, "/offerings/lnk/stats/distribution", "t2", ""); rec.Code != http.StatusForbidden {
+		t.Fatalf("non-teacher: status = %d, want 403", rec.Code)
+	}
+	rec := do(http.MethodGet, "/offerings/lnk/stats/distribution", "t1", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d body=%s", rec.Code, rec.Body.String())
+	}
+	var got api.EphemeralDistributionResp
+	if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Questions) != 3 || got.Questions[0].QuestionID != "single" || got.Questions[2].QuestionID != "word" {
+		t.Fatalf("questions = %+v, want the exam's, in order", got.Questions)
+	}
+	render := func(q api.QuestionDistribution) string {
+		var parts []string
+		for _, o := range q.Options {
+			mark := ""
+			if o.Correct {
+				mark = "*"
+			}
+			parts = append(parts, fmt.Sprintf("%s=%s%s:%d", o.ChoiceID, o.LabelHTML, mark, o.Count))
+		}
+		return strings.Join(parts, " ")
+	}
+	if want := "a=Paris:1 b=Rome*:2 c=Oslo:0"; render(got.Questions[0]) != want {
+		t.Errorf("single: %s, want %s", render(got.Questions[0]), want)
+	}
+	if want := "x=2*:2 y=4:1 z=7*:2"; render(got.Questions[1]) != want {
+		t.Errorf("multi: %s, want %s", render(got.Questions[1]), want)
+	}
+	if s := got.Questions[0].Options[1].Share; s < 0.66 || s > 0.67 {
+		t.Errorf("single b: share = %v, want 2/3", s)
+	}
+	if q := got.Questions[2]; len(q.Options) != 0 || len(q.Other) != 2 {
+		t.Errorf("word: options %+v, other %+v; want text buckets only", q.Options, q.Other)
+	}
+}
+
+func TestGradeEphemeral_ConcurrentTotals(t *testing.T) {
+	dbh := newTestDB(t)
+	store := exam.NewSQLStore(dbh, "sqlite", grading.NewDefaultGrader())
+	if err := store.PutExam(exam.Exam{
+		ID:    "e1",
+		Title: "Quiz",
+		Questions: []exam.Question{
+			{ID: "single", Type: "mcq_single", Points: 1, AnswerKey: []string{"a"}},
+			{ID: "multi", Type: "mcq_multi", Points: 2, AnswerKey: []string{"x", "y"}},
+		},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := dbh.Exec(`INSERT INTO exam_offerings (id, exam_id, course_id, assigned_by, visibility, access_token) VALUES ('lnk','e1','c1','t1','link','tok')`); err != nil {
+		t.Fatal(err)
+	}
+	// db.Open keeps SQLite to one connection, which would serialize the
+	// graders; a second handle on the same file lets their transactions race.
+	var file string
+	if err := dbh.QueryRow(`SELECT file FROM pragma_database_list WHERE name='main'`).Scan(&file); err != nil {
+		t.Fatal(err)
+	}
+	pool, err := sql.Open("sqlite", "file:"+file+"?_pragma=busy_timeout(10000)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = pool.Close() })
+	pool.SetMaxOpenConns(8)
+
+	r := chi.NewRouter()
+	r.Post("/offerings/{offeringID}/grade_ephemeral", api.GradeEphemeralHandler(pool, store, grading.NewDefaultGrader()))
+
+	const n = 24 // half right; half wrong, with partial credit on multi
+	var wg sync.WaitGroup
+	errs := make(chan string, n)
+	for i := 0; i < n; i++ {
+		body := `{"responses":{"single":"a","multi":["x","y"]}}`
+		if i%2 == 1 {
+			body = `{"responses":{"single":"b","multi":["x"]}}`
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/offerings/lnk/grade_ephemeral?access_token=tok", strings.NewReader(body)))
+			if rec.Code != http.StatusOK {
+				errs <- fmt.Sprintf("status = %d body=%s", rec.Code, rec.Body.String())
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for e := range errs {
+		t.Fatal(e)
+	}
+
+	rows, err := dbh.Query(`SELECT question_id, bucket, count, correct, sum_points FROM ephemeral_stats WHERE offering_id='lnk'`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	got := map[string]string{}
+	for rows.Next() {
+		var qid, bucket string
+		var count, correct int64
+		var sum float64
+		if err := rows.Scan(&qid, &bucket, &count, &correct, &sum); err != nil {
+			t.Fatal(err)
+		}
+		got[qid+"/"+bucket] = fmt.Sprintf("%d/%d/%g", count, correct, sum)
+	}
+	for k, want := range map[string]string{
+		"single/*":      "24/12/12",
+		"single/a":      "12/12/12",
+		"single/b":      "12/0/0",
+		"multi/*":       "24/12/36",
+		"multi/opt:x":   "24/12/36",
+		"multi/opt:y":   "12/12/24",
+		"multi/set:x":   "12/0/12",
+		"multi/set:x,y": "12/12/24",
+	} {
+		if got[k] != want {
+			t.Errorf("%s: count/correct/sum = %s, want %s", k, got[k], want)
+		}
+	}
+}
@@ -3,7 +3,6 @@ package http
 
 import (
 	"context"
-	"crypto/subtle"
 	"database/sql"
 	"encoding/json"
 	"net/http"
@@ -20,8 +19,10 @@ import (
 
 	"github.com/go-chi/chi/v5"
 	authmw "github.com/mind-engage/mindengage-lms/internal/auth/middleware"
+	"github.com/mind-engage/mindengage-lms/internal/basepath"
 	ex "github.com/mind-engage/mindengage-lms/internal/exam"
 	"github.com/mind-engage/mindengage-lms/internal/grading"
+	"github.com/mind-engage/mindengage-lms/internal/i18n"
 )
 
 type EphemeralGradeReq struct {
@@ -35,7 +36,63 @@ type ItemResult struct {
 	NeedsManual   bool     `json:"needs_manual,omitempty"`
 	Feedback      []string `json:"feedback,omitempty"`
 	Correct       bool     `json:"correct"`                  // true only if full credit
-	CorrectAnswer []string `json:"correct_answer,omitempty"` // omitted unless ?show_answers=1
+	CorrectAnswer []string `json:"correct_answer,omitempty"` // omitted unless ?show_answers=1 and the feedback policy allows and reveals it
+	WrongChoices  []string `json:"wrong_choices,omitempty"`  // mcq_multi selections not in the key; ?show_answers=partial or 1
+}
+
+// answerReveal is how much of the key an ephemeral grade shows, from
+// ?show_answers: "1" the key itself, "partial" only which of the taker's
+// own mcq_multi selections were wrong, anything else neither. The exam's
+// feedback.show_answers caps it (allowedReveal): the flag is the client's.
+type answerReveal int
+
+const (
+	revealNone answerReveal = iota
+	revealPartial
+	revealFull
+)
+
+func answerRevealFromQuery(r *http.Request) answerReveal {
+	switch strings.ToLower(strings.TrimSpace(r.URL.Query().Get("show_answers"))) {
+	case "1":
+		return revealFull
+	case "partial":
+		return revealPartial
+	}
+	return revealNone
+}
+
+// allowedReveal is the most the exam's feedback policy lets a taker see.
+func allowedReveal(p ex.FeedbackPolicy) answerReveal {
+	switch strings.ToLower(strings.TrimSpace(p.ShowAnswers)) {
+	case ex.ShowAnswersAlways:
+		return revealFull
+	case ex.ShowAnswersPartial:
+		return revealPartial
+	}
+	return revealNone
+}
+
+// wrongChoices is the selections in an mcq_multi response that are not in
+// the key, in response order. It reveals nothing the taker did not pick.
+func wrongChoices(q ex.Question, norm any) []string {
+	sel, ok := norm.([]string)
+	if !ok || strings.ToLower(strings.TrimSpace(q.Type)) != "mcq_multi" {
+		return nil
+	}
+	key := make(map[string]bool, len(q.AnswerKey))
+	for _, k := range q.AnswerKey {
+		key[k] = true
+	}
+	var out []string
+	seen := map[string]bool{}
+	for _, c := range sel {
+		if !key[c] && !seen[c] {
+			seen[c] = true
+			out = append(out, c)
+		}
+	}
+	return out
 }
 
 type EphemeralGradeResp struct {
@@ -72,11 +129,48 @@ type offeringResolveResp struct {
 	TimeLimitSec *int       `json:"time_limit_sec,omitempty"`
 	MaxAttempts  int        `json:"max_attempts"`
 	Visibility   string     `json:"visibility"`
-	State        string     `json:"state,omitempty"` // not_started | active | ended
-	Exam         ex.Exam    `json:"exam"`            // student-safe (no answer_key)
+	State        string     `json:"state"` // not_started | active | ended
+	Exam         ex.Exam    `json:"exam"`  // student-safe (no answer_key); questions only while active
+	localWindow
+}
+
+// Offering window states, as reported by the resolve endpoints.
+const (
+	offeringNotStarted = "not_started"
+	offeringActive     = "active"
+	offeringEnded      = "ended"
+)
+
+// offeringState is the state of the window [start, end] at now (unix
+// seconds); a missing bound leaves that side open.
+func offeringState(start, end sql.NullInt64, now int64) string {
+	switch {
+	case start.Valid && now < start.Int64:
+		return offeringNotStarted
+	case end.Valid && now > end.Int64:
+		return offeringEnded
+	}
+	return offeringActive
+}
+
+// withResolvedExam sets out.Exam to the student-safe exam, keeping its
+// questions back unless the offering is active: the client still gets the
+// title and policy to tell the taker when it opens or that it has closed.
+func withResolvedExam(store ex.Store, out *offeringResolveResp) error {
+	examSafe, err := store.GetExam(out.ExamID)
+	if err != nil {
+		return err
+	}
+	if out.State != offeringActive {
+		examSafe.Questions = []ex.Question{}
+	}
+	out.Exam = examSafe
+	return nil
 }
 
 // GetOfferingByTokenHandler returns offering metadata + student-safe exam via store.GetExam.
+// A valid token always gets 200 with the window's state; 404 means the
+// offering (or its exam) is missing or the token is wrong.
 func GetOfferingByTokenHandler(db *sql.DB, store ex.Store) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		offeringID := chi.URLParam(r, "offeringID")
@@ -105,8 +199,7 @@ func GetOfferingByTokenHandler(db *sql.DB, store ex.Store) http.HandlerFunc {
 			http.Error(w, "not found", http.StatusNotFound)
 			return
 		}
-		if subtle.ConstantTimeCompare([]byte(strings.TrimSpace(dbTok)), []byte(tok)) != 1 {
-			http.Error(w, "not found", http.StatusNotFound)
+		if !requireLinkToken(w, r, db, offeringID, dbTok, tok) {
 			return
 		}
 
@@ -123,24 +216,15 @@ func GetOfferingByTokenHandler(db *sql.DB, store ex.Store) http.HandlerFunc {
 			v := int(tls.Int64)
 			out.TimeLimitSec = &v
 		}
+		out.localWindow = localWindowFor(r, start, end)
 		out.Visibility = vis
-		now := time.Now().UTC().Unix()
-		switch {
-		case start.Valid && now < start.Int64:
-			out.State = "not_started"
-		case end.Valid && now > end.Int64:
-			out.State = "ended"
-		default:
-			out.State = "active"
-		}
+		out.State = offeringState(start, end, time.Now().UTC().Unix())
 
 		// Student-safe exam (no keys) + policy via store
-		examSafe, err := store.GetExam(out.ExamID)
-		if err != nil {
+		if err := withResolvedExam(store, &out); err != nil {
 			http.Error(w, "not found", http.StatusNotFound)
 			return
 		}
-		out.Exam = examSafe
 
 		w.Header().Set("Content-Type", "application/json")
 		w.Header().Set("Cache-Control", "no-store")
@@ -168,8 +252,14 @@ func GradeEphemeralHandler(db *sql.DB, store ex.Store, grader grading.Grader) ht
 			http.Error(w, "not found", http.StatusNotFound)
 			return
 		}
-		if vis != "link" || subtle.ConstantTimeCompare([]byte(dbTok), []byte(tok)) != 1 {
-			http.Error(w, "forbidden", http.StatusForbidden)
+		// Same answer as a missing offering; see the access policy note on
+		// RequireAttemptOwner. An expired token and the window checks below
+		// are 403: the token was good.
+		if vis != "link" {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		if !requireLinkToken(w, r, db, offeringID, dbTok, tok) {
 			return
 		}
 		now := time.Now().UTC().Unix()
@@ -199,51 +289,70 @@ func GradeEphemeralHandler(db *sql.DB, store ex.Store, grader grading.Grader) ht
 			req.Responses = map[string]any{}
 		}
 
-		showAnswers := r.URL.Query().Get("show_answers") == "1"
-
 		// 4) Grade using same engine; normalize response types per strategy
-		var out EphemeralGradeResp
-		out.Items = make([]ItemResult, 0, len(exam.Questions))
-
-		for _, q := range exam.Questions {
-			gq := grading.Q{Type: q.Type, Points: q.Points, AnswerKey: q.AnswerKey}
-			raw := req.Responses[q.ID]
-			norm := normalizeForType(q.Type, raw) // <-- key difference vs earlier sketch
-
-			res, _ := grader.Grade(context.Background(), gq, norm) // ignore error -> 0 points, like Submit()
-
-			item := ItemResult{
-				QuestionID:  q.ID,
-				Points:      res.AutoPoints,
-				PointsMax:   q.Points,
-				NeedsManual: res.NeedsManual,
-				Feedback:    res.Feedback,
-				// full credit only -> Correct=true (partial credit remains false here)
-				Correct: q.Points > 0 && res.AutoPoints >= q.Points,
-			}
-			if showAnswers {
+		out := gradeEphemeral(r.Context(), db, grader, offeringID, exam, req.Responses, answerRevealFromQuery(r))
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(displayGrade(r.Context(), out))
+	}
+}
+
+// gradeEphemeral grades responses without persisting an attempt and bumps the
+// offering's aggregate ephemeral_stats. Shared by the link and public paths.
+func gradeEphemeral(ctx context.Context, db *sql.DB, grader grading.Grader, offeringID string, exam ex.Exam, responses map[string]any, show answerReveal) EphemeralGradeResp {
+	var out EphemeralGradeResp
+	out.Items = make([]ItemResult, 0, len(exam.Questions))
+	reveal := ex.ParseFeedbackPolicy(exam.PolicyRaw)
+	var bumps []ephemeralBump
+	if allowed := allowedReveal(reveal); show > allowed {
+		show = allowed
+	}
+
+	for _, q := range exam.Questions {
+		gq := q.GradingQ()
+		raw := responses[q.ID]
+		norm := normalizeForType(q.Type, raw) // <-- key difference vs earlier sketch
+
+		res, _ := grader.Grade(ctx, gq, norm) // ignore error -> 0 points, like Submit()
+
+		item := ItemResult{
+			QuestionID:  q.ID,
+			Points:      res.AutoPoints,
+			PointsMax:   q.Points,
+			NeedsManual: res.NeedsManual,
+			Feedback:    i18n.LocalizeAll(i18n.Lang(ctx), res.Feedback),
+			// full credit only -> Correct=true (partial credit remains false here)
+			Correct: q.Points > 0 && res.AutoPoints >= q.Points,
+		}
+		// The policy may withhold feedback and answers by question tag.
+		if !reveal.Reveals(q) {
+			item.Feedback = nil
+		} else {
+			if show == revealFull {
 				item.CorrectAnswer = q.AnswerKey
 			}
+			if show >= revealPartial {
+				item.WrongChoices = wrongChoices(q, norm)
+			}
+		}
 
-			out.Score += item.Points
-			out.ScoreMax += item.PointsMax
-			out.Items = append(out.Items, item)
+		out.Score += item.Points
+		out.ScoreMax += item.PointsMax
+		out.Items = append(out.Items, item)
 
-			isCorrect := q.Points > 0 && res.AutoPoints >= q.Points
-			maxPts := q.Points
+		isCorrect := q.Points > 0 && res.AutoPoints >= q.Points
+		maxPts := q.Points
 
-			// 1) always bump totals ("*")
-			_ = bumpEphemeral(db, offeringID, q.ID, "*", isCorrect, res.AutoPoints, maxPts)
+		// 1) always bump totals ("*")
+		bumps = append(bumps, ephemeralBump{q.ID, "*", isCorrect, res.AutoPoints, maxPts})
 
-			// 2) optionally bump answer buckets — use the NORMALIZED response
-			for _, k := range bucketKeys(q.Type, norm) {
-				_ = bumpEphemeral(db, offeringID, q.ID, k, isCorrect, res.AutoPoints, maxPts)
-			}
+		// 2) optionally bump answer buckets — use the NORMALIZED response
+		for _, k := range bucketKeys(q.Type, norm) {
+			bumps = append(bumps, ephemeralBump{q.ID, k, isCorrect, res.AutoPoints, maxPts})
 		}
-
-		w.Header().Set("Content-Type", "application/json")
-		_ = json.NewEncoder(w).Encode(out)
 	}
+	_ = bumpEphemeral(ctx, db, offeringID, bumps)
+	return out
 }
 
 // normalizeForType coerces incoming JSON to what each grading strategy expects.
@@ -368,11 +477,13 @@ func GetEphemeralStatsHandler(db *sql.DB) nethttp.HandlerFunc {
 		var vis, dbTok string
 		if err := db.QueryRowContext(r.Context(),
 			`SELECT visibility, COALESCE(access_token,'') FROM exam_offerings WHERE id=$1`, offID).
-			Scan(&vis, &dbTok); err != nil || vis != "link" ||
-			subtle.ConstantTimeCompare([]byte(strings.TrimSpace(dbTok)), []byte(tok)) != 1 {
+			Scan(&vis, &dbTok); err != nil || vis != "link" {
 			nethttp.Error(w, "not found", nethttp.StatusNotFound)
 			return
 		}
+		if !requireLinkToken(w, r, db, offID, dbTok, tok) {
+			return
+		}
 
 		var since int64
 		if s := strings.TrimSpace(r.URL.Query().Get("since")); s != "" {
@@ -381,92 +492,158 @@ func GetEphemeralStatsHandler(db *sql.DB) nethttp.HandlerFunc {
 			}
 		}
 
-		var rows *sql.Rows
-		var err error
-		if since > 0 {
-			rows, err = db.QueryContext(r.Context(), `
-				SELECT question_id, bucket, count, correct, sum_points, max_points, updated_at
-				  FROM ephemeral_stats
-				 WHERE offering_id=$1 AND updated_at>$2
-				ORDER BY question_id, bucket`, offID, since)
-		} else {
-			rows, err = db.QueryContext(r.Context(), `
-				SELECT question_id, bucket, count, correct, sum_points, max_points, updated_at
-				  FROM ephemeral_stats
-				 WHERE offering_id=$1
-				ORDER BY question_id, bucket`, offID)
-		}
+		out, err := loadEphemeralStats(r.Context(), db, offID, since)
 		if err != nil {
 			nethttp.Error(w, "db error", nethttp.StatusInternalServerError)
 			return
 		}
-		defer rows.Close()
 
-		type acc struct {
-			total int64
-			sum   float64
-			max   float64
-			bks   []Bucket
-		}
-		out := EphemeralStatsResponse{OfferingID: offID, UpdatedAt: time.Now().Unix()}
-		accs := map[string]*acc{}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		_ = json.NewEncoder(w).Encode(out)
+	}
+}
 
-		for rows.Next() {
-			var qid, bucket string
-			var cnt, cor int64
-			var sum, max float64
-			var upd int64
-			if err := rows.Scan(&qid, &bucket, &cnt, &cor, &sum, &max, &upd); err != nil {
-				continue
-			}
-			if upd > out.UpdatedAt {
-				out.UpdatedAt = upd
-			}
-			a := accs[qid]
-			if a == nil {
-				a = &acc{}
-				accs[qid] = a
-			}
-			if bucket == "*" {
-				a.total += cnt
-				a.sum += sum
-				if max > a.max {
-					a.max = max
-				}
-				continue
-			}
-			b := Bucket{Key: bucket, Count: cnt, Correct: cor}
-			if cnt > 0 {
-				b.Avg = sum / float64(cnt)
-			}
-			a.bks = append(a.bks, b)
+// loadEphemeralStats aggregates an offering's ephemeral_stats rows (those
+// updated after since, if positive) per question: "*" rows into the totals,
+// the rest as buckets.
+func loadEphemeralStats(ctx context.Context, db *sql.DB, offID string, since int64) (EphemeralStatsResponse, error) {
+	var rows *sql.Rows
+	var err error
+	if since > 0 {
+		rows, err = db.QueryContext(ctx, `
+			SELECT question_id, bucket, count, correct, sum_points, max_points, updated_at
+			  FROM ephemeral_stats
+			 WHERE offering_id=$1 AND updated_at>$2
+			ORDER BY question_id, bucket`, offID, since)
+	} else {
+		rows, err = db.QueryContext(ctx, `
+			SELECT question_id, bucket, count, correct, sum_points, max_points, updated_at
+			  FROM ephemeral_stats
+			 WHERE offering_id=$1
+			ORDER BY question_id, bucket`, offID)
+	}
+	if err != nil {
+		return EphemeralStatsResponse{}, err
+	}
+	defer rows.Close()
+
+	type acc struct {
+		total int64
+		sum   float64
+		max   float64
+		bks   []Bucket
+	}
+	out := EphemeralStatsResponse{OfferingID: offID, UpdatedAt: time.Now().Unix()}
+	accs := map[string]*acc{}
+
+	for rows.Next() {
+		var qid, bucket string
+		var cnt, cor int64
+		var sum, max float64
+		var upd int64
+		if err := rows.Scan(&qid, &bucket, &cnt, &cor, &sum, &max, &upd); err != nil {
+			continue
+		}
+		if upd > out.UpdatedAt {
+			out.UpdatedAt = upd
+		}
+		a := accs[qid]
+		if a == nil {
+			a = &acc{}
+			accs[qid] = a
+		}
+		if bucket == "*" {
+			a.total += cnt
+			a.sum += sum
 			if max > a.max {
 				a.max = max
 			}
+			continue
 		}
+		b := Bucket{Key: bucket, Count: cnt, Correct: cor}
+		if cnt > 0 {
+			b.Avg = sum / float64(cnt)
+		}
+		a.bks = append(a.bks, b)
+		if max > a.max {
+			a.max = max
+		}
+	}
 
-		for qid, a := range accs {
-			q := QStat{QuestionID: qid, Total: a.total, Max: a.max, Buckets: a.bks}
-			if a.total > 0 {
-				q.Avg = a.sum / float64(a.total)
-			}
-			out.Questions = append(out.Questions, q)
+	for qid, a := range accs {
+		q := QStat{QuestionID: qid, Total: a.total, Max: a.max, Buckets: a.bks}
+		if a.total > 0 {
+			q.Avg = a.sum / float64(a.total)
 		}
+		out.Questions = append(out.Questions, q)
+	}
+	return out, rows.Err()
+}
 
-		w.Header().Set("Content-Type", "application/json")
-		w.Header().Set("Cache-Control", "no-store")
-		_ = json.NewEncoder(w).Encode(out)
+// teacherOffering checks that the caller teaches the course of the offering
+// in the URL (or is an admin) and returns its id and exam; on failure it
+// has written the response.
+func teacherOffering(w nethttp.ResponseWriter, r *nethttp.Request, db *sql.DB, authSvc *authmw.AuthService) (offID, examID string, ok bool) {
+	offID = chi.URLParam(r, "offeringID")
+	sub, role := subjectFromBearer(authSvc, r)
+	if sub == "" {
+		nethttp.Error(w, "unauthorized", nethttp.StatusUnauthorized)
+		return "", "", false
+	}
+	var courseID string
+	if err := db.QueryRowContext(r.Context(), `SELECT course_id, exam_id FROM exam_offerings WHERE id=$1`, offID).
+		Scan(&courseID, &examID); err != nil {
+		nethttp.Error(w, "not found", nethttp.StatusNotFound)
+		return "", "", false
+	}
+	if role != "admin" && !isCourseTeacher(db, sub, courseID) {
+		nethttp.Error(w, "forbidden", nethttp.StatusForbidden)
+		return "", "", false
 	}
+	return offID, examID, true
 }
 
-// Portable UPSERT for SQLite/Postgres (no GREATEST)
-func bumpEphemeral(db *sql.DB, offID, qid, bucket string, correct bool, auto, max float64) error {
-	now := time.Now().Unix()
-	cor := int64(0)
-	if correct {
-		cor = 1
+// ResetEphemeralStatsHandler clears an offering's ephemeral_stats, e.g.
+// before a link is reused for the next period. Teacher-auth, not the
+// access token: anyone holding the link could otherwise wipe the stats.
+// POST /api/offerings/{offeringID}/stats/reset
+func ResetEphemeralStatsHandler(db *sql.DB, authSvc *authmw.AuthService) nethttp.HandlerFunc {
+	return func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		offID, _, ok := teacherOffering(w, r, db, authSvc)
+		if !ok {
+			return
+		}
+		res, err := db.ExecContext(r.Context(), `DELETE FROM ephemeral_stats WHERE offering_id=$1`, offID)
+		if err != nil {
+			nethttp.Error(w, "db error", nethttp.StatusInternalServerError)
+			return
+		}
+		n, _ := res.RowsAffected()
+		respondJSON(w, nethttp.StatusOK, map[string]any{"offering_id": offID, "deleted": n})
+	}
+}
+
+// ephemeralBump is one ephemeral_stats row a graded response adds to.
+type ephemeralBump struct {
+	qid, bucket string
+	correct     bool
+	auto, max   float64
+}
+
+// bumpEphemeral applies one response's bumps in a single transaction, so
+// concurrent graders never see (or leave) a response half counted. The
+// UPSERT avoids GREATEST so it runs on both SQLite and Postgres.
+func bumpEphemeral(ctx context.Context, db *sql.DB, offID string, bumps []ephemeralBump) error {
+	if len(bumps) == 0 {
+		return nil
+	}
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
 	}
-	_, err := db.Exec(`
+	defer func() { _ = tx.Rollback() }()
+	stmt, err := tx.PrepareContext(ctx, `
 		INSERT INTO ephemeral_stats (offering_id, question_id, bucket, count, correct, sum_points, max_points, updated_at)
 		VALUES ($1,$2,$3,1,$4,$5,$6,$7)
 		ON CONFLICT (offering_id, question_id, bucket) DO UPDATE SET
@@ -476,8 +653,22 @@ func bumpEphemeral(db *sql.DB, offID, qid, bucket string, correct bool, auto, ma
 		  max_points = CASE WHEN ephemeral_stats.max_points > EXCLUDED.max_points
 		                    THEN ephemeral_stats.max_points ELSE EXCLUDED.max_points END,
 		  updated_at = EXCLUDED.updated_at
-	`, offID, qid, bucket, cor, auto, max, now)
-	return err
+	`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	now := time.Now().Unix()
+	for _, b := range bumps {
+		cor := int64(0)
+		if b.correct {
+			cor = 1
+		}
+		if _, err := stmt.ExecContext(ctx, offID, b.qid, b.bucket, cor, b.auto, b.max, now); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
 }
 
 func normText(s string) string {
@@ -593,31 +784,31 @@ func ShareOfferingLinkHandler(dbh *sql.DB, authSvc *authmw.AuthService) nethttp.
 			}
 		}
 
-		// Build student-facing base URL
-		base := strings.TrimRight(os.Getenv("STUDENT_BASE"), "/")
-		if base == "" {
-			// derive from scheme+host, ignore path (works even if teacher is served at /teacher)
-			scheme := r.Header.Get("X-Forwarded-Proto")
-			if scheme == "" {
-				if r.TLS != nil {
-					scheme = "https"
-				} else {
-					scheme = "http"
-				}
-			}
-			base = scheme + "://" + r.Host
-		}
-
-		// Construct share URL (only returned as a whole, never the raw token field)
-		q := url.Values{}
-		q.Set("offering", offID)
-		q.Set("access_token", token.String)
-
-		shareURL := base + "/quiz/?" + q.Encode()
-
+		// Only returned as a whole URL, never the raw token field
 		w.Header().Set("Content-Type", "application/json")
 		_ = json.NewEncoder(w).Encode(map[string]string{
-			"share_url": shareURL,
+			"share_url": offeringShareURL(r, offID, token.String),
 		})
 	}
 }
+
+// offeringShareURL is the student-facing quiz link for a link offering.
+func offeringShareURL(r *nethttp.Request, offID, token string) string {
+	base := strings.TrimRight(os.Getenv("STUDENT_BASE"), "/")
+	if base == "" {
+		// derive from scheme+host, ignore path (works even if teacher is served at /teacher)
+		scheme := r.Header.Get("X-Forwarded-Proto")
+		if scheme == "" {
+			if r.TLS != nil {
+				scheme = "https"
+			} else {
+				scheme = "http"
+			}
+		}
+		base = scheme + "://" + r.Host + basepath.From(r.Context())
+	}
+	q := url.Values{}
+	q.Set("offering", offID)
+	q.Set("access_token", token)
+	return base + "/quiz/?" + q.Encode()
+}
@@ -4,10 +4,13 @@ package http
 import (
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"time"
 
 	"github.com/go-chi/chi/v5"
+	ex "github.com/mind-engage/mindengage-lms/internal/exam"
+	"github.com/mind-engage/mindengage-lms/internal/grading"
 )
 
 func ListPublicOfferingsHandler(db *sql.DB) http.HandlerFunc {
@@ -20,6 +23,7 @@ func ListPublicOfferingsHandler(db *sql.DB) http.HandlerFunc {
 		TimeLimitSec *int       `json:"time_limit_sec,omitempty"`
 		MaxAttempts  int        `json:"max_attempts"`
 		Visibility   string     `json:"visibility"`
+		localWindow
 	}
 	return func(w http.ResponseWriter, r *http.Request) {
 		now := time.Now().Unix()
@@ -27,6 +31,7 @@ func ListPublicOfferingsHandler(db *sql.DB) http.HandlerFunc {
 			SELECT id, course_id, exam_id, start_at, end_at, time_limit_sec, max_attempts, visibility
 			  FROM exam_offerings
 			 WHERE visibility='public'
+			   AND course_id IN (SELECT id FROM courses WHERE status='active')
 			   AND (start_at IS NULL OR start_at <= $1)
 			   AND (end_at   IS NULL OR end_at   >= $1)
 			 ORDER BY start_at NULLS FIRST, id
@@ -56,6 +61,7 @@ func ListPublicOfferingsHandler(db *sql.DB) http.HandlerFunc {
 				v := int(tls.Int64)
 				o.TimeLimitSec = &v
 			}
+			o.localWindow = localWindowFor(r, start, end)
 			out = append(out, o)
 		}
 		_ = json.NewEncoder(w).Encode(out)
@@ -71,6 +77,7 @@ func ListCoursePublicOfferingsHandler(db *sql.DB) http.HandlerFunc {
 		Visibility   string `json:"visibility"`
 		StartAtUnix  *int64 `json:"start_at,omitempty"`
 		EndAtUnix    *int64 `json:"end_at,omitempty"`
+		localWindow
 	}
 	return func(w http.ResponseWriter, r *http.Request) {
 		cid := chi.URLParam(r, "courseID")
@@ -80,6 +87,7 @@ func ListCoursePublicOfferingsHandler(db *sql.DB) http.HandlerFunc {
 			  FROM exam_offerings
 			 WHERE course_id = $1
 			   AND visibility = 'public'
+			   AND course_id IN (SELECT id FROM courses WHERE status='active')
 			   AND (start_at IS NULL OR start_at <= $2)
 			   AND (end_at   IS NULL OR end_at   >= $2)
 			 ORDER BY start_at NULLS FIRST, id
@@ -107,9 +115,114 @@ func ListCoursePublicOfferingsHandler(db *sql.DB) http.HandlerFunc {
 					v := int(tls.Int64)
 					o.TimeLimitSec = &v
 				}
+				o.localWindow = localWindowFor(r, start, end)
 				out = append(out, o)
 			}
 		}
 		_ = json.NewEncoder(w).Encode(out)
 	}
 }
+
+var (
+	errOfferingNotStarted = errors.New("not started")
+	errOfferingEnded      = errors.New("ended")
+)
+
+// loadPublicOffering loads an offering with visibility='public' in an active
+// course and checks its window. sql.ErrNoRows means "not public / not found".
+func loadPublicOffering(r *http.Request, db *sql.DB, offeringID string) (offeringResolveResp, error) {
+	var out offeringResolveResp
+	var start, end, tls sql.NullInt64
+	err := db.QueryRowContext(r.Context(), `
+		SELECT o.id, o.exam_id, o.course_id, o.start_at, o.end_at, o.time_limit_sec, o.max_attempts, o.visibility
+		  FROM exam_offerings o
+		  JOIN courses c ON c.id = o.course_id
+		 WHERE o.id = $1 AND o.visibility = 'public' AND c.status = 'active'
+	`, offeringID).Scan(&out.ID, &out.ExamID, &out.CourseID, &start, &end, &tls, &out.MaxAttempts, &out.Visibility)
+	if err != nil {
+		return out, err
+	}
+	if start.Valid {
+		t := time.Unix(start.Int64, 0).UTC()
+		out.StartAt = &t
+	}
+	if end.Valid {
+		t := time.Unix(end.Int64, 0).UTC()
+		out.EndAt = &t
+	}
+	if tls.Valid {
+		v := int(tls.Int64)
+		out.TimeLimitSec = &v
+	}
+	out.localWindow = localWindowFor(r, start, end)
+	out.State = offeringState(start, end, time.Now().UTC().Unix())
+	switch out.State {
+	case offeringNotStarted:
+		return out, errOfferingNotStarted
+	case offeringEnded:
+		return out, errOfferingEnded
+	}
+	return out, nil
+}
+
+// writePublicOfferingError maps loadPublicOffering errors to responses.
+func writePublicOfferingError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, errOfferingNotStarted), errors.Is(err, errOfferingEnded):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, sql.ErrNoRows):
+		http.Error(w, "not found", http.StatusNotFound)
+	default:
+		http.Error(w, "db error", http.StatusInternalServerError)
+	}
+}
+
+// GetPublicOfferingHandler resolves a visibility='public' offering without auth,
+// returning offering metadata + the student-safe exam. Like the link resolve,
+// a closed window is 200 with its state (and no questions), not an error.
+// GET /api/public/offerings/{offeringID}
+func GetPublicOfferingHandler(db *sql.DB, store ex.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		out, err := loadPublicOffering(r, db, chi.URLParam(r, "offeringID"))
+		if err != nil && !errors.Is(err, errOfferingNotStarted) && !errors.Is(err, errOfferingEnded) {
+			writePublicOfferingError(w, err)
+			return
+		}
+		if err := withResolvedExam(store, &out); err != nil {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		_ = json.NewEncoder(w).Encode(out)
+	}
+}
+
+// GradePublicEphemeralHandler is the anonymous counterpart of
+// GradeEphemeralHandler for public offerings: nothing is persisted except the
+// aggregate ephemeral_stats.
+// POST /api/public/offerings/{offeringID}/grade_ephemeral
+func GradePublicEphemeralHandler(db *sql.DB, store ex.Store, grader grading.Grader) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		off, err := loadPublicOffering(r, db, chi.URLParam(r, "offeringID"))
+		if err != nil {
+			writePublicOfferingError(w, err)
+			return
+		}
+		exam, err := store.GetExamAdmin(r.Context(), off.ExamID)
+		if err != nil {
+			http.Error(w, "exam not found", http.StatusNotFound)
+			return
+		}
+		var req EphemeralGradeReq
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "bad json", http.StatusBadRequest)
+			return
+		}
+		out := gradeEphemeral(r.Context(), db, grader, off.ID, exam, req.Responses, answerRevealFromQuery(r))
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(displayGrade(r.Context(), out))
+	}
+}
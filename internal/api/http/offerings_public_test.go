@@ -0,0 +1,407 @@
+package http_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	api "github.com/mind-engage/mindengage-lms/internal/api/http"
+	"github.com/mind-engage/mindengage-lms/internal/exam"
+	"github.com/mind-engage/mindengage-lms/internal/grading"
+)
+
+func TestPublicOffering_Window(t *testing.T) {
+	dbh := newTestDB(t)
+	store := exam.NewSQLStore(dbh, "sqlite", grading.NewDefaultGrader())
+	if err := store.PutExam(exam.Exam{
+		ID:        "e1",
+		Title:     "Public quiz",
+		Questions: []exam.Question{{ID: "q1", Type: "mcq_single", Points: 1, AnswerKey: []string{"a"}}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now().Unix()
+	for _, q := range []string{
+		fmt.Sprintf(`INSERT INTO exam_offerings (id, exam_id, course_id, assigned_by, start_at, end_at, visibility) VALUES ('open','e1','c1','t1',%d,%d,'public')`, now-60, now+3600),
+		fmt.Sprintf(`INSERT INTO exam_offerings (id, exam_id, course_id, assigned_by, start_at, visibility) VALUES ('future','e1','c1','t1',%d,'public')`, now+3600),
+		fmt.Sprintf(`INSERT INTO exam_offerings (id, exam_id, course_id, assigned_by, end_at, visibility) VALUES ('past','e1','c1','t1',%d,'public')`, now-60),
+		`INSERT INTO exam_offerings (id, exam_id, course_id, assigned_by, visibility) VALUES ('private','e1','c1','t1','course')`,
+	} {
+		if _, err := dbh.Exec(q); err != nil {
+			t.Fatalf("%s: %v", q, err)
+		}
+	}
+
+	r := chi.NewRouter()
+	r.Get("/public/offerings/{offeringID}", api.GetPublicOfferingHandler(dbh, store))
+	r.Post("/public/offerings/{offeringID}/grade_ephemeral", api.GradePublicEphemeralHandler(dbh, store, grading.NewDefaultGrader()))
+
+	t.Run("within window", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/public/offerings/open", nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d body=%s", rec.Code, rec.Body.String())
+		}
+		var got struct {
+			State string    `json:"state"`
+			Exam  exam.Exam `json:"exam"`
+		}
+		if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+			t.Fatal(err)
+		}
+		if got.State != "active" || len(got.Exam.Questions) != 1 {
+			t.Fatalf("got %+v", got)
+		}
+		if len(got.Exam.Questions[0].AnswerKey) != 0 {
+			t.Fatal("answer key leaked in public exam")
+		}
+	})
+
+	for id, want := range map[string]string{"future": "not_started", "past": "ended"} {
+		t.Run(id, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/public/offerings/"+id, nil))
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d, want 200", rec.Code)
+			}
+			var got struct {
+				State string    `json:"state"`
+				Exam  exam.Exam `json:"exam"`
+			}
+			if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+				t.Fatal(err)
+			}
+			if got.State != want || got.Exam.Title != "Public quiz" || len(got.Exam.Questions) != 0 {
+				t.Fatalf("got state %q, exam %+v; want %s with the title but no questions", got.State, got.Exam, want)
+			}
+		})
+	}
+	for id, want := range map[string]int{
+		"private": http.StatusNotFound,
+		"missing": http.StatusNotFound,
+	} {
+		t.Run(id, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/public/offerings/"+id, nil))
+			if rec.Code != want {
+				t.Fatalf("status = %d, want %d", rec.Code, want)
+			}
+		})
+	}
+
+	t.Run("anonymous grade", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/public/offerings/open/grade_ephemeral",
+			strings.NewReader(`{"responses":{"q1":"a"}}`)))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d body=%s", rec.Code, rec.Body.String())
+		}
+		var got api.EphemeralGradeResp
+		if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+			t.Fatal(err)
+		}
+		if got.Score != 1 || got.ScoreMax != 1 {
+			t.Fatalf("score = %v/%v, want 1/1", got.Score, got.ScoreMax)
+		}
+
+		rec = httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/public/offerings/past/grade_ephemeral",
+			strings.NewReader(`{"responses":{}}`)))
+		if rec.Code != http.StatusForbidden {
+			t.Fatalf("closed offering grade status = %d, want 403", rec.Code)
+		}
+	})
+}
+
+func TestOfferings_TenantTimeZone(t *testing.T) {
+	dbh := newTestDB(t)
+	store := exam.NewSQLStore(dbh, "sqlite", grading.NewDefaultGrader())
+	if err := store.PutExam(exam.Exam{ID: "e1", Title: "Quiz", Questions: []exam.Question{{ID: "q1", Type: "mcq_single", Points: 1}}}); err != nil {
+		t.Fatal(err)
+	}
+	start, end := time.Now().Add(-time.Hour).Truncate(time.Second), time.Now().Add(time.Hour).Truncate(time.Second)
+	if _, err := dbh.Exec(`INSERT INTO exam_offerings (id, exam_id, course_id, assigned_by, start_at, end_at, visibility) VALUES ('open','e1','c1','t1',$1,$2,'public')`,
+		start.Unix(), end.Unix()); err != nil {
+		t.Fatal(err)
+	}
+	loc, err := time.LoadLocation("Asia/Kolkata") // +05:30, no DST
+	if err != nil {
+		t.Skip("no tzdata:", err)
+	}
+
+	r := chi.NewRouter()
+	r.Use(api.TimeZone(loc))
+	r.Get("/public/offerings/{offeringID}", api.GetPublicOfferingHandler(dbh, store))
+	r.Get("/public/offerings", api.ListPublicOfferingsHandler(dbh))
+
+	type window struct {
+		StartAt      time.Time `json:"start_at"`
+		TimeZone     string    `json:"time_zone"`
+		StartAtLocal string    `json:"start_at_local"`
+		EndAtLocal   string    `json:"end_at_local"`
+	}
+	check := func(name string, w window) {
+		t.Helper()
+		if w.TimeZone != "Asia/Kolkata" {
+			t.Errorf("%s: time_zone = %q", name, w.TimeZone)
+		}
+		if want := start.In(loc).Format(time.RFC3339); w.StartAtLocal != want || !strings.HasSuffix(w.StartAtLocal, "+05:30") {
+			t.Errorf("%s: start_at_local = %q, want %q", name, w.StartAtLocal, want)
+		}
+		if want := end.In(loc).Format(time.RFC3339); w.EndAtLocal != want {
+			t.Errorf("%s: end_at_local = %q, want %q", name, w.EndAtLocal, want)
+		}
+		if !w.StartAt.Equal(start) || w.StartAt.Location() != time.UTC {
+			t.Errorf("%s: start_at = %v, want it unchanged in UTC", name, w.StartAt)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/public/offerings/open", nil))
+	var one window
+	if err := json.NewDecoder(rec.Body).Decode(&one); err != nil {
+		t.Fatal(err)
+	}
+	check("get", one)
+
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/public/offerings", nil))
+	var list []window
+	if err := json.NewDecoder(rec.Body).Decode(&list); err != nil || len(list) != 1 {
+		t.Fatalf("list: %v %d", err, len(list))
+	}
+	check("list", list[0])
+}
+
+func TestGradeEphemeral_FeedbackByTag(t *testing.T) {
+	dbh := newTestDB(t)
+	store := exam.NewSQLStore(dbh, "sqlite", grading.NewDefaultGrader())
+	if err := store.PutExam(exam.Exam{
+		ID:        "e1",
+		Title:     "Tagged quiz",
+		PolicyRaw: json.RawMessage(`{"feedback":{"reveal_tags":["Easy","practice"],"hide_tags":["final"],"show_answers":"always"}}`),
+		Questions: []exam.Question{
+			{ID: "easy", Type: "short_word", Points: 1, AnswerKey: []string{"paris"}, Tags: []string{"easy"}},
+			{ID: "hard", Type: "short_word", Points: 1, AnswerKey: []string{"lyon"}, Tags: []string{"hard"}},
+			{ID: "both", Type: "short_word", Points: 1, AnswerKey: []string{"nice"}, Tags: []string{"practice", "final"}},
+			{ID: "none", Type: "essay", Points: 1},
+		},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now().Unix()
+	if _, err := dbh.Exec(fmt.Sprintf(`INSERT INTO exam_offerings (id, exam_id, course_id, assigned_by, start_at, end_at, visibility) VALUES ('open','e1','c1','t1',%d,%d,'public')`, now-60, now+3600)); err != nil {
+		t.Fatal(err)
+	}
+	r := chi.NewRouter()
+	r.Post("/public/offerings/{offeringID}/grade_ephemeral", api.GradePublicEphemeralHandler(dbh, store, grading.NewDefaultGrader()))
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/public/offerings/open/grade_ephemeral?show_answers=1",
+		strings.NewReader(`{"responses":{"easy":"paris","hard":"paris","both":"nice","none":"because"}}`)))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d body=%s", rec.Code, rec.Body.String())
+	}
+	var got api.EphemeralGradeResp
+	if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	reveals := map[string]bool{"easy": true, "hard": false, "both": false, "none": false}
+	for _, it := range got.Items {
+		if want := reveals[it.QuestionID]; (len(it.CorrectAnswer) > 0) != want {
+			t.Errorf("%s: correct_answer = %v, want revealed %v", it.QuestionID, it.CorrectAnswer, want)
+		}
+		if !reveals[it.QuestionID] && len(it.Feedback) > 0 {
+			t.Errorf("%s: feedback %v shown for a hidden question", it.QuestionID, it.Feedback)
+		}
+	}
+	// Hiding feedback does not change the score.
+	if got.Score != 2 || got.ScoreMax != 4 {
+		t.Fatalf("score = %v/%v, want 2/4", got.Score, got.ScoreMax)
+	}
+}
+
+func TestGradeEphemeral_PartialFeedback(t *testing.T) {
+	dbh := newTestDB(t)
+	store := exam.NewSQLStore(dbh, "sqlite", grading.NewDefaultGrader())
+	if err := store.PutExam(exam.Exam{
+		ID:        "e1",
+		Title:     "Multi quiz",
+		PolicyRaw: json.RawMessage(`{"feedback":{"hide_tags":["final"],"show_answers":"always"}}`),
+		Questions: []exam.Question{
+			{ID: "multi", Type: "mcq_multi", Points: 2, AnswerKey: []string{"a", "c"}},
+			{ID: "single", Type: "mcq_single", Points: 1, AnswerKey: []string{"b"}},
+			{ID: "hidden", Type: "mcq_multi", Points: 1, AnswerKey: []string{"a"}, Tags: []string{"final"}},
+		},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now().Unix()
+	if _, err := dbh.Exec(fmt.Sprintf(`INSERT INTO exam_offerings (id, exam_id, course_id, assigned_by, start_at, end_at, visibility) VALUES ('open','e1','c1','t1',%d,%d,'public')`, now-60, now+3600)); err != nil {
+		t.Fatal(err)
+	}
+	r := chi.NewRouter()
+	r.Post("/public/offerings/{offeringID}/grade_ephemeral", api.GradePublicEphemeralHandler(dbh, store, grading.NewDefaultGrader()))
+	grade := func(query string) map[string]api.ItemResult {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/public/offerings/open/grade_ephemeral"+query,
+			strings.NewReader(`{"responses":{"multi":["a","b","d"],"single":"c","hidden":["a","b"]}}`)))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: status = %d body=%s", query, rec.Code, rec.Body.String())
+		}
+		var got api.EphemeralGradeResp
+		if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+			t.Fatal(err)
+		}
+		items := map[string]api.ItemResult{}
+		for _, it := range got.Items {
+			items[it.QuestionID] = it
+		}
+		return items
+	}
+
+	items := grade("?show_answers=partial")
+	for id, it := range items {
+		if it.Correct {
+			t.Errorf("%s: correct, want incorrect", id)
+		}
+		if len(it.CorrectAnswer) > 0 {
+			t.Errorf("%s: partial mode leaked the key %v", id, it.CorrectAnswer)
+		}
+	}
+	if got := items["multi"].WrongChoices; fmt.Sprint(got) != "[b d]" {
+		t.Errorf("multi: wrong_choices = %v, want [b d]", got)
+	}
+	if got := items["single"].WrongChoices; len(got) != 0 {
+		t.Errorf("single: wrong_choices = %v, want none", got)
+	}
+	if got := items["hidden"].WrongChoices; len(got) != 0 {
+		t.Errorf("hidden: wrong_choices = %v shown for a hidden question", got)
+	}
+
+	for id, it := range grade("") {
+		if len(it.WrongChoices) > 0 || len(it.CorrectAnswer) > 0 {
+			t.Errorf("%s: no show_answers, got wrong_choices %v, key %v", id, it.WrongChoices, it.CorrectAnswer)
+		}
+	}
+	if full := grade("?show_answers=1")["multi"]; fmt.Sprint(full.CorrectAnswer) != "[a c]" || fmt.Sprint(full.WrongChoices) != "[b d]" {
+		t.Errorf("full: got key %v, wrong_choices %v", full.CorrectAnswer, full.WrongChoices)
+	}
+}
+
+func TestGradeEphemeral_ShowAnswersNeedsPolicy(t *testing.T) {
+	dbh := newTestDB(t)
+	store := exam.NewSQLStore(dbh, "sqlite", grading.NewDefaultGrader())
+	r := chi.NewRouter()
+	r.Post("/offerings/{offeringID}/grade_ephemeral", api.GradeEphemeralHandler(dbh, store, grading.NewDefaultGrader()))
+
+	for i, tc := range []struct {
+		policy      string
+		wantKey     bool
+		wantChoices bool
+	}{
+		{policy: ``},
+		{policy: `{"feedback":{"show_answers":"never"}}`},
+		{policy: `{"feedback":{"show_answers":"partial"}}`, wantChoices: true},
+		{policy: `{"feedback":{"show_answers":"always"}}`, wantKey: true, wantChoices: true},
+	} {
+		id := fmt.Sprintf("e%d", i)
+		if err := store.PutExam(exam.Exam{
+			ID:        id,
+			Title:     "Quiz",
+			PolicyRaw: json.RawMessage(tc.policy),
+			Questions: []exam.Question{{ID: "q1", Type: "mcq_multi", Points: 1, AnswerKey: []string{"a", "c"}}},
+		}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := dbh.Exec(`INSERT INTO exam_offerings (id, exam_id, course_id, assigned_by, visibility, access_token) VALUES ($1,$2,'c1','t1','link',$1)`, "off-"+id, id); err != nil {
+			t.Fatal(err)
+		}
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/offerings/off-"+id+"/grade_ephemeral?show_answers=1&access_token=off-"+id,
+			strings.NewReader(`{"responses":{"q1":["a","b"]}}`)))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("policy %s: status = %d body=%s", tc.policy, rec.Code, rec.Body.String())
+		}
+		var got api.EphemeralGradeResp
+		if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+			t.Fatal(err)
+		}
+		it := got.Items[0]
+		if (len(it.CorrectAnswer) > 0) != tc.wantKey {
+			t.Errorf("policy %s: correct_answer = %v, want revealed %v", tc.policy, it.CorrectAnswer, tc.wantKey)
+		}
+		if (len(it.WrongChoices) > 0) != tc.wantChoices {
+			t.Errorf("policy %s: wrong_choices = %v, want shown %v", tc.policy, it.WrongChoices, tc.wantChoices)
+		}
+	}
+}
+
+func TestResolveOfferingByToken_States(t *testing.T) {
+	dbh := newTestDB(t)
+	store := exam.NewSQLStore(dbh, "sqlite", grading.NewDefaultGrader())
+	if err := store.PutExam(exam.Exam{
+		ID:        "e1",
+		Title:     "Link quiz",
+		Questions: []exam.Question{{ID: "q1", Type: "mcq_single", Points: 1, AnswerKey: []string{"a"}}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now().Unix()
+	for _, q := range []string{
+		fmt.Sprintf(`INSERT INTO exam_offerings (id, exam_id, course_id, assigned_by, start_at, visibility, access_token) VALUES ('soon','e1','c1','t1',%d,'link','tok-soon')`, now+3600),
+		fmt.Sprintf(`INSERT INTO exam_offerings (id, exam_id, course_id, assigned_by, start_at, end_at, visibility, access_token) VALUES ('open','e1','c1','t1',%d,%d,'link','tok-open')`, now-60, now+3600),
+		fmt.Sprintf(`INSERT INTO exam_offerings (id, exam_id, course_id, assigned_by, end_at, visibility, access_token) VALUES ('over','e1','c1','t1',%d,'link','tok-over')`, now-60),
+	} {
+		if _, err := dbh.Exec(q); err != nil {
+			t.Fatalf("%s: %v", q, err)
+		}
+	}
+	r := chi.NewRouter()
+	r.Get("/offerings/{offeringID}/resolve", api.GetOfferingByTokenHandler(dbh, store))
+	resolve := func(id, tok string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/offerings/"+id+"/resolve?access_token="+tok, nil))
+		return rec
+	}
+
+	for id, c := range map[string]struct {
+		state     string
+		questions int
+	}{
+		"soon": {"not_started", 0},
+		"open": {"active", 1},
+		"over": {"ended", 0},
+	} {
+		rec := resolve(id, "tok-"+id)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: status = %d body=%s", id, rec.Code, rec.Body.String())
+		}
+		var got struct {
+			State string `json:"state"`
+			Exam  struct {
+				Title     string            `json:"title"`
+				Questions []json.RawMessage `json:"questions"`
+			} `json:"exam"`
+		}
+		if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+			t.Fatal(err)
+		}
+		if got.State != c.state || got.Exam.Title != "Link quiz" || got.Exam.Questions == nil || len(got.Exam.Questions) != c.questions {
+			t.Errorf("%s: state %q, exam %q with %d questions; want %s with %d", id, got.State, got.Exam.Title, len(got.Exam.Questions), c.state, c.questions)
+		}
+	}
+
+	for _, c := range []struct{ id, tok string }{{"open", "tok-soon"}, {"nope", "tok-open"}} {
+		if rec := resolve(c.id, c.tok); rec.Code != http.StatusNotFound {
+			t.Errorf("%s with token %q: status = %d, want 404", c.id, c.tok, rec.Code)
+		}
+	}
+}
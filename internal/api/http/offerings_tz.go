@@ -0,0 +1,50 @@
+package http
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"time"
+)
+
+type timeZoneKey struct{}
+
+// TimeZone sets the tenant time zone offering windows are rendered in, next
+// to the UTC start_at/end_at (see localWindow).
+func TimeZone(loc *time.Location) func(http.Handler) http.Handler {
+	if loc == nil {
+		loc = time.UTC
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), timeZoneKey{}, loc)))
+		})
+	}
+}
+
+func timeZoneFrom(ctx context.Context) *time.Location {
+	if loc, ok := ctx.Value(timeZoneKey{}).(*time.Location); ok {
+		return loc
+	}
+	return time.UTC
+}
+
+// localWindow is embedded in offering responses: the window again, as
+// RFC3339 with the tenant's UTC offset, plus the zone name.
+type localWindow struct {
+	TimeZone     string `json:"time_zone"`
+	StartAtLocal string `json:"start_at_local,omitempty"`
+	EndAtLocal   string `json:"end_at_local,omitempty"`
+}
+
+func localWindowFor(r *http.Request, start, end sql.NullInt64) localWindow {
+	loc := timeZoneFrom(r.Context())
+	lw := localWindow{TimeZone: loc.String()}
+	if start.Valid {
+		lw.StartAtLocal = time.Unix(start.Int64, 0).In(loc).Format(time.RFC3339)
+	}
+	if end.Valid {
+		lw.EndAtLocal = time.Unix(end.Int64, 0).In(loc).Format(time.RFC3339)
+	}
+	return lw
+}
@@ -4,14 +4,20 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"os"
+	"path"
+	"path/filepath"
 	"strings"
 	"time"
 
 	"github.com/go-chi/chi/v5"
 
+	"github.com/mind-engage/mindengage-lms/internal/basepath"
 	"github.com/mind-engage/mindengage-lms/internal/exam"
 	"github.com/mind-engage/mindengage-lms/internal/qti"
 	"github.com/mind-engage/mindengage-lms/internal/qti/export"
@@ -19,73 +25,255 @@ import (
 	"github.com/mind-engage/mindengage-lms/internal/storage"
 )
 
-// POST /qti/import (multipart: file=package.zip)
+// POST /qti/import[?source=canvas] (multipart: file=package.zip)
 func ImportQTIHandler(store exam.Store, bs storage.BlobStore) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		f, hdr, err := r.FormFile("file")
+		base, filename, status, err := unzipUpload(r)
 		if err != nil {
-			http.Error(w, "file required", 400)
+			http.Error(w, err.Error(), status)
 			return
 		}
-		defer f.Close()
+		defer os.RemoveAll(base)
 
-		// read into temp to get ReaderAt+size for unzip
-		tmp, err := os.CreateTemp("", "qti-upload-*")
+		pkg, err := mapQTIPackage(base, strings.ToLower(r.URL.Query().Get("source")))
 		if err != nil {
-			http.Error(w, err.Error(), 500)
+			http.Error(w, err.Error(), 400)
 			return
 		}
-		defer os.Remove(tmp.Name())
-		if _, err := io.Copy(tmp, f); err != nil {
-			http.Error(w, err.Error(), 500)
+		ex, warnings := pkg.exam, pkg.warnings
+		// Validate before any media lands in the blob store.
+		if err := exam.ValidateQuestions(ex.Questions); err != nil {
+			http.Error(w, err.Error(), 400)
 			return
 		}
-		info, _ := tmp.Stat()
-		if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		if bs != nil {
+			assets := newPackageAssets(r, base, ex.ID, bs)
+			warnings = append(warnings, qti.RewriteMedia(&ex, pkg.items, assets.url)...)
+		}
+
+		if err := store.PutExam(ex); err != nil {
 			http.Error(w, err.Error(), 500)
 			return
 		}
+		out := map[string]any{"exam_id": ex.ID, "filename": filename}
+		if len(warnings) > 0 {
+			out["warnings"] = warnings
+		}
+		_ = json.NewEncoder(w).Encode(out)
+	}
+}
 
-		base, err := parser.UnzipToTemp(tmp, info.Size())
+// POST /qti/import-package[?source=canvas] (multipart: file=package.zip)
+// Like /qti/import, but also copies every media file declared in
+// imsmanifest.xml into the blob store under qti/{examID}/, not only the ones
+// items reference.
+func ImportQTIPackageHandler(store exam.Store, bs storage.BlobStore) http.HandlerFunc {
+	type asset struct {
+		Path string `json:"path"`
+		Key  string `json:"key"`
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		base, filename, status, err := unzipUpload(r)
 		if err != nil {
-			http.Error(w, "unzip: "+err.Error(), 400)
+			http.Error(w, err.Error(), status)
 			return
 		}
 		defer os.RemoveAll(base)
 
-		mf, itemFiles, err := parser.ParseManifest(base)
+		pkg, err := mapQTIPackage(base, strings.ToLower(r.URL.Query().Get("source")))
 		if err != nil {
-			http.Error(w, "manifest: "+err.Error(), 400)
+			http.Error(w, err.Error(), 400)
+			return
+		}
+		ex, warnings := pkg.exam, pkg.warnings
+		if err := exam.ValidateQuestions(ex.Questions); err != nil {
+			http.Error(w, err.Error(), 400)
 			return
 		}
 
-		parsed := []parser.ParsedItem{}
-		for _, rel := range itemFiles {
-			it, err := parser.ParseItemFile(base, rel)
-			if err != nil {
-				continue
-			} // skip unsupported for MVP
-			parsed = append(parsed, it)
+		assets := newPackageAssets(r, base, ex.ID, bs)
+		warnings = append(warnings, qti.RewriteMedia(&ex, pkg.items, assets.url)...)
+		for _, rel := range parser.MediaFiles(pkg.manifest) {
+			if _, err := assets.put(rel); err != nil {
+				warnings = append(warnings, "media "+rel+": "+err.Error())
+			}
 		}
 
-		// media rewrite: in MVP we just pass through prompt HTML (assets stay inside package)
-		ex, err := qti.MapToExam(mf, parsed, qti.NoopRewrite)
-		if err != nil {
+		if err := store.PutExam(ex); err != nil {
 			http.Error(w, err.Error(), 500)
 			return
 		}
+		stored := []asset{}
+		for _, rel := range assets.order {
+			stored = append(stored, asset{Path: rel, Key: assets.keys[rel]})
+		}
+		out := map[string]any{
+			"exam_id":  ex.ID,
+			"filename": filename,
+			"items":    len(ex.Questions),
+			"assets":   stored,
+		}
+		if len(warnings) > 0 {
+			out["warnings"] = warnings
+		}
+		_ = json.NewEncoder(w).Encode(out)
+	}
+}
+
+// packageAssets copies files out of an unzipped package into the blob store,
+// once each, under qti/{examID}/.
+type packageAssets struct {
+	base, prefix string
+	urlBase      string // gateway base path the asset URLs start with
+	bs           storage.BlobStore
+	keys         map[string]string // rel -> blob key
+	order        []string
+}
 
-		// give imported exam a stable ID if none supplied
-		if ex.ID == "" {
-			ex.ID = "exam-" + time.Now().Format("20060102150405")
+func newPackageAssets(r *http.Request, base, examID string, bs storage.BlobStore) *packageAssets {
+	return &packageAssets{base: base, prefix: path.Join("qti", examID), urlBase: basepath.From(r.Context()), bs: bs, keys: map[string]string{}}
+}
+
+func (p *packageAssets) put(rel string) (string, error) {
+	if key, ok := p.keys[rel]; ok {
+		return key, nil
+	}
+	f, err := openPackageFile(p.base, rel)
+	if err != nil {
+		return "", errors.New("not found in package")
+	}
+	defer f.Close()
+	key, err := p.bs.Put(path.Join(p.prefix, rel), f)
+	if err != nil {
+		return "", err
+	}
+	p.keys[rel] = key
+	p.order = append(p.order, rel)
+	return key, nil
+}
+
+// url is a qti.AssetResolver: the stored file as served by GET /api/assets/*.
+func (p *packageAssets) url(rel string) (string, error) {
+	key, err := p.put(rel)
+	if err != nil {
+		return "", err
+	}
+	return (&url.URL{Path: p.urlBase + "/api/assets/" + filepath.ToSlash(key)}).EscapedPath(), nil
+}
+
+// unzipUpload extracts the multipart "file" zip into a temp dir the caller
+// must remove. On error, status is the HTTP status to report.
+func unzipUpload(r *http.Request) (base, filename string, status int, err error) {
+	f, hdr, err := r.FormFile("file")
+	if err != nil {
+		return "", "", 400, errors.New("file required")
+	}
+	defer f.Close()
+
+	// read into temp to get ReaderAt+size for unzip
+	tmp, err := os.CreateTemp("", "qti-upload-*")
+	if err != nil {
+		return "", "", 500, err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+	size, err := io.Copy(tmp, f)
+	if err != nil {
+		return "", "", 500, err
+	}
+
+	base, err = parser.UnzipToTemp(tmp, size)
+	if err != nil {
+		return "", "", 400, fmt.Errorf("unzip: %w", err)
+	}
+	return base, hdr.Filename, 0, nil
+}
+
+// openPackageFile opens a manifest path, which some exporters URL-encode.
+// Both the raw and the decoded path must stay inside the package.
+func openPackageFile(base, rel string) (*os.File, error) {
+	p, err := parser.PackagePath(base, rel)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(p)
+	if err != nil {
+		if dec, derr := url.PathUnescape(rel); derr == nil && dec != rel {
+			if p, err = parser.PackagePath(base, dec); err != nil {
+				return nil, err
+			}
+			return os.Open(p)
 		}
+	}
+	return f, err
+}
 
-		if err := store.PutExam(ex); err != nil {
-			http.Error(w, err.Error(), 500)
-			return
+// qtiPackage is a mapped package plus what media rewriting needs.
+type qtiPackage struct {
+	exam     exam.Exam
+	items    []parser.ParsedItem
+	manifest parser.Manifest
+	warnings []string
+}
+
+// mapQTIPackage turns an unzipped package into an exam. source "canvas"
+// switches to the tolerant QTI 1.2 reader for Canvas quiz exports.
+func mapQTIPackage(base, source string) (qtiPackage, error) {
+	mf, itemFiles, err := parser.ParseManifest(base)
+	if err != nil {
+		return qtiPackage{}, fmt.Errorf("manifest: %w", err)
+	}
+	pkg := qtiPackage{manifest: mf, warnings: []string{}}
+
+	if source == "canvas" {
+		var quiz parser.CanvasQuiz
+		for _, rel := range parser.CanvasQuizFiles(mf) {
+			q, err := parser.ParseCanvasFile(base, rel)
+			if err != nil {
+				pkg.warnings = append(pkg.warnings, fmt.Sprintf("%s: %v", rel, err))
+				continue
+			}
+			if quiz.Title == "" {
+				quiz.Title = q.Title
+			}
+			quiz.Items = append(quiz.Items, q.Items...)
 		}
-		_ = json.NewEncoder(w).Encode(map[string]string{"exam_id": ex.ID, "filename": hdr.Filename})
+		if len(quiz.Items) == 0 {
+			return qtiPackage{}, errors.New("no canvas quiz items found")
+		}
+		pkg.items = quiz.Items
+		pkg.exam, err = qti.MapCanvasToExam(mf, quiz, qti.NoopRewrite)
+	} else {
+		for _, rel := range itemFiles {
+			it, err := parser.ParseItemFile(base, rel)
+			if err != nil {
+				continue
+			} // skip unsupported for MVP
+			pkg.items = append(pkg.items, it)
+		}
+		if len(mf.Tests) > 0 {
+			// multi-section package: the (first) assessmentTest drives order and modules
+			t, terr := parser.ParseTestFile(base, mf.Tests[0])
+			if terr != nil {
+				return qtiPackage{}, fmt.Errorf("assessmentTest: %w", terr)
+			}
+			pkg.exam, err = qti.MapTestToExam(mf, t, pkg.items, qti.NoopRewrite)
+		} else {
+			pkg.exam, err = qti.MapToExam(mf, pkg.items, qti.NoopRewrite)
+		}
+	}
+	if err != nil {
+		return qtiPackage{}, err
+	}
+	for _, it := range pkg.items {
+		pkg.warnings = append(pkg.warnings, it.Warnings...)
+	}
+
+	// give imported exam a stable ID if none supplied
+	if pkg.exam.ID == "" {
+		pkg.exam.ID = "exam-" + time.Now().Format("20060102150405")
 	}
+	return pkg, nil
 }
 
 // GET /exams/{id}/export?format=qti
@@ -0,0 +1,716 @@
+package http_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+
+	api "github.com/mind-engage/mindengage-lms/internal/api/http"
+	"github.com/mind-engage/mindengage-lms/internal/exam"
+	"github.com/mind-engage/mindengage-lms/internal/grading"
+	"github.com/mind-engage/mindengage-lms/internal/storage"
+)
+
+func importQTI(t *testing.T, r http.Handler, path, filename string, pkg []byte) *httptest.ResponseRecorder {
+	t.Helper()
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	fw, err := mw.CreateFormFile("file", filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fw.Write(pkg)
+	mw.Close()
+	req := httptest.NewRequest(http.MethodPost, path, &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestQTI_AssessmentTestRoundTrip(t *testing.T) {
+	dbh := newTestDB(t)
+	store := exam.NewSQLStore(dbh, "sqlite", grading.NewDefaultGrader())
+	src := exam.Exam{
+		ID:           "two-part",
+		Title:        "Two Part",
+		TimeLimitSec: 1500,
+		PolicyRaw: json.RawMessage(`{"sections":[
+			{"id":"rw","title":"Reading","modules":[{"id":"rw-m1","time_limit_sec":600},{"id":"rw-m2","time_limit_sec":300}]},
+			{"id":"math","title":"Math","modules":[{"id":"math-m1","time_limit_sec":600}]}],
+			"navigation":{"allow_back":false,"module_locked":true}}`),
+		Questions: []exam.Question{
+			{ID: "m1", Type: "short_word", PromptHTML: "<p>2+2?</p>", AnswerKey: []string{"4"}, Points: 1, SectionID: "math", ModuleID: "math-m1"},
+			{ID: "r1", Type: "mcq_single", PromptHTML: "<p>Pick A</p>", Choices: []exam.Choice{{ID: "A", LabelHTML: "a"}, {ID: "B", LabelHTML: "b"}}, AnswerKey: []string{"A"}, Points: 1, SectionID: "rw", ModuleID: "rw-m1"},
+			{ID: "r2", Type: "essay", PromptHTML: "<p>Explain</p>", Points: 2, SectionID: "rw", ModuleID: "rw-m2"},
+		},
+	}
+	if err := store.PutExam(src); err != nil {
+		t.Fatal(err)
+	}
+
+	r := chi.NewRouter()
+	r.Post("/qti/import", api.ImportQTIHandler(store, nil))
+	r.Get("/exams/{id}/export", api.ExportQTIHandler(store))
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/exams/two-part/export?format=qti", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("export status = %d body=%s", rec.Code, rec.Body.String())
+	}
+
+	rec = importQTI(t, r, "/qti/import", "two-part.zip", rec.Body.Bytes())
+	if rec.Code != http.StatusOK {
+		t.Fatalf("import status = %d body=%s", rec.Code, rec.Body.String())
+	}
+	var out struct {
+		ExamID string `json:"exam_id"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&out); err != nil {
+		t.Fatal(err)
+	}
+	got, err := store.GetExamAdmin(context.Background(), out.ExamID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got.Title != "Two Part" || got.TimeLimitSec != 1500 {
+		t.Fatalf("title/time = %q/%d", got.Title, got.TimeLimitSec)
+	}
+	var pol struct {
+		Sections []struct {
+			ID      string `json:"id"`
+			Title   string `json:"title"`
+			Modules []struct {
+				ID           string `json:"id"`
+				TimeLimitSec int    `json:"time_limit_sec"`
+			} `json:"modules"`
+		} `json:"sections"`
+		Navigation struct {
+			AllowBack    bool `json:"allow_back"`
+			ModuleLocked bool `json:"module_locked"`
+		} `json:"navigation"`
+	}
+	if err := json.Unmarshal(got.PolicyRaw, &pol); err != nil {
+		t.Fatalf("policy: %v (%s)", err, got.PolicyRaw)
+	}
+	if len(pol.Sections) != 2 || pol.Sections[0].ID != "rw" || pol.Sections[1].ID != "math" {
+		t.Fatalf("sections = %+v", pol.Sections)
+	}
+	if pol.Sections[0].Title != "Reading" || len(pol.Sections[0].Modules) != 2 {
+		t.Fatalf("rw section = %+v", pol.Sections[0])
+	}
+	if m := pol.Sections[0].Modules[1]; m.ID != "rw-m2" || m.TimeLimitSec != 300 {
+		t.Fatalf("rw-m2 = %+v", m)
+	}
+	if m := pol.Sections[1].Modules[0]; m.ID != "math-m1" || m.TimeLimitSec != 600 {
+		t.Fatalf("math-m1 = %+v", m)
+	}
+	if pol.Navigation.AllowBack || !pol.Navigation.ModuleLocked {
+		t.Fatalf("navigation = %+v", pol.Navigation)
+	}
+
+	// Questions come back in test order with their module assignment.
+	want := []struct{ id, sec, mod string }{{"r1", "rw", "rw-m1"}, {"r2", "rw", "rw-m2"}, {"m1", "math", "math-m1"}}
+	if len(got.Questions) != len(want) {
+		t.Fatalf("questions = %+v", got.Questions)
+	}
+	for i, w := range want {
+		q := got.Questions[i]
+		if q.ID != w.id || q.SectionID != w.sec || q.ModuleID != w.mod {
+			t.Fatalf("question %d = %s %s/%s, want %+v", i, q.ID, q.SectionID, q.ModuleID, w)
+		}
+	}
+	if got.Questions[0].AnswerKey[0] != "A" || len(got.Questions[0].Choices) != 2 {
+		t.Fatalf("r1 = %+v", got.Questions[0])
+	}
+}
+
+func zipFiles(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, body := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.WriteString(w, body)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+const mapResponseItem = `<?xml version="1.0" encoding="UTF-8"?>
+<assessmentItem identifier="primes" title="Primes" xmlns="http://www.imsglobal.org/xsd/imsqti_v2p1">
+  <responseDeclaration identifier="RESPONSE" cardinality="multiple" baseType="identifier">
+    <correctResponse><value>A</value><value>B</value></correctResponse>
+    <mapping defaultValue="0" lowerBound="0" upperBound="2">
+      <mapEntry mapKey="A" mappedValue="1"/>
+      <mapEntry mapKey="B" mappedValue="1"/>
+      <mapEntry mapKey="C" mappedValue="-1"/>
+    </mapping>
+  </responseDeclaration>
+  <itemBody>
+    <p>Which are prime?</p>
+    <choiceInteraction responseIdentifier="RESPONSE" maxChoices="0">
+      <simpleChoice identifier="A">2</simpleChoice>
+      <simpleChoice identifier="B">3</simpleChoice>
+      <simpleChoice identifier="C">4</simpleChoice>
+    </choiceInteraction>
+  </itemBody>
+  <responseProcessing template="http://www.imsglobal.org/question/qti_v2p1/rptemplates/map_response"/>
+</assessmentItem>`
+
+const oddTemplateItem = `<?xml version="1.0" encoding="UTF-8"?>
+<assessmentItem identifier="word" title="Word" xmlns="http://www.imsglobal.org/xsd/imsqti_v2p1">
+  <responseDeclaration identifier="RESPONSE" cardinality="single" baseType="string">
+    <correctResponse><value>paris</value></correctResponse>
+  </responseDeclaration>
+  <itemBody><p>Capital of France?</p><textEntryInteraction responseIdentifier="RESPONSE"/></itemBody>
+  <responseProcessing template="http://example.com/rptemplates/fancy_rule"/>
+</assessmentItem>`
+
+const twoItemManifest = `<?xml version="1.0" encoding="UTF-8"?>
+<manifest xmlns="http://www.imsglobal.org/xsd/imscp_v1p1">
+  <resources>
+    <resource identifier="primes" type="imsqti_item_xmlv2p1" href="primes.xml"><file href="primes.xml"/></resource>
+    <resource identifier="word" type="imsqti_item_xmlv2p1" href="word.xml"><file href="word.xml"/></resource>
+  </resources>
+</manifest>`
+
+func TestQTI_ImportResponseProcessing(t *testing.T) {
+	dbh := newTestDB(t)
+	store := exam.NewSQLStore(dbh, "sqlite", grading.NewDefaultGrader())
+	r := chi.NewRouter()
+	r.Post("/qti/import", api.ImportQTIHandler(store, nil))
+
+	rec := importQTI(t, r, "/qti/import", "rp.zip", zipFiles(t, map[string]string{
+		"imsmanifest.xml": twoItemManifest,
+		"primes.xml":      mapResponseItem,
+		"word.xml":        oddTemplateItem,
+	}))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("import status = %d body=%s", rec.Code, rec.Body.String())
+	}
+	var out struct {
+		ExamID   string   `json:"exam_id"`
+		Warnings []string `json:"warnings"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&out); err != nil {
+		t.Fatal(err)
+	}
+	if len(out.Warnings) != 1 || !strings.Contains(out.Warnings[0], "fancy_rule") {
+		t.Fatalf("warnings = %v", out.Warnings)
+	}
+
+	ex, err := store.GetExamAdmin(context.Background(), out.ExamID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	byID := map[string]exam.Question{}
+	for _, q := range ex.Questions {
+		byID[q.ID] = q
+	}
+	primes := byID["primes"]
+	if primes.Scoring == nil || primes.Scoring.Mode != "map" || primes.Points != 2 {
+		t.Fatalf("primes = %+v", primes)
+	}
+	if w := primes.Scoring.Weights; w["A"] != 1 || w["B"] != 1 || w["C"] != -1 {
+		t.Fatalf("weights = %v", w)
+	}
+	if word := byID["word"]; word.Scoring == nil || word.Scoring.Mode != "exact" {
+		t.Fatalf("word scoring = %+v", word.Scoring)
+	}
+
+	// Partial weights drive grading: A alone is 1/2, A+C nets zero.
+	g := grading.NewDefaultGrader()
+	for _, tc := range []struct {
+		resp []string
+		want float64
+	}{{[]string{"A"}, 1}, {[]string{"A", "B"}, 2}, {[]string{"A", "C"}, 0}, {[]string{"A", "B", "C"}, 1}} {
+		res, err := g.Grade(context.Background(), primes.GradingQ(), tc.resp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.AutoPoints != tc.want {
+			t.Errorf("%v scored %v, want %v", tc.resp, res.AutoPoints, tc.want)
+		}
+	}
+	// Exact fallback: no fuzzy half-credit for a near miss.
+	res, _ := g.Grade(context.Background(), byID["word"].GradingQ(), "pariss")
+	if res.AutoPoints != 0 {
+		t.Fatalf("near miss scored %v under exact match", res.AutoPoints)
+	}
+}
+
+// Trimmed from a Canvas quiz export: QTI 1.2, href-less manifest resource,
+// entity-escaped HTML, a misspelt <matextmat> and old-style qmd_ metadata.
+const canvasManifest = `<?xml version="1.0" encoding="UTF-8"?>
+<manifest identifier="g1" xmlns="http://www.imsglobal.org/xsd/imsccv1p1/imscp_v1p1">
+  <metadata><schema>IMS Content</schema><schemaversion>1.1.3</schemaversion></metadata>
+  <resources>
+    <resource identifier="g7f" type="imsqti_xmlv1p2">
+      <file href="g7f/g7f.xml"/>
+      <dependency identifierref="g8a"/>
+    </resource>
+    <resource identifier="g8a" type="associatedcontent/imscc_xmlv1p1/learning-application-resource" href="g7f/assessment_meta.xml">
+      <file href="g7f/assessment_meta.xml"/>
+    </resource>
+  </resources>
+</manifest>`
+
+const canvasQuiz = `<?xml version="1.0" encoding="UTF-8"?>
+<questestinterop xmlns="http://www.imsglobal.org/xsd/ims_qtiasiv1p2">
+  <assessment ident="g7f" title="Week 1 Quiz">
+    <qtimetadata>
+      <qtimetadatafield><fieldlabel>cc_maxattempts</fieldlabel><fieldentry>1</fieldentry></qtimetadatafield>
+    </qtimetadata>
+    <section ident="root_section">
+      <item ident="i1" title="Question">
+        <itemmetadata><qtimetadata>
+          <qtimetadatafield><fieldlabel>question_type</fieldlabel><fieldentry>multiple_choice_question</fieldentry></qtimetadatafield>
+          <qtimetadatafield><fieldlabel>points_possible</fieldlabel><fieldentry>2.0</fieldentry></qtimetadatafield>
+        </qtimetadata></itemmetadata>
+        <presentation>
+          <material><mattext texttype="text/html">&lt;p&gt;2 + 2 = ?&lt;img src="$IMS-CC-FILEBASE$/Uploaded%20Media/sum.png"&gt;&lt;/p&gt;</mattext></material>
+          <response_lid ident="response1" rcardinality="Single">
+            <render_choice>
+              <response_label ident="7001"><material><mattext texttype="text/plain">3</mattext></material></response_label>
+              <response_label ident="7002"><material><mattext texttype="text/plain">4</mattext></material></response_label>
+            </render_choice>
+          </response_lid>
+        </presentation>
+        <resprocessing>
+          <outcomes><decvar maxvalue="100" minvalue="0" varname="SCORE" vartype="Decimal"/></outcomes>
+          <respcondition continue="No">
+            <conditionvar><varequal respident="response1">7002</varequal></conditionvar>
+            <setvar action="Set" varname="SCORE">100</setvar>
+          </respcondition>
+        </resprocessing>
+      </item>
+      <item ident="i2" title="Question">
+        <itemmetadata><qtimetadata>
+          <qtimetadatafield><fieldlabel>question_type</fieldlabel><fieldentry>multiple_answers_question</fieldentry></qtimetadatafield>
+        </qtimetadata></itemmetadata>
+        <presentation>
+          <material><matextmat>Pick the primes</matextmat></material>
+          <response_lid ident="response1" rcardinality="Multiple">
+            <render_choice>
+              <response_label ident="a"><material><mattext>2</mattext></material></response_label>
+              <response_label ident="b"><material><mattext>3</mattext></material></response_label>
+              <response_label ident="c"><material><mattext>4</mattext></material></response_label>
+            </render_choice>
+          </response_lid>
+        </presentation>
+        <resprocessing>
+          <respcondition continue="No">
+            <conditionvar><and>
+              <varequal respident="response1">a</varequal>
+              <varequal respident="response1">b</varequal>
+              <not><varequal respident="response1">c</varequal></not>
+            </and></conditionvar>
+            <setvar action="Set" varname="SCORE">100</setvar>
+          </respcondition>
+        </resprocessing>
+      </item>
+      <item ident="i3" title="Question">
+        <itemmetadata><qtimetadata>
+          <qtimetadatafield><fieldlabel>qmd_itemtype</fieldlabel><fieldentry>Fill in the Blank</fieldentry></qtimetadatafield>
+          <qtimetadatafield><fieldlabel>qmd_weighting</fieldlabel><fieldentry>3</fieldentry></qtimetadatafield>
+        </qtimetadata></itemmetadata>
+        <presentation>
+          <material><mattext>Capital of France?</mattext></material>
+          <response_str ident="response1" rcardinality="Single"><render_fib><response_label ident="answer1" rshuffle="No"/></render_fib></response_str>
+        </presentation>
+        <resprocessing>
+          <respcondition continue="No">
+            <conditionvar><varequal respident="response1">Paris</varequal></conditionvar>
+            <setvar action="Set" varname="SCORE">100</setvar>
+          </respcondition>
+        </resprocessing>
+      </item>
+      <item ident="i4" title="Question">
+        <itemmetadata><qtimetadata>
+          <qtimetadatafield><fieldlabel>question_type</fieldlabel><fieldentry>calculated_question</fieldentry></qtimetadatafield>
+        </qtimetadata></itemmetadata>
+        <presentation><material><mattext>x = [a] + 1</mattext></material></presentation>
+      </item>
+    </section>
+  </assessment>
+</questestinterop>`
+
+func TestQTI_ImportCanvas(t *testing.T) {
+	dbh := newTestDB(t)
+	store := exam.NewSQLStore(dbh, "sqlite", grading.NewDefaultGrader())
+	r := chi.NewRouter()
+	r.Post("/qti/import", api.ImportQTIHandler(store, nil))
+	pkg := zipFiles(t, map[string]string{
+		"imsmanifest.xml":                      canvasManifest,
+		"g7f/g7f.xml":                          canvasQuiz,
+		"g7f/assessment_meta.xml":              `<quiz identifier="g7f"><title>Week 1 Quiz</title></quiz>`,
+		"web_resources/Uploaded Media/sum.png": "png",
+	})
+
+	// Without the flag the QTI 2.x reader finds nothing usable.
+	rec := importQTI(t, r, "/qti/import", "canvas.zip", pkg)
+	var strict struct {
+		ExamID string `json:"exam_id"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&strict); err != nil {
+		t.Fatal(err)
+	}
+	if ex, err := store.GetExamAdmin(context.Background(), strict.ExamID); err != nil || len(ex.Questions) != 0 {
+		t.Fatalf("strict import: err=%v questions=%d", err, len(ex.Questions))
+	}
+
+	rec = importQTI(t, r, "/qti/import?source=canvas", "canvas.zip", pkg)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("import status = %d body=%s", rec.Code, rec.Body.String())
+	}
+	var out struct {
+		ExamID   string   `json:"exam_id"`
+		Warnings []string `json:"warnings"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&out); err != nil {
+		t.Fatal(err)
+	}
+	if len(out.Warnings) != 1 || !strings.Contains(out.Warnings[0], "calculated_question") {
+		t.Fatalf("warnings = %v", out.Warnings)
+	}
+	ex, err := store.GetExamAdmin(context.Background(), out.ExamID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ex.Title != "Week 1 Quiz" || len(ex.Questions) != 4 {
+		t.Fatalf("exam = %q with %d questions", ex.Title, len(ex.Questions))
+	}
+
+	q1, q2, q3, q4 := ex.Questions[0], ex.Questions[1], ex.Questions[2], ex.Questions[3]
+	if q1.Type != "mcq_single" || q1.Points != 2 || len(q1.Choices) != 2 || q1.Choices[1].LabelHTML != "4" ||
+		len(q1.AnswerKey) != 1 || q1.AnswerKey[0] != "7002" {
+		t.Fatalf("q1 = %+v", q1)
+	}
+	if !strings.HasPrefix(q1.PromptHTML, "<p>2 + 2 = ?") ||
+		!strings.Contains(q1.PromptHTML, `src="../web_resources/Uploaded%20Media/sum.png"`) {
+		t.Fatalf("q1 prompt = %q", q1.PromptHTML)
+	}
+	if q2.Type != "mcq_multi" || q2.PromptHTML != "Pick the primes" || strings.Join(q2.AnswerKey, ",") != "a,b" {
+		t.Fatalf("q2 = %+v", q2)
+	}
+	if q3.Type != "short_word" || q3.Points != 3 || len(q3.AnswerKey) != 1 || q3.AnswerKey[0] != "Paris" {
+		t.Fatalf("q3 = %+v", q3)
+	}
+	if q4.Type != "essay" {
+		t.Fatalf("q4 = %+v", q4)
+	}
+}
+
+func TestQTI_ImportPackageStoresMedia(t *testing.T) {
+	dbh := newTestDB(t)
+	store := exam.NewSQLStore(dbh, "sqlite", grading.NewDefaultGrader())
+	bs, err := storage.NewFSStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := chi.NewRouter()
+	r.Post("/qti/import-package", api.ImportQTIPackageHandler(store, bs))
+
+	manifest := `<?xml version="1.0" encoding="UTF-8"?>
+<manifest xmlns="http://www.imsglobal.org/xsd/imscp_v1p1">
+  <resources>
+    <resource identifier="primes" type="imsqti_item_xmlv2p1" href="items/primes.xml">
+      <file href="items/primes.xml"/><file href="media/chart.png"/>
+    </resource>
+    <resource identifier="word" type="imsqti_item_xmlv2p1" href="items/word.xml"><file href="items/word.xml"/></resource>
+    <resource identifier="gone" type="webcontent" href="media/missing.png"/>
+  </resources>
+</manifest>`
+	png := "\x89PNG\r\n\x1a\nfake"
+	rec := importQTI(t, r, "/qti/import-package", "pkg.zip", zipFiles(t, map[string]string{
+		"imsmanifest.xml":  manifest,
+		"items/primes.xml": mapResponseItem,
+		"items/word.xml":   oddTemplateItem,
+		"media/chart.png":  png,
+	}))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d body=%s", rec.Code, rec.Body.String())
+	}
+	var out struct {
+		ExamID string `json:"exam_id"`
+		Items  int    `json:"items"`
+		Assets []struct {
+			Path string `json:"path"`
+			Key  string `json:"key"`
+		} `json:"assets"`
+		Warnings []string `json:"warnings"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&out); err != nil {
+		t.Fatal(err)
+	}
+	if out.Items != 2 {
+		t.Fatalf("items = %d, want 2", out.Items)
+	}
+	if len(out.Assets) != 1 || out.Assets[0].Path != "media/chart.png" {
+		t.Fatalf("assets = %+v", out.Assets)
+	}
+	rc, err := bs.Get(out.Assets[0].Key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := io.ReadAll(rc)
+	rc.Close()
+	if string(b) != png {
+		t.Fatalf("stored media = %q", b)
+	}
+	missing := false
+	for _, w := range out.Warnings {
+		missing = missing || strings.Contains(w, "media/missing.png")
+	}
+	if !missing {
+		t.Fatalf("no warning for missing media: %v", out.Warnings)
+	}
+	if ex, err := store.GetExamAdmin(context.Background(), out.ExamID); err != nil || len(ex.Questions) != 2 {
+		t.Fatalf("exam: err=%v %+v", err, ex)
+	}
+}
+
+func TestQTI_ImportRewritesMedia(t *testing.T) {
+	dbh := newTestDB(t)
+	store := exam.NewSQLStore(dbh, "sqlite", grading.NewDefaultGrader())
+	bs, err := storage.NewFSStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := chi.NewRouter()
+	r.Post("/qti/import", api.ImportQTIHandler(store, bs))
+
+	item := `<?xml version="1.0" encoding="UTF-8"?>
+<assessmentItem identifier="pic" title="Pic" xmlns="http://www.imsglobal.org/xsd/imsqti_v2p1">
+  <responseDeclaration identifier="RESPONSE" cardinality="single"><correctResponse><value>A</value></correctResponse></responseDeclaration>
+  <itemBody>
+    <p>Read the chart <img src="../media/bar chart.png" alt="chart"/> and the <img src="gone.png"/> <a href="https://example.com/x.png">link</a></p>
+    <choiceInteraction responseIdentifier="RESPONSE" maxChoices="1">
+      <simpleChoice identifier="A"><img src='../media/bar%20chart.png'/></simpleChoice>
+      <simpleChoice identifier="B">none</simpleChoice>
+    </choiceInteraction>
+  </itemBody>
+</assessmentItem>`
+	rec := importQTI(t, r, "/qti/import", "pic.zip", zipFiles(t, map[string]string{
+		"imsmanifest.xml": `<manifest><resources>
+  <resource identifier="pic" type="imsqti_item_xmlv2p1" href="items/pic.xml"><file href="items/pic.xml"/></resource>
+</resources></manifest>`,
+		"items/pic.xml":       item,
+		"media/bar chart.png": "png-bytes",
+	}))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d body=%s", rec.Code, rec.Body.String())
+	}
+	var out struct {
+		ExamID   string   `json:"exam_id"`
+		Warnings []string `json:"warnings"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&out); err != nil {
+		t.Fatal(err)
+	}
+	if len(out.Warnings) != 1 || !strings.Contains(out.Warnings[0], "items/gone.png") {
+		t.Fatalf("warnings = %v", out.Warnings)
+	}
+
+	ex, err := store.GetExamAdmin(context.Background(), out.ExamID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := ex.Questions[0]
+	wantURL := "/api/assets/qti/" + out.ExamID + "/media/bar%20chart.png"
+	if !strings.Contains(q.PromptHTML, `src="`+wantURL+`"`) {
+		t.Fatalf("prompt not rewritten: %s", q.PromptHTML)
+	}
+	if !strings.Contains(q.PromptHTML, `src="gone.png"`) || !strings.Contains(q.PromptHTML, `href="https://example.com/x.png"`) {
+		t.Fatalf("missing/external refs should be untouched: %s", q.PromptHTML)
+	}
+	if q.Choices[0].LabelHTML != `<img src='`+wantURL+`'/>` {
+		t.Fatalf("choice label = %s", q.Choices[0].LabelHTML)
+	}
+
+	rc, err := bs.Get("qti/" + out.ExamID + "/media/bar chart.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := io.ReadAll(rc)
+	rc.Close()
+	if string(b) != "png-bytes" {
+		t.Fatalf("stored media = %q", b)
+	}
+}
+
+func TestQTI_ExportEscapesMapKeys(t *testing.T) {
+	dbh := newTestDB(t)
+	store := exam.NewSQLStore(dbh, "sqlite", grading.NewDefaultGrader())
+	const key = `R&D <"lab">`
+	if err := store.PutExam(exam.Exam{
+		ID:    "esc",
+		Title: "Escapes",
+		Questions: []exam.Question{{
+			ID: "w1", Type: "short_word", PromptHTML: "<p>Dept?</p>", AnswerKey: []string{key}, Points: 1,
+			Scoring: &exam.Scoring{Mode: "map", Weights: map[string]float64{key: 1, "rd": 0.5}},
+		}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	r := chi.NewRouter()
+	r.Post("/qti/import", api.ImportQTIHandler(store, nil))
+	r.Get("/exams/{id}/export", api.ExportQTIHandler(store))
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/exams/esc/export?format=qti", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("export status = %d body=%s", rec.Code, rec.Body.String())
+	}
+	rec = importQTI(t, r, "/qti/import", "esc.zip", rec.Body.Bytes())
+	if rec.Code != http.StatusOK {
+		t.Fatalf("import status = %d body=%s", rec.Code, rec.Body.String())
+	}
+	var out struct {
+		ExamID string `json:"exam_id"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&out); err != nil {
+		t.Fatal(err)
+	}
+	got, err := store.GetExamAdmin(context.Background(), out.ExamID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := got.Questions[0]
+	if len(q.AnswerKey) != 1 || q.AnswerKey[0] != key {
+		t.Fatalf("answer key = %q, want %q", q.AnswerKey, key)
+	}
+	if q.Scoring == nil || q.Scoring.Weights[key] != 1 || q.Scoring.Weights["rd"] != 0.5 {
+		t.Fatalf("scoring = %+v", q.Scoring)
+	}
+}
+
+func TestQTI_ImportRejectsPathTraversal(t *testing.T) {
+	dbh := newTestDB(t)
+	store := exam.NewSQLStore(dbh, "sqlite", grading.NewDefaultGrader())
+	bs, err := storage.NewFSStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := chi.NewRouter()
+	r.Post("/qti/import-package", api.ImportQTIPackageHandler(store, bs))
+
+	// Packages unzip into os.TempDir()/qti-*, so "../<name>" reaches these.
+	outside := func(pattern, body string) string {
+		f, err := os.CreateTemp("", pattern)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { os.Remove(f.Name()) })
+		f.WriteString(body)
+		f.Close()
+		return filepath.Base(f.Name())
+	}
+	item := outside("qti-secret-*.xml", mapResponseItem)
+	media := outside("qti-secret-*.png", "secret")
+
+	manifest := `<?xml version="1.0" encoding="UTF-8"?>
+<manifest xmlns="http://www.imsglobal.org/xsd/imscp_v1p1">
+  <resources>
+    <resource identifier="word" type="imsqti_item_xmlv2p1" href="word.xml"><file href="word.xml"/></resource>
+    <resource identifier="up" type="imsqti_item_xmlv2p1" href="../` + item + `"/>
+    <resource identifier="enc" type="imsqti_item_xmlv2p1" href="..%2F` + item + `"/>
+    <resource identifier="img" type="webcontent" href="../` + media + `"/>
+    <resource identifier="img2" type="webcontent" href="sub/..%2F..%2F` + media + `"/>
+  </resources>
+</manifest>`
+	rec := importQTI(t, r, "/qti/import-package", "evil.zip", zipFiles(t, map[string]string{
+		"imsmanifest.xml": manifest,
+		"word.xml":        oddTemplateItem,
+	}))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d body=%s", rec.Code, rec.Body.String())
+	}
+	var out struct {
+		ExamID string `json:"exam_id"`
+		Items  int    `json:"items"`
+		Assets []struct {
+			Path string `json:"path"`
+		} `json:"assets"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&out); err != nil {
+		t.Fatal(err)
+	}
+	if out.Items != 1 || len(out.Assets) != 0 {
+		t.Fatalf("items = %d assets = %+v; want only word.xml imported", out.Items, out.Assets)
+	}
+	ex, err := store.GetExamAdmin(context.Background(), out.ExamID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ex.Questions) != 1 || ex.Questions[0].ID != "word" {
+		t.Fatalf("questions = %+v", ex.Questions)
+	}
+}
+
+func TestQTI_InvalidImportStoresNoMedia(t *testing.T) {
+	dbh := newTestDB(t)
+	store := exam.NewSQLStore(dbh, "sqlite", grading.NewDefaultGrader())
+	blobDir := t.TempDir()
+	bs, err := storage.NewFSStore(blobDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := chi.NewRouter()
+	r.Post("/qti/import", api.ImportQTIHandler(store, bs))
+	r.Post("/qti/import-package", api.ImportQTIPackageHandler(store, bs))
+
+	// Two items with one identifier: the exam fails validation.
+	item := `<?xml version="1.0" encoding="UTF-8"?>
+<assessmentItem identifier="pic" title="Pic" xmlns="http://www.imsglobal.org/xsd/imsqti_v2p1">
+  <responseDeclaration identifier="RESPONSE" cardinality="single"><correctResponse><value>A</value></correctResponse></responseDeclaration>
+  <itemBody>
+    <p><img src="../media/chart.png"/></p>
+    <choiceInteraction responseIdentifier="RESPONSE" maxChoices="1">
+      <simpleChoice identifier="A">a</simpleChoice>
+    </choiceInteraction>
+  </itemBody>
+</assessmentItem>`
+	pkg := zipFiles(t, map[string]string{
+		"imsmanifest.xml": `<manifest><resources>
+  <resource identifier="p1" type="imsqti_item_xmlv2p1" href="items/p1.xml"><file href="items/p1.xml"/><file href="media/chart.png"/></resource>
+  <resource identifier="p2" type="imsqti_item_xmlv2p1" href="items/p2.xml"><file href="items/p2.xml"/></resource>
+</resources></manifest>`,
+		"items/p1.xml":    item,
+		"items/p2.xml":    item,
+		"media/chart.png": "png-bytes",
+	})
+	for _, path := range []string{"/qti/import", "/qti/import-package"} {
+		if rec := importQTI(t, r, path, "dup.zip", pkg); rec.Code != http.StatusBadRequest {
+			t.Fatalf("%s: status = %d, want 400 (%s)", path, rec.Code, rec.Body.String())
+		}
+	}
+	var stored []string
+	_ = filepath.Walk(blobDir, func(p string, fi os.FileInfo, err error) error {
+		if err == nil && !fi.IsDir() {
+			stored = append(stored, p)
+		}
+		return nil
+	})
+	if len(stored) != 0 {
+		t.Fatalf("rejected imports left blobs: %v", stored)
+	}
+}
@@ -0,0 +1,71 @@
+package http
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	authmw "github.com/mind-engage/mindengage-lms/internal/auth/middleware"
+	"github.com/mind-engage/mindengage-lms/internal/exam"
+)
+
+// POST /bank/questions: add a question to the bank, or replace the one with
+// the same id. Exams reference it by bank_id (see exam.Bank).
+func PutBankQuestionHandler(db *sql.DB, authSvc *authmw.AuthService) http.HandlerFunc {
+	bank := exam.NewBank(db)
+	return func(w http.ResponseWriter, r *http.Request) {
+		var q exam.Question
+		if err := decodeBody(r, &q); err != nil {
+			badJSON(w, err)
+			return
+		}
+		sub, _ := subjectAndRole(authSvc, r)
+		bq, err := bank.Put(r.Context(), q, sub)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(bq)
+	}
+}
+
+// GET /bank/questions?q=&tag=&type=&limit=&offset=
+func ListBankQuestionsHandler(db *sql.DB) http.HandlerFunc {
+	bank := exam.NewBank(db)
+	return func(w http.ResponseWriter, r *http.Request) {
+		qv := r.URL.Query()
+		limit, _ := strconv.Atoi(qv.Get("limit"))
+		offset, _ := strconv.Atoi(qv.Get("offset"))
+		out, err := bank.List(r.Context(), exam.BankQuery{
+			Q: qv.Get("q"), Tag: qv.Get("tag"), Type: qv.Get("type"), Limit: limit, Offset: offset,
+		})
+		if err != nil {
+			http.Error(w, "db error", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(out)
+	}
+}
+
+// GET /bank/questions/{questionID}
+func GetBankQuestionHandler(db *sql.DB) http.HandlerFunc {
+	bank := exam.NewBank(db)
+	return func(w http.ResponseWriter, r *http.Request) {
+		bq, err := bank.Get(r.Context(), chi.URLParam(r, "questionID"))
+		if errors.Is(err, exam.ErrBankQuestionNotFound) {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			http.Error(w, "db error", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(bq)
+	}
+}
@@ -0,0 +1,108 @@
+package http_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+
+	api "github.com/mind-engage/mindengage-lms/internal/api/http"
+	authmw "github.com/mind-engage/mindengage-lms/internal/auth/middleware"
+	"github.com/mind-engage/mindengage-lms/internal/exam"
+	"github.com/mind-engage/mindengage-lms/internal/grading"
+)
+
+func TestQuestionBank_ComposeExam(t *testing.T) {
+	dbh := newTestDB(t)
+	authSvc := authmw.NewAuthService("test")
+	store := exam.NewSQLStore(dbh, "sqlite", grading.NewDefaultGrader())
+	r := chi.NewRouter()
+	r.Post("/bank/questions", api.PutBankQuestionHandler(dbh, authSvc))
+	r.Get("/bank/questions", api.ListBankQuestionsHandler(dbh))
+	r.Get("/bank/questions/{questionID}", api.GetBankQuestionHandler(dbh))
+	r.Post("/exams", api.UploadExamHandler(store, dbh, authSvc))
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Authorization", bearer(t, authSvc, "t1", "teacher"))
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec
+	}
+
+	for _, body := range []string{
+		`{"id":"cap-fr","type":"short_word","prompt_html":"<p>Capital of France?</p>","answer_key":["paris"],"points":2,"tags":["Geography"]}`,
+		`{"id":"prime","type":"mcq_single","prompt_html":"<p>Pick the prime</p>","answer_key":["b"],"points":1,"tags":["math"],
+		  "choices":[{"id":"a","label_html":"4"},{"id":"b","label_html":"5"}]}`,
+	} {
+		if rec := do(http.MethodPost, "/bank/questions", body); rec.Code != http.StatusOK {
+			t.Fatalf("add bank question: status = %d body=%s", rec.Code, rec.Body.String())
+		}
+	}
+	if rec := do(http.MethodPost, "/bank/questions", `{"prompt_html":"no type"}`); rec.Code != http.StatusBadRequest {
+		t.Fatalf("bank question without type: status = %d, want 400", rec.Code)
+	}
+
+	var found []exam.BankQuestion
+	rec := do(http.MethodGet, "/bank/questions?tag=geography", "")
+	if err := json.NewDecoder(rec.Body).Decode(&found); err != nil {
+		t.Fatal(err)
+	}
+	if len(found) != 1 || found[0].ID != "cap-fr" || found[0].CreatedBy != "t1" {
+		t.Fatalf("tag search = %+v, want cap-fr by t1", found)
+	}
+	rec = do(http.MethodGet, "/bank/questions?q=PRIME", "")
+	if err := json.NewDecoder(rec.Body).Decode(&found); err != nil {
+		t.Fatal(err)
+	}
+	if len(found) != 1 || found[0].ID != "prime" {
+		t.Fatalf("text search = %+v, want prime", found)
+	}
+	if rec := do(http.MethodGet, "/bank/questions/nope", ""); rec.Code != http.StatusNotFound {
+		t.Fatalf("missing bank question: status = %d, want 404", rec.Code)
+	}
+
+	// Bank references mixed with an inline question; the second reference
+	// renames the question and overrides its points.
+	rec = do(http.MethodPost, "/exams", `{"id":"e1","title":"Composed","questions":[
+		{"bank_id":"cap-fr"},
+		{"id":"q-inline","type":"essay","prompt_html":"<p>Why?</p>","points":5},
+		{"bank_id":"prime","id":"q-prime","points":3}]}`)
+	if rec.Code != http.StatusOK && rec.Code != http.StatusCreated {
+		t.Fatalf("upload: status = %d body=%s", rec.Code, rec.Body.String())
+	}
+	ex, err := store.GetExamAdmin(context.Background(), "e1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ex.Questions) != 3 {
+		t.Fatalf("questions = %+v", ex.Questions)
+	}
+	q0, q1, q2 := ex.Questions[0], ex.Questions[1], ex.Questions[2]
+	if q0.ID != "cap-fr" || q0.BankID != "cap-fr" || q0.Type != "short_word" || q0.Points != 2 || len(q0.AnswerKey) != 1 {
+		t.Errorf("first = %+v, want the bank copy of cap-fr", q0)
+	}
+	if q1.ID != "q-inline" || q1.BankID != "" || q1.Type != "essay" {
+		t.Errorf("second = %+v, want the inline essay", q1)
+	}
+	if q2.ID != "q-prime" || q2.BankID != "prime" || q2.Points != 3 || len(q2.Choices) != 2 || q2.AnswerKey[0] != "b" {
+		t.Errorf("third = %+v, want prime as q-prime worth 3", q2)
+	}
+
+	// The exam keeps its copy when the bank question changes later.
+	if rec := do(http.MethodPost, "/bank/questions", `{"id":"cap-fr","type":"short_word","answer_key":["lyon"],"points":2}`); rec.Code != http.StatusOK {
+		t.Fatalf("edit bank question: status = %d", rec.Code)
+	}
+	if ex, _ := store.GetExamAdmin(context.Background(), "e1"); ex.Questions[0].AnswerKey[0] != "paris" {
+		t.Errorf("exam changed with the bank: %v", ex.Questions[0].AnswerKey)
+	}
+
+	rec = do(http.MethodPost, "/exams", `{"id":"e2","title":"Broken","questions":[{"bank_id":"missing"}]}`)
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "missing") {
+		t.Fatalf("unknown bank id: status = %d body=%s, want 400", rec.Code, rec.Body.String())
+	}
+}
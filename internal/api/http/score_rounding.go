@@ -0,0 +1,72 @@
+package http
+
+import (
+	"context"
+	"math"
+	"net/http"
+
+	"github.com/mind-engage/mindengage-lms/internal/exam"
+)
+
+type scoreDecimalsKey struct{}
+
+// ScoreDecimals rounds the scores and points handlers write (attempt
+// results, grading items, ephemeral grades) to n decimals, so sums such as
+// 2.9999999 read 3. Only responses are rounded: stored scores and the sums
+// built from them keep full precision. Without it, or with n < 0, scores
+// are written as computed.
+func ScoreDecimals(n int) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), scoreDecimalsKey{}, n)))
+		})
+	}
+}
+
+// scoreRounder returns the rounding ScoreDecimals set up for ctx.
+func scoreRounder(ctx context.Context) func(float64) float64 {
+	n, ok := ctx.Value(scoreDecimalsKey{}).(int)
+	if !ok || n < 0 {
+		return func(v float64) float64 { return v }
+	}
+	p := math.Pow(10, float64(n))
+	return func(v float64) float64 { return math.Round(v*p) / p }
+}
+
+// displayAttempt is a copy of a with its score rounded for the response.
+func displayAttempt(ctx context.Context, a exam.Attempt) exam.Attempt {
+	a.Score = scoreRounder(ctx)(a.Score)
+	return a
+}
+
+func displayAttempts(ctx context.Context, as []exam.Attempt) []exam.Attempt {
+	out := make([]exam.Attempt, len(as))
+	for i, a := range as {
+		out[i] = displayAttempt(ctx, a)
+	}
+	return out
+}
+
+func displayItems(ctx context.Context, items []exam.AttemptItem) []exam.AttemptItem {
+	round := scoreRounder(ctx)
+	out := make([]exam.AttemptItem, len(items))
+	for i, it := range items {
+		it.PointsMax, it.AutoPoints, it.ManualPoints = round(it.PointsMax), round(it.AutoPoints), round(it.ManualPoints)
+		out[i] = it
+	}
+	return out
+}
+
+// displayGrade rounds an ephemeral grade. Score and ScoreMax are rounded
+// sums of the exact points, not sums of the rounded ones.
+func displayGrade(ctx context.Context, g EphemeralGradeResp) EphemeralGradeResp {
+	round := scoreRounder(ctx)
+	g.Score, g.ScoreMax = round(g.Score), round(g.ScoreMax)
+	items := make([]ItemResult, len(g.Items))
+	for i, it := range g.Items {
+		it.Points, it.PointsMax = round(it.Points), round(it.PointsMax)
+		items[i] = it
+	}
+	g.Items = items
+	return g
+}
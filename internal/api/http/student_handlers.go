@@ -2,14 +2,20 @@ package http
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
+	"strings"
 
 	"github.com/mind-engage/mindengage-lms/internal/exam"
+	"github.com/mind-engage/mindengage-lms/internal/i18n"
 	"github.com/mind-engage/mindengage-lms/internal/rbac"
 
 	"github.com/go-chi/chi/v5"
 )
 
+// CreateAttemptHandler starts an attempt for the caller (the JWT subject).
+// user_id may be omitted; staff (attempt:view-all) may name another taker,
+// anyone else naming one gets 403.
 func CreateAttemptHandler(store exam.Store) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		var req struct {
@@ -20,11 +26,25 @@ func CreateAttemptHandler(store exam.Store) http.HandlerFunc {
 			http.Error(w, "bad json", 400)
 			return
 		}
+		sub := rbac.SubjectFromContext(r.Context())
+		if req.UserID == "" {
+			req.UserID = sub
+		}
 		if req.ExamID == "" || req.UserID == "" {
 			http.Error(w, "exam_id and user_id required", 400)
 			return
 		}
-		a, err := store.NewAttempt(req.ExamID, req.UserID)
+		if req.UserID != sub && !rbac.Can(rbac.RoleFromContext(r.Context()), "attempt:view-all") {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		// Resume the taker's in-progress attempt rather than starting a second
+		// one; ?restart=1 starts afresh if the exam policy sets allow_restart.
+		a, _, err := store.StartAttempt(r.Context(), exam.StartOpts{
+			ExamID: req.ExamID,
+			UserID: req.UserID,
+			Resume: r.URL.Query().Get("restart") != "1" || !allowsRestart(store, req.ExamID),
+		})
 		if err != nil {
 			http.Error(w, err.Error(), 400)
 			return
@@ -33,7 +53,45 @@ func CreateAttemptHandler(store exam.Store) http.HandlerFunc {
 	}
 }
 
+// allowsRestart reports whether the exam's policy has allow_restart set.
+func allowsRestart(store exam.Store, examID string) bool {
+	ex, err := store.GetExam(examID)
+	if err != nil || len(ex.PolicyRaw) == 0 {
+		return false
+	}
+	var p struct {
+		AllowRestart bool `json:"allow_restart"`
+	}
+	_ = json.Unmarshal(ex.PolicyRaw, &p)
+	return p.AllowRestart
+}
+
+// POST /attempts/{attemptID}/responses
+// Replies with the attempt plus saved_at, the server's save time in unix
+// milliseconds. With ?ack=short only the acknowledgment is sent, which is
+// all an autosave needs to show "last saved".
+//
+// With ?partial=1 a response that breaks an attempt rule (locked, outside
+// the module, unknown question) is left out rather than failing the batch;
+// the reply lists the saved ids under accepted and the others under
+// rejected, each with its rule's code and message.
 func SaveResponsesHandler(store exam.Store) http.HandlerFunc {
+	type saveOutcome struct {
+		Accepted []string             `json:"accepted,omitempty"`
+		Rejected map[string]rejection `json:"rejected,omitempty"`
+	}
+	type savedAttempt struct {
+		exam.Attempt
+		SavedAt int64 `json:"saved_at"`
+		saveOutcome
+	}
+	type shortAck struct {
+		AttemptID      string `json:"attempt_id"`
+		SavedAt        int64  `json:"saved_at"`
+		AnsweredCount  int    `json:"answered_count"`
+		TotalQuestions int    `json:"total_questions"`
+		saveOutcome
+	}
 	return func(w http.ResponseWriter, r *http.Request) {
 		id := chi.URLParam(r, "attemptID")
 		var resp map[string]interface{}
@@ -41,33 +99,74 @@ func SaveResponsesHandler(store exam.Store) http.HandlerFunc {
 			http.Error(w, "bad json", 400)
 			return
 		}
-		a, err := store.SaveResponses(id, resp)
-		if err != nil {
-			switch err {
-			case exam.ErrAttemptSubmitted, exam.ErrTimeOver, exam.ErrOutsideModule, exam.ErrEditBackBlocked:
-				http.Error(w, err.Error(), 409)
-			default:
-				http.Error(w, err.Error(), 400)
+		var (
+			a       exam.Attempt
+			outcome saveOutcome
+			err     error
+		)
+		if r.URL.Query().Get("partial") == "1" {
+			var rep exam.SaveReport
+			a, rep, err = store.SaveResponsesPartial(r.Context(), id, resp)
+			outcome.Accepted = rep.Accepted
+			if len(rep.Rejected) > 0 {
+				outcome.Rejected = make(map[string]rejection, len(rep.Rejected))
+				for qid, rerr := range rep.Rejected {
+					outcome.Rejected[qid] = newRejection(r, rerr)
+				}
 			}
+		} else {
+			a, err = store.SaveResponses(r.Context(), id, resp)
+		}
+		if err != nil {
+			writeAttemptError(w, r, err)
 			return
 		}
-		_ = json.NewEncoder(w).Encode(a)
+		if r.URL.Query().Get("ack") == "short" {
+			_ = json.NewEncoder(w).Encode(shortAck{
+				AttemptID:      a.ID,
+				SavedAt:        a.ResponsesUpdatedAt,
+				AnsweredCount:  a.AnsweredCount,
+				TotalQuestions: a.TotalQuestions,
+				saveOutcome:    outcome,
+			})
+			return
+		}
+		_ = json.NewEncoder(w).Encode(savedAttempt{Attempt: a, SavedAt: a.ResponsesUpdatedAt, saveOutcome: outcome})
 	}
 }
 
 func SubmitAttemptHandler(store exam.Store) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		id := chi.URLParam(r, "attemptID")
-		a, err := store.Submit(id)
+		a, err := store.Submit(r.Context(), id)
 		if err != nil {
-			if err == exam.ErrAttemptSubmitted {
-				http.Error(w, err.Error(), 409)
-			} else {
-				http.Error(w, err.Error(), 400)
-			}
+			writeAttemptError(w, r, err)
 			return
 		}
-		_ = json.NewEncoder(w).Encode(a)
+		_ = json.NewEncoder(w).Encode(displayAttempt(r.Context(), a))
+	}
+}
+
+// POST /attempts/{attemptID}/heartbeat: the exam page pings this while open so
+// proctors can spot takers whose browser went quiet. Returns the time left.
+func HeartbeatHandler(store exam.Store) http.HandlerFunc {
+	type resp struct {
+		Status           string `json:"status"`
+		RemainingSeconds int    `json:"remaining_seconds"`
+		LastSeenAt       int64  `json:"last_seen_at"`
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		a, err := store.Heartbeat(r.Context(), chi.URLParam(r, "attemptID"))
+		if errors.Is(err, exam.ErrAttemptNotFound) {
+			http.Error(w, "attempt not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			writeAttemptError(w, r, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(resp{Status: a.Status, RemainingSeconds: a.RemainingSeconds, LastSeenAt: a.LastSeenAt})
 	}
 }
 
@@ -79,7 +178,102 @@ func GetAttemptHandler(store exam.Store) http.HandlerFunc {
 			http.Error(w, err.Error(), 404)
 			return
 		}
-		_ = json.NewEncoder(w).Encode(a)
+		_ = json.NewEncoder(w).Encode(displayAttempt(r.Context(), takerView(r, a)))
+	}
+}
+
+// GET /attempts/{attemptID}/exam: the exam as this attempt was given it
+// (for blueprint exams, its own draw from the bank), without answer keys or
+// scoring overrides.
+func GetAttemptExamHandler(store exam.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ex, err := store.GetAttemptExam(r.Context(), chi.URLParam(r, "attemptID"))
+		if err != nil {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		for i := range ex.Questions {
+			ex.Questions[i].AnswerKey = nil
+			ex.Questions[i].Scoring = nil
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(ex)
+	}
+}
+
+// takerView withholds answers past the review window from callers who are
+// not staff: takers keep the score but not the answers.
+func takerView(r *http.Request, a exam.Attempt) exam.Attempt {
+	if role := rbac.RoleFromContext(r.Context()); a.ReviewClosed && role != "admin" && role != "teacher" {
+		a.Responses = map[string]interface{}{}
+	}
+	return a
+}
+
+// POST /attempts/batch {"ids": [...]}: many attempts in one round trip for
+// dashboards. Each attempt is checked like GET /attempts/{id} (owner or
+// attempt:view-all); ones the caller may not see are listed under "missing"
+// together with ids that do not exist, so the two cannot be told apart.
+func BatchGetAttemptsHandler(store exam.Store) http.HandlerFunc {
+	const maxBatch = 200
+	type reqBody struct {
+		IDs []string `json:"ids"`
+	}
+	type resp struct {
+		Attempts []exam.Attempt `json:"attempts"`
+		Missing  []string       `json:"missing"`
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req reqBody
+		if err := decodeBody(r, &req); err != nil {
+			badJSON(w, err)
+			return
+		}
+		if len(req.IDs) > maxBatch {
+			http.Error(w, "too many ids (max 200)", http.StatusRequestEntityTooLarge)
+			return
+		}
+		sub := rbac.SubjectFromContext(r.Context())
+		viewAll := rbac.Can(rbac.RoleFromContext(r.Context()), "attempt:view-all")
+
+		out := resp{Attempts: []exam.Attempt{}, Missing: []string{}}
+		seen := map[string]bool{}
+		for _, id := range req.IDs {
+			if seen[id] {
+				continue
+			}
+			seen[id] = true
+			a, err := store.GetAttempt(id)
+			if err != nil || !(viewAll || (sub != "" && a.UserID == sub)) {
+				out.Missing = append(out.Missing, id)
+				continue
+			}
+			out.Attempts = append(out.Attempts, displayAttempt(r.Context(), takerView(r, a)))
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(out)
+	}
+}
+
+// Access policy for offerings and attempts: a caller who has not shown they
+// may see a resource (wrong or missing access token, someone else's attempt,
+// an offering that is not link/public) gets 404, exactly as if it did not
+// exist. 403 is kept for callers who have proven access but may not act right
+// now (window not open yet / closed) or whose role lacks the permission
+// regardless of which resource they name.
+
+// RequireAttemptOwner lets only the attempt's owner through; anyone else gets
+// 404 so attempt IDs cannot be probed.
+func RequireAttemptOwner(store exam.Store) func(http.Handler) http.Handler {
+	isOwner := IsAttemptOwner(store)
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !isOwner(r) {
+				http.Error(w, "not found", http.StatusNotFound)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
 	}
 }
 
@@ -96,12 +290,22 @@ func IsAttemptOwner(store exam.Store) func(*http.Request) bool {
 	}
 }
 
+// POST /attempts/{attemptID}/next-module {"from_module_index": n}
+// n is the module the client is leaving; if the attempt has already moved
+// on (a double click, a second tab) the reply is 409 module_moved.
 func NextModuleHandler(store exam.Store) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		id := chi.URLParam(r, "attemptID")
-		a, err := store.AdvanceModule(id) // new method
+		var req struct {
+			FromModuleIndex *int `json:"from_module_index"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.FromModuleIndex == nil {
+			http.Error(w, "from_module_index required", 400)
+			return
+		}
+		a, err := store.AdvanceModule(id, *req.FromModuleIndex)
 		if err != nil {
-			http.Error(w, err.Error(), 400)
+			writeAttemptError(w, r, err)
 			return
 		}
 		_ = json.NewEncoder(w).Encode(a)
@@ -122,14 +326,56 @@ func NavigateHandler(store exam.Store) http.HandlerFunc {
 		}
 		a, err := store.Navigate(id, req.Target)
 		if err != nil {
-			switch err {
-			case exam.ErrAttemptSubmitted, exam.ErrOutsideModule, exam.ErrBackwardNavBlocked, exam.ErrEditBackBlocked, exam.ErrTimeOver:
-				http.Error(w, err.Error(), 409) // conflict semantics
-			default:
-				http.Error(w, err.Error(), 400)
-			}
+			writeAttemptError(w, r, err)
 			return
 		}
 		_ = json.NewEncoder(w).Encode(a)
 	}
 }
+
+// attemptRuleErrors: the attempt rules a taker can run into, with their
+// status (409 conflict semantics; 422 for unknown question ids) and message code.
+var attemptRuleErrors = []struct {
+	err    error
+	status int
+	code   i18n.Code
+}{
+	{exam.ErrAttemptSubmitted, 409, i18n.AttemptSubmitted},
+	{exam.ErrTimeOver, 409, i18n.TimeOver},
+	{exam.ErrOutsideModule, 409, i18n.OutsideModule},
+	{exam.ErrBackwardNavBlocked, 409, i18n.BackwardNavBlocked},
+	{exam.ErrEditBackBlocked, 409, i18n.EditBackBlocked},
+	{exam.ErrModuleCompleted, 409, i18n.ModuleCompleted},
+	{exam.ErrModuleMoved, 409, i18n.ModuleMoved},
+	{exam.ErrNoAttemptsLeft, 409, i18n.NoAttemptsLeft},
+	{exam.ErrUnknownQuestion, http.StatusUnprocessableEntity, i18n.UnknownQuestion},
+}
+
+// rejection is why one response of a partial save was not stored.
+type rejection struct {
+	Code  i18n.Code `json:"code"`
+	Error string    `json:"error"`
+}
+
+func newRejection(r *http.Request, err error) rejection {
+	for _, e := range attemptRuleErrors {
+		if errors.Is(err, e.err) {
+			return rejection{Code: e.code, Error: i18n.Message(i18n.Lang(r.Context()), e.code)}
+		}
+	}
+	return rejection{Error: err.Error()}
+}
+
+// writeAttemptError reports a store error from an attempt write, localized
+// for the request language; anything that is not an attempt rule is a 400.
+func writeAttemptError(w http.ResponseWriter, r *http.Request, err error) {
+	for _, e := range attemptRuleErrors {
+		if errors.Is(err, e.err) {
+			// keep wrapped detail, e.g. `unknown question id: "q9"`
+			detail := strings.TrimPrefix(strings.TrimPrefix(err.Error(), e.err.Error()), ": ")
+			i18n.Error(w, r, e.status, e.code, detail)
+			return
+		}
+	}
+	http.Error(w, err.Error(), 400)
+}
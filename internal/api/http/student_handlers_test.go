@@ -0,0 +1,577 @@
+package http_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	api "github.com/mind-engage/mindengage-lms/internal/api/http"
+	authmw "github.com/mind-engage/mindengage-lms/internal/auth/middleware"
+	"github.com/mind-engage/mindengage-lms/internal/exam"
+	"github.com/mind-engage/mindengage-lms/internal/grading"
+	"github.com/mind-engage/mindengage-lms/internal/rbac"
+)
+
+func TestSaveResponses_UnknownQuestion(t *testing.T) {
+	dbh := newTestDB(t)
+	store := exam.NewSQLStore(dbh, "sqlite", grading.NewDefaultGrader())
+	if err := store.PutExam(exam.Exam{
+		ID:    "e1",
+		Title: "Quiz",
+		Questions: []exam.Question{
+			{ID: "q1", Type: "mcq_single", Points: 1, AnswerKey: []string{"a"}},
+			{ID: "q2", Type: "mcq_single", Points: 1, AnswerKey: []string{"b"}},
+		},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	a, err := store.NewAttempt(context.Background(), "e1", "s1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := chi.NewRouter()
+	r.Post("/attempts/{attemptID}/responses", api.SaveResponsesHandler(store))
+	save := func(body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/attempts/"+a.ID+"/responses", strings.NewReader(body)))
+		return rec
+	}
+
+	rec := save(`{"q1":"a","junk":"x"}`)
+	if rec.Code != http.StatusUnprocessableEntity || !strings.Contains(rec.Body.String(), `"junk"`) {
+		t.Fatalf("unknown id: status = %d body=%q, want 422 naming the id", rec.Code, rec.Body.String())
+	}
+	rec = save(`{"q1":"a","q2":"b"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("known ids: status = %d body=%s", rec.Code, rec.Body.String())
+	}
+	var got exam.Attempt
+	if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Responses) != 2 || got.Responses["junk"] != nil {
+		t.Fatalf("responses = %v, want only q1 and q2", got.Responses)
+	}
+}
+
+func TestGetAttempt_ReviewPeriod(t *testing.T) {
+	ctx := context.Background()
+	dbh := newTestDB(t)
+	authSvc := authmw.NewAuthService("test")
+	store := exam.NewSQLStore(dbh, "sqlite", grading.NewDefaultGrader())
+	t0 := time.Unix(1_700_000_000, 0)
+	clock := t0
+	store.Now = func() time.Time { return clock }
+	if err := store.PutExam(exam.Exam{
+		ID:        "e1",
+		Title:     "Quiz",
+		PolicyRaw: []byte(`{"review_period_sec":300}`),
+		Questions: []exam.Question{{ID: "q1", Type: "mcq_single", Points: 1, AnswerKey: []string{"a"}}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	a, err := store.NewAttempt(ctx, "e1", "s1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.SaveResponses(ctx, a.ID, map[string]interface{}{"q1": "a"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Submit(ctx, a.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	r := chi.NewRouter()
+	r.With(authmw.JWTMiddleware(authSvc)).Get("/attempts/{attemptID}", api.GetAttemptHandler(store))
+	get := func(sub, role string) exam.Attempt {
+		req := httptest.NewRequest(http.MethodGet, "/attempts/"+a.ID, nil)
+		req.Header.Set("Authorization", bearer(t, authSvc, sub, role))
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d body=%s", rec.Code, rec.Body.String())
+		}
+		var got exam.Attempt
+		if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+			t.Fatal(err)
+		}
+		return got
+	}
+
+	clock = t0.Add(100 * time.Second)
+	got := get("s1", "student")
+	if got.SubmittedAt != t0.Unix() || got.ReviewUntil != t0.Unix()+300 || got.ReviewClosed {
+		t.Fatalf("in review: submitted_at=%d review_until=%d closed=%v", got.SubmittedAt, got.ReviewUntil, got.ReviewClosed)
+	}
+	if got.Responses["q1"] != "a" {
+		t.Fatalf("in review: responses = %v, want the submitted answer", got.Responses)
+	}
+
+	clock = t0.Add(301 * time.Second)
+	got = get("s1", "student")
+	if !got.ReviewClosed || len(got.Responses) != 0 {
+		t.Fatalf("after review: closed=%v responses=%v, want answers withheld", got.ReviewClosed, got.Responses)
+	}
+	if got.Score != 1 {
+		t.Fatalf("after review: score = %v, want it kept", got.Score)
+	}
+	if got = get("t1", "teacher"); got.Responses["q1"] != "a" {
+		t.Fatalf("teacher after review: responses = %v, want them shown", got.Responses)
+	}
+}
+
+func TestBatchGetAttempts_MixedOwnership(t *testing.T) {
+	dbh := newTestDB(t)
+	authSvc := authmw.NewAuthService("test")
+	store := exam.NewSQLStore(dbh, "sqlite", grading.NewDefaultGrader())
+	if err := store.PutExam(exam.Exam{
+		ID:        "e1",
+		Title:     "Quiz",
+		Questions: []exam.Question{{ID: "q1", Type: "mcq_single", Points: 1, AnswerKey: []string{"a"}}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	mine, err := store.NewAttempt(context.Background(), "e1", "s1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	theirs, err := store.NewAttempt(context.Background(), "e1", "s2")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := chi.NewRouter()
+	r.With(authmw.JWTMiddleware(authSvc)).Post("/attempts/batch", api.BatchGetAttemptsHandler(store))
+	batch := func(sub, role string) (ids, missing []string) {
+		body := `{"ids":["` + mine.ID + `","` + theirs.ID + `","nope"]}`
+		req := httptest.NewRequest(http.MethodPost, "/attempts/batch", strings.NewReader(body))
+		req.Header.Set("Authorization", bearer(t, authSvc, sub, role))
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: status %d: %s", sub, rec.Code, rec.Body.String())
+		}
+		var out struct {
+			Attempts []exam.Attempt `json:"attempts"`
+			Missing  []string       `json:"missing"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &out); err != nil {
+			t.Fatal(err)
+		}
+		for _, a := range out.Attempts {
+			ids = append(ids, a.ID)
+		}
+		return ids, out.Missing
+	}
+
+	ids, missing := batch("s1", "student")
+	if len(ids) != 1 || ids[0] != mine.ID {
+		t.Fatalf("student got %v, want only own attempt", ids)
+	}
+	if len(missing) != 2 {
+		t.Fatalf("student missing = %v, want other's attempt and unknown id", missing)
+	}
+
+	ids, missing = batch("t1", "teacher")
+	if len(ids) != 2 || len(missing) != 1 || missing[0] != "nope" {
+		t.Fatalf("teacher got %v missing %v", ids, missing)
+	}
+}
+
+func TestCreateAttempt_ResumesInProgress(t *testing.T) {
+	dbh := newTestDB(t)
+	store := exam.NewSQLStore(dbh, "sqlite", grading.NewDefaultGrader())
+	q := []exam.Question{{ID: "q1", Type: "mcq_single", Points: 1, AnswerKey: []string{"a"}}}
+	if err := store.PutExam(exam.Exam{ID: "e1", Title: "Quiz", Questions: q}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.PutExam(exam.Exam{ID: "e2", Title: "Retakeable", Questions: q,
+		PolicyRaw: json.RawMessage(`{"allow_restart":true}`)}); err != nil {
+		t.Fatal(err)
+	}
+
+	h := api.CreateAttemptHandler(store)
+	post := func(sub, role, body, query string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/attempts"+query, strings.NewReader(body))
+		ctx := rbac.WithRole(rbac.WithSubject(req.Context(), sub), role)
+		h(rec, req.WithContext(ctx))
+		return rec
+	}
+	create := func(examID, query string) string {
+		t.Helper()
+		rec := post("s1", "student", `{"exam_id":"`+examID+`"}`, query)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("create %s%s: status = %d body=%s", examID, query, rec.Code, rec.Body.String())
+		}
+		var a exam.Attempt
+		if err := json.NewDecoder(rec.Body).Decode(&a); err != nil {
+			t.Fatal(err)
+		}
+		return a.ID
+	}
+
+	first := create("e1", "")
+	if got := create("e1", ""); got != first {
+		t.Fatalf("second create = %s, want resumed %s", got, first)
+	}
+	if got := create("e1", "?restart=1"); got != first {
+		t.Fatalf("restart without allow_restart = %s, want resumed %s", got, first)
+	}
+	if _, err := store.Submit(context.Background(), first); err != nil {
+		t.Fatal(err)
+	}
+	if got := create("e1", ""); got == first {
+		t.Fatal("create after submit resumed the submitted attempt")
+	}
+
+	other := create("e2", "")
+	if got := create("e2", "?restart=1"); got == other {
+		t.Fatal("restart with allow_restart resumed the old attempt")
+	}
+
+	// Another student's id is refused; staff may start one on their behalf.
+	if rec := post("s2", "student", `{"exam_id":"e2","user_id":"s1"}`, ""); rec.Code != http.StatusForbidden {
+		t.Fatalf("student naming another user: status = %d, want 403", rec.Code)
+	}
+	rec := post("t1", "teacher", `{"exam_id":"e1","user_id":"s3"}`, "")
+	var a exam.Attempt
+	if rec.Code != http.StatusOK || json.NewDecoder(rec.Body).Decode(&a) != nil || a.UserID != "s3" {
+		t.Fatalf("teacher for s3: status = %d attempt = %+v", rec.Code, a)
+	}
+}
+
+func TestSaveResponses_SavedAt(t *testing.T) {
+	dbh := newTestDB(t)
+	store := exam.NewSQLStore(dbh, "sqlite", grading.NewDefaultGrader())
+	now := time.Unix(1700000000, 0)
+	store.Now = func() time.Time { return now }
+	if err := store.PutExam(exam.Exam{
+		ID:    "e1",
+		Title: "Quiz",
+		Questions: []exam.Question{
+			{ID: "q1", Type: "mcq_single", Points: 1, AnswerKey: []string{"a"}},
+			{ID: "q2", Type: "mcq_single", Points: 1, AnswerKey: []string{"a"}},
+		},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	a, err := store.NewAttempt(context.Background(), "e1", "s1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := chi.NewRouter()
+	r.Post("/attempts/{attemptID}/responses", api.SaveResponsesHandler(store))
+	save := func(query, body string, out interface{}) {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/attempts/"+a.ID+"/responses"+query, strings.NewReader(body)))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("save: status = %d body=%s", rec.Code, rec.Body.String())
+		}
+		if err := json.NewDecoder(rec.Body).Decode(out); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var first struct {
+		exam.Attempt
+		SavedAt int64 `json:"saved_at"`
+	}
+	save("", `{"q1":"a"}`, &first)
+	if first.SavedAt != now.UnixMilli() || first.ResponsesUpdatedAt != first.SavedAt {
+		t.Fatalf("saved_at = %d, responses_updated_at = %d; want %d", first.SavedAt, first.ResponsesUpdatedAt, now.UnixMilli())
+	}
+
+	now = now.Add(1500 * time.Millisecond)
+	var ack struct {
+		AttemptID     string `json:"attempt_id"`
+		SavedAt       int64  `json:"saved_at"`
+		AnsweredCount int    `json:"answered_count"`
+	}
+	save("?ack=short", `{"q2":"b"}`, &ack)
+	if ack.AttemptID != a.ID || ack.SavedAt != now.UnixMilli() || ack.AnsweredCount != 2 {
+		t.Fatalf("short ack = %+v", ack)
+	}
+
+	// A save within the same millisecond (or a clock that stepped back)
+	// still moves the timestamp forward.
+	save("?ack=short", `{"q2":"a"}`, &ack)
+	got, err := store.GetAttempt(a.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ack.SavedAt <= now.UnixMilli() || got.ResponsesUpdatedAt != ack.SavedAt {
+		t.Fatalf("third save: saved_at = %d, stored %d; want > %d", ack.SavedAt, got.ResponsesUpdatedAt, now.UnixMilli())
+	}
+}
+
+func TestSaveResponses_PartialBatch(t *testing.T) {
+	dbh := newTestDB(t)
+	store := exam.NewSQLStore(dbh, "sqlite", grading.NewDefaultGrader())
+	if err := store.PutExam(exam.Exam{
+		ID:    "e1",
+		Title: "Two modules",
+		PolicyRaw: json.RawMessage(`{"navigation":{"allow_back":true,"module_locked":true},
+			"sections":[{"id":"s1","modules":[{"id":"m1"},{"id":"m2"}]}]}`),
+		Questions: []exam.Question{
+			{ID: "q1", Type: "mcq_single", ModuleID: "m1", Points: 1, AnswerKey: []string{"a"}},
+			{ID: "q2", Type: "mcq_single", ModuleID: "m1", Points: 1, AnswerKey: []string{"a"}},
+			{ID: "q3", Type: "mcq_single", ModuleID: "m2", Points: 1, AnswerKey: []string{"a"}},
+		},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	a, err := store.NewAttempt(context.Background(), "e1", "s1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := chi.NewRouter()
+	r.Post("/attempts/{attemptID}/responses", api.SaveResponsesHandler(store))
+	save := func(query string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		body := `{"q1":"a","q2":"b","q3":"a","q9":"a"}`
+		r.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/attempts/"+a.ID+"/responses"+query, strings.NewReader(body)))
+		return rec
+	}
+
+	// Without partial, the out-of-module key fails the whole batch.
+	if rec := save(""); rec.Code == http.StatusOK {
+		t.Fatalf("strict save of a mixed batch: status 200, body=%s", rec.Body.String())
+	}
+
+	rec := save("?partial=1")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("partial save: status = %d body=%s", rec.Code, rec.Body.String())
+	}
+	var out struct {
+		Responses map[string]interface{} `json:"responses"`
+		Accepted  []string               `json:"accepted"`
+		Rejected  map[string]struct {
+			Code string `json:"code"`
+		} `json:"rejected"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&out); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(out.Accepted, ",") != "q1,q2" {
+		t.Errorf("accepted = %v, want [q1 q2]", out.Accepted)
+	}
+	if len(out.Rejected) != 2 || out.Rejected["q3"].Code != "outside_module" || out.Rejected["q9"].Code != "unknown_question" {
+		t.Errorf("rejected = %+v", out.Rejected)
+	}
+	got, err := store.GetAttempt(a.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Responses["q1"] != "a" || got.Responses["q2"] != "b" {
+		t.Errorf("valid responses not saved: %v", got.Responses)
+	}
+	if _, ok := got.Responses["q3"]; ok {
+		t.Errorf("out-of-module response saved: %v", got.Responses)
+	}
+}
+
+func TestScoreDecimals_RoundsResponsesOnly(t *testing.T) {
+	ctx := context.Background()
+	dbh := newTestDB(t)
+	store := exam.NewSQLStore(dbh, "sqlite", grading.NewDefaultGrader())
+	tenth := []exam.Question{
+		{ID: "q1", Type: "mcq_single", Points: 0.1, AnswerKey: []string{"a"}},
+		{ID: "q2", Type: "mcq_single", Points: 0.1, AnswerKey: []string{"a"}},
+		{ID: "q3", Type: "mcq_single", Points: 0.1, AnswerKey: []string{"a"}},
+	}
+	third := []exam.Question{
+		{ID: "q1", Type: "mcq_single", Points: 1.0 / 3, AnswerKey: []string{"a"}},
+		{ID: "q2", Type: "mcq_single", Points: 1.0 / 3, AnswerKey: []string{"a"}},
+		{ID: "q3", Type: "mcq_single", Points: 1.0 / 3, AnswerKey: []string{"a"}},
+	}
+	for id, qs := range map[string][]exam.Question{"tenths": tenth, "thirds": third} {
+		if err := store.PutExam(exam.Exam{ID: id, Title: id, Questions: qs}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := dbh.Exec(`INSERT INTO exam_offerings (id, exam_id, course_id, assigned_by, visibility) VALUES ('pub','thirds','c1','t1','public')`); err != nil {
+		t.Fatal(err)
+	}
+	a, err := store.NewAttempt(ctx, "tenths", "s1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.SaveResponses(ctx, a.ID, map[string]interface{}{"q1": "a", "q2": "a", "q3": "a"}); err != nil {
+		t.Fatal(err)
+	}
+
+	r := chi.NewRouter()
+	r.Use(api.ScoreDecimals(2))
+	r.Post("/attempts/{attemptID}/submit", api.SubmitAttemptHandler(store))
+	r.Get("/attempts/{attemptID}/grading", api.GetAttemptGradingHandler(store))
+	r.Post("/public/offerings/{offeringID}/grade_ephemeral", api.GradePublicEphemeralHandler(dbh, store, grading.NewDefaultGrader()))
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest(method, path, strings.NewReader(body)))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s %s: status = %d body=%s", method, path, rec.Code, rec.Body.String())
+		}
+		return rec
+	}
+
+	var sub exam.Attempt
+	if err := json.NewDecoder(do(http.MethodPost, "/attempts/"+a.ID+"/submit", "").Body).Decode(&sub); err != nil {
+		t.Fatal(err)
+	}
+	if sub.Score != 0.3 {
+		t.Errorf("submit: score = %v, want 0.3", sub.Score)
+	}
+	stored, err := store.GetAttempt(a.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var want float64
+	for _, q := range tenth {
+		want += q.Points
+	}
+	if stored.Score != want || want == 0.3 {
+		t.Errorf("stored score = %v, want the unrounded %v", stored.Score, want)
+	}
+	var items []exam.AttemptItem
+	if err := json.NewDecoder(do(http.MethodGet, "/attempts/"+a.ID+"/grading", "").Body).Decode(&items); err != nil {
+		t.Fatal(err)
+	}
+	for _, it := range items {
+		if it.AutoPoints != 0.1 || it.PointsMax != 0.1 {
+			t.Errorf("item %s: %v of %v, want 0.1 of 0.1", it.QuestionID, it.AutoPoints, it.PointsMax)
+		}
+	}
+
+	// Each third shows as 0.33, but the score is the rounded exact sum (1),
+	// not the sum of the rounded points (0.99).
+	var eph api.EphemeralGradeResp
+	if err := json.NewDecoder(do(http.MethodPost, "/public/offerings/pub/grade_ephemeral", `{"responses":{"q1":"a","q2":"a","q3":"a"}}`).Body).Decode(&eph); err != nil {
+		t.Fatal(err)
+	}
+	if eph.Score != 1 || eph.ScoreMax != 1 {
+		t.Errorf("ephemeral: score = %v/%v, want 1/1", eph.Score, eph.ScoreMax)
+	}
+	for _, it := range eph.Items {
+		if it.Points != 0.33 || it.PointsMax != 0.33 {
+			t.Errorf("ephemeral %s: %v of %v, want 0.33 of 0.33", it.QuestionID, it.Points, it.PointsMax)
+		}
+	}
+}
+
+func TestStaleAttempts_ScopedToTeacher(t *testing.T) {
+	dbh := newTestDB(t)
+	store := exam.NewSQLStore(dbh, "sqlite", grading.NewDefaultGrader())
+	if err := store.PutExam(exam.Exam{
+		ID:        "e1",
+		Title:     "Quiz",
+		Questions: []exam.Question{{ID: "q1", Type: "mcq_single", Points: 1, AnswerKey: []string{"a"}}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	for _, q := range []string{
+		`INSERT INTO courses (id, name, created_by) VALUES ('c2','Course 2','t2')`,
+		`INSERT INTO course_teachers (course_id, teacher_id, role) VALUES ('c2','t2','owner')`,
+		`INSERT INTO exam_offerings (id, exam_id, course_id, assigned_by, visibility) VALUES ('o1','e1','c1','t1','course'), ('o2','e1','c2','t2','course')`,
+	} {
+		if _, err := dbh.Exec(q); err != nil {
+			t.Fatalf("%s: %v", q, err)
+		}
+	}
+	ids := map[string]string{}
+	for user, off := range map[string]string{"s1": "o1", "s2": "o2"} {
+		a, err := store.NewAttempt(context.Background(), "e1", user)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := dbh.Exec(`UPDATE attempts SET offering_id=$1 WHERE id=$2`, off, a.ID); err != nil {
+			t.Fatal(err)
+		}
+		ids[off] = a.ID
+	}
+	later := time.Now().Add(time.Hour)
+	store.Now = func() time.Time { return later }
+
+	r := chi.NewRouter()
+	r.Get("/attempts/stale", api.ListStaleAttemptsHandler(store))
+	r.Post("/attempts/{attemptID}/heartbeat", api.HeartbeatHandler(store))
+	stale := func(sub, role string) []string {
+		req := httptest.NewRequest(http.MethodGet, "/attempts/stale", nil)
+		req = req.WithContext(rbac.WithSubject(rbac.WithRole(req.Context(), role), sub))
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: status %d body=%s", sub, rec.Code, rec.Body.String())
+		}
+		var list []exam.Attempt
+		_ = json.NewDecoder(rec.Body).Decode(&list)
+		out := []string{}
+		for _, a := range list {
+			out = append(out, a.ID)
+		}
+		return out
+	}
+	if got := stale("t1", "teacher"); len(got) != 1 || got[0] != ids["o1"] {
+		t.Fatalf("t1 sees %v, want only %s", got, ids["o1"])
+	}
+	if got := stale("t2", "teacher"); len(got) != 1 || got[0] != ids["o2"] {
+		t.Fatalf("t2 sees %v, want only %s", got, ids["o2"])
+	}
+	if got := stale("root", "admin"); len(got) != 2 {
+		t.Fatalf("admin sees %v, want both", got)
+	}
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/attempts/nope/heartbeat", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("heartbeat on missing attempt: status %d, want 404", rec.Code)
+	}
+}
+
+func TestExamJSON_HidesKeysAndScoring(t *testing.T) {
+	dbh := newTestDB(t)
+	store := exam.NewSQLStore(dbh, "sqlite", grading.NewDefaultGrader())
+	if err := store.PutExam(exam.Exam{
+		ID:    "e1",
+		Title: "Quiz",
+		Questions: []exam.Question{{ID: "q1", Type: "mcq_single", Points: 1, AnswerKey: []string{"right"},
+			Scoring: &exam.Scoring{Mode: "map", Weights: map[string]float64{"right": 1}}}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := dbh.Exec(`INSERT INTO exam_offerings (id, exam_id, course_id, assigned_by, visibility, access_token) VALUES ('lnk','e1','c1','t1','link','tok')`); err != nil {
+		t.Fatal(err)
+	}
+	a, err := store.NewAttempt(context.Background(), "e1", "s1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := chi.NewRouter()
+	r.Get("/attempts/{attemptID}/exam", api.GetAttemptExamHandler(store))
+	r.Get("/offerings/{offeringID}/resolve", api.GetOfferingByTokenHandler(dbh, store))
+	for _, path := range []string{"/attempts/" + a.ID + "/exam", "/offerings/lnk/resolve?access_token=tok"} {
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		body := rec.Body.String()
+		if rec.Code != http.StatusOK || !strings.Contains(body, `"q1"`) {
+			t.Fatalf("%s: status = %d body=%s", path, rec.Code, body)
+		}
+		for _, leak := range []string{"answer_key", "scoring", "right"} {
+			if strings.Contains(body, leak) {
+				t.Errorf("%s leaks %q: %s", path, leak, body)
+			}
+		}
+	}
+}
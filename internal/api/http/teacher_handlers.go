@@ -3,6 +3,7 @@ package http
 import (
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
 	"strings"
@@ -62,14 +63,37 @@ func totalTimeFromPolicy(pol formats.Policy) int {
 func UploadExamHandler(store exam.Store, db *sql.DB, authSvc *authmw.AuthService) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		var e exam.Exam
-		if err := json.NewDecoder(r.Body).Decode(&e); err != nil {
-			http.Error(w, "bad json", http.StatusBadRequest)
+		if err := decodeBody(r, &e); err != nil {
+			badJSON(w, err)
 			return
 		}
 		if strings.TrimSpace(e.ID) == "" {
 			http.Error(w, "id required", http.StatusBadRequest)
 			return
 		}
+		// Copy in referenced bank questions before anything looks at them.
+		qs, err := exam.NewBank(db).Resolve(r.Context(), e.Questions)
+		if errors.Is(err, exam.ErrBankQuestionNotFound) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			http.Error(w, "bank lookup: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		e.Questions = qs
+		if err := examLimitsFrom(r.Context()).Check(e.Questions); err != nil {
+			status := http.StatusUnprocessableEntity
+			if errors.Is(err, exam.ErrTooManyQuestions) {
+				status = http.StatusRequestEntityTooLarge
+			}
+			http.Error(w, err.Error(), status)
+			return
+		}
+		if err := exam.ValidateQuestions(e.Questions); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 
 		// Validate policy/profile if present (unchanged)
 		if e.Profile != "" && len(e.PolicyRaw) > 0 {
@@ -223,6 +247,54 @@ func GetExamHandler(store exam.Store) http.HandlerFunc {
 	}
 }
 
+// GET /exams/{examID}/stats[?refresh=1][&group=tag]: item analysis over
+// submitted attempts. Served from the exam_stats cache, which submissions and
+// regrades invalidate; refresh=1 forces a recompute. group=tag adds the
+// per-question rows summed by question tag.
+func GetExamStatsHandler(store exam.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := chi.URLParam(r, "examID")
+		e, err := store.GetExam(id)
+		if err != nil {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		refresh := r.URL.Query().Get("refresh") == "1"
+		st, err := store.ExamStats(r.Context(), id, refresh)
+		if err != nil {
+			http.Error(w, "db error", http.StatusInternalServerError)
+			return
+		}
+		if r.URL.Query().Get("group") == "tag" {
+			st.Tags = exam.StatsByTag(st, e.Questions)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		_ = json.NewEncoder(w).Encode(st)
+	}
+}
+
+// GET /exams/{examID}/questions[?tag=a&tag=b]: the exam's questions (with
+// answer keys; staff only), limited to those carrying any given tag.
+func ListExamQuestionsHandler(store exam.Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := chi.URLParam(r, "examID")
+		e, err := store.GetExamAdmin(r.Context(), id)
+		if err != nil {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		var tags []string
+		for _, t := range r.URL.Query()["tag"] {
+			if t = strings.TrimSpace(t); t != "" {
+				tags = append(tags, t)
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(exam.QuestionsWithTag(e.Questions, tags...))
+	}
+}
+
 // subjectAndRole extracts (sub, role) from Authorization using the same service
 // your other handlers use. Returns ("","") if missing/invalid.
 func subjectAndRole(authSvc *authmw.AuthService, r *http.Request) (string, string) {
@@ -387,3 +459,38 @@ func DeleteCourseHandler(db *sql.DB, authSvc *authmw.AuthService) http.HandlerFu
 		w.WriteHeader(http.StatusNoContent)
 	}
 }
+
+// ArchiveCourseHandler sets a course's status to 'archived' (or back to
+// 'active' with ?undo=1). Unlike DeleteCourseHandler it keeps offerings,
+// enrollments and attempts; archived courses are only hidden from default
+// listings. Caller must be admin or an owner teacher of the course.
+func ArchiveCourseHandler(db *sql.DB, authSvc *authmw.AuthService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		courseID := strings.TrimSpace(chi.URLParam(r, "courseID"))
+		if courseID == "" {
+			http.Error(w, "courseID required", http.StatusBadRequest)
+			return
+		}
+
+		sub, role := subjectAndRole(authSvc, r)
+		if role != "admin" && !isCourseOwner(db, sub, courseID) {
+			http.Error(w, "forbidden (not course owner)", http.StatusForbidden)
+			return
+		}
+
+		status := "archived"
+		if r.URL.Query().Get("undo") == "1" {
+			status = "active"
+		}
+		res, err := db.ExecContext(r.Context(), `UPDATE courses SET status=$1 WHERE id=$2`, status, courseID)
+		if err != nil {
+			http.Error(w, "update course", http.StatusInternalServerError)
+			return
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		respondJSON(w, http.StatusOK, map[string]string{"id": courseID, "status": status})
+	}
+}
@@ -0,0 +1,321 @@
+package http_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+
+	api "github.com/mind-engage/mindengage-lms/internal/api/http"
+	authmw "github.com/mind-engage/mindengage-lms/internal/auth/middleware"
+	"github.com/mind-engage/mindengage-lms/internal/exam"
+	"github.com/mind-engage/mindengage-lms/internal/grading"
+	"github.com/mind-engage/mindengage-lms/internal/httpsec"
+)
+
+func TestArchiveCourse_HidesButPreserves(t *testing.T) {
+	dbh := newTestDB(t)
+	authSvc := authmw.NewAuthService("test")
+	for _, q := range []string{
+		`INSERT INTO course_students (course_id, student_id) VALUES ('c1','s1')`,
+		`INSERT INTO exams (id, title, time_limit_sec, questions_json) VALUES ('e1','E1',0,'[]')`,
+		`INSERT INTO exam_offerings (id, exam_id, course_id, assigned_by) VALUES ('o1','e1','c1','t1')`,
+		`INSERT INTO attempts (id, exam_id, user_id, status, responses_json, offering_id) VALUES ('a1','e1','s1','submitted','{}','o1')`,
+	} {
+		if _, err := dbh.Exec(q); err != nil {
+			t.Fatalf("%s: %v", q, err)
+		}
+	}
+
+	r := chi.NewRouter()
+	r.Get("/courses", api.ListCoursesHandler(dbh, authSvc))
+	r.Post("/courses/{courseID}/archive", api.ArchiveCourseHandler(dbh, authSvc))
+	do := func(method, path, sub, role string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set("Authorization", bearer(t, authSvc, sub, role))
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec
+	}
+	listIDs := func(query, sub, role string) []string {
+		rec := do(http.MethodGet, "/courses"+query, sub, role)
+		var out []struct{ ID string }
+		if err := json.NewDecoder(rec.Body).Decode(&out); err != nil {
+			t.Fatal(err)
+		}
+		ids := []string{}
+		for _, c := range out {
+			ids = append(ids, c.ID)
+		}
+		return ids
+	}
+
+	if rec := do(http.MethodPost, "/courses/c1/archive", "t2", "teacher"); rec.Code != http.StatusForbidden {
+		t.Fatalf("non-owner archive status = %d, want 403", rec.Code)
+	}
+	if rec := do(http.MethodPost, "/courses/c1/archive", "t1", "teacher"); rec.Code != http.StatusOK {
+		t.Fatalf("archive status = %d body=%s", rec.Code, rec.Body.String())
+	}
+
+	if ids := listIDs("", "t1", "teacher"); len(ids) != 0 {
+		t.Fatalf("teacher default list = %v, want archived course hidden", ids)
+	}
+	if ids := listIDs("", "s1", "student"); len(ids) != 0 {
+		t.Fatalf("student default list = %v, want archived course hidden", ids)
+	}
+	if ids := listIDs("?archived=1", "t1", "teacher"); len(ids) != 1 || ids[0] != "c1" {
+		t.Fatalf("archived list = %v, want [c1]", ids)
+	}
+
+	for table, q := range map[string]string{
+		"attempts":        `SELECT COUNT(*) FROM attempts WHERE id='a1'`,
+		"exam_offerings":  `SELECT COUNT(*) FROM exam_offerings WHERE course_id='c1'`,
+		"course_students": `SELECT COUNT(*) FROM course_students WHERE course_id='c1'`,
+	} {
+		if n := count(t, dbh, q); n != 1 {
+			t.Errorf("%s rows = %d after archive, want 1", table, n)
+		}
+	}
+
+	if rec := do(http.MethodPost, "/courses/c1/archive?undo=1", "t1", "teacher"); rec.Code != http.StatusOK {
+		t.Fatalf("unarchive status = %d", rec.Code)
+	}
+	if ids := listIDs("", "t1", "teacher"); len(ids) != 1 {
+		t.Fatalf("after unarchive list = %v, want [c1]", ids)
+	}
+}
+
+func TestUploadExam_BodyLimit(t *testing.T) {
+	dbh := newTestDB(t)
+	authSvc := authmw.NewAuthService("test")
+	store := exam.NewSQLStore(dbh, "sqlite", grading.NewDefaultGrader())
+
+	const limit = 4 << 10
+	r := chi.NewRouter()
+	r.Use(httpsec.MaxJSONBody(limit))
+	r.Post("/exams", api.UploadExamHandler(store, dbh, authSvc))
+	r.Post("/upload", func(w http.ResponseWriter, r *http.Request) {
+		f, _, err := r.FormFile("file")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		n, _ := io.Copy(io.Discard, f)
+		_ = json.NewEncoder(w).Encode(map[string]int64{"size": n})
+	})
+	post := func(path, contentType string, body io.Reader) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, body)
+		req.Header.Set("Content-Type", contentType)
+		req.Header.Set("Authorization", bearer(t, authSvc, "t1", "teacher"))
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec
+	}
+	examJSON := func(prompt string) string {
+		b, _ := json.Marshal(exam.Exam{
+			ID:        "e-big",
+			Title:     "Big",
+			Questions: []exam.Question{{ID: "q1", Type: "short_word", PromptHTML: prompt, Points: 1}},
+		})
+		return string(b)
+	}
+
+	big := examJSON(strings.Repeat("x", limit))
+	if rec := post("/exams", "application/json", strings.NewReader(big)); rec.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("oversized exam status = %d, want 413", rec.Code)
+	}
+	// Same, without a Content-Length (chunked).
+	if rec := post("/exams", "application/json", io.MultiReader(strings.NewReader(big))); rec.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("oversized chunked exam status = %d, want 413", rec.Code)
+	}
+	if _, err := store.GetExam("e-big"); err == nil {
+		t.Fatal("oversized exam was stored")
+	}
+
+	if rec := post("/exams", "application/json", strings.NewReader(examJSON("small"))); rec.Code != http.StatusOK {
+		t.Fatalf("small exam status = %d body=%s", rec.Code, rec.Body.String())
+	}
+
+	// File uploads are exempt from the JSON cap.
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	fw, _ := mw.CreateFormFile("file", "pkg.zip")
+	_, _ = fw.Write(bytes.Repeat([]byte{0}, 2*limit))
+	_ = mw.Close()
+	if rec := post("/upload", mw.FormDataContentType(), &buf); rec.Code != http.StatusOK {
+		t.Fatalf("multipart upload status = %d body=%s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestUploadExam_RejectsDuplicateQuestionIDs(t *testing.T) {
+	dbh := newTestDB(t)
+	authSvc := authmw.NewAuthService("test")
+	store := exam.NewSQLStore(dbh, "sqlite", grading.NewDefaultGrader())
+	r := chi.NewRouter()
+	r.Post("/exams", api.UploadExamHandler(store, dbh, authSvc))
+
+	b, _ := json.Marshal(exam.Exam{
+		ID:    "e-dup",
+		Title: "Dup",
+		Questions: []exam.Question{
+			{ID: "q1", Type: "mcq_single", Points: 1},
+			{ID: "q2", Type: "mcq_single", Points: 1},
+			{ID: "q1", Type: "essay", Points: 5},
+		},
+	})
+	req := httptest.NewRequest(http.MethodPost, "/exams", bytes.NewReader(b))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", bearer(t, authSvc, "t1", "teacher"))
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), `"q1"`) {
+		t.Fatalf("status = %d body=%s, want 400 naming q1", rec.Code, rec.Body.String())
+	}
+	if _, err := store.GetExam("e-dup"); err == nil {
+		t.Fatal("exam with duplicate ids was stored")
+	}
+}
+
+func TestUploadExam_SizeLimits(t *testing.T) {
+	dbh := newTestDB(t)
+	authSvc := authmw.NewAuthService("test")
+	store := exam.NewSQLStore(dbh, "sqlite", grading.NewDefaultGrader())
+	r := chi.NewRouter()
+	r.Use(api.ExamLimits(exam.Limits{MaxQuestions: 3, MaxChoices: 4}))
+	r.Post("/exams", api.UploadExamHandler(store, dbh, authSvc))
+	upload := func(e exam.Exam) *httptest.ResponseRecorder {
+		b, _ := json.Marshal(e)
+		req := httptest.NewRequest(http.MethodPost, "/exams", bytes.NewReader(b))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", bearer(t, authSvc, "t1", "teacher"))
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec
+	}
+	q := func(id string, choices int) exam.Question {
+		out := exam.Question{ID: id, Type: "mcq_single", Points: 1}
+		for i := 0; i < choices; i++ {
+			out.Choices = append(out.Choices, exam.Choice{ID: string(rune('a' + i))})
+		}
+		return out
+	}
+
+	rec := upload(exam.Exam{ID: "big", Title: "Big", Questions: []exam.Question{q("q1", 2), q("q2", 2), q("q3", 2), q("q4", 2)}})
+	if rec.Code != http.StatusRequestEntityTooLarge || !strings.Contains(rec.Body.String(), "limit 3") {
+		t.Fatalf("too many questions: status = %d body=%s, want 413 naming the limit", rec.Code, rec.Body.String())
+	}
+	rec = upload(exam.Exam{ID: "wide", Title: "Wide", Questions: []exam.Question{q("q1", 2), q("q2", 5)}})
+	if rec.Code != http.StatusUnprocessableEntity || !strings.Contains(rec.Body.String(), "limit 4") {
+		t.Fatalf("too many choices: status = %d body=%s, want 422 naming the limit", rec.Code, rec.Body.String())
+	}
+	for _, id := range []string{"big", "wide"} {
+		if _, err := store.GetExam(id); err == nil {
+			t.Fatalf("over-cap exam %s was stored", id)
+		}
+	}
+	if rec := upload(exam.Exam{ID: "ok", Title: "OK", Questions: []exam.Question{q("q1", 4), q("q2", 4), q("q3", 4)}}); rec.Code != http.StatusOK {
+		t.Fatalf("exam at the caps: status = %d body=%s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestExamQuestions_TagFilter(t *testing.T) {
+	dbh := newTestDB(t)
+	store := exam.NewSQLStore(dbh, "sqlite", grading.NewDefaultGrader())
+	if err := store.PutExam(exam.Exam{
+		ID:    "e1",
+		Title: "Tagged",
+		Questions: []exam.Question{
+			{ID: "q1", Type: "mcq_single", Points: 1, AnswerKey: []string{"a"}, Tags: []string{"Algebra", "easy"}},
+			{ID: "q2", Type: "mcq_single", Points: 1, AnswerKey: []string{"a"}, Tags: []string{"geometry"}},
+			{ID: "q3", Type: "mcq_single", Points: 1, AnswerKey: []string{"a"}, Tags: []string{"algebra", "hard"}},
+			{ID: "q4", Type: "mcq_single", Points: 1, AnswerKey: []string{"a"}},
+		},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	for _, resp := range []map[string]interface{}{
+		{"q1": "a", "q2": "a", "q3": "b"},
+		{"q1": "a", "q2": "b", "q3": "a"},
+	} {
+		a, err := store.NewAttempt(context.Background(), "e1", "s1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := store.SaveResponses(context.Background(), a.ID, resp); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := store.Submit(context.Background(), a.ID); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	r := chi.NewRouter()
+	r.Get("/exams/{examID}/questions", api.ListExamQuestionsHandler(store))
+	r.Get("/exams/{examID}/stats", api.GetExamStatsHandler(store))
+	get := func(path string, v any) int {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if rec.Code == http.StatusOK {
+			if err := json.NewDecoder(rec.Body).Decode(v); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return rec.Code
+	}
+	ids := func(qs []exam.Question) string {
+		var out []string
+		for _, q := range qs {
+			out = append(out, q.ID)
+		}
+		return strings.Join(out, ",")
+	}
+
+	for path, want := range map[string]string{
+		"/exams/e1/questions":                       "q1,q2,q3,q4",
+		"/exams/e1/questions?tag=algebra":           "q1,q3",
+		"/exams/e1/questions?tag=hard&tag=geometry": "q2,q3",
+		"/exams/e1/questions?tag=calculus":          "",
+	} {
+		var qs []exam.Question
+		if code := get(path, &qs); code != http.StatusOK {
+			t.Fatalf("%s: status = %d", path, code)
+		}
+		if got := ids(qs); got != want {
+			t.Errorf("%s = %q, want %q", path, got, want)
+		}
+	}
+	var qs []exam.Question
+	if code := get("/exams/nope/questions", &qs); code != http.StatusNotFound {
+		t.Fatalf("missing exam: status = %d, want 404", code)
+	}
+
+	var st exam.ExamStats
+	if code := get("/exams/e1/stats?group=tag", &st); code != http.StatusOK {
+		t.Fatalf("stats: status = %d", code)
+	}
+	got := map[string]exam.TagStats{}
+	for _, ts := range st.Tags {
+		got[ts.Tag] = ts
+	}
+	if len(got) != 4 {
+		t.Fatalf("tag groups = %+v, want algebra, easy, geometry, hard", st.Tags)
+	}
+	if alg := got["algebra"]; alg.Questions != 2 || alg.Count != 4 || alg.Correct != 3 || alg.AvgPoints != 0.75 {
+		t.Errorf("algebra = %+v, want 2 questions, 3 of 4 correct, avg 0.75", alg)
+	}
+	if geo := got["geometry"]; geo.Questions != 1 || geo.Count != 2 || geo.Correct != 1 {
+		t.Errorf("geometry = %+v, want 1 of 2 correct", geo)
+	}
+	var plain exam.ExamStats
+	if get("/exams/e1/stats", &plain); len(plain.Tags) != 0 {
+		t.Errorf("ungrouped stats carry tags: %+v", plain.Tags)
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"errors"
 	"net/http"
 
+	"github.com/mind-engage/mindengage-lms/internal/auth/password"
 	"github.com/mind-engage/mindengage-lms/internal/rbac"
 	"golang.org/x/crypto/bcrypt"
 )
@@ -16,7 +17,25 @@ type changePasswordReq struct {
 	NewPassword string `json:"new_password"`
 }
 
-func ChangePasswordHandler(db *sql.DB) http.HandlerFunc {
+// weakPasswordResp is the 422 body when a password breaks the policy. For
+// bulk imports each violation also names the user it belongs to.
+type weakPasswordResp struct {
+	Error      string              `json:"error"`
+	Violations []passwordViolation `json:"violations"`
+}
+
+type passwordViolation struct {
+	Username string `json:"username,omitempty"`
+	password.Violation
+}
+
+func writeWeakPassword(w http.ResponseWriter, vs []passwordViolation) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusUnprocessableEntity)
+	_ = json.NewEncoder(w).Encode(weakPasswordResp{Error: "weak password", Violations: vs})
+}
+
+func ChangePasswordHandler(db *sql.DB, policy password.Policy) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		userID := rbac.SubjectFromContext(r.Context())
 		if userID == "" {
@@ -35,6 +54,15 @@ func ChangePasswordHandler(db *sql.DB) http.HandlerFunc {
 			return
 		}
 
+		if vs := policy.Check(req.NewPassword); len(vs) > 0 {
+			out := make([]passwordViolation, len(vs))
+			for i, v := range vs {
+				out[i] = passwordViolation{Violation: v}
+			}
+			writeWeakPassword(w, out)
+			return
+		}
+
 		var storedHash string
 		err := db.QueryRow(`SELECT password_hash FROM users WHERE id=$1`, userID).Scan(&storedHash)
 		if err != nil {
@@ -51,7 +79,7 @@ func ChangePasswordHandler(db *sql.DB) http.HandlerFunc {
 			return
 		}
 
-		hash, err := bcrypt.GenerateFromPassword([]byte(req.NewPassword), 12)
+		hash, err := policy.Hash(req.NewPassword)
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
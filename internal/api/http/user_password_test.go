@@ -0,0 +1,75 @@
+package http_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"golang.org/x/crypto/bcrypt"
+
+	api "github.com/mind-engage/mindengage-lms/internal/api/http"
+	authmw "github.com/mind-engage/mindengage-lms/internal/auth/middleware"
+	"github.com/mind-engage/mindengage-lms/internal/auth/password"
+)
+
+func TestPasswordPolicy_ChangeAndImport(t *testing.T) {
+	dbh := newTestDB(t)
+	authSvc := authmw.NewAuthService("test")
+	hash, _ := bcrypt.GenerateFromPassword([]byte("old-secret"), bcrypt.MinCost)
+	if _, err := dbh.Exec(`UPDATE users SET password_hash=$1 WHERE id='s1'`, string(hash)); err != nil {
+		t.Fatal(err)
+	}
+	policy := password.Policy{MinLength: 10, MinClasses: 3, DenyCommon: true}
+
+	r := chi.NewRouter()
+	r.With(authmw.JWTMiddleware(authSvc)).Post("/users/change-password", api.ChangePasswordHandler(dbh, policy))
+	r.Post("/users/bulk", api.BulkUpsertUsersHandler(dbh, authSvc, policy))
+	post := func(path, sub, role, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		req.Header.Set("Authorization", bearer(t, authSvc, sub, role))
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec
+	}
+	var body struct {
+		Violations []struct {
+			Username string `json:"username"`
+			Code     string `json:"code"`
+		} `json:"violations"`
+	}
+
+	rec := post("/users/change-password", "s1", "student", `{"old_password":"old-secret","new_password":"password"}`)
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("weak change: status %d: %s", rec.Code, rec.Body.String())
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil || len(body.Violations) != 3 {
+		t.Fatalf("weak change: violations %s", rec.Body.String())
+	}
+
+	rec = post("/users/change-password", "s1", "student", `{"old_password":"old-secret","new_password":"Correct-horse-42"}`)
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("strong change: status %d: %s", rec.Code, rec.Body.String())
+	}
+
+	rec = post("/users/bulk", "t1", "teacher",
+		`[{"id":"s9","username":"s9","password":"Another-good-one-7"},{"id":"s8","username":"s8","password":"123456"}]`)
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("weak import: status %d: %s", rec.Code, rec.Body.String())
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil || len(body.Violations) == 0 || body.Violations[0].Username != "s8" {
+		t.Fatalf("weak import: violations %s", rec.Body.String())
+	}
+	var n int
+	_ = dbh.QueryRow(`SELECT COUNT(*) FROM users WHERE id IN ('s8','s9')`).Scan(&n)
+	if n != 0 {
+		t.Fatalf("weak import wrote %d users", n)
+	}
+
+	rec = post("/users/bulk", "t1", "teacher", `[{"id":"s9","username":"s9","password":"Another-good-one-7"}]`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("strong import: status %d: %s", rec.Code, rec.Body.String())
+	}
+}
@@ -14,7 +14,7 @@ import (
 	"time"
 
 	authmw "github.com/mind-engage/mindengage-lms/internal/auth/middleware"
-	"golang.org/x/crypto/bcrypt"
+	"github.com/mind-engage/mindengage-lms/internal/auth/password"
 )
 
 type userRow struct {
@@ -24,7 +24,9 @@ type userRow struct {
 	Password string `json:"password,omitempty"` // plaintext optional (LAN-only)
 }
 
-func BulkUpsertUsersHandler(db *sql.DB, authSvc *authmw.AuthService) http.HandlerFunc {
+// BulkUpsertUsersHandler imports users; any plaintext password must meet
+// policy, otherwise nothing is written and every violation is reported.
+func BulkUpsertUsersHandler(db *sql.DB, authSvc *authmw.AuthService, policy password.Policy) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// Who is calling?
 		sub, actorRole := subjectFromBearer(authSvc, r)
@@ -75,8 +77,22 @@ func BulkUpsertUsersHandler(db *sql.DB, authSvc *authmw.AuthService) http.Handle
 			return
 		}
 
+		var weak []passwordViolation
+		for _, u := range rows {
+			if u.Password == "" {
+				continue // existing users keep their hash; new ones are rejected below
+			}
+			for _, v := range policy.Check(u.Password) {
+				weak = append(weak, passwordViolation{Username: u.Username, Violation: v})
+			}
+		}
+		if len(weak) > 0 {
+			writeWeakPassword(w, weak)
+			return
+		}
+
 		// Enforce role rules inside the upsert transaction
-		ins, upd, err := upsertUsers(r.Context(), db, rows, actorRole)
+		ins, upd, err := upsertUsers(r.Context(), db, rows, actorRole, policy)
 		if err != nil {
 			http.Error(w, err.Error(), 500)
 			return
@@ -152,7 +168,7 @@ func parseCSV(r io.Reader) ([]userRow, error) {
 	return rows, nil
 }
 
-func upsertUsers(ctx context.Context, db *sql.DB, rows []userRow, actorRole string) (inserted, updated int, err error) {
+func upsertUsers(ctx context.Context, db *sql.DB, rows []userRow, actorRole string, policy password.Policy) (inserted, updated int, err error) {
 	tx, err := db.BeginTx(ctx, nil)
 	if err != nil {
 		return
@@ -183,11 +199,9 @@ func upsertUsers(ctx context.Context, db *sql.DB, rows []userRow, actorRole stri
 		// Hash password if provided (LAN-only flow). If empty, keep existing hash or reject if new.
 		var phash string
 		if r.Password != "" {
-			b, e := bcrypt.GenerateFromPassword([]byte(r.Password), 12)
-			if e != nil {
-				return inserted, updated, e
+			if phash, err = policy.Hash(r.Password); err != nil {
+				return inserted, updated, err
 			}
-			phash = string(b)
 		}
 
 		// Look up existing user to get canonical id + current role (needed for teacher policy)
@@ -0,0 +1,23 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/mind-engage/mindengage-lms/internal/buildinfo"
+)
+
+// GET /api/version (unauthenticated): which build is running and against
+// what. Deliberately no hostnames, DSNs or feature flags.
+func VersionHandler(mode, dbDriver string) http.HandlerFunc {
+	type resp struct {
+		buildinfo.Info
+		Mode     string `json:"mode"`
+		DBDriver string `json:"db_driver"`
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		_ = json.NewEncoder(w).Encode(resp{Info: buildinfo.Get(), Mode: mode, DBDriver: dbDriver})
+	}
+}
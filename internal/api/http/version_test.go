@@ -0,0 +1,41 @@
+package http_test
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	api "github.com/mind-engage/mindengage-lms/internal/api/http"
+	"github.com/mind-engage/mindengage-lms/internal/buildinfo"
+)
+
+func TestVersionHandler(t *testing.T) {
+	old := [3]string{buildinfo.Version, buildinfo.Commit, buildinfo.BuildTime}
+	buildinfo.Version, buildinfo.Commit, buildinfo.BuildTime = "v1.2.3", "abc123", "2026-01-02T03:04:05Z"
+	t.Cleanup(func() { buildinfo.Version, buildinfo.Commit, buildinfo.BuildTime = old[0], old[1], old[2] })
+
+	rr := httptest.NewRecorder()
+	api.VersionHandler("online", "postgres")(rr, httptest.NewRequest("GET", "/api/version", nil))
+	if rr.Code != 200 {
+		t.Fatalf("status = %d", rr.Code)
+	}
+	var got map[string]any
+	if err := json.Unmarshal(rr.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"version":    "v1.2.3",
+		"commit":     "abc123",
+		"build_time": "2026-01-02T03:04:05Z",
+		"mode":       "online",
+		"db_driver":  "postgres",
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("%s = %v, want %q", k, got[k], v)
+		}
+	}
+	if len(got) != len(want) {
+		t.Errorf("unexpected fields: %v", got)
+	}
+}
@@ -24,11 +24,7 @@ func GoogleLoginHandler(cfg config.Config) http.HandlerFunc {
 			next = r.Referer()
 		}
 		if next == "" {
-			base := strings.TrimRight(cfg.PublicURL, "/")
-			if base == "" {
-				base = "/"
-			}
-			next = base + "/"
+			next = cfg.ExternalURL("/")
 		}
 
 		// VERY simple origin check: only allow same-origin as PUBLIC_URL or localhost (dev)
@@ -78,6 +74,12 @@ func GoogleLoginHandler(cfg config.Config) http.HandlerFunc {
 	}
 }
 
+// Google endpoints the callback calls; tests point them at a fake.
+var (
+	googleTokenURL     = "https://oauth2.googleapis.com/token"
+	googleTokenInfoURL = "https://oauth2.googleapis.com/tokeninfo"
+)
+
 // /api/auth/google/callback → exchange code, verify id_token, upsert, mint internal JWT, set cookie
 func GoogleCallbackHandler(a *authmw.AuthService, db *sql.DB, cfg config.Config) http.HandlerFunc {
 	type tokenResp struct {
@@ -119,7 +121,7 @@ func GoogleCallbackHandler(a *authmw.AuthService, db *sql.DB, cfg config.Config)
 		form.Set("redirect_uri", cfg.GoogleRedirectURI)
 		form.Set("grant_type", "authorization_code")
 
-		resp, err := http.PostForm("https://oauth2.googleapis.com/token", form)
+		resp, err := http.PostForm(googleTokenURL, form)
 		if err != nil {
 			http.Error(w, "token exchange error", http.StatusBadGateway)
 			return
@@ -133,7 +135,7 @@ func GoogleCallbackHandler(a *authmw.AuthService, db *sql.DB, cfg config.Config)
 
 		// 3) Verify id_token via Google tokeninfo (simple server-side verification)
 		// NOTE: For production, prefer verifying the JWT signature with Google's JWKS and checking nonce.
-		tiResp, err := http.Get("https://oauth2.googleapis.com/tokeninfo?id_token=" + url.QueryEscape(tr.IdToken))
+		tiResp, err := http.Get(googleTokenInfoURL + "?id_token=" + url.QueryEscape(tr.IdToken))
 		if err != nil {
 			http.Error(w, "tokeninfo fetch error", http.StatusBadGateway)
 			return
@@ -157,16 +159,30 @@ func GoogleCallbackHandler(a *authmw.AuthService, db *sql.DB, cfg config.Config)
 			return
 		}
 
-		// 4) Determine role (default student; elevate if user exists in DB with role, or add your own rule)
+		mapped, err := googleRole(cfg, ti.Email, ti.EmailVerified)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+
+		// 4) Determine role: an existing account keeps its DB role; a new one
+		// gets its GOOGLE_ROLE_MAP role, else student. The map never rewrites
+		// a stored role, so an administrator's change sticks across logins.
 		role := "student"
+		if mapped != "" {
+			role = mapped
+		}
 		username := ti.Email
 		userID := "google|" + ti.Sub
 
 		if db != nil {
-			// upsert user; keep existing role if present
+			// create the user on first login; otherwise keep its stored role
 			var existingID, existingRole string
 			err := db.QueryRow(`SELECT id, role FROM users WHERE username=$1`, username).Scan(&existingID, &existingRole)
 			switch {
+			case err == sql.ErrNoRows && !cfg.JITProvisioning:
+				http.Error(w, "account not provisioned; ask an administrator to create it", http.StatusForbidden)
+				return
 			case err == sql.ErrNoRows:
 				_, _ = db.Exec(`INSERT INTO users (id, username, role) VALUES ($1, $2, $3)`, userID, username, role)
 			case err == nil:
@@ -203,17 +219,14 @@ func GoogleCallbackHandler(a *authmw.AuthService, db *sql.DB, cfg config.Config)
 			}
 		}
 		if target == "" {
-			target = cfg.PublicURL
-			if target == "" {
-				target = "/"
-			}
+			target = cfg.ExternalURL("/")
 		}
 
 		// Optional: validate same-origin again (defense-in-depth)
 		if u, err := url.Parse(target); err == nil {
 			if base, err2 := url.Parse(cfg.PublicURL); err2 == nil && base.Host != "" {
 				if !(u.Host == "" || (u.Scheme == base.Scheme && u.Host == base.Host) || strings.HasPrefix(u.Host, "localhost")) {
-					target = strings.TrimRight(cfg.PublicURL, "/") + "/"
+					target = cfg.ExternalURL("/")
 				}
 			}
 		}
@@ -231,3 +244,48 @@ func GoogleCallbackHandler(a *authmw.AuthService, db *sql.DB, cfg config.Config)
 		http.Redirect(w, r, u.String(), http.StatusFound)
 	}
 }
+
+// googleRole applies GOOGLE_ALLOWED_DOMAINS and GOOGLE_ROLE_MAP to a verified
+// Google email. It returns the mapped role ("" = none) or an error explaining
+// why the account may not sign in.
+func googleRole(cfg config.Config, email, emailVerified string) (string, error) {
+	email = strings.ToLower(strings.TrimSpace(email))
+	at := strings.LastIndex(email, "@")
+	if at <= 0 || at == len(email)-1 {
+		return "", fmt.Errorf("google account has no usable email")
+	}
+	domain := email[at+1:]
+
+	if len(cfg.GoogleAllowedDomains) > 0 || len(cfg.GoogleRoleMap) > 0 {
+		// domain rules are only as good as Google's say-so on the address
+		if emailVerified != "true" {
+			return "", fmt.Errorf("google email %s is not verified", email)
+		}
+	}
+	if len(cfg.GoogleAllowedDomains) > 0 {
+		ok := false
+		for _, d := range cfg.GoogleAllowedDomains {
+			if strings.EqualFold(strings.TrimPrefix(d, "@"), domain) {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			return "", fmt.Errorf("sign-in with %s accounts is not allowed; allowed domains: %s",
+				domain, strings.Join(cfg.GoogleAllowedDomains, ", "))
+		}
+	}
+
+	role, ok := cfg.GoogleRoleMap[email]
+	if !ok {
+		if role, ok = cfg.GoogleRoleMap[domain]; !ok {
+			role = cfg.GoogleRoleMap["@"+domain]
+		}
+	}
+	switch role {
+	case "", "student", "teacher", "admin":
+		return role, nil
+	default:
+		return "", fmt.Errorf("GOOGLE_ROLE_MAP has invalid role %q for %s", role, email)
+	}
+}
@@ -0,0 +1,203 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	authmw "github.com/mind-engage/mindengage-lms/internal/auth/middleware"
+	"github.com/mind-engage/mindengage-lms/internal/config"
+	"github.com/mind-engage/mindengage-lms/internal/db"
+)
+
+func TestGoogleRole_DomainsAndMapping(t *testing.T) {
+	cfg := config.Config{
+		GoogleAllowedDomains: []string{"example.edu", "staff.example.edu"},
+		GoogleRoleMap: map[string]string{
+			"staff.example.edu":     "teacher",
+			"principal@example.edu": "admin",
+		},
+	}
+	for _, c := range []struct {
+		email, verified string
+		want            string
+		wantErr         string
+	}{
+		{"Ms.Lee@Staff.Example.edu", "true", "teacher", ""},
+		{"principal@example.edu", "true", "admin", ""},
+		{"kid@example.edu", "true", "", ""},
+		{"someone@gmail.com", "true", "", "not allowed"},
+		{"kid@example.edu", "false", "", "not verified"},
+	} {
+		got, err := googleRole(cfg, c.email, c.verified)
+		if c.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), c.wantErr) {
+				t.Errorf("%s: err = %v, want %q", c.email, err, c.wantErr)
+			}
+			continue
+		}
+		if err != nil || got != c.want {
+			t.Errorf("%s: role = %q, %v; want %q", c.email, got, err, c.want)
+		}
+	}
+
+	// No restrictions configured: anyone, no override.
+	if got, err := googleRole(config.Config{}, "a@b.org", ""); err != nil || got != "" {
+		t.Errorf("unrestricted: %q, %v", got, err)
+	}
+}
+
+func TestGoogleCallback_JITOff(t *testing.T) {
+	google := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/token":
+			_ = json.NewEncoder(w).Encode(map[string]string{"id_token": "idt"})
+		case "/tokeninfo":
+			_ = json.NewEncoder(w).Encode(map[string]string{
+				"iss": "accounts.google.com", "aud": "client-1", "sub": "g-42",
+				"email": "newbie@example.edu", "email_verified": "true",
+			})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(google.Close)
+	oldToken, oldInfo := googleTokenURL, googleTokenInfoURL
+	googleTokenURL, googleTokenInfoURL = google.URL+"/token", google.URL+"/tokeninfo"
+	t.Cleanup(func() { googleTokenURL, googleTokenInfoURL = oldToken, oldInfo })
+
+	dsn := "file:" + filepath.Join(t.TempDir(), "test.db") + "?_pragma=busy_timeout(5000)"
+	dbh, err := db.Open(context.Background(), db.DriverSQLite, dsn)
+	if err != nil {
+		t.Fatalf("db open: %v", err)
+	}
+	t.Cleanup(func() { _ = dbh.Close() })
+
+	cfg := config.Config{GoogleClientID: "client-1", PublicURL: "https://lms.example"}
+	h := GoogleCallbackHandler(authmw.NewAuthService("test"), dbh, cfg)
+	callback := func() *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h(rec, httptest.NewRequest(http.MethodGet, "/auth/google/callback?state=s&code=c", nil))
+		return rec
+	}
+	users := func() int {
+		var n int
+		if err := dbh.QueryRow(`SELECT COUNT(*) FROM users WHERE username='newbie@example.edu'`).Scan(&n); err != nil {
+			t.Fatal(err)
+		}
+		return n
+	}
+
+	// Unknown account: refused, and nothing is created behind the refusal.
+	if rec := callback(); rec.Code != http.StatusForbidden {
+		t.Fatalf("unprovisioned: status %d, want 403 (%s)", rec.Code, rec.Body)
+	}
+	if n := users(); n != 0 {
+		t.Fatalf("unprovisioned: %d user rows created, want 0", n)
+	}
+
+	// Once an administrator creates the account, the same login goes through
+	// as that row, not as a fresh google| identity.
+	if _, err := dbh.Exec(`INSERT INTO users (id, username, role) VALUES ('u-newbie','newbie@example.edu','teacher')`); err != nil {
+		t.Fatal(err)
+	}
+	rec := callback()
+	if rec.Code != http.StatusFound {
+		t.Fatalf("provisioned: status %d, want 302 (%s)", rec.Code, rec.Body)
+	}
+	var tok string
+	for _, c := range rec.Result().Cookies() {
+		if c.Name == "me_access_token" {
+			tok = c.Value
+		}
+	}
+	claims, err := authmw.NewAuthService("test").Parse(tok)
+	if err != nil {
+		t.Fatalf("access token: %v", err)
+	}
+	if claims.Sub != "u-newbie" || claims.Role != "teacher" {
+		t.Fatalf("claims = %s/%s, want u-newbie/teacher", claims.Sub, claims.Role)
+	}
+	if n := users(); n != 1 {
+		t.Fatalf("provisioned: %d user rows, want 1", n)
+	}
+}
+
+func TestGoogleCallback_RoleMapOnFirstLogin(t *testing.T) {
+	email := "newbie@example.edu"
+	google := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/token":
+			_ = json.NewEncoder(w).Encode(map[string]string{"id_token": "idt"})
+		case "/tokeninfo":
+			_ = json.NewEncoder(w).Encode(map[string]string{
+				"iss": "accounts.google.com", "aud": "client-1", "sub": "g-42",
+				"email": email, "email_verified": "true",
+			})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(google.Close)
+	oldToken, oldInfo := googleTokenURL, googleTokenInfoURL
+	googleTokenURL, googleTokenInfoURL = google.URL+"/token", google.URL+"/tokeninfo"
+	t.Cleanup(func() { googleTokenURL, googleTokenInfoURL = oldToken, oldInfo })
+
+	dsn := "file:" + filepath.Join(t.TempDir(), "test.db") + "?_pragma=busy_timeout(5000)"
+	dbh, err := db.Open(context.Background(), db.DriverSQLite, dsn)
+	if err != nil {
+		t.Fatalf("db open: %v", err)
+	}
+	t.Cleanup(func() { _ = dbh.Close() })
+
+	cfg := config.Config{
+		GoogleClientID: "client-1", PublicURL: "https://lms.example", JITProvisioning: true,
+		GoogleRoleMap: map[string]string{"example.edu": "teacher"},
+	}
+	h := GoogleCallbackHandler(authmw.NewAuthService("test"), dbh, cfg)
+	login := func() (role, stored string) {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		h(rec, httptest.NewRequest(http.MethodGet, "/auth/google/callback?state=s&code=c", nil))
+		if rec.Code != http.StatusFound {
+			t.Fatalf("%s: status %d, want 302 (%s)", email, rec.Code, rec.Body)
+		}
+		for _, c := range rec.Result().Cookies() {
+			if c.Name == "me_access_token" {
+				claims, err := authmw.NewAuthService("test").Parse(c.Value)
+				if err != nil {
+					t.Fatalf("access token: %v", err)
+				}
+				role = claims.Role
+			}
+		}
+		if err := dbh.QueryRow(`SELECT role FROM users WHERE username=$1`, email).Scan(&stored); err != nil {
+			t.Fatal(err)
+		}
+		return role, stored
+	}
+
+	// A new account gets the mapped role.
+	if role, stored := login(); role != "teacher" || stored != "teacher" {
+		t.Fatalf("first login: token %q, stored %q, want teacher", role, stored)
+	}
+	// An administrator's later change survives the next login.
+	if _, err := dbh.Exec(`UPDATE users SET role='student' WHERE username=$1`, email); err != nil {
+		t.Fatal(err)
+	}
+	if role, stored := login(); role != "student" || stored != "student" {
+		t.Fatalf("after demotion: token %q, stored %q, want student", role, stored)
+	}
+	// So does a pre-provisioned account's role.
+	email = "head@example.edu"
+	if _, err := dbh.Exec(`INSERT INTO users (id, username, role) VALUES ('u-head',$1,'admin')`, email); err != nil {
+		t.Fatal(err)
+	}
+	if role, stored := login(); role != "admin" || stored != "admin" {
+		t.Fatalf("provisioned admin: token %q, stored %q, want admin", role, stored)
+	}
+}
@@ -0,0 +1,49 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+)
+
+// AnonymousPrefix marks server-issued anonymous user ids (attempts.user_id).
+const AnonymousPrefix = "anon-"
+
+// AnonymousCookie carries the anonymous token for browser clients.
+const AnonymousCookie = "me_anon_token"
+
+var ErrBadAnonymousToken = errors.New("bad anonymous token")
+
+// IssueAnonymousToken mints a new anonymous id and its bearer token
+// ("<id>.<mac>"). The id is safe to store and show; only the token proves
+// ownership. Tokens are not JWTs, so JWTMiddleware never accepts them.
+func (a *AuthService) IssueAnonymousToken() (anonID, token string, err error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", "", err
+	}
+	anonID = AnonymousPrefix + hex.EncodeToString(b[:])
+	return anonID, anonID + "." + a.anonMAC(anonID), nil
+}
+
+// ParseAnonymousToken verifies tok and returns its anonymous id.
+func (a *AuthService) ParseAnonymousToken(tok string) (string, error) {
+	id, mac, ok := strings.Cut(strings.TrimSpace(tok), ".")
+	if !ok || !strings.HasPrefix(id, AnonymousPrefix) {
+		return "", ErrBadAnonymousToken
+	}
+	if !hmac.Equal([]byte(mac), []byte(a.anonMAC(id))) {
+		return "", ErrBadAnonymousToken
+	}
+	return id, nil
+}
+
+func (a *AuthService) anonMAC(id string) string {
+	m := hmac.New(sha256.New, a.hmac)
+	m.Write([]byte("anon:" + id)) // domain-separated from JWT signatures
+	return base64.RawURLEncoding.EncodeToString(m.Sum(nil))
+}
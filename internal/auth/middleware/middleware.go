@@ -3,11 +3,13 @@ package auth
 import (
 	"database/sql"
 	"encoding/json"
+	"log/slog"
 	"net/http"
 	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/mind-engage/mindengage-lms/internal/auth/password"
 	"github.com/mind-engage/mindengage-lms/internal/config"
 	"github.com/mind-engage/mindengage-lms/internal/rbac"
 	"golang.org/x/crypto/bcrypt"
@@ -88,6 +90,7 @@ func LoginHandler(a *AuthService, cfg config.Config, db *sql.DB) http.HandlerFun
 					http.Error(w, "invalid credentials", http.StatusUnauthorized)
 					return
 				}
+				rehashIfWeak(db, password.Policy{Cost: cfg.BcryptCost}, id, phash, req.Password)
 				tok, err := a.IssueJWT(id, role) // subject = user ID, role from DB
 				if err != nil {
 					http.Error(w, "issue token", 500)
@@ -112,6 +115,24 @@ func LoginHandler(a *AuthService, cfg config.Config, db *sql.DB) http.HandlerFun
 	}
 }
 
+// rehashIfWeak upgrades a stored hash made at a lower cost than configured,
+// now that the plaintext is known. Best effort: the login succeeds anyway, and
+// the WHERE on the old hash keeps a concurrent password change from being
+// overwritten.
+func rehashIfWeak(db *sql.DB, p password.Policy, userID, oldHash, plain string) {
+	if !p.NeedsRehash(oldHash) {
+		return
+	}
+	h, err := p.Hash(plain)
+	if err != nil {
+		slog.Warn("login: rehash password", "user", userID, "err", err)
+		return
+	}
+	if _, err := db.Exec(`UPDATE users SET password_hash=$1 WHERE id=$2 AND password_hash=$3`, h, userID, oldHash); err != nil {
+		slog.Warn("login: rehash password", "user", userID, "err", err)
+	}
+}
+
 // JWTMiddleware validates the bearer token and injects the user's role into context for RBAC.
 func JWTMiddleware(a *AuthService) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
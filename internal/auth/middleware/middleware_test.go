@@ -0,0 +1,64 @@
+package auth_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+
+	authmw "github.com/mind-engage/mindengage-lms/internal/auth/middleware"
+	"github.com/mind-engage/mindengage-lms/internal/config"
+	"github.com/mind-engage/mindengage-lms/internal/db"
+)
+
+func TestLogin_RehashesLowCostHash(t *testing.T) {
+	dsn := "file:" + filepath.Join(t.TempDir(), "test.db") + "?_pragma=busy_timeout(5000)"
+	dbh, err := db.Open(context.Background(), db.DriverSQLite, dsn)
+	if err != nil {
+		t.Fatalf("db open: %v", err)
+	}
+	t.Cleanup(func() { _ = dbh.Close() })
+
+	legacy, _ := bcrypt.GenerateFromPassword([]byte("s3cret-pass"), bcrypt.MinCost)
+	if _, err := dbh.Exec(`INSERT INTO users (id, username, password_hash, role) VALUES ('u1','alice',$1,'student')`, string(legacy)); err != nil {
+		t.Fatal(err)
+	}
+	h := authmw.LoginHandler(authmw.NewAuthService("test"), config.Config{BcryptCost: bcrypt.MinCost + 1}, dbh)
+	login := func(pw string) int {
+		req := httptest.NewRequest(http.MethodPost, "/auth/login", strings.NewReader(`{"username":"alice","password":"`+pw+`"}`))
+		rec := httptest.NewRecorder()
+		h(rec, req)
+		return rec.Code
+	}
+	storedCost := func() int {
+		var ph string
+		if err := dbh.QueryRow(`SELECT password_hash FROM users WHERE id='u1'`).Scan(&ph); err != nil {
+			t.Fatal(err)
+		}
+		c, err := bcrypt.Cost([]byte(ph))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return c
+	}
+
+	if code := login("wrong"); code != http.StatusUnauthorized {
+		t.Fatalf("bad password: status %d", code)
+	}
+	if c := storedCost(); c != bcrypt.MinCost {
+		t.Fatalf("failed login changed the hash (cost %d)", c)
+	}
+	if code := login("s3cret-pass"); code != http.StatusOK {
+		t.Fatalf("login: status %d", code)
+	}
+	if c := storedCost(); c != bcrypt.MinCost+1 {
+		t.Fatalf("cost after login = %d, want %d", c, bcrypt.MinCost+1)
+	}
+	if code := login("s3cret-pass"); code != http.StatusOK {
+		t.Fatalf("login with upgraded hash: status %d", code)
+	}
+}
@@ -0,0 +1,134 @@
+// Package password holds the policy local-auth passwords must meet when they
+// are set, changed or imported.
+package password
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Policy is the minimum a new password must meet. The zero value accepts
+// anything non-empty.
+type Policy struct {
+	MinLength  int  // in runes
+	MinClasses int  // of lower, upper, digit, symbol
+	DenyCommon bool // reject well-known passwords (see common)
+	Cost       int  // bcrypt cost for new hashes (0 = DefaultCost)
+}
+
+// DefaultCost is the bcrypt cost used unless configured otherwise.
+const DefaultCost = 12
+
+// cost is p.Cost clamped to what bcrypt accepts: below MinCost bcrypt would
+// quietly use its own default, above MaxCost every Hash would fail.
+func (p Policy) cost() int {
+	switch {
+	case p.Cost <= 0:
+		return DefaultCost
+	case p.Cost < bcrypt.MinCost:
+		return bcrypt.MinCost
+	case p.Cost > bcrypt.MaxCost:
+		return bcrypt.MaxCost
+	}
+	return p.Cost
+}
+
+// Validate reports a configured Cost bcrypt cannot use, so a bad BCRYPT_COST
+// fails at startup instead of being clamped silently.
+func (p Policy) Validate() error {
+	if p.Cost != 0 && (p.Cost < bcrypt.MinCost || p.Cost > bcrypt.MaxCost) {
+		return fmt.Errorf("bcrypt cost %d outside %d..%d", p.Cost, bcrypt.MinCost, bcrypt.MaxCost)
+	}
+	return nil
+}
+
+// Hash bcrypts pw at the policy's cost. It does not Check pw.
+func (p Policy) Hash(pw string) (string, error) {
+	b, err := bcrypt.GenerateFromPassword([]byte(pw), p.cost())
+	return string(b), err
+}
+
+// NeedsRehash reports whether hash was made at a lower cost than the policy's
+// (or cannot be read), so a successful login should replace it.
+func (p Policy) NeedsRehash(hash string) bool {
+	c, err := bcrypt.Cost([]byte(hash))
+	return err != nil || c < p.cost()
+}
+
+// Violation is one rule a password broke; Code is stable for clients.
+type Violation struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+const (
+	TooShort    = "too_short"
+	TooFewKinds = "too_few_classes"
+	Common      = "common"
+	Empty       = "empty"
+)
+
+// Check returns every rule pw breaks; nil means it is acceptable.
+func (p Policy) Check(pw string) []Violation {
+	if pw == "" {
+		return []Violation{{Empty, "password is required"}}
+	}
+	var out []Violation
+	if n := len([]rune(pw)); n < p.MinLength {
+		out = append(out, Violation{TooShort, fmt.Sprintf("must be at least %d characters", p.MinLength)})
+	}
+	if p.MinClasses > 1 && classes(pw) < p.MinClasses {
+		out = append(out, Violation{TooFewKinds,
+			fmt.Sprintf("must mix at least %d of: lowercase, uppercase, digits, symbols", p.MinClasses)})
+	}
+	if p.DenyCommon && common[strings.ToLower(pw)] {
+		out = append(out, Violation{Common, "is too common"})
+	}
+	return out
+}
+
+func classes(pw string) int {
+	var lower, upper, digit, other bool
+	for _, r := range pw {
+		switch {
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsDigit(r):
+			digit = true
+		default:
+			other = true
+		}
+	}
+	n := 0
+	for _, b := range []bool{lower, upper, digit, other} {
+		if b {
+			n++
+		}
+	}
+	return n
+}
+
+// common: the most frequent leaked passwords (lowercased), plus the obvious
+// ones for a school LMS.
+var common = func() map[string]bool {
+	m := map[string]bool{}
+	for _, s := range strings.Fields(`
+		123456 123456789 12345678 1234567890 12345 1234567 111111 000000
+		123123 654321 666666 121212 112233 123321 987654321 1q2w3e4r
+		qwerty qwerty123 qwertyuiop 1qaz2wsx asdfghjkl zxcvbnm azerty
+		password password1 password123 passw0rd p@ssw0rd letmein welcome
+		welcome1 admin admin123 administrator root changeme default
+		iloveyou monkey dragon sunshine princess football baseball
+		superman batman trustno1 master shadow michael abc123 abcd1234
+		student student1 student123 teacher teacher1 teacher123 school
+		school123 mindengage exam1234 secret secret123
+	`) {
+		m[s] = true
+	}
+	return m
+}()
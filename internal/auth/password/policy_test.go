@@ -0,0 +1,48 @@
+package password
+
+import (
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestCheck(t *testing.T) {
+	p := Policy{MinLength: 10, MinClasses: 3, DenyCommon: true}
+
+	weak := p.Check("password")
+	codes := map[string]bool{}
+	for _, v := range weak {
+		codes[v.Code] = true
+	}
+	if !codes[TooShort] || !codes[TooFewKinds] || !codes[Common] {
+		t.Fatalf("weak password violations = %+v", weak)
+	}
+
+	if v := p.Check("Correct-horse-42"); v != nil {
+		t.Fatalf("strong password rejected: %+v", v)
+	}
+	if v := (Policy{}).Check(""); len(v) != 1 || v[0].Code != Empty {
+		t.Fatalf("empty password: %+v", v)
+	}
+}
+
+func TestCost_Clamped(t *testing.T) {
+	for _, c := range []struct{ in, want int }{
+		{0, DefaultCost}, {2, bcrypt.MinCost}, {10, 10}, {99, bcrypt.MaxCost},
+	} {
+		if got := (Policy{Cost: c.in}).cost(); got != c.want {
+			t.Errorf("cost(%d) = %d, want %d", c.in, got, c.want)
+		}
+	}
+	for _, bad := range []int{-1, 2, 32} {
+		if err := (Policy{Cost: bad}).Validate(); err == nil {
+			t.Errorf("Validate accepted cost %d", bad)
+		}
+	}
+	if err := (Policy{Cost: 0}).Validate(); err != nil {
+		t.Errorf("zero cost: %v", err)
+	}
+	if err := (Policy{Cost: 12}).Validate(); err != nil {
+		t.Errorf("cost 12: %v", err)
+	}
+}
@@ -0,0 +1,82 @@
+// internal/basepath/basepath.go
+package basepath
+
+import (
+	"context"
+	"net/http"
+	"strings"
+)
+
+/*
+Package basepath lets the gateway run under a reverse-proxy prefix such as
+/lms. Strip removes the prefix before routing, so routes stay mounted at
+their usual paths, and remembers it on the request so handlers that build
+links (redirects, share URLs, asset URLs) can put it back:
+
+	h := basepath.Strip(cfg.BasePath)(router)
+	...
+	http.Redirect(w, r, basepath.Join(r, "/exam/"), http.StatusFound)
+*/
+
+type ctxKey struct{}
+
+// Clean normalizes a configured prefix: "" or "/" -> "", "lms/" -> "/lms".
+func Clean(prefix string) string {
+	prefix = strings.Trim(strings.TrimSpace(prefix), "/")
+	if prefix == "" {
+		return ""
+	}
+	return "/" + prefix
+}
+
+// Strip serves requests under prefix with the prefix removed and 404s the
+// rest. An empty prefix is a no-op.
+func Strip(prefix string) func(http.Handler) http.Handler {
+	prefix = Clean(prefix)
+	return func(next http.Handler) http.Handler {
+		if prefix == "" {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rest, ok := cut(r.URL.Path, prefix)
+			if !ok {
+				http.NotFound(w, r)
+				return
+			}
+			r2 := r.WithContext(context.WithValue(r.Context(), ctxKey{}, prefix))
+			u := *r.URL
+			u.Path = rest
+			if r.URL.RawPath != "" {
+				if raw, ok := cut(r.URL.RawPath, prefix); ok {
+					u.RawPath = raw
+				} else {
+					u.RawPath = ""
+				}
+			}
+			r2.URL = &u
+			next.ServeHTTP(w, r2)
+		})
+	}
+}
+
+// From returns the prefix the request was served under ("" at the root).
+func From(ctx context.Context) string {
+	p, _ := ctx.Value(ctxKey{}).(string)
+	return p
+}
+
+// Join prefixes an absolute path with the request's base path.
+func Join(r *http.Request, path string) string {
+	return From(r.Context()) + path
+}
+
+// "/lms" -> "/", "/lms/api/x" -> "/api/x"; "/lmsx" does not match.
+func cut(path, prefix string) (string, bool) {
+	if path == prefix {
+		return "/", true
+	}
+	if rest, ok := strings.CutPrefix(path, prefix); ok && strings.HasPrefix(rest, "/") {
+		return rest, true
+	}
+	return "", false
+}
@@ -0,0 +1,59 @@
+package basepath_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/mind-engage/mindengage-lms/internal/basepath"
+)
+
+func TestStrip_RoutesUnderPrefix(t *testing.T) {
+	r := chi.NewRouter()
+	r.Get("/api/healthz", func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) })
+	r.Get("/api/courses/{id}", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(chi.URLParam(r, "id")))
+	})
+	r.Get("/exam", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, basepath.Join(r, "/exam/"), http.StatusPermanentRedirect)
+	})
+	r.Get("/", func(w http.ResponseWriter, r *http.Request) { _, _ = w.Write([]byte("home")) })
+	h := basepath.Strip("lms/")(r)
+
+	get := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec
+	}
+
+	for path, want := range map[string]int{
+		"/lms/api/healthz":  http.StatusOK,
+		"/api/healthz":      http.StatusNotFound, // only served under the prefix
+		"/lmsx/api/healthz": http.StatusNotFound,
+		"/lms":              http.StatusOK,
+		"/lms/":             http.StatusOK,
+	} {
+		if rec := get(path); rec.Code != want {
+			t.Errorf("%s: status = %d, want %d", path, rec.Code, want)
+		}
+	}
+	if rec := get("/lms/api/courses/a%2Fb"); rec.Body.String() != "a%2Fb" {
+		t.Errorf("escaped param = %q", rec.Body.String())
+	}
+	if rec := get("/lms/exam"); rec.Header().Get("Location") != "/lms/exam/" {
+		t.Errorf("redirect = %q, want /lms/exam/", rec.Header().Get("Location"))
+	}
+}
+
+func TestStrip_NoPrefix(t *testing.T) {
+	called := false
+	h := basepath.Strip("/")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = basepath.From(r.Context()) == "" && r.URL.Path == "/api/x"
+	}))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/x", nil))
+	if !called {
+		t.Fatal("root deployment should pass requests through untouched")
+	}
+}
@@ -0,0 +1,42 @@
+// Package buildinfo carries the version stamped into the binary at link time:
+//
+//	go build -ldflags "-X github.com/mind-engage/mindengage-lms/internal/buildinfo.Version=v1.2.3 \
+//	  -X github.com/mind-engage/mindengage-lms/internal/buildinfo.Commit=$(git rev-parse HEAD) \
+//	  -X github.com/mind-engage/mindengage-lms/internal/buildinfo.BuildTime=$(date -u +%FT%TZ)"
+package buildinfo
+
+import "runtime/debug"
+
+// Set via -ldflags -X; see the package doc.
+var (
+	Version   = "dev"
+	Commit    = ""
+	BuildTime = ""
+)
+
+// Info is what GET /api/version reports about the running binary.
+type Info struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildTime string `json:"build_time"`
+}
+
+// Get returns the stamped values, falling back to the VCS data the Go
+// toolchain records when ldflags were not set.
+func Get() Info {
+	info := Info{Version: Version, Commit: Commit, BuildTime: BuildTime}
+	if info.Commit != "" && info.BuildTime != "" {
+		return info
+	}
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		for _, s := range bi.Settings {
+			switch {
+			case s.Key == "vcs.revision" && info.Commit == "":
+				info.Commit = s.Value
+			case s.Key == "vcs.time" && info.BuildTime == "":
+				info.BuildTime = s.Value
+			}
+		}
+	}
+	return info
+}
@@ -1,8 +1,14 @@
 package config
 
 import (
+	"net/http"
+	"net/url"
 	"os"
+	"strconv"
 	"strings"
+	"time"
+
+	"github.com/mind-engage/mindengage-lms/internal/basepath"
 )
 
 type Mode string
@@ -16,10 +22,38 @@ type Config struct {
 	Mode      Mode
 	HTTPAddr  string
 	PublicURL string
+	BasePath  string // reverse-proxy prefix, e.g. "/lms" ("" = served at the root)
+	// MetricsAddr is the internal listener for Prometheus /metrics, kept off
+	// the public router; "" turns metrics serving off.
+	MetricsAddr string
+
+	// HTTP server timeouts (see HTTPServer). ReadHeaderTimeout is the
+	// slowloris guard; WriteTimeout must exceed the 30s handler timeout.
+	HTTPReadHeaderTimeout time.Duration
+	HTTPReadTimeout       time.Duration
+	HTTPWriteTimeout      time.Duration
+	HTTPIdleTimeout       time.Duration
+	// Cap on non-multipart request bodies under /api (see httpsec.MaxJSONBody).
+	MaxJSONBodyBytes int64
+	// Reject unknown fields in create/update bodies (courses, offerings, exams).
+	StrictJSON bool
+	// Caps on uploaded exams (0 = none): questions per exam, choices per question.
+	MaxExamQuestions   int
+	MaxQuestionChoices int
+	// Decimals scores and points are rounded to in API responses (< 0 = none).
+	ScoreDecimals int
 
 	DBDriver string
 	DBDSN    string
 
+	// Pool tuning (0 = per-driver default; sqlite is always 1 open conn).
+	DBMaxOpenConns    int
+	DBMaxIdleConns    int
+	DBConnMaxLifetime time.Duration
+
+	// How often attempts left pending by grading_mode=deferred are graded.
+	DeferredGradingInterval time.Duration
+
 	BlobDriver   string // fs|minio|gcs
 	BlobBasePath string // for fs/minio
 
@@ -28,22 +62,69 @@ type Config struct {
 	EnableGoogleAuth bool
 	EnableLTI        bool
 	EnableJWKS       bool
+	// JITProvisioning creates users on their first Google/LTI login; when
+	// off, only pre-created users may sign in that way.
+	JITProvisioning bool
 
 	AdminUser     string
 	AdminPassHash string // bcrypt
 
+	// Local-auth password policy for set/change/import (see password.Policy).
+	PasswordMinLength  int
+	PasswordMinClasses int
+	PasswordDenyCommon bool
+	// bcrypt cost for new hashes; lower-cost hashes are upgraded on login.
+	BcryptCost int
+
 	CORSOriginsOnline  []string
 	CORSOriginsOffline []string
+	// Per route group (see httpsec.CORS): anonymous endpoints default to any
+	// origin without credentials; admin defaults to the mode's origins.
+	CORSOriginsPublic []string
+	CORSOriginsAdmin  []string
+
+	// Content-Security-Policy for the SPAs (empty = httpsec.DefaultCSP).
+	CSP           string
+	CSPReportOnly bool
+	AssetHost     string // CDN origin the default CSP also allows, e.g. https://cdn.example.com
+	// LMS origins allowed to frame the SPAs (default CSP frame-ancestors);
+	// with LTI on, defaults to the origin of LTIPlatformAuthURL.
+	LTIFrameAncestors []string
+
+	// TimeZone is the tenant's IANA zone (e.g. America/New_York) offering
+	// windows are also rendered in; "UTC" by default.
+	TimeZone string
 
 	// LTI 1.3 / OIDC (Tool-side)
 	LTIPlatformAuthURL string
 	LTIToolClientID    string
 	LTIToolRedirectURI string
+	// LTIToolClientSecret authenticates grade passback (AGS) at the
+	// platforms' token endpoints.
+	LTIToolClientSecret string
+	// Tenant whose signing keys /.well-known/jwks.json publishes; with
+	// LTITenantDomain set, {tenant}.{domain} hosts pick their own tenant.
+	LTITenant       string
+	LTITenantDomain string
+	// The platform's key store (platformd's PLATFORM_DB_* and
+	// PLATFORM_KEYS_* settings). The JWKS publishes the keys kept there, so
+	// it survives restarts and matches across replicas; without a DSN the
+	// key set is empty.
+	PlatformDBDriver        string
+	PlatformDBDSN           string
+	PlatformKeysMasterKey   string
+	PlatformKeysGrace       time.Duration
+	PlatformKeysRotateEvery time.Duration
 
 	GoogleClientID     string
 	GoogleClientSecret string
 	GoogleRedirectURI  string // e.g., PUBLIC_URL + "/api/auth/google/callback"
 	GoogleAllowedHD    string // optional: re
+	// Sign-up restriction by email domain (empty = any) and the role given to
+	// accounts created at their first Google login, keyed by full email or
+	// domain, e.g. "example.edu=teacher,head@example.edu=admin".
+	GoogleAllowedDomains []string
+	GoogleRoleMap        map[string]string
 }
 
 func FromEnv() Config {
@@ -56,14 +137,22 @@ func FromEnv() Config {
 		addr = ":8080"
 	}
 	pub := os.Getenv("PUBLIC_URL")
+	base := basepath.Clean(os.Getenv("BASE_PATH"))
+	ext := Config{PublicURL: pub, BasePath: base}
+	metricsAddr, set := os.LookupEnv("METRICS_ADDR") // set but empty = off
+	if !set {
+		metricsAddr = "127.0.0.1:9090"
+	}
 	defRedirect := ""
 	if pub != "" {
-		defRedirect = strings.TrimSuffix(pub, "/") + "/api/lti/launch"
+		defRedirect = ext.ExternalURL("/api/lti/launch")
 	}
-	return Config{
+	cfg := Config{
 		Mode:               mode,
 		HTTPAddr:           addr,
+		MetricsAddr:        metricsAddr,
 		PublicURL:          pub,
+		BasePath:           base,
 		DBDriver:           envOr("DB_DRIVER", "sqlite"),
 		DBDSN:              envOr("DB_DSN", ""),
 		BlobDriver:         envOr("BLOB_DRIVER", "fs"),
@@ -71,24 +160,112 @@ func FromEnv() Config {
 		EnableLocalAuth:    envBool("ENABLE_LOCAL_AUTH", true),
 		EnableLTI:          envBool("ENABLE_LTI", mode == ModeOnline),
 		EnableJWKS:         envBool("ENABLE_JWKS", mode == ModeOnline),
+		JITProvisioning:    envBool("JIT_PROVISIONING", true),
 		AdminUser:          envOr("ADMIN_USER", "admin"),
 		AdminPassHash:      envOr("ADMIN_PASS_HASH", "$2y$12$pyZAiWaTfVtM7UElIRStvOC3gNbnp70nmQU4eYopLGBfCJr1DOvji"),
 		CORSOriginsOnline:  csvOr("CORS_ORIGINS_ONLINE", "https://lms.mindengage.ai"),
 		CORSOriginsOffline: csvOr("CORS_ORIGINS_OFFLINE", "http://localhost:3000,http://localhost:3010,http://localhost:3020"),
 
-		LTIPlatformAuthURL: envOr("LTI_PLATFORM_AUTH_URL", "https://platform.mindengage.ai/oidc/auth"),
-		LTIToolClientID:    envOr("LTI_TOOL_CLIENT_ID", "TOOL_CLIENT_ID"),
-		LTIToolRedirectURI: envOr("LTI_TOOL_REDIRECT_URI", defRedirect),
+		LTIPlatformAuthURL:  envOr("LTI_PLATFORM_AUTH_URL", "https://platform.mindengage.ai/oidc/auth"),
+		LTIToolClientID:     envOr("LTI_TOOL_CLIENT_ID", "TOOL_CLIENT_ID"),
+		LTIToolRedirectURI:  envOr("LTI_TOOL_REDIRECT_URI", defRedirect),
+		LTIToolClientSecret: os.Getenv("LTI_TOOL_CLIENT_SECRET"),
+		LTITenant:           envOr("LTI_TENANT", "default"),
+		LTITenantDomain:     os.Getenv("LTI_TENANT_DOMAIN"),
+
+		PlatformDBDriver:        envOr("PLATFORM_DB_DRIVER", "postgres"),
+		PlatformDBDSN:           os.Getenv("PLATFORM_DB_DSN"),
+		PlatformKeysMasterKey:   os.Getenv("PLATFORM_KEYS_MASTER_KEY"),
+		PlatformKeysGrace:       envDuration("PLATFORM_KEYS_GRACE_PERIOD", 7*24*time.Hour),
+		PlatformKeysRotateEvery: envDuration("PLATFORM_KEYS_ROTATE_EVERY", 90*24*time.Hour),
 
 		EnableGoogleAuth: envBool("ENABLE_GOOGLE_AUTH", false),
 		EnableGuestAuth:  envBool("ENABLE_GUEST_AUTH", false),
 
-		GoogleClientID:     os.Getenv("GOOGLE_CLIENT_ID"),
-		GoogleClientSecret: os.Getenv("GOOGLE_CLIENT_SECRET"),
-		GoogleRedirectURI:  envOr("GOOGLE_REDIRECT_URI", strings.TrimSuffix(pub, "/")+"/api/auth/google/callback"),
-		GoogleAllowedHD:    os.Getenv("GOOGLE_ALLOWED_HD"),
+		GoogleClientID:       os.Getenv("GOOGLE_CLIENT_ID"),
+		GoogleClientSecret:   os.Getenv("GOOGLE_CLIENT_SECRET"),
+		GoogleRedirectURI:    envOr("GOOGLE_REDIRECT_URI", ext.ExternalURL("/api/auth/google/callback")),
+		GoogleAllowedHD:      os.Getenv("GOOGLE_ALLOWED_HD"),
+		GoogleAllowedDomains: csvOr("GOOGLE_ALLOWED_DOMAINS", ""),
+		GoogleRoleMap:        kvOr("GOOGLE_ROLE_MAP"),
+
+		HTTPReadHeaderTimeout: envDuration("HTTP_READ_HEADER_TIMEOUT", 10*time.Second),
+		HTTPReadTimeout:       envDuration("HTTP_READ_TIMEOUT", 60*time.Second),
+		HTTPWriteTimeout:      envDuration("HTTP_WRITE_TIMEOUT", 60*time.Second),
+		HTTPIdleTimeout:       envDuration("HTTP_IDLE_TIMEOUT", 120*time.Second),
+		MaxJSONBodyBytes:      int64(envInt("MAX_JSON_BODY_BYTES", 10<<20)),
+		StrictJSON:            envBool("STRICT_JSON", false),
+		MaxExamQuestions:      envInt("MAX_EXAM_QUESTIONS", 1000),
+		MaxQuestionChoices:    envInt("MAX_QUESTION_CHOICES", 50),
+		ScoreDecimals:         envInt("SCORE_DECIMALS", 2),
+
+		DBMaxOpenConns:    envInt("DB_MAX_OPEN_CONNS", 0),
+		DBMaxIdleConns:    envInt("DB_MAX_IDLE_CONNS", 0),
+		DBConnMaxLifetime: envDuration("DB_CONN_MAX_LIFETIME", 0),
+
+		DeferredGradingInterval: envDuration("DEFERRED_GRADING_INTERVAL", 30*time.Second),
+
+		CSP:           os.Getenv("CSP"),
+		CSPReportOnly: envBool("CSP_REPORT_ONLY", false),
+		AssetHost:     os.Getenv("ASSET_HOST"),
+
+		TimeZone: envOr("TIME_ZONE", "UTC"),
+
+		PasswordMinLength:  envInt("PASSWORD_MIN_LENGTH", 8),
+		PasswordMinClasses: envInt("PASSWORD_MIN_CLASSES", 1),
+		PasswordDenyCommon: envBool("PASSWORD_DENY_COMMON", true),
+		BcryptCost:         envInt("BCRYPT_COST", 12),
+	}
+	if cfg.EnableLTI {
+		cfg.LTIFrameAncestors = csvOr("LTI_FRAME_ANCESTORS", originOf(cfg.LTIPlatformAuthURL))
+	}
+	cfg.CORSOriginsPublic = csvOr("CORS_ORIGINS_PUBLIC", "*")
+	cfg.CORSOriginsAdmin = csvOr("CORS_ORIGINS_ADMIN", strings.Join(cfg.CORSOrigins(), ","))
+	return cfg
+}
+
+// ExternalURL is the public URL of a gateway path: PUBLIC_URL plus BASE_PATH
+// (unless PUBLIC_URL already ends with it). Without PUBLIC_URL it is just the
+// prefixed path.
+func (c Config) ExternalURL(path string) string {
+	pub := strings.TrimRight(c.PublicURL, "/")
+	if !strings.HasSuffix(pub, c.BasePath) {
+		pub += c.BasePath
+	}
+	return pub + path
+}
+
+// CORSOrigins are the front-end origins allowed on authenticated APIs in the
+// current mode.
+func (c Config) CORSOrigins() []string {
+	if c.Mode == ModeOnline {
+		return c.CORSOriginsOnline
+	}
+	return c.CORSOriginsOffline
+}
+
+// HTTPServer builds the gateway's *http.Server with the configured timeouts.
+// Zero values fall back to the same defaults FromEnv uses.
+func (c Config) HTTPServer(h http.Handler) *http.Server {
+	return &http.Server{
+		Addr:              c.HTTPAddr,
+		Handler:           h,
+		ReadHeaderTimeout: durOr(c.HTTPReadHeaderTimeout, 10*time.Second),
+		ReadTimeout:       durOr(c.HTTPReadTimeout, 60*time.Second),
+		WriteTimeout:      durOr(c.HTTPWriteTimeout, 60*time.Second),
+		IdleTimeout:       durOr(c.HTTPIdleTimeout, 120*time.Second),
 	}
 }
+
+// originOf is scheme://host of u, or "" if u isn't an absolute URL.
+func originOf(u string) string {
+	p, err := url.Parse(u)
+	if err != nil || p.Scheme == "" || p.Host == "" {
+		return ""
+	}
+	return p.Scheme + "://" + p.Host
+}
+
 func envOr(k, def string) string {
 	v := os.Getenv(k)
 	if v == "" {
@@ -117,3 +294,33 @@ func csvOr(k, def string) []string {
 	}
 	return out
 }
+
+// kvOr parses "k=v,k=v" (keys lowercased); entries without "=" are skipped.
+func kvOr(k string) map[string]string {
+	out := map[string]string{}
+	for _, p := range csvOr(k, "") {
+		key, val, ok := strings.Cut(p, "=")
+		if key, val = strings.ToLower(strings.TrimSpace(key)), strings.TrimSpace(val); ok && key != "" && val != "" {
+			out[key] = val
+		}
+	}
+	return out
+}
+func envDuration(k string, def time.Duration) time.Duration {
+	if d, err := time.ParseDuration(os.Getenv(k)); err == nil && d > 0 {
+		return d
+	}
+	return def
+}
+func durOr(d, def time.Duration) time.Duration {
+	if d > 0 {
+		return d
+	}
+	return def
+}
+func envInt(k string, def int) int {
+	if n, err := strconv.Atoi(os.Getenv(k)); err == nil {
+		return n
+	}
+	return def
+}
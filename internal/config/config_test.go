@@ -0,0 +1,102 @@
+package config_test
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/mind-engage/mindengage-lms/internal/config"
+)
+
+func TestHTTPServer_Defaults(t *testing.T) {
+	srv := config.Config{}.HTTPServer(http.NotFoundHandler())
+	if srv.ReadHeaderTimeout <= 0 || srv.ReadTimeout <= 0 || srv.WriteTimeout <= 0 || srv.IdleTimeout <= 0 {
+		t.Fatalf("zero timeout in %+v", srv)
+	}
+}
+
+func TestHTTPServer_CutsOffSlowHeaders(t *testing.T) {
+	srv := config.Config{HTTPReadHeaderTimeout: 100 * time.Millisecond}.HTTPServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() { _ = srv.Serve(ln) }()
+	t.Cleanup(func() { _ = srv.Close() })
+
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	// Start a request but never finish the header block.
+	if _, err := io.WriteString(conn, "GET / HTTP/1.1\r\nHost: x\r\nX-Slow: "); err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now()
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	_, err = io.ReadAll(conn)
+	if ne, ok := err.(net.Error); ok && ne.Timeout() {
+		t.Fatal("server did not close the slow connection")
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Fatalf("connection closed after %v, want ~100ms", elapsed)
+	}
+}
+
+func TestExternalURL_BasePath(t *testing.T) {
+	for _, c := range []struct {
+		pub, base, want string
+	}{
+		{"https://lms.example.com", "", "https://lms.example.com/exam/"},
+		{"https://lms.example.com/", "/lms", "https://lms.example.com/lms/exam/"},
+		{"https://example.com/lms", "/lms", "https://example.com/lms/exam/"}, // PUBLIC_URL already has it
+		{"", "/lms", "/lms/exam/"},
+	} {
+		cfg := config.Config{PublicURL: c.pub, BasePath: c.base}
+		if got := cfg.ExternalURL("/exam/"); got != c.want {
+			t.Errorf("ExternalURL(%q, %q) = %q, want %q", c.pub, c.base, got, c.want)
+		}
+	}
+
+	t.Setenv("PUBLIC_URL", "https://example.com")
+	t.Setenv("BASE_PATH", "lms/")
+	cfg := config.FromEnv()
+	if cfg.BasePath != "/lms" || cfg.GoogleRedirectURI != "https://example.com/lms/api/auth/google/callback" {
+		t.Fatalf("base = %q, google redirect = %q", cfg.BasePath, cfg.GoogleRedirectURI)
+	}
+}
+
+func TestFromEnv_LTIFrameAncestors(t *testing.T) {
+	t.Setenv("ENABLE_LTI", "1")
+	t.Setenv("LTI_PLATFORM_AUTH_URL", "https://canvas.example.edu/api/lti/authorize_redirect")
+	if got := config.FromEnv().LTIFrameAncestors; len(got) != 1 || got[0] != "https://canvas.example.edu" {
+		t.Fatalf("default frame ancestors = %v", got)
+	}
+	t.Setenv("LTI_FRAME_ANCESTORS", "https://a.example.edu,https://b.example.edu")
+	if got := config.FromEnv().LTIFrameAncestors; len(got) != 2 {
+		t.Fatalf("frame ancestors = %v", got)
+	}
+	t.Setenv("ENABLE_LTI", "0")
+	if got := config.FromEnv().LTIFrameAncestors; len(got) != 0 {
+		t.Fatalf("frame ancestors with LTI off = %v", got)
+	}
+}
+
+func TestFromEnv_MetricsAddr(t *testing.T) {
+	t.Setenv("METRICS_ADDR", "")
+	if got := config.FromEnv().MetricsAddr; got != "" {
+		t.Fatalf("METRICS_ADDR empty: addr = %q, want off", got)
+	}
+	os.Unsetenv("METRICS_ADDR") // restored by t.Setenv's cleanup
+	if got := config.FromEnv().MetricsAddr; got != "127.0.0.1:9090" {
+		t.Fatalf("default metrics addr = %q, want loopback", got)
+	}
+}
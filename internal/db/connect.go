@@ -4,9 +4,10 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"time"
 
-	_ "github.com/jackc/pgx/v5/stdlib" // driver: pgx
-	_ "modernc.org/sqlite"             // driver: sqlite
+	"github.com/jackc/pgx/v5/stdlib" // driver: pgx
+	_ "modernc.org/sqlite"           // driver: sqlite
 )
 
 type Driver string
@@ -16,8 +17,41 @@ const (
 	DriverPostgres Driver = "postgres"
 )
 
-// Open opens a DB and ensures schema exists.
+// DriverOf reports which driver opened db, for callers that only hold the
+// *sql.DB but need driver-specific SQL (e.g. row locks).
+func DriverOf(db *sql.DB) Driver {
+	if _, ok := db.Driver().(*stdlib.Driver); ok {
+		return DriverPostgres
+	}
+	return DriverSQLite
+}
+
+// PoolConfig tunes the database/sql connection pool. Zero fields use the
+// per-driver defaults from DefaultPool.
+type PoolConfig struct {
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+}
+
+// DefaultPool returns sane pool defaults for driver. SQLite allows a single
+// writer, so its pool is pinned to one connection.
+func DefaultPool(driver Driver) PoolConfig {
+	if driver == DriverSQLite {
+		return PoolConfig{MaxOpenConns: 1, MaxIdleConns: 1, ConnMaxLifetime: 0}
+	}
+	return PoolConfig{MaxOpenConns: 20, MaxIdleConns: 10, ConnMaxLifetime: 30 * time.Minute, ConnMaxIdleTime: 5 * time.Minute}
+}
+
+// Open opens a DB with DefaultPool settings and ensures schema exists.
 func Open(ctx context.Context, driver Driver, dsn string) (*sql.DB, error) {
+	return OpenWithPool(ctx, driver, dsn, PoolConfig{})
+}
+
+// OpenWithPool is Open with explicit pool settings. For SQLite, MaxOpenConns
+// is capped at 1 regardless of configuration.
+func OpenWithPool(ctx context.Context, driver Driver, dsn string, pool PoolConfig) (*sql.DB, error) {
 	var drvName string
 	switch driver {
 	case DriverSQLite:
@@ -38,17 +72,56 @@ func Open(ctx context.Context, driver Driver, dsn string) (*sql.DB, error) {
 	if err != nil {
 		return nil, err
 	}
+	applyPool(db, driver, pool)
 	if err := db.PingContext(ctx); err != nil {
+		_ = db.Close()
 		return nil, err
 	}
 
+	// SQLite PRAGMAs: WAL lets readers proceed during writes, busy_timeout
+	// waits on locks instead of failing with "database is locked". The pool
+	// is a single long-lived conn, so connection-scoped pragmas stick.
+	if driver == DriverSQLite {
+		if _, err := db.ExecContext(ctx, sqlitePragmas); err != nil {
+			_ = db.Close()
+			return nil, fmt.Errorf("sqlite pragmas: %w", err)
+		}
+	}
+
 	if err := ensureSchema(ctx, db, driver); err != nil {
+		_ = db.Close()
 		return nil, err
 	}
 	// No migrations: fresh DB assumed.
 	return db, nil
 }
 
+func applyPool(db *sql.DB, driver Driver, p PoolConfig) {
+	def := DefaultPool(driver)
+	if p.MaxOpenConns <= 0 {
+		p.MaxOpenConns = def.MaxOpenConns
+	}
+	if p.MaxIdleConns <= 0 {
+		p.MaxIdleConns = def.MaxIdleConns
+	}
+	if p.ConnMaxLifetime <= 0 {
+		p.ConnMaxLifetime = def.ConnMaxLifetime
+	}
+	if p.ConnMaxIdleTime <= 0 {
+		p.ConnMaxIdleTime = def.ConnMaxIdleTime
+	}
+	if driver == DriverSQLite {
+		p.MaxOpenConns = 1
+	}
+	if p.MaxIdleConns > p.MaxOpenConns {
+		p.MaxIdleConns = p.MaxOpenConns
+	}
+	db.SetMaxOpenConns(p.MaxOpenConns)
+	db.SetMaxIdleConns(p.MaxIdleConns)
+	db.SetConnMaxLifetime(p.ConnMaxLifetime)
+	db.SetConnMaxIdleTime(p.ConnMaxIdleTime)
+}
+
 func ensureSchema(ctx context.Context, db *sql.DB, driver Driver) error {
 	var schema string
 	switch driver {
@@ -61,6 +134,13 @@ func ensureSchema(ctx context.Context, db *sql.DB, driver Driver) error {
 	return err
 }
 
+const sqlitePragmas = `
+PRAGMA journal_mode = WAL;
+PRAGMA synchronous = NORMAL;
+PRAGMA foreign_keys = ON;
+PRAGMA busy_timeout = 5000;
+`
+
 const schemaSQLite = `
 PRAGMA foreign_keys=ON;
 
@@ -87,6 +167,21 @@ CREATE TABLE IF NOT EXISTS exams (
   policy_json TEXT NOT NULL DEFAULT ''  
 );
 
+-- Question bank: questions reusable across exams (exam.Bank). tags is
+-- ",a,b," lower-cased for LIKE '%,tag,%' search; question_json is the full
+-- question including its answer key.
+CREATE TABLE IF NOT EXISTS question_bank (
+  id            TEXT PRIMARY KEY,
+  q_type        TEXT NOT NULL,
+  prompt_html   TEXT NOT NULL DEFAULT '',
+  tags          TEXT NOT NULL DEFAULT '',
+  question_json TEXT NOT NULL,
+  created_by    TEXT NOT NULL DEFAULT '',
+  created_at    BIGINT NOT NULL,
+  updated_at    BIGINT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_question_bank_type ON question_bank (q_type);
+
 -- ===========================
 -- Courses / enrollment / LOBs
 -- ===========================
@@ -95,7 +190,8 @@ CREATE TABLE IF NOT EXISTS courses (
   id TEXT PRIMARY KEY,
   name TEXT NOT NULL,
   created_by TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
-  created_at INTEGER NOT NULL DEFAULT (strftime('%s','now'))
+  created_at INTEGER NOT NULL DEFAULT (strftime('%s','now')),
+  status TEXT NOT NULL DEFAULT 'active' CHECK (status IN ('active','archived'))
 );
 
 CREATE TABLE IF NOT EXISTS course_teachers (
@@ -124,10 +220,24 @@ CREATE TABLE IF NOT EXISTS exam_offerings (
   time_limit_sec INTEGER,
   max_attempts   INTEGER NOT NULL DEFAULT 1,
   visibility     TEXT NOT NULL DEFAULT 'course' CHECK (visibility IN ('course','public','link')),
-  access_token   TEXT UNIQUE
+  access_token   TEXT UNIQUE,
+  access_token_expires_at BIGINT, -- NULL = the access_token never expires
+  grading_mode   TEXT NOT NULL DEFAULT 'on_submit' CHECK (grading_mode IN ('on_submit','deferred'))
 );
 CREATE INDEX IF NOT EXISTS idx_offerings_course ON exam_offerings(course_id);
 
+-- Extra join tokens for link offerings (e.g. one per class section), next
+-- to exam_offerings.access_token; expires_at NULL = no expiry.
+CREATE TABLE IF NOT EXISTS offering_access_tokens (
+  token       TEXT PRIMARY KEY,
+  offering_id TEXT NOT NULL REFERENCES exam_offerings(id) ON DELETE CASCADE,
+  label       TEXT NOT NULL DEFAULT '',
+  expires_at  BIGINT,
+  created_by  TEXT NOT NULL DEFAULT '',
+  created_at  BIGINT NOT NULL DEFAULT 0
+);
+CREATE INDEX IF NOT EXISTS idx_offering_tokens_offering ON offering_access_tokens(offering_id);
+
 -- Optional: ownership and invitations (future-friendly)
 CREATE TABLE IF NOT EXISTS exam_owners (
   exam_id    TEXT NOT NULL REFERENCES exams(id)   ON DELETE CASCADE,
@@ -164,8 +274,15 @@ CREATE TABLE IF NOT EXISTS attempts (
   offering_id TEXT REFERENCES exam_offerings(id) ON DELETE SET NULL,
   graded_at    BIGINT,
   auto_score   DOUBLE PRECISION NOT NULL DEFAULT 0,
-  manual_score DOUBLE PRECISION NOT NULL DEFAULT 0 
+  manual_score DOUBLE PRECISION NOT NULL DEFAULT 0,
+  last_seen_at BIGINT, -- last client heartbeat (unix seconds)
+  grading_progress TEXT NOT NULL DEFAULT '', -- ''|pending|graded, see exam.GradingPending
+  questions_json TEXT NOT NULL DEFAULT '', -- blueprint exams: this attempt's drawn questions
+  draw_seed BIGINT NOT NULL DEFAULT 0,
+  responses_updated_at BIGINT NOT NULL DEFAULT 0 -- unix milliseconds of the last save
 );
+-- Deferred grading scans (exam.GradingWorker); empty unless some are pending.
+CREATE INDEX IF NOT EXISTS idx_attempts_grading_pending ON attempts (submitted_at, id) WHERE grading_progress = 'pending';
 
 CREATE TABLE IF NOT EXISTS attempt_items (
   attempt_id    TEXT    NOT NULL,
@@ -209,6 +326,36 @@ CREATE TABLE IF NOT EXISTS ephemeral_stats (
 
 CREATE INDEX IF NOT EXISTS idx_ephem_stats_off_q
   ON ephemeral_stats (offering_id, question_id);
+
+-- Cached item analysis over submitted attempts, per exam. Rows are dropped
+-- whenever an attempt of the exam is submitted or (re)graded and rebuilt on
+-- the next read.
+CREATE TABLE IF NOT EXISTS exam_stats (
+  exam_id       TEXT NOT NULL,
+  question_id   TEXT NOT NULL,      -- "*" = totals over attempt scores
+  count         BIGINT NOT NULL DEFAULT 0,
+  correct       BIGINT NOT NULL DEFAULT 0,      -- full-credit items
+  sum_points    DOUBLE PRECISION NOT NULL DEFAULT 0,
+  max_points    DOUBLE PRECISION NOT NULL DEFAULT 0,
+  updated_at    BIGINT NOT NULL,                -- unix seconds
+  PRIMARY KEY (exam_id, question_id)
+);
+
+-- AGS passback intents, written in the submit transaction and delivered by
+-- exam.OutboxWorker (at least once). Undelivered rows have delivered_at NULL.
+CREATE TABLE IF NOT EXISTS passback_outbox (
+  id            INTEGER PRIMARY KEY AUTOINCREMENT,
+  attempt_id    TEXT NOT NULL,
+  exam_id       TEXT NOT NULL,
+  user_id       TEXT NOT NULL,
+  score         DOUBLE PRECISION NOT NULL,
+  created_at    BIGINT NOT NULL,                -- unix seconds
+  tries         INTEGER NOT NULL DEFAULT 0,
+  next_try_at   BIGINT NOT NULL DEFAULT 0,      -- unix seconds
+  delivered_at  BIGINT,
+  last_error    TEXT
+);
+CREATE INDEX IF NOT EXISTS idx_outbox_pending ON passback_outbox (delivered_at, next_try_at);
 `
 
 const schemaPostgres = `
@@ -235,6 +382,21 @@ CREATE TABLE IF NOT EXISTS exams (
   policy_json TEXT NOT NULL DEFAULT ''
 );
 
+-- Question bank: questions reusable across exams (exam.Bank). tags is
+-- ",a,b," lower-cased for LIKE '%,tag,%' search; question_json is the full
+-- question including its answer key.
+CREATE TABLE IF NOT EXISTS question_bank (
+  id            TEXT PRIMARY KEY,
+  q_type        TEXT NOT NULL,
+  prompt_html   TEXT NOT NULL DEFAULT '',
+  tags          TEXT NOT NULL DEFAULT '',
+  question_json TEXT NOT NULL,
+  created_by    TEXT NOT NULL DEFAULT '',
+  created_at    BIGINT NOT NULL,
+  updated_at    BIGINT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_question_bank_type ON question_bank (q_type);
+
 -- ===========================
 -- Courses / enrollment / LOBs
 -- ===========================
@@ -243,7 +405,8 @@ CREATE TABLE IF NOT EXISTS courses (
   id TEXT PRIMARY KEY,
   name TEXT NOT NULL,
   created_by TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
-  created_at BIGINT NOT NULL DEFAULT (EXTRACT(EPOCH FROM NOW())::BIGINT)
+  created_at BIGINT NOT NULL DEFAULT (EXTRACT(EPOCH FROM NOW())::BIGINT),
+  status TEXT NOT NULL DEFAULT 'active' CHECK (status IN ('active','archived'))
 );
 
 CREATE TABLE IF NOT EXISTS course_teachers (
@@ -272,10 +435,24 @@ CREATE TABLE IF NOT EXISTS exam_offerings (
   time_limit_sec INTEGER,
   max_attempts   INTEGER NOT NULL DEFAULT 1,
   visibility     TEXT NOT NULL DEFAULT 'course' CHECK (visibility IN ('course','public','link')),
-  access_token   TEXT UNIQUE
+  access_token   TEXT UNIQUE,
+  access_token_expires_at BIGINT, -- NULL = the access_token never expires
+  grading_mode   TEXT NOT NULL DEFAULT 'on_submit' CHECK (grading_mode IN ('on_submit','deferred'))
 );
 CREATE INDEX IF NOT EXISTS idx_offerings_course ON exam_offerings(course_id);
 
+-- Extra join tokens for link offerings (e.g. one per class section), next
+-- to exam_offerings.access_token; expires_at NULL = no expiry.
+CREATE TABLE IF NOT EXISTS offering_access_tokens (
+  token       TEXT PRIMARY KEY,
+  offering_id TEXT NOT NULL REFERENCES exam_offerings(id) ON DELETE CASCADE,
+  label       TEXT NOT NULL DEFAULT '',
+  expires_at  BIGINT,
+  created_by  TEXT NOT NULL DEFAULT '',
+  created_at  BIGINT NOT NULL DEFAULT 0
+);
+CREATE INDEX IF NOT EXISTS idx_offering_tokens_offering ON offering_access_tokens(offering_id);
+
 -- Optional: ownership and invitations (future-friendly)
 CREATE TABLE IF NOT EXISTS exam_owners (
   exam_id    TEXT NOT NULL REFERENCES exams(id)   ON DELETE CASCADE,
@@ -313,8 +490,15 @@ CREATE TABLE IF NOT EXISTS attempts (
   
   graded_at    BIGINT,
   auto_score   DOUBLE PRECISION NOT NULL DEFAULT 0,
-  manual_score DOUBLE PRECISION NOT NULL DEFAULT 0
+  manual_score DOUBLE PRECISION NOT NULL DEFAULT 0,
+  last_seen_at BIGINT, -- last client heartbeat (unix seconds)
+  grading_progress TEXT NOT NULL DEFAULT '', -- ''|pending|graded, see exam.GradingPending
+  questions_json TEXT NOT NULL DEFAULT '', -- blueprint exams: this attempt's drawn questions
+  draw_seed BIGINT NOT NULL DEFAULT 0,
+  responses_updated_at BIGINT NOT NULL DEFAULT 0 -- unix milliseconds of the last save
 );
+-- Deferred grading scans (exam.GradingWorker); empty unless some are pending.
+CREATE INDEX IF NOT EXISTS idx_attempts_grading_pending ON attempts (submitted_at, id) WHERE grading_progress = 'pending';
 
 CREATE TABLE IF NOT EXISTS attempt_items (
   attempt_id    TEXT    NOT NULL,
@@ -358,4 +542,34 @@ CREATE TABLE IF NOT EXISTS ephemeral_stats (
 
 CREATE INDEX IF NOT EXISTS idx_ephem_stats_off_q
   ON ephemeral_stats (offering_id, question_id);
+
+-- Cached item analysis over submitted attempts, per exam. Rows are dropped
+-- whenever an attempt of the exam is submitted or (re)graded and rebuilt on
+-- the next read.
+CREATE TABLE IF NOT EXISTS exam_stats (
+  exam_id       TEXT NOT NULL,
+  question_id   TEXT NOT NULL,      -- "*" = totals over attempt scores
+  count         BIGINT NOT NULL DEFAULT 0,
+  correct       BIGINT NOT NULL DEFAULT 0,      -- full-credit items
+  sum_points    DOUBLE PRECISION NOT NULL DEFAULT 0,
+  max_points    DOUBLE PRECISION NOT NULL DEFAULT 0,
+  updated_at    BIGINT NOT NULL,                -- unix seconds
+  PRIMARY KEY (exam_id, question_id)
+);
+
+-- AGS passback intents, written in the submit transaction and delivered by
+-- exam.OutboxWorker (at least once). Undelivered rows have delivered_at NULL.
+CREATE TABLE IF NOT EXISTS passback_outbox (
+  id            BIGSERIAL PRIMARY KEY,
+  attempt_id    TEXT NOT NULL,
+  exam_id       TEXT NOT NULL,
+  user_id       TEXT NOT NULL,
+  score         DOUBLE PRECISION NOT NULL,
+  created_at    BIGINT NOT NULL,                -- unix seconds
+  tries         INTEGER NOT NULL DEFAULT 0,
+  next_try_at   BIGINT NOT NULL DEFAULT 0,      -- unix seconds
+  delivered_at  BIGINT,
+  last_error    TEXT
+);
+CREATE INDEX IF NOT EXISTS idx_outbox_pending ON passback_outbox (delivered_at, next_try_at);
 `
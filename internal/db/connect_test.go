@@ -0,0 +1,57 @@
+package db_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/mind-engage/mindengage-lms/internal/db"
+)
+
+func TestOpenWithPool_SQLiteSingleConn(t *testing.T) {
+	dsn := "file:" + filepath.Join(t.TempDir(), "pool.db")
+	// Ask for more; sqlite must still be capped at one writer.
+	dbh, err := db.OpenWithPool(context.Background(), db.DriverSQLite, dsn, db.PoolConfig{MaxOpenConns: 8})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dbh.Close()
+
+	if got := dbh.Stats().MaxOpenConnections; got != 1 {
+		t.Fatalf("MaxOpenConnections = %d, want 1", got)
+	}
+}
+
+func TestDefaultPool_Postgres(t *testing.T) {
+	p := db.DefaultPool(db.DriverPostgres)
+	if p.MaxOpenConns <= 1 || p.MaxIdleConns <= 0 || p.ConnMaxLifetime <= 0 {
+		t.Fatalf("unexpected postgres defaults: %+v", p)
+	}
+}
+
+func TestOpen_SQLitePragmas(t *testing.T) {
+	dsn := "file:" + filepath.Join(t.TempDir(), "wal.db")
+	dbh, err := db.Open(context.Background(), db.DriverSQLite, dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dbh.Close()
+
+	var mode string
+	if err := dbh.QueryRow(`PRAGMA journal_mode`).Scan(&mode); err != nil {
+		t.Fatal(err)
+	}
+	if mode != "wal" {
+		t.Fatalf("journal_mode = %q, want wal", mode)
+	}
+	var busy, fk int
+	if err := dbh.QueryRow(`PRAGMA busy_timeout`).Scan(&busy); err != nil {
+		t.Fatal(err)
+	}
+	if err := dbh.QueryRow(`PRAGMA foreign_keys`).Scan(&fk); err != nil {
+		t.Fatal(err)
+	}
+	if busy != 5000 || fk != 1 {
+		t.Fatalf("busy_timeout=%d foreign_keys=%d", busy, fk)
+	}
+}
@@ -0,0 +1,278 @@
+package exam
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	mathrand "math/rand"
+	"strings"
+	"time"
+)
+
+/*
+Question bank
+
+Bank questions live in question_bank, independent of any exam. An exam
+references one with a question carrying only BankID (plus, optionally, its
+own ID, Points and placement: SectionID, ModuleID, Order); Bank.Resolve
+copies the bank question in when the exam is uploaded. The stored exam is
+self-contained, so later edits to the bank do not change exams (or grades)
+that already use the question.
+
+An exam whose policy has a "blueprint" instead draws questions per attempt:
+NewAttempt picks them with a random seed (Bank.Draw) and stores the drawn
+set on the attempt, which from then on is that attempt's exam.
+*/
+
+var ErrBankQuestionNotFound = errors.New("bank question not found")
+
+// BankQuestion is a bank entry: the question (with answer key) plus who
+// added it and when.
+type BankQuestion struct {
+	Question
+	CreatedBy string `json:"created_by,omitempty"`
+	CreatedAt int64  `json:"created_at"`
+	UpdatedAt int64  `json:"updated_at"`
+}
+
+// BankQuery filters Bank.List. Q matches id or prompt text, Tag one tag and
+// Type the question type; all case-insensitive.
+type BankQuery struct {
+	Q      string
+	Tag    string
+	Type   string
+	Limit  int // default 50, max 500
+	Offset int
+}
+
+type Bank struct {
+	db *sql.DB
+	// Now is the bank's clock; nil means time.Now.
+	Now func() time.Time
+}
+
+func NewBank(db *sql.DB) *Bank { return &Bank{db: db} }
+
+func (b *Bank) now() time.Time {
+	if b.Now != nil {
+		return b.Now()
+	}
+	return time.Now()
+}
+
+// Put adds q to the bank, or replaces the entry with q's id (keeping its
+// creator). An empty id is generated.
+func (b *Bank) Put(ctx context.Context, q Question, createdBy string) (BankQuestion, error) {
+	if strings.TrimSpace(q.Type) == "" {
+		return BankQuestion{}, errors.New("type required")
+	}
+	if q.BankID != "" {
+		return BankQuestion{}, errors.New("a bank question cannot reference another")
+	}
+	q.ID = strings.TrimSpace(q.ID)
+	if q.ID == "" {
+		var sfx [8]byte
+		if _, err := rand.Read(sfx[:]); err != nil {
+			return BankQuestion{}, err
+		}
+		q.ID = "bq-" + hex.EncodeToString(sfx[:])
+	}
+	// Placement belongs to the exam using the question, not the bank.
+	q.SectionID, q.ModuleID, q.Order = "", "", 0
+	qj, err := json.Marshal(q)
+	if err != nil {
+		return BankQuestion{}, err
+	}
+	now := b.now().Unix()
+	if _, err := b.db.ExecContext(ctx, `
+		INSERT INTO question_bank (id, q_type, prompt_html, tags, question_json, created_by, created_at, updated_at)
+		VALUES ($1,$2,$3,$4,$5,$6,$7,$7)
+		ON CONFLICT (id) DO UPDATE SET
+			q_type=EXCLUDED.q_type,
+			prompt_html=EXCLUDED.prompt_html,
+			tags=EXCLUDED.tags,
+			question_json=EXCLUDED.question_json,
+			updated_at=EXCLUDED.updated_at`,
+		q.ID, strings.ToLower(q.Type), q.PromptHTML, tagColumn(q.Tags), string(qj), createdBy, now); err != nil {
+		return BankQuestion{}, err
+	}
+	return b.Get(ctx, q.ID)
+}
+
+// Get returns one bank question, or ErrBankQuestionNotFound.
+func (b *Bank) Get(ctx context.Context, id string) (BankQuestion, error) {
+	row := b.db.QueryRowContext(ctx, `
+		SELECT question_json, created_by, created_at, updated_at FROM question_bank WHERE id=$1`, id)
+	bq, err := scanBankQuestion(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return BankQuestion{}, fmt.Errorf("%w: %s", ErrBankQuestionNotFound, id)
+	}
+	return bq, err
+}
+
+// List returns bank questions matching query, by id.
+func (b *Bank) List(ctx context.Context, query BankQuery) ([]BankQuestion, error) {
+	if query.Limit <= 0 {
+		query.Limit = 50
+	}
+	if query.Limit > 500 {
+		query.Limit = 500
+	}
+	if query.Offset < 0 {
+		query.Offset = 0
+	}
+	where := []string{"1=1"}
+	args := []any{}
+	add := func(cond string, arg any) {
+		args = append(args, arg)
+		where = append(where, strings.ReplaceAll(cond, "?", fmt.Sprintf("$%d", len(args))))
+	}
+	if q := strings.ToLower(strings.TrimSpace(query.Q)); q != "" {
+		add(`(LOWER(id) LIKE ? OR LOWER(prompt_html) LIKE ?)`, "%"+q+"%")
+	}
+	if t := strings.ToLower(strings.TrimSpace(query.Tag)); t != "" {
+		add(`tags LIKE ?`, "%,"+t+",%")
+	}
+	if t := strings.ToLower(strings.TrimSpace(query.Type)); t != "" {
+		add(`q_type=?`, t)
+	}
+	rows, err := b.db.QueryContext(ctx, fmt.Sprintf(`
+		SELECT question_json, created_by, created_at, updated_at
+		  FROM question_bank
+		 WHERE %s
+		 ORDER BY id
+		 LIMIT %d OFFSET %d`, strings.Join(where, " AND "), query.Limit, query.Offset), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []BankQuestion{}
+	for rows.Next() {
+		bq, err := scanBankQuestion(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, bq)
+	}
+	return out, rows.Err()
+}
+
+// Resolve returns qs with every bank reference replaced by a copy of the
+// bank question. The reference's ID (default: the bank id), Points (if
+// set) and placement override the bank's; inline questions pass through.
+func (b *Bank) Resolve(ctx context.Context, qs []Question) ([]Question, error) {
+	out := make([]Question, len(qs))
+	for i, ref := range qs {
+		if strings.TrimSpace(ref.BankID) == "" {
+			out[i] = ref
+			continue
+		}
+		bq, err := b.Get(ctx, strings.TrimSpace(ref.BankID))
+		if err != nil {
+			return nil, err
+		}
+		q := bq.Question
+		q.BankID = bq.ID
+		if ref.ID != "" {
+			q.ID = ref.ID
+		}
+		if ref.Points > 0 {
+			q.Points = ref.Points
+		}
+		q.SectionID, q.ModuleID, q.Order = ref.SectionID, ref.ModuleID, ref.Order
+		out[i] = q
+	}
+	return out, nil
+}
+
+// Draw picks questions for rules from the bank: per rule, Count of the
+// questions matching it, chosen at random with seed among those no earlier
+// rule took and whose id is not in exclude. The same seed over the same
+// bank draws the same questions, in rule order.
+func (b *Bank) Draw(ctx context.Context, rules []BlueprintRule, seed int64, exclude map[string]bool) ([]Question, error) {
+	rng := mathrand.New(mathrand.NewSource(seed))
+	used := map[string]bool{}
+	var out []Question
+	for _, r := range rules {
+		var pool []Question
+		offset := 0
+		for {
+			page, err := b.List(ctx, BankQuery{Tag: r.Tag, Type: r.Type, Limit: 500, Offset: offset})
+			if err != nil {
+				return nil, err
+			}
+			for _, bq := range page {
+				if !used[bq.ID] && !exclude[bq.ID] {
+					pool = append(pool, bq.Question)
+				}
+			}
+			if len(page) < 500 {
+				break
+			}
+			offset += len(page)
+		}
+		if len(pool) < r.Count {
+			return nil, fmt.Errorf("blueprint: %d questions tagged %q, need %d", len(pool), r.Tag, r.Count)
+		}
+		rng.Shuffle(len(pool), func(i, j int) { pool[i], pool[j] = pool[j], pool[i] })
+		for _, q := range pool[:r.Count] {
+			used[q.ID] = true
+			q.BankID = q.ID
+			q.ModuleID = r.ModuleID
+			if r.Points > 0 {
+				q.Points = r.Points
+			}
+			out = append(out, q)
+		}
+	}
+	return out, nil
+}
+
+// assembleFromBlueprint is one attempt's questions: the exam's own, then
+// those drawn by rules. Drawn questions keep their bank ids, so grading
+// (attempt_items) and item analysis report them under those.
+func assembleFromBlueprint(ctx context.Context, b *Bank, fixed []Question, rules []BlueprintRule, seed int64) ([]Question, error) {
+	exclude := make(map[string]bool, len(fixed))
+	for _, q := range fixed {
+		exclude[q.ID] = true
+	}
+	drawn, err := b.Draw(ctx, rules, seed, exclude)
+	if err != nil {
+		return nil, err
+	}
+	qs := append(append([]Question(nil), fixed...), drawn...)
+	for i := range qs {
+		qs[i].Order = i
+	}
+	return qs, nil
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanBankQuestion(row rowScanner) (BankQuestion, error) {
+	var bq BankQuestion
+	var qjson string
+	if err := row.Scan(&qjson, &bq.CreatedBy, &bq.CreatedAt, &bq.UpdatedAt); err != nil {
+		return BankQuestion{}, err
+	}
+	if err := json.Unmarshal([]byte(qjson), &bq.Question); err != nil {
+		return BankQuestion{}, err
+	}
+	return bq, nil
+}
+
+// tagColumn is the tags column value: ",a,b," (normalized), so a tag
+// matches with LIKE '%,tag,%'.
+func tagColumn(tags []string) string {
+	u := uniqueTags(tags)
+	if len(u) == 0 {
+		return ""
+	}
+	return "," + strings.Join(u, ",") + ","
+}
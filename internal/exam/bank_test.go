@@ -0,0 +1,97 @@
+package exam_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/mind-engage/mindengage-lms/internal/exam"
+)
+
+func TestNewAttempt_DrawsByBlueprint(t *testing.T) {
+	ctx := context.Background()
+	store, dbh := newOutboxStore(t)
+	bank := exam.NewBank(dbh)
+	for i := 0; i < 10; i++ {
+		for _, tag := range []string{"algebra", "geometry"} {
+			if _, err := bank.Put(ctx, exam.Question{
+				ID: fmt.Sprintf("%s-%d", tag, i), Type: "mcq_single", Points: 1,
+				AnswerKey: []string{"a"}, Tags: []string{tag},
+			}, "t1"); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if err := store.PutExam(exam.Exam{
+		ID:        "bp-1",
+		Title:     "Blueprint",
+		Questions: []exam.Question{{ID: "intro", Type: "mcq_single", Points: 1, AnswerKey: []string{"a"}}},
+		PolicyRaw: json.RawMessage(`{"blueprint":[{"tag":"algebra","count":2},{"tag":"geometry","count":1,"points":3}]}`),
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	draws := map[string]bool{}
+	for i := 0; i < 4; i++ {
+		a, err := store.NewAttempt(ctx, "bp-1", fmt.Sprintf("s%d", i))
+		if err != nil {
+			t.Fatal(err)
+		}
+		ex, err := store.GetAttemptExam(ctx, a.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(ex.Questions) != 4 || ex.Questions[0].ID != "intro" {
+			t.Fatalf("attempt %d: questions = %+v", i, ex.Questions)
+		}
+		var ids []string
+		perTag := map[string]int{}
+		responses := map[string]interface{}{}
+		for _, q := range ex.Questions[1:] {
+			if q.BankID != q.ID {
+				t.Fatalf("drawn question %q has bank id %q", q.ID, q.BankID)
+			}
+			tag := strings.SplitN(q.ID, "-", 2)[0]
+			perTag[tag]++
+			if tag == "geometry" && q.Points != 3 {
+				t.Errorf("geometry points = %v, want the blueprint's 3", q.Points)
+			}
+			ids = append(ids, q.ID)
+			responses[q.ID] = "a"
+		}
+		if perTag["algebra"] != 2 || perTag["geometry"] != 1 {
+			t.Fatalf("attempt %d drew %v, want 2 algebra and 1 geometry", i, perTag)
+		}
+		draws[strings.Join(ids, ",")] = true
+
+		// Grading uses the attempt's draw and records the bank ids.
+		if _, err := store.SaveResponses(ctx, a.ID, responses); err != nil {
+			t.Fatal(err)
+		}
+		sub, err := store.Submit(ctx, a.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if sub.Score != 5 {
+			t.Fatalf("attempt %d: score = %v, want 5", i, sub.Score)
+		}
+		items, err := store.GetAttemptItems(ctx, a.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := map[string]bool{}
+		for _, it := range items {
+			got[it.QuestionID] = true
+		}
+		for _, id := range ids {
+			if !got[id] {
+				t.Errorf("attempt %d: no item graded for %q (items %+v)", i, id, items)
+			}
+		}
+	}
+	if len(draws) < 2 {
+		t.Fatalf("every attempt drew the same questions: %v", draws)
+	}
+}
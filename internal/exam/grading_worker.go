@@ -0,0 +1,89 @@
+// internal/exam/grading_worker.go
+package exam
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// GradingWorker grades attempts that offerings with grading_mode=deferred
+// left pending, via SQLStore.GradePending. The pending attempts are found
+// through a partial index, so scans are cheap while nothing is deferred.
+type GradingWorker struct {
+	Store *SQLStore
+
+	Interval  time.Duration // between scans; default 30s
+	BatchSize int           // attempts per scan; default 50
+
+	// OnError, if set, is called with failed scans.
+	OnError func(err error)
+
+	mu   sync.Mutex
+	stop chan struct{}
+	done chan struct{}
+}
+
+// Start grades every Interval until Stop. Starting a running worker is a
+// no-op.
+func (w *GradingWorker) Start() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.stop != nil {
+		return
+	}
+	w.stop, w.done = make(chan struct{}), make(chan struct{})
+	go w.loop(w.stop, w.done)
+}
+
+// Stop ends the loop and waits for an in-flight scan to finish.
+func (w *GradingWorker) Stop() {
+	w.mu.Lock()
+	stop, done := w.stop, w.done
+	w.stop, w.done = nil, nil
+	w.mu.Unlock()
+	if stop == nil {
+		return
+	}
+	close(stop)
+	<-done
+}
+
+func (w *GradingWorker) loop(stop, done chan struct{}) {
+	defer close(done)
+	interval := w.Interval
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-stop:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	for {
+		if _, err := w.RunOnce(ctx); err != nil && ctx.Err() == nil && w.OnError != nil {
+			w.OnError(err)
+		}
+		select {
+		case <-stop:
+			return
+		case <-t.C:
+		}
+	}
+}
+
+// RunOnce grades up to BatchSize pending attempts and reports how many it
+// graded.
+func (w *GradingWorker) RunOnce(ctx context.Context) (int, error) {
+	n := w.BatchSize
+	if n <= 0 {
+		n = 50
+	}
+	return w.Store.GradePending(ctx, n)
+}
@@ -0,0 +1,66 @@
+package exam_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/mind-engage/mindengage-lms/internal/exam"
+)
+
+func TestGradingWorker_GradesDeferredAttempts(t *testing.T) {
+	ctx := context.Background()
+	store, dbh := newOutboxStore(t)
+	for _, q := range []string{
+		`INSERT INTO users (id, username, role) VALUES ('t-1','teacher@example.edu','teacher')`,
+		`INSERT INTO courses (id, name, created_by) VALUES ('c-1','Course','t-1')`,
+		`INSERT INTO exam_offerings (id, exam_id, course_id, assigned_by, grading_mode) VALUES ('o-1','ex-1','c-1','t-1','deferred')`,
+	} {
+		if _, err := dbh.Exec(q); err != nil {
+			t.Fatal(err)
+		}
+	}
+	a, err := store.NewAttempt(ctx, "ex-1", "u-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := dbh.Exec(`UPDATE attempts SET offering_id='o-1' WHERE id=$1`, a.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.SaveResponses(ctx, a.ID, map[string]interface{}{"q1": "a"}); err != nil {
+		t.Fatal(err)
+	}
+	if a, err = store.Submit(ctx, a.ID); err != nil || a.GradingProgress != exam.GradingPending {
+		t.Fatalf("submit: progress %q, err %v; want pending", a.GradingProgress, err)
+	}
+
+	w := &exam.GradingWorker{
+		Store:    store,
+		Interval: 10 * time.Millisecond,
+		OnError:  func(err error) { t.Errorf("grading: %v", err) },
+	}
+	w.Start()
+	w.Start() // no-op while running
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		got, err := store.GetAttempt(a.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.GradingProgress == exam.GradingGraded {
+			if got.Score != 2 {
+				t.Fatalf("score = %v, want 2", got.Score)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("deferred attempt not graded")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	w.Stop()
+	w.Stop() // no-op once stopped
+	if n, err := w.RunOnce(ctx); err != nil || n != 0 {
+		t.Fatalf("RunOnce after grading = %d, %v; want 0", n, err)
+	}
+}
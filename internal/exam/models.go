@@ -2,6 +2,8 @@ package exam
 
 import (
 	"encoding/json"
+
+	"github.com/mind-engage/mindengage-lms/internal/grading"
 )
 
 type Choice struct {
@@ -21,6 +23,50 @@ type Question struct {
 	Points    float64  `json:"points"`
 	SectionID string   `json:"section_id,omitempty"`
 	ModuleID  string   `json:"module_id,omitempty"`
+	// Tags label the question (e.g. difficulty, topic); the policy's
+	// feedback block can reveal or hide feedback by tag (FeedbackPolicy).
+	Tags []string `json:"tags,omitempty"`
+	// Difficulty weighs a correct answer for adaptive routing (see Perf);
+	// 1 is typical, higher is harder. 0 means unset and counts as 1.
+	Difficulty float64 `json:"difficulty,omitempty"`
+	// Order is the question's absolute index in the exam. PutExam sorts by it
+	// (stable, so an exam without orders keeps its listed order) and
+	// renumbers it 0..n-1; reads always return questions in this order.
+	Order int `json:"order"`
+
+	// BankID references a question_bank entry. On upload the reference is
+	// replaced by a copy of the bank question (see Bank.Resolve), which keeps
+	// BankID to record where it came from.
+	BankID string `json:"bank_id,omitempty"`
+
+	// Scoring overrides the type's default grading (set by QTI import).
+	Scoring *Scoring `json:"scoring,omitempty"`
+}
+
+// Scoring mirrors grading.Q's override fields. Mode is "exact"
+// (all-or-nothing) or "map" (Weights: response value -> points).
+type Scoring struct {
+	Mode          string             `json:"mode"`
+	Weights       map[string]float64 `json:"weights,omitempty"`
+	DefaultWeight float64            `json:"default_weight,omitempty"`
+}
+
+// GradingQ is the grading view of q, including any scoring override.
+func (q Question) GradingQ() grading.Q {
+	gq := grading.Q{Type: q.Type, Points: q.Points, AnswerKey: q.AnswerKey}
+	if q.Scoring != nil {
+		gq.Scoring = q.Scoring.Mode
+		gq.Weights = q.Scoring.Weights
+		gq.DefaultWeight = q.Scoring.DefaultWeight
+	}
+	return gq
+}
+
+func (q Question) difficulty() float64 {
+	if q.Difficulty <= 0 {
+		return 1
+	}
+	return q.Difficulty
 }
 
 type Attempt struct {
@@ -39,11 +85,75 @@ type Attempt struct {
 	// Timestamps (useful for teacher/admin list views)
 	StartedAt   int64 `json:"started_at"`
 	SubmittedAt int64 `json:"submitted_at,omitempty"`
+	LastSeenAt  int64 `json:"last_seen_at,omitempty"` // last heartbeat from the taker's browser
+	// ResponsesUpdatedAt is when responses were last saved, in unix
+	// milliseconds (finer than the other times so quick saves stay ordered).
+	ResponsesUpdatedAt int64 `json:"responses_updated_at,omitempty"`
+
+	// Review window (policy review_period_sec): until ReviewUntil the taker
+	// may look at their submitted answers; after it ReviewClosed is set and
+	// student-facing reads omit them.
+	ReviewUntil  int64 `json:"review_until,omitempty"`
+	ReviewClosed bool  `json:"review_closed,omitempty"`
 
 	RemainingSeconds int    `json:"remaining_seconds"`
 	CurrentIndex     int    `json:"current_index"`
 	MaxReachedIndex  int    `json:"max_reached_index"`
 	CurrentModuleID  string `json:"current_module_id,omitempty"`
+
+	// Progress, computed on read: questions with a non-empty response out of
+	// the exam's questions, and the same within the current module.
+	AnsweredCount        int `json:"answered_count"`
+	TotalQuestions       int `json:"total_questions"`
+	ModuleAnsweredCount  int `json:"module_answered_count,omitempty"`
+	ModuleTotalQuestions int `json:"module_total_questions,omitempty"`
+
+	// GradingProgress is GradingPending between a deferred submit and its
+	// grading, GradingGraded once scored; empty before submit.
+	GradingProgress string `json:"grading_progress,omitempty"`
+
+	// DrawSeed seeded the draw of this attempt's questions from the bank
+	// (blueprint exams only; see GetAttemptExam).
+	DrawSeed int64 `json:"draw_seed,omitempty"`
+}
+
+// SaveReport is the outcome of a partial save: the question ids whose
+// responses were stored, and for each other one the rule it broke
+// (ErrUnknownQuestion, ErrOutsideModule, ErrModuleCompleted, ErrEditBackBlocked).
+type SaveReport struct {
+	Accepted []string
+	Rejected map[string]error
+}
+
+// Offering grading modes (exam_offerings.grading_mode).
+const (
+	GradeOnSubmit = "on_submit" // Submit grades synchronously (default)
+	GradeDeferred = "deferred"  // Submit only closes the attempt; see SQLStore.GradePending
+)
+
+// Attempt grading progress (attempts.grading_progress).
+const (
+	GradingPending = "pending"
+	GradingGraded  = "graded"
+)
+
+// ExamStats is the item analysis over an exam's submitted attempts.
+type ExamStats struct {
+	ExamID    string          `json:"exam_id"`
+	Attempts  int64           `json:"attempts"`
+	AvgScore  float64         `json:"avg_score"`
+	MaxScore  float64         `json:"max_score"`
+	UpdatedAt int64           `json:"updated_at"` // when the cached rows were computed
+	Questions []QuestionStats `json:"questions"`
+	Tags      []TagStats      `json:"tags,omitempty"` // only when grouped by tag (StatsByTag)
+}
+
+type QuestionStats struct {
+	QuestionID string  `json:"question_id"`
+	Count      int64   `json:"count"`
+	Correct    int64   `json:"correct"` // full credit (auto + manual)
+	AvgPoints  float64 `json:"avg_points"`
+	MaxPoints  float64 `json:"max_points"`
 }
 
 type AttemptItem struct {
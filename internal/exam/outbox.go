@@ -0,0 +1,214 @@
+// internal/exam/outbox.go
+package exam
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"time"
+)
+
+/*
+Passback outbox
+
+Submit writes one passback_outbox row in the same transaction that records
+the score, so a committed submit always has a passback intent and a rolled
+back one never does; a manual regrade writes another with the new score.
+Only stores with Passback set write rows, and only for takers an LTI launch
+mapped to a platform user (lti_user_map, from the gradebook schema): nobody
+else has a gradebook to post to. OutboxWorker delivers the rows afterwards; a row is
+marked delivered only after Deliver succeeds, so a crash in between delivers
+it again (at least once). Deliver must therefore be idempotent, which AGS
+score posts are: the platform keeps the latest score per user.
+*/
+
+// PassbackIntent is one undelivered outbox row.
+type PassbackIntent struct {
+	ID        int64
+	AttemptID string
+	ExamID    string
+	UserID    string
+	Score     float64
+	Tries     int // failed deliveries so far
+}
+
+func (s *SQLStore) enqueuePassback(ctx context.Context, tx *sql.Tx, a Attempt, score float64, now int64) error {
+	if !s.Passback {
+		return nil
+	}
+	var mapped bool
+	if err := tx.QueryRowContext(ctx, `SELECT EXISTS(SELECT 1 FROM lti_user_map WHERE local_user_id=$1)`,
+		a.UserID).Scan(&mapped); err != nil || !mapped {
+		return err
+	}
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO passback_outbox (attempt_id, exam_id, user_id, score, created_at)
+		VALUES ($1,$2,$3,$4,$5)`,
+		a.ID, a.ExamID, a.UserID, score, now)
+	return err
+}
+
+// OutboxWorker delivers passback_outbox rows oldest first. A failed delivery
+// is retried after Backoff, doubled per failure; rows that failed
+// MaxTries times stay in the table undelivered for inspection.
+type OutboxWorker struct {
+	DB      *sql.DB
+	Deliver func(ctx context.Context, p PassbackIntent) error
+
+	Interval  time.Duration // between scans; default 30s
+	Backoff   time.Duration // default 30s
+	MaxTries  int           // default 8
+	BatchSize int           // default 100
+
+	// Now is the worker's clock; nil means time.Now.
+	Now func() time.Time
+	// OnError, if set, is called with scan errors and failed deliveries.
+	OnError func(p PassbackIntent, err error)
+
+	mu   sync.Mutex
+	stop chan struct{}
+	done chan struct{}
+}
+
+// Start delivers every Interval until Stop. Starting a running worker is a
+// no-op.
+func (w *OutboxWorker) Start() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.stop != nil {
+		return
+	}
+	w.stop, w.done = make(chan struct{}), make(chan struct{})
+	go w.loop(w.stop, w.done)
+}
+
+// Stop ends the loop and waits for an in-flight scan to finish.
+func (w *OutboxWorker) Stop() {
+	w.mu.Lock()
+	stop, done := w.stop, w.done
+	w.stop, w.done = nil, nil
+	w.mu.Unlock()
+	if stop == nil {
+		return
+	}
+	close(stop)
+	<-done
+}
+
+func (w *OutboxWorker) loop(stop, done chan struct{}) {
+	defer close(done)
+	interval := w.Interval
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-stop:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	for {
+		if _, err := w.RunOnce(ctx); err != nil && ctx.Err() == nil {
+			w.onError(PassbackIntent{}, err)
+		}
+		select {
+		case <-stop:
+			return
+		case <-t.C:
+		}
+	}
+}
+
+// RunOnce delivers the rows that are due and reports how many succeeded.
+func (w *OutboxWorker) RunOnce(ctx context.Context) (int, error) {
+	now := w.now().Unix()
+	rows, err := w.DB.QueryContext(ctx, `
+		SELECT id, attempt_id, exam_id, user_id, score, tries
+		  FROM passback_outbox
+		 WHERE delivered_at IS NULL AND next_try_at <= $1 AND tries < $2
+		 ORDER BY created_at, id
+		 LIMIT $3`, now, w.maxTries(), w.batchSize())
+	if err != nil {
+		return 0, err
+	}
+	var due []PassbackIntent
+	for rows.Next() {
+		var p PassbackIntent
+		if err := rows.Scan(&p.ID, &p.AttemptID, &p.ExamID, &p.UserID, &p.Score, &p.Tries); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		due = append(due, p)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	delivered := 0
+	for _, p := range due {
+		if ctx.Err() != nil {
+			return delivered, ctx.Err()
+		}
+		if derr := w.Deliver(ctx, p); derr != nil {
+			w.onError(p, derr)
+			next := now + int64(w.backoff(p.Tries+1)/time.Second)
+			if _, err := w.DB.ExecContext(ctx, `
+				UPDATE passback_outbox SET tries=tries+1, next_try_at=$1, last_error=$2 WHERE id=$3`,
+				next, derr.Error(), p.ID); err != nil {
+				return delivered, err
+			}
+			continue
+		}
+		if _, err := w.DB.ExecContext(ctx, `
+			UPDATE passback_outbox SET delivered_at=$1, last_error=NULL WHERE id=$2`, now, p.ID); err != nil {
+			return delivered, err
+		}
+		delivered++
+	}
+	return delivered, nil
+}
+
+// backoff is the wait after the n-th failed delivery.
+func (w *OutboxWorker) backoff(n int) time.Duration {
+	d := w.Backoff
+	if d <= 0 {
+		d = 30 * time.Second
+	}
+	for i := 1; i < n && d < 24*time.Hour; i++ {
+		d *= 2
+	}
+	return d
+}
+
+func (w *OutboxWorker) maxTries() int {
+	if w.MaxTries <= 0 {
+		return 8
+	}
+	return w.MaxTries
+}
+
+func (w *OutboxWorker) batchSize() int {
+	if w.BatchSize <= 0 {
+		return 100
+	}
+	return w.BatchSize
+}
+
+func (w *OutboxWorker) now() time.Time {
+	if w.Now != nil {
+		return w.Now()
+	}
+	return time.Now()
+}
+
+func (w *OutboxWorker) onError(p PassbackIntent, err error) {
+	if w.OnError != nil {
+		w.OnError(p, err)
+	}
+}
@@ -0,0 +1,195 @@
+package exam_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mind-engage/mindengage-lms/internal/db"
+	"github.com/mind-engage/mindengage-lms/internal/exam"
+	"github.com/mind-engage/mindengage-lms/internal/grading"
+	"github.com/mind-engage/mindengage-lms/pkg/lti-ags-gradebook/gradebook"
+)
+
+func newOutboxStore(t *testing.T) (*exam.SQLStore, *sql.DB) {
+	t.Helper()
+	dsn := "file:" + filepath.Join(t.TempDir(), "test.db") + "?_pragma=busy_timeout(5000)"
+	dbh, err := db.Open(context.Background(), db.DriverSQLite, dsn)
+	if err != nil {
+		t.Fatalf("db open: %v", err)
+	}
+	t.Cleanup(func() { _ = dbh.Close() })
+	if err := gradebook.Migrate(context.Background(), dbh, string(db.DriverSQLite)); err != nil {
+		t.Fatal(err)
+	}
+	// u-1 came in through an LTI launch; other users did not.
+	if _, err := dbh.Exec(`INSERT INTO lti_user_map (platform_issuer, platform_sub, local_user_id) VALUES ('https://lms.example','sub-1','u-1')`); err != nil {
+		t.Fatal(err)
+	}
+	store := exam.NewSQLStore(dbh, string(db.DriverSQLite), grading.NewDefaultGrader())
+	store.Passback = true
+	if err := store.PutExam(exam.Exam{
+		ID:        "ex-1",
+		Title:     "Outbox",
+		Questions: []exam.Question{{ID: "q1", Type: "mcq_single", Points: 2, AnswerKey: []string{"a"}}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	return store, dbh
+}
+
+func outboxRows(t *testing.T, dbh *sql.DB, attemptID string) (n int, score float64) {
+	t.Helper()
+	if err := dbh.QueryRow(`SELECT COUNT(*), COALESCE(MAX(score),0) FROM passback_outbox WHERE attempt_id=$1`, attemptID).
+		Scan(&n, &score); err != nil {
+		t.Fatal(err)
+	}
+	return n, score
+}
+
+func TestSubmit_EnqueuesOnePassback(t *testing.T) {
+	ctx := context.Background()
+	store, dbh := newOutboxStore(t)
+	a, err := store.NewAttempt(ctx, "ex-1", "u-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.SaveResponses(ctx, a.ID, map[string]interface{}{"q1": "a"}); err != nil {
+		t.Fatal(err)
+	}
+	// A submit that fails after the enqueue rolls the intent back with it.
+	if _, err := dbh.Exec(`ALTER TABLE exam_stats RENAME TO exam_stats_gone`); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Submit(ctx, a.ID); err == nil {
+		t.Fatal("submit succeeded without exam_stats")
+	}
+	if n, _ := outboxRows(t, dbh, a.ID); n != 0 {
+		t.Fatalf("after failed submit: rows = %d, want 0", n)
+	}
+	if _, err := dbh.Exec(`ALTER TABLE exam_stats_gone RENAME TO exam_stats`); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := store.Submit(ctx, a.ID); err != nil {
+		t.Fatal(err)
+	}
+	if n, score := outboxRows(t, dbh, a.ID); n != 1 || score != 2 {
+		t.Fatalf("after submit: rows = %d, score = %v; want 1 row scoring 2", n, score)
+	}
+
+	// Submitting again returns the finalized attempt without reporting twice.
+	if _, err := store.Submit(ctx, a.ID); err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := outboxRows(t, dbh, a.ID); n != 1 {
+		t.Fatalf("after resubmit: rows = %d, want 1", n)
+	}
+}
+
+func TestPassback_OnlyMappedTakers(t *testing.T) {
+	ctx := context.Background()
+	store, dbh := newOutboxStore(t)
+	submit := func(user string) exam.Attempt {
+		t.Helper()
+		a, err := store.NewAttempt(ctx, "ex-1", user)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := store.Submit(ctx, a.ID); err != nil {
+			t.Fatal(err)
+		}
+		return a
+	}
+
+	for _, user := range []string{"u-2", "anon-123"} {
+		if n, _ := outboxRows(t, dbh, submit(user).ID); n != 0 {
+			t.Errorf("%s: rows = %d, want 0 (no LTI mapping)", user, n)
+		}
+	}
+
+	// A manual regrade of a mapped taker's attempt reports the new score.
+	a := submit("u-1")
+	if _, err := store.ApplyManualGrades(ctx, a.ID, map[string]exam.ManualGradeInput{"q1": {ManualPoints: 1.5}}, "t1", true); err != nil {
+		t.Fatal(err)
+	}
+	var last float64
+	if err := dbh.QueryRow(`SELECT score FROM passback_outbox WHERE attempt_id=$1 ORDER BY id DESC LIMIT 1`, a.ID).Scan(&last); err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := outboxRows(t, dbh, a.ID); n != 2 || last != 1.5 {
+		t.Fatalf("after regrade: rows = %d, latest score = %v; want 2 rows, the new one scoring 1.5", n, last)
+	}
+
+	store.Passback = false
+	if n, _ := outboxRows(t, dbh, submit("u-1").ID); n != 0 {
+		t.Fatalf("passback off: rows = %d, want 0", n)
+	}
+}
+
+func TestOutboxWorker_RetriesUntilDelivered(t *testing.T) {
+	ctx := context.Background()
+	store, dbh := newOutboxStore(t)
+	a, err := store.NewAttempt(ctx, "ex-1", "u-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Submit(ctx, a.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	fail := true
+	var got []exam.PassbackIntent
+	w := &exam.OutboxWorker{
+		DB:      dbh,
+		Backoff: time.Minute,
+		Now:     func() time.Time { return now },
+		Deliver: func(_ context.Context, p exam.PassbackIntent) error {
+			got = append(got, p)
+			if fail {
+				return errors.New("ags: 503")
+			}
+			return nil
+		},
+	}
+	run := func(want int) {
+		t.Helper()
+		n, err := w.RunOnce(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n != want {
+			t.Fatalf("delivered %d, want %d", n, want)
+		}
+	}
+
+	run(0) // fails, backs off a minute
+	var tries int
+	var lastErr string
+	if err := dbh.QueryRow(`SELECT tries, last_error FROM passback_outbox WHERE attempt_id=$1`, a.ID).Scan(&tries, &lastErr); err != nil {
+		t.Fatal(err)
+	}
+	if tries != 1 || lastErr != "ags: 503" {
+		t.Fatalf("after failure: tries = %d, last_error = %q", tries, lastErr)
+	}
+	run(0)
+	if len(got) != 1 {
+		t.Fatalf("retried during backoff (%d deliveries)", len(got))
+	}
+
+	now = now.Add(time.Minute)
+	fail = false
+	run(1)
+	if len(got) != 2 || got[1].AttemptID != a.ID || got[1].UserID != "u-1" || got[1].Tries != 1 {
+		t.Fatalf("deliveries = %+v", got)
+	}
+	now = now.Add(time.Hour)
+	run(0) // delivered rows are not sent again
+	if len(got) != 2 {
+		t.Fatalf("redelivered a delivered row (%d deliveries)", len(got))
+	}
+}
@@ -45,3 +45,141 @@ func extractModules(policyRaw json.RawMessage) []ModuleInfo {
 	}
 	return out
 }
+
+// moduleIndexByID maps every module id in the policy, placeholders and their
+// routed variants alike, to the module's position in delivery order.
+func moduleIndexByID(policyRaw json.RawMessage) map[string]int {
+	if len(policyRaw) == 0 {
+		return nil
+	}
+	var pol struct {
+		Sections []struct {
+			Modules []struct {
+				ID       string `json:"id"`
+				Variants []struct {
+					ID string `json:"id"`
+				} `json:"variants"`
+			} `json:"modules"`
+		} `json:"sections"`
+	}
+	if err := json.Unmarshal(policyRaw, &pol); err != nil {
+		return nil
+	}
+	out := map[string]int{}
+	i := 0
+	for _, s := range pol.Sections {
+		for _, m := range s.Modules {
+			if id := strings.TrimSpace(m.ID); id != "" {
+				out[id] = i
+			}
+			for _, v := range m.Variants {
+				if id := strings.TrimSpace(v.ID); id != "" {
+					out[id] = i
+				}
+			}
+			i++
+		}
+	}
+	return out
+}
+
+// reviewPeriodSec reads the top-level review_period_sec: how long after
+// submission the taker may still see their answers (0 = no limit).
+func reviewPeriodSec(policyRaw json.RawMessage) int {
+	if len(policyRaw) == 0 {
+		return 0
+	}
+	var pol struct {
+		ReviewPeriodSec int `json:"review_period_sec"`
+	}
+	if err := json.Unmarshal(policyRaw, &pol); err != nil || pol.ReviewPeriodSec < 0 {
+		return 0
+	}
+	return pol.ReviewPeriodSec
+}
+
+// FeedbackPolicy is the policy's "feedback" block, deciding per question tag
+// whether a taker's results reveal grading feedback and the correct answer.
+// With RevealTags set, only questions carrying one of them reveal; HideTags
+// withhold the questions they match and win over RevealTags. An empty
+// policy reveals everything. Tags compare case-insensitively.
+//
+// ShowAnswers caps what a taker may ask ephemeral grading to reveal beyond
+// that (?show_answers): ShowAnswersAlways the key, ShowAnswersPartial only
+// which of their own picks were wrong; anything else, e.g. unset, neither.
+type FeedbackPolicy struct {
+	RevealTags  []string `json:"reveal_tags,omitempty"`
+	HideTags    []string `json:"hide_tags,omitempty"`
+	ShowAnswers string   `json:"show_answers,omitempty"`
+}
+
+const (
+	ShowAnswersPartial = "partial"
+	ShowAnswersAlways  = "always"
+)
+
+// ParseFeedbackPolicy reads the feedback block; a missing or malformed one
+// is the empty (reveal all) policy.
+func ParseFeedbackPolicy(policyRaw json.RawMessage) FeedbackPolicy {
+	if len(policyRaw) == 0 {
+		return FeedbackPolicy{}
+	}
+	var pol struct {
+		Feedback FeedbackPolicy `json:"feedback"`
+	}
+	if err := json.Unmarshal(policyRaw, &pol); err != nil {
+		return FeedbackPolicy{}
+	}
+	return pol.Feedback
+}
+
+// Reveals reports whether q's feedback and answer may be shown to the taker.
+func (p FeedbackPolicy) Reveals(q Question) bool {
+	if hasTag(q.Tags, p.HideTags) {
+		return false
+	}
+	return len(p.RevealTags) == 0 || hasTag(q.Tags, p.RevealTags)
+}
+
+func hasTag(tags, want []string) bool {
+	for _, t := range tags {
+		for _, w := range want {
+			if strings.EqualFold(strings.TrimSpace(t), strings.TrimSpace(w)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// BlueprintRule is one entry of the policy's "blueprint": each attempt gets
+// Count bank questions carrying Tag (and of Type, if set), placed in
+// ModuleID. Points, if set, overrides the bank question's points.
+type BlueprintRule struct {
+	Tag      string  `json:"tag"`
+	Type     string  `json:"type,omitempty"`
+	Count    int     `json:"count"`
+	ModuleID string  `json:"module_id,omitempty"`
+	Points   float64 `json:"points,omitempty"`
+}
+
+// blueprintRules reads the policy's blueprint; rules without a positive
+// count are dropped.
+func blueprintRules(policyRaw json.RawMessage) []BlueprintRule {
+	if len(policyRaw) == 0 {
+		return nil
+	}
+	var pol struct {
+		Blueprint []BlueprintRule `json:"blueprint"`
+	}
+	if err := json.Unmarshal(policyRaw, &pol); err != nil {
+		return nil
+	}
+	out := pol.Blueprint[:0]
+	for _, r := range pol.Blueprint {
+		if r.Count > 0 {
+			out = append(out, r)
+		}
+	}
+	return out
+}
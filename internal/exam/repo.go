@@ -1,6 +1,9 @@
 package exam
 
-import "context"
+import (
+	"context"
+	"time"
+)
 
 type ListOpts struct {
 	Q          string
@@ -19,6 +22,18 @@ type AttemptListOpts struct {
 	Sort   string // started_at|submitted_at desc (default: started_at desc)
 }
 
+// StartOpts says whose attempt StartAttempt starts, and whether an attempt
+// already in progress is resumed instead of creating a second one. With an
+// OfferingID the attempt is linked to that offering, and a MaxAttempts above
+// zero caps the taker's attempts on it (ErrNoAttemptsLeft).
+type StartOpts struct {
+	ExamID      string
+	UserID      string
+	Resume      bool
+	OfferingID  string
+	MaxAttempts int
+}
+
 type ManualGradeInput struct {
 	ManualPoints float64 `json:"manual_points"`
 	Comment      string  `json:"comment,omitempty"`
@@ -28,13 +43,23 @@ type Store interface {
 	PutExam(e Exam) error
 	GetExam(id string) (Exam, error)                           // student-safe (no answer keys)
 	GetExamAdmin(ctx context.Context, id string) (Exam, error) // full exam, for export/teachers
-	NewAttempt(examID, userID string) (Attempt, error)
-	SaveResponses(attemptID string, resp map[string]interface{}) (Attempt, error)
-	Submit(attemptID string) (Attempt, error)
+	// GetAttemptExam is the full exam as one attempt was given it (its own
+	// draw for blueprint exams).
+	GetAttemptExam(ctx context.Context, attemptID string) (Exam, error)
+	NewAttempt(ctx context.Context, examID, userID string) (Attempt, error)
+	// StartAttempt resumes or creates an attempt atomically; see StartOpts.
+	// created is false when an in-progress attempt was resumed.
+	StartAttempt(ctx context.Context, opts StartOpts) (a Attempt, created bool, err error)
+	SaveResponses(ctx context.Context, attemptID string, resp map[string]interface{}) (Attempt, error)
+	// SaveResponsesPartial saves what it can of resp; see SaveReport.
+	SaveResponsesPartial(ctx context.Context, attemptID string, resp map[string]interface{}) (Attempt, SaveReport, error)
+	Submit(ctx context.Context, attemptID string) (Attempt, error)
 	GetAttempt(id string) (Attempt, error)
 
 	ListExams(ctx context.Context, opts ListOpts) ([]ExamSummary, error)
-	AdvanceModule(attemptID string) (Attempt, error)
+	// AdvanceModule moves the attempt on from module fromIndex; an attempt
+	// already past it is ErrModuleMoved.
+	AdvanceModule(attemptID string, fromIndex int) (Attempt, error)
 
 	// NEW: list attempts with filters for teacher/admin dashboards (and student “my attempts”)
 	ListAttempts(ctx context.Context, opts AttemptListOpts) ([]Attempt, error)
@@ -42,4 +67,22 @@ type Store interface {
 
 	GetAttemptItems(ctx context.Context, attemptID string) ([]AttemptItem, error)
 	ApplyManualGrades(ctx context.Context, attemptID string, updates map[string]ManualGradeInput, gradedBy string, finalize bool) (Attempt, error)
+
+	// ClaimAttempts reassigns the attempts owned by an anonymous id to userID
+	// (after the anonymous taker logs in), except those that would take the
+	// user past an offering's max_attempts. Returns the number moved.
+	ClaimAttempts(ctx context.Context, anonID, userID string) (int64, error)
+
+	// Heartbeat records that the taker's browser is still there (last_seen_at)
+	// and returns the attempt with its remaining time.
+	Heartbeat(ctx context.Context, attemptID string) (Attempt, error)
+	// ListStaleAttempts lists in-progress attempts (optionally of one exam)
+	// not heard from for staleAfter: no heartbeat, or none since starting.
+	// A non-empty teacherID keeps only attempts in offerings of courses that
+	// teacher teaches.
+	ListStaleAttempts(ctx context.Context, examID, teacherID string, staleAfter time.Duration) ([]Attempt, error)
+
+	// ExamStats returns the cached item analysis for examID, rebuilding it if
+	// a submission or regrade invalidated it, or when refresh is set.
+	ExamStats(ctx context.Context, examID string, refresh bool) (ExamStats, error)
 }
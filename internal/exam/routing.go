@@ -3,9 +3,17 @@ package exam
 import "context"
 
 // Perf is a minimal snapshot of performance that routers can use.
-// Populate RawPoints (e.g., # correct so far in the current section/module).
+// RawPoints is the number correct in the module just finished;
+// AvgDifficulty is the mean Difficulty of those correct items (0 if none).
 type Perf struct {
-	RawPoints float64
+	RawPoints     float64
+	AvgDifficulty float64
+}
+
+// Weighted is the difficulty-weighted score: each correct item counts its
+// difficulty, so three hard items outscore three easy ones.
+func (p Perf) Weighted() float64 {
+	return p.RawPoints * p.AvgDifficulty
 }
 
 // Router decides which concrete module ID to deliver next.
@@ -0,0 +1,76 @@
+package exam
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// QuestionsSchemaVersion is the shape of questions_json written today:
+//
+//	{"schema_version": 2, "questions": [...]}
+//
+// Version 1 is the bare question array stored before the version was
+// stamped. unmarshalQuestions upgrades older versions on load, so every
+// read returns the current shape whatever the row's age.
+const QuestionsSchemaVersion = 2
+
+type questionsDoc struct {
+	SchemaVersion int        `json:"schema_version"`
+	Questions     []Question `json:"questions"`
+}
+
+// questionUpgrades[v] brings questions from version v to v+1.
+var questionUpgrades = map[int]func([]Question){
+	1: upgradeQuestionsV1,
+}
+
+func marshalQuestions(qs []Question) ([]byte, error) {
+	if qs == nil {
+		qs = []Question{}
+	}
+	return json.Marshal(questionsDoc{SchemaVersion: QuestionsSchemaVersion, Questions: qs})
+}
+
+func unmarshalQuestions(qjson string, qs *[]Question) error {
+	raw := bytes.TrimSpace([]byte(qjson))
+	doc := questionsDoc{SchemaVersion: 1}
+	var err error
+	if bytes.HasPrefix(raw, []byte("[")) {
+		err = json.Unmarshal(raw, &doc.Questions)
+	} else {
+		err = json.Unmarshal(raw, &doc)
+	}
+	if err != nil {
+		return err
+	}
+	if doc.SchemaVersion > QuestionsSchemaVersion {
+		return fmt.Errorf("questions schema version %d is newer than this server (%d)", doc.SchemaVersion, QuestionsSchemaVersion)
+	}
+	for v := doc.SchemaVersion; v < QuestionsSchemaVersion; v++ {
+		questionUpgrades[v](doc.Questions)
+	}
+	*qs = orderQuestions(doc.Questions)
+	return nil
+}
+
+// upgradeQuestionsV1 fills what version 1 exams, written before module
+// placement, tags and QTI import existed, may lack or spell loosely: ids,
+// types and module ids come trimmed (types lower-case) and Tags and
+// AnswerKey are never nil.
+func upgradeQuestionsV1(qs []Question) {
+	for i := range qs {
+		q := &qs[i]
+		q.ID = strings.TrimSpace(q.ID)
+		q.Type = strings.ToLower(strings.TrimSpace(q.Type))
+		q.SectionID = strings.TrimSpace(q.SectionID)
+		q.ModuleID = strings.TrimSpace(q.ModuleID)
+		if q.Tags == nil {
+			q.Tags = []string{}
+		}
+		if q.AnswerKey == nil {
+			q.AnswerKey = []string{}
+		}
+	}
+}
@@ -3,23 +3,35 @@ package exam
 
 import (
 	"context"
+	"crypto/rand"
 	"database/sql"
+	"encoding/binary"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"reflect"
+	"sort"
 	"strings"
 	"time"
 
+	"go.opentelemetry.io/otel/attribute"
+
 	"github.com/mind-engage/mindengage-lms/internal/grading"
 	syncx "github.com/mind-engage/mindengage-lms/internal/sync"
 )
 
 var (
+	ErrAttemptNotFound    = errors.New("attempt not found")
 	ErrAttemptSubmitted   = errors.New("attempt already submitted")
 	ErrOutsideModule      = errors.New("outside current module window")
 	ErrBackwardNavBlocked = errors.New("backward navigation blocked")
 	ErrEditBackBlocked    = errors.New("editing a locked (past) question")
 	ErrTimeOver           = errors.New("time over")
+	ErrModuleCompleted    = errors.New("editing a question in a completed module")
+	ErrUnknownQuestion    = errors.New("unknown question id")
+	ErrModuleMoved        = errors.New("attempt is no longer in that module")
+	ErrNoAttemptsLeft     = errors.New("no attempts left for this offering")
 )
 
 // SQLStore persists exams/attempts in SQL (SQLite or Postgres).
@@ -27,12 +39,32 @@ type SQLStore struct {
 	db     *sql.DB
 	driver string // "sqlite" or "postgres"
 	grader grading.Grader
+
+	// Now is the store's clock: attempt start, deadlines, remaining time,
+	// module expiry and heartbeats all read it. nil means time.Now; tests
+	// set a fake clock to step through timing deterministically.
+	Now func() time.Time
+
+	// OnSubmitted, if set, runs once per attempt after Submit finalizes it;
+	// re-submits of an already submitted attempt don't call it.
+	OnSubmitted func(ctx context.Context, a Attempt)
+
+	// Passback enables the AGS passback outbox (see outbox.go). It needs the
+	// gradebook tables (lti_user_map) in the same database.
+	Passback bool
 }
 
 func NewSQLStore(db *sql.DB, driver string, grader grading.Grader) *SQLStore {
 	return &SQLStore{db: db, driver: driver, grader: grader}
 }
 
+func (s *SQLStore) now() time.Time {
+	if s.Now != nil {
+		return s.Now()
+	}
+	return time.Now()
+}
+
 /* ------------------------- Exams ------------------------- */
 
 func (s *SQLStore) PutExam(e Exam) error {
@@ -40,7 +72,11 @@ func (s *SQLStore) PutExam(e Exam) error {
 	if e.TimeLimitSec < 0 {
 		e.TimeLimitSec = 0
 	}
-	qj, err := json.Marshal(e.Questions)
+	if err := ValidateQuestions(e.Questions); err != nil {
+		return err
+	}
+	e.Questions = orderQuestions(append([]Question(nil), e.Questions...))
+	qj, err := marshalQuestions(e.Questions)
 	if err != nil {
 		return err
 	}
@@ -59,7 +95,7 @@ func (s *SQLStore) PutExam(e Exam) error {
 			profile=EXCLUDED.profile,
 			policy_json=EXCLUDED.policy_json
 	`,
-		e.ID, e.Title, e.TimeLimitSec, string(qj), time.Now().Unix(), e.Profile, pjson)
+		e.ID, e.Title, e.TimeLimitSec, string(qj), s.now().Unix(), e.Profile, pjson)
 	return err
 }
 
@@ -79,7 +115,7 @@ func (s *SQLStore) GetExam(id string) (Exam, error) {
 		return Exam{}, err
 	}
 
-	if err := json.Unmarshal([]byte(qjson), &e.Questions); err != nil {
+	if err := unmarshalQuestions(qjson, &e.Questions); err != nil {
 		return Exam{}, err
 	}
 
@@ -88,16 +124,19 @@ func (s *SQLStore) GetExam(id string) (Exam, error) {
 		e.PolicyRaw = json.RawMessage(pjson)
 	}
 
-	// Strip answer keys for student response
+	// Strip answer keys (and the scoring weights that reveal them) for students
 	for i := range e.Questions {
 		e.Questions[i].AnswerKey = nil
+		e.Questions[i].Scoring = nil
 	}
 
 	return e, nil
 }
 
 // Admin fetch: returns full exam (including answer keys), plus profile/policy for exports/timing logic.
-func (s *SQLStore) GetExamAdmin(ctx context.Context, id string) (Exam, error) {
+func (s *SQLStore) GetExamAdmin(ctx context.Context, id string) (_ Exam, err error) {
+	ctx, span := startSpan(ctx, "GetExamAdmin", attribute.String("exam.id", id))
+	defer func() { endSpan(span, err) }()
 	row := s.db.QueryRowContext(ctx, `
 		SELECT id, title, time_limit_sec, questions_json, created_at, profile, policy_json
 		FROM exams WHERE id=$1`, id)
@@ -106,7 +145,7 @@ func (s *SQLStore) GetExamAdmin(ctx context.Context, id string) (Exam, error) {
 	if err := row.Scan(&e.ID, &e.Title, &e.TimeLimitSec, &qjson, &e.CreatedAt, &e.Profile, &pjson); err != nil {
 		return Exam{}, err
 	}
-	if err := json.Unmarshal([]byte(qjson), &e.Questions); err != nil {
+	if err := unmarshalQuestions(qjson, &e.Questions); err != nil {
 		return Exam{}, err
 	}
 	if pjson != "" {
@@ -115,8 +154,31 @@ func (s *SQLStore) GetExamAdmin(ctx context.Context, id string) (Exam, error) {
 	return e, nil
 }
 
+// GetAttemptExam is the full exam (with answer keys) as the attempt sees it:
+// for a blueprint exam, with the questions drawn for this attempt.
+func (s *SQLStore) GetAttemptExam(ctx context.Context, attemptID string) (Exam, error) {
+	var examID, qjson string
+	if err := s.db.QueryRowContext(ctx, `SELECT exam_id, COALESCE(questions_json,'') FROM attempts WHERE id=$1`, attemptID).
+		Scan(&examID, &qjson); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return Exam{}, ErrAttemptNotFound
+		}
+		return Exam{}, err
+	}
+	ex, err := s.GetExamAdmin(ctx, examID)
+	if err != nil || qjson == "" {
+		return ex, err
+	}
+	if err := unmarshalQuestions(qjson, &ex.Questions); err != nil {
+		return Exam{}, err
+	}
+	return ex, nil
+}
+
 // ListExams returns student-safe summaries. Title filter optional.
-func (s *SQLStore) ListExams(ctx context.Context, opts ListOpts) ([]ExamSummary, error) {
+func (s *SQLStore) ListExams(ctx context.Context, opts ListOpts) (_ []ExamSummary, err error) {
+	ctx, span := startSpan(ctx, "ListExams")
+	defer func() { endSpan(span, err) }()
 	if opts.Limit <= 0 {
 		opts.Limit = 50
 	}
@@ -196,14 +258,130 @@ LIMIT %d OFFSET %d
 
 /* ------------------------ Attempts ------------------------ */
 
-func (s *SQLStore) NewAttempt(examID, userID string) (Attempt, error) {
-	// --- unchanged prelude: load exam (admin view) for policy/timing ---
-	ex, err := s.GetExamAdmin(context.Background(), examID)
+func (s *SQLStore) NewAttempt(ctx context.Context, examID, userID string) (_ Attempt, err error) {
+	ctx, span := startSpan(ctx, "NewAttempt", attribute.String("exam.id", examID))
+	defer func() { endSpan(span, err) }()
+
+	row, err := s.draftAttempt(ctx, examID, userID)
+	if err != nil {
+		return Attempt{}, err
+	}
+	return row.insert(ctx, s.db)
+}
+
+// StartAttempt resumes the taker's in-progress attempt or creates one. The
+// check and the insert share a transaction under a per-(exam, user) lock,
+// so concurrent starts cannot both create an attempt, or both take an
+// offering's last attempt.
+func (s *SQLStore) StartAttempt(ctx context.Context, opts StartOpts) (_ Attempt, created bool, err error) {
+	ctx, span := startSpan(ctx, "StartAttempt", attribute.String("exam.id", opts.ExamID))
+	defer func() { endSpan(span, err) }()
+
+	// Drafted up front: sqlite's pool has one connection, which the tx holds.
+	row, err := s.draftAttempt(ctx, opts.ExamID, opts.UserID)
+	if err != nil {
+		return Attempt{}, false, err
+	}
+	row.offeringID = opts.OfferingID
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return Attempt{}, false, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if err := s.lockTaker(ctx, tx, opts.ExamID, opts.UserID); err != nil {
+		return Attempt{}, false, err
+	}
+	if opts.Resume {
+		var openID string
+		err := tx.QueryRowContext(ctx, `
+			SELECT id FROM attempts
+			 WHERE exam_id=$1 AND user_id=$2 AND status='in_progress'
+			   AND ($3 = '' OR offering_id = $3)
+			 ORDER BY started_at DESC LIMIT 1`, opts.ExamID, opts.UserID, opts.OfferingID).Scan(&openID)
+		switch {
+		case err == nil:
+			if err := tx.Commit(); err != nil {
+				return Attempt{}, false, err
+			}
+			a, err := s.GetAttempt(openID)
+			return a, false, err
+		case !errors.Is(err, sql.ErrNoRows):
+			return Attempt{}, false, err
+		}
+	}
+	if opts.OfferingID != "" && opts.MaxAttempts > 0 {
+		var used int
+		if err := tx.QueryRowContext(ctx,
+			`SELECT COUNT(*) FROM attempts WHERE offering_id=$1 AND user_id=$2`,
+			opts.OfferingID, opts.UserID).Scan(&used); err != nil {
+			return Attempt{}, false, err
+		}
+		if used >= opts.MaxAttempts {
+			return Attempt{}, false, ErrNoAttemptsLeft
+		}
+	}
+	a, err := row.insert(ctx, tx)
+	if err != nil {
+		return Attempt{}, false, err
+	}
+	if err := tx.Commit(); err != nil {
+		return Attempt{}, false, err
+	}
+	return a, true, nil
+}
+
+// lockTaker serializes attempt starts for one (exam, user) until tx ends: a
+// transaction-scoped advisory lock on postgres; on sqlite a write takes the
+// database write lock.
+func (s *SQLStore) lockTaker(ctx context.Context, tx *sql.Tx, examID, userID string) error {
+	q, args := `UPDATE attempts SET status=status WHERE exam_id=$1 AND user_id=$2`, []any{examID, userID}
+	if s.driver == "postgres" {
+		q, args = `SELECT pg_advisory_xact_lock(hashtext($1))`, []any{examID + "|" + userID}
+	}
+	_, err := tx.ExecContext(ctx, q, args...)
+	return err
+}
+
+// attemptRow is a new attempt, drafted from its exam and ready to insert.
+type attemptRow struct {
+	id, examID, userID, respJSON string
+	now, firstMod, overall       int64
+	startIdx                     int
+	firstConcrete, ownQJSON      string
+	seed                         int64
+	offeringID                   string // "" leaves the attempt unlinked
+}
+
+// draftAttempt loads the exam (admin view) for policy and timing, and draws
+// blueprint questions, without writing anything.
+func (s *SQLStore) draftAttempt(ctx context.Context, examID, userID string) (attemptRow, error) {
+	ex, err := s.GetExamAdmin(ctx, examID)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return Attempt{}, errors.New("exam not found")
+			return attemptRow{}, errors.New("exam not found")
 		}
-		return Attempt{}, err
+		return attemptRow{}, err
+	}
+
+	// Blueprint exams draw this attempt's own questions from the bank.
+	var ownQJSON string
+	var seed int64
+	if rules := blueprintRules(ex.PolicyRaw); len(rules) > 0 {
+		var b [8]byte
+		if _, err := rand.Read(b[:]); err != nil {
+			return attemptRow{}, err
+		}
+		seed = int64(binary.BigEndian.Uint64(b[:]) >> 1)
+		if ex.Questions, err = assembleFromBlueprint(ctx, NewBank(s.db), ex.Questions, rules, seed); err != nil {
+			return attemptRow{}, err
+		}
+		qj, err := marshalQuestions(ex.Questions)
+		if err != nil {
+			return attemptRow{}, err
+		}
+		ownQJSON = string(qj)
 	}
 
 	// Compute module timings from policy (if any), with fallback to overall time_limit_sec
@@ -212,7 +390,7 @@ func (s *SQLStore) NewAttempt(examID, userID string) (Attempt, error) {
 		modules = []int{ex.TimeLimitSec}
 	}
 
-	now := time.Now().Unix()
+	now := s.now().Unix()
 	overall := int64(0)
 	for _, sec := range modules {
 		if sec > 0 {
@@ -241,22 +419,41 @@ func (s *SQLStore) NewAttempt(examID, userID string) (Attempt, error) {
 		firstConcrete = modIDs[0]
 	}
 
-	// --- persist attempt ---
-	id := time.Now().Format("20060102150405")
-	resp := map[string]interface{}{}
-	respJSON, _ := json.Marshal(resp)
+	// Timestamp prefix keeps ids roughly sortable; the random suffix keeps
+	// concurrent starts (e.g. many anonymous takers) from colliding.
+	var sfx [4]byte
+	if _, err := rand.Read(sfx[:]); err != nil {
+		return attemptRow{}, err
+	}
+	respJSON, _ := json.Marshal(map[string]interface{}{})
+	return attemptRow{
+		id:     s.now().Format("20060102150405") + "-" + hex.EncodeToString(sfx[:]),
+		examID: examID, userID: userID, respJSON: string(respJSON),
+		now: now, firstMod: firstMod, overall: overall,
+		startIdx: startIdx, firstConcrete: firstConcrete,
+		ownQJSON: ownQJSON, seed: seed,
+	}, nil
+}
 
-	_, err = s.db.Exec(`
+// execer is satisfied by both *sql.DB and *sql.Tx.
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// insert persists the attempt through db (the store's *sql.DB or a tx).
+func (r attemptRow) insert(ctx context.Context, db execer) (Attempt, error) {
+	_, err := db.ExecContext(ctx, `
 		INSERT INTO attempts (
 			id, exam_id, user_id, status, score, responses_json, started_at,
 			module_index, module_started_at, module_deadline, overall_deadline,
-			current_index, max_reached_index, current_module_id
+			current_index, max_reached_index, current_module_id, questions_json, draw_seed, offering_id
 		)
-		VALUES ($1,$2,$3,'in_progress',0,$4,$5,$6,$7,$8,$9,$10,$11,$12)
+		VALUES ($1,$2,$3,'in_progress',0,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14,$15)
 	`,
-		id, examID, userID, string(respJSON), now,
-		0, now, nullableDeadline(now, firstMod), nullableDeadline(now, overall),
-		startIdx, startIdx, firstConcrete,
+		r.id, r.examID, r.userID, r.respJSON, r.now,
+		0, r.now, nullableDeadline(r.now, r.firstMod), nullableDeadline(r.now, r.overall),
+		r.startIdx, r.startIdx, r.firstConcrete, r.ownQJSON, r.seed,
+		sql.NullString{String: r.offeringID, Valid: r.offeringID != ""},
 	)
 	if err != nil {
 		return Attempt{}, err
@@ -264,20 +461,40 @@ func (s *SQLStore) NewAttempt(examID, userID string) (Attempt, error) {
 
 	// Return a basic view; clients can call GetAttempt to fetch full timing fields
 	return Attempt{
-		ID:              id,
-		ExamID:          examID,
-		UserID:          userID,
+		ID:              r.id,
+		ExamID:          r.examID,
+		UserID:          r.userID,
 		Status:          "in_progress",
 		Score:           0,
-		Responses:       resp,
-		StartedAt:       now,
+		Responses:       map[string]interface{}{},
+		StartedAt:       r.now,
 		ModuleIndex:     0,
-		ModuleStartedAt: now,
-		CurrentModuleID: firstConcrete,
+		ModuleStartedAt: r.now,
+		CurrentModuleID: r.firstConcrete,
 	}, nil
 }
 
-func (s *SQLStore) SaveResponses(attemptID string, resp map[string]interface{}) (Attempt, error) {
+func (s *SQLStore) SaveResponses(ctx context.Context, attemptID string, resp map[string]interface{}) (_ Attempt, err error) {
+	ctx, span := startSpan(ctx, "SaveResponses", attribute.String("attempt.id", attemptID))
+	defer func() { endSpan(span, err) }()
+	a, _, err := s.saveResponses(ctx, attemptID, resp, false)
+	return a, err
+}
+
+// SaveResponsesPartial saves the responses that pass the attempt rules and
+// reports the rest instead of failing the batch. Attempt-wide errors (time
+// over, already submitted) still fail it.
+func (s *SQLStore) SaveResponsesPartial(ctx context.Context, attemptID string, resp map[string]interface{}) (_ Attempt, _ SaveReport, err error) {
+	ctx, span := startSpan(ctx, "SaveResponsesPartial", attribute.String("attempt.id", attemptID))
+	defer func() { endSpan(span, err) }()
+	return s.saveResponses(ctx, attemptID, resp, true)
+}
+
+// saveResponses checks resp against the attempt rules and merges it in.
+// Unless partial, the first broken rule fails the whole save.
+func (s *SQLStore) saveResponses(ctx context.Context, attemptID string, resp map[string]interface{}, partial bool) (Attempt, SaveReport, error) {
+	var report SaveReport
+
 	// Load attempt (with timing columns for enforcement)
 	var a Attempt
 	var rjson string
@@ -285,18 +502,18 @@ func (s *SQLStore) SaveResponses(attemptID string, resp map[string]interface{})
 	var moduleStarted, moduleDeadline, overallDeadline sql.NullInt64
 	var curModID sql.NullString
 
-	row := s.db.QueryRow(`
+	row := s.db.QueryRowContext(ctx, `
 	  SELECT id, exam_id, user_id, status, score, responses_json,
 			 module_index, module_started_at, module_deadline, overall_deadline,
-			 current_index, max_reached_index, current_module_id
+			 current_index, max_reached_index, current_module_id, COALESCE(responses_updated_at,0)
 	  FROM attempts WHERE id=$1`, attemptID)
 	if err := row.Scan(&a.ID, &a.ExamID, &a.UserID, &a.Status, &a.Score, &rjson,
 		&moduleIdx, &moduleStarted, &moduleDeadline, &overallDeadline,
-		&curIdx, &maxIdx, &curModID); err != nil {
+		&curIdx, &maxIdx, &curModID, &a.ResponsesUpdatedAt); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return Attempt{}, errors.New("attempt not found")
+			return Attempt{}, report, ErrAttemptNotFound
 		}
-		return Attempt{}, err
+		return Attempt{}, report, err
 	}
 	if err := json.Unmarshal([]byte(rjson), &a.Responses); err != nil || a.Responses == nil {
 		a.Responses = map[string]interface{}{}
@@ -306,26 +523,28 @@ func (s *SQLStore) SaveResponses(attemptID string, resp map[string]interface{})
 	}
 
 	// timing guards (unchanged)
-	now := time.Now().Unix()
+	now := s.now().Unix()
 	if overallDeadline.Valid && now > overallDeadline.Int64 {
-		return Attempt{}, ErrTimeOver
+		return Attempt{}, report, ErrTimeOver
 	}
 	if moduleDeadline.Valid && now > moduleDeadline.Int64 {
-		return Attempt{}, ErrTimeOver
+		return Attempt{}, report, ErrTimeOver
 	}
 	if a.Status == "submitted" {
-		return Attempt{}, ErrAttemptSubmitted
+		return Attempt{}, report, ErrAttemptSubmitted
 	}
 
 	// Load exam/policy for enforcement
-	ex, err := s.GetExamAdmin(context.Background(), a.ExamID)
+	ex, err := s.GetAttemptExam(ctx, a.ID)
 	if err != nil {
-		return Attempt{}, err
+		return Attempt{}, report, err
 	}
 	nav := parseNavPolicy(ex.PolicyRaw)
 
-	// Module lock (prefer the concrete current_module_id)
-	if nav.ModuleLocked {
+	// The rules a response must pass, in the order they are reported.
+	qidToIdx, qidToMod, _ := buildIndexMaps(ex.Questions)
+	var allowed map[string]struct{}
+	if nav.ModuleLocked { // prefer the concrete current_module_id
 		targetID := strings.TrimSpace(a.CurrentModuleID)
 		if targetID == "" {
 			// fallback to placeholder by index
@@ -334,22 +553,75 @@ func (s *SQLStore) SaveResponses(attemptID string, resp map[string]interface{})
 				targetID = strings.TrimSpace(modIDs[moduleIdx])
 			}
 		}
-		allowed := allowedQIDsForModuleID(ex, targetID)
-		if allowed != nil {
-			for k := range resp {
-				if _, ok := allowed[k]; !ok {
-					return Attempt{}, ErrOutsideModule
-				}
+		allowed = allowedQIDsForModuleID(ex, targetID)
+	}
+	modIndex := moduleIndexByID(ex.PolicyRaw)
+	checks := []func(k string, v interface{}) error{
+		// Only the exam's own questions can be answered.
+		func(k string, _ interface{}) error {
+			if _, ok := qidToIdx[k]; !ok {
+				return fmt.Errorf("%w: %q", ErrUnknownQuestion, k)
 			}
-		}
+			return nil
+		},
+		// Module lock
+		func(k string, _ interface{}) error {
+			if _, ok := allowed[k]; allowed != nil && !ok {
+				return ErrOutsideModule
+			}
+			return nil
+		},
+		// Modules already advanced past are read-only, whatever allow_back
+		// says (allow_back only applies within the current module). Resending
+		// an unchanged answer is fine: the exam page saves full snapshots.
+		func(k string, v interface{}) error {
+			if moduleIdx == 0 || reflect.DeepEqual(a.Responses[k], v) {
+				return nil
+			}
+			if i, ok := modIndex[strings.TrimSpace(qidToMod[k])]; ok && i < moduleIdx {
+				return ErrModuleCompleted
+			}
+			return nil
+		},
+		// NEW: forward-only editing guard when allow_back=false
+		func(k string, _ interface{}) error {
+			if idx, ok := qidToIdx[k]; ok && !nav.AllowBack && idx < curIdx {
+				return ErrEditBackBlocked
+			}
+			return nil
+		},
 	}
 
-	// NEW: forward-only editing guard when allow_back=false
-	if !nav.AllowBack {
-		qidToIdx, _, _ := buildIndexMaps(ex.Questions)
+	if partial {
+		report.Rejected = map[string]error{}
+		keys := make([]string, 0, len(resp))
 		for k := range resp {
-			if idx, ok := qidToIdx[k]; ok && idx < curIdx {
-				return Attempt{}, ErrEditBackBlocked
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		ok := make(map[string]interface{}, len(resp))
+	next:
+		for _, k := range keys {
+			for _, check := range checks {
+				if err := check(k, resp[k]); err != nil {
+					report.Rejected[k] = err
+					continue next
+				}
+			}
+			ok[k] = resp[k]
+			report.Accepted = append(report.Accepted, k)
+		}
+		resp = ok
+		if len(resp) == 0 {
+			a, err := s.GetAttempt(attemptID)
+			return a, report, err
+		}
+	} else {
+		for _, check := range checks {
+			for k, v := range resp {
+				if err := check(k, v); err != nil {
+					return Attempt{}, report, err
+				}
 			}
 		}
 	}
@@ -359,49 +631,136 @@ func (s *SQLStore) SaveResponses(attemptID string, resp map[string]interface{})
 		a.Responses[k] = v
 	}
 	buf, _ := json.Marshal(a.Responses)
-	if _, err := s.db.Exec(`UPDATE attempts SET responses_json=$1 WHERE id=$2`, string(buf), attemptID); err != nil {
-		return Attempt{}, err
+	// Never step back, even if the clock does: clients show the latest save.
+	savedAt := s.now().UnixMilli()
+	if savedAt <= a.ResponsesUpdatedAt {
+		savedAt = a.ResponsesUpdatedAt + 1
 	}
-	return s.GetAttempt(attemptID)
+	if _, err := s.db.ExecContext(ctx, `UPDATE attempts SET responses_json=$1, responses_updated_at=$2 WHERE id=$3`,
+		string(buf), savedAt, attemptID); err != nil {
+		return Attempt{}, report, err
+	}
+	a, err = s.GetAttempt(attemptID)
+	return a, report, err
 }
 
-func (s *SQLStore) Submit(attemptID string) (Attempt, error) {
+func (s *SQLStore) Submit(ctx context.Context, attemptID string) (_ Attempt, err error) {
+	ctx, span := startSpan(ctx, "Submit", attribute.String("attempt.id", attemptID))
+	defer func() { endSpan(span, err) }()
+
 	a, err := s.GetAttempt(attemptID)
 	if err != nil {
 		return Attempt{}, err
 	}
 	if a.Status == "submitted" {
-		// still recompute scores (idempotent) to ensure item rows exist
-	} // else proceed
+		return a, nil // already finalized (by an earlier or concurrent submit)
+	}
+
+	finalized := false
+	if s.gradingMode(ctx, attemptID) == GradeDeferred {
+		// Only close the attempt; scoreAttempt runs later (GradePending, or the
+		// first teacher read of its items).
+		res, err := s.db.ExecContext(ctx, `
+		  UPDATE attempts
+		     SET status='submitted',
+		         grading_progress=$1,
+		         submitted_at=CASE WHEN COALESCE(submitted_at,0)=0 THEN $2 ELSE submitted_at END
+		   WHERE id=$3 AND status='in_progress'`,
+			GradingPending, s.now().Unix(), attemptID)
+		if err != nil {
+			return Attempt{}, err
+		}
+		n, _ := res.RowsAffected()
+		finalized = n > 0
+	} else if finalized, err = s.scoreAttempt(ctx, a, false); err != nil {
+		return Attempt{}, err
+	}
+
+	if finalized {
+		_ = syncx.NewEventRepo(s.db).Append(ctx, syncx.Event{
+			SiteID:   "local",
+			Type:     "AttemptSubmitted",
+			Key:      attemptID,
+			DataJSON: "{}", // keep minimal; responses already stored
+		})
+	}
 
-	// load full exam WITH keys for grading
-	row := s.db.QueryRow(`SELECT questions_json FROM exams WHERE id=$1`, a.ExamID)
+	a, err = s.GetAttempt(attemptID)
+	if err == nil && finalized && s.OnSubmitted != nil {
+		s.OnSubmitted(ctx, a)
+	}
+	return a, err
+}
+
+// gradingMode is the grading mode of the attempt's offering; attempts outside
+// an offering grade on submit.
+func (s *SQLStore) gradingMode(ctx context.Context, attemptID string) string {
+	var mode string
+	_ = s.db.QueryRowContext(ctx, `
+		SELECT COALESCE(o.grading_mode,'')
+		  FROM attempts a LEFT JOIN exam_offerings o ON o.id = a.offering_id
+		 WHERE a.id=$1`, attemptID).Scan(&mode)
+	if mode == GradeDeferred {
+		return GradeDeferred
+	}
+	return GradeOnSubmit
+}
+
+// scoreAttempt grades a's responses into attempt_items, marks it submitted and
+// graded with the new score, and enqueues its passback, in one transaction
+// holding the attempt row lock. It does nothing (and reports false) unless
+// the attempt is still in progress or, with onlyPending, still waiting for
+// deferred grading, so concurrent submits and graders score it once.
+func (s *SQLStore) scoreAttempt(ctx context.Context, a Attempt, onlyPending bool) (bool, error) {
+	attemptID := a.ID
+	// load the attempt's questions WITH keys for grading
+	row := s.db.QueryRowContext(ctx, `
+		SELECT COALESCE(NULLIF(a.questions_json,''), e.questions_json)
+		  FROM attempts a JOIN exams e ON e.id = a.exam_id
+		 WHERE a.id=$1`, attemptID)
 	var qjson string
 	if err := row.Scan(&qjson); err != nil {
-		return Attempt{}, err
+		return false, err
 	}
 	var questions []Question
-	if err := json.Unmarshal([]byte(qjson), &questions); err != nil {
-		return Attempt{}, err
+	if err := unmarshalQuestions(qjson, &questions); err != nil {
+		return false, err
 	}
 
-	ctx := context.Background()
 	autoTotal := 0.0
 
 	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
-		return Attempt{}, err
+		return false, err
 	}
 	defer func() { _ = tx.Rollback() }()
 
+	// Re-check the state under the lock; grade the responses as stored now.
+	if err := s.lockAttempt(ctx, tx, attemptID); err != nil {
+		return false, err
+	}
+	var status, progress, rjson string
+	if err := tx.QueryRowContext(ctx, `SELECT status, COALESCE(grading_progress,''), responses_json FROM attempts WHERE id=$1`,
+		attemptID).Scan(&status, &progress, &rjson); err != nil {
+		return false, err
+	}
+	if (onlyPending && progress != GradingPending) || (!onlyPending && status != "in_progress") {
+		return false, nil // finalized meanwhile
+	}
+	_ = json.Unmarshal([]byte(rjson), &a.Responses)
+
 	// For manual sum we look at persisted rows (may have pre-existing manual points)
+	graded := make(map[string]bool, len(questions))
 	for _, q := range questions {
+		if graded[q.ID] {
+			continue // duplicate id (see buildIndexMaps): grade it once
+		}
+		graded[q.ID] = true
 		resp, has := a.Responses[q.ID]
 		// grade what we can automatically
 		auto := 0.0
 		if has {
-			gq := grading.Q{Type: q.Type, Points: q.Points, AnswerKey: q.AnswerKey}
-			res, err := s.grader.Grade(ctx, gq, resp)
+			res, err := s.grade(ctx, q.GradingQ(), resp)
 			if err == nil {
 				auto = res.AutoPoints
 			}
@@ -411,7 +770,7 @@ func (s *SQLStore) Submit(attemptID string) (Attempt, error) {
 		// upsert attempt_items
 		respJSON, _ := json.Marshal(resp)
 		needMan := needsManualForType(q.Type, q)
-		_, err := tx.Exec(`
+		_, err := tx.ExecContext(ctx, `
 			INSERT INTO attempt_items (attempt_id, question_id, q_type, points_max, auto_points, manual_points, needs_manual, response_json)
 			VALUES ($1,$2,$3,$4,$5,
 			        COALESCE((SELECT manual_points FROM attempt_items WHERE attempt_id=$1 AND question_id=$2), 0),
@@ -424,60 +783,102 @@ func (s *SQLStore) Submit(attemptID string) (Attempt, error) {
 			  response_json=EXCLUDED.response_json
 		`, attemptID, q.ID, q.Type, q.Points, auto, needMan, string(respJSON))
 		if err != nil {
-			return Attempt{}, err
+			return false, err
 		}
 	}
 
 	// sum manual points currently on items
 	var manualSum float64
-	if err := tx.QueryRow(`SELECT COALESCE(SUM(manual_points),0) FROM attempt_items WHERE attempt_id=$1`, attemptID).Scan(&manualSum); err != nil {
-		return Attempt{}, err
+	if err := tx.QueryRowContext(ctx, `SELECT COALESCE(SUM(manual_points),0) FROM attempt_items WHERE attempt_id=$1`, attemptID).Scan(&manualSum); err != nil {
+		return false, err
 	}
 
-	now := time.Now().Unix()
+	now := s.now().Unix()
 	// status becomes submitted (or stays submitted), and score is auto+manual
-	_, err = tx.Exec(`
+	if _, err := tx.ExecContext(ctx, `
 	  UPDATE attempts
 	     SET status='submitted',
-	         auto_score=$1,
-	         manual_score=$2,
-	         score=$3,
-	         submitted_at=COALESCE(submitted_at, $4)
-	   WHERE id=$5`,
-		autoTotal, manualSum, autoTotal+manualSum, now, attemptID)
-	if err != nil {
-		return Attempt{}, err
+	         grading_progress=$1,
+	         auto_score=$2,
+	         manual_score=$3,
+	         score=$4,
+	         submitted_at=CASE WHEN COALESCE(submitted_at,0)=0 THEN $5 ELSE submitted_at END
+	   WHERE id=$6`,
+		GradingGraded, autoTotal, manualSum, autoTotal+manualSum, now, attemptID); err != nil {
+		return false, err
 	}
-
-	if err := tx.Commit(); err != nil {
-		return Attempt{}, err
+	// Passback intent, committed together with the score it reports.
+	if err := s.enqueuePassback(ctx, tx, a, autoTotal+manualSum, now); err != nil {
+		return false, err
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM exam_stats WHERE exam_id=$1`, a.ExamID); err != nil {
+		return false, err
 	}
 
-	_ = syncx.NewEventRepo(s.db).Append(context.Background(), syncx.Event{
-		SiteID:   "local",
-		Type:     "AttemptSubmitted",
-		Key:      attemptID,
-		DataJSON: "{}", // keep minimal; responses already stored
-	})
+	return true, tx.Commit()
+}
 
-	return s.GetAttempt(attemptID)
+// GradePending scores up to limit attempts awaiting deferred grading, oldest
+// submission first, and reports how many it graded. Run it periodically.
+func (s *SQLStore) GradePending(ctx context.Context, limit int) (int, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id FROM attempts WHERE grading_progress=$1 ORDER BY submitted_at, id LIMIT $2`,
+		GradingPending, limit)
+	if err != nil {
+		return 0, err
+	}
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	n := 0
+	for _, id := range ids {
+		graded, err := s.gradeIfPending(ctx, id)
+		if err != nil {
+			return n, err
+		}
+		if graded {
+			n++
+		}
+	}
+	return n, nil
+}
+
+// gradeIfPending scores the attempt if it still awaits deferred grading.
+func (s *SQLStore) gradeIfPending(ctx context.Context, attemptID string) (bool, error) {
+	a, err := s.GetAttempt(attemptID)
+	if err != nil || a.GradingProgress != GradingPending {
+		return false, err
+	}
+	return s.scoreAttempt(ctx, a, true)
 }
 
 func (s *SQLStore) GetAttempt(id string) (Attempt, error) {
 	row := s.db.QueryRow(`SELECT id,exam_id,user_id,status,score,responses_json,started_at,submitted_at,
 	  module_index, COALESCE(module_started_at,0), COALESCE(module_deadline,0), COALESCE(overall_deadline,0),
-	  current_index, max_reached_index, current_module_id
+	  current_index, max_reached_index, current_module_id, COALESCE(last_seen_at,0), COALESCE(grading_progress,''),
+	  COALESCE(questions_json,''), COALESCE(draw_seed,0), COALESCE(responses_updated_at,0)
 	  FROM attempts WHERE id=$1`, id)
 
 	var a Attempt
-	var rjson string
+	var rjson, ownQJSON string
 	var moduleStarted, moduleDeadline, overallDeadline int64
 	var curModID sql.NullString
 	if err := row.Scan(&a.ID, &a.ExamID, &a.UserID, &a.Status, &a.Score, &rjson, &a.StartedAt, &a.SubmittedAt,
 		&a.ModuleIndex, &moduleStarted, &moduleDeadline, &overallDeadline,
-		&a.CurrentIndex, &a.MaxReachedIndex, &curModID); err != nil {
+		&a.CurrentIndex, &a.MaxReachedIndex, &curModID, &a.LastSeenAt, &a.GradingProgress,
+		&ownQJSON, &a.DrawSeed, &a.ResponsesUpdatedAt); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return Attempt{}, errors.New("attempt not found")
+			return Attempt{}, ErrAttemptNotFound
 		}
 		return Attempt{}, err
 	}
@@ -498,7 +899,7 @@ func (s *SQLStore) GetAttempt(id string) (Attempt, error) {
 	}
 
 	// remaining seconds (unchanged logic)
-	now := time.Now().Unix()
+	now := s.now().Unix()
 	rem := 0
 	if a.ModuleDeadline > 0 {
 		if d := int(a.ModuleDeadline - now); d > 0 {
@@ -513,26 +914,106 @@ func (s *SQLStore) GetAttempt(id string) (Attempt, error) {
 		}
 	}
 	a.RemainingSeconds = rem
+
+	var qjson, pjson sql.NullString
+	if err := s.db.QueryRow(`SELECT questions_json, policy_json FROM exams WHERE id=$1`, a.ExamID).Scan(&qjson, &pjson); err == nil {
+		if ownQJSON != "" {
+			qjson = sql.NullString{String: ownQJSON, Valid: true}
+		}
+		var qs []Question
+		if qjson.Valid && unmarshalQuestions(qjson.String, &qs) == nil {
+			countProgress(&a, qs)
+		}
+		// review window after submission (policy review_period_sec)
+		if a.Status == "submitted" && a.SubmittedAt > 0 {
+			if sec := reviewPeriodSec(json.RawMessage(pjson.String)); sec > 0 {
+				a.ReviewUntil = a.SubmittedAt + int64(sec)
+				a.ReviewClosed = now > a.ReviewUntil
+			}
+		}
+	}
 	return a, nil
 }
 
+// countProgress fills the attempt's answered/total counts, overall and for
+// its current module (if any).
+func countProgress(a *Attempt, qs []Question) {
+	seen := make(map[string]bool, len(qs))
+	for _, q := range qs {
+		if seen[q.ID] {
+			continue
+		}
+		seen[q.ID] = true
+		answered := isAnswered(a.Responses[q.ID])
+		a.TotalQuestions++
+		if answered {
+			a.AnsweredCount++
+		}
+		if a.CurrentModuleID != "" && q.ModuleID == a.CurrentModuleID {
+			a.ModuleTotalQuestions++
+			if answered {
+				a.ModuleAnsweredCount++
+			}
+		}
+	}
+}
+
+// isAnswered reports whether a response counts as an answer: not null, not
+// a blank string and not an empty list or object.
+func isAnswered(v interface{}) bool {
+	switch r := v.(type) {
+	case nil:
+		return false
+	case string:
+		return strings.TrimSpace(r) != ""
+	case []interface{}:
+		return len(r) > 0
+	case map[string]interface{}:
+		return len(r) > 0
+	}
+	return true
+}
+
 /* ------------------ Multi-module support ------------------ */
 
-func (s *SQLStore) AdvanceModule(attemptID string) (Attempt, error) {
+// AdvanceModule moves the attempt from module fromIndex to the next one. The
+// attempt row is locked (lockAttempt) for the read-decide-write, and an
+// attempt no longer at fromIndex is ErrModuleMoved, so of two concurrent
+// calls from the same module only one advances.
+func (s *SQLStore) AdvanceModule(attemptID string, fromIndex int) (Attempt, error) {
+	ctx := context.Background()
+	// Read before the transaction: the sqlite pool has a single connection,
+	// which the transaction holds until it ends.
+	ex, err := s.GetAttemptExam(ctx, attemptID)
+	if err != nil {
+		return Attempt{}, err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return Attempt{}, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if err := s.lockAttempt(ctx, tx, attemptID); err != nil {
+		return Attempt{}, err
+	}
 	var a Attempt
 	var rjson string
 	var moduleIdx, curIdx int
 	var curModID sql.NullString
-
-	row := s.db.QueryRow(`
+	row := tx.QueryRowContext(ctx, `
 		SELECT exam_id, responses_json, module_index, current_index, current_module_id
 		FROM attempts WHERE id=$1`, attemptID)
 	if err := row.Scan(&a.ExamID, &rjson, &moduleIdx, &curIdx, &curModID); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return Attempt{}, errors.New("attempt not found")
+			return Attempt{}, ErrAttemptNotFound
 		}
 		return Attempt{}, err
 	}
+	if moduleIdx != fromIndex {
+		return Attempt{}, ErrModuleMoved
+	}
 	_ = json.Unmarshal([]byte(rjson), &a.Responses)
 	if curModID.Valid {
 		a.CurrentModuleID = curModID.String
@@ -540,11 +1021,6 @@ func (s *SQLStore) AdvanceModule(attemptID string) (Attempt, error) {
 	a.ModuleIndex = moduleIdx
 	a.CurrentIndex = curIdx
 
-	ex, err := s.GetExamAdmin(context.Background(), a.ExamID)
-	if err != nil {
-		return Attempt{}, err
-	}
-
 	// Load navigation policy
 	nav := parseNavPolicy(ex.PolicyRaw)
 
@@ -584,17 +1060,17 @@ func (s *SQLStore) AdvanceModule(attemptID string) (Attempt, error) {
 	if prevID == "" && moduleIdx >= 0 && moduleIdx < len(modIDs) {
 		prevID = strings.TrimSpace(modIDs[moduleIdx])
 	}
-	perfRaw := s.moduleRawPerf(ex, a, prevID)
+	perf := s.moduleRawPerf(ex, a, prevID)
 
 	// Route to a concrete next module id (variant) if router exists
 	concreteNextID := nextPlaceholderID
 	if r := RouterForProfile(ex.Profile); r != nil {
-		if chosen, _ := r.NextModule(context.Background(), ex, a, Perf{RawPoints: perfRaw}); strings.TrimSpace(chosen) != "" {
+		if chosen, _ := r.NextModule(ctx, ex, a, perf); strings.TrimSpace(chosen) != "" {
 			concreteNextID = strings.TrimSpace(chosen)
 		}
 	}
 
-	now := time.Now().Unix()
+	now := s.now().Unix()
 	nextDur := int64(0)
 	if modules[nextIdx] > 0 {
 		nextDur = int64(modules[nextIdx])
@@ -609,7 +1085,7 @@ func (s *SQLStore) AdvanceModule(attemptID string) (Attempt, error) {
 		}
 	}
 
-	_, err = s.db.Exec(`
+	_, err = tx.ExecContext(ctx, `
 	  UPDATE attempts
 	  SET module_index=$1, module_started_at=$2, module_deadline=$3,
 	      current_index=$4, max_reached_index=$4, current_module_id=$5
@@ -620,12 +1096,28 @@ func (s *SQLStore) AdvanceModule(attemptID string) (Attempt, error) {
 	if err != nil {
 		return Attempt{}, err
 	}
+	if err := tx.Commit(); err != nil {
+		return Attempt{}, err
+	}
 	return s.GetAttempt(attemptID)
 }
 
+// lockAttempt locks the attempt row until tx ends: SELECT ... FOR UPDATE on
+// postgres; on sqlite a no-op write takes the database write lock.
+func (s *SQLStore) lockAttempt(ctx context.Context, tx *sql.Tx, attemptID string) error {
+	q := `UPDATE attempts SET status=status WHERE id=$1`
+	if s.driver == "postgres" {
+		q = `SELECT id FROM attempts WHERE id=$1 FOR UPDATE`
+	}
+	_, err := tx.ExecContext(ctx, q, attemptID)
+	return err
+}
+
 /* ---------------------- Attempt listing ------------------- */
 
-func (s *SQLStore) ListAttempts(ctx context.Context, opts AttemptListOpts) ([]Attempt, error) {
+func (s *SQLStore) ListAttempts(ctx context.Context, opts AttemptListOpts) (_ []Attempt, err error) {
+	ctx, span := startSpan(ctx, "ListAttempts")
+	defer func() { endSpan(span, err) }()
 	if opts.Limit <= 0 {
 		opts.Limit = 50
 	}
@@ -774,14 +1266,30 @@ func parseNavPolicy(policyRaw json.RawMessage) navPolicy {
 }
 
 // Map question -> absolute index, question -> moduleID, and reverse index->qid.
+// orderQuestions returns qs sorted by Order (ties keep their position) and
+// renumbered 0..n-1, so a question's Order is its absolute index. Exams
+// stored before Order existed decode with all zeros and keep their order.
+func orderQuestions(qs []Question) []Question {
+	sort.SliceStable(qs, func(i, j int) bool { return qs[i].Order < qs[j].Order })
+	for i := range qs {
+		qs[i].Order = i
+	}
+	return qs
+}
+
 func buildIndexMaps(questions []Question) (qidToIdx map[string]int, qidToMod map[string]string, idxToQID []string) {
 	qidToIdx = make(map[string]int, len(questions))
 	qidToMod = make(map[string]string, len(questions))
 	idxToQID = make([]string, 0, len(questions))
 	for i, q := range questions {
+		idxToQID = append(idxToQID, q.ID)
+		// PutExam rejects duplicate ids; should one be stored anyway, the
+		// first occurrence wins so indices stay consistent.
+		if _, dup := qidToIdx[q.ID]; dup {
+			continue
+		}
 		qidToIdx[q.ID] = i
 		qidToMod[q.ID] = q.ModuleID
-		idxToQID = append(idxToQID, q.ID)
 	}
 	return
 }
@@ -795,11 +1303,9 @@ type moduleWindow struct {
 }
 
 func moduleWindowFor(ex Exam, moduleID string) moduleWindow {
-	qidToIdx, qidToMod, _ := buildIndexMaps(ex.Questions)
 	win := moduleWindow{indices: map[int]struct{}{}, firstIdx: 0, lastIdx: 0, hasAny: false}
-	for qid, mid := range qidToMod {
-		if mid == moduleID {
-			i := qidToIdx[qid]
+	for i, q := range ex.Questions {
+		if q.ModuleID == moduleID {
 			win.indices[i] = struct{}{}
 			if !win.hasAny {
 				win.firstIdx, win.lastIdx, win.hasAny = i, i, true
@@ -831,7 +1337,7 @@ func (s *SQLStore) Navigate(attemptID string, target int) (Attempt, error) {
 		FROM attempts WHERE id=$1`, attemptID)
 	if err := row.Scan(&examID, &status, &moduleIdx, &curIdx, &maxIdx, &moduleDeadline, &overallDeadline, &curModID); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return Attempt{}, errors.New("attempt not found")
+			return Attempt{}, ErrAttemptNotFound
 		}
 		return Attempt{}, err
 	}
@@ -839,13 +1345,13 @@ func (s *SQLStore) Navigate(attemptID string, target int) (Attempt, error) {
 		return Attempt{}, ErrAttemptSubmitted
 	}
 
-	now := time.Now().Unix()
+	now := s.now().Unix()
 	if (moduleDeadline.Valid && now > moduleDeadline.Int64) || (overallDeadline.Valid && now > overallDeadline.Int64) {
 		return Attempt{}, ErrTimeOver
 	}
 
 	// exam + policy
-	ex, err := s.GetExamAdmin(context.Background(), examID)
+	ex, err := s.GetAttemptExam(context.Background(), attemptID)
 	if err != nil {
 		return Attempt{}, err
 	}
@@ -881,26 +1387,33 @@ func (s *SQLStore) Navigate(attemptID string, target int) (Attempt, error) {
 	return s.GetAttempt(attemptID)
 }
 
-// Compute raw performance for a module (simple correct-count; tweak as needed).
-func (s *SQLStore) moduleRawPerf(ex Exam, a Attempt, moduleID string) float64 {
+// Compute performance on a module: the correct count and the average
+// difficulty of the correct items.
+func (s *SQLStore) moduleRawPerf(ex Exam, a Attempt, moduleID string) Perf {
 	moduleID = strings.TrimSpace(moduleID)
 	if moduleID == "" {
-		return 0
+		return Perf{}
 	}
-	raw := 0.0
+	var perf Perf
+	sumDiff := 0.0
 	for _, q := range ex.Questions {
 		if strings.TrimSpace(q.ModuleID) != moduleID {
 			continue
 		}
 		if resp, ok := a.Responses[q.ID]; ok {
-			res, err := s.grader.Grade(context.Background(),
-				grading.Q{Type: q.Type, Points: 1, AnswerKey: q.AnswerKey}, resp)
+			gq := q.GradingQ()
+			gq.Points = 1
+			res, err := s.grade(context.Background(), gq, resp)
 			if err == nil && res.AutoPoints > 0 {
-				raw += 1
+				perf.RawPoints += 1
+				sumDiff += q.difficulty()
 			}
 		}
 	}
-	return raw
+	if perf.RawPoints > 0 {
+		perf.AvgDifficulty = sumDiff / perf.RawPoints
+	}
+	return perf
 }
 
 // helpers
@@ -921,17 +1434,23 @@ func needsManualForType(t string, q Question) bool {
 	case "essay":
 		return true
 	case "short_word":
-		// treat short_word as manual if no answer_key is provided
-		return len(q.AnswerKey) == 0
+		// treat short_word as manual if no answer_key (or mapping) is provided
+		return len(q.AnswerKey) == 0 && (q.Scoring == nil || len(q.Scoring.Weights) == 0)
 	default:
 		return false
 	}
 }
 
-func (s *SQLStore) GetAttemptItems(ctx context.Context, attemptID string) ([]AttemptItem, error) {
+func (s *SQLStore) GetAttemptItems(ctx context.Context, attemptID string) (_ []AttemptItem, err error) {
+	ctx, span := startSpan(ctx, "GetAttemptItems", attribute.String("attempt.id", attemptID))
+	defer func() { endSpan(span, err) }()
+	// Deferred grading happens at the latest when a teacher looks.
+	if _, err := s.gradeIfPending(ctx, attemptID); err != nil {
+		return nil, err
+	}
 	rows, err := s.db.QueryContext(ctx, `
 		SELECT attempt_id, question_id, q_type, points_max, auto_points, manual_points,
-		       needs_manual, response_json, graded_by, graded_at
+		       needs_manual, response_json, COALESCE(comment,''), graded_by, graded_at
 		FROM attempt_items
 		WHERE attempt_id = $1
 	`, attemptID)
@@ -955,7 +1474,8 @@ func (s *SQLStore) GetAttemptItems(ctx context.Context, attemptID string) ([]Att
 			&it.AutoPoints,
 			&it.ManualPoints,
 			&it.NeedsManual,
-			&respRaw,  // response_json (nullable JSON)
+			&respRaw, // response_json (nullable JSON)
+			&it.Comment,
 			&gradedBy, // nullable TEXT
 			&gradedAt, // nullable BIGINT
 		); err != nil {
@@ -1002,10 +1522,15 @@ func normalizeRawJSON(v any) json.RawMessage {
 	}
 }
 
-func (s *SQLStore) ApplyManualGrades(ctx context.Context, attemptID string, updates map[string]ManualGradeInput, gradedBy string, finalize bool) (Attempt, error) {
+func (s *SQLStore) ApplyManualGrades(ctx context.Context, attemptID string, updates map[string]ManualGradeInput, gradedBy string, finalize bool) (_ Attempt, err error) {
+	ctx, span := startSpan(ctx, "ApplyManualGrades", attribute.String("attempt.id", attemptID))
+	defer func() { endSpan(span, err) }()
 	if len(updates) == 0 {
 		return s.GetAttempt(attemptID)
 	}
+	if _, err := s.gradeIfPending(ctx, attemptID); err != nil {
+		return Attempt{}, err
+	}
 
 	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
@@ -1013,7 +1538,7 @@ func (s *SQLStore) ApplyManualGrades(ctx context.Context, attemptID string, upda
 	}
 	defer func() { _ = tx.Rollback() }()
 
-	now := time.Now().Unix()
+	now := s.now().Unix()
 	for qid, u := range updates {
 		if _, err := tx.ExecContext(ctx, `
 			UPDATE attempt_items
@@ -1049,14 +1574,250 @@ func (s *SQLStore) ApplyManualGrades(ctx context.Context, attemptID string, upda
 		   SET manual_score=$1,
 		       auto_score=$2,
 		       score=$3,
-		       %s=%s
-		 WHERE id=$4`, gradedAtExpr, gradedAtExpr),
+		       graded_at=%s
+		 WHERE id=$4`, gradedAtExpr),
 		manualSum, autoSum, autoSum+manualSum, attemptID); err != nil {
 		return Attempt{}, err
 	}
+	// A regrade of a submitted attempt reports the new score, like Submit.
+	a := Attempt{ID: attemptID}
+	var status string
+	if err := tx.QueryRowContext(ctx, `SELECT exam_id, user_id, status FROM attempts WHERE id=$1`, attemptID).
+		Scan(&a.ExamID, &a.UserID, &status); err != nil {
+		return Attempt{}, err
+	}
+	if status == "submitted" {
+		if err := s.enqueuePassback(ctx, tx, a, autoSum+manualSum, now); err != nil {
+			return Attempt{}, err
+		}
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM exam_stats WHERE exam_id=$1`, a.ExamID); err != nil {
+		return Attempt{}, err
+	}
 
 	if err := tx.Commit(); err != nil {
 		return Attempt{}, err
 	}
 	return s.GetAttempt(attemptID)
 }
+
+// ClaimAttempts moves attempts from an anonymous id to a real user. Callers
+// must have verified the anonymous token; this only rewrites ownership. An
+// attempt stays anonymous if the user has already used its offering's
+// max_attempts; the count is taken under the same per-(exam, user) lock as
+// StartAttempt, with locks taken in exam order.
+func (s *SQLStore) ClaimAttempts(ctx context.Context, anonID, userID string) (_ int64, err error) {
+	ctx, span := startSpan(ctx, "ClaimAttempts")
+	defer func() { endSpan(span, err) }()
+
+	if anonID == "" || userID == "" || anonID == userID {
+		return 0, errors.New("anonymous id and user id required")
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	type claim struct {
+		id, examID, offeringID string
+		maxAttempts            int
+	}
+	rows, err := tx.QueryContext(ctx, `
+		SELECT a.id, a.exam_id, COALESCE(a.offering_id,''), COALESCE(o.max_attempts,0)
+		  FROM attempts a
+		  LEFT JOIN exam_offerings o ON o.id = a.offering_id
+		 WHERE a.user_id=$1
+		 ORDER BY a.exam_id, a.started_at, a.id`, anonID)
+	if err != nil {
+		return 0, err
+	}
+	var claims []claim
+	for rows.Next() {
+		var c claim
+		if err := rows.Scan(&c.id, &c.examID, &c.offeringID, &c.maxAttempts); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		claims = append(claims, c)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	var moved int64
+	for _, c := range claims {
+		if err := s.lockTaker(ctx, tx, c.examID, userID); err != nil {
+			return 0, err
+		}
+		if c.offeringID != "" && c.maxAttempts > 0 {
+			var used int
+			if err := tx.QueryRowContext(ctx,
+				`SELECT COUNT(*) FROM attempts WHERE offering_id=$1 AND user_id=$2`,
+				c.offeringID, userID).Scan(&used); err != nil {
+				return 0, err
+			}
+			if used >= c.maxAttempts {
+				continue
+			}
+		}
+		res, err := tx.ExecContext(ctx,
+			`UPDATE attempts SET user_id=$1 WHERE id=$2 AND user_id=$3`, userID, c.id, anonID)
+		if err != nil {
+			return 0, err
+		}
+		n, _ := res.RowsAffected()
+		moved += n
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return moved, nil
+}
+
+/* ------------------------ Heartbeat ----------------------- */
+
+func (s *SQLStore) Heartbeat(ctx context.Context, attemptID string) (_ Attempt, err error) {
+	ctx, span := startSpan(ctx, "Heartbeat", attribute.String("attempt.id", attemptID))
+	defer func() { endSpan(span, err) }()
+
+	res, err := s.db.ExecContext(ctx,
+		`UPDATE attempts SET last_seen_at=$1 WHERE id=$2 AND status='in_progress'`, s.now().Unix(), attemptID)
+	if err != nil {
+		return Attempt{}, err
+	}
+	a, err := s.GetAttempt(attemptID)
+	if err != nil {
+		return Attempt{}, err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return a, ErrAttemptSubmitted
+	}
+	return a, nil
+}
+
+func (s *SQLStore) ListStaleAttempts(ctx context.Context, examID, teacherID string, staleAfter time.Duration) (_ []Attempt, err error) {
+	ctx, span := startSpan(ctx, "ListStaleAttempts", attribute.String("exam.id", examID))
+	defer func() { endSpan(span, err) }()
+
+	cutoff := s.now().Add(-staleAfter).Unix()
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, exam_id, user_id, status, started_at, COALESCE(last_seen_at,0)
+		  FROM attempts
+		 WHERE status='in_progress'
+		   AND COALESCE(last_seen_at, started_at) < $1
+		   AND ($2 = '' OR exam_id = $2)
+		   AND ($3 = '' OR EXISTS (
+		         SELECT 1 FROM exam_offerings o
+		           JOIN course_teachers t ON t.course_id = o.course_id
+		          WHERE o.id = attempts.offering_id AND t.teacher_id = $3))
+		 ORDER BY COALESCE(last_seen_at, started_at)
+	`, cutoff, examID, teacherID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []Attempt{}
+	for rows.Next() {
+		var a Attempt
+		if err := rows.Scan(&a.ID, &a.ExamID, &a.UserID, &a.Status, &a.StartedAt, &a.LastSeenAt); err != nil {
+			return nil, err
+		}
+		out = append(out, a)
+	}
+	return out, rows.Err()
+}
+
+// ExamStats serves the item analysis from exam_stats. Submit and
+// ApplyManualGrades drop the exam's rows, so an empty cache (or refresh)
+// means recompute from attempt_items and store the result.
+func (s *SQLStore) ExamStats(ctx context.Context, examID string, refresh bool) (_ ExamStats, err error) {
+	ctx, span := startSpan(ctx, "ExamStats", attribute.String("exam.id", examID))
+	defer func() { endSpan(span, err) }()
+
+	if !refresh {
+		st, ok, err := s.readExamStats(ctx, examID)
+		if err != nil || ok {
+			return st, err
+		}
+	}
+	if err := s.rebuildExamStats(ctx, examID); err != nil {
+		return ExamStats{}, err
+	}
+	st, _, err := s.readExamStats(ctx, examID)
+	return st, err
+}
+
+func (s *SQLStore) readExamStats(ctx context.Context, examID string) (ExamStats, bool, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT question_id, count, correct, sum_points, max_points, updated_at
+		  FROM exam_stats
+		 WHERE exam_id=$1
+		 ORDER BY question_id`, examID)
+	if err != nil {
+		return ExamStats{}, false, err
+	}
+	defer rows.Close()
+
+	st := ExamStats{ExamID: examID, Questions: []QuestionStats{}}
+	found := false
+	for rows.Next() {
+		var qid string
+		var cnt, cor, upd int64
+		var sum, max float64
+		if err := rows.Scan(&qid, &cnt, &cor, &sum, &max, &upd); err != nil {
+			return ExamStats{}, false, err
+		}
+		found = true
+		st.UpdatedAt = upd
+		avg := 0.0
+		if cnt > 0 {
+			avg = sum / float64(cnt)
+		}
+		if qid == "*" {
+			st.Attempts, st.AvgScore, st.MaxScore = cnt, avg, max
+			continue
+		}
+		st.Questions = append(st.Questions, QuestionStats{
+			QuestionID: qid, Count: cnt, Correct: cor, AvgPoints: avg, MaxPoints: max,
+		})
+	}
+	return st, found, rows.Err()
+}
+
+// rebuildExamStats replaces the exam's cached rows. The "*" row is always
+// written, so an exam with no submissions still counts as cached. Parameters
+// in the select lists are cast: postgres would otherwise type them as text.
+func (s *SQLStore) rebuildExamStats(ctx context.Context, examID string) error {
+	now := s.now().Unix()
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM exam_stats WHERE exam_id=$1`, examID); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO exam_stats (exam_id, question_id, count, correct, sum_points, max_points, updated_at)
+		SELECT CAST($1 AS TEXT), '*', COUNT(*), 0, COALESCE(SUM(score),0), COALESCE(MAX(score),0), CAST($2 AS BIGINT)
+		  FROM attempts
+		 WHERE exam_id=$1 AND status='submitted'`, examID, now); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO exam_stats (exam_id, question_id, count, correct, sum_points, max_points, updated_at)
+		SELECT CAST($1 AS TEXT), i.question_id, COUNT(*),
+		       SUM(CASE WHEN i.points_max > 0 AND i.auto_points + i.manual_points >= i.points_max THEN 1 ELSE 0 END),
+		       SUM(i.auto_points + i.manual_points), MAX(i.points_max), CAST($2 AS BIGINT)
+		  FROM attempt_items i
+		  JOIN attempts a ON a.id = i.attempt_id
+		 WHERE a.exam_id=$1 AND a.status='submitted'
+		 GROUP BY i.question_id`, examID, now); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
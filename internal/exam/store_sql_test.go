@@ -0,0 +1,879 @@
+package exam_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/mind-engage/mindengage-lms/internal/db"
+	"github.com/mind-engage/mindengage-lms/internal/exam"
+	_ "github.com/mind-engage/mindengage-lms/internal/formats/sat"
+	"github.com/mind-engage/mindengage-lms/internal/grading"
+)
+
+func newSQLiteStore(t *testing.T) *exam.SQLStore {
+	t.Helper()
+	dsn := "file:" + filepath.Join(t.TempDir(), "test.db") + "?_pragma=busy_timeout(5000)"
+	dbh, err := db.Open(context.Background(), db.DriverSQLite, dsn)
+	if err != nil {
+		t.Fatalf("db open: %v", err)
+	}
+	t.Cleanup(func() { _ = dbh.Close() })
+	return exam.NewSQLStore(dbh, string(db.DriverSQLite), grading.NewDefaultGrader())
+}
+
+// newPostgresStore opens a store in a fresh schema of the database at
+// EXAM_TEST_POSTGRES_DSN (a postgres:// URL), skipping the test when unset.
+func newPostgresStore(t *testing.T) *exam.SQLStore {
+	t.Helper()
+	dsn := os.Getenv("EXAM_TEST_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("EXAM_TEST_POSTGRES_DSN not set")
+	}
+	ctx := context.Background()
+	admin, err := db.Open(ctx, db.DriverPostgres, dsn)
+	if err != nil {
+		t.Fatalf("db open: %v", err)
+	}
+	t.Cleanup(func() { _ = admin.Close() })
+	schema := fmt.Sprintf("exam_test_%d", time.Now().UnixNano())
+	if _, err := admin.ExecContext(ctx, "CREATE SCHEMA "+schema); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _, _ = admin.ExecContext(ctx, "DROP SCHEMA "+schema+" CASCADE") })
+
+	sep := "?"
+	if strings.Contains(dsn, "?") {
+		sep = "&"
+	}
+	dbh, err := db.Open(ctx, db.DriverPostgres, dsn+sep+"search_path="+schema)
+	if err != nil {
+		t.Fatalf("db open: %v", err)
+	}
+	t.Cleanup(func() { _ = dbh.Close() })
+	return exam.NewSQLStore(dbh, string(db.DriverPostgres), grading.NewDefaultGrader())
+}
+
+func TestSubmit_EmitsSpans(t *testing.T) {
+	exp := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exp))
+	prev := otel.GetTracerProvider()
+	otel.SetTracerProvider(tp)
+	t.Cleanup(func() { otel.SetTracerProvider(prev) })
+
+	store := newSQLiteStore(t)
+	if err := store.PutExam(exam.Exam{
+		ID:    "ex-1",
+		Title: "Tracing",
+		Questions: []exam.Question{
+			{ID: "q1", Type: "mcq_single", Points: 1, AnswerKey: []string{"a"}},
+			{ID: "q2", Type: "mcq_single", Points: 1, AnswerKey: []string{"b"}},
+		},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Parent span stands in for the HTTP handler's request context.
+	ctx, root := tp.Tracer("test").Start(context.Background(), "POST /attempts/{attemptID}/submit")
+	a, err := store.NewAttempt(ctx, "ex-1", "u-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.SaveResponses(ctx, a.ID, map[string]interface{}{"q1": "a", "q2": "a"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Submit(ctx, a.ID); err != nil {
+		t.Fatal(err)
+	}
+	root.End()
+
+	spans := exp.GetSpans()
+	byName := map[string]tracetest.SpanStub{}
+	counts := map[string]int{}
+	for _, s := range spans {
+		byName[s.Name] = s
+		counts[s.Name]++
+	}
+	for _, name := range []string{"exam.NewAttempt", "exam.SaveResponses", "exam.Submit"} {
+		s, ok := byName[name]
+		if !ok {
+			t.Fatalf("missing span %q (got %v)", name, counts)
+		}
+		if s.Parent.SpanID() != root.SpanContext().SpanID() {
+			t.Errorf("%s is not a child of the request span", name)
+		}
+	}
+	if counts["grading.Grade"] != 2 {
+		t.Fatalf("grading.Grade spans = %d, want 2", counts["grading.Grade"])
+	}
+	if g := byName["grading.Grade"]; g.Parent.SpanID() != byName["exam.Submit"].SpanContext.SpanID() {
+		t.Error("grading.Grade is not a child of exam.Submit")
+	}
+}
+
+func TestHeartbeat_StaleAttempts(t *testing.T) {
+	ctx := context.Background()
+	store := newSQLiteStore(t)
+	clock := time.Now()
+	store.Now = func() time.Time { return clock }
+	if err := store.PutExam(exam.Exam{
+		ID:           "ex-1",
+		Title:        "Proctored",
+		TimeLimitSec: 600,
+		Questions:    []exam.Question{{ID: "q1", Type: "mcq_single", Points: 1, AnswerKey: []string{"a"}}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	start := func(user string) string {
+		a, err := store.NewAttempt(ctx, "ex-1", user)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return a.ID
+	}
+	quiet, live, never, done := start("s1"), start("s2"), start("s3"), start("s4")
+	if _, err := store.Submit(ctx, done); err != nil {
+		t.Fatal(err)
+	}
+	stale := func(after time.Duration) []string {
+		list, err := store.ListStaleAttempts(ctx, "ex-1", "", after)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids := []string{}
+		for _, a := range list {
+			ids = append(ids, a.ID)
+		}
+		sort.Strings(ids)
+		return ids
+	}
+
+	a, err := store.Heartbeat(ctx, quiet)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.LastSeenAt != clock.Unix() || a.RemainingSeconds <= 0 {
+		t.Fatalf("heartbeat: last_seen_at = %d (want %d), remaining = %d", a.LastSeenAt, clock.Unix(), a.RemainingSeconds)
+	}
+	if got, _ := store.GetAttempt(quiet); got.LastSeenAt != clock.Unix() {
+		t.Fatalf("stored last_seen_at = %d, want %d", got.LastSeenAt, clock.Unix())
+	}
+
+	clock = clock.Add(60 * time.Second)
+	if _, err := store.Heartbeat(ctx, live); err != nil {
+		t.Fatal(err)
+	}
+	clock = clock.Add(60 * time.Second)
+
+	want := []string{never, quiet}
+	sort.Strings(want)
+	if got := stale(90 * time.Second); len(got) != 2 || got[0] != want[0] || got[1] != want[1] {
+		t.Fatalf("stale after 90s = %v, want %v (not the live or submitted attempt)", got, want)
+	}
+	if got := stale(5 * time.Minute); len(got) != 0 {
+		t.Fatalf("stale after 5m = %v, want none", got)
+	}
+
+	if _, err := store.Heartbeat(ctx, done); !errors.Is(err, exam.ErrAttemptSubmitted) {
+		t.Fatalf("heartbeat on submitted attempt: err = %v", err)
+	}
+}
+
+func TestModuleExpiry_FakeClock(t *testing.T) {
+	ctx := context.Background()
+	store := newSQLiteStore(t)
+	t0 := time.Unix(1_700_000_000, 0)
+	clock := t0
+	store.Now = func() time.Time { return clock }
+	at := func(sec int) { clock = t0.Add(time.Duration(sec) * time.Second) }
+
+	if err := store.PutExam(exam.Exam{
+		ID:        "ex-mod",
+		Title:     "Two modules",
+		PolicyRaw: []byte(`{"sections":[{"id":"s1","modules":[{"id":"m1","time_limit_sec":60},{"id":"m2","time_limit_sec":120}]}]}`),
+		Questions: []exam.Question{
+			{ID: "q1", Type: "mcq_single", ModuleID: "m1", Points: 1, AnswerKey: []string{"a"}},
+			{ID: "q2", Type: "mcq_single", ModuleID: "m2", Points: 1, AnswerKey: []string{"a"}},
+		},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	a, err := store.NewAttempt(ctx, "ex-mod", "s1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.StartedAt != t0.Unix() {
+		t.Fatalf("started_at = %d, want %d", a.StartedAt, t0.Unix())
+	}
+	save := func(qid string) error {
+		_, err := store.SaveResponses(ctx, a.ID, map[string]interface{}{qid: "a"})
+		return err
+	}
+	remaining := func() int {
+		got, err := store.GetAttempt(a.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return got.RemainingSeconds
+	}
+
+	if got := remaining(); got != 60 {
+		t.Fatalf("remaining at start = %d, want 60", got)
+	}
+	at(60) // the deadline second itself is still in time
+	if err := save("q1"); err != nil {
+		t.Fatalf("save at deadline: %v", err)
+	}
+	at(61)
+	if err := save("q1"); !errors.Is(err, exam.ErrTimeOver) {
+		t.Fatalf("save after module 1 expired: err = %v, want ErrTimeOver", err)
+	}
+
+	// Module 2 gets its own 120s from t0+61, capped by the 180s overall deadline.
+	if _, err := store.AdvanceModule(a.ID, 0); err != nil {
+		t.Fatal(err)
+	}
+	if got := remaining(); got != 119 {
+		t.Fatalf("remaining in module 2 = %d, want 119 (overall deadline)", got)
+	}
+	at(180)
+	if err := save("q2"); err != nil {
+		t.Fatalf("save before overall deadline: %v", err)
+	}
+	at(181)
+	if err := save("q2"); !errors.Is(err, exam.ErrTimeOver) {
+		t.Fatalf("save after overall deadline: err = %v, want ErrTimeOver", err)
+	}
+}
+
+func TestSaveResponses_CompletedModuleLocked(t *testing.T) {
+	ctx := context.Background()
+	store := newSQLiteStore(t)
+	if err := store.PutExam(exam.Exam{
+		ID:    "ex-lock",
+		Title: "Locked modules",
+		PolicyRaw: []byte(`{"navigation":{"allow_back":true,"module_locked":false},
+			"sections":[{"id":"s1","modules":[
+				{"id":"m1","time_limit_sec":600,"variants":[{"id":"m1-alt"}]},
+				{"id":"m2","time_limit_sec":600}]}]}`),
+		Questions: []exam.Question{
+			{ID: "q1", Type: "mcq_single", ModuleID: "m1", Points: 1, AnswerKey: []string{"a"}},
+			{ID: "q1b", Type: "mcq_single", ModuleID: "m1-alt", Points: 1, AnswerKey: []string{"a"}},
+			{ID: "q2", Type: "mcq_single", ModuleID: "m2", Points: 1, AnswerKey: []string{"a"}},
+			{ID: "q3", Type: "mcq_single", ModuleID: "m2", Points: 1, AnswerKey: []string{"a"}},
+		},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	a, err := store.NewAttempt(ctx, "ex-lock", "s1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	save := func(resp map[string]interface{}) error {
+		_, err := store.SaveResponses(ctx, a.ID, resp)
+		return err
+	}
+
+	// Within module 1, answers can be changed freely.
+	for _, v := range []string{"a", "b", "a"} {
+		if err := save(map[string]interface{}{"q1": v}); err != nil {
+			t.Fatalf("edit q1 in module 1: %v", err)
+		}
+	}
+	if _, err := store.AdvanceModule(a.ID, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, qid := range []string{"q1", "q1b"} {
+		if err := save(map[string]interface{}{qid: "b"}); !errors.Is(err, exam.ErrModuleCompleted) {
+			t.Fatalf("edit %s after advance: err = %v, want ErrModuleCompleted", qid, err)
+		}
+	}
+	// A full snapshot repeating the kept answer is not an edit.
+	if err := save(map[string]interface{}{"q1": "a", "q2": "a"}); err != nil {
+		t.Fatalf("snapshot with unchanged q1: %v", err)
+	}
+	// A batch changing the completed module is rejected as a whole.
+	if err := save(map[string]interface{}{"q2": "a", "q1": "b"}); !errors.Is(err, exam.ErrModuleCompleted) {
+		t.Fatalf("mixed batch: err = %v, want ErrModuleCompleted", err)
+	}
+	// allow_back still applies inside the current module.
+	for _, resp := range []map[string]interface{}{{"q3": "a"}, {"q2": "b"}, {"q2": "a"}} {
+		if err := save(resp); err != nil {
+			t.Fatalf("edit %v in module 2: %v", resp, err)
+		}
+	}
+	got, err := store.GetAttempt(a.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Responses["q1"] != "a" {
+		t.Fatalf("q1 = %v, want the module-1 answer kept", got.Responses["q1"])
+	}
+}
+
+func TestExamStats_UpdatedOnSubmit(t *testing.T) {
+	t.Run("sqlite", func(t *testing.T) { testExamStatsUpdatedOnSubmit(t, newSQLiteStore(t)) })
+	t.Run("postgres", func(t *testing.T) { testExamStatsUpdatedOnSubmit(t, newPostgresStore(t)) })
+}
+
+func testExamStatsUpdatedOnSubmit(t *testing.T, store *exam.SQLStore) {
+	ctx := context.Background()
+	if err := store.PutExam(exam.Exam{
+		ID:    "ex-1",
+		Title: "Stats",
+		Questions: []exam.Question{
+			{ID: "q1", Type: "mcq_single", Points: 1, AnswerKey: []string{"a"}},
+		},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	submit := func(user, answer string) {
+		t.Helper()
+		a, err := store.NewAttempt(ctx, "ex-1", user)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := store.SaveResponses(ctx, a.ID, map[string]interface{}{"q1": answer}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := store.Submit(ctx, a.ID); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	submit("u1", "a")
+	st, err := store.ExamStats(ctx, "ex-1", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.Attempts != 1 || len(st.Questions) != 1 || st.Questions[0].Correct != 1 {
+		t.Fatalf("after first submit: %+v", st)
+	}
+
+	// A later submission must not be hidden by the cached rows.
+	submit("u2", "b")
+	st, err = store.ExamStats(ctx, "ex-1", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := st.Questions[0]
+	if st.Attempts != 2 || q.Count != 2 || q.Correct != 1 || q.AvgPoints != 0.5 || st.AvgScore != 0.5 {
+		t.Fatalf("after second submit: %+v", st)
+	}
+
+	again, err := store.ExamStats(ctx, "ex-1", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.Attempts != st.Attempts || again.Questions[0] != q {
+		t.Fatalf("refresh = %+v, want %+v", again, st)
+	}
+}
+
+func TestDeferredGrading_SameScore(t *testing.T) {
+	ctx := context.Background()
+	store, dbh := newOutboxStore(t)
+	if err := store.PutExam(exam.Exam{
+		ID:    "ex-2",
+		Title: "Deferred",
+		Questions: []exam.Question{
+			{ID: "q1", Type: "mcq_single", Points: 1, AnswerKey: []string{"a"}},
+			{ID: "q2", Type: "mcq_single", Points: 3, AnswerKey: []string{"b"}},
+			{ID: "q3", Type: "mcq_single", Points: 5, AnswerKey: []string{"c"}},
+		},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	for _, q := range []string{
+		`INSERT INTO users (id, username, role) VALUES ('t-1','teacher@example.edu','teacher')`,
+		`INSERT INTO courses (id, name, created_by) VALUES ('c-1','Course','t-1')`,
+		`INSERT INTO exam_offerings (id, exam_id, course_id, assigned_by, grading_mode) VALUES ('o-now','ex-2','c-1','t-1','on_submit')`,
+		`INSERT INTO exam_offerings (id, exam_id, course_id, assigned_by, grading_mode) VALUES ('o-later','ex-2','c-1','t-1','deferred')`,
+		`INSERT INTO lti_user_map (platform_issuer, platform_sub, local_user_id) VALUES ('https://lms.example','sub-later','u-o-later')`,
+	} {
+		if _, err := dbh.Exec(q); err != nil {
+			t.Fatal(err)
+		}
+	}
+	take := func(offeringID string) exam.Attempt {
+		t.Helper()
+		a, err := store.NewAttempt(ctx, "ex-2", "u-"+offeringID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := dbh.Exec(`UPDATE attempts SET offering_id=$1 WHERE id=$2`, offeringID, a.ID); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := store.SaveResponses(ctx, a.ID, map[string]interface{}{"q1": "a", "q2": "x", "q3": "c"}); err != nil {
+			t.Fatal(err)
+		}
+		a, err = store.Submit(ctx, a.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return a
+	}
+
+	now := take("o-now")
+	if now.GradingProgress != exam.GradingGraded || now.Score != 6 {
+		t.Fatalf("on_submit: progress %q score %v, want graded 6", now.GradingProgress, now.Score)
+	}
+
+	// Deferred: submitted but unscored, no items, no passback yet.
+	later := take("o-later")
+	if later.Status != "submitted" || later.GradingProgress != exam.GradingPending || later.Score != 0 {
+		t.Fatalf("deferred submit: %+v", later)
+	}
+	if n, _ := outboxRows(t, dbh, later.ID); n != 0 {
+		t.Fatalf("deferred submit enqueued %d passbacks before grading", n)
+	}
+
+	n, err := store.GradePending(ctx, 10)
+	if err != nil || n != 1 {
+		t.Fatalf("GradePending = %d, %v; want 1", n, err)
+	}
+	graded, err := store.GetAttempt(later.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if graded.GradingProgress != exam.GradingGraded || graded.Score != now.Score || graded.SubmittedAt != later.SubmittedAt {
+		t.Fatalf("deferred graded: progress %q score %v submitted_at %d; want graded %v at %d",
+			graded.GradingProgress, graded.Score, graded.SubmittedAt, now.Score, later.SubmittedAt)
+	}
+	if n, _ := outboxRows(t, dbh, later.ID); n != 1 {
+		t.Fatalf("passbacks after grading = %d, want 1", n)
+	}
+	if n, _ := store.GradePending(ctx, 10); n != 0 {
+		t.Fatalf("graded twice (%d)", n)
+	}
+
+	// A teacher opening a pending attempt grades it on the spot.
+	again := take("o-later")
+	items, err := store.GetAttemptItems(ctx, again.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 3 {
+		t.Fatalf("items after teacher read = %d, want 3", len(items))
+	}
+	if a, _ := store.GetAttempt(again.ID); a.GradingProgress != exam.GradingGraded || a.Score != now.Score {
+		t.Fatalf("teacher read: progress %q score %v", a.GradingProgress, a.Score)
+	}
+}
+
+func TestGetExam_QuestionOrder(t *testing.T) {
+	ctx := context.Background()
+	store, dbh := newOutboxStore(t)
+	qs := []exam.Question{
+		{ID: "q3", Type: "mcq_single", Points: 1, ModuleID: "B", Order: 7},
+		{ID: "q1", Type: "mcq_single", Points: 1, ModuleID: "A", Order: 1},
+		{ID: "q2", Type: "mcq_single", Points: 1, ModuleID: "A", Order: 4},
+	}
+	if err := store.PutExam(exam.Exam{ID: "ex-ord", Title: "Order", Questions: qs}); err != nil {
+		t.Fatal(err)
+	}
+	if qs[0].ID != "q3" {
+		t.Fatal("PutExam reordered the caller's slice")
+	}
+	ids := func(qs []exam.Question) (out []string) {
+		for i, q := range qs {
+			if q.Order != i {
+				t.Fatalf("%s has order %d at index %d", q.ID, q.Order, i)
+			}
+			out = append(out, q.ID)
+		}
+		return out
+	}
+	want := "[q1 q2 q3]"
+	for i := 0; i < 5; i++ {
+		e, err := store.GetExam("ex-ord")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := fmt.Sprint(ids(e.Questions)); got != want {
+			t.Fatalf("GetExam order = %s, want %s", got, want)
+		}
+	}
+	admin, err := store.GetExamAdmin(ctx, "ex-ord")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := fmt.Sprint(ids(admin.Questions)); got != want {
+		t.Fatalf("GetExamAdmin order = %s, want %s", got, want)
+	}
+
+	// Exams stored before "order" existed keep their JSON order.
+	if _, err := dbh.Exec(`UPDATE exams SET questions_json=$1 WHERE id='ex-ord'`,
+		`[{"id":"b","type":"essay","points":1},{"id":"a","type":"essay","points":1},{"id":"c","type":"essay","points":1}]`); err != nil {
+		t.Fatal(err)
+	}
+	e, err := store.GetExam("ex-ord")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := fmt.Sprint(ids(e.Questions)); got != "[b a c]" {
+		t.Fatalf("legacy order = %s, want [b a c]", got)
+	}
+}
+
+func TestDuplicateQuestionIDs(t *testing.T) {
+	ctx := context.Background()
+	store, dbh := newOutboxStore(t)
+	dup := []exam.Question{
+		{ID: "q1", Type: "mcq_single", Points: 1, AnswerKey: []string{"a"}, ModuleID: "m1"},
+		{ID: "q2", Type: "mcq_single", Points: 1, AnswerKey: []string{"b"}, ModuleID: "m1"},
+		{ID: "q1", Type: "mcq_single", Points: 4, AnswerKey: []string{"a"}, ModuleID: "m2"},
+	}
+	if err := store.PutExam(exam.Exam{ID: "ex-dup", Title: "Dup", Questions: dup}); !errors.Is(err, exam.ErrDuplicateQuestionID) {
+		t.Fatalf("PutExam err = %v, want ErrDuplicateQuestionID", err)
+	}
+
+	// Slipped in past validation (e.g. an old row): first occurrence wins.
+	if err := store.PutExam(exam.Exam{ID: "ex-dup", Title: "Dup", Questions: dup[:2]}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := dbh.Exec(`UPDATE exams SET questions_json=$1 WHERE id='ex-dup'`,
+		`[{"id":"q1","type":"mcq_single","points":1,"answer_key":["a"],"module_id":"m1"},`+
+			`{"id":"q2","type":"mcq_single","points":1,"answer_key":["b"],"module_id":"m1"},`+
+			`{"id":"q1","type":"mcq_single","points":4,"answer_key":["a"],"module_id":"m2"}]`); err != nil {
+		t.Fatal(err)
+	}
+	a, err := store.NewAttempt(ctx, "ex-dup", "u-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.SaveResponses(ctx, a.ID, map[string]interface{}{"q1": "a", "q2": "b"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Navigate(a.ID, 1); err != nil {
+		t.Fatalf("navigate within the first module: %v", err)
+	}
+	a, err = store.Submit(ctx, a.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.Score != 2 {
+		t.Fatalf("score = %v, want 2 (q1 graded once)", a.Score)
+	}
+	items, err := store.GetAttemptItems(ctx, a.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 2 {
+		t.Fatalf("attempt items = %d, want 2", len(items))
+	}
+}
+
+func TestGetAttempt_Progress(t *testing.T) {
+	ctx := context.Background()
+	store := newSQLiteStore(t)
+	if err := store.PutExam(exam.Exam{
+		ID:        "ex-prog",
+		Title:     "Progress",
+		PolicyRaw: []byte(`{"sections":[{"id":"s1","modules":[{"id":"m1"},{"id":"m2"}]}]}`),
+		Questions: []exam.Question{
+			{ID: "q1", Type: "mcq_single", ModuleID: "m1", Points: 1, AnswerKey: []string{"a"}},
+			{ID: "q2", Type: "mcq_multi", ModuleID: "m1", Points: 1, AnswerKey: []string{"a"}},
+			{ID: "q3", Type: "short_word", ModuleID: "m1", Points: 1, AnswerKey: []string{"x"}},
+			{ID: "q4", Type: "mcq_single", ModuleID: "m2", Points: 1, AnswerKey: []string{"a"}},
+			{ID: "q5", Type: "mcq_single", ModuleID: "m2", Points: 1, AnswerKey: []string{"a"}},
+		},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	a, err := store.NewAttempt(ctx, "ex-prog", "s1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	check := func(answered, total, modAnswered, modTotal int) {
+		t.Helper()
+		got, err := store.GetAttempt(a.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.AnsweredCount != answered || got.TotalQuestions != total ||
+			got.ModuleAnsweredCount != modAnswered || got.ModuleTotalQuestions != modTotal {
+			t.Fatalf("progress = %d/%d, module %d/%d; want %d/%d, module %d/%d",
+				got.AnsweredCount, got.TotalQuestions, got.ModuleAnsweredCount, got.ModuleTotalQuestions,
+				answered, total, modAnswered, modTotal)
+		}
+	}
+
+	check(0, 5, 0, 3)
+	// Empty answers (blank string, empty selection) do not count.
+	if _, err := store.SaveResponses(ctx, a.ID, map[string]interface{}{"q1": "a", "q2": []interface{}{}, "q3": "  "}); err != nil {
+		t.Fatal(err)
+	}
+	check(1, 5, 1, 3)
+	if _, err := store.AdvanceModule(a.ID, 0); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.SaveResponses(ctx, a.ID, map[string]interface{}{"q4": "a"}); err != nil {
+		t.Fatal(err)
+	}
+	check(2, 5, 1, 2)
+}
+
+func TestAdvanceModule_Concurrent(t *testing.T) {
+	ctx := context.Background()
+	store := newSQLiteStore(t)
+	if err := store.PutExam(exam.Exam{
+		ID:        "ex-adv",
+		Title:     "Three modules",
+		PolicyRaw: []byte(`{"sections":[{"id":"s1","modules":[{"id":"m1","time_limit_sec":600},{"id":"m2","time_limit_sec":600},{"id":"m3","time_limit_sec":600}]}]}`),
+		Questions: []exam.Question{
+			{ID: "q1", Type: "mcq_single", ModuleID: "m1", Points: 1, AnswerKey: []string{"a"}},
+			{ID: "q2", Type: "mcq_single", ModuleID: "m2", Points: 1, AnswerKey: []string{"a"}},
+			{ID: "q3", Type: "mcq_single", ModuleID: "m3", Points: 1, AnswerKey: []string{"a"}},
+		},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	a, err := store.NewAttempt(ctx, "ex-adv", "s1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	errs := make([]error, 2)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, errs[i] = store.AdvanceModule(a.ID, 0)
+		}(i)
+	}
+	wg.Wait()
+	// Both leave module 0; a third module exists, so a second advance that
+	// ignored where it started would land in m3.
+	if ok := (errs[0] == nil) != (errs[1] == nil); !ok {
+		t.Fatalf("advance errors = %v, %v; want exactly one success", errs[0], errs[1])
+	}
+	if err := errors.Join(errs...); !errors.Is(err, exam.ErrModuleMoved) {
+		t.Fatalf("losing advance err = %v, want ErrModuleMoved", err)
+	}
+	got, err := store.GetAttempt(a.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.ModuleIndex != 1 || got.CurrentModuleID != "m2" {
+		t.Fatalf("module = %d (%s), want 1 (m2)", got.ModuleIndex, got.CurrentModuleID)
+	}
+}
+
+func TestStartAttempt_Concurrent(t *testing.T) {
+	ctx := context.Background()
+	store := newSQLiteStore(t)
+	if err := store.PutExam(exam.Exam{ID: "ex-start", Title: "Quiz",
+		Questions: []exam.Question{{ID: "q1", Type: "mcq_single", Points: 1, AnswerKey: []string{"a"}}}}); err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	ids := make([]string, 4)
+	errs := make([]error, len(ids))
+	for i := range ids {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			a, _, err := store.StartAttempt(ctx, exam.StartOpts{ExamID: "ex-start", UserID: "s1", Resume: true})
+			ids[i], errs[i] = a.ID, err
+		}(i)
+	}
+	wg.Wait()
+	for i, err := range errs {
+		if err != nil {
+			t.Fatalf("start %d: %v", i, err)
+		}
+		if ids[i] != ids[0] {
+			t.Fatalf("starts returned %v, want one attempt", ids)
+		}
+	}
+	list, err := store.ListAttempts(ctx, exam.AttemptListOpts{ExamID: "ex-start", UserID: "s1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 1 {
+		t.Fatalf("%d attempts created, want 1", len(list))
+	}
+
+	a, created, err := store.StartAttempt(ctx, exam.StartOpts{ExamID: "ex-start", UserID: "s1"})
+	if err != nil || !created || a.ID == ids[0] {
+		t.Fatalf("start without resume = %s created=%v err=%v, want a new attempt", a.ID, created, err)
+	}
+}
+
+func TestSubmit_Concurrent(t *testing.T) {
+	ctx := context.Background()
+	store, dbh := newOutboxStore(t)
+	var mu sync.Mutex
+	var hooked []string
+	store.OnSubmitted = func(_ context.Context, a exam.Attempt) {
+		mu.Lock()
+		defer mu.Unlock()
+		hooked = append(hooked, a.Status)
+	}
+	a, err := store.NewAttempt(ctx, "ex-1", "u-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.SaveResponses(ctx, a.ID, map[string]interface{}{"q1": "a"}); err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	got := make([]exam.Attempt, 2)
+	errs := make([]error, 2)
+	for i := range got {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			got[i], errs[i] = store.Submit(ctx, a.ID)
+		}(i)
+	}
+	wg.Wait()
+	for i := range got {
+		if errs[i] != nil {
+			t.Fatalf("submit %d: %v", i, errs[i])
+		}
+		if got[i].Status != "submitted" || got[i].Score != 2 || got[i].SubmittedAt != got[0].SubmittedAt {
+			t.Fatalf("submit %d = %s score %v at %d; want submitted 2 at %d",
+				i, got[i].Status, got[i].Score, got[i].SubmittedAt, got[0].SubmittedAt)
+		}
+	}
+	if n, _ := outboxRows(t, dbh, a.ID); n != 1 {
+		t.Fatalf("passbacks = %d, want 1 (finalized once)", n)
+	}
+	if _, err := store.Submit(ctx, a.ID); err != nil {
+		t.Fatalf("re-submit: %v", err)
+	}
+	if len(hooked) != 1 || hooked[0] != "submitted" {
+		t.Fatalf("OnSubmitted calls = %v, want one for the finalizing submit", hooked)
+	}
+	var items int
+	if err := dbh.QueryRow(`SELECT COUNT(*) FROM attempt_items WHERE attempt_id=$1`, a.ID).Scan(&items); err != nil {
+		t.Fatal(err)
+	}
+	if items != 1 {
+		t.Fatalf("attempt_items = %d, want 1", items)
+	}
+}
+
+func TestAdvanceModule_RoutesByDifficulty(t *testing.T) {
+	ctx := context.Background()
+	store := newSQLiteStore(t)
+	if err := store.PutExam(exam.Exam{
+		ID:      "ex-adapt",
+		Title:   "Adaptive",
+		Profile: "sat.v1",
+		PolicyRaw: []byte(`{"sections":[{"id":"s1","modules":[
+			{"id":"m1","time_limit_sec":600},
+			{"id":"m2","time_limit_sec":600,
+			 "variants":[{"id":"m2-easy"},{"id":"m2-hard"}],
+			 "route":{"by_score":{"threshold":2,"lte":"m2-easy","gt":"m2-hard","weighted":true}}}]}]}`),
+		Questions: []exam.Question{
+			{ID: "easy1", Type: "mcq_single", ModuleID: "m1", Points: 1, AnswerKey: []string{"a"}, Difficulty: 0.5},
+			{ID: "easy2", Type: "mcq_single", ModuleID: "m1", Points: 1, AnswerKey: []string{"a"}, Difficulty: 0.5},
+			{ID: "hard1", Type: "mcq_single", ModuleID: "m1", Points: 1, AnswerKey: []string{"a"}, Difficulty: 2},
+			{ID: "hard2", Type: "mcq_single", ModuleID: "m1", Points: 1, AnswerKey: []string{"a"}, Difficulty: 2},
+			{ID: "e1", Type: "mcq_single", ModuleID: "m2-easy", Points: 1, AnswerKey: []string{"a"}},
+			{ID: "h1", Type: "mcq_single", ModuleID: "m2-hard", Points: 1, AnswerKey: []string{"a"}},
+		},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Both takers get two right; only where they got them differs.
+	route := func(user string, responses map[string]interface{}) string {
+		t.Helper()
+		a, err := store.NewAttempt(ctx, "ex-adapt", user)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := store.SaveResponses(ctx, a.ID, responses); err != nil {
+			t.Fatal(err)
+		}
+		a, err = store.AdvanceModule(a.ID, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return a.CurrentModuleID
+	}
+	if got := route("s-hard", map[string]interface{}{"easy1": "b", "easy2": "b", "hard1": "a", "hard2": "a"}); got != "m2-hard" {
+		t.Errorf("correct on hard items routed to %q, want m2-hard", got)
+	}
+	if got := route("s-easy", map[string]interface{}{"easy1": "a", "easy2": "a", "hard1": "b", "hard2": "b"}); got != "m2-easy" {
+		t.Errorf("correct on easy items routed to %q, want m2-easy", got)
+	}
+}
+
+func TestLoadExam_UpgradesV1Questions(t *testing.T) {
+	ctx := context.Background()
+	store, dbh := newOutboxStore(t)
+
+	// PutExam stamps the current schema version.
+	var stored string
+	if err := dbh.QueryRow(`SELECT questions_json FROM exams WHERE id='ex-1'`).Scan(&stored); err != nil {
+		t.Fatal(err)
+	}
+	if want := fmt.Sprintf(`{"schema_version":%d,`, exam.QuestionsSchemaVersion); !strings.HasPrefix(stored, want) {
+		t.Fatalf("stored questions_json = %s, want it to start with %s", stored, want)
+	}
+
+	// A version 1 row: a bare array, loosely spelled, without tags.
+	if _, err := dbh.Exec(`UPDATE exams SET questions_json=$1 WHERE id='ex-1'`,
+		`[{"id":" q1 ","type":" MCQ_Single ","points":2,"answer_key":["a"],"module_id":"m1 "},{"id":"q2","type":"essay","points":5}]`); err != nil {
+		t.Fatal(err)
+	}
+	admin, err := store.GetExamAdmin(ctx, "ex-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	e, err := store.GetExam("ex-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, got := range [][]exam.Question{admin.Questions, e.Questions} {
+		if len(got) != 2 {
+			t.Fatalf("questions = %+v", got)
+		}
+		q1, q2 := got[0], got[1]
+		if q1.ID != "q1" || q1.Type != "mcq_single" || q1.ModuleID != "m1" || q1.Order != 0 || q2.Order != 1 {
+			t.Errorf("q1 = %+v, q2 order %d; want trimmed, lower-case and ordered", q1, q2.Order)
+		}
+		if q1.Tags == nil || q2.Tags == nil {
+			t.Errorf("tags not defaulted: %#v, %#v", q1.Tags, q2.Tags)
+		}
+	}
+	if admin.Questions[1].AnswerKey == nil {
+		t.Error("answer key not defaulted for the essay")
+	}
+
+	// The upgraded exam still grades.
+	a, err := store.NewAttempt(ctx, "ex-1", "u-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.SaveResponses(ctx, a.ID, map[string]interface{}{"q1": "a"}); err != nil {
+		t.Fatal(err)
+	}
+	sub, err := store.Submit(ctx, a.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sub.Score != 2 {
+		t.Fatalf("score = %v, want 2", sub.Score)
+	}
+}
@@ -0,0 +1,79 @@
+package exam
+
+import (
+	"sort"
+	"strings"
+)
+
+// TagStats is item analysis summed over the questions carrying one tag.
+type TagStats struct {
+	Tag       string  `json:"tag"`
+	Questions int     `json:"questions"`
+	Count     int64   `json:"count"`      // graded responses over those questions
+	Correct   int64   `json:"correct"`    // of which full credit
+	AvgPoints float64 `json:"avg_points"` // per response
+}
+
+// QuestionsWithTag returns the questions carrying any of tags, in exam
+// order. No tags returns every question.
+func QuestionsWithTag(qs []Question, tags ...string) []Question {
+	out := make([]Question, 0, len(qs))
+	for _, q := range qs {
+		if len(tags) == 0 || hasTag(q.Tags, tags) {
+			out = append(out, q)
+		}
+	}
+	return out
+}
+
+// StatsByTag groups st's per-question rows by the tags of qs (lower-cased,
+// sorted). A question with several tags counts towards each; untagged ones
+// towards none.
+func StatsByTag(st ExamStats, qs []Question) []TagStats {
+	byQID := make(map[string]QuestionStats, len(st.Questions))
+	for _, s := range st.Questions {
+		byQID[s.QuestionID] = s
+	}
+	groups := map[string]*TagStats{}
+	sums := map[string]float64{}
+	seen := map[string]bool{}
+	for _, q := range qs {
+		if seen[q.ID] {
+			continue
+		}
+		seen[q.ID] = true
+		s := byQID[q.ID]
+		for _, tag := range uniqueTags(q.Tags) {
+			g := groups[tag]
+			if g == nil {
+				g = &TagStats{Tag: tag}
+				groups[tag] = g
+			}
+			g.Questions++
+			g.Count += s.Count
+			g.Correct += s.Correct
+			sums[tag] += s.AvgPoints * float64(s.Count)
+		}
+	}
+	out := make([]TagStats, 0, len(groups))
+	for tag, g := range groups {
+		if g.Count > 0 {
+			g.AvgPoints = sums[tag] / float64(g.Count)
+		}
+		out = append(out, *g)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Tag < out[j].Tag })
+	return out
+}
+
+// uniqueTags normalizes tags (trimmed, lower-cased) and drops repeats.
+func uniqueTags(tags []string) []string {
+	out := make([]string, 0, len(tags))
+	for _, t := range tags {
+		t = strings.ToLower(strings.TrimSpace(t))
+		if t != "" && !hasTag(out, []string{t}) {
+			out = append(out, t)
+		}
+	}
+	return out
+}
@@ -0,0 +1,43 @@
+// internal/exam/tracing.go
+package exam
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/mind-engage/mindengage-lms/internal/grading"
+)
+
+/*
+Tracing: SQLStore operations and grader calls open OpenTelemetry spans named
+"exam.<Op>" / "grading.Grade". The tracer comes from the global provider, which
+is a no-op until the binary calls otel.SetTracerProvider, so there is no cost
+when tracing is not configured.
+*/
+
+var tracer = otel.Tracer("github.com/mind-engage/mindengage-lms/internal/exam")
+
+// startSpan opens a child span of ctx for a store operation.
+func startSpan(ctx context.Context, op string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return tracer.Start(ctx, "exam."+op, trace.WithAttributes(attrs...))
+}
+
+// endSpan records err (if any) and ends the span.
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// grade wraps s.grader.Grade in a span.
+func (s *SQLStore) grade(ctx context.Context, q grading.Q, resp interface{}) (res grading.Result, err error) {
+	ctx, span := tracer.Start(ctx, "grading.Grade", trace.WithAttributes(attribute.String("question.type", q.Type)))
+	defer func() { endSpan(span, err) }()
+	return s.grader.Grade(ctx, q, resp)
+}
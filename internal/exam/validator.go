@@ -1 +1,51 @@
 package exam
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	ErrDuplicateQuestionID = errors.New("duplicate question id")
+	ErrTooManyQuestions    = errors.New("too many questions")
+	ErrTooManyChoices      = errors.New("too many choices")
+)
+
+// ValidateQuestions checks that every question has an id and that ids are
+// unique: responses, attempt items and module windows are all keyed by it.
+func ValidateQuestions(qs []Question) error {
+	seen := make(map[string]int, len(qs))
+	for i, q := range qs {
+		id := strings.TrimSpace(q.ID)
+		if id == "" {
+			return fmt.Errorf("question %d: id required", i+1)
+		}
+		if j, dup := seen[id]; dup {
+			return fmt.Errorf("%w %q (questions %d and %d)", ErrDuplicateQuestionID, id, j+1, i+1)
+		}
+		seen[id] = i
+	}
+	return nil
+}
+
+// Limits caps the size of an uploaded exam; a zero field is no cap.
+type Limits struct {
+	MaxQuestions int // questions per exam
+	MaxChoices   int // choices per question
+}
+
+// Check reports the first cap qs exceeds, naming the limit.
+func (l Limits) Check(qs []Question) error {
+	if l.MaxQuestions > 0 && len(qs) > l.MaxQuestions {
+		return fmt.Errorf("%w: %d, limit %d", ErrTooManyQuestions, len(qs), l.MaxQuestions)
+	}
+	if l.MaxChoices > 0 {
+		for _, q := range qs {
+			if len(q.Choices) > l.MaxChoices {
+				return fmt.Errorf("%w in question %q: %d, limit %d", ErrTooManyChoices, q.ID, len(q.Choices), l.MaxChoices)
+			}
+		}
+	}
+	return nil
+}
@@ -7,13 +7,23 @@ import (
 
 // Policy holds timing, navigation, scoring rules, etc., independent of the item content.
 type Policy struct {
-	Sections    []Section      `json:"sections,omitempty"`
-	Navigation  Navigation     `json:"navigation,omitempty"`
-	Calculator  Calculator     `json:"calculator,omitempty"`
-	Scoring     Scoring        `json:"scoring,omitempty"`
-	Constraints Constraints    `json:"item_constraints,omitempty"`
-	Proctor     Proctor        `json:"proctor,omitempty"`
-	Meta        map[string]any `json:"meta,omitempty"` // free-form e.g. versioning, locale
+	Sections    []Section   `json:"sections,omitempty"`
+	Navigation  Navigation  `json:"navigation,omitempty"`
+	Calculator  Calculator  `json:"calculator,omitempty"`
+	Scoring     Scoring     `json:"scoring,omitempty"`
+	Constraints Constraints `json:"item_constraints,omitempty"`
+	Proctor     Proctor     `json:"proctor,omitempty"`
+	// ReviewPeriodSec: after submission, how long the taker may still see
+	// their answers (0 = no limit).
+	ReviewPeriodSec int `json:"review_period_sec,omitempty"`
+	// AllowRestart lets a taker abandon an in-progress attempt and start a
+	// new one (POST /attempts?restart=1); by default they resume it.
+	AllowRestart bool     `json:"allow_restart,omitempty"`
+	Feedback     Feedback `json:"feedback,omitempty"`
+	// Blueprint draws each attempt's questions from the question bank, in
+	// addition to the exam's own.
+	Blueprint []BlueprintRule `json:"blueprint,omitempty"`
+	Meta      map[string]any  `json:"meta,omitempty"` // free-form e.g. versioning, locale
 }
 
 type Section struct {
@@ -32,6 +42,26 @@ type Navigation struct {
 	ModuleLocked bool `json:"module_locked,omitempty"`
 }
 
+// Feedback gates, by question tag, which results reveal grading feedback
+// and the correct answer. HideTags win over RevealTags; empty reveals all.
+// ShowAnswers ("partial", "always"; empty means never) is how much of the
+// key a taker may request from ephemeral grading, e.g. "always" for practice.
+type Feedback struct {
+	RevealTags  []string `json:"reveal_tags,omitempty"`
+	HideTags    []string `json:"hide_tags,omitempty"`
+	ShowAnswers string   `json:"show_answers,omitempty"`
+}
+
+// BlueprintRule asks for Count bank questions with Tag (and Type, if set),
+// placed in ModuleID and worth Points (0 keeps the bank's).
+type BlueprintRule struct {
+	Tag      string  `json:"tag"`
+	Type     string  `json:"type,omitempty"`
+	Count    int     `json:"count"`
+	ModuleID string  `json:"module_id,omitempty"`
+	Points   float64 `json:"points,omitempty"`
+}
+
 type Calculator struct {
 	AllowedSections []string `json:"allowed_sections,omitempty"`
 	Policy          string   `json:"policy,omitempty"` // e.g., "desmos", "basic", "none"
@@ -82,6 +112,19 @@ func ValidatePolicy(profile string, pol *Policy) error {
 			}
 		}
 	}
+	for i, r := range pol.Blueprint {
+		if r.Count <= 0 {
+			return fmt.Errorf("blueprint[%d]: count must be positive", i)
+		}
+	}
+	switch pol.Feedback.ShowAnswers {
+	case "", "never", "partial", "always":
+	default:
+		return fmt.Errorf("feedback.show_answers: unknown value %q", pol.Feedback.ShowAnswers)
+	}
+	if pol.ReviewPeriodSec < 0 {
+		return errors.New("negative review_period_sec")
+	}
 	// Additional profile-specific checks are enforced by Adapter.Validate.
 	return nil
 }
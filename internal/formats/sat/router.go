@@ -15,6 +15,9 @@ import (
 //
 //	"variants": [{ "id": "rw-m2-easy" }, { "id": "rw-m2-hard" }],
 //	"route": { "by_score": { "threshold": 18, "lte": "rw-m2-easy", "gt": "rw-m2-hard" } }
+//
+// With "weighted": true in by_score, the threshold applies to the
+// difficulty-weighted score (exam.Perf.Weighted) instead of the raw count.
 type Router struct{}
 
 func NewRouter() *Router { return &Router{} }
@@ -107,6 +110,7 @@ type byScore struct {
 	GT        string  `json:"gt,omitempty"`
 	GTE       string  `json:"gte,omitempty"`
 	Default   string  `json:"default,omitempty"`
+	Weighted  bool    `json:"weighted,omitempty"`
 }
 
 /* ----------------------------- Helpers ----------------------------- */
@@ -132,7 +136,11 @@ func hasVariant(pm placeholderMod, id string) bool {
 func chooseVariant(pm placeholderMod, perf exam.Perf) string {
 	r := pm.Route
 	if r.ByScore != nil {
-		return chooseByScore(*r.ByScore, perf.RawPoints)
+		score := perf.RawPoints
+		if r.ByScore.Weighted {
+			score = perf.Weighted()
+		}
+		return chooseByScore(*r.ByScore, score)
 	}
 	return ""
 }
@@ -20,8 +20,20 @@ type Q struct {
 	Type      string
 	Points    float64
 	AnswerKey []string
+
+	// Optional override of the type's default scoring (e.g. from QTI response
+	// processing). ScoringExact disables partial/fuzzy credit; ScoringMap
+	// awards Weights[value] per response value, clamped to [0, Points].
+	Scoring       string
+	Weights       map[string]float64
+	DefaultWeight float64 // for response values missing from Weights
 }
 
+const (
+	ScoringExact = "exact"
+	ScoringMap   = "map"
+)
+
 // Result is the outcome of grading a single question response.
 type Result struct {
 	AutoPoints  float64  // points awarded automatically
@@ -45,6 +57,14 @@ type defaultGrader struct {
 }
 
 func (g *defaultGrader) Grade(ctx context.Context, q Q, response interface{}) (Result, error) {
+	if q.Type != "essay" && q.Type != "scan" {
+		switch {
+		case q.Scoring == ScoringMap && len(q.Weights) > 0:
+			return mapResponseStrategy{}.Grade(ctx, q, response)
+		case q.Scoring == ScoringExact:
+			return exactStrategy{}.Grade(ctx, q, response)
+		}
+	}
 	s, ok := g.strategies[q.Type]
 	if !ok {
 		return Result{MaxPoints: q.Points, NeedsManual: true, Feedback: []string{"no strategy available"}}, nil
@@ -171,6 +191,63 @@ func (s shortWordStrategy) Grade(_ context.Context, q Q, response interface{}) (
 	return res, nil
 }
 
+// exactStrategy is all-or-nothing: the response (or response set) must match
+// an answer key exactly, ignoring surrounding whitespace.
+type exactStrategy struct{}
+
+func (exactStrategy) Grade(_ context.Context, q Q, response interface{}) (Result, error) {
+	res := Result{MaxPoints: q.Points}
+	if resp, ok := response.(string); ok {
+		for _, k := range q.AnswerKey {
+			if strings.TrimSpace(resp) == strings.TrimSpace(k) {
+				res.AutoPoints = q.Points
+				break
+			}
+		}
+		return res, nil
+	}
+	respSlice, ok := toStringSlice(response)
+	if !ok {
+		return res, errors.New("response must be string or []string")
+	}
+	if len(q.AnswerKey) > 0 && setEqual(toSet(q.AnswerKey), toSet(respSlice)) {
+		res.AutoPoints = q.Points
+	}
+	return res, nil
+}
+
+// mapResponseStrategy sums the weight of each distinct response value
+// (QTI map_response), clamped to [0, Points].
+type mapResponseStrategy struct{}
+
+func (mapResponseStrategy) Grade(_ context.Context, q Q, response interface{}) (Result, error) {
+	res := Result{MaxPoints: q.Points}
+	var values []string
+	if resp, ok := response.(string); ok {
+		values = []string{resp}
+	} else if respSlice, ok := toStringSlice(response); ok {
+		values = respSlice
+	} else {
+		return res, errors.New("response must be string or []string")
+	}
+	sum := 0.0
+	for v := range toSet(values) {
+		if w, ok := q.Weights[strings.TrimSpace(v)]; ok {
+			sum += w
+		} else {
+			sum += q.DefaultWeight
+		}
+	}
+	if sum < 0 {
+		sum = 0
+	}
+	if sum > q.Points {
+		sum = q.Points
+	}
+	res.AutoPoints = sum
+	return res, nil
+}
+
 type essayStrategy struct{}
 
 func (essayStrategy) Grade(_ context.Context, q Q, response interface{}) (Result, error) {
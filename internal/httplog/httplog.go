@@ -0,0 +1,136 @@
+// internal/httplog/httplog.go
+package httplog
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+
+	"github.com/mind-engage/mindengage-lms/internal/rbac"
+)
+
+/*
+Package httplog emits one structured (slog) line per HTTP request:
+
+	request_id, method, route, path, status, bytes, latency_ms,
+	tenant (if resolved), sub + role (if authenticated)
+
+The access-log middleware runs outermost, but auth runs inside route groups
+and attaches sub/role to a *derived* context the outer middleware never sees.
+So Middleware stashes a mutable entry on the context, and CaptureAuth (mounted
+after the auth middlewares) copies sub/role into it.
+
+Wiring:
+
+	r.Use(middleware.RequestID)
+	r.Use(httplog.Middleware(logger, httplog.Options{}))
+	...
+	pr.Use(authmw.JWTMiddleware(authSvc), authmw.AttachRoleFromDB(dbh, ...))
+	pr.Use(httplog.CaptureAuth)
+*/
+
+// Options tunes Middleware.
+type Options struct {
+	// Optional: resolves the tenant for the request (omitted from logs if nil/empty).
+	Tenant func(*http.Request) string
+	// Optional: skip logging for some requests (e.g., health checks).
+	Skip func(*http.Request) bool
+}
+
+type ctxKey struct{}
+
+// entry collects request-scoped fields set by inner middlewares.
+type entry struct {
+	mu    sync.Mutex
+	attrs []slog.Attr
+}
+
+// Middleware logs each request with logger (slog.Default() if nil).
+func Middleware(logger *slog.Logger, opts Options) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if opts.Skip != nil && opts.Skip(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			start := time.Now()
+			e := &entry{}
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			r = r.WithContext(context.WithValue(r.Context(), ctxKey{}, e))
+
+			next.ServeHTTP(ww, r)
+
+			status := ww.Status()
+			if status == 0 {
+				status = http.StatusOK
+			}
+			attrs := []slog.Attr{
+				slog.String("request_id", middleware.GetReqID(r.Context())),
+				slog.String("method", r.Method),
+				slog.String("route", routePattern(r)),
+				slog.String("path", r.URL.Path),
+				slog.Int("status", status),
+				slog.Int("bytes", ww.BytesWritten()),
+				slog.Float64("latency_ms", float64(time.Since(start).Microseconds())/1000),
+			}
+			if opts.Tenant != nil {
+				if t := opts.Tenant(r); t != "" {
+					attrs = append(attrs, slog.String("tenant", t))
+				}
+			}
+			e.mu.Lock()
+			attrs = append(attrs, e.attrs...)
+			e.mu.Unlock()
+
+			level := slog.LevelInfo
+			if status >= 500 {
+				level = slog.LevelError
+			}
+			lg := logger
+			if lg == nil {
+				lg = slog.Default()
+			}
+			lg.LogAttrs(r.Context(), level, "http request", attrs...)
+		})
+	}
+}
+
+// CaptureAuth copies the authenticated sub/role (see rbac.WithSubject/WithRole)
+// into the request's log entry. Mount it after the auth middlewares.
+func CaptureAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		if sub := rbac.SubjectFromContext(ctx); sub != "" {
+			Annotate(ctx, slog.String("sub", sub))
+		}
+		if role := rbac.RoleFromContext(ctx); role != "" {
+			Annotate(ctx, slog.String("role", role))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Annotate adds fields to the current request's log line. No-op outside Middleware.
+func Annotate(ctx context.Context, attrs ...slog.Attr) {
+	e, _ := ctx.Value(ctxKey{}).(*entry)
+	if e == nil {
+		return
+	}
+	e.mu.Lock()
+	e.attrs = append(e.attrs, attrs...)
+	e.mu.Unlock()
+}
+
+// routePattern returns the matched chi route (e.g. /api/attempts/{attemptID}),
+// which keeps log cardinality low. Empty if no route matched.
+func routePattern(r *http.Request) string {
+	if rc := chi.RouteContext(r.Context()); rc != nil {
+		return rc.RoutePattern()
+	}
+	return ""
+}
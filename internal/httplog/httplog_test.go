@@ -0,0 +1,96 @@
+package httplog_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+
+	"github.com/mind-engage/mindengage-lms/internal/httplog"
+	"github.com/mind-engage/mindengage-lms/internal/rbac"
+)
+
+// fakeAuth stands in for JWTMiddleware: it sets sub/role on a derived context.
+func fakeAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := rbac.WithSubject(r.Context(), "u-42")
+		ctx = rbac.WithRole(ctx, "teacher")
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func TestMiddleware_LogsRequestFields(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+
+	r := chi.NewRouter()
+	r.Use(middleware.RequestID)
+	r.Use(httplog.Middleware(logger, httplog.Options{
+		Tenant: func(r *http.Request) string { return r.Header.Get("X-Tenant-ID") },
+	}))
+	r.Group(func(pr chi.Router) {
+		pr.Use(fakeAuth, httplog.CaptureAuth)
+		pr.Post("/attempts/{attemptID}/submit", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte("ok"))
+		})
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/attempts/a-1/submit", nil)
+	req.Header.Set("X-Request-Id", "req-1")
+	req.Header.Set("X-Tenant-ID", "school-a")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	var got map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("log line is not JSON: %v (%q)", err, buf.String())
+	}
+	want := map[string]any{
+		"msg":        "http request",
+		"request_id": "req-1",
+		"method":     "POST",
+		"route":      "/attempts/{attemptID}/submit",
+		"path":       "/attempts/a-1/submit",
+		"status":     float64(201),
+		"bytes":      float64(2),
+		"tenant":     "school-a",
+		"sub":        "u-42",
+		"role":       "teacher",
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("%s = %#v, want %#v", k, got[k], v)
+		}
+	}
+	if _, ok := got["latency_ms"]; !ok {
+		t.Error("latency_ms missing")
+	}
+}
+
+func TestMiddleware_AnonymousOmitsUser(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+
+	h := httplog.Middleware(logger, httplog.Options{})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "boom", http.StatusInternalServerError)
+	}))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/x", nil))
+
+	var got map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got["level"] != "ERROR" || got["status"] != float64(500) {
+		t.Fatalf("level/status = %v/%v", got["level"], got["status"])
+	}
+	for _, k := range []string{"sub", "role", "tenant"} {
+		if _, ok := got[k]; ok {
+			t.Errorf("unexpected field %q", k)
+		}
+	}
+}
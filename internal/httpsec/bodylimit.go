@@ -0,0 +1,111 @@
+// internal/httpsec/bodylimit.go
+package httpsec
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+)
+
+// DefaultMaxJSONBody is the request body cap MaxJSONBody applies when given 0.
+const DefaultMaxJSONBody = 10 << 20
+
+// MaxJSONBody rejects request bodies larger than limit bytes with 413 before
+// the handler sees them, so handlers can keep decoding r.Body directly.
+// Bodies without a declared length are read (up to limit+1 bytes) and
+// replayed. multipart/form-data uploads are exempt: the upload handlers
+// stream those to disk and size them on their own.
+func MaxJSONBody(limit int64) func(http.Handler) http.Handler {
+	if limit <= 0 {
+		limit = DefaultMaxJSONBody
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Body == nil || r.Body == http.NoBody || isMultipart(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if r.ContentLength > limit {
+				tooLarge(w)
+				return
+			}
+			if r.ContentLength < 0 {
+				buf, err := io.ReadAll(io.LimitReader(r.Body, limit+1))
+				_ = r.Body.Close()
+				if err != nil {
+					http.Error(w, "read body: "+err.Error(), http.StatusBadRequest)
+					return
+				}
+				if int64(len(buf)) > limit {
+					tooLarge(w)
+					return
+				}
+				r.Body = io.NopCloser(bytes.NewReader(buf))
+				r.ContentLength = int64(len(buf))
+				next.ServeHTTP(w, r)
+				return
+			}
+			// Declared length is within the limit; hold the client to it. A
+			// handler that fails on the cut-off body usually answers 400;
+			// report that as 413.
+			lb := &limitedBody{ReadCloser: http.MaxBytesReader(w, r.Body, limit)}
+			r.Body = lb
+			next.ServeHTTP(&limitWriter{ResponseWriter: w, body: lb}, r)
+		})
+	}
+}
+
+// limitedBody notes when MaxBytesReader cut the body off.
+type limitedBody struct {
+	io.ReadCloser
+	tripped bool
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	var mbe *http.MaxBytesError
+	if errors.As(err, &mbe) {
+		b.tripped = true
+	}
+	return n, err
+}
+
+// limitWriter turns a handler's 400 into 413 once the body limit tripped.
+type limitWriter struct {
+	http.ResponseWriter
+	body  *limitedBody
+	wrote bool
+}
+
+func (l *limitWriter) WriteHeader(code int) {
+	if !l.wrote {
+		l.wrote = true
+		if l.body.tripped && code == http.StatusBadRequest {
+			l.Header().Set("Connection", "close")
+			code = http.StatusRequestEntityTooLarge
+		}
+	}
+	l.ResponseWriter.WriteHeader(code)
+}
+
+func (l *limitWriter) Write(b []byte) (int, error) {
+	if !l.wrote {
+		l.WriteHeader(http.StatusOK)
+	}
+	return l.ResponseWriter.Write(b)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (l *limitWriter) Unwrap() http.ResponseWriter { return l.ResponseWriter }
+
+func isMultipart(r *http.Request) bool {
+	mt, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	return mt == "multipart/form-data"
+}
+
+func tooLarge(w http.ResponseWriter) {
+	w.Header().Set("Connection", "close")
+	http.Error(w, "request body too large", http.StatusRequestEntityTooLarge)
+}
@@ -0,0 +1,43 @@
+package httpsec_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mind-engage/mindengage-lms/internal/httpsec"
+)
+
+func TestMaxJSONBody_MaxBytesErrorIs413(t *testing.T) {
+	// A handler that answers every decode error with 400, like most do.
+	h := httpsec.MaxJSONBody(16)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var v map[string]string
+		if err := json.NewDecoder(r.Body).Decode(&v); err != nil {
+			http.Error(w, "bad json", http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	do := func(body string, declared int64) int {
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.ContentLength = declared
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	// Declared length under the limit, body over it: MaxBytesReader trips.
+	if code := do(`{"k":"`+strings.Repeat("x", 64)+`"}`, 8); code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("over-long body status = %d, want 413", code)
+	}
+	// Plain bad JSON inside the limit stays 400.
+	if code := do(`{"k":`, 5); code != http.StatusBadRequest {
+		t.Fatalf("bad json status = %d, want 400", code)
+	}
+	if code := do(`{"k":"v"}`, 9); code != http.StatusNoContent {
+		t.Fatalf("ok status = %d, want 204", code)
+	}
+}
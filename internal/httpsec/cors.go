@@ -0,0 +1,79 @@
+// internal/httpsec/cors.go
+package httpsec
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/go-chi/cors"
+)
+
+/*
+Package httpsec holds the gateway's browser-facing security middleware.
+
+CORS is chosen per route group by path prefix, so anonymous endpoints can be
+called from any origin while authenticated and admin APIs stay locked to the
+configured front-ends:
+
+	r.Use(httpsec.CORS(httpsec.CORSPolicy{Origins: cfg.CORSOrigins(), AllowCredentials: true},
+		httpsec.CORSGroup{Prefix: "/api/public", Policy: httpsec.CORSPolicy{Origins: []string{"*"}}},
+		httpsec.CORSGroup{Prefix: "/api/admin", Policy: httpsec.CORSPolicy{Origins: cfg.CORSOriginsAdmin, AllowCredentials: true}},
+	))
+
+It must run before routing (r.Use on the root router): a preflight has to be
+answered even where no OPTIONS route exists.
+*/
+
+// CORSPolicy is one group's CORS settings.
+type CORSPolicy struct {
+	Origins          []string // "*" allows any origin (only without credentials)
+	AllowCredentials bool
+	Headers          []string // allowed in addition to Authorization and Content-Type
+}
+
+// CORSGroup applies Policy to paths under Prefix.
+type CORSGroup struct {
+	Prefix string
+	Policy CORSPolicy
+}
+
+// CORS applies the policy of the longest matching group prefix, or def.
+func CORS(def CORSPolicy, groups ...CORSGroup) func(http.Handler) http.Handler {
+	groups = append([]CORSGroup(nil), groups...)
+	sort.SliceStable(groups, func(i, j int) bool { return len(groups[i].Prefix) > len(groups[j].Prefix) })
+
+	return func(next http.Handler) http.Handler {
+		defH := def.handler()(next)
+		hs := make([]http.Handler, len(groups))
+		for i, g := range groups {
+			hs[i] = g.Policy.handler()(next)
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			for i, g := range groups {
+				if pathHasPrefix(r.URL.Path, g.Prefix) {
+					hs[i].ServeHTTP(w, r)
+					return
+				}
+			}
+			defH.ServeHTTP(w, r)
+		})
+	}
+}
+
+func (p CORSPolicy) handler() func(http.Handler) http.Handler {
+	return cors.Handler(cors.Options{
+		AllowedOrigins:   p.Origins,
+		AllowedMethods:   []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+		AllowedHeaders:   append([]string{"Authorization", "Content-Type"}, p.Headers...),
+		ExposedHeaders:   []string{"Content-Length", "X-Error-Code"}, // i18n error codes
+		AllowCredentials: p.AllowCredentials,
+		MaxAge:           300,
+	})
+}
+
+// "/api/admin" matches /api/admin and /api/admin/..., not /api/administer.
+func pathHasPrefix(path, prefix string) bool {
+	prefix = strings.TrimSuffix(prefix, "/")
+	return path == prefix || strings.HasPrefix(path, prefix+"/")
+}
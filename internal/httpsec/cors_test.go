@@ -0,0 +1,71 @@
+package httpsec_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mind-engage/mindengage-lms/internal/httpsec"
+)
+
+func preflight(h http.Handler, path, origin string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodOptions, path, nil)
+	req.Header.Set("Origin", origin)
+	req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+	req.Header.Set("Access-Control-Request-Headers", "Content-Type")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestCORS_PerGroup(t *testing.T) {
+	h := httpsec.CORS(
+		httpsec.CORSPolicy{Origins: []string{"https://lms.example.com"}, AllowCredentials: true},
+		httpsec.CORSGroup{Prefix: "/api/public", Policy: httpsec.CORSPolicy{Origins: []string{"*"}}},
+		httpsec.CORSGroup{Prefix: "/api/admin", Policy: httpsec.CORSPolicy{Origins: []string{"https://admin.example.com"}, AllowCredentials: true}},
+	)(http.NotFoundHandler())
+
+	const stranger = "https://blog.example.org"
+	cases := []struct {
+		path, origin, wantAllow string
+		wantCreds               bool
+	}{
+		{"/api/public/offerings/o1", stranger, "*", false},
+		{"/api/admin/audit", stranger, "", false},
+		{"/api/admin/audit", "https://lms.example.com", "", false}, // default origin is not an admin origin
+		{"/api/admin/audit", "https://admin.example.com", "https://admin.example.com", true},
+		{"/api/exams", stranger, "", false},
+		{"/api/exams", "https://lms.example.com", "https://lms.example.com", true},
+		{"/api/administer", "https://admin.example.com", "", false}, // not under /api/admin
+	}
+	for _, c := range cases {
+		rec := preflight(h, c.path, c.origin)
+		if got := rec.Header().Get("Access-Control-Allow-Origin"); got != c.wantAllow {
+			t.Errorf("%s from %s: Allow-Origin = %q, want %q", c.path, c.origin, got, c.wantAllow)
+		}
+		if got := rec.Header().Get("Access-Control-Allow-Credentials") == "true"; got != c.wantCreds {
+			t.Errorf("%s from %s: credentials = %v, want %v", c.path, c.origin, got, c.wantCreds)
+		}
+	}
+
+	// the preflight itself is answered, never routed (no OPTIONS routes exist)
+	if rec := preflight(h, "/api/public/courses", stranger); rec.Code == http.StatusNotFound {
+		t.Fatalf("preflight reached the router: %d", rec.Code)
+	}
+}
+
+func TestCORS_ExposesErrorCode(t *testing.T) {
+	h := httpsec.CORS(httpsec.CORSPolicy{Origins: []string{"https://lms.example.com"}})(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-Error-Code", "time_over")
+			w.WriteHeader(http.StatusConflict)
+		}))
+	req := httptest.NewRequest(http.MethodPost, "/api/attempts/a1/responses", nil)
+	req.Header.Set("Origin", "https://lms.example.com")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if got := rec.Header().Get("Access-Control-Expose-Headers"); !strings.Contains(got, "X-Error-Code") {
+		t.Fatalf("Expose-Headers = %q, want X-Error-Code", got)
+	}
+}
@@ -0,0 +1,103 @@
+// internal/httpsec/headers.go
+package httpsec
+
+import (
+	"net/http"
+	"strings"
+)
+
+// HeaderOptions configures Headers.
+type HeaderOptions struct {
+	// CSP is the Content-Security-Policy for HTML responses; empty means
+	// DefaultCSP(AssetHost, FrameAncestors...).
+	CSP string
+	// AssetHost is an extra origin (CDN) allowed for scripts, styles, images,
+	// fonts and media by the default policy.
+	AssetHost string
+	// FrameAncestors are origins (the LMS platforms) the default policy lets
+	// frame the SPAs besides 'self': LTI launches open /exam/ in an iframe.
+	FrameAncestors []string
+	// ReportOnly sends Content-Security-Policy-Report-Only instead, to try a
+	// policy without breaking pages.
+	ReportOnly bool
+}
+
+// DefaultCSP allows the SPAs' own origin (plus assetHost, if any), inline
+// styles, data:/blob: images and media, and no plugins. Only the SPAs' own
+// origin and frameAncestors may frame them.
+func DefaultCSP(assetHost string, frameAncestors ...string) string {
+	src := "'self'"
+	if h := strings.TrimSpace(assetHost); h != "" {
+		src += " " + h
+	}
+	ancestors := "'self'"
+	for _, o := range frameAncestors {
+		if o = strings.TrimSpace(o); o != "" {
+			ancestors += " " + o
+		}
+	}
+	return strings.Join([]string{
+		"default-src 'self'",
+		"script-src " + src,
+		"style-src " + src + " 'unsafe-inline'",
+		"img-src " + src + " data: blob:",
+		"font-src " + src + " data:",
+		"media-src " + src + " blob:",
+		"connect-src 'self'",
+		"object-src 'none'",
+		"base-uri 'self'",
+		"frame-ancestors " + ancestors,
+	}, "; ")
+}
+
+// Headers sets the baseline security headers on every response and the CSP on
+// HTML responses (APIs and assets don't need one).
+func Headers(opts HeaderOptions) func(http.Handler) http.Handler {
+	csp := opts.CSP
+	if csp == "" {
+		csp = DefaultCSP(opts.AssetHost, opts.FrameAncestors...)
+	}
+	cspHeader := "Content-Security-Policy"
+	if opts.ReportOnly {
+		cspHeader = "Content-Security-Policy-Report-Only"
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-Content-Type-Options", "nosniff")
+			// No X-Frame-Options: LTI platforms frame the app, and the CSP's
+			// frame-ancestors already limits who may.
+			w.Header().Set("Referrer-Policy", "strict-origin-when-cross-origin")
+			next.ServeHTTP(&cspWriter{ResponseWriter: w, header: cspHeader, policy: csp}, r)
+		})
+	}
+}
+
+// cspWriter adds the CSP header once the response turns out to be HTML.
+type cspWriter struct {
+	http.ResponseWriter
+	header, policy string
+	wrote          bool
+}
+
+func (c *cspWriter) WriteHeader(code int) {
+	if !c.wrote {
+		c.wrote = true
+		if strings.HasPrefix(c.Header().Get("Content-Type"), "text/html") {
+			c.Header().Set(c.header, c.policy)
+		}
+	}
+	c.ResponseWriter.WriteHeader(code)
+}
+
+func (c *cspWriter) Write(b []byte) (int, error) {
+	if !c.wrote {
+		if c.Header().Get("Content-Type") == "" {
+			c.Header().Set("Content-Type", http.DetectContentType(b))
+		}
+		c.WriteHeader(http.StatusOK)
+	}
+	return c.ResponseWriter.Write(b)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (c *cspWriter) Unwrap() http.ResponseWriter { return c.ResponseWriter }
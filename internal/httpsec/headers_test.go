@@ -0,0 +1,61 @@
+package httpsec_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mind-engage/mindengage-lms/internal/httpsec"
+)
+
+func serve(mw func(http.Handler) http.Handler, contentType, body string) *httptest.ResponseRecorder {
+	inner := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if contentType != "" {
+			w.Header().Set("Content-Type", contentType)
+		}
+		_, _ = w.Write([]byte(body))
+	})
+	rec := httptest.NewRecorder()
+	mw(inner).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	return rec
+}
+
+func TestHeaders_CSP(t *testing.T) {
+	def := httpsec.Headers(httpsec.HeaderOptions{AssetHost: "https://cdn.example.com"})
+
+	rec := serve(def, "text/html; charset=utf-8", "<html></html>")
+	csp := rec.Header().Get("Content-Security-Policy")
+	if !strings.Contains(csp, "script-src 'self' https://cdn.example.com") || !strings.Contains(csp, "object-src 'none'") {
+		t.Fatalf("default csp = %q", csp)
+	}
+	if rec.Header().Get("X-Content-Type-Options") != "nosniff" {
+		t.Fatal("baseline headers missing")
+	}
+
+	// sniffed HTML (no Content-Type set by the handler) still gets it
+	if rec := serve(def, "", "<!doctype html><html></html>"); rec.Header().Get("Content-Security-Policy") == "" {
+		t.Fatal("no csp on sniffed html")
+	}
+	// JSON does not
+	if rec := serve(def, "application/json", `{}`); rec.Header().Get("Content-Security-Policy") != "" {
+		t.Fatal("csp on json response")
+	}
+
+	if !strings.Contains(csp, "frame-ancestors 'self'") {
+		t.Fatalf("default frame-ancestors = %q", csp)
+	}
+	lti := httpsec.Headers(httpsec.HeaderOptions{FrameAncestors: []string{"https://canvas.example.edu", " "}})
+	if csp := serve(lti, "text/html", "<html></html>").Header().Get("Content-Security-Policy"); !strings.HasSuffix(csp, "frame-ancestors 'self' https://canvas.example.edu") {
+		t.Fatalf("lti frame-ancestors = %q", csp)
+	}
+
+	custom := httpsec.Headers(httpsec.HeaderOptions{CSP: "default-src 'none'", ReportOnly: true})
+	rec = serve(custom, "text/html", "<html></html>")
+	if got := rec.Header().Get("Content-Security-Policy-Report-Only"); got != "default-src 'none'" {
+		t.Fatalf("report-only csp = %q", got)
+	}
+	if rec.Header().Get("Content-Security-Policy") != "" {
+		t.Fatal("enforcing header set in report-only mode")
+	}
+}
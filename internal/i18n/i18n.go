@@ -0,0 +1,187 @@
+// Package i18n localizes the short messages the API returns to people: error
+// bodies for attempt rules and grading feedback. Messages are keyed by a
+// stable Code; English is the default and the fallback for anything a
+// catalog lacks.
+package i18n
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Code identifies a message independently of its wording. Clients that branch
+// on errors should read the X-Error-Code header rather than the body.
+type Code string
+
+const (
+	AttemptSubmitted   Code = "attempt_submitted"
+	TimeOver           Code = "time_over"
+	OutsideModule      Code = "outside_module"
+	BackwardNavBlocked Code = "backward_nav_blocked"
+	EditBackBlocked    Code = "edit_back_blocked"
+	ModuleCompleted    Code = "module_completed"
+	ModuleMoved        Code = "module_moved"
+	NoAttemptsLeft     Code = "no_attempts_left"
+	UnknownQuestion    Code = "unknown_question"
+
+	FeedbackManualRequired Code = "feedback_manual_required"
+	FeedbackNoStrategy     Code = "feedback_no_strategy"
+	FeedbackFuzzyMatch     Code = "feedback_fuzzy_match"
+	FeedbackOCRMissing     Code = "feedback_ocr_missing"
+)
+
+// Default is the language used when negotiation finds nothing better.
+const Default = "en"
+
+// catalogs[lang][code]. The English texts match the engine's own strings so
+// English responses are unchanged.
+var catalogs = map[string]map[Code]string{
+	"en": {
+		AttemptSubmitted:   "attempt already submitted",
+		TimeOver:           "time over",
+		OutsideModule:      "outside current module window",
+		BackwardNavBlocked: "backward navigation blocked",
+		EditBackBlocked:    "editing a locked (past) question",
+		ModuleCompleted:    "editing a question in a completed module",
+		ModuleMoved:        "attempt is no longer in that module",
+		NoAttemptsLeft:     "no attempts left for this offering",
+		UnknownQuestion:    "unknown question id",
+
+		FeedbackManualRequired: "manual grading required",
+		FeedbackNoStrategy:     "no strategy available",
+		FeedbackFuzzyMatch:     "close match (fuzzy)",
+		FeedbackOCRMissing:     "OCR not configured",
+	},
+	"es": {
+		AttemptSubmitted:   "el intento ya fue enviado",
+		TimeOver:           "se acabó el tiempo",
+		OutsideModule:      "fuera de la ventana del módulo actual",
+		BackwardNavBlocked: "no se permite volver atrás",
+		EditBackBlocked:    "no se puede editar una pregunta bloqueada (anterior)",
+		ModuleCompleted:    "no se puede editar una pregunta de un módulo completado",
+		ModuleMoved:        "el intento ya no está en ese módulo",
+		NoAttemptsLeft:     "no quedan intentos para esta oferta",
+		UnknownQuestion:    "id de pregunta desconocido",
+
+		FeedbackManualRequired: "requiere calificación manual",
+		FeedbackNoStrategy:     "no hay estrategia de calificación disponible",
+		FeedbackFuzzyMatch:     "coincidencia aproximada",
+		FeedbackOCRMissing:     "OCR no configurado",
+	},
+}
+
+// byEnglish maps English texts back to their codes, for strings produced
+// before localization (grading feedback).
+var byEnglish = func() map[string]Code {
+	m := make(map[string]Code, len(catalogs[Default]))
+	for c, s := range catalogs[Default] {
+		m[s] = c
+	}
+	return m
+}()
+
+// Message returns code's text in lang, falling back to English, then to the
+// code itself.
+func Message(lang string, code Code) string {
+	if s, ok := catalogs[lang][code]; ok {
+		return s
+	}
+	if s, ok := catalogs[Default][code]; ok {
+		return s
+	}
+	return string(code)
+}
+
+// Localize translates an English catalog text into lang; other strings pass
+// through unchanged.
+func Localize(lang, english string) string {
+	if c, ok := byEnglish[english]; ok {
+		return Message(lang, c)
+	}
+	return english
+}
+
+// LocalizeAll is Localize over a slice, returning a new slice.
+func LocalizeAll(lang string, msgs []string) []string {
+	if len(msgs) == 0 || lang == Default {
+		return msgs
+	}
+	out := make([]string, len(msgs))
+	for i, m := range msgs {
+		out[i] = Localize(lang, m)
+	}
+	return out
+}
+
+// Negotiate picks the best catalog language for an Accept-Language header,
+// honoring q-values and matching regional tags (es-MX) to their base (es).
+func Negotiate(header string) string {
+	type pref struct {
+		lang string
+		q    float64
+	}
+	var prefs []pref
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" {
+			continue
+		}
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if f, err := strconv.ParseFloat(v, 64); err == nil {
+				q = f
+			}
+		}
+		if q > 0 {
+			prefs = append(prefs, pref{tag, q})
+		}
+	}
+	sort.SliceStable(prefs, func(i, j int) bool { return prefs[i].q > prefs[j].q })
+	for _, p := range prefs {
+		base, _, _ := strings.Cut(p.lang, "-")
+		if _, ok := catalogs[base]; ok {
+			return base
+		}
+	}
+	return Default
+}
+
+type langKey struct{}
+
+// Middleware negotiates the request language once and stores it for Lang.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept-Language")
+		lang := Negotiate(r.Header.Get("Accept-Language"))
+		next.ServeHTTP(w, r.WithContext(WithLang(r.Context(), lang)))
+	})
+}
+
+// WithLang returns ctx carrying lang.
+func WithLang(ctx context.Context, lang string) context.Context {
+	return context.WithValue(ctx, langKey{}, lang)
+}
+
+// Lang is the request language set by Middleware (Default if none).
+func Lang(ctx context.Context) string {
+	if l, ok := ctx.Value(langKey{}).(string); ok && l != "" {
+		return l
+	}
+	return Default
+}
+
+// Error writes a localized plain-text error with the code in X-Error-Code.
+// detail, if any, is appended after a colon (e.g. the offending id).
+func Error(w http.ResponseWriter, r *http.Request, status int, code Code, detail string) {
+	msg := Message(Lang(r.Context()), code)
+	if detail != "" {
+		msg += ": " + detail
+	}
+	w.Header().Set("X-Error-Code", string(code))
+	w.Header().Set("Content-Language", Lang(r.Context()))
+	http.Error(w, msg, status)
+}
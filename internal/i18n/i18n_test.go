@@ -0,0 +1,59 @@
+package i18n_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mind-engage/mindengage-lms/internal/i18n"
+)
+
+func TestNegotiate(t *testing.T) {
+	for header, want := range map[string]string{
+		"":                          "en",
+		"es":                        "es",
+		"es-MX,es;q=0.9,en;q=0.8":   "es",
+		"fr-FR, en;q=0.5, es;q=0.7": "es",
+		"de, fr":                    "en",
+		"es;q=0, en":                "en",
+	} {
+		if got := i18n.Negotiate(header); got != want {
+			t.Errorf("Negotiate(%q) = %q, want %q", header, got, want)
+		}
+	}
+}
+
+func TestError_Spanish(t *testing.T) {
+	h := i18n.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		i18n.Error(w, r, http.StatusConflict, i18n.Code(r.URL.Query().Get("code")), "")
+	}))
+	for code, want := range map[i18n.Code][2]string{
+		i18n.TimeOver:         {"time over", "se acabó el tiempo"},
+		i18n.AttemptSubmitted: {"attempt already submitted", "el intento ya fue enviado"},
+	} {
+		for i, lang := range []string{"en-US", "es-ES,es;q=0.9"} {
+			req := httptest.NewRequest(http.MethodGet, "/?code="+string(code), nil)
+			req.Header.Set("Accept-Language", lang)
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			if got := strings.TrimSpace(rec.Body.String()); got != want[i] {
+				t.Errorf("%s in %s: body = %q, want %q", code, lang, got, want[i])
+			}
+			if rec.Header().Get("X-Error-Code") != string(code) {
+				t.Errorf("%s in %s: X-Error-Code = %q", code, lang, rec.Header().Get("X-Error-Code"))
+			}
+		}
+	}
+}
+
+func TestLocalizeFeedback(t *testing.T) {
+	got := i18n.LocalizeAll("es", []string{"manual grading required", "OCR failed: boom"})
+	if got[0] != "requiere calificación manual" || got[1] != "OCR failed: boom" {
+		t.Fatalf("LocalizeAll = %q", got)
+	}
+	if got := i18n.Message(i18n.Lang(context.Background()), i18n.TimeOver); got != "time over" {
+		t.Fatalf("default message = %q", got)
+	}
+}
@@ -57,7 +57,7 @@ type Result struct {
 }
 
 // Claim from LTI launch: https://purl.imsglobal.org/spec/lti-ags/claim/endpoint
-type endpointClaim struct {
+type EndpointClaim struct {
 	LineItems string   `json:"lineitems"`
 	Scope     []string `json:"scope"`
 }
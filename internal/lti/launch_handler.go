@@ -3,25 +3,60 @@ package lti
 
 import (
 	"database/sql"
+	"errors"
+	"log/slog"
 	"net/http"
 	"strings"
 
 	"github.com/golang-jwt/jwt/v5"
 	auth "github.com/mind-engage/mindengage-lms/internal/auth/middleware"
 	"github.com/mind-engage/mindengage-lms/internal/config"
+	"github.com/mind-engage/mindengage-lms/pkg/lti-ags-gradebook/gradebook"
 )
 
 // LTIClaims embeds RegisteredClaims so it satisfies jwt.Claims in v5.
 type LTIClaims struct {
 	jwt.RegisteredClaims
+	AuthorizedParty string `json:"azp,omitempty"`
+
 	Email string   `json:"email"`
 	Name  string   `json:"name"`
 	Roles []string `json:"https://purl.imsglobal.org/spec/lti/claim/roles"`
+
+	DeploymentID string            `json:"https://purl.imsglobal.org/spec/lti/claim/deployment_id"`
+	Context      ContextClaim      `json:"https://purl.imsglobal.org/spec/lti/claim/context"`
+	ResourceLink ResourceLinkClaim `json:"https://purl.imsglobal.org/spec/lti/claim/resource_link"`
+	AGS          *EndpointClaim    `json:"https://purl.imsglobal.org/spec/lti-ags/claim/endpoint,omitempty"`
+}
+
+// ClientID is the client the token was issued to: azp when present, else a
+// single aud. A token for several audiences without azp has none.
+func (c LTIClaims) ClientID() string {
+	if c.AuthorizedParty != "" {
+		return c.AuthorizedParty
+	}
+	if len(c.Audience) == 1 {
+		return c.Audience[0]
+	}
+	return ""
+}
+
+type ContextClaim struct {
+	ID    string `json:"id"`
+	Label string `json:"label,omitempty"`
+	Title string `json:"title,omitempty"`
+}
+
+type ResourceLinkClaim struct {
+	ID    string `json:"id"`
+	Title string `json:"title,omitempty"`
 }
 
 // Receives id_token POST, extracts user & role, upserts DB user, and mints internal JWT.
-// NOTE: Signature/claims verification is still TODO; this parses the token without verification
-// for dev purposes. In production, verify with Platform issuer and JWKS.
+// Tokens from platforms registered in lti_platforms must verify against the
+// platform's JWKS (see verifyLaunch) or the launch is refused; only those
+// launches record links. Other issuers are still parsed without verification
+// for dev purposes.
 func LaunchHandler(a *auth.AuthService, db *sql.DB, cfg config.Config) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		if err := r.ParseForm(); err != nil {
@@ -34,13 +69,27 @@ func LaunchHandler(a *auth.AuthService, db *sql.DB, cfg config.Config) http.Hand
 			return
 		}
 
-		// DEV ONLY: parse without verifying
+		// Parse without verifying to find the issuer ...
 		var claims LTIClaims
 		parser := jwt.NewParser()
 		if _, _, err := parser.ParseUnverified(idtok, &claims); err != nil {
 			http.Error(w, "bad id_token", http.StatusBadRequest)
 			return
 		}
+		// ... then verify against it if registered. Unregistered: DEV ONLY.
+		var platform *gradebook.Platform
+		if db != nil {
+			p, err := verifyLaunch(r.Context(), db, idtok, &claims)
+			switch {
+			case err == nil:
+				platform = &p
+			case errors.Is(err, gradebook.ErrPlatformNotFound):
+			default:
+				slog.Warn("lti launch: verify", "iss", claims.Issuer, "err", err)
+				http.Error(w, "invalid id_token", http.StatusUnauthorized)
+				return
+			}
+		}
 
 		// Map LTI roles -> internal role
 		role := "student"
@@ -70,16 +119,47 @@ func LaunchHandler(a *auth.AuthService, db *sql.DB, cfg config.Config) http.Hand
 		// Upsert user for DB-backed role resolution via auth.AttachRoleFromDB
 		if db != nil {
 			var existingID string
-			err := db.QueryRow(`SELECT id FROM users WHERE username=$1`, username).Scan(&existingID)
+			// A platform user seen before (lti_user_map) keeps its local user,
+			// even if the email it launches with has changed. The map is only
+			// read and written for verified launches: its iss/sub are the
+			// identity, and unverified claims can name anyone.
+			err := sql.ErrNoRows
+			if platform != nil {
+				if mapped := mappedUser(r.Context(), db, claims.Issuer, claims.Subject); mapped != "" {
+					err = db.QueryRow(`SELECT id FROM users WHERE id=$1`, mapped).Scan(&existingID)
+				}
+			}
+			if err == sql.ErrNoRows {
+				err = db.QueryRow(`SELECT id FROM users WHERE username=$1`, username).Scan(&existingID)
+			}
+			mapTo := ""
 			switch {
+			case err == sql.ErrNoRows && !cfg.JITProvisioning:
+				http.Error(w, "account not provisioned; ask an administrator to create it", http.StatusForbidden)
+				return
 			case err == sql.ErrNoRows:
-				_, _ = db.Exec(`INSERT INTO users (id, username, role) VALUES ($1, $2, $3)`, userID, username, role)
+				if _, err := db.Exec(`INSERT INTO users (id, username, role) VALUES ($1, $2, $3)`, userID, username, role); err == nil {
+					mapTo = userID
+				}
 			case err == nil:
 				_, _ = db.Exec(`UPDATE users SET role=$1 WHERE id=$2`, role, existingID)
 				userID = existingID
+				mapTo = existingID
 			default:
 				// On DB error, continue; RBAC may rely on claims fallback depending on config.
 			}
+			if mapTo != "" && platform != nil {
+				if err := mapUser(r.Context(), db, claims.Issuer, claims.Subject, mapTo); err != nil {
+					slog.Warn("lti launch: map user", "iss", claims.Issuer, "sub", claims.Subject, "err", err)
+				}
+			}
+		}
+
+		// Record where this link's grades go (lti_links), for passback.
+		if platform != nil {
+			if err := recordLaunchLink(r.Context(), db, *platform, claims); err != nil {
+				slog.Warn("lti launch: record link", "iss", claims.Issuer, "context", claims.Context.ID, "err", err)
+			}
 		}
 
 		// Mint internal JWT for our API
@@ -99,11 +179,6 @@ func LaunchHandler(a *auth.AuthService, db *sql.DB, cfg config.Config) http.Hand
 			SameSite: http.SameSiteNoneMode,
 		})
 
-		target := cfg.PublicURL
-		if target == "" {
-			target = "/"
-		}
-		target = strings.TrimRight(target, "/") + "/exam/"
-		http.Redirect(w, r, target, http.StatusFound)
+		http.Redirect(w, r, cfg.ExternalURL("/exam/"), http.StatusFound)
 	}
 }
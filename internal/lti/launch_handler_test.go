@@ -0,0 +1,311 @@
+package lti_test
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	auth "github.com/mind-engage/mindengage-lms/internal/auth/middleware"
+	"github.com/mind-engage/mindengage-lms/internal/config"
+	"github.com/mind-engage/mindengage-lms/internal/db"
+	"github.com/mind-engage/mindengage-lms/internal/lti"
+	"github.com/mind-engage/mindengage-lms/pkg/lti-ags-gradebook/gradebook"
+)
+
+func newLaunchDB(t *testing.T) *sql.DB {
+	t.Helper()
+	ctx := context.Background()
+	dsn := "file:" + filepath.Join(t.TempDir(), "test.db") + "?_pragma=busy_timeout(5000)"
+	dbh, err := db.Open(ctx, db.DriverSQLite, dsn)
+	if err != nil {
+		t.Fatalf("db open: %v", err)
+	}
+	t.Cleanup(func() { _ = dbh.Close() })
+	if err := gradebook.Migrate(ctx, dbh, "sqlite"); err != nil {
+		t.Fatal(err)
+	}
+	return dbh
+}
+
+// testPlatform is a registered LMS: its signing key is served as a JWKS.
+type testPlatform struct {
+	issuer, clientID string
+	key              *rsa.PrivateKey
+}
+
+func newTestPlatform(t *testing.T, dbh *sql.DB, issuer, clientID string) *testPlatform {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	enc := base64.RawURLEncoding.EncodeToString
+	jwks := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]any{"keys": []map[string]any{{
+			"kty": "RSA", "kid": "k1", "alg": "RS256", "use": "sig",
+			"n": enc(key.N.Bytes()), "e": enc(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	}))
+	t.Cleanup(jwks.Close)
+	if _, err := dbh.Exec(`INSERT INTO lti_platforms (issuer, client_id, token_url, jwks_url, auth_url) VALUES ($1,$2,$3,$4,$5)`,
+		issuer, clientID, issuer+"/token", jwks.URL, issuer+"/auth"); err != nil {
+		t.Fatal(err)
+	}
+	return &testPlatform{issuer: issuer, clientID: clientID, key: key}
+}
+
+// sign issues a launch id_token for the platform; iss, aud and exp default
+// to a valid launch when left empty.
+func (p *testPlatform) sign(t *testing.T, c lti.LTIClaims) string {
+	t.Helper()
+	if c.Issuer == "" {
+		c.Issuer = p.issuer
+	}
+	if c.Audience == nil {
+		c.Audience = jwt.ClaimStrings{p.clientID}
+	}
+	if c.ExpiresAt == nil {
+		c.ExpiresAt = jwt.NewNumericDate(time.Now().Add(5 * time.Minute))
+	}
+	tok := jwt.NewWithClaims(jwt.SigningMethodRS256, c)
+	tok.Header["kid"] = "k1"
+	s, err := tok.SignedString(p.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func postLaunch(t *testing.T, dbh *sql.DB, jit bool, idtok string) int {
+	t.Helper()
+	h := lti.LaunchHandler(auth.NewAuthService("test"), dbh, config.Config{JITProvisioning: jit})
+	req := httptest.NewRequest(http.MethodPost, "/lti/launch", strings.NewReader(url.Values{"id_token": {idtok}}.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rec := httptest.NewRecorder()
+	h(rec, req)
+	return rec.Code
+}
+
+// unsigned is a dev launch from an unregistered issuer (HS256, never verified).
+func unsigned(t *testing.T, c lti.LTIClaims) string {
+	t.Helper()
+	tok, err := jwt.NewWithClaims(jwt.SigningMethodHS256, c).SignedString([]byte("platform"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tok
+}
+
+func TestLaunch_JITProvisioning(t *testing.T) {
+	dbh := newLaunchDB(t)
+	if _, err := dbh.Exec(`INSERT INTO users (id, username, role) VALUES ('known','known@example.edu','student')`); err != nil {
+		t.Fatal(err)
+	}
+
+	launch := func(jit bool, email string) int {
+		return postLaunch(t, dbh, jit, unsigned(t, lti.LTIClaims{
+			RegisteredClaims: jwt.RegisteredClaims{Issuer: "https://lms.example", Subject: email},
+			Email:            email,
+		}))
+	}
+	exists := func(email string) bool {
+		var n int
+		_ = dbh.QueryRow(`SELECT COUNT(*) FROM users WHERE username=$1`, email).Scan(&n)
+		return n > 0
+	}
+
+	// Provisioning off: unknown subjects are refused and not created.
+	if code := launch(false, "new@example.edu"); code != http.StatusForbidden {
+		t.Fatalf("jit off, unknown: status %d", code)
+	}
+	if exists("new@example.edu") {
+		t.Fatal("jit off created a user")
+	}
+	if code := launch(false, "known@example.edu"); code != http.StatusFound {
+		t.Fatalf("jit off, known: status %d", code)
+	}
+
+	// Provisioning on: the unknown subject is created on first launch.
+	if code := launch(true, "new@example.edu"); code != http.StatusFound {
+		t.Fatalf("jit on, unknown: status %d", code)
+	}
+	if !exists("new@example.edu") {
+		t.Fatal("jit on did not create the user")
+	}
+}
+
+func TestLaunch_RecordsLink(t *testing.T) {
+	dbh := newLaunchDB(t)
+	lms := newTestPlatform(t, dbh, "https://lms.example", "c")
+
+	claims := func(lineItems string, scopes ...string) lti.LTIClaims {
+		return lti.LTIClaims{
+			RegisteredClaims: jwt.RegisteredClaims{Subject: "s1"},
+			DeploymentID:     "dep-1",
+			Context:          lti.ContextClaim{ID: "ctx-1", Title: "Physics"},
+			ResourceLink:     lti.ResourceLinkClaim{ID: "rl-1"},
+			AGS:              &lti.EndpointClaim{LineItems: lineItems, Scope: scopes},
+		}
+	}
+	launch := func(idtok string, want int) {
+		t.Helper()
+		if code := postLaunch(t, dbh, true, idtok); code != want {
+			t.Fatalf("launch: status %d, want %d", code, want)
+		}
+	}
+	link := func() (n int, lineItems, scopes string) {
+		t.Helper()
+		if err := dbh.QueryRow(`SELECT COUNT(*), COALESCE(MAX(lineitems_url),''), COALESCE(MAX(scopes),'') FROM lti_links
+			WHERE platform_issuer='https://lms.example' AND deployment_id='dep-1' AND context_id='ctx-1' AND resource_link_id='rl-1'`).
+			Scan(&n, &lineItems, &scopes); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	launch(lms.sign(t, claims("https://lms.example/ctx-1/lineitems",
+		"https://purl.imsglobal.org/spec/lti-ags/scope/lineitem")), http.StatusFound)
+	if n, li, sc := link(); n != 1 || li != "https://lms.example/ctx-1/lineitems" || sc != `["https://purl.imsglobal.org/spec/lti-ags/scope/lineitem"]` {
+		t.Fatalf("first launch: n=%d lineitems=%q scopes=%q", n, li, sc)
+	}
+
+	// A relaunch updates the same row.
+	launch(lms.sign(t, claims("https://lms.example/ctx-1/lineitems?v=2",
+		"https://purl.imsglobal.org/spec/lti-ags/scope/lineitem", "https://purl.imsglobal.org/spec/lti-ags/scope/score")), http.StatusFound)
+	if n, li, sc := link(); n != 1 || li != "https://lms.example/ctx-1/lineitems?v=2" || !strings.Contains(sc, "scope/score") {
+		t.Fatalf("relaunch: n=%d lineitems=%q scopes=%q", n, li, sc)
+	}
+
+	// Tokens that claim the registered issuer but do not verify are refused
+	// and change nothing.
+	forged := func(mut func(*lti.LTIClaims)) lti.LTIClaims {
+		c := claims("https://evil.example/lineitems")
+		c.Issuer, c.Audience = "https://lms.example", jwt.ClaimStrings{"c"}
+		if mut != nil {
+			mut(&c)
+		}
+		return c
+	}
+	other := newTestPlatform(t, dbh, "https://unrelated.example", "u")
+	for name, tok := range map[string]string{
+		"unsigned":     unsigned(t, forged(nil)),
+		"other key":    other.sign(t, forged(nil)),
+		"other client": lms.sign(t, forged(func(c *lti.LTIClaims) { c.Audience = jwt.ClaimStrings{"not-c"} })),
+		"expired":      lms.sign(t, forged(func(c *lti.LTIClaims) { c.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Hour)) })),
+	} {
+		if code := postLaunch(t, dbh, true, tok); code != http.StatusUnauthorized {
+			t.Errorf("%s: status %d, want 401", name, code)
+		}
+	}
+	if _, li, _ := link(); li != "https://lms.example/ctx-1/lineitems?v=2" {
+		t.Fatalf("after rejected launches: lineitems=%q", li)
+	}
+
+	// A verified launch cannot point passback off the platform's host.
+	launch(lms.sign(t, claims("https://evil.example/lineitems")), http.StatusFound)
+	launch(lms.sign(t, claims("http://lms.example/lineitems")), http.StatusFound)
+	if _, li, _ := link(); li != "https://lms.example/ctx-1/lineitems?v=2" {
+		t.Fatalf("off-host lineitems recorded: %q", li)
+	}
+
+	// Unregistered platforms are not recorded, but the launch still succeeds.
+	dev := claims("https://other.example/lineitems")
+	dev.Issuer = "https://other.example"
+	launch(unsigned(t, dev), http.StatusFound)
+	var total int
+	if err := dbh.QueryRow(`SELECT COUNT(*) FROM lti_links`).Scan(&total); err != nil || total != 1 {
+		t.Fatalf("lti_links rows = %d (%v), want 1", total, err)
+	}
+}
+
+func TestLaunch_UserMapping(t *testing.T) {
+	dbh := newLaunchDB(t)
+	lms := newTestPlatform(t, dbh, "https://lms.example", "c")
+
+	launch := func(idtok string) {
+		t.Helper()
+		if code := postLaunch(t, dbh, true, idtok); code != http.StatusFound {
+			t.Fatalf("launch: status %d", code)
+		}
+	}
+	claims := func(sub, email string) lti.LTIClaims {
+		return lti.LTIClaims{RegisteredClaims: jwt.RegisteredClaims{Subject: sub}, Email: email}
+	}
+	mappings := func(iss, sub string) (n int, id string) {
+		t.Helper()
+		if err := dbh.QueryRow(`SELECT COUNT(*), COALESCE(MAX(local_user_id),'') FROM lti_user_map WHERE platform_issuer=$1 AND platform_sub=$2`,
+			iss, sub).Scan(&n, &id); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	mapped := func(sub string) string {
+		t.Helper()
+		n, id := mappings("https://lms.example", sub)
+		if n != 1 {
+			t.Fatalf("mapping for %s: %d rows", sub, n)
+		}
+		return id
+	}
+	users := func() int {
+		var n int
+		_ = dbh.QueryRow(`SELECT COUNT(*) FROM users`).Scan(&n)
+		return n
+	}
+
+	// First launch creates the user and maps the platform sub to it.
+	launch(lms.sign(t, claims("sub-1", "ada@example.edu")))
+	if got := mapped("sub-1"); got != "https://lms.example|sub-1" {
+		t.Fatalf("first launch mapped to %q", got)
+	}
+	if users() != 1 {
+		t.Fatalf("users = %d, want 1", users())
+	}
+
+	// A repeat launch with a changed email reuses the mapped user.
+	launch(lms.sign(t, claims("sub-1", "ada.lovelace@example.edu")))
+	if got := mapped("sub-1"); got != "https://lms.example|sub-1" || users() != 1 {
+		t.Fatalf("repeat launch: mapped to %q, users = %d", got, users())
+	}
+
+	// An existing local account is adopted by username and mapped.
+	if _, err := dbh.Exec(`INSERT INTO users (id, username, role) VALUES ('local-7','grace@example.edu','student')`); err != nil {
+		t.Fatal(err)
+	}
+	launch(lms.sign(t, claims("sub-2", "grace@example.edu")))
+	if got := mapped("sub-2"); got != "local-7" {
+		t.Fatalf("existing account mapped to %q, want local-7", got)
+	}
+
+	// Unverified launches neither write the map nor follow it: a dev token
+	// naming a mapped sub with another email gets its own user.
+	dev := claims("sub-9", "eve@example.edu")
+	dev.Issuer = "https://dev.example"
+	launch(unsigned(t, dev))
+	if n, _ := mappings("https://dev.example", "sub-9"); n != 0 {
+		t.Fatalf("unverified launch wrote %d mappings", n)
+	}
+	if _, err := dbh.Exec(`INSERT INTO lti_user_map (platform_issuer, platform_sub, local_user_id) VALUES ('https://dev.example','sub-1','local-7')`); err != nil {
+		t.Fatal(err)
+	}
+	before := users()
+	hijack := claims("sub-1", "mallory@example.edu")
+	hijack.Issuer = "https://dev.example"
+	launch(unsigned(t, hijack))
+	if users() != before+1 {
+		t.Fatalf("unverified launch followed the map: users %d -> %d", before, users())
+	}
+}
@@ -0,0 +1,79 @@
+// internal/lti/launch_link.go
+package lti
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+
+	"github.com/mind-engage/mindengage-lms/pkg/lti-ags-gradebook/gradebook"
+)
+
+// errLineItemsHost refuses a lineitems URL that is not on the platform's host.
+var errLineItemsHost = errors.New("lineitems URL is not on the platform's host")
+
+// recordLaunchLink upserts the lti_links row (gradebook schema, see
+// pkg/lti-ags-gradebook/gradebook) for a launch verified against platform p,
+// keyed by issuer, deployment, context and resource link, with the AGS
+// lineitems URL and scopes the platform granted. The latest launch wins.
+// Launches without those ids record nothing; a lineitems URL off the
+// platform's host is an error and records nothing either.
+func recordLaunchLink(ctx context.Context, db *sql.DB, p gradebook.Platform, c LTIClaims) error {
+	if c.DeploymentID == "" || c.Context.ID == "" || c.ResourceLink.ID == "" {
+		return nil
+	}
+	if c.AGS != nil && c.AGS.LineItems != "" && !onPlatformHost(p, c.AGS.LineItems) {
+		return errLineItemsHost
+	}
+
+	var lineItems, scopes any
+	if c.AGS != nil {
+		if c.AGS.LineItems != "" {
+			lineItems = c.AGS.LineItems
+		}
+		if len(c.AGS.Scope) > 0 {
+			b, _ := json.Marshal(c.AGS.Scope)
+			scopes = string(b)
+		}
+	}
+	_, err := db.ExecContext(ctx, `
+		INSERT INTO lti_links (platform_issuer, deployment_id, context_id, resource_link_id, lineitems_url, scopes)
+		VALUES ($1,$2,$3,$4,$5,$6)
+		ON CONFLICT (platform_issuer, deployment_id, context_id, resource_link_id)
+		DO UPDATE SET
+			lineitems_url=EXCLUDED.lineitems_url,
+			scopes=EXCLUDED.scopes,
+			updated_at=CURRENT_TIMESTAMP`,
+		p.Issuer, c.DeploymentID, c.Context.ID, c.ResourceLink.ID, lineItems, scopes)
+	return err
+}
+
+// mappedUser returns the local user recorded in lti_user_map for a platform
+// user, or "" if there is none (or the lookup fails).
+func mappedUser(ctx context.Context, db *sql.DB, issuer, sub string) string {
+	if issuer == "" || sub == "" {
+		return ""
+	}
+	var id string
+	if err := db.QueryRowContext(ctx, `SELECT local_user_id FROM lti_user_map WHERE platform_issuer=$1 AND platform_sub=$2`,
+		issuer, sub).Scan(&id); err != nil {
+		return ""
+	}
+	return id
+}
+
+// mapUser records (or moves) the platform user's mapping to localUserID.
+// Passback reads it back to find the sub to post scores for.
+func mapUser(ctx context.Context, db *sql.DB, issuer, sub, localUserID string) error {
+	if issuer == "" || sub == "" {
+		return nil
+	}
+	_, err := db.ExecContext(ctx, `
+		INSERT INTO lti_user_map (platform_issuer, platform_sub, local_user_id)
+		VALUES ($1,$2,$3)
+		ON CONFLICT (platform_issuer, platform_sub)
+		DO UPDATE SET local_user_id=EXCLUDED.local_user_id`,
+		issuer, sub, localUserID)
+	return err
+}
@@ -0,0 +1,77 @@
+// internal/lti/launch_verify.go
+package lti
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/mind-engage/mindengage-lms/pkg/lti-ags-gradebook/gradebook"
+	"github.com/mind-engage/mindengage-lms/pkg/lti-ags-gradebook/sqlstore"
+	platformlti "github.com/mind-engage/mindengage-lms/pkg/platform/lti"
+)
+
+// launchKeys caches platform key sets across launches.
+var launchKeys platformlti.KeySetSource = &platformlti.JWKSCache{TTL: 10 * time.Minute}
+
+// verifyLaunch looks up the platform that issued idtok (claims holds its
+// unverified claims) and verifies the token against it: RS256 signature
+// from the platform's JWKS, iss, aud (the registered client id) and exp. On
+// success claims is replaced by the verified claims. gradebook.ErrPlatformNotFound
+// means the issuer is not registered and nothing could be verified.
+func verifyLaunch(ctx context.Context, db *sql.DB, idtok string, claims *LTIClaims) (gradebook.Platform, error) {
+	p, err := gradebook.PlatformForLaunch(&sqlstore.Store{DB: db}, claims.Issuer, claims.ClientID())
+	if err != nil {
+		return gradebook.Platform{}, err
+	}
+	var verified LTIClaims
+	_, err = jwt.ParseWithClaims(idtok, &verified, func(t *jwt.Token) (any, error) {
+		kid, _ := t.Header["kid"].(string)
+		set, err := launchKeys.KeySetFor(ctx, p.JWKSURL, kid)
+		if err != nil {
+			return nil, err
+		}
+		keys, err := platformlti.RSAPublicKeysFromJWKS(set, kid)
+		if err != nil {
+			return nil, err
+		}
+		var vks jwt.VerificationKeySet
+		for _, k := range keys {
+			vks.Keys = append(vks.Keys, k)
+		}
+		return vks, nil
+	},
+		jwt.WithValidMethods([]string{"RS256"}),
+		jwt.WithIssuer(p.Issuer),
+		jwt.WithAudience(p.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(time.Minute),
+	)
+	if err != nil {
+		return gradebook.Platform{}, fmt.Errorf("id_token from %s: %w", p.Issuer, err)
+	}
+	*claims = verified
+	return p, nil
+}
+
+// onPlatformHost reports whether raw points at the registered platform: same
+// scheme and host as its issuer, token or auth URL. A verified launch can
+// still carry an AGS endpoint elsewhere (a misconfigured or compromised
+// course), and passback would send the platform's access token there.
+func onPlatformHost(p gradebook.Platform, raw string) bool {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" || u.User != nil {
+		return false
+	}
+	for _, reg := range []string{p.Issuer, p.TokenURL, p.AuthURL} {
+		r, err := url.Parse(reg)
+		if err == nil && r.Host != "" && r.Scheme == u.Scheme && strings.EqualFold(r.Host, u.Host) {
+			return true
+		}
+	}
+	return false
+}
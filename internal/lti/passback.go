@@ -0,0 +1,85 @@
+// internal/lti/passback.go
+package lti
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/mind-engage/mindengage-lms/internal/exam"
+	"github.com/mind-engage/mindengage-lms/pkg/lti-ags-gradebook/agshttp"
+	"github.com/mind-engage/mindengage-lms/pkg/lti-ags-gradebook/gradebook"
+)
+
+// Passback delivers exam.OutboxWorker intents as AGS score posts. A score
+// goes to every line item registered for the exam (gradebook_lineitems) on
+// a platform where the taker has a platform user (lti_user_map), with the
+// tool's client credentials at that platform's token endpoint.
+type Passback struct {
+	DB *sql.DB
+	// ClientSecret is the tool's secret for the client_credentials grant;
+	// the client id is the platform's (lti_platforms.client_id).
+	ClientSecret string
+	Timeout      time.Duration // per AGS request; default 15s
+
+	// Now stamps the score; nil means time.Now.
+	Now func() time.Time
+}
+
+// Deliver posts p's score. It fails while the exam has no line item for any
+// of the taker's platforms, so the worker retries once one is registered.
+func (b *Passback) Deliver(ctx context.Context, p exam.PassbackIntent) error {
+	rows, err := b.DB.QueryContext(ctx, `
+		SELECT li.line_item_url, li.score_max, m.platform_sub, pl.token_url, pl.client_id
+		  FROM gradebook_lineitems li
+		  JOIN lti_user_map m ON m.platform_issuer = li.platform_issuer AND m.local_user_id = $2
+		  JOIN lti_platforms pl ON pl.issuer = li.platform_issuer
+		 WHERE li.exam_id = $1
+		 ORDER BY li.id`, p.ExamID, p.UserID)
+	if err != nil {
+		return err
+	}
+	type target struct {
+		lineItemURL, sub, tokenURL, clientID string
+		scoreMax                             float64
+	}
+	var targets []target
+	for rows.Next() {
+		var t target
+		if err := rows.Scan(&t.lineItemURL, &t.scoreMax, &t.sub, &t.tokenURL, &t.clientID); err != nil {
+			rows.Close()
+			return err
+		}
+		targets = append(targets, t)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if len(targets) == 0 {
+		return fmt.Errorf("passback: no line item for exam %s on the taker's platforms", p.ExamID)
+	}
+
+	timeout := b.Timeout
+	if timeout <= 0 {
+		timeout = 15 * time.Second
+	}
+	now := time.Now
+	if b.Now != nil {
+		now = b.Now
+	}
+	for _, t := range targets {
+		ags := agshttp.New(agshttp.Config{
+			TokenURL: t.tokenURL, ClientID: t.clientID, ClientSecret: b.ClientSecret, Timeout: timeout,
+		})
+		if err := ags.PostScore(t.lineItemURL, gradebook.Score{
+			UserID: t.sub, ScoreGiven: p.Score, ScoreMaximum: t.scoreMax,
+			ActivityProgress: "Completed", GradingProgress: "FullyGraded",
+			Timestamp: now(),
+		}); err != nil {
+			return fmt.Errorf("passback %s: %w", t.lineItemURL, err)
+		}
+	}
+	return nil
+}
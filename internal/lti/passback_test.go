@@ -0,0 +1,69 @@
+package lti_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/mind-engage/mindengage-lms/internal/exam"
+	"github.com/mind-engage/mindengage-lms/internal/lti"
+)
+
+func TestPassback_Deliver(t *testing.T) {
+	dbh := newLaunchDB(t)
+	var scores []map[string]any
+	mux := http.NewServeMux()
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		if id, secret, _ := r.BasicAuth(); id != "tool-1" || secret != "s3cret" {
+			if r.PostFormValue("client_id") != "tool-1" || r.PostFormValue("client_secret") != "s3cret" {
+				http.Error(w, "bad client", http.StatusUnauthorized)
+				return
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"access_token":"tok","token_type":"Bearer","expires_in":3600}`))
+	})
+	mux.HandleFunc("/lineitems/1/scores", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer tok" {
+			http.Error(w, "no token", http.StatusUnauthorized)
+			return
+		}
+		var s map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&s)
+		scores = append(scores, s)
+	})
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	for _, q := range []string{
+		`INSERT INTO lti_platforms (issuer, client_id, token_url, jwks_url, auth_url) VALUES ('https://lms.example','tool-1','` + ts.URL + `/token','x','x')`,
+		`INSERT INTO lti_user_map (platform_issuer, platform_sub, local_user_id) VALUES ('https://lms.example','sub-9','u-1')`,
+		`INSERT INTO gradebook_lineitems (exam_id, platform_issuer, deployment_id, context_id, resource_link_id, label, score_max, line_item_url)
+		 VALUES ('ex-1','https://lms.example','d','c','rl','Quiz',10,'` + ts.URL + `/lineitems/1')`,
+	} {
+		if _, err := dbh.Exec(q); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	pb := &lti.Passback{DB: dbh, ClientSecret: "s3cret"}
+	ctx := context.Background()
+	if err := pb.Deliver(ctx, exam.PassbackIntent{AttemptID: "a1", ExamID: "ex-1", UserID: "u-1", Score: 7.5}); err != nil {
+		t.Fatal(err)
+	}
+	if len(scores) != 1 || scores[0]["userId"] != "sub-9" || scores[0]["scoreGiven"] != 7.5 || scores[0]["scoreMaximum"] != 10.0 {
+		t.Fatalf("scores posted = %v", scores)
+	}
+
+	// No line item for the exam: an error, so the worker retries later.
+	err := pb.Deliver(ctx, exam.PassbackIntent{AttemptID: "a2", ExamID: "ex-2", UserID: "u-1", Score: 1})
+	if err == nil || !strings.Contains(err.Error(), "no line item") {
+		t.Fatalf("unregistered exam: err = %v", err)
+	}
+	if len(scores) != 1 {
+		t.Fatalf("posted %d scores, want 1", len(scores))
+	}
+}
@@ -0,0 +1,184 @@
+// internal/metrics/metrics.go
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"github.com/mind-engage/mindengage-lms/internal/exam"
+	"github.com/mind-engage/mindengage-lms/internal/grading"
+)
+
+/*
+Package metrics exposes Prometheus metrics for the gateway:
+
+	mindengage_attempts_created_total
+	mindengage_attempts_submitted_total
+	mindengage_grading_duration_seconds{type}
+	mindengage_ags_passback_total{result="success|failure"}
+	mindengage_http_request_duration_seconds{route,method,status}
+
+Collectors register on an injected prometheus.Registerer so tests can use a
+private registry. Attempt, grading and passback metrics are collected by
+decorating the exam.Store, grading.Grader and outbox Deliver func rather than
+touching the handlers.
+
+Wiring:
+
+	reg := prometheus.NewRegistry()
+	m := metrics.New(reg)
+	store = metrics.Store(store, m)
+	grader = metrics.Grader(grader, m)
+	outbox.Deliver = metrics.Passback(outbox.Deliver, m)
+	r.Use(m.Middleware)
+	r.Handle("/metrics", metrics.Handler(reg))
+*/
+
+// Metrics holds the gateway collectors.
+type Metrics struct {
+	AttemptsCreated   prometheus.Counter
+	AttemptsSubmitted prometheus.Counter
+	GradingDuration   *prometheus.HistogramVec
+	AGSPassback       *prometheus.CounterVec
+	HTTPDuration      *prometheus.HistogramVec
+}
+
+// New creates the collectors and registers them on reg.
+func New(reg prometheus.Registerer) *Metrics {
+	m := &Metrics{
+		AttemptsCreated: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: "mindengage",
+			Name:      "attempts_created_total",
+			Help:      "Attempts started.",
+		}),
+		AttemptsSubmitted: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: "mindengage",
+			Name:      "attempts_submitted_total",
+			Help:      "Attempts finalized by submit (re-submits not counted).",
+		}),
+		GradingDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "mindengage",
+			Name:      "grading_duration_seconds",
+			Help:      "Time spent grading a single question response.",
+			Buckets:   []float64{.0005, .001, .005, .01, .05, .1, .5, 1, 5},
+		}, []string{"type"}),
+		AGSPassback: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "mindengage",
+			Name:      "ags_passback_total",
+			Help:      "AGS score passbacks by result.",
+		}, []string{"result"}),
+		HTTPDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "mindengage",
+			Name:      "http_request_duration_seconds",
+			Help:      "HTTP request latency by matched route.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"route", "method", "status"}),
+	}
+	reg.MustRegister(m.AttemptsCreated, m.AttemptsSubmitted, m.GradingDuration, m.AGSPassback, m.HTTPDuration)
+	return m
+}
+
+// Handler serves the /metrics endpoint for g.
+func Handler(g prometheus.Gatherer) http.Handler {
+	return promhttp.HandlerFor(g, promhttp.HandlerOpts{})
+}
+
+// Middleware records request duration by chi route pattern (not raw path,
+// which would explode label cardinality).
+func (m *Metrics) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		next.ServeHTTP(ww, r)
+
+		route := "unmatched"
+		if rc := chi.RouteContext(r.Context()); rc != nil && rc.RoutePattern() != "" {
+			route = rc.RoutePattern()
+		}
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+		m.HTTPDuration.WithLabelValues(route, r.Method, strconv.Itoa(status)).Observe(time.Since(start).Seconds())
+	})
+}
+
+// ObservePassback counts an AGS score passback outcome; Passback calls it
+// for every outbox delivery.
+func (m *Metrics) ObservePassback(err error) {
+	if err != nil {
+		m.AGSPassback.WithLabelValues("failure").Inc()
+		return
+	}
+	m.AGSPassback.WithLabelValues("success").Inc()
+}
+
+// ObserveSubmitted counts a finalized attempt. It matches the exam
+// SQLStore.OnSubmitted hook, which skips idempotent re-submits.
+func (m *Metrics) ObserveSubmitted(context.Context, exam.Attempt) {
+	m.AttemptsSubmitted.Inc()
+}
+
+/* ---------------------------- Decorators ---------------------------- */
+
+type store struct {
+	exam.Store
+	m *Metrics
+}
+
+// Store wraps s so attempts created by NewAttempt and StartAttempt are
+// counted (a resumed attempt is not). Submissions are counted through
+// ObserveSubmitted instead: a successful Submit may be a no-op re-submit.
+func Store(s exam.Store, m *Metrics) exam.Store {
+	return &store{Store: s, m: m}
+}
+
+func (s *store) NewAttempt(ctx context.Context, examID, userID string) (exam.Attempt, error) {
+	a, err := s.Store.NewAttempt(ctx, examID, userID)
+	if err == nil {
+		s.m.AttemptsCreated.Inc()
+	}
+	return a, err
+}
+
+func (s *store) StartAttempt(ctx context.Context, opts exam.StartOpts) (exam.Attempt, bool, error) {
+	a, created, err := s.Store.StartAttempt(ctx, opts)
+	if err == nil && created {
+		s.m.AttemptsCreated.Inc()
+	}
+	return a, created, err
+}
+
+type grader struct {
+	grading.Grader
+	m *Metrics
+}
+
+// Passback wraps an exam.OutboxWorker Deliver func so each delivery is
+// counted as a passback success or failure.
+func Passback(deliver func(context.Context, exam.PassbackIntent) error, m *Metrics) func(context.Context, exam.PassbackIntent) error {
+	return func(ctx context.Context, p exam.PassbackIntent) error {
+		err := deliver(ctx, p)
+		m.ObservePassback(err)
+		return err
+	}
+}
+
+// Grader wraps g so each Grade call is timed by question type.
+func Grader(g grading.Grader, m *Metrics) grading.Grader {
+	return &grader{Grader: g, m: m}
+}
+
+func (g *grader) Grade(ctx context.Context, q grading.Q, response interface{}) (grading.Result, error) {
+	start := time.Now()
+	res, err := g.Grader.Grade(ctx, q, response)
+	g.m.GradingDuration.WithLabelValues(q.Type).Observe(time.Since(start).Seconds())
+	return res, err
+}
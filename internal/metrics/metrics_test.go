@@ -0,0 +1,104 @@
+package metrics_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/mind-engage/mindengage-lms/internal/db"
+	"github.com/mind-engage/mindengage-lms/internal/exam"
+	"github.com/mind-engage/mindengage-lms/internal/grading"
+	"github.com/mind-engage/mindengage-lms/internal/metrics"
+)
+
+func TestObserveSubmitted_CountsFinalizeOnly(t *testing.T) {
+	ctx := context.Background()
+	dbh, err := db.Open(ctx, db.DriverSQLite, "file:"+filepath.Join(t.TempDir(), "m.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = dbh.Close() })
+	m := metrics.New(prometheus.NewRegistry())
+	sqlStore := exam.NewSQLStore(dbh, string(db.DriverSQLite), grading.NewDefaultGrader())
+	sqlStore.OnSubmitted = m.ObserveSubmitted
+	s := metrics.Store(sqlStore, m)
+	if err := s.PutExam(exam.Exam{
+		ID:        "ex-1",
+		Questions: []exam.Question{{ID: "q1", Type: "mcq_single", Points: 1, AnswerKey: []string{"a"}}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	a, err := s.NewAttempt(ctx, "ex-1", "u-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, err := s.Submit(ctx, a.ID); err != nil {
+			t.Fatalf("submit %d: %v", i, err)
+		}
+	}
+	if got := testutil.ToFloat64(m.AttemptsSubmitted); got != 1 {
+		t.Fatalf("attempts_submitted_total = %v, want 1 (re-submit not counted)", got)
+	}
+	if got := testutil.ToFloat64(m.AttemptsCreated); got != 1 {
+		t.Fatalf("attempts_created_total = %v, want 1", got)
+	}
+}
+
+func TestMiddleware_RouteLabelAndHandler(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	m := metrics.New(reg)
+
+	r := chi.NewRouter()
+	r.Use(m.Middleware)
+	r.Get("/api/attempts/{attemptID}", func(w http.ResponseWriter, r *http.Request) {})
+	r.Handle("/metrics", metrics.Handler(reg))
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/attempts/a-1", nil))
+	m.ObservePassback(nil)
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body := rec.Body.String()
+	for _, want := range []string{
+		`mindengage_http_request_duration_seconds_count{method="GET",route="/api/attempts/{attemptID}",status="200"} 1`,
+		`mindengage_ags_passback_total{result="success"} 1`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("metrics output missing %q", want)
+		}
+	}
+}
+
+func TestPassback_CountsDeliveryOutcomes(t *testing.T) {
+	m := metrics.New(prometheus.NewRegistry())
+	fail := errors.New("platform down")
+	results := []error{nil, fail, nil}
+	deliver := metrics.Passback(func(ctx context.Context, p exam.PassbackIntent) error {
+		err := results[0]
+		results = results[1:]
+		return err
+	}, m)
+
+	for i := 0; i < 3; i++ {
+		err := deliver(context.Background(), exam.PassbackIntent{AttemptID: "a-1"})
+		if i == 1 && !errors.Is(err, fail) {
+			t.Fatalf("delivery %d err = %v, want %v", i, err, fail)
+		}
+	}
+	if got := testutil.ToFloat64(m.AGSPassback.WithLabelValues("success")); got != 2 {
+		t.Fatalf("passback success = %v, want 2", got)
+	}
+	if got := testutil.ToFloat64(m.AGSPassback.WithLabelValues("failure")); got != 1 {
+		t.Fatalf("passback failure = %v, want 1", got)
+	}
+}
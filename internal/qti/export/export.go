@@ -6,6 +6,7 @@ import (
 	"encoding/xml"
 	"fmt"
 	"io"
+	"sort"
 	"strings"
 
 	"github.com/mind-engage/mindengage-lms/internal/exam"
@@ -33,6 +34,18 @@ func BuildPackage(ex exam.Exam, fetchMedia func(path string) (io.ReadCloser, err
 		w, _ := zw.Create(itemName)
 		io.WriteString(w, buildItemXML(q))
 	}
+	// multi-module exams also get an assessmentTest describing sections/timing
+	if test, ok := buildTestXML(ex); ok {
+		mf.Resources = append([]imsResource{{
+			Identifier: ex.ID,
+			Type:       "imsqti_test_xmlv2p1",
+			Href:       testFileName,
+			Files:      []imsFile{{Href: testFileName}},
+		}}, mf.Resources...)
+		w, _ := zw.Create(testFileName)
+		io.WriteString(w, test)
+	}
+
 	// write manifest
 	mfw, _ := zw.Create("imsmanifest.xml")
 	b, _ := xml.MarshalIndent(mf, "", "  ")
@@ -77,39 +90,39 @@ func buildItemXML(q exam.Question) string {
 		}
 		var correct strings.Builder
 		for _, v := range q.AnswerKey {
-			correct.WriteString(fmt.Sprintf("<value>%s</value>", v))
+			correct.WriteString(fmt.Sprintf("<value>%s</value>", escapeXML(v)))
 		}
 		return fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
 <assessmentItem identifier="%s" title="%s" xmlns="http://www.imsglobal.org/xsd/imsqti_v2p1">
   <responseDeclaration identifier="RESPONSE" cardinality="%s">
-    <correctResponse>%s</correctResponse>
+    <correctResponse>%s</correctResponse>%s
   </responseDeclaration>
   <itemBody>
     %s
     <choiceInteraction responseIdentifier="RESPONSE" maxChoices="%d">
       %s
     </choiceInteraction>
-  </itemBody>
+  </itemBody>%s
 </assessmentItem>`,
-			q.ID, q.ID, card, correct.String(), q.PromptHTML, maxChoices(card), choices.String(),
+			q.ID, q.ID, card, correct.String(), mappingXML(q), q.PromptHTML, maxChoices(card), choices.String(), responseProcessingXML(q),
 		)
 	case "short_word":
 		// treat as textEntry
 		var correct strings.Builder
 		for _, v := range q.AnswerKey {
-			correct.WriteString(fmt.Sprintf("<value>%s</value>", v))
+			correct.WriteString(fmt.Sprintf("<value>%s</value>", escapeXML(v)))
 		}
 		return fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
 <assessmentItem identifier="%s" title="%s" xmlns="http://www.imsglobal.org/xsd/imsqti_v2p1">
   <responseDeclaration identifier="RESPONSE" cardinality="single">
-    <correctResponse>%s</correctResponse>
+    <correctResponse>%s</correctResponse>%s
   </responseDeclaration>
   <itemBody>
     %s
     <textEntryInteraction responseIdentifier="RESPONSE"/>
-  </itemBody>
+  </itemBody>%s
 </assessmentItem>`,
-			q.ID, q.ID, correct.String(), q.PromptHTML,
+			q.ID, q.ID, correct.String(), mappingXML(q), q.PromptHTML, responseProcessingXML(q),
 		)
 	default: // essay
 		return fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
@@ -127,3 +140,48 @@ func maxChoices(card string) int {
 	}
 	return 1
 }
+
+const rpTemplates = "http://www.imsglobal.org/question/qti_v2p1/rptemplates/"
+
+// mappingXML renders "map" scoring weights as a QTI <mapping>.
+func mappingXML(q exam.Question) string {
+	if q.Scoring == nil || q.Scoring.Mode != "map" || len(q.Scoring.Weights) == 0 {
+		return ""
+	}
+	keys := make([]string, 0, len(q.Scoring.Weights))
+	for k := range q.Scoring.Weights {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var b strings.Builder
+	fmt.Fprintf(&b, "\n    <mapping defaultValue=\"%g\" upperBound=\"%g\">", q.Scoring.DefaultWeight, q.Points)
+	for _, k := range keys {
+		fmt.Fprintf(&b, `<mapEntry mapKey="%s" mappedValue="%g"/>`, escapeXML(k), q.Scoring.Weights[k])
+	}
+	b.WriteString("</mapping>")
+	return b.String()
+}
+
+// escapeXML escapes s for use as XML text or a quoted attribute value;
+// short_word keys are free text ("a<b", "R&D").
+func escapeXML(s string) string {
+	var b strings.Builder
+	_ = xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+// responseProcessingXML names the standard template matching q's scoring.
+func responseProcessingXML(q exam.Question) string {
+	if q.Scoring == nil {
+		return ""
+	}
+	switch q.Scoring.Mode {
+	case "map":
+		if len(q.Scoring.Weights) > 0 {
+			return "\n  <responseProcessing template=\"" + rpTemplates + "map_response\"/>"
+		}
+	case "exact":
+		return "\n  <responseProcessing template=\"" + rpTemplates + "match_correct\"/>"
+	}
+	return ""
+}
@@ -0,0 +1,123 @@
+package export
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"strings"
+
+	"github.com/mind-engage/mindengage-lms/internal/exam"
+)
+
+const testFileName = "assessmentTest.xml"
+
+// policy sections/modules as stored in exam.PolicyRaw
+type examPolicy struct {
+	Sections []struct {
+		ID      string `json:"id"`
+		Title   string `json:"title"`
+		Modules []struct {
+			ID           string `json:"id"`
+			TimeLimitSec int    `json:"time_limit_sec"`
+		} `json:"modules"`
+	} `json:"sections"`
+	Navigation struct {
+		AllowBack *bool `json:"allow_back"`
+	} `json:"navigation"`
+}
+
+// --- mini XML model for assessmentTest (export only) ---
+type xmlTest struct {
+	XMLName    xml.Name       `xml:"assessmentTest"`
+	Xmlns      string         `xml:"xmlns,attr"`
+	Identifier string         `xml:"identifier,attr"`
+	Title      string         `xml:"title,attr"`
+	TimeLimits *xmlTimeLimits `xml:"timeLimits,omitempty"`
+	Parts      []xmlTestPart  `xml:"testPart"`
+}
+type xmlTestPart struct {
+	Identifier     string       `xml:"identifier,attr"`
+	NavigationMode string       `xml:"navigationMode,attr"`
+	SubmissionMode string       `xml:"submissionMode,attr"`
+	Sections       []xmlSection `xml:"assessmentSection"`
+}
+type xmlSection struct {
+	Identifier string         `xml:"identifier,attr"`
+	Title      string         `xml:"title,attr"`
+	Visible    bool           `xml:"visible,attr"`
+	TimeLimits *xmlTimeLimits `xml:"timeLimits,omitempty"`
+	ItemRefs   []xmlItemRef   `xml:"assessmentItemRef"`
+}
+type xmlItemRef struct {
+	Identifier string `xml:"identifier,attr"`
+	Href       string `xml:"href,attr"`
+}
+type xmlTimeLimits struct {
+	MaxTime int `xml:"maxTime,attr"`
+}
+
+// buildTestXML renders the policy's sections as testParts and modules as
+// assessmentSections. ok is false when the exam has no sections policy.
+// Questions not assigned to a known module go into a trailing testPart.
+func buildTestXML(ex exam.Exam) (string, bool) {
+	if len(ex.PolicyRaw) == 0 {
+		return "", false
+	}
+	var pol examPolicy
+	if err := json.Unmarshal(ex.PolicyRaw, &pol); err != nil || len(pol.Sections) == 0 {
+		return "", false
+	}
+	nav := "nonlinear"
+	if pol.Navigation.AllowBack != nil && !*pol.Navigation.AllowBack {
+		nav = "linear"
+	}
+
+	byModule := map[string][]xmlItemRef{}
+	for _, q := range ex.Questions {
+		byModule[q.ModuleID] = append(byModule[q.ModuleID], xmlItemRef{Identifier: q.ID, Href: q.ID + ".xml"})
+	}
+
+	t := xmlTest{
+		Xmlns:      "http://www.imsglobal.org/xsd/imsqti_v2p1",
+		Identifier: ex.ID,
+		Title:      ex.Title,
+	}
+	if ex.TimeLimitSec > 0 {
+		t.TimeLimits = &xmlTimeLimits{MaxTime: ex.TimeLimitSec}
+	}
+	for _, s := range pol.Sections {
+		part := xmlTestPart{Identifier: s.ID, NavigationMode: nav, SubmissionMode: "simultaneous"}
+		for _, m := range s.Modules {
+			sec := xmlSection{Identifier: m.ID, Title: s.Title, Visible: true, ItemRefs: byModule[m.ID]}
+			if m.TimeLimitSec > 0 {
+				sec.TimeLimits = &xmlTimeLimits{MaxTime: m.TimeLimitSec}
+			}
+			delete(byModule, m.ID)
+			part.Sections = append(part.Sections, sec)
+		}
+		t.Parts = append(t.Parts, part)
+	}
+	if len(byModule) > 0 {
+		var rest []xmlItemRef
+		for _, q := range ex.Questions {
+			if _, ok := byModule[q.ModuleID]; ok {
+				rest = append(rest, xmlItemRef{Identifier: q.ID, Href: q.ID + ".xml"})
+			}
+		}
+		t.Parts = append(t.Parts, xmlTestPart{
+			Identifier:     "unassigned",
+			NavigationMode: nav,
+			SubmissionMode: "simultaneous",
+			Sections:       []xmlSection{{Identifier: "unassigned-m1", Visible: true, ItemRefs: rest}},
+		})
+	}
+
+	b, err := xml.MarshalIndent(t, "", "  ")
+	if err != nil {
+		return "", false
+	}
+	var out strings.Builder
+	fmt.Fprint(&out, xml.Header)
+	out.Write(b)
+	return out.String(), true
+}
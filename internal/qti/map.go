@@ -1,12 +1,14 @@
 package qti
 
 import (
+	"encoding/json"
 	"fmt"
 	"html"
 	"path/filepath"
 	"strings"
 
 	"github.com/mind-engage/mindengage-lms/internal/exam"
+	"github.com/mind-engage/mindengage-lms/internal/formats"
 	"github.com/mind-engage/mindengage-lms/internal/qti/parser"
 )
 
@@ -31,6 +33,10 @@ func MapToExam(mf parser.Manifest, items []parser.ParsedItem, rewriteMedia func(
 		for _, c := range it.Choices {
 			choices = append(choices, exam.Choice{ID: c.ID, LabelHTML: c.Label})
 		}
+		var sc *exam.Scoring
+		if it.Scoring != parser.ScoringDefault {
+			sc = &exam.Scoring{Mode: string(it.Scoring), Weights: it.Weights, DefaultWeight: it.DefaultWeight}
+		}
 		q = append(q, exam.Question{
 			ID:         it.ID,
 			Type:       t,
@@ -38,6 +44,7 @@ func MapToExam(mf parser.Manifest, items []parser.ParsedItem, rewriteMedia func(
 			Choices:    choices,
 			AnswerKey:  it.AnswerKey,
 			Points:     it.Points,
+			Scoring:    sc,
 		})
 	}
 	return exam.Exam{
@@ -48,6 +55,121 @@ func MapToExam(mf parser.Manifest, items []parser.ParsedItem, rewriteMedia func(
 	}, nil
 }
 
+// MapTestToExam maps items the same way as MapToExam, then orders them by the
+// assessmentTest and builds the sections/modules policy: each testPart becomes
+// a section and each of its top-level assessmentSections a timed module.
+// Items the test does not reference are appended unassigned.
+func MapTestToExam(mf parser.Manifest, t parser.ParsedTest, items []parser.ParsedItem, rewriteMedia func(htmlIn string) string) (exam.Exam, error) {
+	ex, err := MapToExam(mf, items, rewriteMedia)
+	if err != nil {
+		return exam.Exam{}, err
+	}
+	byHref := make(map[string]int, len(items))
+	byID := make(map[string]int, len(items))
+	for i, it := range items {
+		byHref[it.Href] = i
+		byID[it.ID] = i
+	}
+
+	var pol testPolicy
+	pol.Navigation.AllowBack = true
+	used := make([]bool, len(items))
+	ordered := make([]exam.Question, 0, len(items))
+	total := 0
+	for pi, p := range t.Parts {
+		sec := formats.Section{ID: p.ID}
+		if sec.ID == "" {
+			sec.ID = fmt.Sprintf("part-%d", pi+1)
+		}
+		if p.Linear {
+			pol.Navigation.AllowBack = false
+		}
+		for si, s := range p.Sections {
+			mod := formats.Module{ID: s.ID, TimeLimitSec: s.TimeLimitSec}
+			if mod.ID == "" {
+				mod.ID = fmt.Sprintf("%s-m%d", sec.ID, si+1)
+			}
+			if mod.TimeLimitSec == 0 && len(p.Sections) == 1 {
+				mod.TimeLimitSec = p.TimeLimitSec
+			}
+			if sec.Title == "" {
+				sec.Title = s.Title
+			}
+			total += mod.TimeLimitSec
+			for _, ref := range s.Items {
+				i, ok := byHref[ref.Href]
+				if !ok {
+					i, ok = byID[ref.ID]
+				}
+				if !ok || used[i] {
+					continue
+				}
+				used[i] = true
+				q := ex.Questions[i]
+				q.SectionID, q.ModuleID = sec.ID, mod.ID
+				ordered = append(ordered, q)
+			}
+			sec.Modules = append(sec.Modules, mod)
+		}
+		pol.Sections = append(pol.Sections, sec)
+	}
+	for i, q := range ex.Questions {
+		if !used[i] {
+			ordered = append(ordered, q)
+		}
+	}
+	ex.Questions = ordered
+
+	// Only lock modules when there is more than one to move between.
+	nMods := 0
+	for _, s := range pol.Sections {
+		nMods += len(s.Modules)
+	}
+	pol.Navigation.ModuleLocked = !pol.Navigation.AllowBack && nMods > 1
+	raw, err := json.Marshal(pol)
+	if err != nil {
+		return exam.Exam{}, err
+	}
+	ex.PolicyRaw = raw
+
+	switch {
+	case t.TimeLimitSec > 0:
+		ex.TimeLimitSec = t.TimeLimitSec
+	case total > 0:
+		ex.TimeLimitSec = total
+	}
+	retitle(&ex, t.Title)
+	return ex, nil
+}
+
+// MapCanvasToExam maps a Canvas quiz; the quiz title names the exam.
+func MapCanvasToExam(mf parser.Manifest, quiz parser.CanvasQuiz, rewriteMedia func(htmlIn string) string) (exam.Exam, error) {
+	ex, err := MapToExam(mf, quiz.Items, rewriteMedia)
+	if err != nil {
+		return exam.Exam{}, err
+	}
+	retitle(&ex, quiz.Title)
+	return ex, nil
+}
+
+// retitle names the exam (and derives its id) from a package-supplied title.
+func retitle(ex *exam.Exam, title string) {
+	if title = strings.TrimSpace(title); title != "" {
+		ex.Title = title
+		ex.ID = "exam-" + slug(title)
+	}
+}
+
+// testPolicy is the subset of formats.Policy an assessmentTest can express.
+// Navigation is spelled out because allow_back defaults to true when absent.
+type testPolicy struct {
+	Sections   []formats.Section `json:"sections"`
+	Navigation struct {
+		AllowBack    bool `json:"allow_back"`
+		ModuleLocked bool `json:"module_locked"`
+	} `json:"navigation"`
+}
+
 func titleFromManifest(m parser.Manifest) string {
 	for _, r := range m.Resources {
 		base := filepath.Base(r.Href)
@@ -58,7 +180,11 @@ func titleFromManifest(m parser.Manifest) string {
 	return "Imported Exam"
 }
 func safeIDFromTitle(m parser.Manifest, prefix string) string {
-	t := strings.ToLower(titleFromManifest(m))
+	return fmt.Sprintf("%s-%s", prefix, slug(titleFromManifest(m)))
+}
+
+func slug(title string) string {
+	t := strings.ToLower(title)
 	t = strings.Map(func(r rune) rune {
 		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
 			return r
@@ -71,7 +197,7 @@ func safeIDFromTitle(m parser.Manifest, prefix string) string {
 	if t == "" {
 		t = "exam"
 	}
-	return fmt.Sprintf("%s-%s", prefix, t)
+	return t
 }
 
 // Basic media rewriter default (no-op)
@@ -0,0 +1,93 @@
+package qti
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/mind-engage/mindengage-lms/internal/exam"
+	"github.com/mind-engage/mindengage-lms/internal/qti/parser"
+)
+
+// AssetResolver stores a package-relative media file and returns the URL the
+// stored question should reference instead.
+type AssetResolver func(rel string) (string, error)
+
+var mediaAttrRe = regexp.MustCompile(`(?i)(\s(?:src|href|data)\s*=\s*)("[^"]*"|'[^']*')`)
+
+// RewriteMedia points relative src/href/data attributes in the exam's prompts
+// and choice labels at resolved asset URLs. Paths are relative to the item
+// file the question came from. References that cannot be resolved are left
+// untouched and reported once each.
+func RewriteMedia(ex *exam.Exam, items []parser.ParsedItem, resolve AssetResolver) []string {
+	itemDir := make(map[string]string, len(items))
+	for _, it := range items {
+		itemDir[it.ID] = path.Dir(it.Href)
+	}
+
+	var warnings []string
+	done := map[string]string{} // rel -> url ("" if unresolved)
+	rewrite := func(dir, in string) string {
+		return mediaAttrRe.ReplaceAllStringFunc(in, func(m string) string {
+			sub := mediaAttrRe.FindStringSubmatch(m)
+			quote, val := sub[2][:1], sub[2][1:len(sub[2])-1]
+			if isExternalRef(val) {
+				return m
+			}
+			ref := stripQuery(val)
+			if dec, err := url.PathUnescape(ref); err == nil {
+				ref = dec // src is a URL; package paths are not escaped
+			}
+			rel := parser.CleanHref(dir, ref)
+			u, seen := done[rel]
+			if !seen {
+				if rel == ".." || strings.HasPrefix(rel, "../") {
+					warnings = append(warnings, fmt.Sprintf("media %s: outside the package", val))
+				} else if got, err := resolve(rel); err != nil {
+					warnings = append(warnings, fmt.Sprintf("media %s: %v", rel, err))
+				} else {
+					u = got
+				}
+				done[rel] = u
+			}
+			if u == "" {
+				return m
+			}
+			return sub[1] + quote + u + quote
+		})
+	}
+
+	for i := range ex.Questions {
+		q := &ex.Questions[i]
+		dir, ok := itemDir[q.ID]
+		if !ok {
+			dir = "."
+		}
+		q.PromptHTML = rewrite(dir, q.PromptHTML)
+		for j := range q.Choices {
+			q.Choices[j].LabelHTML = rewrite(dir, q.Choices[j].LabelHTML)
+		}
+	}
+	return warnings
+}
+
+// absolute paths, other hosts, schemes (http:, data:, mailto:) and fragments
+func isExternalRef(v string) bool {
+	v = strings.TrimSpace(v)
+	if v == "" || strings.HasPrefix(v, "#") || strings.HasPrefix(v, "/") {
+		return true
+	}
+	if i := strings.Index(v, ":"); i >= 0 && !strings.Contains(v[:i], "/") {
+		return true
+	}
+	return false
+}
+
+func stripQuery(v string) string {
+	if i := strings.IndexAny(v, "?#"); i >= 0 {
+		return v[:i]
+	}
+	return v
+}
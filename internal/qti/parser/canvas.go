@@ -0,0 +1,323 @@
+package parser
+
+import (
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// Canvas quiz exports are QTI 1.2 (<questestinterop>) rather than 2.x, with a
+// few habits of their own:
+//   - one file holds the whole quiz (assessment > section > item)
+//   - the question type and points live in itemmetadata fields
+//     (question_type/points_possible, or qmd_itemtype/qmd_weighting in older
+//     exports)
+//   - text comes in <mattext>, sometimes misspelt (<matextmat>), with the HTML
+//     entity-escaped
+//   - manifest resources carry no href, only <file> children
+//   - media paths are prefixed with $IMS-CC-FILEBASE$ (the web_resources dir)
+
+type canvasQTI struct {
+	XMLName     xml.Name          `xml:"questestinterop"`
+	Assessments []canvasAssessEl  `xml:"assessment"`
+	Items       []canvasItemEl    `xml:"item"` // bare item bank exports
+	Sections    []canvasSectionEl `xml:"section"`
+}
+type canvasAssessEl struct {
+	Ident    string            `xml:"ident,attr"`
+	Title    string            `xml:"title,attr"`
+	Sections []canvasSectionEl `xml:"section"`
+}
+type canvasSectionEl struct {
+	Sections []canvasSectionEl `xml:"section"` // question groups nest sections
+	Items    []canvasItemEl    `xml:"item"`
+}
+type canvasItemEl struct {
+	Ident    string         `xml:"ident,attr"`
+	Title    string         `xml:"title,attr"`
+	Meta     []canvasField  `xml:"itemmetadata>qtimetadata>qtimetadatafield"`
+	Material []canvasMatEl  `xml:"presentation>material"`
+	Lids     []canvasLidEl  `xml:"presentation>response_lid"`
+	Strs     []canvasStrEl  `xml:"presentation>response_str"`
+	Conds    []canvasCondEl `xml:"resprocessing>respcondition"`
+	FlowMat  []canvasMatEl  `xml:"presentation>flow>material"` // flow-wrapped variants
+	FlowLids []canvasLidEl  `xml:"presentation>flow>response_lid"`
+}
+type canvasField struct {
+	Label string `xml:"fieldlabel"`
+	Entry string `xml:"fieldentry"`
+}
+type canvasMatEl struct {
+	// any text-ish child: mattext, matextmat, ...
+	Parts []struct {
+		XMLName xml.Name
+		Text    string `xml:",chardata"`
+	} `xml:",any"`
+}
+type canvasLidEl struct {
+	Ident       string `xml:"ident,attr"`
+	Cardinality string `xml:"rcardinality,attr"` // Single|Multiple
+	Labels      []struct {
+		Ident    string        `xml:"ident,attr"`
+		Material []canvasMatEl `xml:"material"`
+	} `xml:"render_choice>response_label"`
+}
+type canvasStrEl struct {
+	Ident string `xml:"ident,attr"`
+}
+type canvasCondEl struct {
+	Cond struct {
+		Inner string `xml:",innerxml"`
+	} `xml:"conditionvar"`
+	SetVars []struct {
+		Action string `xml:"action,attr"`
+		Value  string `xml:",chardata"`
+	} `xml:"setvar"`
+}
+
+// CanvasQuiz is a parsed Canvas QTI 1.2 file.
+type CanvasQuiz struct {
+	Title string
+	Items []ParsedItem
+}
+
+// ParseCanvasFile reads a Canvas QTI 1.2 quiz file. Items that cannot be
+// mapped are imported as essays with a warning rather than dropped.
+func ParseCanvasFile(baseDir, rel string) (CanvasQuiz, error) {
+	p, err := PackagePath(baseDir, rel)
+	if err != nil {
+		return CanvasQuiz{}, err
+	}
+	b, err := os.ReadFile(p)
+	if err != nil {
+		return CanvasQuiz{}, err
+	}
+	var doc canvasQTI
+	if err := xml.Unmarshal(b, &doc); err != nil {
+		return CanvasQuiz{}, err
+	}
+
+	var quiz CanvasQuiz
+	var items []canvasItemEl
+	items = append(items, doc.Items...)
+	collectCanvasItems(doc.Sections, &items)
+	for _, a := range doc.Assessments {
+		if quiz.Title == "" {
+			quiz.Title = strings.TrimSpace(a.Title)
+		}
+		collectCanvasItems(a.Sections, &items)
+	}
+
+	// $IMS-CC-FILEBASE$ is the package's web_resources dir; make it relative
+	// to this file so media resolution treats it like any other src.
+	href := CleanHref(".", rel)
+	up := strings.Repeat("../", strings.Count(href, "/"))
+	fileBase := strings.NewReplacer("$IMS-CC-FILEBASE$", up+"web_resources", "%24IMS-CC-FILEBASE%24", up+"web_resources")
+
+	for _, it := range items {
+		pi := parseCanvasItem(it)
+		pi.Href = href
+		pi.PromptHTML = fileBase.Replace(pi.PromptHTML)
+		for i := range pi.Choices {
+			pi.Choices[i].Label = fileBase.Replace(pi.Choices[i].Label)
+		}
+		quiz.Items = append(quiz.Items, pi)
+	}
+	return quiz, nil
+}
+
+func collectCanvasItems(secs []canvasSectionEl, out *[]canvasItemEl) {
+	for _, s := range secs {
+		*out = append(*out, s.Items...)
+		collectCanvasItems(s.Sections, out)
+	}
+}
+
+func parseCanvasItem(it canvasItemEl) ParsedItem {
+	meta := map[string]string{}
+	for _, f := range it.Meta {
+		meta[strings.ToLower(strings.TrimSpace(f.Label))] = strings.TrimSpace(f.Entry)
+	}
+	pi := ParsedItem{ID: it.Ident, Title: it.Title, Points: 1}
+	for _, k := range []string{"points_possible", "qmd_weighting"} {
+		if p, err := strconv.ParseFloat(meta[k], 64); err == nil && p > 0 {
+			pi.Points = p
+			break
+		}
+	}
+
+	mats := append(append([]canvasMatEl{}, it.Material...), it.FlowMat...)
+	if len(mats) > 0 {
+		pi.PromptHTML = canvasText(mats[0])
+	}
+	lids := append(append([]canvasLidEl{}, it.Lids...), it.FlowLids...)
+
+	qtype := meta["question_type"]
+	if qtype == "" {
+		qtype = canvasTypeFromQMD(meta["qmd_itemtype"])
+	}
+	switch qtype {
+	case "multiple_choice_question", "true_false_question":
+		pi.Kind = InteractionChoiceSingle
+	case "multiple_answers_question":
+		pi.Kind = InteractionChoiceMulti
+	case "short_answer_question":
+		pi.Kind = InteractionTextEntry
+	case "essay_question":
+		pi.Kind = InteractionExtendedText
+	case "":
+		// no metadata: infer from the response element
+		switch {
+		case len(lids) > 0 && strings.EqualFold(lids[0].Cardinality, "multiple"):
+			pi.Kind = InteractionChoiceMulti
+		case len(lids) > 0:
+			pi.Kind = InteractionChoiceSingle
+		case len(it.Strs) > 0:
+			pi.Kind = InteractionTextEntry
+		default:
+			pi.Kind = InteractionExtendedText
+		}
+	default:
+		pi.Kind = InteractionExtendedText
+		pi.Warnings = append(pi.Warnings, fmt.Sprintf("item %s: canvas question_type %q not supported; imported as essay", it.Ident, qtype))
+		return pi
+	}
+
+	if (pi.Kind == InteractionChoiceSingle || pi.Kind == InteractionChoiceMulti) && len(lids) > 0 {
+		for _, l := range lids[0].Labels {
+			label := ""
+			if len(l.Material) > 0 {
+				label = canvasText(l.Material[0])
+			}
+			pi.Choices = append(pi.Choices, Choice{ID: l.Ident, Label: label})
+		}
+	}
+	if pi.Kind != InteractionExtendedText {
+		pi.AnswerKey = canvasCorrect(it.Conds)
+		if len(pi.AnswerKey) == 0 {
+			pi.Warnings = append(pi.Warnings, fmt.Sprintf("item %s: no correct response found", it.Ident))
+		}
+	}
+	return pi
+}
+
+// canvasText joins the text-bearing children of a <material>. Canvas (and
+// tools copying it) is inconsistent about the element name, so anything
+// ending in "text" or "textmat" counts.
+func canvasText(m canvasMatEl) string {
+	var parts []string
+	for _, p := range m.Parts {
+		n := strings.ToLower(p.XMLName.Local)
+		if strings.HasSuffix(n, "text") || strings.HasSuffix(n, "textmat") {
+			if t := strings.TrimSpace(p.Text); t != "" {
+				parts = append(parts, t)
+			}
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// canvasCorrect collects the values that earn full credit: the positive
+// <varequal>s (not under <not>) of conditions that set SCORE to 100.
+func canvasCorrect(conds []canvasCondEl) []string {
+	var out []string
+	seen := map[string]bool{}
+	for _, c := range conds {
+		full := false
+		for _, sv := range c.SetVars {
+			v, err := strconv.ParseFloat(strings.TrimSpace(sv.Value), 64)
+			if err == nil && v >= 100 && !strings.EqualFold(sv.Action, "Add") {
+				full = true
+			}
+		}
+		if !full {
+			continue
+		}
+		for _, v := range positiveVarEquals(c.Cond.Inner) {
+			if !seen[v] {
+				seen[v] = true
+				out = append(out, v)
+			}
+		}
+	}
+	return out
+}
+
+func positiveVarEquals(inner string) []string {
+	var out []string
+	dec := xml.NewDecoder(strings.NewReader(inner))
+	negated := 0
+	for {
+		t, err := dec.Token()
+		if err != nil {
+			return out
+		}
+		switch se := t.(type) {
+		case xml.StartElement:
+			switch strings.ToLower(se.Name.Local) {
+			case "not":
+				negated++
+			case "varequal":
+				var v struct {
+					Text string `xml:",chardata"`
+				}
+				if err := dec.DecodeElement(&v, &se); err == nil && negated == 0 {
+					out = append(out, strings.TrimSpace(v.Text))
+				}
+			}
+		case xml.EndElement:
+			if strings.EqualFold(se.Name.Local, "not") {
+				negated--
+			}
+		}
+	}
+}
+
+// older (Respondus-style) exports name the type in qmd_itemtype
+func canvasTypeFromQMD(t string) string {
+	switch strings.ToLower(strings.TrimSpace(t)) {
+	case "multiple choice":
+		return "multiple_choice_question"
+	case "multiple response", "multiple answer":
+		return "multiple_answers_question"
+	case "true/false", "true false":
+		return "true_false_question"
+	case "fill in the blank", "short answer":
+		return "short_answer_question"
+	case "essay":
+		return "essay_question"
+	case "":
+		return ""
+	default:
+		return strings.ToLower(t)
+	}
+}
+
+// CanvasQuizFiles lists the QTI 1.2 quiz files of a Canvas manifest, taking
+// the first .xml <file> when a resource has no href and skipping
+// assessment_meta.xml.
+func CanvasQuizFiles(mf Manifest) []string {
+	var out []string
+	seen := map[string]bool{}
+	for _, r := range mf.Resources {
+		if !strings.HasPrefix(strings.ToLower(r.Type), "imsqti_xmlv1p2") {
+			continue
+		}
+		cands := append([]string{r.Href}, r.Files...)
+		for _, f := range cands {
+			lf := strings.ToLower(f)
+			if f == "" || !strings.HasSuffix(lf, ".xml") || path.Base(lf) == "assessment_meta.xml" {
+				continue
+			}
+			if !seen[f] {
+				seen[f] = true
+				out = append(out, f)
+			}
+			break
+		}
+	}
+	return out
+}
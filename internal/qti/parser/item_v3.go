@@ -2,8 +2,10 @@ package parser
 
 import (
 	"encoding/xml"
+	"fmt"
 	"os"
-	"path/filepath"
+	"path"
+	"strconv"
 	"strings"
 )
 
@@ -14,6 +16,11 @@ type assessmentItem struct {
 	Body         itemBody            `xml:"itemBody"`
 	ResponseDecl responseDeclaration `xml:"responseDeclaration"`
 	OutcomeDecl  outcomeDeclaration  `xml:"outcomeDeclaration"`
+	RespProc     *responseProcessing `xml:"responseProcessing"`
+}
+type responseProcessing struct {
+	Template string `xml:"template,attr"`
+	RawXML   string `xml:",innerxml"` // inline rules (not supported)
 }
 type itemBody struct {
 	// We extract just enough: prompt html-ish and interaction type
@@ -25,6 +32,14 @@ type responseDeclaration struct {
 	Correct     struct {
 		Values []string `xml:"value"`
 	} `xml:"correctResponse"`
+	Mapping *struct {
+		DefaultValue float64 `xml:"defaultValue,attr"`
+		UpperBound   string  `xml:"upperBound,attr"`
+		Entries      []struct {
+			Key   string  `xml:"mapKey,attr"`
+			Value float64 `xml:"mappedValue,attr"`
+		} `xml:"mapEntry"`
+	} `xml:"mapping"`
 	// numeric tolerance extension (non-standard) via mapping or <value> forms
 }
 type outcomeDeclaration struct {
@@ -43,14 +58,30 @@ const (
 
 type ParsedItem struct {
 	ID         string
+	Href       string // package-relative path of the item file
 	Title      string
 	PromptHTML string
 	Kind       InteractionType
 	Choices    []Choice // for choice
 	AnswerKey  []string // correct ids or strings
 	Points     float64
+
+	// Scoring from responseProcessing: ScoringDefault (no processing given),
+	// ScoringExact (match_correct, or fallback) or ScoringMap (map_response).
+	Scoring       ScoringMode
+	Weights       map[string]float64 // mapKey -> mappedValue (ScoringMap)
+	DefaultWeight float64
+	Warnings      []string
 }
 
+type ScoringMode string
+
+const (
+	ScoringDefault ScoringMode = ""
+	ScoringExact   ScoringMode = "exact"
+	ScoringMap     ScoringMode = "map"
+)
+
 type Choice struct {
 	ID    string
 	Label string // HTML
@@ -59,7 +90,11 @@ type Choice struct {
 // NOTE: We don't fully parse <itemBody> interactions; a robust parser is larger.
 // For MVP we infer from body content (presence of <choiceInteraction>, etc.) and extract labels heuristically.
 func ParseItemFile(baseDir, rel string) (ParsedItem, error) {
-	b, err := os.ReadFile(filepath.Join(baseDir, rel))
+	p, err := PackagePath(baseDir, rel)
+	if err != nil {
+		return ParsedItem{}, err
+	}
+	b, err := os.ReadFile(p)
 	if err != nil {
 		return ParsedItem{}, err
 	}
@@ -70,6 +105,7 @@ func ParseItemFile(baseDir, rel string) (ParsedItem, error) {
 
 	pi := ParsedItem{
 		ID:         it.Identifier,
+		Href:       CleanHref(".", rel),
 		Title:      it.Title,
 		PromptHTML: extractPrompt(it.Body.RawXML),
 		Points:     1, // default, can be extended reading outcomeDecl
@@ -94,9 +130,56 @@ func ParseItemFile(baseDir, rel string) (ParsedItem, error) {
 		// fallback: treat as extended text
 		pi.Kind = InteractionExtendedText
 	}
+	if pi.Kind != InteractionExtendedText {
+		applyResponseProcessing(&pi, it)
+	}
 	return pi, nil
 }
 
+// applyResponseProcessing maps the standard templates onto our scoring modes.
+// Anything we cannot interpret (unknown templates, inline rules) falls back to
+// exact matching with a warning.
+func applyResponseProcessing(pi *ParsedItem, it assessmentItem) {
+	rp := it.RespProc
+	if rp == nil {
+		return
+	}
+	tpl := strings.TrimSuffix(path.Base(strings.TrimSpace(rp.Template)), ".xml")
+	switch strings.ToLower(tpl) {
+	case "match_correct", "cc2_match", "cc2_match_basic":
+		pi.Scoring = ScoringExact
+	case "map_response", "cc2_map_response":
+		m := it.ResponseDecl.Mapping
+		if m == nil || len(m.Entries) == 0 {
+			pi.Scoring = ScoringExact
+			pi.Warnings = append(pi.Warnings, fmt.Sprintf("item %s: map_response without mapping; using exact match", pi.ID))
+			return
+		}
+		pi.Scoring = ScoringMap
+		pi.DefaultWeight = m.DefaultValue
+		pi.Weights = make(map[string]float64, len(m.Entries))
+		positive := 0.0
+		for _, e := range m.Entries {
+			pi.Weights[strings.TrimSpace(e.Key)] = e.Value
+			if e.Value > 0 {
+				positive += e.Value
+			}
+		}
+		// the item is worth its upper bound, else everything attainable
+		if ub, err := strconv.ParseFloat(strings.TrimSpace(m.UpperBound), 64); err == nil && ub > 0 {
+			pi.Points = ub
+		} else if positive > 0 {
+			pi.Points = positive
+		}
+	case ".", "":
+		pi.Scoring = ScoringExact
+		pi.Warnings = append(pi.Warnings, fmt.Sprintf("item %s: custom responseProcessing not supported; using exact match", pi.ID))
+	default:
+		pi.Scoring = ScoringExact
+		pi.Warnings = append(pi.Warnings, fmt.Sprintf("item %s: unsupported responseProcessing template %q; using exact match", pi.ID, tpl))
+	}
+}
+
 // --- very small HTML-ish extraction helpers (heuristic) ---
 
 func extractPrompt(inner string) string {
@@ -13,6 +13,7 @@ import (
 // Public manifest types in parser (no import of qti)
 type Manifest struct {
 	Resources []ManifestResource
+	Tests     []string // assessmentTest hrefs (kept out of the item list)
 }
 
 type ManifestResource struct {
@@ -48,6 +49,9 @@ func UnzipToTemp(r io.ReaderAt, size int64) (string, error) {
 	}
 	for _, f := range zr.File {
 		dst := filepath.Join(tmp, f.Name)
+		if !strings.HasPrefix(dst, tmp+string(os.PathSeparator)) {
+			return "", fmt.Errorf("illegal path in zip: %q", f.Name)
+		}
 		if f.FileInfo().IsDir() {
 			if err := os.MkdirAll(dst, 0o755); err != nil {
 				return "", err
@@ -75,6 +79,18 @@ func UnzipToTemp(r io.ReaderAt, size int64) (string, error) {
 	return tmp, nil
 }
 
+// PackagePath joins a manifest/test href onto the unzipped package root,
+// refusing any path that resolves outside it ("../../etc/passwd"). Hrefs
+// come from the uploaded package and are untrusted.
+func PackagePath(base, rel string) (string, error) {
+	p := filepath.Join(base, filepath.FromSlash(rel))
+	r, err := filepath.Rel(base, p)
+	if err != nil || r == ".." || strings.HasPrefix(r, ".."+string(os.PathSeparator)) {
+		return "", fmt.Errorf("path outside package: %q", rel)
+	}
+	return p, nil
+}
+
 func ParseManifest(base string) (Manifest, []string, error) {
 	paths := []string{"imsmanifest.xml", "manifest.xml"}
 	var mfPath string
@@ -110,6 +126,10 @@ func ParseManifest(base string) (Manifest, []string, error) {
 			res.Files = append(res.Files, f.Href)
 		}
 		out.Resources = append(out.Resources, res)
+		if isTestResource(r.Type) {
+			out.Tests = append(out.Tests, r.Href)
+			continue
+		}
 		if strings.HasSuffix(strings.ToLower(r.Href), ".xml") &&
 			!strings.Contains(strings.ToLower(r.Href), "manifest") {
 			items = append(items, r.Href)
@@ -117,3 +137,32 @@ func ParseManifest(base string) (Manifest, []string, error) {
 	}
 	return out, items, nil
 }
+
+// imsqti_test_xmlv2p1, imsqti_test_xmlv3p0, ...
+func isTestResource(typ string) bool {
+	return strings.HasPrefix(strings.ToLower(typ), "imsqti_test_")
+}
+
+// MediaFiles lists the non-XML files the manifest declares (images, audio,
+// ...), as cleaned package-relative paths.
+func MediaFiles(mf Manifest) []string {
+	var out []string
+	seen := map[string]bool{}
+	for _, r := range mf.Resources {
+		for _, f := range append([]string{r.Href}, r.Files...) {
+			if f == "" {
+				continue
+			}
+			f = CleanHref(".", f)
+			switch strings.ToLower(filepath.Ext(f)) {
+			case ".xml", ".qti", "":
+				continue
+			}
+			if !seen[f] {
+				seen[f] = true
+				out = append(out, f)
+			}
+		}
+	}
+	return out
+}
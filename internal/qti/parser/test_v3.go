@@ -0,0 +1,131 @@
+package parser
+
+import (
+	"encoding/xml"
+	"math"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// assessmentTest: testPart -> assessmentSection (possibly nested) -> assessmentItemRef
+type assessmentTest struct {
+	XMLName    xml.Name     `xml:"assessmentTest"`
+	Identifier string       `xml:"identifier,attr"`
+	Title      string       `xml:"title,attr"`
+	TimeLimits *timeLimits  `xml:"timeLimits"`
+	Parts      []testPartEl `xml:"testPart"`
+}
+type testPartEl struct {
+	Identifier     string      `xml:"identifier,attr"`
+	NavigationMode string      `xml:"navigationMode,attr"` // linear|nonlinear
+	TimeLimits     *timeLimits `xml:"timeLimits"`
+	Sections       []sectionEl `xml:"assessmentSection"`
+}
+type sectionEl struct {
+	Identifier string      `xml:"identifier,attr"`
+	Title      string      `xml:"title,attr"`
+	TimeLimits *timeLimits `xml:"timeLimits"`
+	Sections   []sectionEl `xml:"assessmentSection"`
+	ItemRefs   []itemRefEl `xml:"assessmentItemRef"`
+}
+type itemRefEl struct {
+	Identifier string `xml:"identifier,attr"`
+	Href       string `xml:"href,attr"`
+}
+type timeLimits struct {
+	MaxTime string `xml:"maxTime,attr"` // seconds (may be fractional)
+}
+
+// ParsedTest is the structure of a QTI assessmentTest: each testPart holds
+// top-level assessmentSections, which we treat as timed modules.
+type ParsedTest struct {
+	ID           string
+	Title        string
+	TimeLimitSec int
+	Parts        []TestPart
+}
+
+type TestPart struct {
+	ID           string
+	Linear       bool
+	TimeLimitSec int
+	Sections     []TestSection
+}
+
+type TestSection struct {
+	ID           string
+	Title        string
+	TimeLimitSec int
+	Items        []TestItemRef // nested sections are flattened in document order
+}
+
+type TestItemRef struct {
+	ID   string
+	Href string // resolved relative to the package root
+}
+
+// ParseTestFile reads an assessmentTest. Item hrefs are resolved against the
+// test file's directory so they compare equal to manifest item paths.
+func ParseTestFile(baseDir, rel string) (ParsedTest, error) {
+	p, err := PackagePath(baseDir, rel)
+	if err != nil {
+		return ParsedTest{}, err
+	}
+	b, err := os.ReadFile(p)
+	if err != nil {
+		return ParsedTest{}, err
+	}
+	var t assessmentTest
+	if err := xml.Unmarshal(b, &t); err != nil {
+		return ParsedTest{}, err
+	}
+
+	dir := path.Dir(filepath.ToSlash(rel))
+	pt := ParsedTest{
+		ID:           t.Identifier,
+		Title:        t.Title,
+		TimeLimitSec: t.TimeLimits.seconds(),
+	}
+	for _, p := range t.Parts {
+		part := TestPart{
+			ID:           p.Identifier,
+			Linear:       !strings.EqualFold(p.NavigationMode, "nonlinear"),
+			TimeLimitSec: p.TimeLimits.seconds(),
+		}
+		for _, s := range p.Sections {
+			sec := TestSection{ID: s.Identifier, Title: s.Title, TimeLimitSec: s.TimeLimits.seconds()}
+			collectItemRefs(dir, s, &sec.Items)
+			part.Sections = append(part.Sections, sec)
+		}
+		pt.Parts = append(pt.Parts, part)
+	}
+	return pt, nil
+}
+
+func collectItemRefs(dir string, s sectionEl, out *[]TestItemRef) {
+	for _, r := range s.ItemRefs {
+		*out = append(*out, TestItemRef{ID: r.Identifier, Href: CleanHref(dir, r.Href)})
+	}
+	for _, child := range s.Sections {
+		collectItemRefs(dir, child, out)
+	}
+}
+
+// CleanHref joins href onto dir (both slash-separated) and normalizes it.
+func CleanHref(dir, href string) string {
+	return path.Clean(path.Join(dir, filepath.ToSlash(strings.TrimSpace(href))))
+}
+
+func (tl *timeLimits) seconds() int {
+	if tl == nil {
+		return 0
+	}
+	f, err := strconv.ParseFloat(strings.TrimSpace(tl.MaxTime), 64)
+	if err != nil || f <= 0 {
+		return 0
+	}
+	return int(math.Round(f))
+}
@@ -0,0 +1,129 @@
+// internal/ratelimit/ratelimit.go
+package ratelimit
+
+import (
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+/*
+Package ratelimit is a small in-memory token-bucket limiter for unauthenticated
+endpoints (public offerings, anonymous grading). It is per-process: behind
+several gateway replicas each one enforces its own budget.
+
+Wiring:
+
+	lim := &ratelimit.Limiter{Rate: 1, Burst: 10}
+	r.With(lim.Middleware).Get("/public/offerings/{offeringID}", ...)
+*/
+
+// Limiter allows Burst requests at once per key, refilling at Rate per second.
+type Limiter struct {
+	Rate  float64 // tokens per second (default 1)
+	Burst int     // bucket size (default 10)
+
+	// Optional: request key (default: client IP from RemoteAddr; mount
+	// middleware.RealIP first when behind a proxy).
+	Key func(*http.Request) string
+	// Optional: clock (tests).
+	Now func() time.Time
+
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	swept   time.Time
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+func (l *Limiter) rate() float64 {
+	if l.Rate > 0 {
+		return l.Rate
+	}
+	return 1
+}
+
+func (l *Limiter) burst() float64 {
+	if l.Burst > 0 {
+		return float64(l.Burst)
+	}
+	return 10
+}
+
+func (l *Limiter) now() time.Time {
+	if l.Now != nil {
+		return l.Now()
+	}
+	return time.Now()
+}
+
+// Allow takes a token for key, reporting whether one was available.
+func (l *Limiter) Allow(key string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	if l.buckets == nil {
+		l.buckets = map[string]*bucket{}
+		l.swept = now
+	}
+	l.sweep(now)
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: l.burst(), last: now}
+		l.buckets[key] = b
+	}
+	b.tokens += now.Sub(b.last).Seconds() * l.rate()
+	if b.tokens > l.burst() {
+		b.tokens = l.burst()
+	}
+	b.last = now
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// sweep drops buckets that have refilled completely, bounding memory.
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.swept) < time.Minute {
+		return
+	}
+	full := time.Duration(l.burst() / l.rate() * float64(time.Second))
+	for k, b := range l.buckets {
+		if now.Sub(b.last) > full {
+			delete(l.buckets, k)
+		}
+	}
+	l.swept = now
+}
+
+// Middleware rejects requests over budget with 429 and a Retry-After hint.
+func (l *Limiter) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := clientIP(r)
+		if l.Key != nil {
+			key = l.Key(r)
+		}
+		if !l.Allow(key) {
+			w.Header().Set("Retry-After", strconv.Itoa(int(1/l.rate())+1))
+			http.Error(w, "too many requests", http.StatusTooManyRequests)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func clientIP(r *http.Request) string {
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+	return r.RemoteAddr
+}
@@ -0,0 +1,41 @@
+package ratelimit_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/mind-engage/mindengage-lms/internal/ratelimit"
+)
+
+func TestLimiter_BurstThenRefill(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	lim := &ratelimit.Limiter{Rate: 1, Burst: 2, Now: func() time.Time { return now }}
+	h := lim.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	hit := func(ip string) int {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = ip + ":1234"
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	for i := 0; i < 2; i++ {
+		if code := hit("10.0.0.1"); code != http.StatusOK {
+			t.Fatalf("request %d status = %d", i, code)
+		}
+	}
+	if code := hit("10.0.0.1"); code != http.StatusTooManyRequests {
+		t.Fatalf("over budget status = %d, want 429", code)
+	}
+	if code := hit("10.0.0.2"); code != http.StatusOK {
+		t.Fatalf("other client status = %d, want 200", code)
+	}
+
+	now = now.Add(time.Second)
+	if code := hit("10.0.0.1"); code != http.StatusOK {
+		t.Fatalf("after refill status = %d, want 200", code)
+	}
+}
@@ -6,6 +6,12 @@ import (
 
 var defaultChecker = NewChecker(nil)
 
+// Can reports whether role holds perm under the default policy, for handlers
+// that authorize per item rather than per route.
+func Can(role, perm string) bool {
+	return role != "" && defaultChecker.Has(role, perm)
+}
+
 // Require enforces a single permission.
 func Require(perm string) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
@@ -60,3 +66,19 @@ func RequireOwnerOr(perm string, isOwner func(r *http.Request) bool) func(http.H
 		})
 	}
 }
+
+// RequireOwnerOrNotFound is RequireOwnerOr for resources whose existence must
+// not leak: a caller who is neither the owner nor holds perm gets the same
+// 404 as for a resource that does not exist.
+func RequireOwnerOrNotFound(perm string, isOwner func(r *http.Request) bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			role := RoleFromContext(r.Context())
+			if isOwner(r) || (role != "" && defaultChecker.Has(role, perm)) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			http.Error(w, "not found", http.StatusNotFound)
+		})
+	}
+}
@@ -16,6 +16,7 @@ var RolePermissions = map[string][]string{
 		"course:manage_students",
 		"course:create_offering",
 		"course:delete_own",
+		"course:archive_own",
 		"exam:create",
 		"exam:delete_own",
 		"exam:view",
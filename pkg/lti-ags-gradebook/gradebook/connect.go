@@ -76,6 +76,38 @@ func Migrate(ctx context.Context, db *sql.DB, driver string) error {
 			}
 		}
 	}
+	return upgradeSyncStatus(ctx, db)
+}
+
+// upgradeSyncStatus brings a grade_sync_status table created by an older
+// schema (retries, no line_item_url) up to date. CREATE IF NOT EXISTS leaves
+// such tables alone, so the columns are checked here.
+func upgradeSyncStatus(ctx context.Context, db *sql.DB) error {
+	rows, err := db.QueryContext(ctx, `SELECT * FROM grade_sync_status WHERE 1=0`)
+	if err != nil {
+		return err
+	}
+	cols, err := rows.Columns()
+	_ = rows.Close()
+	if err != nil {
+		return err
+	}
+	have := map[string]bool{}
+	for _, c := range cols {
+		have[strings.ToLower(c)] = true
+	}
+	var stmts []string
+	if have["retries"] && !have["retry_count"] {
+		stmts = append(stmts, `ALTER TABLE grade_sync_status RENAME COLUMN retries TO retry_count`)
+	}
+	if !have["line_item_url"] {
+		stmts = append(stmts, `ALTER TABLE grade_sync_status ADD COLUMN line_item_url TEXT`)
+	}
+	for _, stmt := range stmts {
+		if _, err := db.ExecContext(ctx, stmt); err != nil {
+			return fmt.Errorf("migration failed at: %s\nerror: %w", stmt, err)
+		}
+	}
 	return nil
 }
 
@@ -199,11 +231,15 @@ CREATE TABLE IF NOT EXISTS gradebook_lineitems (
 -- Status for passback per attempt
 CREATE TABLE IF NOT EXISTS grade_sync_status (
   attempt_id          TEXT PRIMARY KEY, -- align to your attempt id type
+  line_item_url       TEXT,             -- line item the score is posted to, once known
   status              TEXT NOT NULL CHECK (status IN ('pending','ok','failed')),
-  retries             INT NOT NULL DEFAULT 0,
   last_error          TEXT,
-  updated_at          TIMESTAMPTZ NOT NULL DEFAULT now()
+  updated_at          TIMESTAMPTZ NOT NULL DEFAULT now(),
+  retry_count         INT NOT NULL DEFAULT 0
 );
+
+-- Retry scans pick pending/failed rows
+CREATE INDEX IF NOT EXISTS grade_sync_status_status_idx ON grade_sync_status (status, updated_at);
 `
 
 // SQLite schema uses compatible types and CURRENT_TIMESTAMP defaults.
@@ -260,9 +296,12 @@ CREATE TABLE IF NOT EXISTS gradebook_lineitems (
 
 CREATE TABLE IF NOT EXISTS grade_sync_status (
   attempt_id          TEXT PRIMARY KEY,
+  line_item_url       TEXT,
   status              TEXT NOT NULL CHECK (status IN ('pending','ok','failed')),
-  retries             INTEGER NOT NULL DEFAULT 0,
   last_error          TEXT,
-  updated_at          DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+  updated_at          DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  retry_count         INTEGER NOT NULL DEFAULT 0
 );
+
+CREATE INDEX IF NOT EXISTS grade_sync_status_status_idx ON grade_sync_status (status, updated_at);
 `
@@ -0,0 +1,88 @@
+// pkg/gradebook/platform.go
+package gradebook
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+)
+
+var (
+	ErrPlatformExists   = errors.New("gradebook: platform already registered")
+	ErrPlatformNotFound = errors.New("gradebook: platform not registered")
+)
+
+// PlatformStore manages lti_platforms registrations; sqlstore.Store implements it.
+type PlatformStore interface {
+	RegisterPlatform(p Platform) error // ErrPlatformExists if the issuer is taken
+	UpdatePlatform(p Platform) error   // ErrPlatformNotFound if it is not
+	GetPlatform(issuer string) (Platform, error)
+	ListPlatforms() ([]Platform, error)
+}
+
+// ValidatePlatform trims p and checks it is usable: a client id, and an
+// issuer and token/JWKS/auth URLs that are absolute https URLs (http only for
+// loopback hosts, for local development). The issuer, as it appears in the
+// launch id_token, must not carry a query or fragment.
+func ValidatePlatform(p Platform) (Platform, error) {
+	p.Issuer = strings.TrimSpace(p.Issuer)
+	p.ClientID = strings.TrimSpace(p.ClientID)
+	p.TokenURL = strings.TrimSpace(p.TokenURL)
+	p.JWKSURL = strings.TrimSpace(p.JWKSURL)
+	p.AuthURL = strings.TrimSpace(p.AuthURL)
+	if p.ClientID == "" {
+		return p, errors.New("client_id required")
+	}
+	for _, f := range []struct{ name, v string }{
+		{"issuer", p.Issuer}, {"token_url", p.TokenURL}, {"jwks_url", p.JWKSURL}, {"auth_url", p.AuthURL},
+	} {
+		if err := checkPlatformURL(f.v); err != nil {
+			return p, fmt.Errorf("%s: %w", f.name, err)
+		}
+	}
+	if u, _ := url.Parse(p.Issuer); u.RawQuery != "" {
+		return p, errors.New("issuer: query not allowed")
+	}
+	return p, nil
+}
+
+func checkPlatformURL(raw string) error {
+	if raw == "" {
+		return errors.New("required")
+	}
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return errors.New("must be an absolute URL")
+	}
+	if u.User != nil || u.Fragment != "" {
+		return errors.New("userinfo and fragments not allowed")
+	}
+	switch u.Scheme {
+	case "https":
+		return nil
+	case "http":
+		if h := u.Hostname(); h == "localhost" || net.ParseIP(h).IsLoopback() {
+			return nil
+		}
+	}
+	return errors.New("must use https")
+}
+
+// PlatformForLaunch returns the registration for a launch id_token's iss,
+// checking that the token was addressed (aud) to the registered client id.
+func PlatformForLaunch(ps PlatformStore, issuer, clientID string) (Platform, error) {
+	p, err := ps.GetPlatform(issuer)
+	if errors.Is(err, sql.ErrNoRows) || errors.Is(err, ErrPlatformNotFound) {
+		return Platform{}, ErrPlatformNotFound
+	}
+	if err != nil {
+		return Platform{}, err
+	}
+	if p.ClientID != clientID {
+		return Platform{}, fmt.Errorf("gradebook: launch client %q is not registered for %s", clientID, issuer)
+	}
+	return p, nil
+}
@@ -0,0 +1,169 @@
+// pkg/gradebook/retry.go
+package gradebook
+
+import (
+	"sync"
+	"time"
+)
+
+// SyncStatusLister finds passbacks worth retrying; sqlstore.Store implements it.
+type SyncStatusLister interface {
+	// ListRetryableSync returns up to limit attempts in status whose
+	// retry_count is below maxRetries, least recently updated first.
+	ListRetryableSync(status string, maxRetries, limit int) ([]SyncStatus, error)
+}
+
+// RetryWorker periodically re-runs SyncAttempt for failed passbacks, and for
+// pending ones that look abandoned (a sync that died mid-way). A failed
+// attempt waits BaseBackoff after its first failure, doubling per failure up
+// to MaxBackoff; once retry_count reaches MaxRetries it stays failed.
+type RetryWorker struct {
+	Syncer *Syncer
+	Status SyncStatusLister
+
+	Interval    time.Duration // between scans; default 1m
+	BaseBackoff time.Duration // default 30s
+	MaxBackoff  time.Duration // default 1h
+	MaxRetries  int           // default 5
+	StaleAfter  time.Duration // pending this long counts as abandoned; default 10m
+	BatchSize   int           // rows per status per scan; default 100
+
+	// Logf, if set, receives scan and retry errors.
+	Logf func(format string, args ...any)
+
+	mu   sync.Mutex
+	stop chan struct{}
+	done chan struct{}
+}
+
+func NewRetryWorker(s *Syncer, status SyncStatusLister) *RetryWorker {
+	return &RetryWorker{
+		Syncer:      s,
+		Status:      status,
+		Interval:    time.Minute,
+		BaseBackoff: 30 * time.Second,
+		MaxBackoff:  time.Hour,
+		MaxRetries:  5,
+		StaleAfter:  10 * time.Minute,
+		BatchSize:   100,
+	}
+}
+
+// Start runs a scan every Interval until Stop. Starting a running worker is
+// a no-op.
+func (w *RetryWorker) Start() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.stop != nil {
+		return
+	}
+	w.stop, w.done = make(chan struct{}), make(chan struct{})
+	go w.loop(w.stop, w.done)
+}
+
+// Stop ends the loop and waits for an in-flight scan to finish.
+func (w *RetryWorker) Stop() {
+	w.mu.Lock()
+	stop, done := w.stop, w.done
+	w.stop, w.done = nil, nil
+	w.mu.Unlock()
+	if stop == nil {
+		return
+	}
+	close(stop)
+	<-done
+}
+
+func (w *RetryWorker) loop(stop, done chan struct{}) {
+	defer close(done)
+	interval := w.Interval
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		if _, err := w.RunOnce(); err != nil {
+			w.logf("gradebook retry: scan: %v", err)
+		}
+		select {
+		case <-stop:
+			return
+		case <-t.C:
+		}
+	}
+}
+
+// RunOnce retries every due passback once and reports how many it tried.
+// Individual sync failures are recorded by SyncAttempt and only logged here.
+func (w *RetryWorker) RunOnce() (int, error) {
+	now := w.Syncer.Now()
+	tried := 0
+	for _, status := range []string{SyncFailed, SyncPending} {
+		rows, err := w.Status.ListRetryableSync(status, w.maxRetries(), w.batchSize())
+		if err != nil {
+			return tried, err
+		}
+		for _, st := range rows {
+			if !w.due(st, now) {
+				continue
+			}
+			tried++
+			if err := w.Syncer.SyncAttempt(st.AttemptID); err != nil {
+				w.logf("gradebook retry: attempt %s (retry %d): %v", st.AttemptID, st.RetryCount+1, err)
+			}
+		}
+	}
+	return tried, nil
+}
+
+func (w *RetryWorker) due(st SyncStatus, now time.Time) bool {
+	if st.Status == SyncPending {
+		stale := w.StaleAfter
+		if stale <= 0 {
+			stale = 10 * time.Minute
+		}
+		return !now.Before(st.UpdatedAt.Add(stale))
+	}
+	return !now.Before(st.UpdatedAt.Add(w.Backoff(st.RetryCount)))
+}
+
+// Backoff is how long a passback that has failed retries times waits before
+// the next try.
+func (w *RetryWorker) Backoff(retries int) time.Duration {
+	base, max := w.BaseBackoff, w.MaxBackoff
+	if base <= 0 {
+		base = 30 * time.Second
+	}
+	if max <= 0 {
+		max = time.Hour
+	}
+	d := base
+	for i := 1; i < retries && d < max; i++ {
+		d *= 2
+	}
+	if d > max {
+		d = max
+	}
+	return d
+}
+
+func (w *RetryWorker) maxRetries() int {
+	if w.MaxRetries <= 0 {
+		return 5
+	}
+	return w.MaxRetries
+}
+
+func (w *RetryWorker) batchSize() int {
+	if w.BatchSize <= 0 {
+		return 100
+	}
+	return w.BatchSize
+}
+
+func (w *RetryWorker) logf(format string, args ...any) {
+	if w.Logf != nil {
+		w.Logf(format, args...)
+	}
+}
@@ -0,0 +1,151 @@
+package gradebook_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	gradebook "github.com/mind-engage/mindengage-lms/pkg/lti-ags-gradebook/gradebook"
+)
+
+// fakeClock is shared by the syncer and the fake store so backoff can be stepped.
+type fakeClock struct{ t time.Time }
+
+func (c *fakeClock) Now() time.Time          { return c.t }
+func (c *fakeClock) Advance(d time.Duration) { c.t = c.t.Add(d) }
+
+func TestRetryWorker_FailsThenSucceeds(t *testing.T) {
+	st, ags, syncer, attemptID := seedBasic(t)
+	clock := &fakeClock{t: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)}
+	st.now, syncer.Now = clock.Now, clock.Now
+
+	ags.postErr = errors.New("ags: 503")
+	if err := syncer.SyncAttempt(attemptID); err == nil {
+		t.Fatal("expected the first passback to fail")
+	}
+	w := gradebook.NewRetryWorker(syncer, st)
+	w.BaseBackoff, w.MaxBackoff = 30*time.Second, time.Hour
+
+	run := func(wantTried int) {
+		t.Helper()
+		n, err := w.RunOnce()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n != wantTried {
+			t.Fatalf("tried %d, want %d", n, wantTried)
+		}
+	}
+	run(0) // still backing off
+	clock.Advance(30 * time.Second)
+	run(1) // first retry fails again
+	if got := st.syncStatus[attemptID]; got.status != "failed" || got.retries != 2 {
+		t.Fatalf("after retry 1: %+v", got)
+	}
+	clock.Advance(30 * time.Second)
+	run(0) // backoff doubled to 1m
+	clock.Advance(30 * time.Second)
+	ags.postErr = nil
+	run(1)
+	if got := st.syncStatus[attemptID]; got.status != "ok" || got.lastErr != "" {
+		t.Fatalf("after retry 2: %+v", got)
+	}
+	clock.Advance(time.Hour)
+	run(0) // nothing left to do
+
+	want := []string{
+		attemptID + ":pending", attemptID + ":failed",
+		attemptID + ":pending", attemptID + ":failed",
+		attemptID + ":pending", attemptID + ":ok",
+	}
+	if !reflect.DeepEqual(st.syncLog, want) {
+		t.Fatalf("transitions = %v\nwant %v", st.syncLog, want)
+	}
+	if ags.postCalls != 3 {
+		t.Fatalf("PostScore calls = %d, want 3", ags.postCalls)
+	}
+}
+
+func TestRetryWorker_GivesUpAfterMaxRetries(t *testing.T) {
+	st, ags, syncer, attemptID := seedBasic(t)
+	clock := &fakeClock{t: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)}
+	st.now, syncer.Now = clock.Now, clock.Now
+
+	ags.postErr = errors.New("ags: 503")
+	_ = syncer.SyncAttempt(attemptID)
+	w := gradebook.NewRetryWorker(syncer, st)
+	w.MaxRetries = 3
+	for i := 0; i < 10; i++ {
+		clock.Advance(2 * time.Hour)
+		if _, err := w.RunOnce(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := st.syncStatus[attemptID]; got.status != "failed" || got.retries != 3 {
+		t.Fatalf("status = %+v, want failed after 3 tries", got)
+	}
+	if ags.postCalls != 3 {
+		t.Fatalf("PostScore calls = %d, want 3", ags.postCalls)
+	}
+}
+
+func TestRetryWorker_RetriesStalePending(t *testing.T) {
+	st, ags, syncer, attemptID := seedBasic(t)
+	clock := &fakeClock{t: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)}
+	st.now, syncer.Now = clock.Now, clock.Now
+
+	_ = st.MarkSyncPending(attemptID) // a sync that never finished
+	w := gradebook.NewRetryWorker(syncer, st)
+	if n, _ := w.RunOnce(); n != 0 {
+		t.Fatalf("fresh pending retried (%d)", n)
+	}
+	clock.Advance(w.StaleAfter)
+	if n, _ := w.RunOnce(); n != 1 {
+		t.Fatalf("stale pending not retried (%d)", n)
+	}
+	if st.syncStatus[attemptID].status != "ok" || ags.postCalls != 1 {
+		t.Fatalf("status = %+v, posts = %d", st.syncStatus[attemptID], ags.postCalls)
+	}
+}
+
+// signalLister reports each scan so the Start/Stop test can wait for one.
+type signalLister struct{ scans chan struct{} }
+
+func (l signalLister) ListRetryableSync(string, int, int) ([]gradebook.SyncStatus, error) {
+	select {
+	case l.scans <- struct{}{}:
+	default:
+	}
+	return nil, nil
+}
+
+func TestRetryWorker_StartStop(t *testing.T) {
+	_, _, syncer, _ := seedBasic(t)
+	l := signalLister{scans: make(chan struct{}, 1)}
+	w := gradebook.NewRetryWorker(syncer, l)
+	w.Interval = time.Millisecond
+
+	w.Start()
+	w.Start() // no-op while running
+	for i := 0; i < 2; i++ {
+		select {
+		case <-l.scans:
+		case <-time.After(2 * time.Second):
+			t.Fatal("worker did not scan")
+		}
+	}
+	w.Stop()
+	w.Stop() // no-op when stopped
+
+	// drain anything sent before Stop returned; nothing may follow it
+	select {
+	case <-l.scans:
+	default:
+	}
+	select {
+	case <-l.scans:
+		t.Fatal("worker scanned after Stop")
+	case <-time.After(20 * time.Millisecond):
+	}
+}
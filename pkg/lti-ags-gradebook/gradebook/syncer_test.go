@@ -17,13 +17,19 @@ type fakeStore struct {
 	lineitems   map[string]gradebook.GradebookLineItem // key: exam|issuer|dep|ctx|rl
 	lineitemSeq int64
 	userMap     map[string]string // key: issuer|localUserID => platformSub
-	syncStatus  map[string]struct {
-		status, lastErr string
-		retries         int
-	}
+	syncStatus  map[string]syncState
+	syncLog     []string // attemptID:status, in order
+	now         func() time.Time
+
 	platformByIssr map[string]gradebook.Platform
 }
 
+type syncState struct {
+	status, lastErr string
+	retries         int
+	updated         time.Time
+}
+
 func newFakeStore() *fakeStore {
 	return &fakeStore{
 		exams:     map[string]gradebook.Exam{},
@@ -31,10 +37,9 @@ func newFakeStore() *fakeStore {
 		links:     map[string]gradebook.LTILink{},
 		lineitems: map[string]gradebook.GradebookLineItem{},
 		userMap:   map[string]string{},
-		syncStatus: map[string]struct {
-			status, lastErr string
-			retries         int
-		}{},
+
+		syncStatus:     map[string]syncState{},
+		now:            time.Now,
 		platformByIssr: map[string]gradebook.Platform{},
 	}
 }
@@ -106,21 +111,39 @@ func (s *fakeStore) GetPlatformUserID(issuer, localUserID string) (string, error
 func (s *fakeStore) MarkSyncPending(attemptID string) error {
 	state := s.syncStatus[attemptID]
 	state.status = "pending"
-	s.syncStatus[attemptID] = state
+	s.setSync(attemptID, state)
 	return nil
 }
 func (s *fakeStore) MarkSyncOK(attemptID string) error {
 	state := s.syncStatus[attemptID]
 	state.status, state.lastErr = "ok", ""
-	s.syncStatus[attemptID] = state
+	s.setSync(attemptID, state)
 	return nil
 }
 func (s *fakeStore) MarkSyncFailed(attemptID, lastErr string) error {
 	state := s.syncStatus[attemptID]
 	state.status, state.lastErr, state.retries = "failed", lastErr, state.retries+1
-	s.syncStatus[attemptID] = state
+	s.setSync(attemptID, state)
 	return nil
 }
+func (s *fakeStore) setSync(attemptID string, state syncState) {
+	state.updated = s.now()
+	s.syncStatus[attemptID] = state
+	s.syncLog = append(s.syncLog, attemptID+":"+state.status)
+}
+
+func (s *fakeStore) ListRetryableSync(status string, maxRetries, limit int) ([]gradebook.SyncStatus, error) {
+	var out []gradebook.SyncStatus
+	for id, st := range s.syncStatus {
+		if st.status == status && st.retries < maxRetries && len(out) < limit {
+			out = append(out, gradebook.SyncStatus{
+				AttemptID: id, Status: st.status, LastError: st.lastErr,
+				RetryCount: st.retries, UpdatedAt: st.updated,
+			})
+		}
+	}
+	return out, nil
+}
 
 func (s *fakeStore) GetPlatform(issuer string) (gradebook.Platform, error) {
 	p, ok := s.platformByIssr[issuer]
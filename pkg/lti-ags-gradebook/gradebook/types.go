@@ -33,6 +33,23 @@ type GradebookLineItem struct {
 	LineItemURL                                                     string // absolute URL
 }
 
+// Passback states recorded in grade_sync_status.
+const (
+	SyncPending = "pending"
+	SyncOK      = "ok"
+	SyncFailed  = "failed"
+)
+
+// SyncStatus is the passback state of one attempt.
+type SyncStatus struct {
+	AttemptID   string
+	LineItemURL string // empty until the line item is known
+	Status      string // SyncPending | SyncOK | SyncFailed
+	LastError   string
+	RetryCount  int
+	UpdatedAt   time.Time
+}
+
 // Store: implement this in your app, or use pkg/sqlstore.Store
 type Store interface {
 	GetExam(id string) (Exam, error)
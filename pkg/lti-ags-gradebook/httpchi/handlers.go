@@ -1,11 +1,13 @@
 package httpchi
 
 import (
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"net/http"
 
 	"github.com/go-chi/chi/v5"
-	"github.com/mind-engage/ags-gradebook/gradebook"
+	"github.com/mind-engage/mindengage-lms/pkg/lti-ags-gradebook/gradebook"
 )
 
 type API struct{ Syncer *gradebook.Syncer }
@@ -60,3 +62,89 @@ func (a *API) postResync(w http.ResponseWriter, r *http.Request) {
 	}
 	_ = json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
 }
+
+// PlatformAPI manages LTI platform registrations (lti_platforms).
+type PlatformAPI struct{ Store gradebook.PlatformStore }
+
+func (a *PlatformAPI) Routes(r chi.Router) {
+	r.Get("/lti/platforms", a.getPlatforms)
+	r.Post("/lti/platforms", a.postPlatform)
+	r.Put("/lti/platforms", a.putPlatform)
+}
+
+type platformJSON struct {
+	Issuer   string `json:"issuer"`
+	ClientID string `json:"client_id"`
+	TokenURL string `json:"token_url"`
+	JWKSURL  string `json:"jwks_url"`
+	AuthURL  string `json:"auth_url"`
+}
+
+func toPlatformJSON(p gradebook.Platform) platformJSON {
+	return platformJSON{Issuer: p.Issuer, ClientID: p.ClientID, TokenURL: p.TokenURL, JWKSURL: p.JWKSURL, AuthURL: p.AuthURL}
+}
+
+func (req platformJSON) platform() gradebook.Platform {
+	return gradebook.Platform{Issuer: req.Issuer, ClientID: req.ClientID, TokenURL: req.TokenURL, JWKSURL: req.JWKSURL, AuthURL: req.AuthURL}
+}
+
+// getPlatforms lists registrations, or returns one with ?issuer=.
+func (a *PlatformAPI) getPlatforms(w http.ResponseWriter, r *http.Request) {
+	if iss := r.URL.Query().Get("issuer"); iss != "" {
+		p, err := a.Store.GetPlatform(iss)
+		if errors.Is(err, sql.ErrNoRows) || errors.Is(err, gradebook.ErrPlatformNotFound) {
+			http.Error(w, "platform not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(toPlatformJSON(p))
+		return
+	}
+	ps, err := a.Store.ListPlatforms()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	out := make([]platformJSON, 0, len(ps))
+	for _, p := range ps {
+		out = append(out, toPlatformJSON(p))
+	}
+	_ = json.NewEncoder(w).Encode(out)
+}
+
+func (a *PlatformAPI) postPlatform(w http.ResponseWriter, r *http.Request) {
+	a.savePlatform(w, r, a.Store.RegisterPlatform, http.StatusCreated)
+}
+
+func (a *PlatformAPI) putPlatform(w http.ResponseWriter, r *http.Request) {
+	a.savePlatform(w, r, a.Store.UpdatePlatform, http.StatusOK)
+}
+
+func (a *PlatformAPI) savePlatform(w http.ResponseWriter, r *http.Request, save func(gradebook.Platform) error, okStatus int) {
+	var req platformJSON
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid JSON", http.StatusBadRequest)
+		return
+	}
+	p, err := gradebook.ValidatePlatform(req.platform())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	switch err := save(p); {
+	case errors.Is(err, gradebook.ErrPlatformExists):
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	case errors.Is(err, gradebook.ErrPlatformNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(okStatus)
+	_ = json.NewEncoder(w).Encode(toPlatformJSON(p))
+}
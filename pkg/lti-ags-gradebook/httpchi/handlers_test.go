@@ -0,0 +1,77 @@
+package httpchi_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	_ "modernc.org/sqlite"
+
+	gb "github.com/mind-engage/mindengage-lms/pkg/lti-ags-gradebook/gradebook"
+	"github.com/mind-engage/mindengage-lms/pkg/lti-ags-gradebook/httpchi"
+	"github.com/mind-engage/mindengage-lms/pkg/lti-ags-gradebook/sqlstore"
+)
+
+func TestPlatformAPI(t *testing.T) {
+	db, err := gb.ConnectAndMigrate(context.Background(), "sqlite", filepath.Join(t.TempDir(), "gradebook.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	r := chi.NewRouter()
+	(&httpchi.PlatformAPI{Store: &sqlstore.Store{DB: db}}).Routes(r)
+	do := func(method, target, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest(method, target, strings.NewReader(body)))
+		return rec
+	}
+
+	const canvas = `{"issuer":"https://canvas.example.edu","client_id":"100",
+		"token_url":"https://canvas.example.edu/login/oauth2/token",
+		"jwks_url":"https://canvas.example.edu/api/lti/security/jwks",
+		"auth_url":"https://canvas.example.edu/api/lti/authorize_redirect"}`
+
+	if rec := do(http.MethodPost, "/lti/platforms", canvas); rec.Code != http.StatusCreated {
+		t.Fatalf("register: %d %s", rec.Code, rec.Body)
+	}
+	if rec := do(http.MethodPost, "/lti/platforms", canvas); rec.Code != http.StatusConflict {
+		t.Fatalf("register twice: %d", rec.Code)
+	}
+	for name, body := range map[string]string{
+		"invalid JSON":   `{`,
+		"plain http":     strings.Replace(canvas, "https://canvas.example.edu/api/lti/security/jwks", "http://canvas.example.edu/jwks", 1),
+		"no client id":   strings.Replace(canvas, `"client_id":"100"`, `"client_id":" "`, 1),
+		"issuer w/query": strings.Replace(canvas, `"issuer":"https://canvas.example.edu"`, `"issuer":"https://canvas.example.edu?x=1"`, 1),
+	} {
+		if rec := do(http.MethodPost, "/lti/platforms", body); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: %d, want 400", name, rec.Code)
+		}
+	}
+
+	if rec := do(http.MethodPut, "/lti/platforms", strings.Replace(canvas, `"100"`, `"200"`, 1)); rec.Code != http.StatusOK {
+		t.Fatalf("update: %d %s", rec.Code, rec.Body)
+	}
+	moodle := strings.ReplaceAll(canvas, "canvas.example.edu", "moodle.example.edu")
+	if rec := do(http.MethodPut, "/lti/platforms", moodle); rec.Code != http.StatusNotFound {
+		t.Fatalf("update unknown: %d", rec.Code)
+	}
+
+	rec := do(http.MethodGet, "/lti/platforms?issuer=https://canvas.example.edu", "")
+	var one map[string]string
+	if err := json.Unmarshal(rec.Body.Bytes(), &one); err != nil || one["client_id"] != "200" {
+		t.Fatalf("get: %d %s", rec.Code, rec.Body)
+	}
+	if rec := do(http.MethodGet, "/lti/platforms?issuer=https://moodle.example.edu", ""); rec.Code != http.StatusNotFound {
+		t.Fatalf("get unknown: %d", rec.Code)
+	}
+	rec = do(http.MethodGet, "/lti/platforms", "")
+	var all []map[string]string
+	if err := json.Unmarshal(rec.Body.Bytes(), &all); err != nil || len(all) != 1 || all[0]["issuer"] != "https://canvas.example.edu" {
+		t.Fatalf("list: %d %s", rec.Code, rec.Body)
+	}
+}
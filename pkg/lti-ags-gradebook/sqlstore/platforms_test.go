@@ -0,0 +1,101 @@
+package sqlstore_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	gb "github.com/mind-engage/mindengage-lms/pkg/lti-ags-gradebook/gradebook"
+	"github.com/mind-engage/mindengage-lms/pkg/lti-ags-gradebook/sqlstore"
+)
+
+func Test_Platforms_RegisterGetUpdate(t *testing.T) {
+	ctx := context.Background()
+	db, err := gb.ConnectAndMigrate(ctx, "sqlite", filepath.Join(t.TempDir(), "gradebook.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	st := &sqlstore.Store{DB: db}
+
+	p := gb.Platform{
+		Issuer:   " https://canvas.example.edu ",
+		ClientID: "10000000000001",
+		TokenURL: "https://canvas.example.edu/login/oauth2/token",
+		JWKSURL:  "https://canvas.example.edu/api/lti/security/jwks",
+		AuthURL:  "https://canvas.example.edu/api/lti/authorize_redirect",
+	}
+	if err := st.RegisterPlatform(p); err != nil {
+		t.Fatal(err)
+	}
+	got, err := st.GetPlatform("https://canvas.example.edu")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.ClientID != p.ClientID || got.JWKSURL != p.JWKSURL {
+		t.Fatalf("get = %+v", got)
+	}
+
+	// Issuers are unique.
+	dup := p
+	dup.ClientID = "other"
+	if err := st.RegisterPlatform(dup); !errors.Is(err, gb.ErrPlatformExists) {
+		t.Fatalf("duplicate issuer: err = %v", err)
+	}
+
+	// Update replaces the registration; unknown issuers are reported.
+	p.Issuer = "https://canvas.example.edu"
+	p.ClientID = "10000000000002"
+	if err := st.UpdatePlatform(p); err != nil {
+		t.Fatal(err)
+	}
+	missing := p
+	missing.Issuer = "https://moodle.example.edu"
+	if err := st.UpdatePlatform(missing); !errors.Is(err, gb.ErrPlatformNotFound) {
+		t.Fatalf("update unknown: err = %v", err)
+	}
+
+	// Launch lookup checks the client id the launch was addressed to.
+	if _, err := gb.PlatformForLaunch(st, p.Issuer, "10000000000002"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := gb.PlatformForLaunch(st, p.Issuer, "10000000000001"); err == nil {
+		t.Fatal("stale client id accepted at launch")
+	}
+	if _, err := gb.PlatformForLaunch(st, "https://moodle.example.edu", "x"); !errors.Is(err, gb.ErrPlatformNotFound) {
+		t.Fatalf("unknown issuer at launch: err = %v", err)
+	}
+
+	ps, err := st.ListPlatforms()
+	if err != nil || len(ps) != 1 {
+		t.Fatalf("list = %+v (%v)", ps, err)
+	}
+
+	// URLs are validated.
+	for name, bad := range map[string]func(*gb.Platform){
+		"http issuer":      func(p *gb.Platform) { p.Issuer = "http://lms.example" },
+		"issuer query":     func(p *gb.Platform) { p.Issuer = "https://lms.example/?x=1" },
+		"relative token":   func(p *gb.Platform) { p.TokenURL = "/token" },
+		"userinfo jwks":    func(p *gb.Platform) { p.JWKSURL = "https://u:p@lms.example/jwks" },
+		"missing auth":     func(p *gb.Platform) { p.AuthURL = "" },
+		"missing clientID": func(p *gb.Platform) { p.ClientID = " " },
+	} {
+		q := gb.Platform{
+			Issuer: "https://lms.example", ClientID: "c",
+			TokenURL: "https://lms.example/token", JWKSURL: "https://lms.example/jwks", AuthURL: "https://lms.example/auth",
+		}
+		bad(&q)
+		if err := st.RegisterPlatform(q); err == nil {
+			t.Errorf("%s: registered", name)
+		}
+	}
+	// Loopback http is allowed for local development.
+	local := gb.Platform{
+		Issuer: "http://localhost:8080", ClientID: "dev",
+		TokenURL: "http://127.0.0.1:8080/token", JWKSURL: "http://127.0.0.1:8080/jwks", AuthURL: "http://localhost:8080/auth",
+	}
+	if err := st.RegisterPlatform(local); err != nil {
+		t.Fatalf("loopback platform: %v", err)
+	}
+}
@@ -9,6 +9,11 @@ import (
 
 type Store struct{ DB *sql.DB }
 
+var (
+	_ gradebook.SyncStatusLister = (*Store)(nil)
+	_ gradebook.PlatformStore    = (*Store)(nil)
+)
+
 func (s *Store) GetExam(id string) (gradebook.Exam, error) {
 	var ex gradebook.Exam
 	err := s.DB.QueryRow(`SELECT id, title, max_points FROM exams WHERE id=$1`, id).
@@ -79,7 +84,7 @@ func (s *Store) GetPlatformUserID(issuer, localUserID string) (string, error) {
 
 func (s *Store) MarkSyncPending(attemptID string) error {
 	_, err := s.DB.Exec(`
-		INSERT INTO grade_sync_status (attempt_id, status, retries, updated_at)
+		INSERT INTO grade_sync_status (attempt_id, status, retry_count, updated_at)
 		VALUES ($1,'pending',0,CURRENT_TIMESTAMP)
 		ON CONFLICT (attempt_id)
 		DO UPDATE SET status='pending', updated_at=CURRENT_TIMESTAMP`,
@@ -97,21 +102,160 @@ func (s *Store) MarkSyncOK(attemptID string) error {
 
 func (s *Store) MarkSyncFailed(attemptID string, lastErr string) error {
 	_, err := s.DB.Exec(`
-		INSERT INTO grade_sync_status (attempt_id, status, retries, last_error, updated_at)
+		INSERT INTO grade_sync_status (attempt_id, status, retry_count, last_error, updated_at)
 		VALUES ($1,'failed',1,$2,CURRENT_TIMESTAMP)
 		ON CONFLICT (attempt_id)
 		DO UPDATE SET
 			status='failed',
-			retries=grade_sync_status.retries+1,
+			retry_count=grade_sync_status.retry_count+1,
 			last_error=$2,
 			updated_at=CURRENT_TIMESTAMP`,
 		attemptID, lastErr)
 	return err
 }
 
+// RecordSyncStatus writes an attempt's passback state as given, replacing any
+// earlier row. An empty LineItemURL keeps the one already recorded.
+func (s *Store) RecordSyncStatus(st gradebook.SyncStatus) error {
+	_, err := s.DB.Exec(`
+		INSERT INTO grade_sync_status (attempt_id, line_item_url, status, last_error, retry_count, updated_at)
+		VALUES ($1,$2,$3,$4,$5,CURRENT_TIMESTAMP)
+		ON CONFLICT (attempt_id)
+		DO UPDATE SET
+			line_item_url=COALESCE(EXCLUDED.line_item_url, grade_sync_status.line_item_url),
+			status=EXCLUDED.status,
+			last_error=EXCLUDED.last_error,
+			retry_count=EXCLUDED.retry_count,
+			updated_at=CURRENT_TIMESTAMP`,
+		st.AttemptID, nullString(st.LineItemURL), st.Status, nullString(st.LastError), st.RetryCount)
+	return err
+}
+
+// GetSyncStatus returns the passback state of one attempt (sql.ErrNoRows if
+// it was never synced).
+func (s *Store) GetSyncStatus(attemptID string) (gradebook.SyncStatus, error) {
+	return scanSyncStatus(s.DB.QueryRow(`
+		SELECT `+syncStatusCols+`
+		FROM grade_sync_status WHERE attempt_id=$1`, attemptID))
+}
+
+// ListSyncStatus returns up to limit attempts in the given state, least
+// recently updated first, for retry scans.
+func (s *Store) ListSyncStatus(status string, limit int) ([]gradebook.SyncStatus, error) {
+	return s.listSyncStatus(`
+		SELECT `+syncStatusCols+`
+		FROM grade_sync_status WHERE status=$1
+		ORDER BY updated_at, attempt_id LIMIT $2`, status, limit)
+}
+
+// ListRetryableSync is ListSyncStatus without the attempts that have used
+// up maxRetries; gradebook.RetryWorker scans with it.
+func (s *Store) ListRetryableSync(status string, maxRetries, limit int) ([]gradebook.SyncStatus, error) {
+	return s.listSyncStatus(`
+		SELECT `+syncStatusCols+`
+		FROM grade_sync_status WHERE status=$1 AND retry_count < $2
+		ORDER BY updated_at, attempt_id LIMIT $3`, status, maxRetries, limit)
+}
+
+func (s *Store) listSyncStatus(query string, args ...any) ([]gradebook.SyncStatus, error) {
+	rows, err := s.DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []gradebook.SyncStatus
+	for rows.Next() {
+		st, err := scanSyncStatus(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, st)
+	}
+	return out, rows.Err()
+}
+
+const syncStatusCols = `attempt_id, COALESCE(line_item_url,''), status, COALESCE(last_error,''), retry_count, updated_at`
+
+func scanSyncStatus(row interface{ Scan(...any) error }) (gradebook.SyncStatus, error) {
+	var st gradebook.SyncStatus
+	err := row.Scan(&st.AttemptID, &st.LineItemURL, &st.Status, &st.LastError, &st.RetryCount, &st.UpdatedAt)
+	return st, err
+}
+
+func nullString(s string) any {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
 func (s *Store) GetPlatform(issuer string) (gradebook.Platform, error) {
 	var p gradebook.Platform
 	err := s.DB.QueryRow(`SELECT issuer, client_id, token_url, jwks_url, auth_url FROM lti_platforms WHERE issuer=$1`, issuer).
 		Scan(&p.Issuer, &p.ClientID, &p.TokenURL, &p.JWKSURL, &p.AuthURL)
 	return p, err
 }
+
+// RegisterPlatform adds a platform; issuers are unique.
+func (s *Store) RegisterPlatform(p gradebook.Platform) error {
+	p, err := gradebook.ValidatePlatform(p)
+	if err != nil {
+		return err
+	}
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	var n int
+	if err := tx.QueryRow(`SELECT COUNT(*) FROM lti_platforms WHERE issuer=$1`, p.Issuer).Scan(&n); err != nil {
+		return err
+	}
+	if n > 0 {
+		return gradebook.ErrPlatformExists
+	}
+	if _, err := tx.Exec(`
+		INSERT INTO lti_platforms (issuer, client_id, token_url, jwks_url, auth_url)
+		VALUES ($1,$2,$3,$4,$5)`,
+		p.Issuer, p.ClientID, p.TokenURL, p.JWKSURL, p.AuthURL); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// UpdatePlatform replaces the client id and URLs of a registered issuer.
+func (s *Store) UpdatePlatform(p gradebook.Platform) error {
+	p, err := gradebook.ValidatePlatform(p)
+	if err != nil {
+		return err
+	}
+	res, err := s.DB.Exec(`
+		UPDATE lti_platforms
+		   SET client_id=$2, token_url=$3, jwks_url=$4, auth_url=$5, updated_at=CURRENT_TIMESTAMP
+		 WHERE issuer=$1`,
+		p.Issuer, p.ClientID, p.TokenURL, p.JWKSURL, p.AuthURL)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return gradebook.ErrPlatformNotFound
+	}
+	return nil
+}
+
+func (s *Store) ListPlatforms() ([]gradebook.Platform, error) {
+	rows, err := s.DB.Query(`SELECT issuer, client_id, token_url, jwks_url, auth_url FROM lti_platforms ORDER BY issuer`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []gradebook.Platform
+	for rows.Next() {
+		var p gradebook.Platform
+		if err := rows.Scan(&p.Issuer, &p.ClientID, &p.TokenURL, &p.JWKSURL, &p.AuthURL); err != nil {
+			return nil, err
+		}
+		out = append(out, p)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,110 @@
+package sqlstore_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	gb "github.com/mind-engage/mindengage-lms/pkg/lti-ags-gradebook/gradebook"
+	"github.com/mind-engage/mindengage-lms/pkg/lti-ags-gradebook/sqlstore"
+)
+
+func Test_SyncStatus_PendingAndFailedScans(t *testing.T) {
+	ctx := context.Background()
+	db, err := gb.ConnectAndMigrate(ctx, "sqlite", filepath.Join(t.TempDir(), "gradebook.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	st := &sqlstore.Store{DB: db}
+
+	if _, err := st.GetSyncStatus("nope"); !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("unknown attempt: err = %v, want sql.ErrNoRows", err)
+	}
+
+	// a1 stays pending, a2 fails twice, a3 succeeds.
+	for _, id := range []string{"a1", "a2", "a3"} {
+		if err := st.MarkSyncPending(id); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := 0; i < 2; i++ {
+		if err := st.MarkSyncFailed("a2", "ags: 503"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := st.RecordSyncStatus(gb.SyncStatus{AttemptID: "a3", LineItemURL: "https://lms.example/li/1", Status: gb.SyncPending}); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.MarkSyncOK("a3"); err != nil {
+		t.Fatal(err)
+	}
+
+	pending, err := st.ListSyncStatus(gb.SyncPending, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != 1 || pending[0].AttemptID != "a1" || pending[0].RetryCount != 0 {
+		t.Fatalf("pending = %+v, want [a1]", pending)
+	}
+	failed, err := st.ListSyncStatus(gb.SyncFailed, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(failed) != 1 || failed[0].AttemptID != "a2" || failed[0].RetryCount != 2 || failed[0].LastError != "ags: 503" {
+		t.Fatalf("failed = %+v, want a2 with 2 retries", failed)
+	}
+	if failed[0].UpdatedAt.IsZero() {
+		t.Fatal("failed row has no updated_at")
+	}
+
+	ok, err := st.GetSyncStatus("a3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok.Status != gb.SyncOK || ok.LineItemURL != "https://lms.example/li/1" {
+		t.Fatalf("a3 = %+v, want ok with its line item", ok)
+	}
+	// Recording without a line item keeps the known one.
+	if err := st.RecordSyncStatus(gb.SyncStatus{AttemptID: "a3", Status: gb.SyncFailed, LastError: "x", RetryCount: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := st.GetSyncStatus("a3"); got.LineItemURL != "https://lms.example/li/1" || got.Status != gb.SyncFailed {
+		t.Fatalf("a3 after record = %+v", got)
+	}
+}
+
+func Test_Migrate_UpgradesLegacySyncStatus(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "gradebook.db")
+	db, err := gb.Connect(ctx, "sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if _, err := db.Exec(`CREATE TABLE grade_sync_status (
+  attempt_id TEXT PRIMARY KEY,
+  status     TEXT NOT NULL,
+  retries    INTEGER NOT NULL DEFAULT 0,
+  last_error TEXT,
+  updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+INSERT INTO grade_sync_status (attempt_id, status, retries, last_error) VALUES ('old', 'failed', 3, 'boom');`); err != nil {
+		t.Fatal(err)
+	}
+	// Twice: the upgrade must be idempotent.
+	for i := 0; i < 2; i++ {
+		if err := gb.Migrate(ctx, db, "sqlite"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	got, err := (&sqlstore.Store{DB: db}).GetSyncStatus("old")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.RetryCount != 3 || got.LastError != "boom" || got.LineItemURL != "" {
+		t.Fatalf("legacy row = %+v", got)
+	}
+}
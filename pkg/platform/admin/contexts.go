@@ -0,0 +1,235 @@
+// pkg/platform/admin/contexts.go
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/mind-engage/mindengage-lms/pkg/platform/tenants"
+)
+
+/*
+Contexts (courses) and enrollments
+
+NRPS and AGS read contexts and enrollments from the platform store; these
+endpoints are how they get there:
+
+	POST /tenants/{tenantID}/contexts                              create a context
+	GET  /tenants/{tenantID}/contexts                              list (offset/limit)
+	POST /tenants/{tenantID}/contexts/{contextID}/enrollments      upsert one member, or {"Members":[...]}
+	PUT  /tenants/{tenantID}/contexts/{contextID}/enrollments      same as POST
+	GET  /tenants/{tenantID}/contexts/{contextID}/enrollments      list (offset/limit)
+
+An enrollment is keyed by (user_sub, role); upserting it again updates name,
+email and status. Roles must be LIS membership role URIs or one of their short
+aliases ("learner", "instructor", ...); aliases are stored expanded. A bulk
+upsert is validated as a whole and written in one transaction.
+
+Mount next to Routes behind platform-admin auth; RoutesWithContexts serves
+both under one prefix, e.g. r.Mount("/admin", admin.RoutesWithContexts(...)).
+*/
+
+// ContextStore persists contexts and enrollments (nrps.SQLStore implements it).
+type ContextStore interface {
+	CreateContext(ctx context.Context, c tenants.Context) error
+	ListContexts(ctx context.Context, tenantID string, offset, limit int) ([]tenants.Context, error)
+	// UpsertEnrollments writes all enrollments (one context) atomically.
+	UpsertEnrollments(ctx context.Context, es []tenants.Enrollment) error
+	ListEnrollments(ctx context.Context, tenantID, contextID string, offset, limit int) ([]tenants.Enrollment, error)
+}
+
+// Conflict is a sentinel error that Store implementations can return to signal 409s.
+var Conflict = errors.New("admin: already exists")
+
+// ContextRoutes returns an http.Handler with the context/enrollment endpoints.
+func ContextRoutes(store ContextStore) http.Handler {
+	r := chi.NewRouter()
+	contextRoutes(r, store)
+	return r
+}
+
+func contextRoutes(r chi.Router, store ContextStore) {
+	r.Post("/tenants/{tenantID}/contexts", createContext(store))
+	r.Get("/tenants/{tenantID}/contexts", listContexts(store))
+	r.Post("/tenants/{tenantID}/contexts/{contextID}/enrollments", upsertEnrollments(store))
+	r.Put("/tenants/{tenantID}/contexts/{contextID}/enrollments", upsertEnrollments(store))
+	r.Get("/tenants/{tenantID}/contexts/{contextID}/enrollments", listEnrollments(store))
+}
+
+func createContext(store ContextStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req CreateContextReq
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeErr(w, http.StatusBadRequest, "invalid JSON: "+err.Error())
+			return
+		}
+		if strings.TrimSpace(req.ID) == "" {
+			writeErr(w, http.StatusBadRequest, "id (context_id) is required")
+			return
+		}
+		c := tenants.Context{
+			ID:       strings.TrimSpace(req.ID),
+			TenantID: chi.URLParam(r, "tenantID"),
+			Label:    strings.TrimSpace(req.Label),
+			Title:    strings.TrimSpace(req.Title),
+		}
+		if err := store.CreateContext(r.Context(), c); err != nil {
+			switch {
+			case errors.Is(err, NotFound):
+				writeErr(w, http.StatusNotFound, "tenant not found")
+			case errors.Is(err, Conflict):
+				writeErr(w, http.StatusConflict, "context already exists")
+			default:
+				writeErr(w, http.StatusInternalServerError, err.Error())
+			}
+			return
+		}
+		writeJSON(w, http.StatusCreated, c)
+	}
+}
+
+func listContexts(store ContextStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		offset, limit := parsePage(r, 0, 100)
+		items, err := store.ListContexts(r.Context(), chi.URLParam(r, "tenantID"), offset, limit)
+		if err != nil {
+			writeErr(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		if items == nil {
+			items = []tenants.Context{}
+		}
+		writeJSON(w, http.StatusOK, items)
+	}
+}
+
+// maxEnrollmentBatch bounds one bulk upsert.
+const maxEnrollmentBatch = 1000
+
+func upsertEnrollments(store ContextStore) http.HandlerFunc {
+	type body struct {
+		EnrollReq
+		Members []EnrollReq
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req body
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeErr(w, http.StatusBadRequest, "invalid JSON: "+err.Error())
+			return
+		}
+		members := req.Members
+		if len(members) == 0 {
+			members = []EnrollReq{req.EnrollReq}
+		}
+		if len(members) > maxEnrollmentBatch {
+			writeErr(w, http.StatusRequestEntityTooLarge, "too many members (max 1000)")
+			return
+		}
+		tenantID, contextID := chi.URLParam(r, "tenantID"), chi.URLParam(r, "contextID")
+		es := make([]tenants.Enrollment, 0, len(members))
+		for i, m := range members {
+			e, msg := enrollmentFromReq(tenantID, contextID, m)
+			if msg != "" {
+				writeErr(w, http.StatusBadRequest, "members["+strconv.Itoa(i)+"]: "+msg)
+				return
+			}
+			es = append(es, e)
+		}
+		if err := store.UpsertEnrollments(r.Context(), es); err != nil {
+			if errors.Is(err, NotFound) {
+				writeErr(w, http.StatusNotFound, "context not found")
+				return
+			}
+			writeErr(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		writeJSON(w, http.StatusOK, es)
+	}
+}
+
+func enrollmentFromReq(tenantID, contextID string, m EnrollReq) (tenants.Enrollment, string) {
+	if strings.TrimSpace(m.UserSub) == "" {
+		return tenants.Enrollment{}, "user_sub is required"
+	}
+	role, ok := lisRole(m.Role)
+	if !ok {
+		return tenants.Enrollment{}, "unknown role " + strconv.Quote(m.Role)
+	}
+	status := strings.TrimSpace(m.Status)
+	switch status {
+	case "":
+		status = "Active"
+	case "Active", "Inactive", "Deleted":
+	default:
+		return tenants.Enrollment{}, "status must be Active, Inactive or Deleted"
+	}
+	return tenants.Enrollment{
+		TenantID:  tenantID,
+		ContextID: contextID,
+		UserSub:   strings.TrimSpace(m.UserSub),
+		Role:      role,
+		Name:      strings.TrimSpace(m.Name),
+		Email:     strings.TrimSpace(m.Email),
+		Status:    status,
+
+		ResourceLinkIDs: trimAll(m.ResourceLinkIDs),
+		Custom:          m.Custom,
+	}, ""
+}
+
+const lisMembership = "http://purl.imsglobal.org/vocab/lis/v2/membership#"
+
+// lisRoles are the context roles NRPS reports, by URI and short alias.
+var lisRoles = map[string]string{
+	"administrator":     lisMembership + "Administrator",
+	"contentdeveloper":  lisMembership + "ContentDeveloper",
+	"instructor":        lisMembership + "Instructor",
+	"learner":           lisMembership + "Learner",
+	"mentor":            lisMembership + "Mentor",
+	"manager":           lisMembership + "Manager",
+	"member":            lisMembership + "Member",
+	"officer":           lisMembership + "Officer",
+	"teachingassistant": lisMembership + "TeachingAssistant",
+}
+
+// lisRole expands an alias (case-insensitive) or accepts a known role URI,
+// including the Instructor#TeachingAssistant sub-role form.
+func lisRole(in string) (string, bool) {
+	in = strings.TrimSpace(in)
+	if uri, ok := lisRoles[strings.ToLower(in)]; ok {
+		return uri, true
+	}
+	for _, uri := range lisRoles {
+		if in == uri {
+			return uri, true
+		}
+	}
+	if in == lisMembership+"Instructor#TeachingAssistant" {
+		return in, true
+	}
+	return "", false
+}
+
+func listEnrollments(store ContextStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		offset, limit := parsePage(r, 0, 100)
+		items, err := store.ListEnrollments(r.Context(), chi.URLParam(r, "tenantID"), chi.URLParam(r, "contextID"), offset, limit)
+		if err != nil {
+			if errors.Is(err, NotFound) {
+				writeErr(w, http.StatusNotFound, "context not found")
+				return
+			}
+			writeErr(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		if items == nil {
+			items = []tenants.Enrollment{}
+		}
+		writeJSON(w, http.StatusOK, items)
+	}
+}
@@ -1,17 +1,51 @@
 package admin
 
+import "time"
+
 type CreateToolReq struct {
 	ClientID      string
 	Name          string
 	JWKSURL       string
 	RedirectURIs  []string
+	RedirectMatch string // exact (default) | prefix | ignore_query
 	AllowedScopes []string
 	AuthMethods   []string
 }
 
+// RotateSecretReq is the (optional) body of a client secret rotation.
+type RotateSecretReq struct {
+	// OverlapSeconds is how long the replaced secret keeps working
+	// (default 24h, 0 keeps the default; use the revoke endpoint to end early).
+	OverlapSeconds int `json:"overlap_seconds"`
+}
+
+// RotateSecretResp carries the new client secret. It is shown exactly once;
+// only its bcrypt hash is stored.
+type RotateSecretResp struct {
+	ClientID                 string    `json:"client_id"`
+	ClientSecret             string    `json:"client_secret"`
+	PreviousSecretValidUntil time.Time `json:"previous_secret_valid_until"`
+}
+
 type CreateDeploymentReq struct {
 	ID        string
 	ClientID  string
 	ContextID string
 	Title     string
 }
+
+type CreateContextReq struct {
+	ID    string
+	Label string
+	Title string
+}
+
+type EnrollReq struct {
+	UserSub         string
+	Role            string
+	Name            string
+	Email           string
+	Status          string
+	ResourceLinkIDs []string
+	Custom          map[string]string
+}
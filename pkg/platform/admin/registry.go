@@ -3,24 +3,31 @@ package admin
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/base64"
 	"encoding/json"
 	"errors"
+	"io"
 	"net/http"
 	"net/url"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/mind-engage/mindengage-lms/pkg/platform/lti"
 	"github.com/mind-engage/mindengage-lms/pkg/platform/tenants"
+	"golang.org/x/crypto/bcrypt"
 )
 
 /*
 Package admin exposes a minimal, multi-tenant-aware HTTP API to manage:
   - Tools (client_id, JWKS URL, redirect URIs, allowed scopes, auth methods)
+    and their client_secret_post secret (rotate with an overlap, revoke the old)
   - Deployments (deployment_id bound to tenant + client_id + context_id)
 
-It is intentionally thin and delegates persistence to a Store interface,
-so you can provide a SQL or other implementation elsewhere.
+It is intentionally thin and delegates persistence to a Store interface;
+SQLStore implements it over the platform tables.
 
 Route prefix (suggested): /admin
 All endpoints are scoped by {tenantID} path param.
@@ -34,6 +41,11 @@ type Store interface {
 	ListTools(ctx context.Context, tenantID string, offset, limit int) ([]tenants.Tool, error)
 	UpdateTool(ctx context.Context, t tenants.Tool) error
 	DeleteTool(ctx context.Context, tenantID, clientID string) error
+	// RotateToolSecret makes secretHash the tool's client secret and keeps the
+	// replaced one valid until prevExpiresAt.
+	RotateToolSecret(ctx context.Context, tenantID, clientID, secretHash string, prevExpiresAt time.Time) error
+	// RevokePreviousToolSecret ends the rotation overlap immediately.
+	RevokePreviousToolSecret(ctx context.Context, tenantID, clientID string) error
 
 	// Deployments
 	CreateDeployment(ctx context.Context, d tenants.Deployment) error
@@ -55,21 +67,34 @@ type DeploymentFilter struct {
 // Mount it under something like: r.Mount("/admin", admin.Routes(store))
 func Routes(store Store) http.Handler {
 	r := chi.NewRouter()
+	toolRoutes(r, store)
+	return r
+}
+
+// RoutesWithContexts serves Routes and ContextRoutes from one handler, for
+// mounting both under the same prefix.
+func RoutesWithContexts(store Store, contexts ContextStore) http.Handler {
+	r := chi.NewRouter()
+	toolRoutes(r, store)
+	contextRoutes(r, contexts)
+	return r
+}
 
+func toolRoutes(r chi.Router, store Store) {
 	// Tools
 	r.Post("/tenants/{tenantID}/tools", createTool(store))
 	r.Get("/tenants/{tenantID}/tools", listTools(store))
 	r.Get("/tenants/{tenantID}/tools/{clientID}", getTool(store))
 	r.Put("/tenants/{tenantID}/tools/{clientID}", updateTool(store))
 	r.Delete("/tenants/{tenantID}/tools/{clientID}", deleteTool(store))
+	r.Post("/tenants/{tenantID}/tools/{clientID}/secret/rotate", rotateToolSecret(store))
+	r.Delete("/tenants/{tenantID}/tools/{clientID}/secret/previous", revokePreviousToolSecret(store))
 
 	// Deployments
 	r.Post("/tenants/{tenantID}/deployments", createDeployment(store))
 	r.Get("/tenants/{tenantID}/deployments", listDeployments(store))
 	r.Get("/tenants/{tenantID}/deployments/{id}", getDeployment(store))
 	r.Delete("/tenants/{tenantID}/deployments/{id}", deleteDeployment(store))
-
-	return r
 }
 
 /* ------------------------------- Tools ------------------------------------ */
@@ -94,16 +119,20 @@ func createTool(store Store) http.HandlerFunc {
 			Name:          strings.TrimSpace(req.Name),
 			JWKSURL:       strings.TrimSpace(req.JWKSURL),
 			RedirectURIs:  trimAll(req.RedirectURIs),
+			RedirectMatch: redirectMatchOrDefault(req.RedirectMatch),
 			AllowedScopes: trimAll(req.AllowedScopes),
 			AuthMethods:   trimAll(req.AuthMethods),
 		}
 
 		if err := store.CreateTool(r.Context(), t); err != nil {
-			if errors.Is(err, NotFound) {
+			switch {
+			case errors.Is(err, NotFound):
 				writeErr(w, http.StatusNotFound, "tenant not found")
-				return
+			case errors.Is(err, Conflict):
+				writeErr(w, http.StatusConflict, "client_id already registered")
+			default:
+				writeErr(w, http.StatusInternalServerError, err.Error())
 			}
-			writeErr(w, http.StatusInternalServerError, err.Error())
 			return
 		}
 		writeJSON(w, http.StatusCreated, t)
@@ -172,6 +201,7 @@ func updateTool(store Store) http.HandlerFunc {
 			Name:          strings.TrimSpace(req.Name),
 			JWKSURL:       strings.TrimSpace(req.JWKSURL),
 			RedirectURIs:  trimAll(req.RedirectURIs),
+			RedirectMatch: redirectMatchOrDefault(req.RedirectMatch),
 			AllowedScopes: trimAll(req.AllowedScopes),
 			AuthMethods:   trimAll(req.AuthMethods),
 		}
@@ -203,6 +233,79 @@ func deleteTool(store Store) http.HandlerFunc {
 	}
 }
 
+// How long a replaced client secret keeps working: default and upper bound.
+const (
+	defaultSecretOverlap = 24 * time.Hour
+	maxSecretOverlap     = 30 * 24 * time.Hour
+)
+
+// rotateToolSecret issues a new client_secret_post secret. The old one keeps
+// authenticating for the overlap so the Tool can be reconfigured first.
+func rotateToolSecret(store Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		tenantID := chi.URLParam(r, "tenantID")
+		clientID := chi.URLParam(r, "clientID")
+
+		var req RotateSecretReq
+		if r.ContentLength != 0 {
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+				writeErr(w, http.StatusBadRequest, "invalid JSON: "+err.Error())
+				return
+			}
+		}
+		overlap := time.Duration(req.OverlapSeconds) * time.Second
+		switch {
+		case req.OverlapSeconds < 0 || overlap > maxSecretOverlap:
+			writeErr(w, http.StatusBadRequest, "overlap_seconds must be between 0 and 2592000")
+			return
+		case overlap == 0:
+			overlap = defaultSecretOverlap
+		}
+
+		secret, err := newClientSecret()
+		if err != nil {
+			writeErr(w, http.StatusInternalServerError, "secret generation failed")
+			return
+		}
+		hash, err := bcrypt.GenerateFromPassword([]byte(secret), bcrypt.DefaultCost)
+		if err != nil {
+			writeErr(w, http.StatusInternalServerError, "secret hashing failed")
+			return
+		}
+		until := time.Now().Add(overlap).UTC()
+		if err := store.RotateToolSecret(r.Context(), tenantID, clientID, string(hash), until); err != nil {
+			if errors.Is(err, NotFound) {
+				writeErr(w, http.StatusNotFound, "tool not found")
+				return
+			}
+			writeErr(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		w.Header().Set("Cache-Control", "no-store")
+		writeJSON(w, http.StatusOK, RotateSecretResp{
+			ClientID:                 clientID,
+			ClientSecret:             secret,
+			PreviousSecretValidUntil: until,
+		})
+	}
+}
+
+func revokePreviousToolSecret(store Store) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		tenantID := chi.URLParam(r, "tenantID")
+		clientID := chi.URLParam(r, "clientID")
+		if err := store.RevokePreviousToolSecret(r.Context(), tenantID, clientID); err != nil {
+			if errors.Is(err, NotFound) {
+				writeErr(w, http.StatusNotFound, "tool not found")
+				return
+			}
+			writeErr(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
 /* ---------------------------- Deployments --------------------------------- */
 
 func createDeployment(store Store) http.HandlerFunc {
@@ -227,11 +330,14 @@ func createDeployment(store Store) http.HandlerFunc {
 			Title:     strings.TrimSpace(req.Title),
 		}
 		if err := store.CreateDeployment(r.Context(), d); err != nil {
-			if errors.Is(err, NotFound) {
+			switch {
+			case errors.Is(err, NotFound):
 				writeErr(w, http.StatusNotFound, "tenant or tool not found")
-				return
+			case errors.Is(err, Conflict):
+				writeErr(w, http.StatusConflict, "deployment already exists")
+			default:
+				writeErr(w, http.StatusInternalServerError, err.Error())
 			}
-			writeErr(w, http.StatusInternalServerError, err.Error())
 			return
 		}
 		writeJSON(w, http.StatusCreated, d)
@@ -315,12 +421,22 @@ func validateCreateToolReq(req CreateToolReq) string {
 			return "redirect_uris must contain only http(s) URLs"
 		}
 	}
+	if !lti.ValidRedirectMatch(strings.TrimSpace(req.RedirectMatch)) {
+		return "redirect_match must be one of exact, prefix, ignore_query"
+	}
 	if len(req.AuthMethods) == 0 {
 		return "auth_methods is required (e.g., [\"private_key_jwt\"])"
 	}
 	return ""
 }
 
+func redirectMatchOrDefault(m string) string {
+	if m = strings.TrimSpace(m); m == "" {
+		return lti.RedirectExact
+	}
+	return m
+}
+
 func validateCreateDeploymentReq(req CreateDeploymentReq) string {
 	if strings.TrimSpace(req.ID) == "" {
 		return "id (deployment_id) is required"
@@ -379,6 +495,15 @@ func writeErr(w http.ResponseWriter, status int, msg string) {
 	writeJSON(w, status, errResp{Error: msg})
 }
 
+// newClientSecret returns 32 random bytes, base64url encoded.
+func newClientSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
 // trimAll trims whitespace from every string in the slice and removes empties.
 func trimAll(xs []string) []string {
 	out := make([]string, 0, len(xs))
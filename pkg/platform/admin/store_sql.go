@@ -0,0 +1,295 @@
+// pkg/platform/admin/store_sql.go
+package admin
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/mind-engage/mindengage-lms/pkg/platform/storage"
+	"github.com/mind-engage/mindengage-lms/pkg/platform/tenants"
+)
+
+/*
+SQLStore implements Store over the tools / deployments tables (see
+pkg/platform/storage/migrations.go). List columns are stored as JSON arrays.
+
+Client secrets are only written by RotateToolSecret: the current secret_hash
+moves to prev_secret_hash with its expiry, and RevokePreviousToolSecret clears
+both. lti.SQLOAuthRegistry reads them back for the token endpoint. All queries
+on tenant data go through storage.Tenant.
+*/
+type SQLStore struct {
+	DB *sql.DB
+}
+
+var _ Store = (*SQLStore)(nil)
+
+const toolCols = `client_id, name, jwks_url, redirect_uris, redirect_match, allowed_scopes, auth_methods,
+	COALESCE(secret_hash,''), COALESCE(prev_secret_hash,''), prev_secret_expires_at`
+
+/* ---------------------------------- Tools ---------------------------------- */
+
+func (s *SQLStore) CreateTool(ctx context.Context, t tenants.Tool) error {
+	tq, err := storage.Tenant(s.DB, t.TenantID)
+	if err != nil {
+		return err
+	}
+	if err := s.tenantExists(ctx, t.TenantID); err != nil {
+		return err
+	}
+	// client_id is unique across tenants; never reveal whose it is.
+	var n int
+	if err := s.DB.QueryRowContext(ctx, `SELECT COUNT(*) FROM tools WHERE client_id=$1`, t.ClientID).Scan(&n); err != nil {
+		return err
+	}
+	if n > 0 {
+		return Conflict
+	}
+	redirects, scopes, methods, err := toolLists(t)
+	if err != nil {
+		return err
+	}
+	_, err = tq.Insert(ctx, "tools",
+		"client_id, name, jwks_url, redirect_uris, redirect_match, allowed_scopes, auth_methods", "",
+		t.ClientID, t.Name, t.JWKSURL, redirects, t.RedirectMatch, scopes, methods)
+	return err
+}
+
+func (s *SQLStore) GetTool(ctx context.Context, tenantID, clientID string) (tenants.Tool, error) {
+	tq, err := storage.Tenant(s.DB, tenantID)
+	if err != nil {
+		return tenants.Tool{}, err
+	}
+	t, err := scanTool(tenantID, tq.QueryRow(ctx, "tools", toolCols, "client_id=$2", "", clientID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return tenants.Tool{}, NotFound
+	}
+	return t, err
+}
+
+func (s *SQLStore) ListTools(ctx context.Context, tenantID string, offset, limit int) ([]tenants.Tool, error) {
+	tq, err := storage.Tenant(s.DB, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := tq.Query(ctx, "tools", toolCols, "", "ORDER BY client_id LIMIT $2 OFFSET $3", limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []tenants.Tool
+	for rows.Next() {
+		t, err := scanTool(tenantID, rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, t)
+	}
+	return out, rows.Err()
+}
+
+// UpdateTool replaces the tool's registration; its secrets are left alone.
+func (s *SQLStore) UpdateTool(ctx context.Context, t tenants.Tool) error {
+	tq, err := storage.Tenant(s.DB, t.TenantID)
+	if err != nil {
+		return err
+	}
+	redirects, scopes, methods, err := toolLists(t)
+	if err != nil {
+		return err
+	}
+	res, err := tq.Update(ctx, "tools", `name=$3, jwks_url=$4, redirect_uris=$5, redirect_match=$6,
+		allowed_scopes=$7, auth_methods=$8, updated_at=CURRENT_TIMESTAMP`, "client_id=$2",
+		t.ClientID, t.Name, t.JWKSURL, redirects, t.RedirectMatch, scopes, methods)
+	return affected(res, err)
+}
+
+func (s *SQLStore) DeleteTool(ctx context.Context, tenantID, clientID string) error {
+	tq, err := storage.Tenant(s.DB, tenantID)
+	if err != nil {
+		return err
+	}
+	return affected(tq.Delete(ctx, "tools", "client_id=$2", clientID))
+}
+
+func (s *SQLStore) RotateToolSecret(ctx context.Context, tenantID, clientID, secretHash string, prevExpiresAt time.Time) error {
+	tq, err := storage.Tenant(s.DB, tenantID)
+	if err != nil {
+		return err
+	}
+	// SET expressions read the row as it was, so prev_secret_hash gets the
+	// outgoing secret (NULL on a first rotation, which accepts nothing).
+	res, err := tq.Update(ctx, "tools", `prev_secret_hash=secret_hash, prev_secret_expires_at=$4,
+		secret_hash=$3, updated_at=CURRENT_TIMESTAMP`, "client_id=$2",
+		clientID, secretHash, prevExpiresAt.UTC())
+	return affected(res, err)
+}
+
+func (s *SQLStore) RevokePreviousToolSecret(ctx context.Context, tenantID, clientID string) error {
+	tq, err := storage.Tenant(s.DB, tenantID)
+	if err != nil {
+		return err
+	}
+	return affected(tq.Update(ctx, "tools",
+		`prev_secret_hash=NULL, prev_secret_expires_at=NULL, updated_at=CURRENT_TIMESTAMP`,
+		"client_id=$2", clientID))
+}
+
+/* ------------------------------- Deployments ------------------------------- */
+
+func (s *SQLStore) CreateDeployment(ctx context.Context, d tenants.Deployment) error {
+	tq, err := storage.Tenant(s.DB, d.TenantID)
+	if err != nil {
+		return err
+	}
+	// The tool must belong to this tenant, not just exist.
+	if _, err := s.GetTool(ctx, d.TenantID, d.ClientID); err != nil {
+		return err
+	}
+	var n int
+	if err := s.DB.QueryRowContext(ctx, `SELECT COUNT(*) FROM deployments WHERE id=$1`, d.ID).Scan(&n); err != nil {
+		return err
+	}
+	if n > 0 {
+		return Conflict
+	}
+	_, err = tq.Insert(ctx, "deployments", "id, client_id, context_id, title", "",
+		d.ID, d.ClientID, d.ContextID, nullIfEmpty(d.Title))
+	return err
+}
+
+func (s *SQLStore) GetDeployment(ctx context.Context, tenantID, id string) (tenants.Deployment, error) {
+	tq, err := storage.Tenant(s.DB, tenantID)
+	if err != nil {
+		return tenants.Deployment{}, err
+	}
+	d := tenants.Deployment{TenantID: tenantID}
+	err = tq.QueryRow(ctx, "deployments", `id, client_id, context_id, COALESCE(title,'')`, "id=$2", "", id).
+		Scan(&d.ID, &d.ClientID, &d.ContextID, &d.Title)
+	if errors.Is(err, sql.ErrNoRows) {
+		return tenants.Deployment{}, NotFound
+	}
+	return d, err
+}
+
+func (s *SQLStore) ListDeployments(ctx context.Context, tenantID string, filter DeploymentFilter, offset, limit int) ([]tenants.Deployment, error) {
+	tq, err := storage.Tenant(s.DB, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	cond := ""
+	var args []any
+	and := func(clause string, v any) {
+		args = append(args, v)
+		if cond != "" {
+			cond += " AND "
+		}
+		cond += clause + "=$" + strconv.Itoa(len(args)+1)
+	}
+	if filter.ClientID != "" {
+		and("client_id", filter.ClientID)
+	}
+	if filter.ContextID != "" {
+		and("context_id", filter.ContextID)
+	}
+	args = append(args, limit, offset)
+	tail := `ORDER BY id LIMIT $` + strconv.Itoa(len(args)) + ` OFFSET $` + strconv.Itoa(len(args)+1)
+	rows, err := tq.Query(ctx, "deployments", `id, client_id, context_id, COALESCE(title,'')`, cond, tail, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []tenants.Deployment
+	for rows.Next() {
+		d := tenants.Deployment{TenantID: tenantID}
+		if err := rows.Scan(&d.ID, &d.ClientID, &d.ContextID, &d.Title); err != nil {
+			return nil, err
+		}
+		out = append(out, d)
+	}
+	return out, rows.Err()
+}
+
+func (s *SQLStore) DeleteDeployment(ctx context.Context, tenantID, id string) error {
+	tq, err := storage.Tenant(s.DB, tenantID)
+	if err != nil {
+		return err
+	}
+	return affected(tq.Delete(ctx, "deployments", "id=$2", id))
+}
+
+/* --------------------------------- helpers --------------------------------- */
+
+func (s *SQLStore) tenantExists(ctx context.Context, tenantID string) error {
+	var n int
+	if err := s.DB.QueryRowContext(ctx, `SELECT COUNT(*) FROM tenants WHERE id=$1`, tenantID).Scan(&n); err != nil {
+		return err
+	}
+	if n == 0 {
+		return NotFound
+	}
+	return nil
+}
+
+func scanTool(tenantID string, row storage.Row) (tenants.Tool, error) {
+	var (
+		t                          = tenants.Tool{TenantID: tenantID}
+		redirects, scopes, methods string
+		prevExpires                sql.NullTime
+	)
+	if err := row.Scan(&t.ClientID, &t.Name, &t.JWKSURL, &redirects, &t.RedirectMatch, &scopes, &methods,
+		&t.SecretHash, &t.PrevSecretHash, &prevExpires); err != nil {
+		return tenants.Tool{}, err
+	}
+	if err := json.Unmarshal([]byte(redirects), &t.RedirectURIs); err != nil {
+		return tenants.Tool{}, fmt.Errorf("admin: tool %q redirect_uris: %w", t.ClientID, err)
+	}
+	if err := json.Unmarshal([]byte(scopes), &t.AllowedScopes); err != nil {
+		return tenants.Tool{}, fmt.Errorf("admin: tool %q allowed_scopes: %w", t.ClientID, err)
+	}
+	if err := json.Unmarshal([]byte(methods), &t.AuthMethods); err != nil {
+		return tenants.Tool{}, fmt.Errorf("admin: tool %q auth_methods: %w", t.ClientID, err)
+	}
+	if prevExpires.Valid {
+		t.PrevSecretExpiresAt = prevExpires.Time.UTC()
+	}
+	return t, nil
+}
+
+// toolLists encodes the tool's list columns; nil lists are stored as [].
+func toolLists(t tenants.Tool) (redirects, scopes, methods string, err error) {
+	enc := func(xs []string) string {
+		if xs == nil {
+			xs = []string{}
+		}
+		b, e := json.Marshal(xs)
+		if e != nil && err == nil {
+			err = e
+		}
+		return string(b)
+	}
+	return enc(t.RedirectURIs), enc(t.AllowedScopes), enc(t.AuthMethods), err
+}
+
+// affected maps "no row matched" to NotFound.
+func affected(res sql.Result, err error) error {
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return NotFound
+	}
+	return nil
+}
+
+func nullIfEmpty(s string) any {
+	if s == "" {
+		return nil
+	}
+	return s
+}
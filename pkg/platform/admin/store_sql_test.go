@@ -0,0 +1,170 @@
+package admin_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	_ "modernc.org/sqlite"
+
+	"github.com/mind-engage/mindengage-lms/pkg/platform/admin"
+	"github.com/mind-engage/mindengage-lms/pkg/platform/lti"
+	"github.com/mind-engage/mindengage-lms/pkg/platform/storage"
+)
+
+type staticIssuer string
+
+func (s staticIssuer) IssuerForTenant(context.Context, string) (string, error) { return string(s), nil }
+
+func newSQLStore(t *testing.T) *admin.SQLStore {
+	t.Helper()
+	ctx := context.Background()
+	db, err := storage.Connect(ctx, "sqlite", filepath.Join(t.TempDir(), "platform.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := storage.Up(ctx, db, "sqlite"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.SQL.Exec(`INSERT INTO tenants (id, issuer) VALUES ('default', 'https://lti.example.com'), ('other', 'https://other.example.com')`); err != nil {
+		t.Fatal(err)
+	}
+	return &admin.SQLStore{DB: db.SQL}
+}
+
+func serve(h http.Handler, method, target, body string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(method, target, strings.NewReader(body)))
+	return rec
+}
+
+const toolJSON = `{"ClientID":"tool-1","Name":"Quiz","JWKSURL":"https://tool.example/jwks",
+	"RedirectURIs":["https://tool.example/launch"],"AuthMethods":["client_secret_post"]}`
+
+func TestSQLStore_ToolsAndDeployments(t *testing.T) {
+	adm := admin.Routes(newSQLStore(t))
+
+	if rec := serve(adm, http.MethodPost, "/tenants/default/tools", toolJSON); rec.Code != http.StatusCreated {
+		t.Fatalf("create tool: %d %s", rec.Code, rec.Body)
+	}
+	// client_id is global: another tenant cannot take it either.
+	if rec := serve(adm, http.MethodPost, "/tenants/other/tools", toolJSON); rec.Code != http.StatusConflict {
+		t.Fatalf("duplicate client_id: %d", rec.Code)
+	}
+	if rec := serve(adm, http.MethodGet, "/tenants/other/tools/tool-1", ""); rec.Code != http.StatusNotFound {
+		t.Fatalf("other tenant's tool: %d", rec.Code)
+	}
+	rec := serve(adm, http.MethodPut, "/tenants/default/tools/tool-1", strings.Replace(toolJSON, `"Quiz"`, `"Quiz v2"`, 1))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("update tool: %d %s", rec.Code, rec.Body)
+	}
+	rec = serve(adm, http.MethodGet, "/tenants/default/tools/tool-1", "")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"Quiz v2"`) || strings.Contains(rec.Body.String(), "Secret") {
+		t.Fatalf("get tool: %d %s", rec.Code, rec.Body)
+	}
+
+	dep := `{"ID":"dep-1","ClientID":"tool-1","ContextID":"ctx-1"}`
+	if rec := serve(adm, http.MethodPost, "/tenants/other/deployments", dep); rec.Code != http.StatusNotFound {
+		t.Fatalf("deployment of another tenant's tool: %d", rec.Code)
+	}
+	if rec := serve(adm, http.MethodPost, "/tenants/default/deployments", dep); rec.Code != http.StatusCreated {
+		t.Fatalf("create deployment: %d %s", rec.Code, rec.Body)
+	}
+	rec = serve(adm, http.MethodGet, "/tenants/default/deployments?client_id=tool-1&context_id=ctx-1", "")
+	var deps []map[string]any
+	if err := json.Unmarshal(rec.Body.Bytes(), &deps); err != nil || len(deps) != 1 {
+		t.Fatalf("list deployments: %d %s", rec.Code, rec.Body)
+	}
+	rec = serve(adm, http.MethodGet, "/tenants/default/deployments?context_id=ctx-2", "")
+	if err := json.Unmarshal(rec.Body.Bytes(), &deps); err != nil || len(deps) != 0 {
+		t.Fatalf("filtered deployments: %d %s", rec.Code, rec.Body)
+	}
+
+	if rec := serve(adm, http.MethodDelete, "/tenants/default/tools/tool-1", ""); rec.Code != http.StatusNoContent {
+		t.Fatalf("delete tool: %d", rec.Code)
+	}
+	if rec := serve(adm, http.MethodGet, "/tenants/default/deployments/dep-1", ""); rec.Code != http.StatusNotFound {
+		t.Fatalf("deployment outlived its tool: %d", rec.Code)
+	}
+}
+
+func TestSQLStore_SecretRotationGraceAndRevoke(t *testing.T) {
+	store := newSQLStore(t)
+	adm := admin.Routes(store)
+	if rec := serve(adm, http.MethodPost, "/tenants/default/tools", toolJSON); rec.Code != http.StatusCreated {
+		t.Fatalf("create tool: %d %s", rec.Code, rec.Body)
+	}
+	rotate := func(body string) admin.RotateSecretResp {
+		t.Helper()
+		rec := serve(adm, http.MethodPost, "/tenants/default/tools/tool-1/secret/rotate", body)
+		var resp admin.RotateSecretResp
+		if rec.Code != http.StatusOK || json.Unmarshal(rec.Body.Bytes(), &resp) != nil || resp.ClientSecret == "" {
+			t.Fatalf("rotate: %d %s", rec.Code, rec.Body)
+		}
+		return resp
+	}
+
+	now := time.Now()
+	ts := &lti.TokenServer{
+		ResolveTenantID: func(*http.Request) (string, error) { return "default", nil },
+		Issuers:         staticIssuer("https://lti.example.com"),
+		Registry:        lti.SQLOAuthRegistry{DB: store.DB},
+		Signer:          &lti.KeyManager{Storage: lti.NewInMemoryKeyStorage(), RSAKeyBits: 1024},
+		Now:             func() time.Time { return now },
+	}
+	token := func(secret string) int {
+		form := url.Values{"grant_type": {"client_credentials"}, "client_id": {"tool-1"}, "client_secret": {secret}}
+		req := httptest.NewRequest(http.MethodPost, "/oauth/token", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rec := httptest.NewRecorder()
+		ts.Handler()(rec, req)
+		return rec.Code
+	}
+
+	first := rotate("")
+	if code := token(first.ClientSecret); code != http.StatusOK {
+		t.Fatalf("first secret: status %d", code)
+	}
+	second := rotate(`{"overlap_seconds":3600}`)
+	for name, secret := range map[string]string{"new": second.ClientSecret, "previous": first.ClientSecret} {
+		if code := token(secret); code != http.StatusOK {
+			t.Fatalf("%s secret during overlap: status %d", name, code)
+		}
+	}
+
+	// The overlap ends on its own ...
+	now = second.PreviousSecretValidUntil.Add(time.Second)
+	if code := token(first.ClientSecret); code != http.StatusUnauthorized {
+		t.Fatalf("previous secret after overlap: status %d", code)
+	}
+	if code := token(second.ClientSecret); code != http.StatusOK {
+		t.Fatalf("new secret after overlap: status %d", code)
+	}
+
+	// ... or when revoked.
+	now = time.Now()
+	third := rotate("")
+	if code := token(second.ClientSecret); code != http.StatusOK {
+		t.Fatalf("previous secret before revoke: status %d", code)
+	}
+	if rec := serve(adm, http.MethodDelete, "/tenants/default/tools/tool-1/secret/previous", ""); rec.Code != http.StatusNoContent {
+		t.Fatalf("revoke: %d %s", rec.Code, rec.Body)
+	}
+	if code := token(second.ClientSecret); code != http.StatusUnauthorized {
+		t.Fatalf("previous secret after revoke: status %d", code)
+	}
+	if code := token(third.ClientSecret); code != http.StatusOK {
+		t.Fatalf("new secret after revoke: status %d", code)
+	}
+
+	if rec := serve(adm, http.MethodPost, "/tenants/other/tools/tool-1/secret/rotate", ""); rec.Code != http.StatusNotFound {
+		t.Fatalf("rotate another tenant's tool: %d", rec.Code)
+	}
+}
@@ -3,38 +3,47 @@ package config
 import "time"
 
 type TLS struct {
-	CertFile string
-	KeyFile  string
+	CertFile string `json:"cert_file"`
+	KeyFile  string `json:"key_file"`
 }
 
 type DB struct {
-	Driver string // postgres|sqlite
-	DSN    string
+	Driver string `json:"driver"` // postgres|sqlite
+	DSN    string `json:"dsn"`
 }
 
 type Issuer struct {
 	// Base like "https://{tenant}.lti.mindengage.com"
-	BaseURL       string
-	HostIsTenant  bool   // true if {tenant}.domain
-	PathTenantKey string // e.g. "/t/{tenant}" when HostIsTenant=false
+	BaseURL       string `json:"base_url"`
+	HostIsTenant  bool   `json:"host_is_tenant"`  // true if {tenant}.domain
+	PathTenantKey string `json:"path_tenant_key"` // e.g. "/t/{tenant}" when HostIsTenant=false
+	DefaultTenant string `json:"default_tenant"`  // used when a request names no tenant
 }
 
 type Keys struct {
-	RotateEvery time.Duration
-	GracePeriod time.Duration
-	KeystoreURI string // e.g., file://, kms://
+	RotateEvery time.Duration `json:"rotate_every"` // "2160h" in files/env
+	GracePeriod time.Duration `json:"grace_period"`
+	KeystoreURI string        `json:"keystore_uri"` // e.g., file://, kms://
+	MasterKey   string        `json:"master_key"`   // base64, 32 bytes; seals private keys in the DB
 }
 
 type Platform struct {
-	Bind                 string // ":8443"
-	TenantHeader         string // optional override for tenancy
-	DevAllowClientSecret bool
+	Bind                 string `json:"bind"`          // ":8443"
+	TenantHeader         string `json:"tenant_header"` // optional override for tenancy
+	DevAllowClientSecret bool   `json:"dev_allow_client_secret"`
+}
+
+type AGS struct {
+	// Rescale stored results when a line item's scoreMaximum changes
+	// (default: keep them on the scale they were graded against).
+	RescaleOnMaxChange bool `json:"rescale_on_max_change"`
 }
 
 type Config struct {
-	TLS      TLS
-	DB       DB
-	Issuer   Issuer
-	Keys     Keys
-	Platform Platform
+	TLS      TLS      `json:"tls"`
+	DB       DB       `json:"db"`
+	Issuer   Issuer   `json:"issuer"`
+	Keys     Keys     `json:"keys"`
+	Platform Platform `json:"platform"`
+	AGS      AGS      `json:"ags"`
 }
@@ -0,0 +1,232 @@
+package config
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mind-engage/mindengage-lms/pkg/platform/tenants"
+)
+
+// Defaults applied before the file and environment are read.
+const (
+	DefaultBind        = ":8080"
+	DefaultRotateEvery = 90 * 24 * time.Hour
+	DefaultGracePeriod = 7 * 24 * time.Hour
+)
+
+// Load builds the platformd config: defaults, then the JSON file at path (if
+// non-empty), then PLATFORM_* environment variables, which win. The result is
+// validated; the error lists every problem found.
+func Load(path string) (Config, error) {
+	cfg := Config{
+		Platform: Platform{Bind: DefaultBind},
+		Keys:     Keys{RotateEvery: DefaultRotateEvery, GracePeriod: DefaultGracePeriod},
+	}
+	if path != "" {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return Config{}, fmt.Errorf("config: read %s: %w", path, err)
+		}
+		dec := json.NewDecoder(strings.NewReader(string(b)))
+		dec.DisallowUnknownFields() // typos should not silently fall back to defaults
+		if err := dec.Decode(&cfg); err != nil {
+			return Config{}, fmt.Errorf("config: parse %s: %w", path, err)
+		}
+	}
+	if err := cfg.applyEnv(os.LookupEnv); err != nil {
+		return Config{}, err
+	}
+	if cfg.DB.DSN != "" && cfg.DB.Driver == "" {
+		cfg.DB.Driver = "postgres"
+	}
+	if err := cfg.Validate(); err != nil {
+		return Config{}, err
+	}
+	return cfg, nil
+}
+
+func (c *Config) applyEnv(lookup func(string) (string, bool)) error {
+	var errs []error
+	str := func(key string, dst *string) {
+		if v, ok := lookup(key); ok {
+			*dst = strings.TrimSpace(v)
+		}
+	}
+	boolean := func(key string, dst *bool) {
+		if v, ok := lookup(key); ok {
+			b, err := strconv.ParseBool(strings.TrimSpace(v))
+			if err != nil {
+				errs = append(errs, fmt.Errorf("config: %s: want true/false, got %q", key, v))
+				return
+			}
+			*dst = b
+		}
+	}
+	dur := func(key string, dst *time.Duration) {
+		if v, ok := lookup(key); ok {
+			d, err := time.ParseDuration(strings.TrimSpace(v))
+			if err != nil {
+				errs = append(errs, fmt.Errorf("config: %s: want a duration like 720h, got %q", key, v))
+				return
+			}
+			*dst = d
+		}
+	}
+
+	str("PLATFORM_BIND", &c.Platform.Bind)
+	str("PLATFORM_TENANT_HEADER", &c.Platform.TenantHeader)
+	boolean("PLATFORM_DEV_ALLOW_CLIENT_SECRET", &c.Platform.DevAllowClientSecret)
+
+	str("PLATFORM_ISSUER_BASE_URL", &c.Issuer.BaseURL)
+	boolean("PLATFORM_HOST_IS_TENANT", &c.Issuer.HostIsTenant)
+	str("PLATFORM_PATH_TENANT_KEY", &c.Issuer.PathTenantKey)
+	str("PLATFORM_DEFAULT_TENANT", &c.Issuer.DefaultTenant)
+
+	str("PLATFORM_DB_DRIVER", &c.DB.Driver)
+	str("PLATFORM_DB_DSN", &c.DB.DSN)
+
+	dur("PLATFORM_KEYS_ROTATE_EVERY", &c.Keys.RotateEvery)
+	dur("PLATFORM_KEYS_GRACE_PERIOD", &c.Keys.GracePeriod)
+	str("PLATFORM_KEYSTORE_URI", &c.Keys.KeystoreURI)
+	str("PLATFORM_KEYS_MASTER_KEY", &c.Keys.MasterKey)
+
+	boolean("PLATFORM_AGS_RESCALE_ON_MAX_CHANGE", &c.AGS.RescaleOnMaxChange)
+
+	str("PLATFORM_TLS_CERT_FILE", &c.TLS.CertFile)
+	str("PLATFORM_TLS_KEY_FILE", &c.TLS.KeyFile)
+	return errors.Join(errs...)
+}
+
+// Validate reports missing or inconsistent settings.
+func (c Config) Validate() error {
+	var errs []error
+	bad := func(format string, args ...any) { errs = append(errs, fmt.Errorf("config: "+format, args...)) }
+
+	if c.Platform.Bind == "" {
+		bad("platform.bind (PLATFORM_BIND) is required")
+	}
+
+	if c.Issuer.BaseURL == "" {
+		bad("issuer.base_url (PLATFORM_ISSUER_BASE_URL) is required")
+	} else if u, err := url.Parse(strings.ReplaceAll(c.Issuer.BaseURL, "{tenant}", "t")); err != nil || u.Host == "" ||
+		(u.Scheme != "https" && u.Scheme != "http") {
+		bad("issuer.base_url %q must be an absolute http(s) URL", c.Issuer.BaseURL)
+	}
+	if c.Issuer.HostIsTenant && !strings.Contains(c.Issuer.BaseURL, "{tenant}.") {
+		bad("issuer.base_url must start the host with {tenant}. when host_is_tenant is set")
+	}
+	if !c.Issuer.HostIsTenant && c.Issuer.PathTenantKey != "" && !strings.HasSuffix(c.Issuer.PathTenantKey, "/{tenant}") {
+		bad("issuer.path_tenant_key %q must end in /{tenant}", c.Issuer.PathTenantKey)
+	}
+
+	if c.DB.DSN != "" {
+		switch c.DB.Driver {
+		case "postgres", "sqlite":
+		default:
+			bad("db.driver %q must be postgres or sqlite", c.DB.Driver)
+		}
+	}
+
+	if c.Keys.RotateEvery <= 0 {
+		bad("keys.rotate_every must be positive")
+	}
+	if c.Keys.GracePeriod < 0 || c.Keys.GracePeriod >= c.Keys.RotateEvery {
+		bad("keys.grace_period (%v) must be non-negative and shorter than keys.rotate_every (%v)", c.Keys.GracePeriod, c.Keys.RotateEvery)
+	}
+
+	if c.Keys.MasterKey != "" {
+		if k, err := base64.StdEncoding.DecodeString(c.Keys.MasterKey); err != nil || len(k) != 32 {
+			bad("keys.master_key (PLATFORM_KEYS_MASTER_KEY) must be 32 bytes, base64-encoded")
+		}
+	}
+	if strings.HasPrefix(c.Keys.KeystoreURI, "kms://") {
+		// No KMS provider client is wired, so every wrap/unwrap would fail.
+		bad("keys.keystore_uri: kms:// is not supported yet; use keys.master_key")
+	}
+	if c.DB.DSN != "" && c.Keys.MasterKey == "" {
+		bad("keys.master_key is required to store signing keys in the database")
+	}
+
+	if (c.TLS.CertFile == "") != (c.TLS.KeyFile == "") {
+		bad("tls.cert_file and tls.key_file must be set together")
+	}
+	return errors.Join(errs...)
+}
+
+// IssuerFor is the issuer URL published for tenant.
+func (c Config) IssuerFor(tenant string) string {
+	base := strings.TrimSuffix(c.Issuer.BaseURL, "/")
+	if !c.Issuer.HostIsTenant && c.Issuer.PathTenantKey != "" {
+		base += c.Issuer.PathTenantKey
+	}
+	return strings.ReplaceAll(base, "{tenant}", tenant)
+}
+
+// TenantOptions translates the issuer settings for tenants.NewResolver.
+func (c Config) TenantOptions() tenants.Options {
+	opts := tenants.Options{
+		HostIsTenant:  c.Issuer.HostIsTenant,
+		HeaderKey:     c.Platform.TenantHeader,
+		DefaultTenant: c.Issuer.DefaultTenant,
+	}
+	if u, err := url.Parse(strings.ReplaceAll(c.Issuer.BaseURL, "{tenant}.", "")); err == nil {
+		opts.BaseDomain = u.Host
+		opts.ForceHTTPS = u.Scheme == "https"
+	}
+	if !c.Issuer.HostIsTenant {
+		opts.PathPrefix = strings.TrimSuffix(c.Issuer.PathTenantKey, "/{tenant}")
+	}
+	return opts
+}
+
+// UnmarshalJSON accepts durations as strings ("720h") or nanoseconds.
+func (k *Keys) UnmarshalJSON(b []byte) error {
+	var raw struct {
+		RotateEvery json.RawMessage `json:"rotate_every"`
+		GracePeriod json.RawMessage `json:"grace_period"`
+		KeystoreURI string          `json:"keystore_uri"`
+		MasterKey   string          `json:"master_key"`
+	}
+	dec := json.NewDecoder(strings.NewReader(string(b)))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&raw); err != nil {
+		return err
+	}
+	k.KeystoreURI = raw.KeystoreURI
+	k.MasterKey = raw.MasterKey
+	if err := parseJSONDuration(raw.RotateEvery, &k.RotateEvery); err != nil {
+		return fmt.Errorf("keys.rotate_every: %w", err)
+	}
+	if err := parseJSONDuration(raw.GracePeriod, &k.GracePeriod); err != nil {
+		return fmt.Errorf("keys.grace_period: %w", err)
+	}
+	return nil
+}
+
+func parseJSONDuration(raw json.RawMessage, dst *time.Duration) error {
+	if len(raw) == 0 {
+		return nil // keep the default
+	}
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		*dst = d
+		return nil
+	}
+	var n int64
+	if err := json.Unmarshal(raw, &n); err != nil {
+		return errors.New("want a duration string like \"720h\"")
+	}
+	*dst = time.Duration(n)
+	return nil
+}
@@ -0,0 +1,99 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mind-engage/mindengage-lms/pkg/platform/config"
+)
+
+func writeConfig(t *testing.T, body string) string {
+	t.Helper()
+	p := filepath.Join(t.TempDir(), "platformd.json")
+	if err := os.WriteFile(p, []byte(body), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestLoad_EnvOverridesFile(t *testing.T) {
+	p := writeConfig(t, `{
+		"platform": {"bind": ":9000"},
+		"issuer": {"base_url": "https://{tenant}.lti.example.com", "host_is_tenant": true},
+		"db": {"driver": "sqlite", "dsn": "file:platform.db"},
+		"keys": {"rotate_every": "720h", "grace_period": "48h", "master_key": "AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA="}
+	}`)
+	t.Setenv("PLATFORM_BIND", ":9443")
+	t.Setenv("PLATFORM_KEYS_GRACE_PERIOD", "24h")
+
+	cfg, err := config.Load(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Platform.Bind != ":9443" {
+		t.Errorf("bind = %q, want env value", cfg.Platform.Bind)
+	}
+	if cfg.Keys.RotateEvery != 720*time.Hour || cfg.Keys.GracePeriod != 24*time.Hour {
+		t.Errorf("keys = %+v", cfg.Keys)
+	}
+	if cfg.DB.Driver != "sqlite" || cfg.DB.DSN != "file:platform.db" {
+		t.Errorf("db = %+v", cfg.DB)
+	}
+	if got := cfg.IssuerFor("acme"); got != "https://acme.lti.example.com" {
+		t.Errorf("issuer = %q", got)
+	}
+	opts := cfg.TenantOptions()
+	if opts.BaseDomain != "lti.example.com" || !opts.HostIsTenant || !opts.ForceHTTPS {
+		t.Errorf("tenant options = %+v", opts)
+	}
+}
+
+func TestLoad_EnvOnlyWithDefaults(t *testing.T) {
+	t.Setenv("PLATFORM_ISSUER_BASE_URL", "http://localhost:8080")
+	t.Setenv("PLATFORM_PATH_TENANT_KEY", "/t/{tenant}")
+
+	cfg, err := config.Load("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Platform.Bind != config.DefaultBind || cfg.Keys.RotateEvery != config.DefaultRotateEvery || cfg.Keys.GracePeriod != config.DefaultGracePeriod {
+		t.Errorf("defaults not applied: %+v", cfg)
+	}
+	if got := cfg.IssuerFor("acme"); got != "http://localhost:8080/t/acme" {
+		t.Errorf("issuer = %q", got)
+	}
+	if got := cfg.TenantOptions().PathPrefix; got != "/t" {
+		t.Errorf("path prefix = %q", got)
+	}
+}
+
+func TestLoad_InvalidConfig(t *testing.T) {
+	p := writeConfig(t, `{
+		"issuer": {"base_url": "lti.example.com"},
+		"db": {"driver": "mysql", "dsn": "x"},
+		"keys": {"rotate_every": "24h", "grace_period": "48h", "master_key": "c2hvcnQ=", "keystore_uri": "kms://platform-keys"},
+		"tls": {"cert_file": "cert.pem"}
+	}`)
+	_, err := config.Load(p)
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	for _, want := range []string{"issuer.base_url", "db.driver", "keys.grace_period", "keys.master_key", "keys.keystore_uri", "tls.cert_file"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not mention %s", err, want)
+		}
+	}
+
+	t.Setenv("PLATFORM_ISSUER_BASE_URL", "https://lti.example.com")
+	t.Setenv("PLATFORM_KEYS_ROTATE_EVERY", "90 days")
+	if _, err := config.Load(""); err == nil || !strings.Contains(err.Error(), "PLATFORM_KEYS_ROTATE_EVERY") {
+		t.Errorf("bad env duration: err = %v", err)
+	}
+
+	if _, err := config.Load(writeConfig(t, `{"platfrom": {}}`)); err == nil {
+		t.Error("unknown field accepted")
+	}
+}
@@ -0,0 +1,136 @@
+// pkg/platform/lti/access_token.go
+package lti
+
+import (
+	"context"
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	mw "github.com/mind-engage/mindengage-lms/pkg/platform/lti/middleware"
+)
+
+/*
+Access token verification (Platform side)
+
+AccessTokenVerifier checks the Bearer tokens TokenServer mints before any
+service (AGS, NRPS, admin) trusts their scopes:
+
+  - RS256 signature by one of the tenant's keys (Keys, normally the same
+    KeyManager that signed it; retired keys still verify during overlap)
+  - typ "access", iss equal to the tenant's issuer, exp in the future
+
+The "tenant" claim only picks whose keys to check; a token naming another
+tenant fails the signature check. Mount it with middleware.BearerAuth:
+
+    verifier := &lti.AccessTokenVerifier{Keys: keyManager, Issuers: issuers}
+    r.With(mw.BearerAuth(verifier), mw.RequireScopes(mw.ScopePlatformAdmin)).Post(...)
+*/
+
+// AccessTokenVerifier implements middleware.TokenVerifier for tokens issued
+// by TokenServer.
+type AccessTokenVerifier struct {
+	Keys    JWKSProvider
+	Issuers IssuerResolver
+
+	// Optional: clock skew allowed on exp (default 30s).
+	Leeway time.Duration
+	Now    func() time.Time
+}
+
+var _ mw.TokenVerifier = (*AccessTokenVerifier)(nil)
+
+// VerifyAccessToken implements middleware.TokenVerifier.
+func (v *AccessTokenVerifier) VerifyAccessToken(ctx context.Context, raw string) (mw.AccessClaims, error) {
+	if v.Keys == nil || v.Issuers == nil {
+		return mw.AccessClaims{}, errors.New("access token verifier not configured")
+	}
+	hdr, payload, sig, err := splitJWT(raw)
+	if err != nil {
+		return mw.AccessClaims{}, errors.New("malformed token")
+	}
+	var h struct {
+		Alg string `json:"alg"`
+		KID string `json:"kid"`
+	}
+	if err := json.Unmarshal(hdr, &h); err != nil || h.Alg != "RS256" {
+		return mw.AccessClaims{}, errors.New("unsupported token alg")
+	}
+	var c struct {
+		Iss      string `json:"iss"`
+		Sub      string `json:"sub"`
+		Exp      int64  `json:"exp"`
+		Iat      int64  `json:"iat"`
+		JTI      string `json:"jti"`
+		Tenant   string `json:"tenant"`
+		ClientID string `json:"client_id"`
+		Scope    string `json:"scope"`
+		Typ      string `json:"typ"`
+	}
+	if err := json.Unmarshal(payload, &c); err != nil {
+		return mw.AccessClaims{}, errors.New("invalid token claims")
+	}
+	if strings.TrimSpace(c.Tenant) == "" {
+		return mw.AccessClaims{}, errors.New("token has no tenant")
+	}
+
+	set, err := v.Keys.PublicJWKS(ctx, c.Tenant)
+	if err != nil {
+		return mw.AccessClaims{}, errors.New("tenant keys unavailable")
+	}
+	pubKeys, err := RSAPublicKeysFromJWKS(set, h.KID)
+	if err != nil {
+		return mw.AccessClaims{}, errors.New("unknown signing key")
+	}
+	sum := sha256.Sum256([]byte(signingInput(raw)))
+	verified := false
+	for _, pk := range pubKeys {
+		if rsa.VerifyPKCS1v15(pk, crypto.SHA256, sum[:], sig) == nil {
+			verified = true
+			break
+		}
+	}
+	if !verified {
+		return mw.AccessClaims{}, errors.New("signature verification failed")
+	}
+
+	// Signed by the tenant: now the claims can be trusted.
+	if c.Typ != "access" {
+		return mw.AccessClaims{}, errors.New("not an access token")
+	}
+	issuer, err := v.Issuers.IssuerForTenant(ctx, c.Tenant)
+	if err != nil || issuer != c.Iss {
+		return mw.AccessClaims{}, errors.New("issuer mismatch")
+	}
+	now := v.now()
+	if c.Exp == 0 || now.After(time.Unix(c.Exp, 0).Add(v.leeway())) {
+		return mw.AccessClaims{}, errors.New("token expired")
+	}
+	return mw.AccessClaims{
+		Subject:   c.Sub,
+		TenantID:  c.Tenant,
+		ClientID:  c.ClientID,
+		Scopes:    strings.Fields(c.Scope),
+		IssuedAt:  time.Unix(c.Iat, 0),
+		ExpiresAt: time.Unix(c.Exp, 0),
+		JTI:       c.JTI,
+	}, nil
+}
+
+func (v *AccessTokenVerifier) now() time.Time {
+	if v.Now != nil {
+		return v.Now()
+	}
+	return time.Now()
+}
+
+func (v *AccessTokenVerifier) leeway() time.Duration {
+	if v.Leeway > 0 {
+		return v.Leeway
+	}
+	return 30 * time.Second
+}
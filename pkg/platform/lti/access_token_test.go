@@ -0,0 +1,107 @@
+package lti_test
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mind-engage/mindengage-lms/pkg/platform/lti"
+	mw "github.com/mind-engage/mindengage-lms/pkg/platform/lti/middleware"
+)
+
+func TestAccessTokenVerifier(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	km := &lti.KeyManager{Storage: lti.NewInMemoryKeyStorage(), RSAKeyBits: 1024}
+	v := &lti.AccessTokenVerifier{Keys: km, Issuers: staticIssuer("https://platform.example")}
+
+	claims := func(mut func(map[string]any)) map[string]any {
+		c := map[string]any{
+			"iss":       "https://platform.example",
+			"sub":       "tool",
+			"aud":       "https://platform.example",
+			"iat":       now.Unix(),
+			"exp":       now.Add(time.Hour).Unix(),
+			"jti":       "j1",
+			"tenant":    "t1",
+			"client_id": "tool",
+			"scope":     mw.ScopePlatformAdmin,
+			"typ":       "access",
+		}
+		if mut != nil {
+			mut(c)
+		}
+		return c
+	}
+	sign := func(c map[string]any) string {
+		tok, err := km.Sign(ctx, "t1", c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return tok
+	}
+
+	good := sign(claims(nil))
+	got, err := v.VerifyAccessToken(ctx, good)
+	if err != nil {
+		t.Fatalf("valid token: %v", err)
+	}
+	if got.TenantID != "t1" || got.ClientID != "tool" || len(got.Scopes) != 1 || got.Scopes[0] != mw.ScopePlatformAdmin {
+		t.Fatalf("claims = %+v", got)
+	}
+
+	parts := strings.Split(good, ".")
+	enc := base64.RawURLEncoding.EncodeToString
+	forgedPayload := enc([]byte(`{"iss":"https://platform.example","tenant":"t1","typ":"access","exp":9999999999,"scope":"` + mw.ScopePlatformAdmin + ` extra"}`))
+
+	for name, tok := range map[string]string{
+		"unsigned":        enc([]byte(`{"alg":"none"}`)) + "." + forgedPayload + ".",
+		"tampered":        parts[0] + "." + forgedPayload + "." + parts[2],
+		"expired":         sign(claims(func(c map[string]any) { c["exp"] = now.Add(-time.Hour).Unix() })),
+		"wrong issuer":    sign(claims(func(c map[string]any) { c["iss"] = "https://evil.example" })),
+		"not access":      sign(claims(func(c map[string]any) { c["typ"] = "id" })),
+		"other tenant":    sign(claims(func(c map[string]any) { c["tenant"] = "t2" })),
+		"malformed token": "not-a-jwt",
+	} {
+		if _, err := v.VerifyAccessToken(ctx, tok); err == nil {
+			t.Errorf("%s: verified, want error", name)
+		}
+	}
+}
+
+func TestRequireScopes_NeedsVerifiedToken(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(http.StatusNoContent) })
+	h := mw.RequireScopes(mw.ScopePlatformAdmin)(ok)
+
+	// Decodes fine, claims admin, but nothing verified it.
+	enc := base64.RawURLEncoding.EncodeToString
+	forged := enc([]byte(`{"alg":"none"}`)) + "." + enc([]byte(`{"scope":"`+mw.ScopePlatformAdmin+`"}`)) + "."
+	req := httptest.NewRequest(http.MethodGet, "/admin/line_items", nil)
+	req.Header.Set("Authorization", "Bearer "+forged)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("unsigned token: status %d, want 403", rec.Code)
+	}
+
+	km := &lti.KeyManager{Storage: lti.NewInMemoryKeyStorage(), RSAKeyBits: 1024}
+	tok, err := km.Sign(context.Background(), "t1", map[string]any{
+		"iss": "https://platform.example", "tenant": "t1", "typ": "access",
+		"exp": time.Now().Add(time.Hour).Unix(), "scope": mw.ScopePlatformAdmin,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	v := &lti.AccessTokenVerifier{Keys: km, Issuers: staticIssuer("https://platform.example")}
+	req = httptest.NewRequest(http.MethodGet, "/admin/line_items", nil)
+	req.Header.Set("Authorization", "Bearer "+tok)
+	rec = httptest.NewRecorder()
+	mw.BearerAuth(v)(h).ServeHTTP(rec, req)
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("verified token: status %d, want 204 (%s)", rec.Code, rec.Body)
+	}
+}
@@ -7,6 +7,9 @@ import (
 	mw "github.com/mind-engage/mindengage-lms/pkg/platform/lti/middleware"
 )
 
+// Routes mounts the AGS endpoints. Scopes come from claims verified by
+// middleware.BearerAuth (or WithScopesCtx), so mount one of them in front;
+// a bare Bearer header grants nothing.
 func Routes(s *Server) http.Handler {
 	r := chi.NewRouter()
 	// Line items collection
@@ -15,6 +18,10 @@ func Routes(s *Server) http.Handler {
 	r.With(mw.RequireScopes("https://purl.imsglobal.org/spec/lti-ags/scope/lineitem")).
 		Post("/contexts/{contextId}/line_items", s.PostLineItem)
 
+	// Platform-wide search by resourceId (admin only)
+	r.With(mw.RequireScopes(mw.ScopePlatformAdmin)).
+		Get("/line_items", s.FindLineItems)
+
 	// Item
 	r.With(mw.RequireScopes("https://purl.imsglobal.org/spec/lti-ags/scope/lineitem.readonly")).
 		Get("/line_items/{id}", s.GetLineItem)
@@ -25,9 +25,10 @@ Server implements the LTI AGS endpoints:
   POST /contexts/{contextId}/line_items
   GET  /line_items/{id}
   PUT  /line_items/{id}
-  DELETE /line_items/{id}
+  DELETE /line_items/{id}            (409 if results exist, unless ?force=1)
   POST /line_items/{id}/scores
   GET  /line_items/{id}/results
+  GET  /line_items?resource_id=...   (platform admin; across contexts)
 
 This file focuses on HTTP handling, JSON/media types, and basic pagination.
 Storage (DB) operations are abstracted behind the Storage interface below.
@@ -39,9 +40,13 @@ type Storage interface {
 	CreateLineItem(ctx context.Context, tenantID string, li LineItem) (LineItem, error)
 	GetLineItem(ctx context.Context, tenantID, lineItemID string) (LineItem, error)
 	UpdateLineItem(ctx context.Context, tenantID string, li LineItem) (LineItem, error)
-	DeleteLineItem(ctx context.Context, tenantID, lineItemID string) error
+	// DeleteLineItem removes a line item and, with force, its results.
+	// Without force it deletes nothing and returns HasResults if any exist.
+	DeleteLineItem(ctx context.Context, tenantID, lineItemID string, force bool) error
 	// List line items for a context/course with optional filters.
 	ListLineItems(ctx context.Context, tenantID, contextID string, filter ListFilter, offset, limit int) ([]LineItem, error)
+	// Find line items for a resourceId across all contexts in the tenant.
+	FindLineItemsByResource(ctx context.Context, tenantID, resourceID string, offset, limit int) ([]LineItem, error)
 
 	// Scores/Results
 	// Upsert the latest result for a user on a line item (server computes semantics).
@@ -53,6 +58,9 @@ type Storage interface {
 // NotFound sentinel to allow 404 mapping.
 var NotFound = errors.New("ags: not found")
 
+// HasResults is returned by an unforced DeleteLineItem on a graded line item.
+var HasResults = errors.New("ags: line item has results")
+
 // ListFilter narrows line item collection queries.
 type ListFilter struct {
 	ResourceID     string
@@ -204,6 +212,49 @@ func (s *Server) GetLineItems(w http.ResponseWriter, r *http.Request) {
 	_ = json.NewEncoder(w).Encode(out)
 }
 
+// FindLineItems lists line items for ?resource_id= across every context in
+// the tenant. Intended for admin grade reconciliation, not for Tools.
+func (s *Server) FindLineItems(w http.ResponseWriter, r *http.Request) {
+	tenantID, err := s.requireTenant(r)
+	if err != nil {
+		writeErr(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	q := r.URL.Query()
+	resourceID := strings.TrimSpace(q.Get("resource_id"))
+	if resourceID == "" {
+		writeErr(w, http.StatusBadRequest, "resource_id is required")
+		return
+	}
+	limit, page := parseLimitPage(q, 50, 1, 100)
+	offset := (page - 1) * limit
+
+	items, err := s.Store.FindLineItemsByResource(r.Context(), tenantID, resourceID, offset, limit)
+	if err != nil {
+		writeStorageErr(w, err)
+		return
+	}
+
+	if len(items) == limit {
+		nextURL := cloneURL(r.URL)
+		nq := nextURL.Query()
+		nq.Set("limit", strconv.Itoa(limit))
+		nq.Set("page", strconv.Itoa(page+1))
+		nextURL.RawQuery = nq.Encode()
+		w.Header().Add("Link", fmt.Sprintf("<%s>; rel=\"next\"", nextURL.String()))
+	}
+
+	out := make([]any, 0, len(items))
+	for _, it := range items {
+		m := mapLineItemOut(it)
+		m["contextId"] = it.ContextID // results span contexts
+		out = append(out, m)
+	}
+
+	w.Header().Set("Content-Type", "application/vnd.ims.lis.v2.lineitemcontainer+json")
+	_ = json.NewEncoder(w).Encode(out)
+}
+
 func (s *Server) GetLineItem(w http.ResponseWriter, r *http.Request) {
 	tenantID, err := s.requireTenant(r)
 	if err != nil {
@@ -220,6 +271,8 @@ func (s *Server) GetLineItem(w http.ResponseWriter, r *http.Request) {
 	_ = json.NewEncoder(w).Encode(mapLineItemOut(item))
 }
 
+// PutLineItem updates a line item. What a scoreMaximum change does to stored
+// results is up to the Storage (see SQLStore.OnMaxChange).
 func (s *Server) PutLineItem(w http.ResponseWriter, r *http.Request) {
 	tenantID, err := s.requireTenant(r)
 	if err != nil {
@@ -280,7 +333,16 @@ func (s *Server) DeleteLineItem(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	liID := s.absoluteLineItemIDFromPath(r)
-	if err := s.Store.DeleteLineItem(r.Context(), tenantID, liID); err != nil {
+
+	// Deleting a line item cascades to its results; refuse to drop grades
+	// unless the caller says so explicitly.
+	force := r.URL.Query().Get("force")
+	err = s.Store.DeleteLineItem(r.Context(), tenantID, liID, force == "1" || force == "true")
+	if errors.Is(err, HasResults) {
+		writeErr(w, http.StatusConflict, "line item has results; retry with ?force=1 to delete them too")
+		return
+	}
+	if err != nil {
 		writeStorageErr(w, err)
 		return
 	}
@@ -0,0 +1,139 @@
+package ags_test
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"testing"
+
+	"github.com/mind-engage/mindengage-lms/pkg/platform/lti/ags"
+	mw "github.com/mind-engage/mindengage-lms/pkg/platform/lti/middleware"
+)
+
+type memStore struct {
+	items map[string]ags.LineItem // key: id
+}
+
+func newMemStore(items ...ags.LineItem) *memStore {
+	s := &memStore{items: map[string]ags.LineItem{}}
+	for _, it := range items {
+		s.items[it.ID] = it
+	}
+	return s
+}
+
+func (s *memStore) CreateLineItem(_ context.Context, _ string, li ags.LineItem) (ags.LineItem, error) {
+	s.items[li.ID] = li
+	return li, nil
+}
+
+func (s *memStore) GetLineItem(_ context.Context, _, id string) (ags.LineItem, error) {
+	li, ok := s.items[id]
+	if !ok {
+		return ags.LineItem{}, ags.NotFound
+	}
+	return li, nil
+}
+
+func (s *memStore) UpdateLineItem(_ context.Context, _ string, li ags.LineItem) (ags.LineItem, error) {
+	s.items[li.ID] = li
+	return li, nil
+}
+
+func (s *memStore) DeleteLineItem(_ context.Context, _, id string, _ bool) error {
+	delete(s.items, id)
+	return nil
+}
+
+func (s *memStore) ListLineItems(_ context.Context, _, contextID string, f ags.ListFilter, _, _ int) ([]ags.LineItem, error) {
+	var out []ags.LineItem
+	for _, it := range s.items {
+		if it.ContextID == contextID && (f.ResourceID == "" || it.ResourceID == f.ResourceID) {
+			out = append(out, it)
+		}
+	}
+	return out, nil
+}
+
+func (s *memStore) FindLineItemsByResource(_ context.Context, _, resourceID string, _, _ int) ([]ags.LineItem, error) {
+	var out []ags.LineItem
+	for _, it := range s.items {
+		if it.ResourceID == resourceID {
+			out = append(out, it)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out, nil
+}
+
+func (s *memStore) UpsertScore(context.Context, string, string, ags.Score) (ags.Result, error) {
+	return ags.Result{}, nil
+}
+
+func (s *memStore) ListResults(context.Context, string, string, string, int, int) ([]ags.Result, error) {
+	return nil, nil
+}
+
+func newHandler(store ags.Storage, scopes ...string) http.Handler {
+	srv := ags.NewServer(nil)
+	srv.Store = store
+	srv.ResolveTenantID = func(*http.Request) (string, error) { return "default", nil }
+	return mw.WithScopesCtx(scopes)(ags.Routes(srv))
+}
+
+func TestFindLineItemsByResource(t *testing.T) {
+	store := newMemStore(
+		ags.LineItem{ID: "li-1", ContextID: "ctx-a", ResourceID: "quiz-1", Label: "Quiz A", ScoreMaximum: 10},
+		ags.LineItem{ID: "li-2", ContextID: "ctx-b", ResourceID: "quiz-1", Label: "Quiz B", ScoreMaximum: 10},
+		ags.LineItem{ID: "li-3", ContextID: "ctx-a", ResourceID: "quiz-2", Label: "Other", ScoreMaximum: 5},
+	)
+
+	t.Run("admin sees items across contexts", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		newHandler(store, mw.ScopePlatformAdmin).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/line_items?resource_id=quiz-1", nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d body=%s", rec.Code, rec.Body.String())
+		}
+		var got []map[string]any
+		if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+			t.Fatal(err)
+		}
+		if len(got) != 2 {
+			t.Fatalf("got %d items, want 2", len(got))
+		}
+		if got[0]["contextId"] != "ctx-a" || got[1]["contextId"] != "ctx-b" {
+			t.Fatalf("contexts = %v, %v", got[0]["contextId"], got[1]["contextId"])
+		}
+	})
+
+	t.Run("resource_id required", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		newHandler(store, mw.ScopePlatformAdmin).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/line_items", nil))
+		if rec.Code != http.StatusBadRequest {
+			t.Fatalf("status = %d, want 400", rec.Code)
+		}
+	})
+
+	t.Run("tool scope is not enough", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		newHandler(store, mw.ScopeLineItem).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/line_items?resource_id=quiz-1", nil))
+		if rec.Code != http.StatusForbidden {
+			t.Fatalf("status = %d, want 403", rec.Code)
+		}
+	})
+
+	t.Run("unverified token claiming admin is refused", func(t *testing.T) {
+		enc := base64.RawURLEncoding.EncodeToString
+		forged := enc([]byte(`{"alg":"none"}`)) + "." + enc([]byte(`{"scope":"`+mw.ScopePlatformAdmin+`"}`)) + "."
+		req := httptest.NewRequest(http.MethodGet, "/line_items?resource_id=quiz-1", nil)
+		req.Header.Set("Authorization", "Bearer "+forged)
+		rec := httptest.NewRecorder()
+		newHandler(store).ServeHTTP(rec, req)
+		if rec.Code != http.StatusForbidden {
+			t.Fatalf("status = %d, want 403", rec.Code)
+		}
+	})
+}
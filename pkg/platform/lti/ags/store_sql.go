@@ -0,0 +1,280 @@
+// pkg/platform/lti/ags/store_sql.go
+package ags
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mind-engage/mindengage-lms/pkg/platform/storage"
+)
+
+/*
+SQLStore implements Storage over platform_line_items / platform_results
+(see pkg/platform/storage/migrations.go). Queries use $n placeholders, which
+both lib/pq and modernc sqlite accept, and go through storage.Tenant so each
+one is confined to the caller's tenant ($1).
+
+Results are stored on the line item's scale: a score of 4/5 posted to a line
+item with scoreMaximum 10 is kept as resultScore 8, resultMaximum 10.
+
+Changing a line item's scoreMaximum (PUT) does not touch existing results by
+default; they keep the resultMaximum they were graded against, so a result
+whose resultMaximum differs from its line item's scoreMaximum is stale. Set
+OnMaxChange to MaxChangeRescale to rewrite them proportionally instead
+(8/10 becomes 16/20) in the same transaction as the update.
+*/
+
+// MaxChangePolicy decides what happens to stored results when a line item's
+// scoreMaximum changes.
+type MaxChangePolicy int
+
+const (
+	// MaxChangeKeep leaves results as graded; resultMaximum marks the scale.
+	MaxChangeKeep MaxChangePolicy = iota
+	// MaxChangeRescale scales resultScore and resultMaximum to the new maximum.
+	MaxChangeRescale
+)
+
+type SQLStore struct {
+	DB          *sql.DB
+	OnMaxChange MaxChangePolicy
+}
+
+const lineItemCols = `id, context_id, resource_link_id, COALESCE(resource_id,''), label, score_max, created_at, updated_at`
+
+func (s *SQLStore) CreateLineItem(ctx context.Context, tenantID string, li LineItem) (LineItem, error) {
+	tq, err := storage.Tenant(s.DB, tenantID)
+	if err != nil {
+		return LineItem{}, err
+	}
+	_, err = tq.Insert(ctx, "platform_line_items",
+		"id, context_id, resource_link_id, resource_id, label, score_max, created_at, updated_at", "",
+		li.ID, li.ContextID, li.ResourceLinkID, nullIfEmpty(li.ResourceID), li.Label, li.ScoreMaximum,
+		li.CreatedAt.UTC(), li.UpdatedAt.UTC())
+	if err != nil {
+		return LineItem{}, err
+	}
+	return li, nil
+}
+
+func (s *SQLStore) GetLineItem(ctx context.Context, tenantID, lineItemID string) (LineItem, error) {
+	tq, err := storage.Tenant(s.DB, tenantID)
+	if err != nil {
+		return LineItem{}, err
+	}
+	li, err := scanLineItem(tq.QueryRow(ctx, "platform_line_items", lineItemCols, "id=$2", "", lineItemID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return LineItem{}, NotFound
+	}
+	return li, err
+}
+
+func (s *SQLStore) UpdateLineItem(ctx context.Context, tenantID string, li LineItem) (LineItem, error) {
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return LineItem{}, err
+	}
+	defer tx.Rollback()
+	tq, err := storage.Tenant(tx, tenantID)
+	if err != nil {
+		return LineItem{}, err
+	}
+
+	var oldMax float64
+	err = tq.QueryRow(ctx, "platform_line_items", "score_max", "id=$2", "", li.ID).Scan(&oldMax)
+	if errors.Is(err, sql.ErrNoRows) {
+		return LineItem{}, NotFound
+	}
+	if err != nil {
+		return LineItem{}, err
+	}
+
+	if _, err := tq.Update(ctx, "platform_line_items",
+		"resource_link_id=$2, resource_id=$3, label=$4, score_max=$5, updated_at=$6", "id=$7",
+		li.ResourceLinkID, nullIfEmpty(li.ResourceID), li.Label, li.ScoreMaximum, li.UpdatedAt.UTC(),
+		li.ID); err != nil {
+		return LineItem{}, err
+	}
+
+	if s.OnMaxChange == MaxChangeRescale && li.ScoreMaximum != oldMax {
+		// each result scales from its own maximum, which may predate oldMax
+		if _, err := tq.Update(ctx, "platform_results",
+			"result_score = result_score * $2 / result_maximum, result_maximum = $2",
+			"line_item_id=$3 AND result_maximum > 0",
+			li.ScoreMaximum, li.ID); err != nil {
+			return LineItem{}, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return LineItem{}, err
+	}
+	return li, nil
+}
+
+// DeleteLineItem without force is one conditional DELETE, so a score posted
+// concurrently either lands first (and blocks the delete) or finds the line
+// item gone.
+func (s *SQLStore) DeleteLineItem(ctx context.Context, tenantID, lineItemID string, force bool) error {
+	tq, err := storage.Tenant(s.DB, tenantID)
+	if err != nil {
+		return err
+	}
+	cond := "id=$2"
+	if !force {
+		cond += " AND NOT EXISTS (SELECT 1 FROM platform_results r WHERE r.tenant_id=$1 AND r.line_item_id=$2)"
+	}
+	res, err := tq.Delete(ctx, "platform_line_items", cond, lineItemID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n > 0 {
+		return nil
+	}
+	if force {
+		return NotFound
+	}
+	// Nothing deleted: either there is no such line item or it has results.
+	if _, err := s.GetLineItem(ctx, tenantID, lineItemID); err != nil {
+		return err
+	}
+	return HasResults
+}
+
+func (s *SQLStore) ListLineItems(ctx context.Context, tenantID, contextID string, filter ListFilter, offset, limit int) ([]LineItem, error) {
+	cond := `context_id=$2`
+	args := []any{contextID}
+	if filter.ResourceID != "" {
+		args = append(args, filter.ResourceID)
+		cond += ` AND resource_id=$` + strconv.Itoa(len(args)+1)
+	}
+	if filter.ResourceLinkID != "" {
+		args = append(args, filter.ResourceLinkID)
+		cond += ` AND resource_link_id=$` + strconv.Itoa(len(args)+1)
+	}
+	args = append(args, limit, offset)
+	tail := `ORDER BY created_at, id LIMIT $` + strconv.Itoa(len(args)) + ` OFFSET $` + strconv.Itoa(len(args)+1)
+	return s.queryLineItems(ctx, tenantID, cond, tail, args...)
+}
+
+func (s *SQLStore) FindLineItemsByResource(ctx context.Context, tenantID, resourceID string, offset, limit int) ([]LineItem, error) {
+	return s.queryLineItems(ctx, tenantID, `resource_id=$2`, `ORDER BY context_id, created_at, id LIMIT $3 OFFSET $4`,
+		resourceID, limit, offset)
+}
+
+func (s *SQLStore) UpsertScore(ctx context.Context, tenantID, lineItemID string, in Score) (Result, error) {
+	li, err := s.GetLineItem(ctx, tenantID, lineItemID)
+	if err != nil {
+		return Result{}, err
+	}
+	res := Result{UserID: in.UserID, Comment: in.Comment, Timestamp: in.Timestamp.UTC()}
+	if in.ScoreGiven != nil {
+		score := *in.ScoreGiven
+		if in.ScoreMaximum != nil && *in.ScoreMaximum > 0 {
+			score = score / *in.ScoreMaximum * li.ScoreMaximum
+		}
+		max := li.ScoreMaximum
+		res.ResultScore, res.ResultMaximum = &score, &max
+	}
+	tq, err := storage.Tenant(s.DB, tenantID)
+	if err != nil {
+		return Result{}, err
+	}
+	_, err = tq.Insert(ctx, "platform_results",
+		"line_item_id, user_sub, result_score, result_maximum, timestamp, comment", `
+		ON CONFLICT (tenant_id, line_item_id, user_sub) DO UPDATE SET
+			result_score = excluded.result_score,
+			result_maximum = excluded.result_maximum,
+			timestamp = excluded.timestamp,
+			comment = excluded.comment`,
+		lineItemID, in.UserID, res.ResultScore, res.ResultMaximum, res.Timestamp, in.Comment)
+	if err != nil {
+		return Result{}, err
+	}
+	return res, nil
+}
+
+func (s *SQLStore) ListResults(ctx context.Context, tenantID, lineItemID, userID string, offset, limit int) ([]Result, error) {
+	tq, err := storage.Tenant(s.DB, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	cond := `line_item_id=$2`
+	args := []any{lineItemID}
+	if userID != "" {
+		args = append(args, userID)
+		cond += ` AND user_sub=$3`
+	}
+	args = append(args, limit, offset)
+	tail := `ORDER BY user_sub LIMIT $` + strconv.Itoa(len(args)) + ` OFFSET $` + strconv.Itoa(len(args)+1)
+
+	rows, err := tq.Query(ctx, "platform_results",
+		`user_sub, result_score, result_maximum, timestamp, COALESCE(comment,'')`, cond, tail, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []Result
+	for rows.Next() {
+		var (
+			r          Result
+			score, max sql.NullFloat64
+			ts         sql.NullTime
+		)
+		if err := rows.Scan(&r.UserID, &score, &max, &ts, &r.Comment); err != nil {
+			return nil, err
+		}
+		if score.Valid {
+			r.ResultScore = &score.Float64
+		}
+		if max.Valid {
+			r.ResultMaximum = &max.Float64
+		}
+		if ts.Valid {
+			r.Timestamp = ts.Time.UTC()
+		}
+		out = append(out, r)
+	}
+	return out, rows.Err()
+}
+
+func (s *SQLStore) queryLineItems(ctx context.Context, tenantID, cond, tail string, args ...any) ([]LineItem, error) {
+	tq, err := storage.Tenant(s.DB, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := tq.Query(ctx, "platform_line_items", lineItemCols, cond, tail, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []LineItem
+	for rows.Next() {
+		li, err := scanLineItem(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, li)
+	}
+	return out, rows.Err()
+}
+
+func scanLineItem(row interface{ Scan(...any) error }) (LineItem, error) {
+	var li LineItem
+	var created, updated time.Time
+	if err := row.Scan(&li.ID, &li.ContextID, &li.ResourceLinkID, &li.ResourceID, &li.Label, &li.ScoreMaximum, &created, &updated); err != nil {
+		return LineItem{}, err
+	}
+	li.CreatedAt, li.UpdatedAt = created.UTC(), updated.UTC()
+	return li, nil
+}
+
+func nullIfEmpty(s string) any {
+	if strings.TrimSpace(s) == "" {
+		return nil
+	}
+	return s
+}
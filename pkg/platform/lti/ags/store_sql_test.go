@@ -0,0 +1,158 @@
+package ags_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	_ "modernc.org/sqlite"
+
+	"github.com/mind-engage/mindengage-lms/pkg/platform/lti/ags"
+	mw "github.com/mind-engage/mindengage-lms/pkg/platform/lti/middleware"
+	"github.com/mind-engage/mindengage-lms/pkg/platform/storage"
+)
+
+func newSQLStore(t *testing.T, policy ags.MaxChangePolicy) *ags.SQLStore {
+	t.Helper()
+	ctx := context.Background()
+	db, err := storage.Connect(ctx, "sqlite", filepath.Join(t.TempDir(), "platform.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := storage.Up(ctx, db, "sqlite"); err != nil {
+		t.Fatal(err)
+	}
+	for _, q := range []string{
+		`INSERT INTO tenants (id, issuer) VALUES ('default', 'https://lti.example.com')`,
+		`INSERT INTO contexts (tenant_id, id, title) VALUES ('default', 'ctx-1', 'Course 1')`,
+	} {
+		if _, err := db.SQL.Exec(q); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return &ags.SQLStore{DB: db.SQL, OnMaxChange: policy}
+}
+
+// agsClient drives the AGS routes with every scope granted.
+type agsClient struct {
+	t *testing.T
+	h http.Handler
+}
+
+func (c agsClient) raw(method, target, body string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	c.h.ServeHTTP(rec, httptest.NewRequest(method, target, strings.NewReader(body)))
+	return rec
+}
+
+func (c agsClient) do(method, target, body string) *httptest.ResponseRecorder {
+	c.t.Helper()
+	rec := c.raw(method, target, body)
+	if rec.Code >= 300 {
+		c.t.Fatalf("%s %s: status = %d body=%s", method, target, rec.Code, rec.Body.String())
+	}
+	return rec
+}
+
+func (c agsClient) results(itemPath string) []ags.Result {
+	c.t.Helper()
+	var out []ags.Result
+	if err := json.NewDecoder(c.do(http.MethodGet, itemPath+"/results", "").Body).Decode(&out); err != nil {
+		c.t.Fatal(err)
+	}
+	return out
+}
+
+func newAGSClient(t *testing.T, store ags.Storage) agsClient {
+	return agsClient{t: t, h: newHandler(store, mw.ScopeLineItem, mw.ScopeLineItemRead, mw.ScopeScore, mw.ScopeResultRead)}
+}
+
+// createScored makes a line item (max 10) with one result of 4/5 and returns
+// the item's route path.
+func createScored(c agsClient) string {
+	rec := c.do(http.MethodPost, "/contexts/ctx-1/line_items", `{"label":"Quiz 1","scoreMaximum":10,"resourceLinkId":"rl-1"}`)
+	var li struct {
+		ID string `json:"id"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&li); err != nil {
+		c.t.Fatal(err)
+	}
+	itemPath := "/line_items/" + path.Base(li.ID)
+	c.do(http.MethodPost, itemPath+"/scores", `{"userId":"u1","scoreGiven":4,"scoreMaximum":5}`)
+	return itemPath
+}
+
+func TestSQLStore_ScoreMaxChange(t *testing.T) {
+	t.Run("rescale", func(t *testing.T) {
+		c := newAGSClient(t, newSQLStore(t, ags.MaxChangeRescale))
+		itemPath := createScored(c)
+		if got := c.results(itemPath); len(got) != 1 || *got[0].ResultScore != 8 || *got[0].ResultMaximum != 10 {
+			t.Fatalf("before = %+v", got)
+		}
+
+		c.do(http.MethodPut, itemPath, `{"scoreMaximum":20}`)
+		got := c.results(itemPath)
+		if len(got) != 1 || *got[0].ResultScore != 16 || *got[0].ResultMaximum != 20 {
+			t.Fatalf("after rescale = %+v", got)
+		}
+
+		// label-only edits leave results alone
+		c.do(http.MethodPut, itemPath, `{"label":"Quiz 1 (final)"}`)
+		if got := c.results(itemPath); *got[0].ResultScore != 16 {
+			t.Fatalf("after label edit = %+v", got)
+		}
+	})
+
+	t.Run("keep (default)", func(t *testing.T) {
+		c := newAGSClient(t, newSQLStore(t, ags.MaxChangeKeep))
+		itemPath := createScored(c)
+		c.do(http.MethodPut, itemPath, `{"scoreMaximum":20}`)
+		got := c.results(itemPath)
+		if len(got) != 1 || *got[0].ResultScore != 8 || *got[0].ResultMaximum != 10 {
+			t.Fatalf("after = %+v, want 8/10 untouched", got)
+		}
+	})
+}
+
+func TestDeleteLineItem_ResultsGuard(t *testing.T) {
+	c := newAGSClient(t, newSQLStore(t, ags.MaxChangeKeep))
+	itemPath := createScored(c)
+
+	if rec := c.raw(http.MethodDelete, itemPath, ""); rec.Code != http.StatusConflict {
+		t.Fatalf("delete with results: status = %d, want 409", rec.Code)
+	}
+	if got := c.results(itemPath); len(got) != 1 {
+		t.Fatalf("results after refused delete = %+v", got)
+	}
+
+	if rec := c.raw(http.MethodDelete, itemPath+"?force=1", ""); rec.Code != http.StatusNoContent {
+		t.Fatalf("forced delete: status = %d body=%s", rec.Code, rec.Body.String())
+	}
+	if rec := c.raw(http.MethodGet, itemPath, ""); rec.Code != http.StatusNotFound {
+		t.Fatalf("get after delete: status = %d, want 404", rec.Code)
+	}
+	if got := c.results(itemPath); len(got) != 0 {
+		t.Fatalf("results survived forced delete: %+v", got)
+	}
+
+	// no results: plain delete works
+	rec := c.do(http.MethodPost, "/contexts/ctx-1/line_items", `{"label":"Empty","scoreMaximum":5,"resourceLinkId":"rl-2"}`)
+	var li struct {
+		ID string `json:"id"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&li); err != nil {
+		t.Fatal(err)
+	}
+	if rec := c.raw(http.MethodDelete, "/line_items/"+path.Base(li.ID), ""); rec.Code != http.StatusNoContent {
+		t.Fatalf("delete without results: status = %d", rec.Code)
+	}
+	if rec := c.raw(http.MethodDelete, "/line_items/"+path.Base(li.ID), ""); rec.Code != http.StatusNotFound {
+		t.Fatalf("delete again: status = %d, want 404", rec.Code)
+	}
+}
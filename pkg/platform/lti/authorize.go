@@ -55,6 +55,7 @@ const (
 	ltiClaimResource    = "https://purl.imsglobal.org/spec/lti/claim/resource_link"
 	ltiClaimRoles       = "https://purl.imsglobal.org/spec/lti/claim/roles"
 	ltiClaimToolPlat    = "https://purl.imsglobal.org/spec/lti/claim/tool_platform"
+	ltiClaimCustom      = "https://purl.imsglobal.org/spec/lti/claim/custom"
 
 	// AGS & NRPS
 	agsClaimEndpoint = "https://purl.imsglobal.org/spec/lti-ags/claim/endpoint"
@@ -80,6 +81,15 @@ type Tool struct {
 	ClientID     string
 	Name         string
 	RedirectURIs []string
+	// RedirectMatch is how a requested redirect_uri is compared with
+	// RedirectURIs: RedirectExact (default), RedirectPrefix or
+	// RedirectIgnoreQuery. See redirectAllowed.
+	RedirectMatch string
+
+	// Optional: emit id_token "aud" as an array for this Tool. ExtraAudiences
+	// are appended after the client id (and imply array form).
+	AudienceAsArray bool
+	ExtraAudiences  []string
 }
 
 // ToolRegistry looks up a Tool by (tenantID, clientID).
@@ -141,6 +151,8 @@ type AuthorizeServer struct {
 	ProductName    string // e.g., "MindEngage"
 	ProductVersion string // e.g., "1.0"
 	PlatformGUID   string // stable GUID/URN for your platform deployment
+	// If true, "aud" is always emitted as an array (per-Tool setting also applies).
+	AudienceAsArray bool
 }
 
 // AuthorizeHandler returns the http.Handler for GET /oauth/authorize.
@@ -194,7 +206,7 @@ func (s *AuthorizeServer) AuthorizeHandler() http.HandlerFunc {
 			writeErr(w, http.StatusUnauthorized, "unknown tool/client")
 			return
 		}
-		if !redirectAllowed(redirectURI, tool.RedirectURIs) {
+		if !redirectAllowed(redirectURI, tool.RedirectURIs, tool.RedirectMatch) {
 			writeErr(w, http.StatusUnauthorized, "redirect_uri not allowed for client")
 			return
 		}
@@ -220,12 +232,12 @@ func (s *AuthorizeServer) AuthorizeHandler() http.HandlerFunc {
 		// Base OIDC id_token claims
 		claims := map[string]any{
 			"iss":   iss,
-			"aud":   clientID,
+			"aud":   s.audience(clientID, tool),
 			"sub":   nonEmpty(li.UserID, "user-"+randHex(8)), // resolver SHOULD set
 			"iat":   now.Unix(),
 			"exp":   exp.Unix(),
 			"nonce": nonce,
-			"azp":   clientID,
+			"azp":   clientID, // always the client id, even for array aud
 		}
 
 		// tool_platform claim (recommended)
@@ -331,6 +343,21 @@ func (s *AuthorizeServer) ttl() time.Duration {
 	return 5 * time.Minute
 }
 
+// audience returns the id_token "aud": the client id as a string, or an array
+// led by the client id when configured on the server or Tool.
+func (s *AuthorizeServer) audience(clientID string, tool Tool) any {
+	if !s.AudienceAsArray && !tool.AudienceAsArray && len(tool.ExtraAudiences) == 0 {
+		return clientID
+	}
+	out := []string{clientID}
+	for _, a := range tool.ExtraAudiences {
+		if a = strings.TrimSpace(a); a != "" && a != clientID {
+			out = append(out, a)
+		}
+	}
+	return out
+}
+
 func eqFold(a, b string) bool { return strings.EqualFold(strings.TrimSpace(a), strings.TrimSpace(b)) }
 
 func nonEmpty(s, d string) string {
@@ -363,15 +390,82 @@ func isHTTPURL(s string) bool {
 	return u.Host != ""
 }
 
-func redirectAllowed(uri string, allowed []string) bool {
+// Redirect URI matching modes (Tool.RedirectMatch).
+const (
+	RedirectExact       = "exact"        // byte-for-byte equal
+	RedirectPrefix      = "prefix"       // same origin, path at or below a registered path; any query
+	RedirectIgnoreQuery = "ignore_query" // same origin and path; any query
+)
+
+// ValidRedirectMatch reports whether mode is a known matching mode ("" = exact).
+func ValidRedirectMatch(mode string) bool {
+	switch mode {
+	case "", RedirectExact, RedirectPrefix, RedirectIgnoreQuery:
+		return true
+	}
+	return false
+}
+
+// redirectAllowed reports whether uri may receive an id_token for a Tool
+// registered with allowed under mode. The relaxed modes still pin scheme,
+// host and port to a registered URI, refuse userinfo and fragments, and only
+// accept clean paths (no dot segments or encoded slashes), so they cannot be
+// bent into an open redirect to another origin or a sibling path.
+func redirectAllowed(uri string, allowed []string, mode string) bool {
+	if mode == "" || mode == RedirectExact {
+		for _, a := range allowed {
+			if strings.TrimSpace(a) == uri {
+				return true
+			}
+		}
+		return false
+	}
+	if !ValidRedirectMatch(mode) {
+		return false
+	}
+	u, ok := parseRedirect(uri)
+	if !ok {
+		return false
+	}
 	for _, a := range allowed {
-		if strings.TrimSpace(a) == uri {
-			return true
+		reg, ok := parseRedirect(strings.TrimSpace(a))
+		if !ok || !strings.EqualFold(u.Scheme, reg.Scheme) || !strings.EqualFold(u.Host, reg.Host) {
+			continue
+		}
+		switch mode {
+		case RedirectIgnoreQuery:
+			if u.Path == reg.Path {
+				return true
+			}
+		case RedirectPrefix:
+			base := strings.TrimSuffix(reg.Path, "/")
+			if u.Path == reg.Path || u.Path == base || strings.HasPrefix(u.Path, base+"/") {
+				return true
+			}
 		}
 	}
 	return false
 }
 
+// parseRedirect accepts absolute http(s) URLs without userinfo, fragment,
+// encoded path separators or dot segments.
+func parseRedirect(s string) (*url.URL, bool) {
+	u, err := url.Parse(s)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" ||
+		u.User != nil || u.Fragment != "" || strings.Contains(s, "#") {
+		return nil, false
+	}
+	if u.RawPath != "" || strings.Contains(u.Path, "\\") {
+		return nil, false // %2F, %5C and friends
+	}
+	for _, seg := range strings.Split(u.Path, "/") {
+		if seg == "." || seg == ".." {
+			return nil, false
+		}
+	}
+	return u, true
+}
+
 func writeErr(w http.ResponseWriter, status int, msg string) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
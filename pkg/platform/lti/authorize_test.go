@@ -0,0 +1,159 @@
+package lti_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"testing"
+
+	"github.com/mind-engage/mindengage-lms/pkg/platform/lti"
+)
+
+type staticIssuer string
+
+func (s staticIssuer) IssuerForTenant(context.Context, string) (string, error) { return string(s), nil }
+
+type toolMap map[string]lti.Tool
+
+func (m toolMap) GetTool(_ context.Context, _, clientID string) (lti.Tool, error) {
+	return m[clientID], nil
+}
+
+type staticLaunch struct{}
+
+func (staticLaunch) Resolve(context.Context, string, string, string, string) (lti.LaunchInfo, error) {
+	return lti.LaunchInfo{UserID: "u1", DeploymentID: "d1", ResourceLinkID: "rl1"}, nil
+}
+
+// capturingSigner records the last claims instead of signing.
+type capturingSigner struct{ claims map[string]any }
+
+func (s *capturingSigner) Sign(_ context.Context, _ string, claims map[string]any) (string, error) {
+	s.claims = claims
+	return "x.y.z", nil
+}
+
+func authorize(t *testing.T, srv *lti.AuthorizeServer, clientID string) {
+	t.Helper()
+	q := url.Values{
+		"response_type": {"id_token"},
+		"response_mode": {"form_post"},
+		"client_id":     {clientID},
+		"redirect_uri":  {"https://tool.example/launch"},
+		"nonce":         {"n-1"},
+	}
+	rec := httptest.NewRecorder()
+	srv.AuthorizeHandler()(rec, httptest.NewRequest(http.MethodGet, "/oauth/authorize?"+q.Encode(), nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d body=%s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestAuthorize_Audience(t *testing.T) {
+	redirects := []string{"https://tool.example/launch"}
+	tools := toolMap{
+		"single": {ClientID: "single", RedirectURIs: redirects},
+		"array":  {ClientID: "array", RedirectURIs: redirects, AudienceAsArray: true},
+		"extra":  {ClientID: "extra", RedirectURIs: redirects, ExtraAudiences: []string{"https://aud.example"}},
+	}
+	cases := []struct {
+		clientID string
+		want     any
+	}{
+		{"single", "single"},
+		{"array", []string{"array"}},
+		{"extra", []string{"extra", "https://aud.example"}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.clientID, func(t *testing.T) {
+			signer := &capturingSigner{}
+			srv := &lti.AuthorizeServer{
+				Issuers:         staticIssuer("https://platform.example"),
+				Registry:        tools,
+				Launches:        staticLaunch{},
+				Signer:          signer,
+				ResolveTenantID: func(*http.Request) (string, error) { return "default", nil },
+			}
+			authorize(t, srv, tc.clientID)
+			if got := signer.claims["aud"]; !reflect.DeepEqual(got, tc.want) {
+				t.Fatalf("aud = %#v, want %#v", got, tc.want)
+			}
+			if got := signer.claims["azp"]; got != tc.clientID {
+				t.Fatalf("azp = %#v, want %q", got, tc.clientID)
+			}
+		})
+	}
+
+	t.Run("server-wide array", func(t *testing.T) {
+		signer := &capturingSigner{}
+		srv := &lti.AuthorizeServer{
+			Issuers:         staticIssuer("https://platform.example"),
+			Registry:        tools,
+			Launches:        staticLaunch{},
+			Signer:          signer,
+			ResolveTenantID: func(*http.Request) (string, error) { return "default", nil },
+			AudienceAsArray: true,
+		}
+		authorize(t, srv, "single")
+		if got := signer.claims["aud"]; !reflect.DeepEqual(got, []string{"single"}) {
+			t.Fatalf("aud = %#v, want [single]", got)
+		}
+	})
+}
+
+func TestAuthorize_RedirectMatch(t *testing.T) {
+	registered := []string{"https://tool.example/lti/launch?v=1"}
+	tools := toolMap{
+		"exact":  {ClientID: "exact", RedirectURIs: registered},
+		"prefix": {ClientID: "prefix", RedirectURIs: registered, RedirectMatch: lti.RedirectPrefix},
+		"query":  {ClientID: "query", RedirectURIs: registered, RedirectMatch: lti.RedirectIgnoreQuery},
+	}
+	srv := &lti.AuthorizeServer{
+		Issuers:         staticIssuer("https://platform.example"),
+		Registry:        tools,
+		Launches:        staticLaunch{},
+		Signer:          &capturingSigner{},
+		ResolveTenantID: func(*http.Request) (string, error) { return "default", nil },
+	}
+	cases := []struct {
+		clientID, redirect string
+		ok                 bool
+	}{
+		{"exact", "https://tool.example/lti/launch?v=1", true},
+		{"exact", "https://tool.example/lti/launch?v=2", false},
+		{"exact", "https://tool.example/lti/launch", false},
+
+		{"query", "https://tool.example/lti/launch?course=7", true},
+		{"query", "https://tool.example/lti/launch", true},
+		{"query", "https://TOOL.example/lti/launch", true},
+		{"query", "https://tool.example/lti/launch/x", false},
+		{"query", "http://tool.example/lti/launch", false},
+		{"query", "https://tool.example:8443/lti/launch", false},
+
+		{"prefix", "https://tool.example/lti/launch/course/7?x=1", true},
+		{"prefix", "https://tool.example/lti/launch", true},
+		{"prefix", "https://tool.example/lti/launchpad", false},
+		{"prefix", "https://tool.example/lti/launch/../../admin", false},
+		{"prefix", "https://tool.example/lti/launch/%2e%2e/admin", false},
+		{"prefix", "https://tool.example/lti/launch%2F..%2Fadmin", false},
+		{"prefix", "https://tool.example.evil.test/lti/launch/x", false},
+		{"prefix", "https://tool.example@evil.test/lti/launch/x", false},
+		{"prefix", "https://tool.example/lti/launch/x#frag", false},
+	}
+	for _, tc := range cases {
+		q := url.Values{
+			"response_type": {"id_token"},
+			"response_mode": {"form_post"},
+			"client_id":     {tc.clientID},
+			"redirect_uri":  {tc.redirect},
+			"nonce":         {"n-1"},
+		}
+		rec := httptest.NewRecorder()
+		srv.AuthorizeHandler()(rec, httptest.NewRequest(http.MethodGet, "/oauth/authorize?"+q.Encode(), nil))
+		if got := rec.Code == http.StatusOK; got != tc.ok {
+			t.Errorf("%s %s: status %d, want allowed=%v", tc.clientID, tc.redirect, rec.Code, tc.ok)
+		}
+	}
+}
@@ -0,0 +1,201 @@
+// pkg/platform/lti/deeplinking/verifier.go
+package deeplinking
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mind-engage/mindengage-lms/pkg/platform/lti"
+)
+
+/*
+JWTVerifier is the concrete Verifier for Tool-signed Deep Linking responses.
+
+Steps:
+  1) Look up the Tool's jwks_url (tools table) by tenant + client_id
+  2) Load the key set through a shared cache (refetched once on kid miss)
+  3) Verify the signature (RS256 or ES256)
+  4) Check iss == client_id, aud contains the Platform issuer, exp/iat
+  5) Check message_type == "LtiDeepLinkingResponse"
+*/
+
+// ToolJWKSLookup returns the jwks_url registered for a Tool.
+type ToolJWKSLookup interface {
+	ToolJWKSURL(ctx context.Context, tenantID, clientID string) (string, error)
+}
+
+// SQLToolJWKS reads jwks_url from the platform tools table.
+type SQLToolJWKS struct {
+	DB *sql.DB
+}
+
+func (s SQLToolJWKS) ToolJWKSURL(ctx context.Context, tenantID, clientID string) (string, error) {
+	var u string
+	err := s.DB.QueryRowContext(ctx,
+		`SELECT jwks_url FROM tools WHERE client_id=$1 AND tenant_id=$2`,
+		clientID, tenantID).Scan(&u)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", fmt.Errorf("unknown tool %q", clientID)
+	}
+	return u, err
+}
+
+// JWTVerifier implements Verifier.
+type JWTVerifier struct {
+	Tools ToolJWKSLookup // required
+	// Optional: defaults to a JWKSCache shared by all calls on this verifier.
+	Keys lti.KeySetSource
+
+	// Optional knobs
+	Leeway time.Duration // clock skew allowance (default 60s)
+	MaxAge time.Duration // reject iat older than this (default 10m)
+	Now    func() time.Time
+
+	once  sync.Once
+	cache *lti.JWKSCache
+}
+
+// VerifyToolJWT implements Verifier.
+func (v *JWTVerifier) VerifyToolJWT(ctx context.Context, tenantID, toolClientID, rawJWT, expectedAud string) (map[string]any, error) {
+	if v.Tools == nil {
+		return nil, errors.New("verifier not configured")
+	}
+	parts := strings.Split(rawJWT, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("malformed JWT")
+	}
+	hdrRaw, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, errors.New("malformed JWT header")
+	}
+	var hdr struct {
+		Alg string `json:"alg"`
+		KID string `json:"kid"`
+	}
+	if err := json.Unmarshal(hdrRaw, &hdr); err != nil {
+		return nil, errors.New("malformed JWT header")
+	}
+	if hdr.Alg != "RS256" && hdr.Alg != "ES256" {
+		return nil, fmt.Errorf("unsupported alg %q", hdr.Alg)
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, errors.New("malformed JWT signature")
+	}
+
+	jwksURL, err := v.Tools.ToolJWKSURL(ctx, tenantID, toolClientID)
+	if err != nil {
+		return nil, fmt.Errorf("tool lookup: %w", err)
+	}
+	set, err := v.keys().KeySetFor(ctx, jwksURL, hdr.KID)
+	if err != nil {
+		return nil, fmt.Errorf("tool jwks: %w", err)
+	}
+	sum := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := verifySig(set, hdr.Alg, hdr.KID, sum[:], sig); err != nil {
+		return nil, err
+	}
+
+	claims, err := unverifiedClaims(rawJWT)
+	if err != nil {
+		return nil, errors.New("malformed JWT claims")
+	}
+	if iss := asString(claims["iss"]); iss != toolClientID {
+		return nil, errors.New("iss mismatch")
+	}
+	if !lti.AudContains(claims["aud"], expectedAud) {
+		return nil, errors.New("aud mismatch")
+	}
+	now := v.now()
+	exp := int64(toFloat(claims["exp"]))
+	if exp == 0 || now.Add(-v.leeway()).Unix() > exp {
+		return nil, errors.New("jwt expired")
+	}
+	iat := int64(toFloat(claims["iat"]))
+	if iat == 0 {
+		return nil, errors.New("missing iat")
+	}
+	if iat > now.Add(v.leeway()).Unix() {
+		return nil, errors.New("iat in the future")
+	}
+	if now.Unix()-iat > int64(v.maxAge().Seconds()) {
+		return nil, errors.New("jwt too old")
+	}
+	if mt := asString(claims["https://purl.imsglobal.org/spec/lti/claim/message_type"]); mt != "LtiDeepLinkingResponse" {
+		return nil, errors.New("unexpected message_type")
+	}
+	return claims, nil
+}
+
+func verifySig(set lti.JWKS, alg, kid string, digest, sig []byte) error {
+	switch alg {
+	case "RS256":
+		keys, err := lti.RSAPublicKeysFromJWKS(set, kid)
+		if err != nil {
+			return err
+		}
+		for _, pk := range keys {
+			if rsa.VerifyPKCS1v15(pk, crypto.SHA256, digest, sig) == nil {
+				return nil
+			}
+		}
+	case "ES256":
+		// JWS ES256 signatures are raw R||S, 32 bytes each.
+		if len(sig) != 64 {
+			return errors.New("invalid ES256 signature length")
+		}
+		r := new(big.Int).SetBytes(sig[:32])
+		s := new(big.Int).SetBytes(sig[32:])
+		keys, err := lti.ECPublicKeysFromJWKS(set, kid, "P-256")
+		if err != nil {
+			return err
+		}
+		for _, pk := range keys {
+			if ecdsa.Verify(pk, digest, r, s) {
+				return nil
+			}
+		}
+	}
+	return errors.New("signature verification failed")
+}
+
+func (v *JWTVerifier) keys() lti.KeySetSource {
+	if v.Keys != nil {
+		return v.Keys
+	}
+	v.once.Do(func() { v.cache = &lti.JWKSCache{} })
+	return v.cache
+}
+
+func (v *JWTVerifier) leeway() time.Duration {
+	if v.Leeway > 0 {
+		return v.Leeway
+	}
+	return 60 * time.Second
+}
+
+func (v *JWTVerifier) maxAge() time.Duration {
+	if v.MaxAge > 0 {
+		return v.MaxAge
+	}
+	return 10 * time.Minute
+}
+
+func (v *JWTVerifier) now() time.Time {
+	if v.Now != nil {
+		return v.Now()
+	}
+	return time.Now().UTC()
+}
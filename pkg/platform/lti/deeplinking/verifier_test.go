@@ -0,0 +1,149 @@
+package deeplinking_test
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/mind-engage/mindengage-lms/pkg/platform/lti"
+	"github.com/mind-engage/mindengage-lms/pkg/platform/lti/deeplinking"
+)
+
+type staticLookup map[string]string // client_id -> jwks_url
+
+func (l staticLookup) ToolJWKSURL(_ context.Context, _, clientID string) (string, error) {
+	return l[clientID], nil
+}
+
+type staticKeys struct{ set lti.JWKS }
+
+func (k staticKeys) KeySetFor(context.Context, string, string) (lti.JWKS, error) { return k.set, nil }
+
+const (
+	platformIss = "https://platform.example"
+	toolID      = "tool-123"
+)
+
+func dlClaims(aud any, now time.Time) map[string]any {
+	return map[string]any{
+		"iss": toolID,
+		"aud": aud,
+		"iat": now.Unix(),
+		"exp": now.Add(5 * time.Minute).Unix(),
+		"https://purl.imsglobal.org/spec/lti/claim/message_type": "LtiDeepLinkingResponse",
+	}
+}
+
+func signRS256(t *testing.T, key *rsa.PrivateKey, kid string, claims map[string]any) string {
+	t.Helper()
+	input := jwtInput(t, "RS256", kid, claims)
+	sum := sha256.Sum256([]byte(input))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, sum[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return input + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func signES256(t *testing.T, key *ecdsa.PrivateKey, kid string, claims map[string]any) string {
+	t.Helper()
+	input := jwtInput(t, "ES256", kid, claims)
+	sum := sha256.Sum256([]byte(input))
+	r, s, err := ecdsa.Sign(rand.Reader, key, sum[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig := append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+	return input + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func jwtInput(t *testing.T, alg, kid string, claims map[string]any) string {
+	t.Helper()
+	h, _ := json.Marshal(map[string]any{"alg": alg, "kid": kid, "typ": "JWT"})
+	p, err := json.Marshal(claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(h) + "." + base64.RawURLEncoding.EncodeToString(p)
+}
+
+func TestJWTVerifier(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Unix(1_700_000_000, 0)
+	v := &deeplinking.JWTVerifier{
+		Tools: staticLookup{toolID: "https://tool.example/jwks"},
+		Keys: staticKeys{set: lti.JWKS{Keys: []map[string]any{
+			lti.RSAPublicJWK(&rsaKey.PublicKey, "rsa-1", "RS256"),
+			lti.ECPublicJWK(&ecKey.PublicKey, "ec-1", "ES256"),
+		}}},
+		Now: func() time.Time { return now },
+	}
+	ctx := context.Background()
+
+	cases := []struct {
+		name    string
+		jwt     string
+		wantErr bool
+	}{
+		{"rs256 ok", signRS256(t, rsaKey, "rsa-1", dlClaims(platformIss, now)), false},
+		{"es256 ok", signES256(t, ecKey, "ec-1", dlClaims(platformIss, now)), false},
+		{"aud array ok", signRS256(t, rsaKey, "rsa-1", dlClaims([]any{"other", platformIss}, now)), false},
+		{"wrong aud", signRS256(t, rsaKey, "rsa-1", dlClaims("https://evil.example", now)), true},
+		{"expired", signRS256(t, rsaKey, "rsa-1", dlClaims(platformIss, now.Add(-time.Hour))), true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := v.VerifyToolJWT(ctx, "default", toolID, tc.jwt, platformIss)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tc.wantErr)
+			}
+		})
+	}
+
+	t.Run("bad signature", func(t *testing.T) {
+		other, _ := rsa.GenerateKey(rand.Reader, 2048)
+		jwt := signRS256(t, other, "rsa-1", dlClaims(platformIss, now))
+		if _, err := v.VerifyToolJWT(ctx, "default", toolID, jwt, platformIss); err == nil {
+			t.Fatal("expected signature failure")
+		}
+	})
+
+	t.Run("missing iat", func(t *testing.T) {
+		c := dlClaims(platformIss, now)
+		delete(c, "iat")
+		if _, err := v.VerifyToolJWT(ctx, "default", toolID, signRS256(t, rsaKey, "rsa-1", c), platformIss); err == nil {
+			t.Fatal("expected missing iat failure")
+		}
+	})
+
+	t.Run("iat too old", func(t *testing.T) {
+		c := dlClaims(platformIss, now)
+		c["iat"] = now.Add(-time.Hour).Unix()
+		if _, err := v.VerifyToolJWT(ctx, "default", toolID, signRS256(t, rsaKey, "rsa-1", c), platformIss); err == nil {
+			t.Fatal("expected jwt too old failure")
+		}
+	})
+
+	t.Run("wrong message type", func(t *testing.T) {
+		c := dlClaims(platformIss, now)
+		c["https://purl.imsglobal.org/spec/lti/claim/message_type"] = "LtiResourceLinkRequest"
+		if _, err := v.VerifyToolJWT(ctx, "default", toolID, signRS256(t, rsaKey, "rsa-1", c), platformIss); err == nil {
+			t.Fatal("expected message_type failure")
+		}
+	})
+}
@@ -145,7 +145,9 @@ func computeETag(b []byte) string {
 // These return only PUBLIC parameters as per RFC 7517 and set typical metadata:
 //   - "use": "sig"
 //   - "key_ops": ["verify"]
-// Caller should provide a stable "kid" and an "alg" (e.g., "RS256", "ES256").
+// Caller should provide a stable "kid". Strict tools reject keys without
+// "alg", so it is always set: RS256 when an RSA caller leaves it empty, and
+// derived from the curve for EC.
 // ------------------------------------------------------------------------------------
 
 // RSAPublicJWK builds a minimal RSA JWK map (n,e) for the given key.
@@ -153,6 +155,9 @@ func RSAPublicJWK(pub *rsa.PublicKey, kid, alg string) map[string]any {
 	if pub == nil || pub.N == nil || pub.E == 0 {
 		return nil
 	}
+	if alg == "" {
+		alg = "RS256"
+	}
 	return map[string]any{
 		"kty":     "RSA",
 		"kid":     kid,
@@ -165,9 +170,9 @@ func RSAPublicJWK(pub *rsa.PublicKey, kid, alg string) map[string]any {
 }
 
 // ECPublicJWK builds a minimal EC JWK map (crv,x,y) for the given key.
-// The caller is responsible for the right "alg" according to curve:
-//   - P-256 => ES256, P-384 => ES384, P-521 => ES512
-func ECPublicJWK(pub *ecdsa.PublicKey, kid, alg string) map[string]any {
+// An EC key can only sign with its curve's algorithm, so "alg" follows the
+// curve (P-256 => ES256, P-384 => ES384, P-521 => ES512) whatever is passed.
+func ECPublicJWK(pub *ecdsa.PublicKey, kid, _ string) map[string]any {
 	if pub == nil || pub.X == nil || pub.Y == nil || pub.Curve == nil {
 		return nil
 	}
@@ -175,6 +180,7 @@ func ECPublicJWK(pub *ecdsa.PublicKey, kid, alg string) map[string]any {
 	if crv == "" {
 		return nil
 	}
+	alg := curveAlg[crv]
 	return map[string]any{
 		"kty":     "EC",
 		"kid":     kid,
@@ -187,6 +193,8 @@ func ECPublicJWK(pub *ecdsa.PublicKey, kid, alg string) map[string]any {
 	}
 }
 
+var curveAlg = map[string]string{"P-256": "ES256", "P-384": "ES384", "P-521": "ES512"}
+
 func curveName(pk *ecdsa.PublicKey) string {
 	switch pk.Curve.Params().Name {
 	case "P-256", "prime256v1", "secp256r1":
@@ -0,0 +1,196 @@
+// pkg/platform/lti/jwks_cache.go
+package lti
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+/*
+Remote JWKS fetching with caching (Tool keys)
+
+Tools publish their public keys at a jwks_url. Verifying a Tool-signed JWT
+(deep linking responses, private_key_jwt assertions) needs those keys, but
+fetching them on every request is slow and hammers the Tool.
+
+JWKSCache wraps a JWKSFetcher and keeps each key set for a TTL. When a JWT
+references a kid that is not in the cached set (the Tool rotated keys), the
+cache refetches once — rate-limited by RefreshCooldown so random kids can't be
+used to force a fetch per request.
+
+Usage:
+    cache := &lti.JWKSCache{TTL: 10 * time.Minute}
+    set, err := cache.KeySetFor(ctx, tool.JWKSURL, kid)
+*/
+
+// JWKSFetcher retrieves a remote JWKS document.
+type JWKSFetcher interface {
+	FetchJWKS(ctx context.Context, jwksURL string) (JWKS, error)
+}
+
+// KeySetSource returns a key set for jwksURL that should contain kid.
+// An empty kid means "any current key set".
+type KeySetSource interface {
+	KeySetFor(ctx context.Context, jwksURL, kid string) (JWKS, error)
+}
+
+// HTTPJWKSFetcher fetches JWKS documents over HTTP(S).
+type HTTPJWKSFetcher struct {
+	// Optional: defaults to a client with a 10s timeout.
+	Client *http.Client
+	// Optional: maximum response size in bytes (default 1 MiB).
+	MaxBytes int64
+}
+
+// FetchJWKS implements JWKSFetcher.
+func (f *HTTPJWKSFetcher) FetchJWKS(ctx context.Context, jwksURL string) (JWKS, error) {
+	if !isHTTPURL(jwksURL) {
+		return JWKS{}, fmt.Errorf("jwks: invalid url %q", jwksURL)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, jwksURL, nil)
+	if err != nil {
+		return JWKS{}, err
+	}
+	req.Header.Set("Accept", "application/jwk-set+json, application/json")
+	resp, err := f.client().Do(req)
+	if err != nil {
+		return JWKS{}, fmt.Errorf("jwks: fetch: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return JWKS{}, fmt.Errorf("jwks: fetch: unexpected status %d", resp.StatusCode)
+	}
+	var set JWKS
+	if err := json.NewDecoder(io.LimitReader(resp.Body, f.maxBytes())).Decode(&set); err != nil {
+		return JWKS{}, fmt.Errorf("jwks: decode: %w", err)
+	}
+	return set, nil
+}
+
+func (f *HTTPJWKSFetcher) client() *http.Client {
+	if f.Client != nil {
+		return f.Client
+	}
+	return &http.Client{Timeout: 10 * time.Second}
+}
+
+func (f *HTTPJWKSFetcher) maxBytes() int64 {
+	if f.MaxBytes > 0 {
+		return f.MaxBytes
+	}
+	return 1 << 20
+}
+
+// JWKSCache caches key sets per URL. Safe for concurrent use; share one
+// instance across all verifications.
+type JWKSCache struct {
+	// Optional: defaults to &HTTPJWKSFetcher{}.
+	Fetcher JWKSFetcher
+
+	// Optional knobs
+	TTL             time.Duration // default 10m
+	RefreshCooldown time.Duration // min time between kid-miss refetches (default 30s)
+	Now             func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]jwksCacheEntry
+}
+
+type jwksCacheEntry struct {
+	set       JWKS
+	fetchedAt time.Time
+}
+
+// FetchJWKS implements JWKSFetcher, serving from cache while fresh.
+func (c *JWKSCache) FetchJWKS(ctx context.Context, jwksURL string) (JWKS, error) {
+	return c.KeySetFor(ctx, jwksURL, "")
+}
+
+// KeySetFor implements KeySetSource. A cached set is returned while within
+// TTL; if kid is non-empty and absent from the cached set, the set is
+// refetched once (subject to RefreshCooldown).
+func (c *JWKSCache) KeySetFor(ctx context.Context, jwksURL, kid string) (JWKS, error) {
+	jwksURL = strings.TrimSpace(jwksURL)
+	if jwksURL == "" {
+		return JWKS{}, errors.New("jwks: url required")
+	}
+	now := c.now()
+
+	c.mu.Lock()
+	e, ok := c.entries[jwksURL]
+	c.mu.Unlock()
+
+	if ok && now.Sub(e.fetchedAt) < c.ttl() {
+		if kid == "" || jwksHasKID(e.set, kid) {
+			return e.set, nil
+		}
+		if now.Sub(e.fetchedAt) < c.cooldown() {
+			// Refetched too recently; don't let unknown kids force a fetch.
+			return e.set, nil
+		}
+	}
+
+	set, err := c.fetcher().FetchJWKS(ctx, jwksURL)
+	if err != nil {
+		return JWKS{}, err
+	}
+
+	c.mu.Lock()
+	if c.entries == nil {
+		c.entries = map[string]jwksCacheEntry{}
+	}
+	c.entries[jwksURL] = jwksCacheEntry{set: set, fetchedAt: now}
+	c.mu.Unlock()
+	return set, nil
+}
+
+// Invalidate drops the cached set for jwksURL (e.g., after a tool update).
+func (c *JWKSCache) Invalidate(jwksURL string) {
+	c.mu.Lock()
+	delete(c.entries, strings.TrimSpace(jwksURL))
+	c.mu.Unlock()
+}
+
+func (c *JWKSCache) fetcher() JWKSFetcher {
+	if c.Fetcher != nil {
+		return c.Fetcher
+	}
+	return &HTTPJWKSFetcher{}
+}
+
+func (c *JWKSCache) ttl() time.Duration {
+	if c.TTL > 0 {
+		return c.TTL
+	}
+	return 10 * time.Minute
+}
+
+func (c *JWKSCache) cooldown() time.Duration {
+	if c.RefreshCooldown > 0 {
+		return c.RefreshCooldown
+	}
+	return 30 * time.Second
+}
+
+func (c *JWKSCache) now() time.Time {
+	if c.Now != nil {
+		return c.Now()
+	}
+	return time.Now()
+}
+
+func jwksHasKID(set JWKS, kid string) bool {
+	for _, k := range set.Keys {
+		if got, _ := k["kid"].(string); got == kid {
+			return true
+		}
+	}
+	return false
+}
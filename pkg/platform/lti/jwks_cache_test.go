@@ -0,0 +1,101 @@
+package lti_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/mind-engage/mindengage-lms/pkg/platform/lti"
+)
+
+type countingFetcher struct {
+	calls int
+	sets  []lti.JWKS // returned in order; last one repeats
+}
+
+func (f *countingFetcher) FetchJWKS(_ context.Context, _ string) (lti.JWKS, error) {
+	i := f.calls
+	if i >= len(f.sets) {
+		i = len(f.sets) - 1
+	}
+	f.calls++
+	return f.sets[i], nil
+}
+
+func keySet(kids ...string) lti.JWKS {
+	set := lti.JWKS{}
+	for _, k := range kids {
+		set.Keys = append(set.Keys, map[string]any{"kty": "RSA", "kid": k})
+	}
+	return set
+}
+
+type fakeClock struct{ t time.Time }
+
+func (c *fakeClock) Now() time.Time          { return c.t }
+func (c *fakeClock) Advance(d time.Duration) { c.t = c.t.Add(d) }
+
+func TestJWKSCache_NoRefetchWithinTTL(t *testing.T) {
+	f := &countingFetcher{sets: []lti.JWKS{keySet("k1")}}
+	clk := &fakeClock{t: time.Unix(1_700_000_000, 0)}
+	c := &lti.JWKSCache{Fetcher: f, TTL: 10 * time.Minute, Now: clk.Now}
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		if _, err := c.KeySetFor(ctx, "https://tool.example/jwks", "k1"); err != nil {
+			t.Fatalf("KeySetFor: %v", err)
+		}
+		clk.Advance(time.Minute)
+	}
+	if f.calls != 1 {
+		t.Fatalf("fetch calls = %d, want 1", f.calls)
+	}
+
+	// Past TTL -> refetch
+	clk.Advance(10 * time.Minute)
+	if _, err := c.FetchJWKS(ctx, "https://tool.example/jwks"); err != nil {
+		t.Fatalf("FetchJWKS: %v", err)
+	}
+	if f.calls != 2 {
+		t.Fatalf("fetch calls after TTL = %d, want 2", f.calls)
+	}
+}
+
+func TestJWKSCache_KidMissRefreshes(t *testing.T) {
+	f := &countingFetcher{sets: []lti.JWKS{keySet("old"), keySet("old", "new")}}
+	clk := &fakeClock{t: time.Unix(1_700_000_000, 0)}
+	c := &lti.JWKSCache{Fetcher: f, TTL: time.Hour, RefreshCooldown: 30 * time.Second, Now: clk.Now}
+	ctx := context.Background()
+
+	if _, err := c.KeySetFor(ctx, "https://tool.example/jwks", "old"); err != nil {
+		t.Fatalf("KeySetFor: %v", err)
+	}
+
+	// Within cooldown, a miss does not refetch.
+	if _, err := c.KeySetFor(ctx, "https://tool.example/jwks", "new"); err != nil {
+		t.Fatalf("KeySetFor: %v", err)
+	}
+	if f.calls != 1 {
+		t.Fatalf("fetch calls within cooldown = %d, want 1", f.calls)
+	}
+
+	clk.Advance(time.Minute)
+	set, err := c.KeySetFor(ctx, "https://tool.example/jwks", "new")
+	if err != nil {
+		t.Fatalf("KeySetFor: %v", err)
+	}
+	if f.calls != 2 {
+		t.Fatalf("fetch calls after kid miss = %d, want 2", f.calls)
+	}
+	if len(set.Keys) != 2 {
+		t.Fatalf("refreshed set has %d keys, want 2", len(set.Keys))
+	}
+
+	// The refreshed set now serves "new" from cache.
+	if _, err := c.KeySetFor(ctx, "https://tool.example/jwks", "new"); err != nil {
+		t.Fatalf("KeySetFor: %v", err)
+	}
+	if f.calls != 2 {
+		t.Fatalf("fetch calls after hit = %d, want 2", f.calls)
+	}
+}
@@ -0,0 +1,150 @@
+package lti_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/mind-engage/mindengage-lms/pkg/platform/lti"
+)
+
+func TestJWKSHandler_ServesActiveKey(t *testing.T) {
+	ctx := context.Background()
+	store := lti.NewInMemoryKeyStorage()
+	km := &lti.KeyManager{Storage: store, Alg: "RS256", RSAKeyBits: 1024}
+	kid, err := km.EnsureKey(ctx, "t1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again, _ := km.EnsureKey(ctx, "t1"); again != kid {
+		t.Fatalf("EnsureKey rotated a fresh key: %s then %s", kid, again)
+	}
+	rec, err := store.Get(ctx, "t1", kid)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h := &lti.JWKSHandler{
+		ResolveTenantID: func(r *http.Request) (string, error) { return r.Header.Get("X-ME-Tenant"), nil },
+		Provider:        km,
+	}
+	fetch := func(tenant string) lti.JWKS {
+		req := httptest.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil)
+		req.Header.Set("X-ME-Tenant", tenant)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: status %d: %s", tenant, w.Code, w.Body.String())
+		}
+		var set lti.JWKS
+		if err := json.Unmarshal(w.Body.Bytes(), &set); err != nil {
+			t.Fatal(err)
+		}
+		return set
+	}
+
+	set := fetch("t1")
+	if len(set.Keys) != 1 {
+		t.Fatalf("keys = %v, want the one active key", set.Keys)
+	}
+	k := set.Keys[0]
+	if k["kid"] != kid || k["kty"] != "RSA" || k["alg"] != "RS256" || k["use"] != "sig" {
+		t.Fatalf("jwk metadata = %v", k)
+	}
+	if _, ok := k["d"]; ok {
+		t.Fatal("jwk leaks private exponent")
+	}
+	nb, _ := base64.RawURLEncoding.DecodeString(k["n"].(string))
+	eb, _ := base64.RawURLEncoding.DecodeString(k["e"].(string))
+	pub := rec.RSAPrivate.PublicKey
+	if new(big.Int).SetBytes(nb).Cmp(pub.N) != 0 || int(new(big.Int).SetBytes(eb).Int64()) != pub.E {
+		t.Fatal("jwk n/e do not match the active key")
+	}
+
+	// Tenants are kept apart: another tenant does not see t1's key.
+	if other := fetch("t2"); len(other.Keys) != 0 {
+		t.Fatalf("t2 keys = %v", other.Keys)
+	}
+}
+
+func TestPublicJWKS_VisibilityWindow(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	km := &lti.KeyManager{
+		Storage: lti.NewInMemoryKeyStorage(),
+		Overlap: 24 * time.Hour,
+		Now:     func() time.Time { return now },
+	}
+	priv, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	day := 24 * time.Hour
+	for _, k := range []struct {
+		kid      string
+		nbf, exp time.Time
+	}{
+		{"future", now.Add(time.Hour), now.Add(30 * day)},
+		{"active", now.Add(-day), now.Add(29 * day)},
+		{"overlap", now.Add(-30 * day), now.Add(-time.Hour)}, // expired, within 24h overlap
+		{"edge", now.Add(-30 * day), now.Add(-day)},          // exactly NotAfter+Overlap == now
+		{"expired", now.Add(-60 * day), now.Add(-2 * day)},
+	} {
+		if err := km.SeedRSAKey(ctx, "t1", k.kid, priv, k.nbf, k.exp); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	set, err := km.PublicJWKS(ctx, "t1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]bool{}
+	for _, k := range set.Keys {
+		got[k["kid"].(string)] = true
+	}
+	want := map[string]bool{"active": true, "overlap": true, "edge": true}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("published kids = %v, want %v", got, want)
+	}
+}
+
+func TestPublicJWK_AdvertisesAlgAndUse(t *testing.T) {
+	rk, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ek, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []struct {
+		name string
+		jwk  map[string]any
+		alg  string
+	}{
+		{"rsa default", lti.RSAPublicJWK(&rk.PublicKey, "r1", ""), "RS256"},
+		{"rsa explicit", lti.RSAPublicJWK(&rk.PublicKey, "r2", "RS512"), "RS512"},
+		{"ec from curve", lti.ECPublicJWK(&ek.PublicKey, "e1", ""), "ES384"},
+		{"ec mismatched alg", lti.ECPublicJWK(&ek.PublicKey, "e2", "ES256"), "ES384"},
+	} {
+		// round-trip through JSON, as tools see it
+		b, _ := json.Marshal(c.jwk)
+		var got map[string]any
+		_ = json.Unmarshal(b, &got)
+		ops, _ := got["key_ops"].([]any)
+		if got["alg"] != c.alg || got["use"] != "sig" || len(ops) != 1 || ops[0] != "verify" {
+			t.Errorf("%s: jwk = %s", c.name, b)
+		}
+	}
+}
@@ -52,7 +52,8 @@ How to wire:
     }
 
 Production notes:
-- Replace InMemoryKeyStorage with a durable store (SQL/kv/HSM) in production.
+- Replace InMemoryKeyStorage with a durable store in production (SQLKeyStorage
+  in keys_sql.go, or kv/HSM).
 - Never expose private material; PublicJWKS returns only public parameters.
 - Overlap should be >= maximum token lifetime + clock skew.
 */
@@ -93,11 +94,11 @@ func (k KeyRecord) IsActive(now time.Time) bool {
 	return !now.Before(k.NotBefore) && now.Before(k.NotAfter)
 }
 
-// IsVisibleInJWKS returns true when the key should still be published in JWKS
-// to validate tokens signed near the end of its life (caller adds overlap).
-func (k KeyRecord) IsVisibleInJWKS(now, overlap time.Time) bool {
-	// Visible until NotAfter+overlap >= now
-	return now.Before(overlap)
+// IsVisibleInJWKS reports whether the key belongs in the published JWKS:
+// NotBefore <= now <= NotAfter+overlap. Keys not yet valid are withheld; keys
+// past NotAfter stay visible for overlap so tokens they signed still verify.
+func (k KeyRecord) IsVisibleInJWKS(now time.Time, overlap time.Duration) bool {
+	return !now.Before(k.NotBefore) && !now.After(k.NotAfter.Add(overlap))
 }
 
 // -------------------------------- Storage ------------------------------------
@@ -214,20 +215,9 @@ func (km *KeyManager) PublicJWKS(ctx context.Context, tenantID string) (JWKS, er
 		return JWKS{}, err
 	}
 	now := km.now()
-	// Include keys whose NotBefore <= now and now < NotAfter+Overlap
-	cutoff := now.Add(km.overlap())
 	var jwks JWKS
 	for _, k := range keys {
-		if now.Before(k.NotBefore) {
-			continue
-		}
-		if !cutoff.Before(k.NotAfter) || cutoff.Equal(k.NotAfter) {
-			// keep while cutoff <= NotAfter ? No: we want (now <= NotAfter+overlap)
-			// So include when now <= NotAfter+overlap
-			// If cutoff <= NotAfter fails, then now > NotAfter+overlap, so skip.
-			// The condition above is inverted; rewrite simply:
-		}
-		if now.After(k.NotAfter.Add(km.overlap())) {
+		if !k.IsVisibleInJWKS(now, km.overlap()) {
 			continue
 		}
 		if pub := k.Public(); pub != nil {
@@ -254,13 +244,13 @@ func (km *KeyManager) ensureCurrentKey(ctx context.Context, tenantID string) (Ke
 	}
 	now := km.now()
 
-	// Find an active key
+	// Find the newest active key (during overlap the old one is still active,
+	// and storage order is arbitrary)
 	var current *KeyRecord
 	for i := range keys {
-		if keys[i].IsActive(now) {
+		if keys[i].IsActive(now) && (current == nil || keys[i].NotAfter.After(current.NotAfter)) {
 			cp := keys[i]
 			current = &cp
-			break
 		}
 	}
 	if current != nil && now.Add(km.overlap()).Before(current.NotAfter) {
@@ -427,3 +417,14 @@ func (km *KeyManager) ActiveKID(ctx context.Context, tenantID string) (string, e
 	}
 	return "", ErrNoActiveKey
 }
+
+// EnsureKey makes sure tenantID has an active signing key, generating one if
+// needed, and returns its kid. Call it at startup so the JWKS already lists
+// the key before the first token is signed.
+func (km *KeyManager) EnsureKey(ctx context.Context, tenantID string) (string, error) {
+	if km.Storage == nil {
+		return "", errors.New("keys: storage not configured")
+	}
+	rec, err := km.ensureCurrentKey(ctx, tenantID)
+	return rec.KID, err
+}
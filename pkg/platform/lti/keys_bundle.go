@@ -0,0 +1,157 @@
+// pkg/platform/lti/keys_bundle.go
+package lti
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+/*
+Key bundles move a tenant's signing keys between instances (backup/restore,
+disaster recovery). A bundle is JSON:
+
+	{"version":1,"tenant_id":"t1","kdf":"scrypt","salt":"...","sealed":"..."}
+
+"sealed" is AES-256-GCM (AESGCMEncryptor) under a key derived from an
+operator passphrase, so the bundle is safe to store with ordinary backups.
+Each key keeps its kid and validity window, so tokens issued before the
+restore still verify against the restored JWKS.
+*/
+
+const keyBundleVersion = 1
+
+// scrypt cost parameters (interactive-login strength, ~100ms).
+const (
+	bundleScryptN = 1 << 15
+	bundleScryptR = 8
+	bundleScryptP = 1
+)
+
+type keyBundle struct {
+	Version  int    `json:"version"`
+	TenantID string `json:"tenant_id"`
+	KDF      string `json:"kdf"`
+	Salt     []byte `json:"salt"`
+	Sealed   []byte `json:"sealed"`
+}
+
+type bundledKey struct {
+	JWK       map[string]any `json:"jwk"`
+	CreatedAt time.Time      `json:"created_at"`
+	NotBefore time.Time      `json:"nbf"`
+	NotAfter  time.Time      `json:"exp"`
+}
+
+// ExportKeys returns every key of tenantID, private halves included, as a
+// bundle encrypted under passphrase.
+func ExportKeys(ctx context.Context, st KeyStorage, tenantID, passphrase string) ([]byte, error) {
+	if passphrase == "" {
+		return nil, errors.New("keys: bundle passphrase required")
+	}
+	recs, err := st.List(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	if len(recs) == 0 {
+		return nil, fmt.Errorf("keys: tenant %q has no keys", tenantID)
+	}
+	keys := make([]bundledKey, 0, len(recs))
+	for _, rec := range recs {
+		jwk := privateJWK(rec)
+		if jwk == nil {
+			return nil, fmt.Errorf("keys: key %s has no private key", rec.KID)
+		}
+		keys = append(keys, bundledKey{JWK: jwk, CreatedAt: rec.CreatedAt, NotBefore: rec.NotBefore, NotAfter: rec.NotAfter})
+	}
+	plain, err := json.Marshal(keys)
+	if err != nil {
+		return nil, err
+	}
+
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	enc, err := bundleEncryptor(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+	sealed, err := enc.Seal(ctx, plain, bundleAAD(tenantID))
+	if err != nil {
+		return nil, err
+	}
+	return json.MarshalIndent(keyBundle{
+		Version:  keyBundleVersion,
+		TenantID: tenantID,
+		KDF:      "scrypt",
+		Salt:     salt,
+		Sealed:   sealed,
+	}, "", "  ")
+}
+
+// ImportKeys decrypts a bundle made by ExportKeys and saves its keys for
+// tenantID (the bundle's own tenant when empty), replacing keys with the same
+// kid. It returns how many keys were written.
+func ImportKeys(ctx context.Context, st KeyStorage, tenantID string, bundle []byte, passphrase string) (int, error) {
+	var b keyBundle
+	if err := json.Unmarshal(bundle, &b); err != nil {
+		return 0, fmt.Errorf("keys: bundle: %w", err)
+	}
+	if b.Version != keyBundleVersion || b.KDF != "scrypt" {
+		return 0, fmt.Errorf("keys: unsupported bundle version %d/%s", b.Version, b.KDF)
+	}
+	if tenantID == "" {
+		tenantID = b.TenantID
+	}
+	enc, err := bundleEncryptor(passphrase, b.Salt)
+	if err != nil {
+		return 0, err
+	}
+	plain, err := enc.Open(ctx, b.Sealed, bundleAAD(b.TenantID))
+	if err != nil {
+		return 0, errors.New("keys: cannot decrypt bundle (wrong passphrase?)")
+	}
+	var keys []bundledKey
+	if err := json.Unmarshal(plain, &keys); err != nil {
+		return 0, fmt.Errorf("keys: bundle: %w", err)
+	}
+
+	// decode everything before writing anything
+	recs := make([]KeyRecord, 0, len(keys))
+	for _, k := range keys {
+		rec, err := keyRecordFromJWK(k.JWK)
+		if err != nil {
+			return 0, err
+		}
+		rec.CreatedAt, rec.NotBefore, rec.NotAfter = k.CreatedAt, k.NotBefore, k.NotAfter
+		recs = append(recs, rec)
+	}
+	for i, rec := range recs {
+		if err := st.Save(ctx, tenantID, rec); err != nil {
+			return i, fmt.Errorf("keys: save %s: %w", rec.KID, err)
+		}
+	}
+	return len(recs), nil
+}
+
+// bundleAAD binds the sealed keys to the bundle's (unencrypted) tenant field.
+func bundleAAD(tenantID string) []byte {
+	return []byte("key_bundle/" + tenantID)
+}
+
+func bundleEncryptor(passphrase string, salt []byte) (*AESGCMEncryptor, error) {
+	if passphrase == "" {
+		return nil, errors.New("keys: bundle passphrase required")
+	}
+	key, err := scrypt.Key([]byte(passphrase), salt, bundleScryptN, bundleScryptR, bundleScryptP, 32)
+	if err != nil {
+		return nil, err
+	}
+	return NewAESGCMEncryptor(key)
+}
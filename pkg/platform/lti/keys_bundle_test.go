@@ -0,0 +1,76 @@
+package lti_test
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/mind-engage/mindengage-lms/pkg/platform/lti"
+)
+
+func TestKeyBundle_ExportImportReproducesJWKS(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := func() time.Time { return now }
+
+	db := openKeyDB(t, filepath.Join(t.TempDir(), "src.db"))
+	defer db.Close()
+	src := &lti.KeyManager{
+		Storage:          &lti.SQLKeyStorage{DB: db.SQL, Enc: testEncryptor(t)},
+		RSAKeyBits:       1024,
+		RotationInterval: 30 * 24 * time.Hour,
+		Overlap:          24 * time.Hour,
+		Now:              clock,
+	}
+	claims := map[string]any{"sub": "u1"}
+	if _, err := src.Sign(ctx, "t1", claims); err != nil {
+		t.Fatal(err)
+	}
+	// a second, rotated key: both must travel
+	now = now.Add(30*24*time.Hour - time.Hour)
+	tok, err := src.Sign(ctx, "t1", claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := src.PublicJWKS(ctx, "t1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(want.Keys) != 2 {
+		t.Fatalf("source jwks has %d keys, want 2", len(want.Keys))
+	}
+
+	bundle, err := lti.ExportKeys(ctx, src.Storage, "t1", "correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dst := &lti.KeyManager{Storage: lti.NewInMemoryKeyStorage(), Overlap: 24 * time.Hour, Now: clock}
+	if _, err := lti.ImportKeys(ctx, dst.Storage, "", bundle, "wrong"); err == nil {
+		t.Fatal("imported with the wrong passphrase")
+	}
+	n, err := lti.ImportKeys(ctx, dst.Storage, "", bundle, "correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Fatalf("imported %d keys, want 2", n)
+	}
+	got, err := dst.PublicJWKS(ctx, "t1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("jwks after import = %v, want %v", got, want)
+	}
+	// same active key and window: the restored instance keeps signing as before
+	tok2, err := dst.Sign(ctx, "t1", claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tok2 != tok {
+		t.Fatal("restored instance signs differently")
+	}
+}
@@ -0,0 +1,119 @@
+// pkg/platform/lti/keys_encrypt.go
+package lti
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Encryptor implementations for SQLKeyStorage.
+//
+//   - AESGCMEncryptor: local master key (32 bytes), the default.
+//   - KMSEncryptor:    delegates to an external KMS (kms://<key-id>); the
+//     client is pluggable and nothing is wired yet, so config rejects kms://.
+
+// aesGCMVersion prefixes AES-GCM ciphertexts so the format can change later.
+const aesGCMVersion byte = 1
+
+// AESGCMEncryptor seals with AES-256-GCM under a master key. Output is
+// version || nonce || ciphertext+tag; aad is the GCM additional data.
+type AESGCMEncryptor struct {
+	aead cipher.AEAD
+}
+
+// NewAESGCMEncryptor returns an encryptor for a 32-byte master key.
+func NewAESGCMEncryptor(masterKey []byte) (*AESGCMEncryptor, error) {
+	if len(masterKey) != 32 {
+		return nil, fmt.Errorf("keys: master key must be 32 bytes, got %d", len(masterKey))
+	}
+	block, err := aes.NewCipher(masterKey)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &AESGCMEncryptor{aead: aead}, nil
+}
+
+func (e *AESGCMEncryptor) Seal(_ context.Context, plaintext, aad []byte) ([]byte, error) {
+	nonce := make([]byte, e.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	out := append([]byte{aesGCMVersion}, nonce...)
+	return e.aead.Seal(out, nonce, plaintext, aad), nil
+}
+
+func (e *AESGCMEncryptor) Open(_ context.Context, ciphertext, aad []byte) ([]byte, error) {
+	ns := e.aead.NonceSize()
+	if len(ciphertext) < 1+ns+e.aead.Overhead() || ciphertext[0] != aesGCMVersion {
+		return nil, errors.New("keys: malformed ciphertext")
+	}
+	pt, err := e.aead.Open(nil, ciphertext[1:1+ns], ciphertext[1+ns:], aad)
+	if err != nil {
+		return nil, errors.New("keys: decrypt failed (wrong master key or record?)")
+	}
+	return pt, nil
+}
+
+// KMSClient is the slice of a cloud KMS API the platform needs. aad maps to
+// the provider's encryption context / additional authenticated data.
+type KMSClient interface {
+	Encrypt(ctx context.Context, keyID string, plaintext, aad []byte) ([]byte, error)
+	Decrypt(ctx context.Context, keyID string, ciphertext, aad []byte) ([]byte, error)
+}
+
+// ErrKMSNotConfigured is returned by a KMSEncryptor without a client.
+var ErrKMSNotConfigured = errors.New("keys: kms client not configured")
+
+// KMSEncryptor seals through an external KMS key.
+type KMSEncryptor struct {
+	KeyID  string    // provider key id/ARN, from kms://<key-id>
+	Client KMSClient // nil until a provider is wired; every call then fails
+}
+
+// NewKMSEncryptor parses a kms://<key-id> URI.
+func NewKMSEncryptor(uri string, client KMSClient) (*KMSEncryptor, error) {
+	id, ok := strings.CutPrefix(uri, "kms://")
+	if !ok || id == "" {
+		return nil, fmt.Errorf("keys: want kms://<key-id>, got %q", uri)
+	}
+	return &KMSEncryptor{KeyID: id, Client: client}, nil
+}
+
+func (e *KMSEncryptor) Seal(ctx context.Context, plaintext, aad []byte) ([]byte, error) {
+	if e.Client == nil {
+		return nil, ErrKMSNotConfigured
+	}
+	return e.Client.Encrypt(ctx, e.KeyID, plaintext, aad)
+}
+
+func (e *KMSEncryptor) Open(ctx context.Context, ciphertext, aad []byte) ([]byte, error) {
+	if e.Client == nil {
+		return nil, ErrKMSNotConfigured
+	}
+	return e.Client.Decrypt(ctx, e.KeyID, ciphertext, aad)
+}
+
+// NewEncryptor picks the Encryptor for the platform's key settings: AES-GCM
+// under masterKeyB64. A kms:// keystore URI fails with ErrKMSNotConfigured
+// until a provider client is wired, so startup stops instead of every later
+// wrap or unwrap.
+func NewEncryptor(keystoreURI, masterKeyB64 string) (Encryptor, error) {
+	if strings.HasPrefix(keystoreURI, "kms://") {
+		return nil, ErrKMSNotConfigured
+	}
+	master, err := base64.StdEncoding.DecodeString(masterKeyB64)
+	if err != nil {
+		return nil, fmt.Errorf("keys: master key: %w", err)
+	}
+	return NewAESGCMEncryptor(master)
+}
@@ -0,0 +1,75 @@
+package lti_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"errors"
+	"testing"
+
+	"github.com/mind-engage/mindengage-lms/pkg/platform/lti"
+)
+
+func TestAESGCMEncryptor_RoundTrip(t *testing.T) {
+	ctx := context.Background()
+	key := make([]byte, 32)
+	_, _ = rand.Read(key)
+	enc, err := lti.NewAESGCMEncryptor(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plain := []byte(`{"jwk":{"kty":"RSA","d":"secret"}}`)
+	aad := []byte("tenant_keys/t1/k1")
+
+	c1, err := enc.Seal(ctx, plain, aad)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c2, _ := enc.Seal(ctx, plain, aad)
+	if bytes.Contains(c1, plain) || bytes.Contains(c1, []byte("secret")) {
+		t.Fatal("ciphertext contains plaintext")
+	}
+	if bytes.Equal(c1, c2) {
+		t.Fatal("sealing twice gave the same ciphertext (nonce reuse)")
+	}
+	got, err := enc.Open(ctx, c1, aad)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, plain) {
+		t.Fatalf("open = %q", got)
+	}
+
+	if _, err := enc.Open(ctx, c1, []byte("tenant_keys/t1/k2")); err == nil {
+		t.Fatal("opened under another record's aad")
+	}
+	c1[len(c1)-1] ^= 1
+	if _, err := enc.Open(ctx, c1, aad); err == nil {
+		t.Fatal("tampered ciphertext opened")
+	}
+	other := make([]byte, 32)
+	other[0] = 1
+	wrong, _ := lti.NewAESGCMEncryptor(other)
+	if _, err := wrong.Open(ctx, c2, aad); err == nil {
+		t.Fatal("opened with the wrong master key")
+	}
+	if _, err := lti.NewAESGCMEncryptor(key[:16]); err == nil {
+		t.Fatal("short master key accepted")
+	}
+}
+
+func TestKMSEncryptor_Unconfigured(t *testing.T) {
+	enc, err := lti.NewKMSEncryptor("kms://platform-keys", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := enc.Seal(context.Background(), []byte("x"), nil); !errors.Is(err, lti.ErrKMSNotConfigured) {
+		t.Fatalf("err = %v", err)
+	}
+	if _, err := lti.NewKMSEncryptor("file:///tmp/k", nil); err == nil {
+		t.Fatal("non-kms uri accepted")
+	}
+	if _, err := lti.NewEncryptor("kms://platform-keys", ""); !errors.Is(err, lti.ErrKMSNotConfigured) {
+		t.Fatalf("NewEncryptor(kms://) err = %v, want ErrKMSNotConfigured", err)
+	}
+}
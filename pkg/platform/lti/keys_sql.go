@@ -0,0 +1,276 @@
+// pkg/platform/lti/keys_sql.go
+package lti
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+)
+
+/*
+SQLKeyStorage persists tenant signing keys in the tenant_keys table
+(see pkg/platform/storage/migrations.go):
+
+	public_jwk       public JWK (what JWKS publishes)
+	private_jwk_enc  private JWK + NotBefore, sealed by an Encryptor, base64
+	created_at       KeyRecord.CreatedAt
+	rotates_at       KeyRecord.NotAfter
+
+Keys survive restarts, so tokens signed before a deploy keep verifying and the
+KeyManager keeps signing with the same kid until rotation is due.
+*/
+
+// Encryptor seals private key material before it is written to storage and
+// opens it on read. Implementations may call out to a KMS.
+//
+// aad is authenticated but not encrypted: callers pass the id of the record
+// the ciphertext belongs to, so a sealed value copied into another row fails
+// to open.
+type Encryptor interface {
+	Seal(ctx context.Context, plaintext, aad []byte) ([]byte, error)
+	Open(ctx context.Context, ciphertext, aad []byte) ([]byte, error)
+}
+
+// SQLKeyStorage implements KeyStorage over tenant_keys. The tenant row must
+// exist (tenant_keys references tenants).
+type SQLKeyStorage struct {
+	DB  *sql.DB
+	Enc Encryptor // required; private keys are never stored in the clear
+}
+
+// sealedKey is the plaintext sealed into private_jwk_enc.
+type sealedKey struct {
+	JWK       map[string]any `json:"jwk"`
+	NotBefore time.Time      `json:"nbf"`
+}
+
+func (s *SQLKeyStorage) List(ctx context.Context, tenantID string) ([]KeyRecord, error) {
+	if err := s.check(); err != nil {
+		return nil, err
+	}
+	rows, err := s.DB.QueryContext(ctx,
+		`SELECT kid, public_jwk, private_jwk_enc, created_at, rotates_at FROM tenant_keys WHERE tenant_id=$1`,
+		tenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []KeyRecord
+	for rows.Next() {
+		rec, err := s.scan(ctx, tenantID, rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, rec)
+	}
+	return out, rows.Err()
+}
+
+func (s *SQLKeyStorage) Save(ctx context.Context, tenantID string, rec KeyRecord) error {
+	if err := s.check(); err != nil {
+		return err
+	}
+	if tenantID == "" || rec.KID == "" {
+		return errors.New("keystore: tenant and kid required")
+	}
+	pub := rec.Public()
+	priv := privateJWK(rec)
+	if pub == nil || priv == nil {
+		return fmt.Errorf("keystore: key %s has no private key", rec.KID)
+	}
+	pubJSON, err := json.Marshal(pub)
+	if err != nil {
+		return err
+	}
+	plain, err := json.Marshal(sealedKey{JWK: priv, NotBefore: rec.NotBefore.UTC()})
+	if err != nil {
+		return err
+	}
+	sealed, err := s.Enc.Seal(ctx, plain, keyAAD(tenantID, rec.KID))
+	if err != nil {
+		return fmt.Errorf("keystore: seal %s: %w", rec.KID, err)
+	}
+	created := rec.CreatedAt
+	if created.IsZero() {
+		created = time.Now()
+	}
+	_, err = s.DB.ExecContext(ctx, `
+		INSERT INTO tenant_keys (tenant_id, kid, public_jwk, private_jwk_enc, created_at, rotates_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (tenant_id, kid) DO UPDATE SET
+			public_jwk = excluded.public_jwk,
+			private_jwk_enc = excluded.private_jwk_enc,
+			created_at = excluded.created_at,
+			rotates_at = excluded.rotates_at`,
+		tenantID, rec.KID, string(pubJSON), base64.StdEncoding.EncodeToString(sealed),
+		created.UTC(), rec.NotAfter.UTC())
+	return err
+}
+
+func (s *SQLKeyStorage) Get(ctx context.Context, tenantID, kid string) (KeyRecord, error) {
+	if err := s.check(); err != nil {
+		return KeyRecord{}, err
+	}
+	row := s.DB.QueryRowContext(ctx,
+		`SELECT kid, public_jwk, private_jwk_enc, created_at, rotates_at FROM tenant_keys WHERE tenant_id=$1 AND kid=$2`,
+		tenantID, kid)
+	rec, err := s.scan(ctx, tenantID, row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return KeyRecord{}, errors.New("keystore: key not found")
+	}
+	return rec, err
+}
+
+func (s *SQLKeyStorage) check() error {
+	if s == nil || s.DB == nil {
+		return errors.New("keystore: db not configured")
+	}
+	if s.Enc == nil {
+		return errors.New("keystore: encryptor not configured")
+	}
+	return nil
+}
+
+// keyAAD identifies a tenant_keys row for Encryptor.
+func keyAAD(tenantID, kid string) []byte {
+	return []byte("tenant_keys/" + tenantID + "/" + kid)
+}
+
+func (s *SQLKeyStorage) scan(ctx context.Context, tenantID string, row interface{ Scan(...any) error }) (KeyRecord, error) {
+	var (
+		kid, pubJSON, enc string
+		created           time.Time
+		rotates           sql.NullTime
+	)
+	if err := row.Scan(&kid, &pubJSON, &enc, &created, &rotates); err != nil {
+		return KeyRecord{}, err
+	}
+	sealed, err := base64.StdEncoding.DecodeString(enc)
+	if err != nil {
+		return KeyRecord{}, fmt.Errorf("keystore: key %s: %w", kid, err)
+	}
+	plain, err := s.Enc.Open(ctx, sealed, keyAAD(tenantID, kid))
+	if err != nil {
+		return KeyRecord{}, fmt.Errorf("keystore: open %s: %w", kid, err)
+	}
+	var sk sealedKey
+	if err := json.Unmarshal(plain, &sk); err != nil {
+		return KeyRecord{}, fmt.Errorf("keystore: key %s: %w", kid, err)
+	}
+	rec, err := keyRecordFromJWK(sk.JWK)
+	if err != nil {
+		return KeyRecord{}, fmt.Errorf("keystore: key %s: %w", kid, err)
+	}
+	rec.KID = kid
+	rec.CreatedAt = created.UTC()
+	rec.NotBefore = sk.NotBefore
+	if rotates.Valid {
+		rec.NotAfter = rotates.Time.UTC()
+	}
+	return rec, nil
+}
+
+// privateJWK is the full private JWK (RFC 7518 §6.2.2 / §6.3.2) for rec.
+func privateJWK(rec KeyRecord) map[string]any {
+	jwk := rec.Public()
+	if jwk == nil {
+		return nil
+	}
+	delete(jwk, "key_ops")
+	switch {
+	case rec.RSAPrivate != nil:
+		k := rec.RSAPrivate
+		k.Precompute()
+		jwk["d"] = bigIntToB64(k.D)
+		if len(k.Primes) == 2 {
+			jwk["p"] = bigIntToB64(k.Primes[0])
+			jwk["q"] = bigIntToB64(k.Primes[1])
+			jwk["dp"] = bigIntToB64(k.Precomputed.Dp)
+			jwk["dq"] = bigIntToB64(k.Precomputed.Dq)
+			jwk["qi"] = bigIntToB64(k.Precomputed.Qinv)
+		}
+	case rec.ECDSAPrivate != nil:
+		jwk["d"] = bigIntToB64(rec.ECDSAPrivate.D)
+	}
+	return jwk
+}
+
+// keyRecordFromJWK rebuilds the private key of a JWK written by privateJWK.
+func keyRecordFromJWK(jwk map[string]any) (KeyRecord, error) {
+	str := func(k string) string { s, _ := jwk[k].(string); return s }
+	num := func(k string) (*big.Int, error) {
+		b, err := base64.RawURLEncoding.DecodeString(str(k))
+		if err != nil || len(b) == 0 {
+			return nil, fmt.Errorf("jwk: bad %q", k)
+		}
+		return new(big.Int).SetBytes(b), nil
+	}
+	rec := KeyRecord{KID: str("kid"), Alg: str("alg")}
+
+	switch str("kty") {
+	case "RSA":
+		var n, e, d *big.Int
+		var err error
+		for _, f := range []struct {
+			name string
+			dst  **big.Int
+		}{{"n", &n}, {"e", &e}, {"d", &d}} {
+			if *f.dst, err = num(f.name); err != nil {
+				return KeyRecord{}, err
+			}
+		}
+		k := &rsa.PrivateKey{PublicKey: rsa.PublicKey{N: n, E: int(e.Int64())}, D: d}
+		if str("p") != "" {
+			p, err := num("p")
+			if err != nil {
+				return KeyRecord{}, err
+			}
+			q, err := num("q")
+			if err != nil {
+				return KeyRecord{}, err
+			}
+			k.Primes = []*big.Int{p, q}
+		}
+		if err := k.Validate(); err != nil {
+			return KeyRecord{}, fmt.Errorf("jwk: %w", err)
+		}
+		k.Precompute()
+		rec.RSAPrivate = k
+	case "EC":
+		var curve elliptic.Curve
+		switch str("crv") {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return KeyRecord{}, fmt.Errorf("jwk: unsupported crv %q", str("crv"))
+		}
+		x, err := num("x")
+		if err != nil {
+			return KeyRecord{}, err
+		}
+		y, err := num("y")
+		if err != nil {
+			return KeyRecord{}, err
+		}
+		d, err := num("d")
+		if err != nil {
+			return KeyRecord{}, err
+		}
+		rec.ECDSAPrivate = &ecdsa.PrivateKey{PublicKey: ecdsa.PublicKey{Curve: curve, X: x, Y: y}, D: d}
+	default:
+		return KeyRecord{}, fmt.Errorf("jwk: unsupported kty %q", str("kty"))
+	}
+	return rec, nil
+}
@@ -0,0 +1,153 @@
+package lti_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/base64"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	_ "modernc.org/sqlite"
+
+	"github.com/mind-engage/mindengage-lms/pkg/platform/lti"
+	"github.com/mind-engage/mindengage-lms/pkg/platform/storage"
+)
+
+func testEncryptor(t *testing.T) lti.Encryptor {
+	t.Helper()
+	enc, err := lti.NewAESGCMEncryptor(bytes.Repeat([]byte{7}, 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return enc
+}
+
+func openKeyDB(t *testing.T, path string) *storage.DB {
+	t.Helper()
+	ctx := context.Background()
+	db, err := storage.Connect(ctx, "sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := storage.Up(ctx, db, "sqlite"); err != nil {
+		t.Fatal(err)
+	}
+	_, err = db.SQL.ExecContext(ctx, `INSERT OR IGNORE INTO tenants (id, issuer) VALUES ('t1', 'https://t1.example.com')`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestSQLKeyStorage_SurvivesRestart(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "platform.db")
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	claims := map[string]any{"iss": "https://t1.example.com", "sub": "u1", "iat": now.Unix()}
+
+	db := openKeyDB(t, path)
+	km := &lti.KeyManager{
+		Storage:    &lti.SQLKeyStorage{DB: db.SQL, Enc: testEncryptor(t)},
+		RSAKeyBits: 1024, // fast; size is irrelevant here
+		Now:        func() time.Time { return now },
+	}
+	tok1, err := km.Sign(ctx, "t1", claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+	jwks1, err := km.PublicJWKS(ctx, "t1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	kid1, _ := km.ActiveKID(ctx, "t1")
+	db.Close()
+
+	// "restart": new connection, new manager
+	db = openKeyDB(t, path)
+	defer db.Close()
+	km = &lti.KeyManager{
+		Storage: &lti.SQLKeyStorage{DB: db.SQL, Enc: testEncryptor(t)},
+		Now:     func() time.Time { return now.Add(time.Hour) },
+	}
+	if kid, _ := km.ActiveKID(ctx, "t1"); kid != kid1 {
+		t.Fatalf("active kid after restart = %q, want %q", kid, kid1)
+	}
+	tok2, err := km.Sign(ctx, "t1", claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tok2 != tok1 {
+		t.Fatal("token signed after restart differs")
+	}
+	jwks2, err := km.PublicJWKS(ctx, "t1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(jwks1, jwks2) {
+		t.Fatalf("jwks changed: %v vs %v", jwks1, jwks2)
+	}
+
+	// the private key is sealed, not stored as JSON
+	var enc string
+	if err := db.SQL.QueryRow(`SELECT private_jwk_enc FROM tenant_keys WHERE kid=$1`, kid1).Scan(&enc); err != nil {
+		t.Fatal(err)
+	}
+	raw, err := base64.StdEncoding.DecodeString(enc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(raw, []byte(`"jwk"`)) || bytes.Contains(raw, []byte(kid1)) {
+		t.Fatalf("private_jwk_enc looks like plaintext: %.40s", raw)
+	}
+	if _, err := (&lti.SQLKeyStorage{DB: db.SQL}).Get(ctx, "t1", kid1); err == nil {
+		t.Fatal("Get without an encryptor should fail")
+	}
+}
+
+func TestSQLKeyStorage_ECDSA(t *testing.T) {
+	ctx := context.Background()
+	db := openKeyDB(t, filepath.Join(t.TempDir(), "platform.db"))
+	defer db.Close()
+	st := &lti.SQLKeyStorage{DB: db.SQL, Enc: testEncryptor(t)}
+
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nb := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	rec := lti.KeyRecord{KID: "ec-1", Alg: "ES256", CreatedAt: nb, NotBefore: nb, NotAfter: nb.Add(24 * time.Hour), ECDSAPrivate: priv}
+	if err := st.Save(ctx, "t1", rec); err != nil {
+		t.Fatal(err)
+	}
+	got, err := st.Get(ctx, "t1", "ec-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.ECDSAPrivate == nil || !got.ECDSAPrivate.Equal(priv) {
+		t.Fatal("ecdsa key did not round-trip")
+	}
+
+	// A sealed key pasted into another row doesn't open there.
+	rec2 := rec
+	rec2.KID = "ec-2"
+	if err := st.Save(ctx, "t1", rec2); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.SQL.Exec(`UPDATE tenant_keys SET private_jwk_enc=(SELECT private_jwk_enc FROM tenant_keys WHERE kid='ec-1') WHERE kid='ec-2'`); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := st.Get(ctx, "t1", "ec-2"); err == nil {
+		t.Fatal("swapped ciphertext opened under another kid")
+	}
+	if got.Alg != "ES256" || !got.NotBefore.Equal(rec.NotBefore) || !got.NotAfter.Equal(rec.NotAfter) || !got.CreatedAt.Equal(rec.CreatedAt) {
+		t.Fatalf("metadata = %+v", got)
+	}
+	if _, err := st.Get(ctx, "t1", "missing"); err == nil {
+		t.Fatal("expected not found")
+	}
+}
@@ -61,6 +61,9 @@ const (
 
 	// NRPS (Names & Role Provisioning Service)
 	ScopeNRPSContextMembershipRead = "https://purl.imsglobal.org/spec/lti-nrps/scope/contextmembership.readonly"
+
+	// Platform administration (non-IMS; only granted to trusted admin clients)
+	ScopePlatformAdmin = "https://mindengage.com/spec/platform/scope/admin"
 )
 
 // --- Claims model & verifier interface --------------------------------------
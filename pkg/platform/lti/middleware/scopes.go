@@ -3,20 +3,20 @@ package middleware
 
 import (
 	"context"
-	"encoding/base64"
 	"encoding/json"
 	"net/http"
 	"strings"
 )
 
 // RequireScopes enforces that the caller's access token includes ALL of the
-// required scopes. It accepts either:
+// required scopes. It reads them from:
 //
-//  1. Scopes attached to the request context by a prior authn middleware via
-//     WithScopesCtx / SetScopesOnContext, OR
-//  2. Scopes decoded from the Bearer JWT's payload "scope" (space-separated)
-//     or "scp" (array) claims (best-effort read; signature is assumed to be
-//     validated by your authn middleware).
+//  1. Scopes attached to the request context via WithScopesCtx /
+//     SetScopesOnContext, OR
+//  2. The AccessClaims BearerAuth verified.
+//
+// It never decodes the Authorization header itself: an unverified token
+// carries no scopes, so mount BearerAuth in front.
 //
 // Special-case: if a handler requires ".../lineitem.readonly", the presence of
 // the write scope ".../lineitem" also satisfies it.
@@ -75,13 +75,9 @@ func haveScopesSet(r *http.Request) map[string]struct{} {
 		}
 	}
 
-	// 2) Fallback: best-effort read from Bearer token payload (already validated upstream)
-	// NOTE: This does NOT validate the signature; it's only used if authn
-	// didn't already attach scopes.
-	if tok := strings.TrimSpace(bearerToken(r.Header.Get("Authorization"))); tok != "" {
-		if scopes := parseScopesFromJWT(tok); len(scopes) > 0 {
-			return toSet(scopes)
-		}
+	// 2) Claims verified by BearerAuth
+	if cl, ok := FromContext(r.Context()); ok {
+		return toSet(uniqueScopes(cl.Scopes))
 	}
 
 	return map[string]struct{}{}
@@ -107,50 +103,6 @@ func satisfiesAll(have map[string]struct{}, required []string) bool {
 	return true
 }
 
-func bearerToken(authorization string) string {
-	if authorization == "" {
-		return ""
-	}
-	parts := strings.Fields(authorization)
-	if len(parts) == 2 && strings.EqualFold(parts[0], "Bearer") {
-		return parts[1]
-	}
-	return ""
-}
-
-func parseScopesFromJWT(jwt string) []string {
-	// JWT is header.payload.signature (base64url). We only need payload.
-	dot1 := strings.IndexByte(jwt, '.')
-	dot2 := strings.LastIndexByte(jwt, '.')
-	if dot1 <= 0 || dot2 <= dot1 {
-		return nil
-	}
-	payloadB64 := jwt[dot1+1 : dot2]
-	payload, err := base64.RawURLEncoding.DecodeString(payloadB64)
-	if err != nil {
-		return nil
-	}
-	var claims map[string]any
-	if err := json.Unmarshal(payload, &claims); err != nil {
-		return nil
-	}
-	// "scope": space-separated string
-	if s, ok := claims["scope"].(string); ok && strings.TrimSpace(s) != "" {
-		return uniqueScopes(strings.Fields(s))
-	}
-	// "scp": array of strings
-	if arr, ok := claims["scp"].([]any); ok {
-		var out []string
-		for _, v := range arr {
-			if str, ok := v.(string); ok {
-				out = append(out, str)
-			}
-		}
-		return uniqueScopes(out)
-	}
-	return nil
-}
-
 func uniqueScopes(in []string) []string {
 	if len(in) == 0 {
 		return nil
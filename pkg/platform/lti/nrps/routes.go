@@ -29,7 +29,10 @@ Behavior:
 - Requires an OAuth2 Bearer token with the scope:
     https://purl.imsglobal.org/spec/lti-nrps/scope/contextmembership.readonly
   (Enforce this with your auth middleware outside of this package.)
-- Supports optional filtering by `role` query param (exact URI or short alias).
+- Members with custom claims in storage get a `message` array holding them
+  (https://purl.imsglobal.org/spec/lti/claim/custom); others get none.
+- Supports optional filtering by `role` query param (exact URI or short alias)
+  and by `rlid` (resource link id: only members associated with that link).
 - Supports pagination with `limit` (default 50, max 200) and `page_token`.
   If the storage indicates there is another page (non-empty next token),
   the response will include a RFC-5988 `Link: <...>; rel="next"` header.
@@ -57,10 +60,11 @@ Wire-up example:
 // Storage abstracts persistence for NRPS data.
 type Storage interface {
 	// ListMemberships returns a page of memberships for a context. If roleFilter
-	// is non-empty, results should be filtered to members that have the role.
-	// pageToken is an opaque implementation-defined cursor for pagination.
+	// is non-empty, results should be filtered to members that have the role;
+	// if resourceLinkID is non-empty, to members associated with that resource
+	// link. pageToken is an opaque implementation-defined cursor for pagination.
 	// Returns: items, nextPageToken ("" when no more), error.
-	ListMemberships(ctx context.Context, tenantID, contextID, roleFilter, pageToken string, limit int) ([]Membership, string, error)
+	ListMemberships(ctx context.Context, tenantID, contextID, roleFilter, resourceLinkID, pageToken string, limit int) ([]Membership, string, error)
 
 	// GetContextMeta returns minimal context metadata for container "context".
 	// If your platform doesn't track label/title, return empty strings.
@@ -84,6 +88,10 @@ type Membership struct {
 	LISPersonSourcedID   string         `json:"lis_person_sourcedid,omitempty"`
 	LTI11LegacyUserID    string         `json:"lti11_legacy_user_id,omitempty"` // optional legacy
 	AdditionalProperties map[string]any `json:"-"`                              // storage-only, not serialized
+
+	// Custom claims for this member; when set, the member carries a "message"
+	// entry with https://purl.imsglobal.org/spec/lti/claim/custom.
+	Custom map[string]string `json:"-"`
 }
 
 // ContextMeta populates the container's "context" object.
@@ -132,6 +140,7 @@ func (s *Server) GetMemberships(w http.ResponseWriter, r *http.Request) {
 	roleFilter := normalizeRole(q.Get("role")) // accepts full URI or friendly alias
 	limit := clamp(parseInt(q.Get("limit"), 50), 1, 200)
 	pageToken := strings.TrimSpace(q.Get("page_token"))
+	rlid := strings.TrimSpace(q.Get("rlid"))
 
 	meta, err := s.Store.GetContextMeta(r.Context(), tenantID, contextID)
 	if err != nil {
@@ -142,7 +151,7 @@ func (s *Server) GetMemberships(w http.ResponseWriter, r *http.Request) {
 		meta.ID = contextID
 	}
 
-	items, nextToken, err := s.Store.ListMemberships(r.Context(), tenantID, contextID, roleFilter, pageToken, limit)
+	items, nextToken, err := s.Store.ListMemberships(r.Context(), tenantID, contextID, roleFilter, rlid, pageToken, limit)
 	if err != nil {
 		writeStorageErr(w, err)
 		return
@@ -215,10 +224,21 @@ func mapMembers(in []Membership) []map[string]any {
 			"lti11_legacy_user_id": m.LTI11LegacyUserID,
 			"picture":              m.Picture,
 		})
+		if len(m.Custom) > 0 {
+			out[len(out)-1]["message"] = []map[string]any{{
+				claimMessageType: "LtiResourceLinkRequest",
+				claimCustom:      m.Custom,
+			}}
+		}
 	}
 	return out
 }
 
+const (
+	claimMessageType = "https://purl.imsglobal.org/spec/lti/claim/message_type"
+	claimCustom      = "https://purl.imsglobal.org/spec/lti/claim/custom"
+)
+
 func normalizeRoles(rs []string) []string {
 	if len(rs) == 0 {
 		return rs
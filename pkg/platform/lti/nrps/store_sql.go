@@ -0,0 +1,278 @@
+// pkg/platform/lti/nrps/store_sql.go
+package nrps
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/mind-engage/mindengage-lms/pkg/platform/admin"
+	"github.com/mind-engage/mindengage-lms/pkg/platform/storage"
+	"github.com/mind-engage/mindengage-lms/pkg/platform/tenants"
+)
+
+/*
+SQLStore implements Storage over the contexts / enrollments tables (see
+pkg/platform/storage/migrations.go), and admin.ContextStore so the admin API
+can create what NRPS serves.
+
+Enrollment roles are stored as LIS role URIs (aliases such as "learner" are
+expanded on write), so the role filter is a plain equality. The rlid filter
+keeps members listed in resource_link_members for that link; associations are
+added by enrollment upserts and never removed by them. Page tokens are offsets
+into the context's members ordered by user_sub. All queries on tenant data
+go through storage.Tenant.
+*/
+type SQLStore struct {
+	DB *sql.DB
+}
+
+var _ admin.ContextStore = (*SQLStore)(nil)
+
+/* ------------------------------ NRPS reads --------------------------------- */
+
+func (s *SQLStore) GetContextMeta(ctx context.Context, tenantID, contextID string) (ContextMeta, error) {
+	tq, err := storage.Tenant(s.DB, tenantID)
+	if err != nil {
+		return ContextMeta{}, err
+	}
+	var m ContextMeta
+	err = tq.QueryRow(ctx, "contexts", `id, COALESCE(label,''), COALESCE(title,'')`, "id=$2", "", contextID).
+		Scan(&m.ID, &m.Label, &m.Title)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ContextMeta{}, errNotFound
+	}
+	return m, err
+}
+
+func (s *SQLStore) ListMemberships(ctx context.Context, tenantID, contextID, roleFilter, resourceLinkID, pageToken string, limit int) ([]Membership, string, error) {
+	tq, err := storage.Tenant(s.DB, tenantID)
+	if err != nil {
+		return nil, "", err
+	}
+	offset, _ := strconv.Atoi(pageToken)
+	if offset < 0 {
+		offset = 0
+	}
+	// One page of members (limit+1 to see whether another page follows) ...
+	cond := `context_id=$2`
+	args := []any{contextID}
+	if roleFilter != "" {
+		args = append(args, normalizeRole(roleFilter))
+		cond += ` AND role=$` + strconv.Itoa(len(args)+1)
+	}
+	if resourceLinkID != "" {
+		args = append(args, resourceLinkID)
+		cond += ` AND user_sub IN (SELECT user_sub FROM resource_link_members
+			WHERE tenant_id=$1 AND context_id=$2 AND resource_link_id=$` + strconv.Itoa(len(args)+1) + `)`
+	}
+	args = append(args, limit+1, offset)
+	tail := `ORDER BY user_sub LIMIT $` + strconv.Itoa(len(args)) + ` OFFSET $` + strconv.Itoa(len(args)+1)
+	subs, err := querySubs(ctx, tq, cond, tail, args...)
+	if err != nil || len(subs) == 0 {
+		return nil, "", err
+	}
+	next := ""
+	if len(subs) > limit {
+		subs = subs[:limit]
+		next = strconv.Itoa(offset + limit)
+	}
+
+	// ... then every role those members hold.
+	rows, err := tq.Query(ctx, "enrollments",
+		`user_sub, role, COALESCE(name,''), COALESCE(email,''), COALESCE(status,''), custom`,
+		`context_id=$2 AND user_sub >= $3 AND user_sub <= $4`, `ORDER BY user_sub, role`,
+		contextID, subs[0], subs[len(subs)-1])
+	if err != nil {
+		return nil, "", err
+	}
+	defer rows.Close()
+	page := make(map[string]bool, len(subs))
+	for _, sub := range subs {
+		page[sub] = true
+	}
+	var out []Membership
+	for rows.Next() {
+		var sub, role, name, email, status string
+		// custom is JSONB on Postgres, where COALESCE(custom,'') does not type-check
+		var custom sql.NullString
+		if err := rows.Scan(&sub, &role, &name, &email, &status, &custom); err != nil {
+			return nil, "", err
+		}
+		if !page[sub] {
+			continue // between two page members but filtered out by role
+		}
+		if n := len(out); n == 0 || out[n-1].UserID != sub {
+			out = append(out, Membership{UserID: sub, Name: name, Email: email, Status: status})
+		}
+		m := &out[len(out)-1]
+		m.Roles = append(m.Roles, role)
+		if custom.Valid {
+			// custom is kept per enrollment row; a member's rows are merged
+			var claims map[string]string
+			if err := json.Unmarshal([]byte(custom.String), &claims); err != nil {
+				return nil, "", fmt.Errorf("nrps: enrollment %s custom: %w", sub, err)
+			}
+			for k, v := range claims {
+				if m.Custom == nil {
+					m.Custom = map[string]string{}
+				}
+				m.Custom[k] = v
+			}
+		}
+	}
+	return out, next, rows.Err()
+}
+
+func querySubs(ctx context.Context, tq storage.Scoped, cond, tail string, args ...any) ([]string, error) {
+	rows, err := tq.Query(ctx, "enrollments", "DISTINCT user_sub", cond, tail, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []string
+	for rows.Next() {
+		var sub string
+		if err := rows.Scan(&sub); err != nil {
+			return nil, err
+		}
+		out = append(out, sub)
+	}
+	return out, rows.Err()
+}
+
+/* --------------------------- admin.ContextStore ---------------------------- */
+
+func (s *SQLStore) CreateContext(ctx context.Context, c tenants.Context) error {
+	tq, err := storage.Tenant(s.DB, c.TenantID)
+	if err != nil {
+		return err
+	}
+	var n int
+	if err := s.DB.QueryRowContext(ctx, `SELECT COUNT(*) FROM tenants WHERE id=$1`, c.TenantID).Scan(&n); err != nil {
+		return err
+	}
+	if n == 0 {
+		return admin.NotFound
+	}
+	if _, err := s.GetContextMeta(ctx, c.TenantID, c.ID); err == nil {
+		return admin.Conflict
+	}
+	_, err = tq.Insert(ctx, "contexts", "id, label, title", "", c.ID, nullIfEmpty(c.Label), nullIfEmpty(c.Title))
+	return err
+}
+
+func (s *SQLStore) ListContexts(ctx context.Context, tenantID string, offset, limit int) ([]tenants.Context, error) {
+	tq, err := storage.Tenant(s.DB, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := tq.Query(ctx, "contexts", `id, COALESCE(label,''), COALESCE(title,'')`,
+		"", "ORDER BY id LIMIT $2 OFFSET $3", limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []tenants.Context
+	for rows.Next() {
+		c := tenants.Context{TenantID: tenantID}
+		if err := rows.Scan(&c.ID, &c.Label, &c.Title); err != nil {
+			return nil, err
+		}
+		out = append(out, c)
+	}
+	return out, rows.Err()
+}
+
+func (s *SQLStore) UpsertEnrollments(ctx context.Context, es []tenants.Enrollment) error {
+	if len(es) == 0 {
+		return nil
+	}
+	// The handler sends one context per call; check it once.
+	if _, err := s.GetContextMeta(ctx, es[0].TenantID, es[0].ContextID); err != nil {
+		if errors.Is(err, errNotFound) {
+			return admin.NotFound
+		}
+		return err
+	}
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	tq, err := storage.Tenant(tx, es[0].TenantID)
+	if err != nil {
+		return err
+	}
+	for _, e := range es {
+		if e.TenantID != es[0].TenantID || e.ContextID != es[0].ContextID {
+			return errors.New("nrps: enrollments must share one context")
+		}
+		if _, err := tq.Insert(ctx, "enrollments", "context_id, user_sub, role, name, email, status, custom", `
+			ON CONFLICT (tenant_id, context_id, user_sub, role) DO UPDATE SET
+				name = excluded.name,
+				email = excluded.email,
+				status = excluded.status,
+				custom = excluded.custom,
+				updated_at = CURRENT_TIMESTAMP`,
+			e.ContextID, e.UserSub, normalizeRole(e.Role),
+			nullIfEmpty(e.Name), nullIfEmpty(e.Email), emptyAs(e.Status, "Active"), customJSON(e.Custom)); err != nil {
+			return err
+		}
+		for _, rlid := range e.ResourceLinkIDs {
+			if _, err := tq.Insert(ctx, "resource_link_members", "context_id, resource_link_id, user_sub",
+				"ON CONFLICT DO NOTHING", e.ContextID, rlid, e.UserSub); err != nil {
+				return err
+			}
+		}
+	}
+	return tx.Commit()
+}
+
+func (s *SQLStore) ListEnrollments(ctx context.Context, tenantID, contextID string, offset, limit int) ([]tenants.Enrollment, error) {
+	if _, err := s.GetContextMeta(ctx, tenantID, contextID); err != nil {
+		if errors.Is(err, errNotFound) {
+			return nil, admin.NotFound
+		}
+		return nil, err
+	}
+	tq, err := storage.Tenant(s.DB, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := tq.Query(ctx, "enrollments",
+		`user_sub, role, COALESCE(name,''), COALESCE(email,''), COALESCE(status,'')`,
+		"context_id=$2", "ORDER BY user_sub, role LIMIT $3 OFFSET $4", contextID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []tenants.Enrollment
+	for rows.Next() {
+		e := tenants.Enrollment{TenantID: tenantID, ContextID: contextID}
+		if err := rows.Scan(&e.UserSub, &e.Role, &e.Name, &e.Email, &e.Status); err != nil {
+			return nil, err
+		}
+		out = append(out, e)
+	}
+	return out, rows.Err()
+}
+
+func customJSON(m map[string]string) any {
+	if len(m) == 0 {
+		return nil
+	}
+	b, _ := json.Marshal(m)
+	return string(b)
+}
+
+func nullIfEmpty(s string) any {
+	if strings.TrimSpace(s) == "" {
+		return nil
+	}
+	return s
+}
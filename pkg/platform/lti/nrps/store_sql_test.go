@@ -0,0 +1,274 @@
+package nrps_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	_ "modernc.org/sqlite"
+
+	"github.com/mind-engage/mindengage-lms/pkg/platform/admin"
+	"github.com/mind-engage/mindengage-lms/pkg/platform/lti/nrps"
+	"github.com/mind-engage/mindengage-lms/pkg/platform/storage"
+)
+
+func newSQLStore(t *testing.T) *nrps.SQLStore {
+	t.Helper()
+	ctx := context.Background()
+	db, err := storage.Connect(ctx, "sqlite", filepath.Join(t.TempDir(), "platform.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := storage.Up(ctx, db, "sqlite"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.SQL.Exec(`INSERT INTO tenants (id, issuer) VALUES ('default', 'https://lti.example.com')`); err != nil {
+		t.Fatal(err)
+	}
+	return &nrps.SQLStore{DB: db.SQL}
+}
+
+func serve(h http.Handler, method, target, body string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(method, target, strings.NewReader(body)))
+	return rec
+}
+
+func TestSQLStore_AdminContextThenMemberships(t *testing.T) {
+	store := newSQLStore(t)
+	adm := admin.ContextRoutes(store)
+
+	if rec := serve(adm, http.MethodPost, "/tenants/default/contexts", `{"ID":"ctx-1","Label":"BIO101","Title":"Biology"}`); rec.Code != http.StatusCreated {
+		t.Fatalf("create context: %d %s", rec.Code, rec.Body.String())
+	}
+	if rec := serve(adm, http.MethodPost, "/tenants/default/contexts", `{"ID":"ctx-1"}`); rec.Code != http.StatusConflict {
+		t.Fatalf("duplicate context: %d", rec.Code)
+	}
+	if rec := serve(adm, http.MethodPost, "/tenants/nope/contexts", `{"ID":"ctx-1"}`); rec.Code != http.StatusNotFound {
+		t.Fatalf("unknown tenant: %d", rec.Code)
+	}
+	for _, body := range []string{
+		`{"UserSub":"u-alice","Role":"learner","Name":"Alice","Email":"alice@example.com"}`,
+		`{"UserSub":"u-bob","Role":"instructor","Name":"Bob"}`,
+		`{"UserSub":"u-bob","Role":"learner","Name":"Bob"}`,
+		`{"UserSub":"u-carol","Role":"instructor","Name":"Carol"}`,
+	} {
+		if rec := serve(adm, http.MethodPut, "/tenants/default/contexts/ctx-1/enrollments", body); rec.Code != http.StatusOK {
+			t.Fatalf("enroll %s: %d %s", body, rec.Code, rec.Body.String())
+		}
+	}
+	if rec := serve(adm, http.MethodPut, "/tenants/default/contexts/ctx-9/enrollments", `{"UserSub":"u","Role":"learner"}`); rec.Code != http.StatusNotFound {
+		t.Fatalf("enroll into unknown context: %d", rec.Code)
+	}
+	if rec := serve(adm, http.MethodGet, "/tenants/default/contexts", ""); !strings.Contains(rec.Body.String(), `"BIO101"`) {
+		t.Fatalf("list contexts: %s", rec.Body.String())
+	}
+
+	srv := nrps.Routes(&nrps.Server{
+		Store:           store,
+		ResolveTenantID: func(*http.Request) (string, error) { return "default", nil },
+	})
+	type container struct {
+		Context struct{ ID, Title string }
+		Members []struct {
+			UserID string   `json:"user_id"`
+			Roles  []string `json:"roles"`
+			Status string   `json:"status"`
+		}
+	}
+	get := func(target string) (container, http.Header) {
+		t.Helper()
+		rec := serve(srv, http.MethodGet, target, "")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("GET %s: %d %s", target, rec.Code, rec.Body.String())
+		}
+		var c container
+		if err := json.Unmarshal(rec.Body.Bytes(), &c); err != nil {
+			t.Fatal(err)
+		}
+		return c, rec.Header()
+	}
+
+	c, _ := get("/contexts/ctx-1/memberships")
+	if c.Context.ID != "ctx-1" || c.Context.Title != "Biology" || len(c.Members) != 3 {
+		t.Fatalf("memberships: %+v", c)
+	}
+	if bob := c.Members[1]; bob.UserID != "u-bob" || len(bob.Roles) != 2 || bob.Status != "Active" {
+		t.Fatalf("bob: %+v", bob)
+	}
+
+	c, _ = get("/contexts/ctx-1/memberships?role=instructor")
+	if len(c.Members) != 2 || c.Members[0].UserID != "u-bob" || c.Members[1].UserID != "u-carol" {
+		t.Fatalf("instructors: %+v", c.Members)
+	}
+
+	c, h := get("/contexts/ctx-1/memberships?limit=2")
+	if len(c.Members) != 2 || !strings.Contains(h.Get("Link"), "page_token=2") {
+		t.Fatalf("page 1: %+v link=%q", c.Members, h.Get("Link"))
+	}
+	c, h = get("/contexts/ctx-1/memberships?limit=2&page_token=2")
+	if len(c.Members) != 1 || c.Members[0].UserID != "u-carol" || h.Get("Link") != "" {
+		t.Fatalf("page 2: %+v link=%q", c.Members, h.Get("Link"))
+	}
+
+	if rec := serve(srv, http.MethodGet, "/contexts/ctx-9/memberships", ""); rec.Code != http.StatusNotFound {
+		t.Fatalf("unknown context: %d", rec.Code)
+	}
+}
+
+func TestSQLStore_BulkEnrollmentUpsert(t *testing.T) {
+	const (
+		learner    = "http://purl.imsglobal.org/vocab/lis/v2/membership#Learner"
+		instructor = "http://purl.imsglobal.org/vocab/lis/v2/membership#Instructor"
+	)
+	store := newSQLStore(t)
+	adm := admin.ContextRoutes(store)
+	if rec := serve(adm, http.MethodPost, "/tenants/default/contexts", `{"ID":"ctx-1"}`); rec.Code != http.StatusCreated {
+		t.Fatalf("create context: %d", rec.Code)
+	}
+	target := "/tenants/default/contexts/ctx-1/enrollments"
+
+	bad := `{"Members":[{"UserSub":"u-1","Role":"learner"},{"UserSub":"u-2","Role":"http://example.com/roles#Wizard"}]}`
+	if rec := serve(adm, http.MethodPost, target, bad); rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "members[1]") {
+		t.Fatalf("unknown role: %d %s", rec.Code, rec.Body.String())
+	}
+
+	bulk := `{"Members":[
+		{"UserSub":"u-1","Role":"Learner","Name":"Ann"},
+		{"UserSub":"u-2","Role":"` + instructor + `","Name":"Ben"},
+		{"UserSub":"u-2","Role":"learner","Name":"Ben"},
+		{"UserSub":"u-3","Role":"learner","Status":"Inactive"}]}`
+	if rec := serve(adm, http.MethodPost, target, bulk); rec.Code != http.StatusOK {
+		t.Fatalf("bulk upsert: %d %s", rec.Code, rec.Body.String())
+	}
+	// Upserting again updates in place.
+	if rec := serve(adm, http.MethodPost, target, `{"UserSub":"u-1","Role":"learner","Name":"Ann Lee"}`); rec.Code != http.StatusOK {
+		t.Fatalf("single upsert: %d %s", rec.Code, rec.Body.String())
+	}
+
+	srv := nrps.Routes(&nrps.Server{
+		Store:           store,
+		ResolveTenantID: func(*http.Request) (string, error) { return "default", nil },
+	})
+	rec := serve(srv, http.MethodGet, "/contexts/ctx-1/memberships", "")
+	var c struct {
+		Members []struct {
+			UserID string   `json:"user_id"`
+			Name   string   `json:"name"`
+			Roles  []string `json:"roles"`
+			Status string   `json:"status"`
+		}
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &c); err != nil || len(c.Members) != 3 {
+		t.Fatalf("memberships: %v %s", err, rec.Body.String())
+	}
+	ann, ben, u3 := c.Members[0], c.Members[1], c.Members[2]
+	if ann.Name != "Ann Lee" || len(ann.Roles) != 1 || ann.Roles[0] != learner {
+		t.Fatalf("u-1: %+v", ann)
+	}
+	if len(ben.Roles) != 2 || ben.Roles[0] != instructor || ben.Roles[1] != learner {
+		t.Fatalf("u-2 roles: %v", ben.Roles)
+	}
+	if u3.Status != "Inactive" {
+		t.Fatalf("u-3 status: %q", u3.Status)
+	}
+}
+
+func TestSQLStore_ResourceLinkFilter(t *testing.T) {
+	store := newSQLStore(t)
+	adm := admin.ContextRoutes(store)
+	if rec := serve(adm, http.MethodPost, "/tenants/default/contexts", `{"ID":"ctx-1"}`); rec.Code != http.StatusCreated {
+		t.Fatalf("create context: %d", rec.Code)
+	}
+	bulk := `{"Members":[
+		{"UserSub":"u-1","Role":"learner","ResourceLinkIDs":["rl-a"]},
+		{"UserSub":"u-2","Role":"learner","ResourceLinkIDs":["rl-b"]},
+		{"UserSub":"u-3","Role":"instructor","ResourceLinkIDs":["rl-a","rl-b"]},
+		{"UserSub":"u-4","Role":"learner"}]}`
+	if rec := serve(adm, http.MethodPost, "/tenants/default/contexts/ctx-1/enrollments", bulk); rec.Code != http.StatusOK {
+		t.Fatalf("enroll: %d %s", rec.Code, rec.Body.String())
+	}
+
+	srv := nrps.Routes(&nrps.Server{
+		Store:           store,
+		ResolveTenantID: func(*http.Request) (string, error) { return "default", nil },
+	})
+	members := func(query string) []string {
+		t.Helper()
+		rec := serve(srv, http.MethodGet, "/contexts/ctx-1/memberships"+query, "")
+		var c struct {
+			Members []struct {
+				UserID string `json:"user_id"`
+			}
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &c); err != nil {
+			t.Fatalf("%s: %v %s", query, err, rec.Body.String())
+		}
+		var ids []string
+		for _, m := range c.Members {
+			ids = append(ids, m.UserID)
+		}
+		return ids
+	}
+
+	cases := map[string]string{
+		"":                                "u-1 u-2 u-3 u-4",
+		"?rlid=rl-a":                      "u-1 u-3",
+		"?rlid=rl-b":                      "u-2 u-3",
+		"?rlid=rl-b&role=learner":         "u-2",
+		"?rlid=rl-none":                   "",
+		"?rlid=rl-a&limit=1&page_token=1": "u-3",
+	}
+	for q, want := range cases {
+		if got := strings.Join(members(q), " "); got != want {
+			t.Errorf("%q: members = %q, want %q", q, got, want)
+		}
+	}
+}
+
+func TestSQLStore_MemberCustomMessage(t *testing.T) {
+	const claimCustom = "https://purl.imsglobal.org/spec/lti/claim/custom"
+	store := newSQLStore(t)
+	adm := admin.ContextRoutes(store)
+	if rec := serve(adm, http.MethodPost, "/tenants/default/contexts", `{"ID":"ctx-1"}`); rec.Code != http.StatusCreated {
+		t.Fatalf("create context: %d", rec.Code)
+	}
+	bulk := `{"Members":[
+		{"UserSub":"u-1","Role":"learner","Custom":{"student_id":"S-17"}},
+		{"UserSub":"u-1","Role":"mentor","Custom":{"section":"B"}},
+		{"UserSub":"u-2","Role":"learner"}]}`
+	if rec := serve(adm, http.MethodPost, "/tenants/default/contexts/ctx-1/enrollments", bulk); rec.Code != http.StatusOK {
+		t.Fatalf("enroll: %d %s", rec.Code, rec.Body.String())
+	}
+
+	srv := nrps.Routes(&nrps.Server{
+		Store:           store,
+		ResolveTenantID: func(*http.Request) (string, error) { return "default", nil },
+	})
+	rec := serve(srv, http.MethodGet, "/contexts/ctx-1/memberships", "")
+	var c struct {
+		Members []struct {
+			UserID  string           `json:"user_id"`
+			Message []map[string]any `json:"message"`
+		}
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &c); err != nil || len(c.Members) != 2 {
+		t.Fatalf("memberships: %v %s", err, rec.Body.String())
+	}
+	withCustom, plain := c.Members[0], c.Members[1]
+	if len(withCustom.Message) != 1 {
+		t.Fatalf("u-1 message: %+v", withCustom.Message)
+	}
+	custom, _ := withCustom.Message[0][claimCustom].(map[string]any)
+	if custom["student_id"] != "S-17" || custom["section"] != "B" {
+		t.Fatalf("u-1 custom: %v", withCustom.Message[0])
+	}
+	if plain.Message != nil {
+		t.Fatalf("u-2 should have no message: %+v", plain)
+	}
+}
@@ -0,0 +1,186 @@
+// pkg/platform/lti/selftest.go
+package lti
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+/*
+Tool self-test (Platform side)
+
+Onboarding a Tool usually fails on the first launch: wrong redirect URI, the
+Tool not trusting our JWKS, an issuer/client_id mismatch. The self-test does a
+synthetic resource-link launch without a browser: it signs a test id_token
+exactly like the authorize endpoint would, POSTs it (form_post) to one of the
+Tool's registered redirect URIs and reports what came back.
+
+	POST /admin/tools/{clientID}/self-test   {"redirect_uri": "...", "deployment_id": "..."}
+
+Both body fields are optional (default: first registered redirect URI and
+deployment "self-test"). A 2xx or 3xx answer counts as accepted; redirects are
+not followed so the Tool's own answer is what gets reported.
+
+Mount it behind platform-admin auth; it makes outbound requests on demand.
+*/
+
+// SelfTestServer performs synthetic launches against registered Tools.
+type SelfTestServer struct {
+	Issuers         IssuerResolver
+	Registry        ToolRegistry
+	Signer          Signer
+	ResolveTenantID func(*http.Request) (string, error)
+
+	// Optional knobs
+	Client *http.Client // default: 10s timeout, redirects not followed
+	Now    func() time.Time
+}
+
+// SelfTestResult is the report returned by the self-test endpoint.
+type SelfTestResult struct {
+	ClientID    string `json:"client_id"`
+	RedirectURI string `json:"redirect_uri"`
+	Accepted    bool   `json:"accepted"`
+	Status      int    `json:"status,omitempty"`   // the Tool's HTTP status
+	Location    string `json:"location,omitempty"` // where the Tool redirected to, if it did
+	LatencyMS   int64  `json:"latency_ms"`
+	Error       string `json:"error,omitempty"` // transport error, or why the Tool refused
+	Body        string `json:"body,omitempty"`  // start of the Tool's response when refused
+}
+
+const selfTestBodyLimit = 512
+
+// Handler returns the http.HandlerFunc for POST /admin/tools/{clientID}/self-test.
+func (s *SelfTestServer) Handler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if s.Issuers == nil || s.Registry == nil || s.Signer == nil || s.ResolveTenantID == nil {
+			writeErr(w, http.StatusInternalServerError, "self-test not configured")
+			return
+		}
+		tenantID, err := s.ResolveTenantID(r)
+		if err != nil || tenantID == "" {
+			writeErr(w, http.StatusBadRequest, "unable to resolve tenant")
+			return
+		}
+		clientID := chi.URLParam(r, "clientID")
+
+		var req struct {
+			RedirectURI  string `json:"redirect_uri"`
+			DeploymentID string `json:"deployment_id"`
+		}
+		if r.ContentLength != 0 {
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+				writeErr(w, http.StatusBadRequest, "invalid JSON: "+err.Error())
+				return
+			}
+		}
+
+		tool, err := s.Registry.GetTool(r.Context(), tenantID, clientID)
+		if err != nil || tool.ClientID == "" {
+			writeErr(w, http.StatusNotFound, "tool not found")
+			return
+		}
+		redirectURI := strings.TrimSpace(req.RedirectURI)
+		if redirectURI == "" && len(tool.RedirectURIs) > 0 {
+			redirectURI = strings.TrimSpace(tool.RedirectURIs[0])
+		}
+		if redirectURI == "" || !redirectAllowed(redirectURI, tool.RedirectURIs, tool.RedirectMatch) {
+			writeErr(w, http.StatusBadRequest, "redirect_uri is not registered for this tool")
+			return
+		}
+
+		iss, err := s.Issuers.IssuerForTenant(r.Context(), tenantID)
+		if err != nil || !isHTTPURL(iss) {
+			writeErr(w, http.StatusInternalServerError, "issuer resolution failed")
+			return
+		}
+		idToken, err := s.Signer.Sign(r.Context(), tenantID, s.claims(iss, clientID, redirectURI, req.DeploymentID))
+		if err != nil {
+			writeErr(w, http.StatusInternalServerError, "signing failed")
+			return
+		}
+
+		res := s.launch(r.Context(), redirectURI, idToken)
+		res.ClientID = clientID
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(res)
+	}
+}
+
+// claims builds a minimal resource-link launch, marked as a self-test so a
+// Tool can tell it apart from a real user.
+func (s *SelfTestServer) claims(iss, clientID, redirectURI, deploymentID string) map[string]any {
+	now := s.now()
+	return map[string]any{
+		"iss":               iss,
+		"aud":               clientID,
+		"azp":               clientID,
+		"sub":               "self-test",
+		"iat":               now.Unix(),
+		"exp":               now.Add(5 * time.Minute).Unix(),
+		"nonce":             randHex(16),
+		ltiClaimMessageType: msgTypeResourceLink,
+		ltiClaimVersion:     "1.3.0",
+		ltiClaimTarget:      redirectURI,
+		ltiClaimDeployment:  nonEmpty(deploymentID, "self-test"),
+		ltiClaimResource:    map[string]any{"id": "self-test"},
+		ltiClaimRoles:       []string{"http://purl.imsglobal.org/vocab/lis/v2/membership#Instructor"},
+		ltiClaimCustom:      map[string]any{"mindengage_self_test": "true"},
+	}
+}
+
+func (s *SelfTestServer) launch(ctx context.Context, redirectURI, idToken string) SelfTestResult {
+	res := SelfTestResult{RedirectURI: redirectURI}
+	form := url.Values{"id_token": {idToken}, "state": {"self-test-" + randHex(8)}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, redirectURI, strings.NewReader(form.Encode()))
+	if err != nil {
+		res.Error = err.Error()
+		return res
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	start := time.Now()
+	resp, err := s.client().Do(req)
+	res.LatencyMS = time.Since(start).Milliseconds()
+	if err != nil {
+		res.Error = err.Error()
+		return res
+	}
+	defer resp.Body.Close()
+
+	res.Status = resp.StatusCode
+	res.Location = resp.Header.Get("Location")
+	res.Accepted = resp.StatusCode >= 200 && resp.StatusCode < 400
+	if !res.Accepted {
+		b, _ := io.ReadAll(io.LimitReader(resp.Body, selfTestBodyLimit))
+		res.Body = string(b)
+		res.Error = "tool rejected the launch: " + resp.Status
+	}
+	return res
+}
+
+func (s *SelfTestServer) client() *http.Client {
+	if s.Client != nil {
+		return s.Client
+	}
+	return &http.Client{
+		Timeout: 10 * time.Second,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+func (s *SelfTestServer) now() time.Time {
+	if s.Now != nil {
+		return s.Now()
+	}
+	return time.Now()
+}
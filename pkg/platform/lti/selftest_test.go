@@ -0,0 +1,89 @@
+package lti_test
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/mind-engage/mindengage-lms/pkg/platform/lti"
+)
+
+// mockTool accepts launches whose id_token verifies against pub and is
+// addressed to clientID, like a correctly configured Tool would.
+func mockTool(t *testing.T, pub *rsa.PublicKey, clientID string) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tok, err := jwt.Parse(r.PostFormValue("id_token"), func(*jwt.Token) (any, error) { return pub, nil },
+			jwt.WithValidMethods([]string{"RS256"}), jwt.WithAudience(clientID))
+		if err != nil || !tok.Valid {
+			http.Error(w, "invalid id_token", http.StatusUnauthorized)
+			return
+		}
+		http.Redirect(w, r, "/app", http.StatusFound)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestSelfTest_SyntheticLaunch(t *testing.T) {
+	ctx := context.Background()
+	km := &lti.KeyManager{Storage: lti.NewInMemoryKeyStorage(), RSAKeyBits: 1024}
+	kid, err := km.EnsureKey(ctx, "t1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec, _ := km.Storage.Get(ctx, "t1", kid)
+	other := &lti.KeyManager{Storage: lti.NewInMemoryKeyStorage(), RSAKeyBits: 1024}
+	otherKID, _ := other.EnsureKey(ctx, "t1")
+	otherRec, _ := other.Storage.Get(ctx, "t1", otherKID)
+
+	good := mockTool(t, &rec.RSAPrivate.PublicKey, "tool-1")
+	misconfigured := mockTool(t, &otherRec.RSAPrivate.PublicKey, "tool-2") // trusts someone else's JWKS
+	tools := toolMap{
+		"tool-1": {ClientID: "tool-1", RedirectURIs: []string{good.URL + "/launch"}},
+		"tool-2": {ClientID: "tool-2", RedirectURIs: []string{misconfigured.URL + "/launch"}},
+	}
+	st := &lti.SelfTestServer{
+		Issuers:         staticIssuer("https://platform.example"),
+		Registry:        tools,
+		Signer:          km,
+		ResolveTenantID: func(*http.Request) (string, error) { return "t1", nil },
+	}
+	r := chi.NewRouter()
+	r.Post("/admin/tools/{clientID}/self-test", st.Handler())
+	run := func(clientID, body string) (int, lti.SelfTestResult) {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/admin/tools/"+clientID+"/self-test", strings.NewReader(body)))
+		var res lti.SelfTestResult
+		if w.Code == http.StatusOK {
+			if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return w.Code, res
+	}
+
+	code, res := run("tool-1", "")
+	if code != http.StatusOK || !res.Accepted || res.Status != http.StatusFound || res.Location != "/app" {
+		t.Fatalf("good tool: %d %+v", code, res)
+	}
+
+	code, res = run("tool-2", "")
+	if code != http.StatusOK || res.Accepted || res.Status != http.StatusUnauthorized || !strings.Contains(res.Body, "invalid id_token") {
+		t.Fatalf("misconfigured tool: %d %+v", code, res)
+	}
+
+	if code, _ := run("tool-1", `{"redirect_uri":"https://evil.example/launch"}`); code != http.StatusBadRequest {
+		t.Fatalf("unregistered redirect: status %d", code)
+	}
+	if code, _ := run("nope", ""); code != http.StatusNotFound {
+		t.Fatalf("unknown tool: status %d", code)
+	}
+}
@@ -4,6 +4,8 @@ package lti
 import (
 	"context"
 	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
 	"crypto/rsa"
 	"crypto/sha256"
 	"crypto/subtle"
@@ -11,6 +13,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"log"
 	"math/big"
 	"net/http"
 	"net/url"
@@ -72,7 +75,14 @@ type OAuthClient struct {
 	SecretHash string
 	JWKS       JWKS
 
-	// AllowedScopes restricts scope grants; empty means "any known LTI scopes".
+	// PrevSecretHash is the secret replaced by the last rotation. It keeps
+	// authenticating until PrevSecretExpiresAt so the Tool can switch over
+	// without downtime; a zero PrevSecretExpiresAt disables it.
+	PrevSecretHash      string
+	PrevSecretExpiresAt time.Time
+
+	// AllowedScopes restricts scope grants; empty falls back to
+	// TokenServer.DefaultScopes (which denies everything unless configured).
 	AllowedScopes []string
 }
 
@@ -95,10 +105,44 @@ type TokenServer struct {
 	// Optional knobs
 	AccessTokenTTL time.Duration // default 3600s
 	Now            func() time.Time
+	// ScopeTTL caps the lifetime of tokens carrying a scope (e.g. a short TTL
+	// for AGS score). A token lives for the smallest TTL among its granted
+	// scopes, never longer than AccessTokenTTL.
+	ScopeTTL map[string]time.Duration
 	// If your platform sits behind a proxy prefix (e.g., "/api")
 	ExternalBasePath string
+
+	// DefaultScopes applies to clients registered without AllowedScopes.
+	// Empty (the default) grants nothing: such clients must be given explicit
+	// scopes. Set to PermissiveDefaultScopes to restore the old behavior.
+	DefaultScopes []string
+	// KnownScopes is the allowlist of scope strings the Platform recognizes
+	// (default: KnownLTIScopes). Requested scopes outside it are refused with
+	// invalid_scope, and client/default scopes outside it are never granted.
+	KnownScopes []string
+	// DropUnknownScopes drops (and logs) unknown requested scopes instead of
+	// refusing the request.
+	DropUnknownScopes bool
+	// Optional: logs use of DefaultScopes and dropped scopes (default: log.Printf).
+	Logf func(format string, args ...any)
+}
+
+// PermissiveDefaultScopes is the broad AGS/NRPS set that used to be granted to
+// clients without AllowedScopes. Opt in via TokenServer.DefaultScopes.
+var PermissiveDefaultScopes = []string{
+	"https://purl.imsglobal.org/spec/lti-ags/scope/lineitem",
+	"https://purl.imsglobal.org/spec/lti-ags/scope/lineitem.readonly",
+	"https://purl.imsglobal.org/spec/lti-ags/scope/score",
+	"https://purl.imsglobal.org/spec/lti-ags/scope/result.readonly",
+	"https://purl.imsglobal.org/spec/lti-nrps/scope/contextmembership.readonly",
 }
 
+// KnownLTIScopes are the scopes this Platform serves: AGS, NRPS and the
+// platform admin scope.
+var KnownLTIScopes = append(append([]string{}, PermissiveDefaultScopes...),
+	"https://mindengage.com/spec/platform/scope/admin",
+)
+
 type tokenResponse struct {
 	AccessToken string `json:"access_token"`
 	TokenType   string `json:"token_type"`
@@ -200,7 +244,7 @@ func (s *TokenServer) Handler() http.HandlerFunc {
 				return
 			}
 		case clientSecret != "":
-			if err := verifySecret(client.SecretHash, clientSecret); err != nil {
+			if err := verifySecret(client, clientSecret, s.now()); err != nil {
 				writeOAuthError(w, http.StatusUnauthorized, errInvalidClient, "invalid client_secret")
 				return
 			}
@@ -210,30 +254,46 @@ func (s *TokenServer) Handler() http.HandlerFunc {
 		}
 
 		// Scope negotiation: intersection(requested, allowed)
+		allowed := client.AllowedScopes
+		if len(allowed) == 0 {
+			allowed = s.DefaultScopes
+			if len(allowed) > 0 {
+				s.logf("lti token: tenant=%s client=%s has no allowed scopes; using default scopes %v", tenantID, clientID, allowed)
+			}
+		}
+		known := s.knownScopes()
+		if dropped := subtractScopes(allowed, known); len(dropped) > 0 {
+			s.logf("lti token: tenant=%s client=%s: not granting unknown scopes %v", tenantID, clientID, dropped)
+			allowed = intersectScopes(allowed, known)
+		}
 		requested := parseScopes(r.PostFormValue("scope"))
-		granted := intersectScopes(requested, client.AllowedScopes)
+		if unknown := subtractScopes(requested, known); len(unknown) > 0 {
+			if !s.DropUnknownScopes {
+				writeOAuthError(w, http.StatusBadRequest, errInvalidScope, "unknown scope: "+strings.Join(unknown, " "))
+				return
+			}
+			s.logf("lti token: tenant=%s client=%s: dropping unknown requested scopes %v", tenantID, clientID, unknown)
+			if requested = intersectScopes(requested, known); len(requested) == 0 {
+				writeOAuthError(w, http.StatusBadRequest, errInvalidScope, "no known scopes requested")
+				return
+			}
+		}
+		var granted []string
+		if len(allowed) > 0 {
+			granted = intersectScopes(requested, allowed)
+		}
 		if len(granted) == 0 && len(requested) > 0 {
 			writeOAuthError(w, http.StatusBadRequest, errInvalidScope, "requested scopes not allowed")
 			return
 		}
-		// If no scopes requested, grant all allowed (or a safe default set)
-		if len(granted) == 0 {
-			if len(client.AllowedScopes) > 0 {
-				granted = uniqueScopes(client.AllowedScopes)
-			} else {
-				// very permissive fallback (platform may narrow later via route-level checks)
-				granted = []string{
-					"https://purl.imsglobal.org/spec/lti-ags/scope/lineitem",
-					"https://purl.imsglobal.org/spec/lti-ags/scope/lineitem.readonly",
-					"https://purl.imsglobal.org/spec/lti-ags/scope/score",
-					"https://purl.imsglobal.org/spec/lti-ags/scope/result.readonly",
-					"https://purl.imsglobal.org/spec/lti-nrps/scope/contextmembership.readonly",
-				}
-			}
+		// If no scopes requested, grant all allowed (empty under the deny default)
+		if len(requested) == 0 {
+			granted = uniqueScopes(allowed)
 		}
 
 		now := s.now()
-		exp := now.Add(s.ttl())
+		ttl := s.ttlFor(granted)
+		exp := now.Add(ttl)
 
 		claims := map[string]any{
 			"iss":       issuer,
@@ -257,7 +317,7 @@ func (s *TokenServer) Handler() http.HandlerFunc {
 		resp := tokenResponse{
 			AccessToken: jwt,
 			TokenType:   "Bearer",
-			ExpiresIn:   int64(s.ttl().Seconds()),
+			ExpiresIn:   int64(ttl.Seconds()),
 			Scope:       strings.Join(granted, " "),
 		}
 
@@ -271,7 +331,17 @@ func (s *TokenServer) Handler() http.HandlerFunc {
 
 /* ---------------------- client_secret_post verification -------------------- */
 
-func verifySecret(storedHash, provided string) error {
+// verifySecret accepts the client's current secret, or its previous one while
+// the rotation overlap (PrevSecretExpiresAt) has not passed.
+func verifySecret(client OAuthClient, provided string, now time.Time) error {
+	err := compareSecret(client.SecretHash, provided)
+	if err == nil || strings.TrimSpace(client.PrevSecretHash) == "" || !now.Before(client.PrevSecretExpiresAt) {
+		return err
+	}
+	return compareSecret(client.PrevSecretHash, provided)
+}
+
+func compareSecret(storedHash, provided string) error {
 	// Accept either bcrypt hash (prefix "$2") or raw equality (dev only).
 	stored := strings.TrimSpace(storedHash)
 	if stored == "" {
@@ -326,7 +396,7 @@ func (s *TokenServer) verifyClientAssertion(_ context.Context, client OAuthClien
 		return errors.New("iss/sub mismatch")
 	}
 	// aud must contain the token endpoint URL
-	if !audContains(c.Aud, audience) {
+	if !AudContains(c.Aud, audience) {
 		return errors.New("aud mismatch")
 	}
 	// exp / iat / nbf windows
@@ -354,7 +424,7 @@ func (s *TokenServer) verifyClientAssertion(_ context.Context, client OAuthClien
 	toVerify := signingInput(assertion) // "base64url(header).base64url(payload)"
 	sum := sha256.Sum256([]byte(toVerify))
 
-	pubKeys, err := rsaPublicKeysFromJWKS(client.JWKS, h.KID)
+	pubKeys, err := RSAPublicKeysFromJWKS(client.JWKS, h.KID)
 	if err != nil {
 		return err
 	}
@@ -416,7 +486,9 @@ func peekJWTIssuerSubject(jwt string) (iss, sub string, err error) {
 	return c.Iss, c.Sub, nil
 }
 
-func audContains(aud interface{}, want string) bool {
+// AudContains reports whether a JWT aud claim (a string or an array of
+// strings) names want.
+func AudContains(aud interface{}, want string) bool {
 	switch v := aud.(type) {
 	case string:
 		return strings.TrimSpace(v) == want
@@ -436,7 +508,9 @@ func audContains(aud interface{}, want string) bool {
 	return false
 }
 
-func rsaPublicKeysFromJWKS(set JWKS, kid string) ([]*rsa.PublicKey, error) {
+// RSAPublicKeysFromJWKS returns the RSA public keys in set. If kid is non-empty,
+// only keys with that kid are returned.
+func RSAPublicKeysFromJWKS(set JWKS, kid string) ([]*rsa.PublicKey, error) {
 	var out []*rsa.PublicKey
 	for _, k := range set.Keys {
 		if k == nil {
@@ -483,6 +557,57 @@ func rsaPublicKeysFromJWKS(set JWKS, kid string) ([]*rsa.PublicKey, error) {
 	return out, nil
 }
 
+// ECPublicKeysFromJWKS returns the EC public keys on curve crv (e.g. "P-256")
+// in set. If kid is non-empty, only keys with that kid are returned.
+func ECPublicKeysFromJWKS(set JWKS, kid, crv string) ([]*ecdsa.PublicKey, error) {
+	var curve elliptic.Curve
+	switch crv {
+	case "P-256":
+		curve = elliptic.P256()
+	case "P-384":
+		curve = elliptic.P384()
+	case "P-521":
+		curve = elliptic.P521()
+	default:
+		return nil, fmt.Errorf("unsupported curve %q", crv)
+	}
+	var out []*ecdsa.PublicKey
+	for _, k := range set.Keys {
+		if k == nil {
+			continue
+		}
+		if t, _ := k["kty"].(string); t != "EC" {
+			continue
+		}
+		if c, _ := k["crv"].(string); c != crv {
+			continue
+		}
+		if kid != "" {
+			if got, _ := k["kid"].(string); got != kid {
+				continue
+			}
+		}
+		xStr, _ := k["x"].(string)
+		yStr, _ := k["y"].(string)
+		xb, err := base64.RawURLEncoding.DecodeString(xStr)
+		if err != nil || len(xb) == 0 {
+			continue
+		}
+		yb, err := base64.RawURLEncoding.DecodeString(yStr)
+		if err != nil || len(yb) == 0 {
+			continue
+		}
+		out = append(out, &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(xb), Y: new(big.Int).SetBytes(yb)})
+	}
+	if len(out) == 0 {
+		if kid != "" {
+			return nil, fmt.Errorf("no EC key with kid %q", kid)
+		}
+		return nil, errors.New("no EC keys in JWKS")
+	}
+	return out, nil
+}
+
 // scope helpers
 
 func parseScopes(s string) []string {
@@ -508,6 +633,21 @@ func uniqueScopes(in []string) []string {
 	return out
 }
 
+// subtractScopes returns the scopes in xs that are not in known.
+func subtractScopes(xs, known []string) []string {
+	set := map[string]struct{}{}
+	for _, k := range known {
+		set[strings.TrimSpace(k)] = struct{}{}
+	}
+	var out []string
+	for _, x := range xs {
+		if _, ok := set[strings.TrimSpace(x)]; !ok {
+			out = append(out, x)
+		}
+	}
+	return out
+}
+
 func intersectScopes(requested, allowed []string) []string {
 	if len(requested) == 0 {
 		return nil
@@ -538,6 +678,17 @@ func (s *TokenServer) ttl() time.Duration {
 	return time.Hour
 }
 
+// ttlFor is the access token lifetime for the granted scopes.
+func (s *TokenServer) ttlFor(scopes []string) time.Duration {
+	ttl := s.ttl()
+	for _, sc := range scopes {
+		if d, ok := s.ScopeTTL[sc]; ok && d > 0 && d < ttl {
+			ttl = d
+		}
+	}
+	return ttl
+}
+
 func (s *TokenServer) now() time.Time {
 	if s.Now != nil {
 		return s.Now()
@@ -545,6 +696,21 @@ func (s *TokenServer) now() time.Time {
 	return time.Now().UTC()
 }
 
+func (s *TokenServer) knownScopes() []string {
+	if len(s.KnownScopes) > 0 {
+		return s.KnownScopes
+	}
+	return KnownLTIScopes
+}
+
+func (s *TokenServer) logf(format string, args ...any) {
+	if s.Logf != nil {
+		s.Logf(format, args...)
+		return
+	}
+	log.Printf(format, args...)
+}
+
 func (s *TokenServer) absoluteTokenURL(r *http.Request) string {
 	scheme := schemeFromRequest(r)
 	host := hostWithoutPort(r.Host)
@@ -0,0 +1,240 @@
+package lti_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/mind-engage/mindengage-lms/pkg/platform/lti"
+)
+
+type clientMap map[string]lti.OAuthClient
+
+func (m clientMap) GetOAuthClient(_ context.Context, _, clientID string) (lti.OAuthClient, error) {
+	c, ok := m[clientID]
+	if !ok {
+		return lti.OAuthClient{}, fmt.Errorf("unknown client %q", clientID)
+	}
+	return c, nil
+}
+
+func requestToken(t *testing.T, ts *lti.TokenServer, clientID, scope string) (int, map[string]any) {
+	t.Helper()
+	form := url.Values{
+		"grant_type":    {"client_credentials"},
+		"client_id":     {clientID},
+		"client_secret": {"s3cret"},
+	}
+	if scope != "" {
+		form.Set("scope", scope)
+	}
+	req := httptest.NewRequest(http.MethodPost, "/oauth/token", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rec := httptest.NewRecorder()
+	ts.Handler()(rec, req)
+	var body map[string]any
+	_ = json.NewDecoder(rec.Body).Decode(&body)
+	return rec.Code, body
+}
+
+func newTokenServer(signer lti.Signer, defaults []string, logs *[]string) *lti.TokenServer {
+	return &lti.TokenServer{
+		ResolveTenantID: func(*http.Request) (string, error) { return "default", nil },
+		Issuers:         staticIssuer("https://platform.example"),
+		Registry: clientMap{
+			"bare":   {ClientID: "bare", SecretHash: "s3cret"},
+			"scoped": {ClientID: "scoped", SecretHash: "s3cret", AllowedScopes: []string{"https://purl.imsglobal.org/spec/lti-ags/scope/score"}},
+		},
+		Signer:        signer,
+		DefaultScopes: defaults,
+		Logf:          func(f string, a ...any) { *logs = append(*logs, fmt.Sprintf(f, a...)) },
+	}
+}
+
+func TestTokenServer_DefaultScopes(t *testing.T) {
+	t.Run("deny default: no allowed, no requested -> empty grant", func(t *testing.T) {
+		var logs []string
+		signer := &capturingSigner{}
+		code, body := requestToken(t, newTokenServer(signer, nil, &logs), "bare", "")
+		if code != http.StatusOK {
+			t.Fatalf("status = %d body=%v", code, body)
+		}
+		if got := signer.claims["scope"]; got != "" {
+			t.Fatalf("scope = %q, want empty", got)
+		}
+		if len(logs) != 0 {
+			t.Fatalf("unexpected logs: %v", logs)
+		}
+	})
+
+	t.Run("deny default: requested scopes rejected", func(t *testing.T) {
+		var logs []string
+		code, body := requestToken(t, newTokenServer(&capturingSigner{}, nil, &logs), "bare", "https://purl.imsglobal.org/spec/lti-ags/scope/score")
+		if code != http.StatusBadRequest || body["error"] != "invalid_scope" {
+			t.Fatalf("status = %d body=%v, want 400 invalid_scope", code, body)
+		}
+	})
+
+	t.Run("configured defaults are granted and logged", func(t *testing.T) {
+		var logs []string
+		signer := &capturingSigner{}
+		code, _ := requestToken(t, newTokenServer(signer, lti.PermissiveDefaultScopes, &logs), "bare", "")
+		if code != http.StatusOK {
+			t.Fatalf("status = %d", code)
+		}
+		if got := strings.Fields(signer.claims["scope"].(string)); len(got) != len(lti.PermissiveDefaultScopes) {
+			t.Fatalf("scope = %v", got)
+		}
+		if len(logs) != 1 {
+			t.Fatalf("logs = %v, want one entry", logs)
+		}
+	})
+
+	t.Run("explicit allowed scopes ignore defaults", func(t *testing.T) {
+		var logs []string
+		signer := &capturingSigner{}
+		code, _ := requestToken(t, newTokenServer(signer, lti.PermissiveDefaultScopes, &logs), "scoped", "")
+		if code != http.StatusOK {
+			t.Fatalf("status = %d", code)
+		}
+		if got := signer.claims["scope"]; got != "https://purl.imsglobal.org/spec/lti-ags/scope/score" {
+			t.Fatalf("scope = %q", got)
+		}
+		if len(logs) != 0 {
+			t.Fatalf("unexpected logs: %v", logs)
+		}
+	})
+}
+
+func TestTokenServer_SecretRotationOverlap(t *testing.T) {
+	hash := func(s string) string {
+		h, err := bcrypt.GenerateFromPassword([]byte(s), bcrypt.MinCost)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(h)
+	}
+	rotatedAt := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	now := rotatedAt
+	ts := &lti.TokenServer{
+		ResolveTenantID: func(*http.Request) (string, error) { return "default", nil },
+		Issuers:         staticIssuer("https://platform.example"),
+		Registry: clientMap{"tool": {
+			ClientID:            "tool",
+			SecretHash:          hash("new-secret"),
+			PrevSecretHash:      hash("old-secret"),
+			PrevSecretExpiresAt: rotatedAt.Add(time.Hour),
+		}},
+		Signer: &capturingSigner{},
+		Now:    func() time.Time { return now },
+	}
+	try := func(secret string) int {
+		form := url.Values{"grant_type": {"client_credentials"}, "client_id": {"tool"}, "client_secret": {secret}}
+		req := httptest.NewRequest(http.MethodPost, "/oauth/token", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rec := httptest.NewRecorder()
+		ts.Handler()(rec, req)
+		return rec.Code
+	}
+
+	for _, secret := range []string{"new-secret", "old-secret"} {
+		if code := try(secret); code != http.StatusOK {
+			t.Fatalf("during overlap %s: status %d", secret, code)
+		}
+	}
+	if code := try("other"); code != http.StatusUnauthorized {
+		t.Fatalf("wrong secret during overlap: status %d", code)
+	}
+
+	now = rotatedAt.Add(time.Hour)
+	if code := try("old-secret"); code != http.StatusUnauthorized {
+		t.Fatalf("old secret after overlap: status %d", code)
+	}
+	if code := try("new-secret"); code != http.StatusOK {
+		t.Fatalf("new secret after overlap: status %d", code)
+	}
+}
+
+func TestTokenServer_UnknownScopes(t *testing.T) {
+	const score = "https://purl.imsglobal.org/spec/lti-ags/scope/score"
+	const bogus = "https://evil.example/scope/everything"
+
+	t.Run("unknown requested scope rejected", func(t *testing.T) {
+		var logs []string
+		code, body := requestToken(t, newTokenServer(&capturingSigner{}, lti.PermissiveDefaultScopes, &logs), "bare", score+" "+bogus)
+		if code != http.StatusBadRequest || body["error"] != "invalid_scope" {
+			t.Fatalf("status = %d body=%v, want 400 invalid_scope", code, body)
+		}
+	})
+
+	t.Run("drop mode grants the known part", func(t *testing.T) {
+		var logs []string
+		signer := &capturingSigner{}
+		ts := newTokenServer(signer, lti.PermissiveDefaultScopes, &logs)
+		ts.DropUnknownScopes = true
+		code, body := requestToken(t, ts, "bare", score+" "+bogus)
+		if code != http.StatusOK {
+			t.Fatalf("status = %d body=%v", code, body)
+		}
+		if got := signer.claims["scope"]; got != score {
+			t.Fatalf("scope = %q, want %q", got, score)
+		}
+		if code, _ := requestToken(t, ts, "bare", bogus); code != http.StatusBadRequest {
+			t.Fatalf("only unknown scopes: status = %d, want 400", code)
+		}
+	})
+
+	t.Run("unknown default scope never granted", func(t *testing.T) {
+		var logs []string
+		signer := &capturingSigner{}
+		code, _ := requestToken(t, newTokenServer(signer, []string{score, bogus}, &logs), "bare", "")
+		if code != http.StatusOK {
+			t.Fatalf("status = %d", code)
+		}
+		if got := signer.claims["scope"]; got != score {
+			t.Fatalf("scope = %q, want %q", got, score)
+		}
+	})
+}
+
+func TestTokenServer_ScopeTTL(t *testing.T) {
+	const (
+		score    = "https://purl.imsglobal.org/spec/lti-ags/scope/score"
+		lineRead = "https://purl.imsglobal.org/spec/lti-ags/scope/lineitem.readonly"
+	)
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	var logs []string
+	signer := &capturingSigner{}
+	ts := newTokenServer(signer, lti.PermissiveDefaultScopes, &logs)
+	ts.Now = func() time.Time { return now }
+	ts.ScopeTTL = map[string]time.Duration{score: 5 * time.Minute, lineRead: 2 * time.Hour}
+
+	cases := []struct {
+		scope string
+		want  time.Duration
+	}{
+		{score + " " + lineRead, 5 * time.Minute}, // shortest wins
+		{lineRead, time.Hour},                     // capped by AccessTokenTTL
+		{"https://purl.imsglobal.org/spec/lti-ags/scope/result.readonly", time.Hour},
+	}
+	for _, tc := range cases {
+		code, body := requestToken(t, ts, "bare", tc.scope)
+		if code != http.StatusOK {
+			t.Fatalf("%q: status = %d body=%v", tc.scope, code, body)
+		}
+		if got := signer.claims["exp"]; got != now.Add(tc.want).Unix() {
+			t.Errorf("%q: exp = %v, want now+%v", tc.scope, got, tc.want)
+		}
+		if got := body["expires_in"]; got != tc.want.Seconds() {
+			t.Errorf("%q: expires_in = %v, want %v", tc.scope, got, tc.want.Seconds())
+		}
+	}
+}
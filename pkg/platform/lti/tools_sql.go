@@ -0,0 +1,76 @@
+// pkg/platform/lti/tools_sql.go
+package lti
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// SQLToolRegistry implements ToolRegistry over the platform tools table
+// (see pkg/platform/storage/migrations.go).
+type SQLToolRegistry struct {
+	DB *sql.DB
+}
+
+func (s SQLToolRegistry) GetTool(ctx context.Context, tenantID, clientID string) (Tool, error) {
+	var name, redirects, match string
+	err := s.DB.QueryRowContext(ctx,
+		`SELECT name, redirect_uris, redirect_match FROM tools WHERE client_id=$1 AND tenant_id=$2`,
+		clientID, tenantID).Scan(&name, &redirects, &match)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Tool{}, fmt.Errorf("unknown tool %q", clientID)
+	}
+	if err != nil {
+		return Tool{}, err
+	}
+	t := Tool{ClientID: clientID, Name: name, RedirectMatch: match}
+	if err := json.Unmarshal([]byte(redirects), &t.RedirectURIs); err != nil {
+		return Tool{}, fmt.Errorf("tool %q: redirect_uris: %w", clientID, err)
+	}
+	return t, nil
+}
+
+// SQLOAuthRegistry implements OAuthClientRegistry over the tools table. The
+// previous client secret is returned with its expiry, so a rotated Tool keeps
+// authenticating until the overlap ends or is revoked.
+type SQLOAuthRegistry struct {
+	DB *sql.DB
+	// Keys fetches the Tool's JWKS for private_key_jwt; without it only
+	// client_secret_post works.
+	Keys JWKSFetcher
+}
+
+func (s SQLOAuthRegistry) GetOAuthClient(ctx context.Context, tenantID, clientID string) (OAuthClient, error) {
+	var (
+		jwksURL, scopes    string
+		secret, prevSecret string
+		prevExpires        sql.NullTime
+	)
+	err := s.DB.QueryRowContext(ctx,
+		`SELECT jwks_url, allowed_scopes, COALESCE(secret_hash,''), COALESCE(prev_secret_hash,''), prev_secret_expires_at
+		 FROM tools WHERE client_id=$1 AND tenant_id=$2`,
+		clientID, tenantID).Scan(&jwksURL, &scopes, &secret, &prevSecret, &prevExpires)
+	if errors.Is(err, sql.ErrNoRows) {
+		return OAuthClient{}, fmt.Errorf("unknown client %q", clientID)
+	}
+	if err != nil {
+		return OAuthClient{}, err
+	}
+	c := OAuthClient{ClientID: clientID, SecretHash: secret, PrevSecretHash: prevSecret}
+	if prevExpires.Valid {
+		c.PrevSecretExpiresAt = prevExpires.Time.UTC()
+	}
+	if err := json.Unmarshal([]byte(scopes), &c.AllowedScopes); err != nil {
+		return OAuthClient{}, fmt.Errorf("tool %q: allowed_scopes: %w", clientID, err)
+	}
+	if s.Keys != nil && jwksURL != "" {
+		// A JWKS outage only fails private_key_jwt, not secret auth.
+		if set, err := s.Keys.FetchJWKS(ctx, jwksURL); err == nil {
+			c.JWKS = set
+		}
+	}
+	return c, nil
+}
@@ -22,7 +22,8 @@ func Up(ctx context.Context, db *DB, driver string) error {
 	}
 
 	var schema string
-	switch normalizeDriver(driver) {
+	dialect := normalizeDriver(driver)
+	switch dialect {
 	case "postgres":
 		schema = schemaPostgres
 	case "sqlite":
@@ -44,9 +45,70 @@ func Up(ctx context.Context, db *DB, driver string) error {
 			}
 		}
 	}
+	return upgradeColumns(ctx, db, dialect)
+}
+
+// addedColumn is a column introduced after its table first shipped.
+// CREATE TABLE IF NOT EXISTS leaves existing tables alone, so Up adds these
+// with ALTER TABLE when they are missing.
+type addedColumn struct {
+	table, name      string
+	postgres, sqlite string // column definition per dialect
+}
+
+var addedColumns = []addedColumn{
+	{"tools", "redirect_match", `TEXT NOT NULL DEFAULT 'exact'`, `TEXT NOT NULL DEFAULT 'exact'`},
+	{"tools", "secret_hash", `TEXT`, `TEXT`},
+	{"tools", "prev_secret_hash", `TEXT`, `TEXT`},
+	{"tools", "prev_secret_expires_at", `TIMESTAMPTZ`, `DATETIME`},
+	{"enrollments", "custom", `JSONB`, `TEXT`},
+}
+
+// upgradeColumns brings tables created by an older schema up to date.
+func upgradeColumns(ctx context.Context, db *DB, dialect string) error {
+	have := map[string]map[string]bool{}
+	for _, c := range addedColumns {
+		cols, ok := have[c.table]
+		if !ok {
+			var err error
+			if cols, err = columnSet(ctx, db, c.table); err != nil {
+				return err
+			}
+			have[c.table] = cols
+		}
+		if cols[c.name] {
+			continue
+		}
+		def := c.sqlite
+		if dialect == "postgres" {
+			def = c.postgres
+		}
+		stmt := fmt.Sprintf(`ALTER TABLE %s ADD COLUMN %s %s`, c.table, c.name, def)
+		if _, err := db.SQL.ExecContext(ctx, stmt); err != nil {
+			return fmt.Errorf("migrations: failed at:\n%s\nerr: %w", stmt, err)
+		}
+		cols[c.name] = true
+	}
 	return nil
 }
 
+func columnSet(ctx context.Context, db *DB, table string) (map[string]bool, error) {
+	rows, err := db.SQL.QueryContext(ctx, `SELECT * FROM `+table+` WHERE 1=0`)
+	if err != nil {
+		return nil, err
+	}
+	cols, err := rows.Columns()
+	_ = rows.Close()
+	if err != nil {
+		return nil, err
+	}
+	set := make(map[string]bool, len(cols))
+	for _, c := range cols {
+		set[strings.ToLower(c)] = true
+	}
+	return set, nil
+}
+
 /* ----------------------------- POSTGRES SCHEMA ----------------------------- */
 
 const schemaPostgres = `
@@ -76,8 +138,12 @@ CREATE TABLE IF NOT EXISTS tools (
   name               TEXT NOT NULL,
   jwks_url           TEXT NOT NULL,
   redirect_uris      JSONB NOT NULL,                  -- array of strings
+  redirect_match     TEXT NOT NULL DEFAULT 'exact',   -- exact|prefix|ignore_query
   allowed_scopes     JSONB NOT NULL,                  -- array of strings
   auth_methods       JSONB NOT NULL,                  -- e.g., ["private_key_jwt","client_secret_post"]
+  secret_hash        TEXT,                            -- bcrypt, client_secret_post
+  prev_secret_hash   TEXT,                            -- accepted until prev_secret_expires_at
+  prev_secret_expires_at TIMESTAMPTZ,
   created_at         TIMESTAMPTZ NOT NULL DEFAULT now(),
   updated_at         TIMESTAMPTZ NOT NULL DEFAULT now()
 );
@@ -112,6 +178,7 @@ CREATE TABLE IF NOT EXISTS enrollments (
   name               TEXT,
   email              TEXT,
   status             TEXT,                            -- Active|Inactive|...
+  custom             JSONB,                           -- NRPS message custom claims
   updated_at         TIMESTAMPTZ NOT NULL DEFAULT now(),
   PRIMARY KEY (tenant_id, context_id, user_sub, role),
   FOREIGN KEY (tenant_id, context_id)
@@ -121,6 +188,17 @@ CREATE TABLE IF NOT EXISTS enrollments (
 CREATE INDEX IF NOT EXISTS enrollments_role_idx
   ON enrollments (tenant_id, context_id, role);
 
+-- Members with access to a resource link (NRPS ?rlid=)
+CREATE TABLE IF NOT EXISTS resource_link_members (
+  tenant_id          TEXT NOT NULL,
+  context_id         TEXT NOT NULL,
+  resource_link_id   TEXT NOT NULL,
+  user_sub           TEXT NOT NULL,
+  PRIMARY KEY (tenant_id, context_id, resource_link_id, user_sub),
+  FOREIGN KEY (tenant_id, context_id)
+    REFERENCES contexts(tenant_id, id) ON DELETE CASCADE
+);
+
 -- AGS line items & results ---------------------------------------------------
 -- Line item "id" in AGS is a URL; we store it as TEXT and use it as the PK.
 CREATE TABLE IF NOT EXISTS platform_line_items (
@@ -209,8 +287,12 @@ CREATE TABLE IF NOT EXISTS tools (
   name               TEXT NOT NULL,
   jwks_url           TEXT NOT NULL,
   redirect_uris      TEXT NOT NULL,                   -- JSON array
+  redirect_match     TEXT NOT NULL DEFAULT 'exact',   -- exact|prefix|ignore_query
   allowed_scopes     TEXT NOT NULL,                   -- JSON array
   auth_methods       TEXT NOT NULL,                   -- JSON array
+  secret_hash        TEXT,                            -- bcrypt, client_secret_post
+  prev_secret_hash   TEXT,                            -- accepted until prev_secret_expires_at
+  prev_secret_expires_at DATETIME,
   created_at         DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
   updated_at         DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
   FOREIGN KEY (tenant_id) REFERENCES tenants(id) ON DELETE CASCADE,
@@ -252,6 +334,7 @@ CREATE TABLE IF NOT EXISTS enrollments (
   name               TEXT,
   email              TEXT,
   status             TEXT,
+  custom             TEXT,                            -- JSON object
   updated_at         DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
   PRIMARY KEY (tenant_id, context_id, user_sub, role),
   FOREIGN KEY (tenant_id, context_id)
@@ -261,6 +344,16 @@ CREATE TABLE IF NOT EXISTS enrollments (
 CREATE INDEX IF NOT EXISTS enrollments_role_idx
   ON enrollments (tenant_id, context_id, role);
 
+CREATE TABLE IF NOT EXISTS resource_link_members (
+  tenant_id          TEXT NOT NULL,
+  context_id         TEXT NOT NULL,
+  resource_link_id   TEXT NOT NULL,
+  user_sub           TEXT NOT NULL,
+  PRIMARY KEY (tenant_id, context_id, resource_link_id, user_sub),
+  FOREIGN KEY (tenant_id, context_id)
+    REFERENCES contexts(tenant_id, id) ON DELETE CASCADE
+);
+
 -- AGS line items & results ---------------------------------------------------
 CREATE TABLE IF NOT EXISTS platform_line_items (
   id                 TEXT PRIMARY KEY,                -- absolute URL this platform returns
@@ -0,0 +1,68 @@
+package storage_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	_ "modernc.org/sqlite"
+
+	"github.com/mind-engage/mindengage-lms/pkg/platform/storage"
+)
+
+func TestUp_UpgradesOlderTables(t *testing.T) {
+	ctx := context.Background()
+	db, err := storage.Connect(ctx, "sqlite", filepath.Join(t.TempDir(), "platform.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	// Tables as the first schema created them.
+	if _, err := db.SQL.Exec(`
+CREATE TABLE tools (
+  client_id          TEXT PRIMARY KEY,
+  tenant_id          TEXT NOT NULL,
+  name               TEXT NOT NULL,
+  jwks_url           TEXT NOT NULL,
+  redirect_uris      TEXT NOT NULL,
+  allowed_scopes     TEXT NOT NULL,
+  auth_methods       TEXT NOT NULL,
+  created_at         DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at         DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+INSERT INTO tools (client_id, tenant_id, name, jwks_url, redirect_uris, allowed_scopes, auth_methods)
+VALUES ('c1', 't1', 'Tool', 'https://tool/jwks', '[]', '[]', '[]');
+CREATE TABLE enrollments (
+  tenant_id          TEXT NOT NULL,
+  context_id         TEXT NOT NULL,
+  user_sub           TEXT NOT NULL,
+  role               TEXT NOT NULL,
+  name               TEXT,
+  email              TEXT,
+  status             TEXT,
+  updated_at         DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (tenant_id, context_id, user_sub, role)
+);`); err != nil {
+		t.Fatal(err)
+	}
+
+	// Twice: the upgrade must be idempotent.
+	for i := 0; i < 2; i++ {
+		if err := storage.Up(ctx, db, "sqlite"); err != nil {
+			t.Fatalf("Up #%d: %v", i+1, err)
+		}
+	}
+	var match string
+	if err := db.SQL.QueryRow(`SELECT redirect_match FROM tools WHERE client_id='c1'`).Scan(&match); err != nil {
+		t.Fatal(err)
+	}
+	if match != "exact" {
+		t.Fatalf("redirect_match = %q, want exact", match)
+	}
+	if _, err := db.SQL.Exec(`UPDATE tools SET secret_hash='h', prev_secret_hash='p', prev_secret_expires_at=CURRENT_TIMESTAMP WHERE client_id='c1'`); err != nil {
+		t.Fatalf("secret columns: %v", err)
+	}
+	if _, err := db.SQL.Exec(`INSERT INTO enrollments (tenant_id, context_id, user_sub, role, custom) VALUES ('t1', 'ctx', 'u1', 'Learner', '{}')`); err != nil {
+		t.Fatalf("enrollments.custom: %v", err)
+	}
+}
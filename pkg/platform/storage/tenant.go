@@ -0,0 +1,135 @@
+// pkg/platform/storage/tenant.go
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strconv"
+	"strings"
+)
+
+/*
+Tenant-scoped statements
+
+Every platform table carries tenant_id, and every store query must filter on
+it; forgetting the predicate leaks another tenant's rows. Scoped builds the
+statements instead of taking raw SQL, so the predicate cannot be left out:
+
+	tq, err := storage.Tenant(db, tenantID)
+	rows, err := tq.Query(ctx, "platform_line_items", "id, label",
+		"context_id=$2", "ORDER BY id LIMIT $3", contextID, limit)
+	// SELECT id, label FROM platform_line_items
+	//   WHERE tenant_id=$1 AND (context_id=$2) ORDER BY id LIMIT $3
+
+$1 is always the tenant id and caller arguments start at $2. The condition is
+parenthesized, so an OR inside it cannot widen the scope. A subquery on
+another table in cond must scope itself with tenant_id=$1 too. Table and
+column names are spliced in as-is; they come from code, never from requests.
+*/
+
+// Querier is what *sql.DB and *sql.Tx have in common.
+type Querier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// ErrNoTenant is returned for statements without a tenant to scope them to.
+var ErrNoTenant = errors.New("storage: tenant id required")
+
+// Scoped runs statements confined to one tenant's rows. The zero value
+// refuses every statement.
+type Scoped struct {
+	q        Querier
+	tenantID string
+}
+
+// Tenant scopes q (a *sql.DB or *sql.Tx) to tenantID.
+func Tenant(q Querier, tenantID string) (Scoped, error) {
+	if q == nil || strings.TrimSpace(tenantID) == "" {
+		return Scoped{}, ErrNoTenant
+	}
+	return Scoped{q: q, tenantID: tenantID}, nil
+}
+
+// TenantID is the tenant the statements are scoped to.
+func (s Scoped) TenantID() string { return s.tenantID }
+
+// Row is the subset of *sql.Row that QueryRow returns.
+type Row interface {
+	Scan(dest ...any) error
+}
+
+type errRow struct{ err error }
+
+func (r errRow) Scan(...any) error { return r.err }
+
+// Query runs SELECT cols FROM table WHERE tenant_id=$1 AND (cond) tail.
+// cond may be empty; tail holds ORDER BY / LIMIT / OFFSET.
+func (s Scoped) Query(ctx context.Context, table, cols, cond, tail string, args ...any) (*sql.Rows, error) {
+	if s.q == nil {
+		return nil, ErrNoTenant
+	}
+	return s.q.QueryContext(ctx, s.selectSQL(table, cols, cond, tail), s.args(args)...)
+}
+
+// QueryRow is Query for a single row.
+func (s Scoped) QueryRow(ctx context.Context, table, cols, cond, tail string, args ...any) Row {
+	if s.q == nil {
+		return errRow{ErrNoTenant}
+	}
+	return s.q.QueryRowContext(ctx, s.selectSQL(table, cols, cond, tail), s.args(args)...)
+}
+
+// Insert runs INSERT INTO table (tenant_id, cols) VALUES ($1, ...) tail, with
+// one placeholder per argument; tail may hold an ON CONFLICT clause.
+func (s Scoped) Insert(ctx context.Context, table, cols, tail string, args ...any) (sql.Result, error) {
+	if s.q == nil {
+		return nil, ErrNoTenant
+	}
+	ph := make([]string, len(args)+1)
+	for i := range ph {
+		ph[i] = "$" + strconv.Itoa(i+1)
+	}
+	q := "INSERT INTO " + table + " (tenant_id, " + cols + ") VALUES (" + strings.Join(ph, ", ") + ")"
+	if tail != "" {
+		q += " " + tail
+	}
+	return s.q.ExecContext(ctx, q, s.args(args)...)
+}
+
+// Update runs UPDATE table SET set WHERE tenant_id=$1 AND (cond).
+func (s Scoped) Update(ctx context.Context, table, set, cond string, args ...any) (sql.Result, error) {
+	if s.q == nil {
+		return nil, ErrNoTenant
+	}
+	return s.q.ExecContext(ctx, "UPDATE "+table+" SET "+set+" WHERE "+s.where(cond), s.args(args)...)
+}
+
+// Delete runs DELETE FROM table WHERE tenant_id=$1 AND (cond).
+func (s Scoped) Delete(ctx context.Context, table, cond string, args ...any) (sql.Result, error) {
+	if s.q == nil {
+		return nil, ErrNoTenant
+	}
+	return s.q.ExecContext(ctx, "DELETE FROM "+table+" WHERE "+s.where(cond), s.args(args)...)
+}
+
+func (s Scoped) selectSQL(table, cols, cond, tail string) string {
+	q := "SELECT " + cols + " FROM " + table + " WHERE " + s.where(cond)
+	if tail != "" {
+		q += " " + tail
+	}
+	return q
+}
+
+func (s Scoped) where(cond string) string {
+	if strings.TrimSpace(cond) == "" {
+		return "tenant_id=$1"
+	}
+	return "tenant_id=$1 AND (" + cond + ")"
+}
+
+func (s Scoped) args(args []any) []any {
+	return append([]any{s.tenantID}, args...)
+}
@@ -0,0 +1,95 @@
+package storage_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	_ "modernc.org/sqlite"
+
+	"github.com/mind-engage/mindengage-lms/pkg/platform/storage"
+)
+
+func TestScoped_CannotEscapeTenant(t *testing.T) {
+	ctx := context.Background()
+	db, err := storage.Connect(ctx, "sqlite", filepath.Join(t.TempDir(), "platform.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if err := storage.Up(ctx, db, "sqlite"); err != nil {
+		t.Fatal(err)
+	}
+	for _, q := range []string{
+		`INSERT INTO tenants (id, issuer) VALUES ('a', 'https://a.example'), ('b', 'https://b.example')`,
+		`INSERT INTO contexts (tenant_id, id, title) VALUES ('a', 'c1', 'A1'), ('b', 'c1', 'B1'), ('b', 'c2', 'B2')`,
+	} {
+		if _, err := db.SQL.Exec(q); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// No tenant, no statements.
+	if _, err := storage.Tenant(db.SQL, ""); !errors.Is(err, storage.ErrNoTenant) {
+		t.Fatalf("empty tenant: err = %v", err)
+	}
+	var zero storage.Scoped
+	if _, err := zero.Query(ctx, "contexts", "id", "", ""); !errors.Is(err, storage.ErrNoTenant) {
+		t.Fatalf("zero Scoped Query: err = %v", err)
+	}
+	if err := zero.QueryRow(ctx, "contexts", "id", "", "").Scan(new(string)); !errors.Is(err, storage.ErrNoTenant) {
+		t.Fatalf("zero Scoped QueryRow: err = %v", err)
+	}
+	if _, err := zero.Delete(ctx, "contexts", ""); !errors.Is(err, storage.ErrNoTenant) {
+		t.Fatalf("zero Scoped Delete: err = %v", err)
+	}
+
+	a, err := storage.Tenant(db.SQL, "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	titles := func(cond string, args ...any) []string {
+		t.Helper()
+		rows, err := a.Query(ctx, "contexts", "title", cond, "ORDER BY title", args...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer rows.Close()
+		var out []string
+		for rows.Next() {
+			var s string
+			if err := rows.Scan(&s); err != nil {
+				t.Fatal(err)
+			}
+			out = append(out, s)
+		}
+		return out
+	}
+	if got := titles(""); len(got) != 1 || got[0] != "A1" {
+		t.Fatalf("unfiltered = %v, want [A1]", got)
+	}
+	// An OR in the condition stays inside the tenant predicate.
+	if got := titles("id=$2 OR 1=1", "c2"); len(got) != 1 || got[0] != "A1" {
+		t.Fatalf("OR condition = %v, want [A1]", got)
+	}
+
+	// Writes are scoped the same way.
+	if _, err := a.Insert(ctx, "contexts", "id, title", "", "c2", "A2"); err != nil {
+		t.Fatal(err)
+	}
+	res, err := a.Update(ctx, "contexts", "title=$2", "1=1", "renamed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := res.RowsAffected(); n != 2 {
+		t.Fatalf("update touched %d rows, want tenant a's 2", n)
+	}
+	if _, err := a.Delete(ctx, "contexts", ""); err != nil {
+		t.Fatal(err)
+	}
+	var left int
+	if err := db.SQL.QueryRow(`SELECT COUNT(*) FROM contexts WHERE tenant_id='b' AND title LIKE 'B%'`).Scan(&left); err != nil || left != 2 {
+		t.Fatalf("tenant b rows = %d (%v), want 2 untouched", left, err)
+	}
+}
@@ -0,0 +1,97 @@
+// pkg/platform/tenants/issuers.go
+package tenants
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// ErrUnknownTenant is returned when a tenant has no row in the tenants table.
+var ErrUnknownTenant = errors.New("tenants: unknown tenant")
+
+// SQLIssuerResolver reads each tenant's issuer from tenants.issuer. It
+// satisfies lti.IssuerResolver and deeplinking.IssuerResolver. Lookups are
+// cached for TTL; unknown tenants are not cached so a newly created tenant
+// works immediately.
+type SQLIssuerResolver struct {
+	DB  *sql.DB
+	TTL time.Duration    // default 5m
+	Now func() time.Time // for tests
+
+	mu    sync.Mutex
+	cache map[string]cachedIssuer
+}
+
+type cachedIssuer struct {
+	issuer  string
+	expires time.Time
+}
+
+func (s *SQLIssuerResolver) IssuerForTenant(ctx context.Context, tenantID string) (string, error) {
+	now := s.now()
+	s.mu.Lock()
+	if c, ok := s.cache[tenantID]; ok && now.Before(c.expires) {
+		s.mu.Unlock()
+		return c.issuer, nil
+	}
+	s.mu.Unlock()
+
+	var iss string
+	err := s.DB.QueryRowContext(ctx, `SELECT issuer FROM tenants WHERE id=$1`, tenantID).Scan(&iss)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", fmt.Errorf("%w %q", ErrUnknownTenant, tenantID)
+	}
+	if err != nil {
+		return "", err
+	}
+	if err := ValidateIssuer(iss); err != nil {
+		return "", fmt.Errorf("tenants: tenant %q: %w", tenantID, err)
+	}
+
+	s.mu.Lock()
+	if s.cache == nil {
+		s.cache = make(map[string]cachedIssuer)
+	}
+	s.cache[tenantID] = cachedIssuer{issuer: iss, expires: now.Add(s.ttl())}
+	s.mu.Unlock()
+	return iss, nil
+}
+
+// Invalidate drops a cached issuer (call after changing tenants.issuer).
+func (s *SQLIssuerResolver) Invalidate(tenantID string) {
+	s.mu.Lock()
+	delete(s.cache, tenantID)
+	s.mu.Unlock()
+}
+
+// ValidateIssuer checks an issuer is an absolute http(s) URL without query or
+// fragment, as LTI requires of the iss claim.
+func ValidateIssuer(iss string) error {
+	u, err := url.Parse(iss)
+	if err != nil || u.Host == "" || (u.Scheme != "https" && u.Scheme != "http") {
+		return fmt.Errorf("issuer %q is not an http(s) URL", iss)
+	}
+	if u.RawQuery != "" || u.Fragment != "" {
+		return fmt.Errorf("issuer %q must not have a query or fragment", iss)
+	}
+	return nil
+}
+
+func (s *SQLIssuerResolver) ttl() time.Duration {
+	if s.TTL <= 0 {
+		return 5 * time.Minute
+	}
+	return s.TTL
+}
+
+func (s *SQLIssuerResolver) now() time.Time {
+	if s.Now != nil {
+		return s.Now()
+	}
+	return time.Now()
+}
@@ -0,0 +1,66 @@
+package tenants_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	_ "modernc.org/sqlite"
+
+	"github.com/mind-engage/mindengage-lms/pkg/platform/storage"
+	"github.com/mind-engage/mindengage-lms/pkg/platform/tenants"
+)
+
+func TestSQLIssuerResolver(t *testing.T) {
+	ctx := context.Background()
+	db, err := storage.Connect(ctx, "sqlite", filepath.Join(t.TempDir(), "platform.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if err := storage.Up(ctx, db, "sqlite"); err != nil {
+		t.Fatal(err)
+	}
+	for _, q := range []string{
+		`INSERT INTO tenants (id, issuer) VALUES ('acme', 'https://acme.lti.example.com')`,
+		`INSERT INTO tenants (id, issuer) VALUES ('globex', 'https://lti.example.com/t/globex')`,
+		`INSERT INTO tenants (id, issuer) VALUES ('broken', 'ftp://example.com')`,
+	} {
+		if _, err := db.SQL.Exec(q); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	r := &tenants.SQLIssuerResolver{DB: db.SQL, TTL: time.Minute, Now: func() time.Time { return now }}
+
+	for tenant, want := range map[string]string{
+		"acme":   "https://acme.lti.example.com",
+		"globex": "https://lti.example.com/t/globex",
+	} {
+		got, err := r.IssuerForTenant(ctx, tenant)
+		if err != nil || got != want {
+			t.Fatalf("%s: issuer = %q, %v; want %q", tenant, got, err, want)
+		}
+	}
+	if _, err := r.IssuerForTenant(ctx, "nope"); !errors.Is(err, tenants.ErrUnknownTenant) {
+		t.Fatalf("unknown tenant err = %v", err)
+	}
+	if _, err := r.IssuerForTenant(ctx, "broken"); err == nil {
+		t.Fatal("non-http issuer accepted")
+	}
+
+	// cached until the TTL runs out
+	if _, err := db.SQL.Exec(`UPDATE tenants SET issuer='https://new.example.com' WHERE id='acme'`); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := r.IssuerForTenant(ctx, "acme"); got != "https://acme.lti.example.com" {
+		t.Fatalf("within ttl = %q", got)
+	}
+	now = now.Add(2 * time.Minute)
+	if got, _ := r.IssuerForTenant(ctx, "acme"); got != "https://new.example.com" {
+		t.Fatalf("after ttl = %q", got)
+	}
+}
@@ -1,5 +1,7 @@
 package tenants
 
+import "time"
+
 type Tenant struct {
 	ID        string
 	Issuer    string // https://{tenant}.lti.mindengage.com
@@ -12,8 +14,15 @@ type Tool struct {
 	Name          string
 	JWKSURL       string
 	RedirectURIs  []string
+	RedirectMatch string // exact (default) | prefix | ignore_query
 	AllowedScopes []string
 	AuthMethods   []string // "private_key_jwt", "client_secret_post"
+
+	// client_secret_post secrets (bcrypt); never serialized. The previous
+	// secret stays valid until PrevSecretExpiresAt after a rotation.
+	SecretHash          string    `json:"-"`
+	PrevSecretHash      string    `json:"-"`
+	PrevSecretExpiresAt time.Time `json:"-"`
 }
 
 type Deployment struct {
@@ -23,3 +32,28 @@ type Deployment struct {
 	ContextID string
 	Title     string
 }
+
+// Context is an LMS course as NRPS/AGS see it.
+type Context struct {
+	ID       string
+	TenantID string
+	Label    string
+	Title    string
+}
+
+// Enrollment gives a user one role in a context.
+type Enrollment struct {
+	TenantID  string
+	ContextID string
+	UserSub   string // platform user id (sub)
+	Role      string // LIS role URI, or an alias such as "learner"/"instructor"
+	Name      string
+	Email     string
+	Status    string // Active (default) | Inactive
+
+	// ResourceLinkIDs are resource links the member is associated with
+	// (NRPS ?rlid= filter); upserts add to them.
+	ResourceLinkIDs []string
+	// Custom claims reported in the member's NRPS "message"; optional.
+	Custom map[string]string
+}
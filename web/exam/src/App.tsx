@@ -832,7 +832,8 @@ function ExamScreen({ jwt, exam, offering, onExit }: { jwt: string; exam: Exam;
       if (res.status === 409) {
         const t = (await res.text()) || "";
         // Only mark submitted if it's actually submitted
-        if (t.toLowerCase().includes("already submitted")) {
+        // (the body may be localized; X-Error-Code is stable)
+        if (res.headers.get("X-Error-Code") === "attempt_submitted" || t.toLowerCase().includes("already submitted")) {
           setAttempt((a) => (a ? { ...a, status: "submitted" } : a));
           return;
         }
@@ -926,7 +927,8 @@ function ExamScreen({ jwt, exam, offering, onExit }: { jwt: string; exam: Exam;
     try {
       const res = await fetch(`${API_BASE}/attempts/${attempt.id}/next-module`, {
         method: "POST",
-        headers: { Authorization: `Bearer ${jwt}` },
+        headers: { Authorization: `Bearer ${jwt}`, "Content-Type": "application/json" },
+        body: JSON.stringify({ from_module_index: attempt.module_index ?? 0 }),
       });
       if (!res.ok) throw new Error(await res.text());
       const data = await res.json() as Attempt;